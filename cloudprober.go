@@ -31,6 +31,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/common/tlsconfig"
@@ -39,6 +40,7 @@ import (
 	"github.com/cloudprober/cloudprober/config/runconfig"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/prober"
+	spb "github.com/cloudprober/cloudprober/prober/proto"
 	"github.com/cloudprober/cloudprober/probes"
 	"github.com/cloudprober/cloudprober/servers"
 	"github.com/cloudprober/cloudprober/surfacers"
@@ -218,6 +220,37 @@ func InitFromConfig(configFile string) error {
 	return nil
 }
 
+// ReloadConfig reloads probes from the provided config. Only the probes that
+// were added, removed or modified are stopped or started; probes with
+// unchanged definitions keep running. Changes to other parts of the config
+// (surfacers, servers, etc) require a restart to take effect.
+func ReloadConfig(configFile string) error {
+	cloudProber.Lock()
+	defer cloudProber.Unlock()
+
+	if cloudProber.prober == nil {
+		return fmt.Errorf("prober is not initialized")
+	}
+
+	configStr, err := config.ParseTemplate(configFile, sysvars.Vars())
+	if err != nil {
+		return err
+	}
+
+	cfg := &configpb.ProberConfig{}
+	if err := proto.UnmarshalText(configStr, cfg); err != nil {
+		return err
+	}
+
+	if err := cloudProber.prober.ReloadProbes(cfg.GetProbe()); err != nil {
+		return err
+	}
+
+	cloudProber.config = cfg
+	cloudProber.textConfig = configStr
+	return nil
+}
+
 // Start starts a previously initialized Cloudprober.
 func Start(ctx context.Context) {
 	cloudProber.Lock()
@@ -249,6 +282,43 @@ func Start(ctx context.Context) {
 	cloudProber.prober.Start(ctx)
 }
 
+// Shutdown drains a previously started Cloudprober: it stops accepting new
+// probe results, writes the results that are already queued to the surfacers
+// and flushes the surfacers, waiting for up to gracePeriod. Start context
+// should be canceled before calling Shutdown. It returns false if draining
+// didn't finish within the grace period.
+func Shutdown(gracePeriod time.Duration) bool {
+	cloudProber.Lock()
+	defer cloudProber.Unlock()
+
+	if cloudProber.prober == nil {
+		panic("Prober is not initialized. Did you call cloudprober.InitFromConfig first?")
+	}
+	return cloudProber.prober.Shutdown(gracePeriod)
+}
+
+// RunOnce runs the probes of a previously initialized Cloudprober for the given
+// number of cycles, flushes the surfacers and returns. It returns true if all
+// the probe runs succeeded. Default servers are not started in this mode.
+func RunOnce(ctx context.Context, count int) bool {
+	cloudProber.Lock()
+	defer cloudProber.Unlock()
+
+	if cloudProber.prober == nil {
+		panic("Prober is not initialized. Did you call cloudprober.InitFromConfig first?")
+	}
+
+	defer func() {
+		cloudProber.defaultServerLn.Close()
+		if cloudProber.defaultGRPCLn != nil {
+			cloudProber.defaultGRPCLn.Close()
+		}
+		cloudProber.cancelInitCtx()
+	}()
+
+	return cloudProber.prober.RunOnce(ctx, count)
+}
+
 // GetConfig returns the prober config.
 func GetConfig() *configpb.ProberConfig {
 	cloudProber.Lock()
@@ -269,3 +339,17 @@ func GetInfo() (map[string]*probes.ProbeInfo, []*surfacers.SurfacerInfo, []*serv
 	defer cloudProber.Unlock()
 	return cloudProber.prober.Probes, cloudProber.prober.Surfacers, cloudProber.prober.Servers
 }
+
+// CloudproberService returns the prober's cloudprober gRPC service
+// implementation. It's used to call the service methods, e.g. from the web
+// interface, without going through a gRPC server. It returns nil if the
+// prober is not initialized yet.
+func CloudproberService() spb.CloudproberServer {
+	cloudProber.Lock()
+	defer cloudProber.Unlock()
+	// Return a nil interface, instead of an interface holding a nil *Prober.
+	if cloudProber.prober == nil {
+		return nil
+	}
+	return cloudProber.prober
+}
@@ -84,3 +84,9 @@ func TestGetDefaultServerPort(t *testing.T) {
 	}
 
 }
+
+func TestCloudproberServiceNotInitialized(t *testing.T) {
+	if svc := CloudproberService(); svc != nil {
+		t.Errorf("CloudproberService()=%v, want nil before initialization", svc)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/cloudprober/cloudprober/common/file"
 	"github.com/cloudprober/cloudprober/config"
 	"github.com/cloudprober/cloudprober/config/runconfig"
+	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/sysvars"
 	"github.com/cloudprober/cloudprober/web"
 )
@@ -47,9 +48,10 @@ var (
 	stopTime         = flag.Duration("stop_time", 0, "How long to wait for cleanup before process exits on SIGINT and SIGTERM")
 	cpuprofile       = flag.String("cpuprof", "", "Write cpu profile to file")
 	memprofile       = flag.String("memprof", "", "Write heap profile to file")
-	configTest       = flag.Bool("configtest", false, "Dry run to test config file")
+	configTest       = flag.Bool("configtest", false, "Dry run to test config file. Probe options are validated as well, and errors are reported for each probe")
 	dumpConfig       = flag.Bool("dumpconfig", false, "Dump processed config to stdout")
 	testInstanceName = flag.String("test_instance_name", "ig-us-central1-a-01-0000", "Instance name example to be used in tests")
+	runOnceCount     = flag.Int("run_once_count", 0, "If set, run each probe this many times and exit. Exit code is non-zero if any probe run failed")
 
 	// configTestVars provides a sane set of sysvars for config testing.
 	configTestVars = map[string]string(nil)
@@ -168,10 +170,19 @@ func main() {
 
 	if *configTest {
 		sysvars.Init(nil, configTestVars)
-		_, err := config.ParseForTest(configFileToString(*configFile), sysvars.Vars())
+		cfg, err := config.ParseForTest(configFileToString(*configFile), sysvars.Vars())
 		if err != nil {
 			glog.Exitf("Error parsing config file. Err: %v", err)
 		}
+		results := options.ValidateConfig(cfg)
+		for _, r := range results {
+			for _, fe := range r.Errors {
+				fmt.Printf("probe %s: %s: %v\n", r.Probe, fe.Field, fe.Err)
+			}
+		}
+		if len(results) != 0 {
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -182,6 +193,15 @@ func main() {
 		glog.Exitf("Error initializing cloudprober. Err: %v", err)
 	}
 
+	if *runOnceCount > 0 {
+		success := cloudprober.RunOnce(context.Background(), *runOnceCount)
+		glog.Flush()
+		if !success {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// web.Init sets up web UI for cloudprober.
 	web.Init()
 	startCtx := context.Background()
@@ -190,22 +210,46 @@ func main() {
 		*stopTime = time.Duration(cloudprober.GetConfig().GetStopTimeSec()) * time.Second
 	}
 
-	if *stopTime != 0 {
+	gracePeriod := time.Duration(cloudprober.GetConfig().GetShutdownGracePeriodSec()) * time.Second
+
+	var sigs chan os.Signal
+	var cancelF context.CancelFunc
+	if *stopTime != 0 || gracePeriod != 0 {
 		// Set up signal handling for the cancelation of the start context.
-		sigs := make(chan os.Signal, 1)
+		sigs = make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		ctx, cancelF := context.WithCancel(startCtx)
-		startCtx = ctx
+		startCtx, cancelF = context.WithCancel(startCtx)
+	}
+	cloudprober.Start(startCtx)
 
+	if sigs != nil {
 		go func() {
 			sig := <-sigs
 			glog.Warningf("Received signal \"%v\", canceling the start context and waiting for %v before closing", sig, *stopTime)
+			start := time.Now()
 			cancelF()
-			time.Sleep(*stopTime)
+			if gracePeriod != 0 {
+				if cloudprober.Shutdown(gracePeriod) {
+					glog.Infof("Drained surfacers in %v", time.Since(start))
+				}
+			}
+			time.Sleep(*stopTime - time.Since(start))
+			glog.Flush()
 			os.Exit(0)
 		}()
 	}
-	cloudprober.Start(startCtx)
+
+	// Reload probes from the config on SIGHUP.
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			glog.Info("Received SIGHUP, reloading probes from the config")
+			if err := cloudprober.ReloadConfig(getConfig()); err != nil {
+				glog.Errorf("Error reloading config, continuing with the current config. Err: %v", err)
+			}
+		}
+	}()
 
 	// Wait forever
 	select {}
@@ -44,10 +44,11 @@ var InterfaceByName = func(s string) (Addr, error) { return net.InterfaceByName(
 // ResolveIntfAddr takes the name of a network interface and IP version, and
 // returns the first IP address of the interface that matches the specified IP
 // version. If no IP version is specified (ipVer is 0), simply the first IP
-// address is returned.
+// address is returned. For IPv6 link-local addresses, zone is set to the
+// interface name, as these addresses are unusable for binding without it.
 // TODO(manugarg): This functions is currently tested through options_test. We
 // should fix that.
-func ResolveIntfAddr(intfName string, ipVer int) (net.IP, error) {
+func ResolveIntfAddr(intfName string, ipVer int) (*net.IPAddr, error) {
 	i, err := InterfaceByName(intfName)
 	if err != nil {
 		return nil, fmt.Errorf("resolveIntfAddr(%v, %d) got error getting interface: %v", intfName, ipVer, err)
@@ -75,7 +76,11 @@ func ResolveIntfAddr(intfName string, ipVer int) (net.IP, error) {
 		}
 
 		if ipVer == 0 || IPVersion(ip) == ipVer {
-			return ip, nil
+			ipAddr := &net.IPAddr{IP: ip}
+			if IPVersion(ip) == 6 && ip.IsLinkLocalUnicast() {
+				ipAddr.Zone = intfName
+			}
+			return ipAddr, nil
 		}
 	}
 	return nil, fmt.Errorf("resolveIntfAddr(%v, %d) found no apprpriate IP addresses in %v", intfName, ipVer, addrs)
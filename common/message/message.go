@@ -18,6 +18,7 @@ package message
 
 import (
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"time"
 
@@ -165,6 +166,27 @@ func (m *Message) Payload() []byte {
 	return m.m.GetPayload()
 }
 
+// messageNonce computes a message's nonce from the sender's nonce key and the
+// message's flow, sequence number and source timestamp.
+func messageNonce(key uint64, srcPort, dst string, seq, tsUsec []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(Uint64ToNetworkBytes(key))
+	h.Write([]byte(srcPort + "-" + dst))
+	h.Write(seq)
+	h.Write(tsUsec)
+	return h.Sum64()
+}
+
+// VerifyNonce verifies that the message carries the nonce computed using the
+// given key. A mismatch indicates that the message was corrupted in transit,
+// or wasn't created using CreateMessageWithNonce with the same key.
+func (m *Message) VerifyNonce(key uint64) bool {
+	if m.m.Nonce == nil {
+		return false
+	}
+	return m.m.GetNonce() == messageNonce(key, m.SrcPort(), m.Dst(), m.m.GetSeq(), m.m.GetSrc().GetTimestampUsec())
+}
+
 // NewFlowStateMap returns a new FlowStateMap variable.
 func NewFlowStateMap() *FlowStateMap {
 	return &FlowStateMap{
@@ -207,6 +229,17 @@ func (fs *FlowState) NextSeq() uint64 {
 // representation of the message and sequence number used on success.
 // TODO: add Message.CreateMessage() fn and use it in FlowState.CreateMessage.
 func (fs *FlowState) CreateMessage(ts time.Time, payload []byte, maxLen int) ([]byte, uint64, error) {
+	return fs.createMessage(ts, payload, maxLen, nil)
+}
+
+// CreateMessageWithNonce is similar to CreateMessage, but it also adds a nonce
+// to the message, derived from the given key. Nonce can be verified on the
+// echoed message using Message.VerifyNonce.
+func (fs *FlowState) CreateMessageWithNonce(ts time.Time, payload []byte, maxLen int, nonceKey uint64) ([]byte, uint64, error) {
+	return fs.createMessage(ts, payload, maxLen, &nonceKey)
+}
+
+func (fs *FlowState) createMessage(ts time.Time, payload []byte, maxLen int, nonceKey *uint64) ([]byte, uint64, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
@@ -226,6 +259,9 @@ func (fs *FlowState) CreateMessage(ts time.Time, payload []byte, maxLen int) ([]
 		},
 		Payload: payload,
 	}
+	if nonceKey != nil {
+		msg.Nonce = proto.Uint64(messageNonce(*nonceKey, fs.srcPort, fs.dst, msg.GetSeq(), msg.GetSrc().GetTimestampUsec()))
+	}
 	bytes, err := proto.Marshal(msg)
 	if err != nil {
 		return nil, 0, err
@@ -97,6 +97,53 @@ func TestMessageEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestMessageNonce(t *testing.T) {
+	fs := NewFlowStateMap().FlowState("src", "1234", "dst")
+	key := uint64(0xabcd)
+
+	msgBytes, _, err := fs.CreateMessageWithNonce(time.Now(), []byte("payload"), 1024, key)
+	if err != nil {
+		t.Fatalf("Error creating message: %v", err)
+	}
+
+	// Returns a copy of the message bytes with the given field modified.
+	modified := func(f func(*msgpb.Msg)) []byte {
+		m := &msgpb.Msg{}
+		if err := proto.Unmarshal(msgBytes, m); err != nil {
+			t.Fatalf("Error unmarshaling message: %v", err)
+		}
+		f(m)
+		b, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("Error marshaling message: %v", err)
+		}
+		return b
+	}
+
+	for _, test := range []struct {
+		desc     string
+		msgBytes []byte
+		key      uint64
+		want     bool
+	}{
+		{desc: "valid", msgBytes: msgBytes, key: key, want: true},
+		{desc: "wrong_key", msgBytes: msgBytes, key: key + 1},
+		{desc: "seq_modified", msgBytes: modified(func(m *msgpb.Msg) { m.Seq = Uint64ToNetworkBytes(5) }), key: key},
+		{desc: "port_modified", msgBytes: modified(func(m *msgpb.Msg) { m.Src.Port = proto.String("1235") }), key: key},
+		{desc: "no_nonce", msgBytes: modified(func(m *msgpb.Msg) { m.Nonce = nil }), key: key},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			msg, err := NewMessage(test.msgBytes)
+			if err != nil {
+				t.Fatalf("Error parsing message: %v", err)
+			}
+			if got := msg.VerifyNonce(test.key); got != test.want {
+				t.Errorf("VerifyNonce()=%v, want=%v", got, test.want)
+			}
+		})
+	}
+}
+
 // TestInvalidMessages tests encoding/decoding error paths.
 func TestInvalidMessage(t *testing.T) {
 	fss := NewFlowStateMap()
@@ -0,0 +1,72 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cloudprober/cloudprober/common/file"
+	configpb "github.com/cloudprober/cloudprober/common/oauth/proto"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+var authStyles = map[configpb.ClientCredentials_AuthStyle]oauth2.AuthStyle{
+	configpb.ClientCredentials_AUTO:      oauth2.AuthStyleAutoDetect,
+	configpb.ClientCredentials_IN_PARAMS: oauth2.AuthStyleInParams,
+	configpb.ClientCredentials_IN_HEADER: oauth2.AuthStyleInHeader,
+}
+
+// newClientCredentialsTokenSource returns a token source for the client
+// credentials grant. Returned token source caches the token till it expires.
+func newClientCredentialsTokenSource(c *configpb.ClientCredentials) (oauth2.TokenSource, error) {
+	if c.GetTokenUrl() == "" {
+		return nil, errors.New("oauth: token_url is required for client_credentials")
+	}
+
+	secret := c.GetClientSecret()
+	if c.GetClientSecretFile() != "" {
+		if secret != "" {
+			return nil, errors.New("oauth: only one of client_secret and client_secret_file can be set")
+		}
+		b, err := file.ReadFile(c.GetClientSecretFile())
+		if err != nil {
+			return nil, fmt.Errorf("error reading client_secret_file (%s): %v", c.GetClientSecretFile(), err)
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+
+	var params url.Values
+	if len(c.GetEndpointParams()) > 0 {
+		params = make(url.Values)
+		for k, v := range c.GetEndpointParams() {
+			params.Set(k, v)
+		}
+	}
+
+	cfg := &clientcredentials.Config{
+		ClientID:       c.GetClientId(),
+		ClientSecret:   secret,
+		TokenURL:       c.GetTokenUrl(),
+		Scopes:         c.GetScope(),
+		EndpointParams: params,
+		AuthStyle:      authStyles[c.GetAuthStyle()],
+	}
+	return cfg.TokenSource(context.Background()), nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	configpb "github.com/cloudprober/cloudprober/common/oauth/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+// testTokenServer returns a token endpoint that issues tokens to the client
+// "test-client" with secret "test-secret", and the number of tokens issued.
+func testTokenServer(t *testing.T, expiresIn int) (*httptest.Server, func() int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var issued int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+		}
+		if r.FormValue("grant_type") != "client_credentials" || id != "test-client" || secret != "test-secret" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		issued++
+		n := issued
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d-%s", "token_type": "bearer", "expires_in": %d}`, n, r.FormValue("audience"), expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return issued
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	secretFile := createTempFile(t, []byte("test-secret\n"))
+
+	for _, test := range []struct {
+		desc      string
+		c         *configpb.ClientCredentials
+		expiresIn int
+		wantToken string
+		wantIssue int
+		wantErr   bool
+	}{
+		{
+			desc: "secret",
+			c: &configpb.ClientCredentials{
+				ClientSecret: proto.String("test-secret"),
+			},
+			expiresIn: 3600,
+			wantToken: "token-1-",
+			wantIssue: 1,
+		},
+		{
+			desc: "secret_file_in_params_with_audience",
+			c: &configpb.ClientCredentials{
+				ClientSecretFile: proto.String(secretFile),
+				AuthStyle:        configpb.ClientCredentials_IN_PARAMS.Enum(),
+				EndpointParams:   map[string]string{"audience": "test-aud"},
+			},
+			expiresIn: 3600,
+			wantToken: "token-1-test-aud",
+			wantIssue: 1,
+		},
+		{
+			desc: "expiring_token_refreshed",
+			c: &configpb.ClientCredentials{
+				ClientSecret: proto.String("test-secret"),
+				AuthStyle:    configpb.ClientCredentials_IN_HEADER.Enum(),
+			},
+			// Tokens expiring within 10s are considered expired.
+			expiresIn: 5,
+			wantToken: "token-3-",
+			wantIssue: 3,
+		},
+		{
+			desc: "invalid_secret",
+			c: &configpb.ClientCredentials{
+				ClientSecret: proto.String("wrong-secret"),
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ts, issued := testTokenServer(t, test.expiresIn)
+			test.c.TokenUrl = proto.String(ts.URL)
+			test.c.ClientId = proto.String("test-client")
+
+			tokSrc, err := TokenSourceFromConfig(&configpb.Config{
+				Type: &configpb.Config_ClientCredentials{ClientCredentials: test.c},
+			}, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var tokStr string
+			for i := 0; i < 3; i++ {
+				tok, err := tokSrc.Token()
+				if (err != nil) != test.wantErr {
+					t.Fatalf("Got error: %v, want error: %v", err, test.wantErr)
+				}
+				if err == nil {
+					tokStr = tok.AccessToken
+				}
+			}
+			if test.wantErr {
+				return
+			}
+			if tokStr != test.wantToken || issued() != test.wantIssue {
+				t.Errorf("Got token=%s, tokens issued=%d, want token=%s, tokens issued=%d", tokStr, issued(), test.wantToken, test.wantIssue)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsConfigErrors(t *testing.T) {
+	for _, c := range []*configpb.ClientCredentials{
+		{ClientId: proto.String("test-client")},
+		{
+			TokenUrl:         proto.String("http://localhost/token"),
+			ClientSecret:     proto.String("test-secret"),
+			ClientSecretFile: proto.String("/secret"),
+		},
+		{
+			TokenUrl:         proto.String("http://localhost/token"),
+			ClientSecretFile: proto.String("/non-existent-file"),
+		},
+	} {
+		if _, err := TokenSourceFromConfig(&configpb.Config{
+			Type: &configpb.Config_ClientCredentials{ClientCredentials: c},
+		}, nil); err == nil {
+			t.Errorf("Config: %v, expected error, got none", c)
+		}
+	}
+}
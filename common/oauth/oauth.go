@@ -35,6 +35,9 @@ func TokenSourceFromConfig(c *configpb.Config, l *logger.Logger) (oauth2.TokenSo
 	case *configpb.Config_BearerToken:
 		return newBearerTokenSource(c.GetBearerToken(), l)
 
+	case *configpb.Config_ClientCredentials:
+		return newClientCredentialsTokenSource(c.GetClientCredentials())
+
 	case *configpb.Config_GoogleCredentials:
 		f := c.GetGoogleCredentials().GetJsonFile()
 
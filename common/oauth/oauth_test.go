@@ -17,6 +17,8 @@ package oauth
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -100,3 +102,45 @@ func TestGoogleCredentials(t *testing.T) {
 		t.Errorf("Config: %v, Unexpected error: %v", c, err)
 	}
 }
+
+func TestGoogleCredentialsWorkloadIdentityFederation(t *testing.T) {
+	// Fake STS endpoint, exchanging the subject token for an access token.
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("subject_token") != "test-subject-token" {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "federated-token", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	defer sts.Close()
+
+	subjectTokenF := createTempFile(t, []byte("test-subject-token"))
+	jsonF := createTempFile(t, []byte(fmt.Sprintf(`{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/provider",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "%s",
+  "credential_source": {"file": "%s"}
+}`, sts.URL, subjectTokenF)))
+
+	ts, err := TokenSourceFromConfig(&configpb.Config{
+		Type: &configpb.Config_GoogleCredentials{
+			GoogleCredentials: &configpb.GoogleCredentials{
+				JsonFile: proto.String(jsonF),
+				Scope:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Error getting token: %v", err)
+	}
+	if tok.AccessToken != "federated-token" {
+		t.Errorf("Got token=%s, want=federated-token", tok.AccessToken)
+	}
+}
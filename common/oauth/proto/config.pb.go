@@ -20,6 +20,69 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ClientCredentials_AuthStyle int32
+
+const (
+	// Try the HTTP basic auth first, and fall back to the parameters in
+	// the request body.
+	ClientCredentials_AUTO ClientCredentials_AuthStyle = 0
+	// Client ID and secret in the request body.
+	ClientCredentials_IN_PARAMS ClientCredentials_AuthStyle = 1
+	// Client ID and secret through the HTTP basic auth.
+	ClientCredentials_IN_HEADER ClientCredentials_AuthStyle = 2
+)
+
+// Enum value maps for ClientCredentials_AuthStyle.
+var (
+	ClientCredentials_AuthStyle_name = map[int32]string{
+		0: "AUTO",
+		1: "IN_PARAMS",
+		2: "IN_HEADER",
+	}
+	ClientCredentials_AuthStyle_value = map[string]int32{
+		"AUTO":      0,
+		"IN_PARAMS": 1,
+		"IN_HEADER": 2,
+	}
+)
+
+func (x ClientCredentials_AuthStyle) Enum() *ClientCredentials_AuthStyle {
+	p := new(ClientCredentials_AuthStyle)
+	*p = x
+	return p
+}
+
+func (x ClientCredentials_AuthStyle) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClientCredentials_AuthStyle) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ClientCredentials_AuthStyle) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_enumTypes[0]
+}
+
+func (x ClientCredentials_AuthStyle) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ClientCredentials_AuthStyle) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ClientCredentials_AuthStyle(num)
+	return nil
+}
+
+// Deprecated: Use ClientCredentials_AuthStyle.Descriptor instead.
+func (ClientCredentials_AuthStyle) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawDescGZIP(), []int{3, 0}
+}
+
 type Config struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -28,6 +91,7 @@ type Config struct {
 	// Types that are assignable to Type:
 	//	*Config_BearerToken
 	//	*Config_GoogleCredentials
+	//	*Config_ClientCredentials
 	Type isConfig_Type `protobuf_oneof:"type"`
 }
 
@@ -84,6 +148,13 @@ func (x *Config) GetGoogleCredentials() *GoogleCredentials {
 	return nil
 }
 
+func (x *Config) GetClientCredentials() *ClientCredentials {
+	if x, ok := x.GetType().(*Config_ClientCredentials); ok {
+		return x.ClientCredentials
+	}
+	return nil
+}
+
 type isConfig_Type interface {
 	isConfig_Type()
 }
@@ -96,10 +167,16 @@ type Config_GoogleCredentials struct {
 	GoogleCredentials *GoogleCredentials `protobuf:"bytes,2,opt,name=google_credentials,json=googleCredentials,oneof"`
 }
 
+type Config_ClientCredentials struct {
+	ClientCredentials *ClientCredentials `protobuf:"bytes,3,opt,name=client_credentials,json=clientCredentials,oneof"`
+}
+
 func (*Config_BearerToken) isConfig_Type() {}
 
 func (*Config_GoogleCredentials) isConfig_Type() {}
 
+func (*Config_ClientCredentials) isConfig_Type() {}
+
 // Bearer token is added to the HTTP request through an HTTP header:
 // "Authorization: Bearer <access_token>"
 type BearerToken struct {
@@ -217,7 +294,13 @@ func (*BearerToken_Cmd) isBearerToken_Source() {}
 func (*BearerToken_GceServiceAccount) isBearerToken_Source() {}
 
 // Google credentials in JSON format. We simply use oauth2/google package to
-// use these credentials.
+// use these credentials. Besides service account keys, json_file can be an
+// external account credential configuration, for GCP workload identity
+// federation, e.g. as generated by:
+//
+//	gcloud iam workload-identity-pools create-cred-config ...
+//
+// Tokens are refreshed automatically before they expire.
 type GoogleCredentials struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -292,6 +375,113 @@ func (x *GoogleCredentials) GetAudience() string {
 	return ""
 }
 
+// OAuth2 client credentials grant (RFC 6749, section 4.4), against an
+// arbitrary token URL. Tokens are cached, and refreshed automatically before
+// they expire.
+type ClientCredentials struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Token endpoint URL, e.g. https://auth.example.com/oauth2/token.
+	TokenUrl *string `protobuf:"bytes,1,opt,name=token_url,json=tokenUrl" json:"token_url,omitempty"`
+	ClientId *string `protobuf:"bytes,2,opt,name=client_id,json=clientId" json:"client_id,omitempty"`
+	// Client secret, directly or from a file. Only one of them can be set.
+	ClientSecret     *string  `protobuf:"bytes,3,opt,name=client_secret,json=clientSecret" json:"client_secret,omitempty"`
+	ClientSecretFile *string  `protobuf:"bytes,4,opt,name=client_secret_file,json=clientSecretFile" json:"client_secret_file,omitempty"`
+	Scope            []string `protobuf:"bytes,5,rep,name=scope" json:"scope,omitempty"`
+	// Additional parameters for the token requests, e.g. audience.
+	EndpointParams map[string]string `protobuf:"bytes,6,rep,name=endpoint_params,json=endpointParams" json:"endpoint_params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// How to send the client credentials to the token endpoint.
+	AuthStyle *ClientCredentials_AuthStyle `protobuf:"varint,7,opt,name=auth_style,json=authStyle,enum=cloudprober.oauth.ClientCredentials_AuthStyle,def=0" json:"auth_style,omitempty"`
+}
+
+// Default values for ClientCredentials fields.
+const (
+	Default_ClientCredentials_AuthStyle = ClientCredentials_AUTO
+)
+
+func (x *ClientCredentials) Reset() {
+	*x = ClientCredentials{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientCredentials) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientCredentials) ProtoMessage() {}
+
+func (x *ClientCredentials) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientCredentials.ProtoReflect.Descriptor instead.
+func (*ClientCredentials) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ClientCredentials) GetTokenUrl() string {
+	if x != nil && x.TokenUrl != nil {
+		return *x.TokenUrl
+	}
+	return ""
+}
+
+func (x *ClientCredentials) GetClientId() string {
+	if x != nil && x.ClientId != nil {
+		return *x.ClientId
+	}
+	return ""
+}
+
+func (x *ClientCredentials) GetClientSecret() string {
+	if x != nil && x.ClientSecret != nil {
+		return *x.ClientSecret
+	}
+	return ""
+}
+
+func (x *ClientCredentials) GetClientSecretFile() string {
+	if x != nil && x.ClientSecretFile != nil {
+		return *x.ClientSecretFile
+	}
+	return ""
+}
+
+func (x *ClientCredentials) GetScope() []string {
+	if x != nil {
+		return x.Scope
+	}
+	return nil
+}
+
+func (x *ClientCredentials) GetEndpointParams() map[string]string {
+	if x != nil {
+		return x.EndpointParams
+	}
+	return nil
+}
+
+func (x *ClientCredentials) GetAuthStyle() ClientCredentials_AuthStyle {
+	if x != nil && x.AuthStyle != nil {
+		return *x.AuthStyle
+	}
+	return Default_ClientCredentials_AuthStyle
+}
+
 var File_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawDesc = []byte{
@@ -300,7 +490,7 @@ var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawD
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x6f, 0x61, 0x75, 0x74,
 	0x68, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x11, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x22, 0xac, 0x01, 0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66,
+	0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x22, 0x83, 0x02, 0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66,
 	0x69, 0x67, 0x12, 0x43, 0x0a, 0x0c, 0x62, 0x65, 0x61, 0x72, 0x65, 0x72, 0x5f, 0x74, 0x6f, 0x6b,
 	0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
 	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x42, 0x65, 0x61,
@@ -310,28 +500,64 @@ var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawD
 	0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
 	0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x47, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x43, 0x72,
 	0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x48, 0x00, 0x52, 0x11, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x42, 0x06,
-	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0xa9, 0x01, 0x0a, 0x0b, 0x42, 0x65, 0x61, 0x72, 0x65,
-	0x72, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x14, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x03,
-	0x63, 0x6d, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x03, 0x63, 0x6d, 0x64,
-	0x12, 0x30, 0x0a, 0x13, 0x67, 0x63, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f,
-	0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
-	0x11, 0x67, 0x63, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x41, 0x63, 0x63, 0x6f, 0x75,
-	0x6e, 0x74, 0x12, 0x34, 0x0a, 0x14, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x6e,
-	0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x5a, 0x20, 0x01, 0x28, 0x02,
-	0x3a, 0x02, 0x36, 0x30, 0x52, 0x12, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x42, 0x08, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72,
-	0x63, 0x65, 0x22, 0x91, 0x01, 0x0a, 0x11, 0x47, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x43, 0x72, 0x65,
-	0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6a, 0x73, 0x6f, 0x6e,
-	0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6a, 0x73, 0x6f,
-	0x6e, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x02,
-	0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x2d, 0x0a, 0x13, 0x6a,
-	0x77, 0x74, 0x5f, 0x61, 0x73, 0x5f, 0x61, 0x63, 0x63, 0x65, 0x73, 0x73, 0x5f, 0x74, 0x6f, 0x6b,
-	0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x6a, 0x77, 0x74, 0x41, 0x73, 0x41,
-	0x63, 0x63, 0x65, 0x73, 0x73, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x75,
-	0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x75,
-	0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x42, 0x37, 0x5a, 0x35, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x67, 0x6c, 0x65, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x55,
+	0x0a, 0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x61, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x43,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73,
+	0x48, 0x00, 0x52, 0x11, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x61, 0x6c, 0x73, 0x42, 0x06, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0xa9, 0x01,
+	0x0a, 0x0b, 0x42, 0x65, 0x61, 0x72, 0x65, 0x72, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x14, 0x0a,
+	0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x04, 0x66,
+	0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x03, 0x63, 0x6d, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x48, 0x00, 0x52, 0x03, 0x63, 0x6d, 0x64, 0x12, 0x30, 0x0a, 0x13, 0x67, 0x63, 0x65, 0x5f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x11, 0x67, 0x63, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x14, 0x72, 0x65, 0x66,
+	0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65,
+	0x63, 0x18, 0x5a, 0x20, 0x01, 0x28, 0x02, 0x3a, 0x02, 0x36, 0x30, 0x52, 0x12, 0x72, 0x65, 0x66,
+	0x72, 0x65, 0x73, 0x68, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x42,
+	0x08, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x22, 0x91, 0x01, 0x0a, 0x11, 0x47, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12,
+	0x1b, 0x0a, 0x09, 0x6a, 0x73, 0x6f, 0x6e, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6a, 0x73, 0x6f, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63, 0x6f,
+	0x70, 0x65, 0x12, 0x2d, 0x0a, 0x13, 0x6a, 0x77, 0x74, 0x5f, 0x61, 0x73, 0x5f, 0x61, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x10, 0x6a, 0x77, 0x74, 0x41, 0x73, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x54, 0x6f, 0x6b, 0x65,
+	0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x22, 0xe6, 0x03,
+	0x0a, 0x11, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x61, 0x6c, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x55, 0x72, 0x6c,
+	0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x23, 0x0a,
+	0x0d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x12, 0x2c, 0x0a, 0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x61, 0x0a, 0x0f, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x38, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6f, 0x61,
+	0x75, 0x74, 0x68, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x61, 0x6c, 0x73, 0x2e, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0e, 0x65, 0x6e, 0x64, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x53, 0x0a, 0x0a, 0x61, 0x75, 0x74,
+	0x68, 0x5f, 0x73, 0x74, 0x79, 0x6c, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2e, 0x2e,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74,
+	0x68, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x61, 0x6c, 0x73, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x53, 0x74, 0x79, 0x6c, 0x65, 0x3a, 0x04, 0x41,
+	0x55, 0x54, 0x4f, 0x52, 0x09, 0x61, 0x75, 0x74, 0x68, 0x53, 0x74, 0x79, 0x6c, 0x65, 0x1a, 0x41,
+	0x0a, 0x13, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0x33, 0x0a, 0x09, 0x41, 0x75, 0x74, 0x68, 0x53, 0x74, 0x79, 0x6c, 0x65, 0x12, 0x08,
+	0x0a, 0x04, 0x41, 0x55, 0x54, 0x4f, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x49, 0x4e, 0x5f, 0x50,
+	0x41, 0x52, 0x41, 0x4d, 0x53, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x49, 0x4e, 0x5f, 0x48, 0x45,
+	0x41, 0x44, 0x45, 0x52, 0x10, 0x02, 0x42, 0x37, 0x5a, 0x35, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
 	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
 	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d,
 	0x6d, 0x6f, 0x6e, 0x2f, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
@@ -349,20 +575,27 @@ func file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_raw
 	return file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_goTypes = []interface{}{
-	(*Config)(nil),            // 0: cloudprober.oauth.Config
-	(*BearerToken)(nil),       // 1: cloudprober.oauth.BearerToken
-	(*GoogleCredentials)(nil), // 2: cloudprober.oauth.GoogleCredentials
+	(ClientCredentials_AuthStyle)(0), // 0: cloudprober.oauth.ClientCredentials.AuthStyle
+	(*Config)(nil),                   // 1: cloudprober.oauth.Config
+	(*BearerToken)(nil),              // 2: cloudprober.oauth.BearerToken
+	(*GoogleCredentials)(nil),        // 3: cloudprober.oauth.GoogleCredentials
+	(*ClientCredentials)(nil),        // 4: cloudprober.oauth.ClientCredentials
+	nil,                              // 5: cloudprober.oauth.ClientCredentials.EndpointParamsEntry
 }
 var file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_depIdxs = []int32{
-	1, // 0: cloudprober.oauth.Config.bearer_token:type_name -> cloudprober.oauth.BearerToken
-	2, // 1: cloudprober.oauth.Config.google_credentials:type_name -> cloudprober.oauth.GoogleCredentials
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	2, // 0: cloudprober.oauth.Config.bearer_token:type_name -> cloudprober.oauth.BearerToken
+	3, // 1: cloudprober.oauth.Config.google_credentials:type_name -> cloudprober.oauth.GoogleCredentials
+	4, // 2: cloudprober.oauth.Config.client_credentials:type_name -> cloudprober.oauth.ClientCredentials
+	5, // 3: cloudprober.oauth.ClientCredentials.endpoint_params:type_name -> cloudprober.oauth.ClientCredentials.EndpointParamsEntry
+	0, // 4: cloudprober.oauth.ClientCredentials.auth_style:type_name -> cloudprober.oauth.ClientCredentials.AuthStyle
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_init() }
@@ -407,10 +640,23 @@ func file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_ini
 				return nil
 			}
 		}
+		file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientCredentials); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes[0].OneofWrappers = []interface{}{
 		(*Config_BearerToken)(nil),
 		(*Config_GoogleCredentials)(nil),
+		(*Config_ClientCredentials)(nil),
 	}
 	file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes[1].OneofWrappers = []interface{}{
 		(*BearerToken_File)(nil),
@@ -422,13 +668,14 @@ func file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_ini
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_goTypes,
 		DependencyIndexes: file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_enumTypes,
 		MessageInfos:      file_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto_msgTypes,
 	}.Build()
 	File_github_com_cloudprober_cloudprober_common_oauth_proto_config_proto = out.File
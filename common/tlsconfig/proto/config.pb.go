@@ -35,8 +35,20 @@ type TLSConfig struct {
 	DisableCertValidation *bool `protobuf:"varint,4,opt,name=disable_cert_validation,json=disableCertValidation" json:"disable_cert_validation,omitempty"`
 	// ServerName override
 	ServerName *string `protobuf:"bytes,5,opt,name=server_name,json=serverName" json:"server_name,omitempty"`
+	// How often to check the certificate and key files for changes, when they
+	// are used as the client certificate (e.g. for mutual TLS in HTTP and gRPC
+	// probes). Files are checked at the time of a new TLS handshake, at most
+	// once per interval, and the key pair is reloaded if either of them has
+	// changed. If reloading fails, previous certificate continues to be used.
+	// Set it to a negative value to disable reloading.
+	CertReloadIntervalSec *int32 `protobuf:"varint,6,opt,name=cert_reload_interval_sec,json=certReloadIntervalSec,def=60" json:"cert_reload_interval_sec,omitempty"`
 }
 
+// Default values for TLSConfig fields.
+const (
+	Default_TLSConfig_CertReloadIntervalSec = int32(60)
+)
+
 func (x *TLSConfig) Reset() {
 	*x = TLSConfig{}
 	if protoimpl.UnsafeEnabled {
@@ -104,6 +116,13 @@ func (x *TLSConfig) GetServerName() string {
 	return ""
 }
 
+func (x *TLSConfig) GetCertReloadIntervalSec() int32 {
+	if x != nil && x.CertReloadIntervalSec != nil {
+		return *x.CertReloadIntervalSec
+	}
+	return Default_TLSConfig_CertReloadIntervalSec
+}
+
 var File_github_com_cloudprober_cloudprober_common_tlsconfig_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_common_tlsconfig_proto_config_proto_rawDesc = []byte{
@@ -113,7 +132,7 @@ var file_github_com_cloudprober_cloudprober_common_tlsconfig_proto_config_proto_
 	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
 	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x15, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
 	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22,
-	0xcc, 0x01, 0x0a, 0x09, 0x54, 0x4c, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x20, 0x0a,
+	0x89, 0x02, 0x0a, 0x09, 0x54, 0x4c, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x20, 0x0a,
 	0x0c, 0x63, 0x61, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x61, 0x43, 0x65, 0x72, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x12,
 	0x22, 0x0a, 0x0d, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65,
@@ -125,11 +144,15 @@ var file_github_com_cloudprober_cloudprober_common_tlsconfig_proto_config_proto_
 	0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x43,
 	0x65, 0x72, 0x74, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a,
 	0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x42, 0x3b,
-	0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
-	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63,
-	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x3b,
+	0x0a, 0x18, 0x63, 0x65, 0x72, 0x74, 0x5f, 0x72, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05,
+	0x3a, 0x02, 0x36, 0x30, 0x52, 0x15, 0x63, 0x65, 0x72, 0x74, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64,
+	0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x42, 0x3b, 0x5a, 0x39, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -0,0 +1,163 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/file"
+	"github.com/cloudprober/cloudprober/logger"
+)
+
+var (
+	loggerOnce sync.Once
+	pkgLogger  *logger.Logger
+)
+
+func getLogger() *logger.Logger {
+	loggerOnce.Do(func() {
+		l, err := logger.NewCloudproberLog("tlsconfig")
+		if err != nil {
+			l = &logger.Logger{}
+		}
+		pkgLogger = l
+	})
+	return pkgLogger
+}
+
+// certLoader loads a certificate key pair and reloads it if the underlying
+// files change. Files are checked for changes lazily, when the certificate is
+// requested for a TLS handshake.
+type certLoader struct {
+	certFile, keyFile string
+	checkInterval     time.Duration
+	l                 *logger.Logger
+
+	mu            sync.Mutex
+	cert          *tls.Certificate
+	certModTime   time.Time
+	keyModTime    time.Time
+	lastCheckTime time.Time
+
+	now func() time.Time // Overridden in tests.
+}
+
+func loadKeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	certPEMBlock, err := file.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("common/tlsconfig: error reading TLS cert file (%s): %v", certFile, err)
+	}
+	keyPEMBlock, err := file.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("common/tlsconfig: error reading TLS key file (%s): %v", keyFile, err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEMBlock, keyPEMBlock)
+	if err != nil {
+		return nil, fmt.Errorf("common/tlsconfig: error initializing cert from cert key pair: %v", err)
+	}
+	return &cert, nil
+}
+
+// modTimes returns the cert and key files' modification times.
+func (cl *certLoader) modTimes() (time.Time, time.Time, error) {
+	certModTime, err := file.ModTime(cl.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyModTime, err := file.ModTime(cl.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certModTime, keyModTime, nil
+}
+
+// newCertLoader loads the key pair and returns a certLoader for it. If the
+// files' modification times are not available (e.g. for the files on GCS),
+// certificate is not reloaded. Negative checkInterval disables reloading.
+func newCertLoader(certFile, keyFile string, checkInterval time.Duration, l *logger.Logger) (*certLoader, error) {
+	cl := &certLoader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		checkInterval: checkInterval,
+		l:             l,
+		now:           time.Now,
+	}
+
+	if checkInterval >= 0 {
+		var err error
+		cl.certModTime, cl.keyModTime, err = cl.modTimes()
+		if err != nil {
+			l.Warningf("common/tlsconfig: not watching cert and key files (%s, %s) for changes: %v", certFile, keyFile, err)
+			cl.checkInterval = -1
+		}
+	}
+
+	cert, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cl.cert = cert
+	cl.lastCheckTime = cl.now()
+	return cl, nil
+}
+
+// maybeReload reloads the key pair if it's time to check the files and either
+// of them has changed. On error, it keeps the previous certificate.
+func (cl *certLoader) maybeReload() {
+	if cl.checkInterval < 0 {
+		return
+	}
+	now := cl.now()
+	if now.Sub(cl.lastCheckTime) < cl.checkInterval {
+		return
+	}
+	cl.lastCheckTime = now
+
+	certModTime, keyModTime, err := cl.modTimes()
+	if err != nil {
+		cl.l.Errorf("common/tlsconfig: error checking cert and key files (%s, %s) for changes, using previous cert: %v", cl.certFile, cl.keyFile, err)
+		return
+	}
+	if certModTime.Equal(cl.certModTime) && keyModTime.Equal(cl.keyModTime) {
+		return
+	}
+
+	cert, err := loadKeyPair(cl.certFile, cl.keyFile)
+	if err != nil {
+		cl.l.Errorf("%v, using previous cert", err)
+		return
+	}
+	cl.l.Infof("common/tlsconfig: reloaded cert from %s and %s", cl.certFile, cl.keyFile)
+	cl.cert, cl.certModTime, cl.keyModTime = cert, certModTime, keyModTime
+}
+
+// getCertificate returns the current certificate, reloading it if required.
+func (cl *certLoader) getCertificate() *tls.Certificate {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.maybeReload()
+	return cl.cert
+}
+
+// getClientCertificate implements the tls.Config.GetClientCertificate
+// callback.
+func (cl *certLoader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return cl.getCertificate(), nil
+}
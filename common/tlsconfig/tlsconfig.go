@@ -19,6 +19,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"time"
 
 	"github.com/cloudprober/cloudprober/common/file"
 	configpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
@@ -48,20 +49,18 @@ func UpdateTLSConfig(tlsConfig *tls.Config, c *configpb.TLSConfig, addClientCACe
 	}
 
 	if c.GetTlsCertFile() != "" {
-		certPEMBlock, err := file.ReadFile(c.GetTlsCertFile())
+		reloadInterval := time.Duration(c.GetCertReloadIntervalSec()) * time.Second
+		cl, err := newCertLoader(c.GetTlsCertFile(), c.GetTlsKeyFile(), reloadInterval, getLogger())
 		if err != nil {
-			return fmt.Errorf("common/tlsconfig: error reading TLS cert file (%s): %v", c.GetTlsCertFile(), err)
-		}
-		keyPEMBlock, err := file.ReadFile(c.GetTlsKeyFile())
-		if err != nil {
-			return fmt.Errorf("common/tlsconfig: error reading TLS key file (%s): %v", c.GetTlsKeyFile(), err)
+			return err
 		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, *cl.cert)
 
-		cert, err := tls.X509KeyPair(certPEMBlock, keyPEMBlock)
-		if err != nil {
-			return fmt.Errorf("common/tlsconfig: error initializing cert from cert key pair: %v", err)
+		// Clients pick the certificate through GetClientCertificate, which
+		// lets us reload it when cert files change, e.g. on rotation.
+		if !addClientCACerts && c.GetCertReloadIntervalSec() >= 0 {
+			tlsConfig.GetClientCertificate = cl.getClientCertificate
 		}
-		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
 	}
 
 	if c.GetServerName() != "" {
@@ -70,3 +69,19 @@ func UpdateTLSConfig(tlsConfig *tls.Config, c *configpb.TLSConfig, addClientCACe
 
 	return nil
 }
+
+var versionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// VersionName returns the name of the TLS version, e.g. "TLS 1.3", for the
+// metric labels. Unknown versions are returned in hex.
+func VersionName(version uint16) string {
+	if name, ok := versionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
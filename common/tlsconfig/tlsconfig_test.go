@@ -0,0 +1,202 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	configpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+)
+
+// writeKeyPair generates a self-signed certificate with the given common name
+// and writes it, along with its key, to the given files. Files' modification
+// time is set to mtime.
+func writeKeyPair(t *testing.T, cn, certFile, keyFile string, mtime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error marshaling key: %v", err)
+	}
+
+	for f, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: certDER},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := ioutil.WriteFile(f, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("Error writing file %s: %v", f, err)
+		}
+		if err := os.Chtimes(f, mtime, mtime); err != nil {
+			t.Fatalf("Error setting mod time for %s: %v", f, err)
+		}
+	}
+}
+
+func TestClientCertReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	mtime := time.Now().Add(-time.Hour)
+	writeKeyPair(t, "cert1", certFile, keyFile, mtime)
+
+	// Test server responds with the client certificate's common name.
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	tlsConfig := &tls.Config{}
+	err = UpdateTLSConfig(tlsConfig, &configpb.TLSConfig{
+		TlsCertFile:           proto.String(certFile),
+		TlsKeyFile:            proto.String(keyFile),
+		DisableCertValidation: proto.Bool(true),
+		CertReloadIntervalSec: proto.Int32(0),
+	}, false)
+	if err != nil {
+		t.Fatalf("UpdateTLSConfig: %v", err)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+	}
+
+	verifyCert := func(want string) {
+		t.Helper()
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		if string(b) != want {
+			t.Errorf("Server got client cert: %s, want: %s", string(b), want)
+		}
+	}
+
+	verifyCert("cert1")
+
+	// Swap the certificate, new cert should be presented on the next
+	// connection.
+	mtime = mtime.Add(time.Minute)
+	writeKeyPair(t, "cert2", certFile, keyFile, mtime)
+	verifyCert("cert2")
+
+	// Corrupt the key file, previous cert should continue to be used.
+	mtime = mtime.Add(time.Minute)
+	if err := ioutil.WriteFile(keyFile, []byte("bad key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(keyFile, mtime, mtime)
+	verifyCert("cert2")
+
+	mtime = mtime.Add(time.Minute)
+	writeKeyPair(t, "cert3", certFile, keyFile, mtime)
+	verifyCert("cert3")
+}
+
+func TestCertReloadInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	mtime := time.Now().Add(-time.Hour)
+	writeKeyPair(t, "cert1", certFile, keyFile, mtime)
+
+	now := time.Now()
+	for _, test := range []struct {
+		desc     string
+		interval time.Duration
+		advance  time.Duration
+		wantCN   string
+	}{
+		{desc: "within_interval", interval: time.Minute, advance: 30 * time.Second, wantCN: "cert1"},
+		{desc: "after_interval", interval: time.Minute, advance: 2 * time.Minute, wantCN: "cert2"},
+		{desc: "reload_disabled", interval: -1, advance: 2 * time.Minute, wantCN: "cert1"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			writeKeyPair(t, "cert1", certFile, keyFile, mtime)
+
+			cl, err := newCertLoader(certFile, keyFile, test.interval, nil)
+			if err != nil {
+				t.Fatalf("newCertLoader: %v", err)
+			}
+			cl.lastCheckTime = now
+			cl.now = func() time.Time { return now.Add(test.advance) }
+
+			writeKeyPair(t, "cert2", certFile, keyFile, mtime.Add(time.Minute))
+
+			x509Cert, err := x509.ParseCertificate(cl.getCertificate().Certificate[0])
+			if err != nil {
+				t.Fatalf("Error parsing certificate: %v", err)
+			}
+			if x509Cert.Subject.CommonName != test.wantCN {
+				t.Errorf("Got cert: %s, want: %s", x509Cert.Subject.CommonName, test.wantCN)
+			}
+		})
+	}
+}
+
+func TestVersionName(t *testing.T) {
+	for version, want := range map[uint16]string{
+		tls.VersionTLS12: "TLS 1.2",
+		tls.VersionTLS13: "TLS 1.3",
+		0x0300:           "0x0300",
+	} {
+		if got := VersionName(version); got != want {
+			t.Errorf("VersionName(0x%04x)=%s, want=%s", version, got, want)
+		}
+	}
+}
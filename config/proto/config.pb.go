@@ -26,6 +26,65 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Cloud metadata to derive the label's value from. Cloud metadata is
+// collected at startup from the GCE or EC2 metadata server (see the
+// --cloud_metadata flag).
+type InstanceLabel_CloudMetadata int32
+
+const (
+	InstanceLabel_ZONE   InstanceLabel_CloudMetadata = 0
+	InstanceLabel_REGION InstanceLabel_CloudMetadata = 1
+)
+
+// Enum value maps for InstanceLabel_CloudMetadata.
+var (
+	InstanceLabel_CloudMetadata_name = map[int32]string{
+		0: "ZONE",
+		1: "REGION",
+	}
+	InstanceLabel_CloudMetadata_value = map[string]int32{
+		"ZONE":   0,
+		"REGION": 1,
+	}
+)
+
+func (x InstanceLabel_CloudMetadata) Enum() *InstanceLabel_CloudMetadata {
+	p := new(InstanceLabel_CloudMetadata)
+	*p = x
+	return p
+}
+
+func (x InstanceLabel_CloudMetadata) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (InstanceLabel_CloudMetadata) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (InstanceLabel_CloudMetadata) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_config_proto_config_proto_enumTypes[0]
+}
+
+func (x InstanceLabel_CloudMetadata) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *InstanceLabel_CloudMetadata) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = InstanceLabel_CloudMetadata(num)
+	return nil
+}
+
+// Deprecated: Use InstanceLabel_CloudMetadata.Descriptor instead.
+func (InstanceLabel_CloudMetadata) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescGZIP(), []int{1, 0}
+}
+
 type ProberConfig struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -120,17 +179,43 @@ type ProberConfig struct {
 	// You may want to set it to 0 if cloudprober is running as a backend for
 	// the probes and you don't want time lost in stop and start.
 	StopTimeSec *int32 `protobuf:"varint,99,opt,name=stop_time_sec,json=stopTimeSec,def=5" json:"stop_time_sec,omitempty"`
+	// Grace period for draining the surfacers on SIGINT and SIGTERM. On these
+	// signals, cloudprober stops the probes, stops accepting new probe results,
+	// writes the results that are already queued to the surfacers and flushes
+	// the surfacers that buffer data internally (e.g. file, pubsub, cloudwatch),
+	// waiting for up to the grace period for all of this to finish. The process
+	// exits after the drain is complete, or after stop_time_sec, whichever is
+	// later. Set it to 0 to disable draining.
+	ShutdownGracePeriodSec *int32 `protobuf:"varint,107,opt,name=shutdown_grace_period_sec,json=shutdownGracePeriodSec,def=10" json:"shutdown_grace_period_sec,omitempty"`
 	// Global targets options. Per-probe options are specified within the probe
 	// stanza.
 	GlobalTargetsOptions *proto5.GlobalTargetsOptions `protobuf:"bytes,100,opt,name=global_targets_options,json=globalTargetsOptions" json:"global_targets_options,omitempty"`
+	// Maximum number of external probe commands running at the same time,
+	// across all external probes. External probes can further limit their own
+	// commands using their max_concurrent_commands field. Default is no limit.
+	MaxConcurrentExternalCommands *int32 `protobuf:"varint,106,opt,name=max_concurrent_external_commands,json=maxConcurrentExternalCommands" json:"max_concurrent_external_commands,omitempty"`
+	// Labels to add to all the metrics exported by this cloudprober instance,
+	// e.g. to slice the metrics by the probing location in multi-region
+	// deployments. Labels already set on the metrics, e.g. probe's additional
+	// labels, take precedence over these. Example:
+	//   instance_label {
+	//     key: "region"
+	//     cloud_metadata: REGION
+	//   }
+	//   instance_label {
+	//     key: "env"
+	//     value: "prod"
+	//   }
+	InstanceLabel []*InstanceLabel `protobuf:"bytes,108,rep,name=instance_label,json=instanceLabel" json:"instance_label,omitempty"`
 }
 
 // Default values for ProberConfig fields.
 const (
-	Default_ProberConfig_DisableJitter       = bool(false)
-	Default_ProberConfig_SysvarsIntervalMsec = int32(10000)
-	Default_ProberConfig_SysvarsEnvVar       = string("SYSVARS")
-	Default_ProberConfig_StopTimeSec         = int32(5)
+	Default_ProberConfig_DisableJitter          = bool(false)
+	Default_ProberConfig_SysvarsIntervalMsec    = int32(10000)
+	Default_ProberConfig_SysvarsEnvVar          = string("SYSVARS")
+	Default_ProberConfig_StopTimeSec            = int32(5)
+	Default_ProberConfig_ShutdownGracePeriodSec = int32(10)
 )
 
 func (x *ProberConfig) Reset() {
@@ -256,6 +341,13 @@ func (x *ProberConfig) GetStopTimeSec() int32 {
 	return Default_ProberConfig_StopTimeSec
 }
 
+func (x *ProberConfig) GetShutdownGracePeriodSec() int32 {
+	if x != nil && x.ShutdownGracePeriodSec != nil {
+		return *x.ShutdownGracePeriodSec
+	}
+	return Default_ProberConfig_ShutdownGracePeriodSec
+}
+
 func (x *ProberConfig) GetGlobalTargetsOptions() *proto5.GlobalTargetsOptions {
 	if x != nil {
 		return x.GlobalTargetsOptions
@@ -263,6 +355,111 @@ func (x *ProberConfig) GetGlobalTargetsOptions() *proto5.GlobalTargetsOptions {
 	return nil
 }
 
+func (x *ProberConfig) GetMaxConcurrentExternalCommands() int32 {
+	if x != nil && x.MaxConcurrentExternalCommands != nil {
+		return *x.MaxConcurrentExternalCommands
+	}
+	return 0
+}
+
+func (x *ProberConfig) GetInstanceLabel() []*InstanceLabel {
+	if x != nil {
+		return x.InstanceLabel
+	}
+	return nil
+}
+
+type InstanceLabel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key *string `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	// Types that are assignable to ValueSource:
+	//	*InstanceLabel_Value
+	//	*InstanceLabel_CloudMetadata_
+	ValueSource isInstanceLabel_ValueSource `protobuf_oneof:"value_source"`
+}
+
+func (x *InstanceLabel) Reset() {
+	*x = InstanceLabel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InstanceLabel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstanceLabel) ProtoMessage() {}
+
+func (x *InstanceLabel) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstanceLabel.ProtoReflect.Descriptor instead.
+func (*InstanceLabel) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *InstanceLabel) GetKey() string {
+	if x != nil && x.Key != nil {
+		return *x.Key
+	}
+	return ""
+}
+
+func (m *InstanceLabel) GetValueSource() isInstanceLabel_ValueSource {
+	if m != nil {
+		return m.ValueSource
+	}
+	return nil
+}
+
+func (x *InstanceLabel) GetValue() string {
+	if x, ok := x.GetValueSource().(*InstanceLabel_Value); ok {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *InstanceLabel) GetCloudMetadata() InstanceLabel_CloudMetadata {
+	if x, ok := x.GetValueSource().(*InstanceLabel_CloudMetadata_); ok {
+		return x.CloudMetadata
+	}
+	return InstanceLabel_ZONE
+}
+
+type isInstanceLabel_ValueSource interface {
+	isInstanceLabel_ValueSource()
+}
+
+type InstanceLabel_Value struct {
+	// Static value.
+	Value string `protobuf:"bytes,2,opt,name=value,oneof"`
+}
+
+type InstanceLabel_CloudMetadata_ struct {
+	// Value from the cloud metadata. If it's not available, e.g. if
+	// cloudprober is not running on GCE or EC2, label is not added.
+	CloudMetadata InstanceLabel_CloudMetadata `protobuf:"varint,3,opt,name=cloud_metadata,json=cloudMetadata,enum=cloudprober.InstanceLabel_CloudMetadata,oneof"`
+}
+
+func (*InstanceLabel_Value) isInstanceLabel_ValueSource() {}
+
+func (*InstanceLabel_CloudMetadata_) isInstanceLabel_ValueSource() {}
+
 type SharedTargets struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -275,7 +472,7 @@ type SharedTargets struct {
 func (x *SharedTargets) Reset() {
 	*x = SharedTargets{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1]
+		mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -288,7 +485,7 @@ func (x *SharedTargets) String() string {
 func (*SharedTargets) ProtoMessage() {}
 
 func (x *SharedTargets) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1]
+	mi := &file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -301,7 +498,7 @@ func (x *SharedTargets) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SharedTargets.ProtoReflect.Descriptor instead.
 func (*SharedTargets) Descriptor() ([]byte, []int) {
-	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescGZIP(), []int{1}
+	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *SharedTargets) GetName() string {
@@ -350,7 +547,7 @@ var file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDesc =
 	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
 	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2f,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x22, 0xe9, 0x05, 0x0a, 0x0c, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x43, 0x6f,
+	0x6f, 0x74, 0x6f, 0x22, 0xb4, 0x07, 0x0a, 0x0c, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x43, 0x6f,
 	0x6e, 0x66, 0x69, 0x67, 0x12, 0x32, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x01, 0x20,
 	0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
 	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65,
@@ -390,23 +587,48 @@ var file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDesc =
 	0x07, 0x53, 0x59, 0x53, 0x56, 0x41, 0x52, 0x53, 0x52, 0x0d, 0x73, 0x79, 0x73, 0x76, 0x61, 0x72,
 	0x73, 0x45, 0x6e, 0x76, 0x56, 0x61, 0x72, 0x12, 0x25, 0x0a, 0x0d, 0x73, 0x74, 0x6f, 0x70, 0x5f,
 	0x74, 0x69, 0x6d, 0x65, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x63, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01,
-	0x35, 0x52, 0x0b, 0x73, 0x74, 0x6f, 0x70, 0x54, 0x69, 0x6d, 0x65, 0x53, 0x65, 0x63, 0x12, 0x5f,
-	0x0a, 0x16, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73,
-	0x5f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x64, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29,
-	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x61, 0x72,
-	0x67, 0x65, 0x74, 0x73, 0x2e, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x61, 0x72, 0x67, 0x65,
-	0x74, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x14, 0x67, 0x6c, 0x6f, 0x62, 0x61,
-	0x6c, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22,
-	0x5e, 0x0a, 0x0d, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73,
-	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18,
-	0x02, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x72, 0x2e, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x54, 0x61, 0x72, 0x67,
-	0x65, 0x74, 0x73, 0x44, 0x65, 0x66, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x42,
-	0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
-	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f,
+	0x35, 0x52, 0x0b, 0x73, 0x74, 0x6f, 0x70, 0x54, 0x69, 0x6d, 0x65, 0x53, 0x65, 0x63, 0x12, 0x3d,
+	0x0a, 0x19, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x5f, 0x67, 0x72, 0x61, 0x63, 0x65,
+	0x5f, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x6b, 0x20, 0x01, 0x28,
+	0x05, 0x3a, 0x02, 0x31, 0x30, 0x52, 0x16, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x47,
+	0x72, 0x61, 0x63, 0x65, 0x50, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x53, 0x65, 0x63, 0x12, 0x5f, 0x0a,
+	0x16, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x5f,
+	0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x64, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x73, 0x2e, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x14, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x47,
+	0x0a, 0x20, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x5f, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x73, 0x18, 0x6a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x1d, 0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x41, 0x0a, 0x0e, 0x69, 0x6e, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x6c, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x49, 0x6e,
+	0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x0d, 0x69, 0x6e, 0x73,
+	0x74, 0x61, 0x6e, 0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x22, 0xc3, 0x01, 0x0a, 0x0d, 0x49,
+	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x16,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x51, 0x0a, 0x0e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f,
+	0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x28,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x49, 0x6e, 0x73,
+	0x74, 0x61, 0x6e, 0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64,
+	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x48, 0x00, 0x52, 0x0d, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x22, 0x25, 0x0a, 0x0d, 0x43, 0x6c, 0x6f,
+	0x75, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x08, 0x0a, 0x04, 0x5a, 0x4f,
+	0x4e, 0x45, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x45, 0x47, 0x49, 0x4f, 0x4e, 0x10, 0x01,
+	0x42, 0x0e, 0x0a, 0x0c, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x22, 0x5e, 0x0a, 0x0d, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x54, 0x61, 0x72,
+	0x67, 0x65, 0x74, 0x73, 0x44, 0x65, 0x66, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73,
+	0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -421,32 +643,37 @@ func file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescGZ
 	return file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_github_com_cloudprober_cloudprober_config_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_github_com_cloudprober_cloudprober_config_proto_config_proto_goTypes = []interface{}{
-	(*ProberConfig)(nil),                // 0: cloudprober.ProberConfig
-	(*SharedTargets)(nil),               // 1: cloudprober.SharedTargets
-	(*proto.ProbeDef)(nil),              // 2: cloudprober.probes.ProbeDef
-	(*proto1.SurfacerDef)(nil),          // 3: cloudprober.surfacer.SurfacerDef
-	(*proto2.ServerDef)(nil),            // 4: cloudprober.servers.ServerDef
-	(*proto3.ServerConf)(nil),           // 5: cloudprober.rds.ServerConf
-	(*proto4.TLSConfig)(nil),            // 6: cloudprober.tlsconfig.TLSConfig
-	(*proto5.GlobalTargetsOptions)(nil), // 7: cloudprober.targets.GlobalTargetsOptions
-	(*proto5.TargetsDef)(nil),           // 8: cloudprober.targets.TargetsDef
+	(InstanceLabel_CloudMetadata)(0),    // 0: cloudprober.InstanceLabel.CloudMetadata
+	(*ProberConfig)(nil),                // 1: cloudprober.ProberConfig
+	(*InstanceLabel)(nil),               // 2: cloudprober.InstanceLabel
+	(*SharedTargets)(nil),               // 3: cloudprober.SharedTargets
+	(*proto.ProbeDef)(nil),              // 4: cloudprober.probes.ProbeDef
+	(*proto1.SurfacerDef)(nil),          // 5: cloudprober.surfacer.SurfacerDef
+	(*proto2.ServerDef)(nil),            // 6: cloudprober.servers.ServerDef
+	(*proto3.ServerConf)(nil),           // 7: cloudprober.rds.ServerConf
+	(*proto4.TLSConfig)(nil),            // 8: cloudprober.tlsconfig.TLSConfig
+	(*proto5.GlobalTargetsOptions)(nil), // 9: cloudprober.targets.GlobalTargetsOptions
+	(*proto5.TargetsDef)(nil),           // 10: cloudprober.targets.TargetsDef
 }
 var file_github_com_cloudprober_cloudprober_config_proto_config_proto_depIdxs = []int32{
-	2, // 0: cloudprober.ProberConfig.probe:type_name -> cloudprober.probes.ProbeDef
-	3, // 1: cloudprober.ProberConfig.surfacer:type_name -> cloudprober.surfacer.SurfacerDef
-	4, // 2: cloudprober.ProberConfig.server:type_name -> cloudprober.servers.ServerDef
-	1, // 3: cloudprober.ProberConfig.shared_targets:type_name -> cloudprober.SharedTargets
-	5, // 4: cloudprober.ProberConfig.rds_server:type_name -> cloudprober.rds.ServerConf
-	6, // 5: cloudprober.ProberConfig.grpc_tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
-	7, // 6: cloudprober.ProberConfig.global_targets_options:type_name -> cloudprober.targets.GlobalTargetsOptions
-	8, // 7: cloudprober.SharedTargets.targets:type_name -> cloudprober.targets.TargetsDef
-	8, // [8:8] is the sub-list for method output_type
-	8, // [8:8] is the sub-list for method input_type
-	8, // [8:8] is the sub-list for extension type_name
-	8, // [8:8] is the sub-list for extension extendee
-	0, // [0:8] is the sub-list for field type_name
+	4,  // 0: cloudprober.ProberConfig.probe:type_name -> cloudprober.probes.ProbeDef
+	5,  // 1: cloudprober.ProberConfig.surfacer:type_name -> cloudprober.surfacer.SurfacerDef
+	6,  // 2: cloudprober.ProberConfig.server:type_name -> cloudprober.servers.ServerDef
+	3,  // 3: cloudprober.ProberConfig.shared_targets:type_name -> cloudprober.SharedTargets
+	7,  // 4: cloudprober.ProberConfig.rds_server:type_name -> cloudprober.rds.ServerConf
+	8,  // 5: cloudprober.ProberConfig.grpc_tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	9,  // 6: cloudprober.ProberConfig.global_targets_options:type_name -> cloudprober.targets.GlobalTargetsOptions
+	2,  // 7: cloudprober.ProberConfig.instance_label:type_name -> cloudprober.InstanceLabel
+	0,  // 8: cloudprober.InstanceLabel.cloud_metadata:type_name -> cloudprober.InstanceLabel.CloudMetadata
+	10, // 9: cloudprober.SharedTargets.targets:type_name -> cloudprober.targets.TargetsDef
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_config_proto_config_proto_init() }
@@ -468,6 +695,18 @@ func file_github_com_cloudprober_cloudprober_config_proto_config_proto_init() {
 			}
 		}
 		file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InstanceLabel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SharedTargets); i {
 			case 0:
 				return &v.state
@@ -480,18 +719,23 @@ func file_github_com_cloudprober_cloudprober_config_proto_config_proto_init() {
 			}
 		}
 	}
+	file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*InstanceLabel_Value)(nil),
+		(*InstanceLabel_CloudMetadata_)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_config_proto_config_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_cloudprober_cloudprober_config_proto_config_proto_goTypes,
 		DependencyIndexes: file_github_com_cloudprober_cloudprober_config_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_config_proto_config_proto_enumTypes,
 		MessageInfos:      file_github_com_cloudprober_cloudprober_config_proto_config_proto_msgTypes,
 	}.Build()
 	File_github_com_cloudprober_cloudprober_config_proto_config_proto = out.File
@@ -306,6 +306,48 @@ func (d *Distribution) Data() *DistributionData {
 	}
 }
 
+// Percentile returns an estimate of the p-th percentile (0 < p <= 100) of the
+// samples in the distribution. As distribution keeps only bucket counts and
+// not the samples themselves, percentile is estimated by linear interpolation
+// within the bucket that contains the percentile rank (p/100 * count), i.e.
+// samples are assumed to be uniformly spread within a bucket. Estimation
+// error is bounded by the width of that bucket. For the first (-Inf) and the
+// last (+Inf) buckets, their finite bound is returned.
+//
+// Percentile returns NaN if distribution is empty or p is out of range.
+func (d *Distribution) Percentile(p float64) float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.count == 0 || p <= 0 || p > 100 {
+		return math.NaN()
+	}
+
+	rank := p / 100 * float64(d.count)
+	var cum int64
+	for i, c := range d.bucketCounts {
+		if c == 0 || float64(cum+c) < rank {
+			cum += c
+			continue
+		}
+
+		lower, upper := d.lowerBounds[i], math.Inf(1)
+		if i+1 < len(d.lowerBounds) {
+			upper = d.lowerBounds[i+1]
+		}
+		if math.IsInf(lower, -1) {
+			return upper
+		}
+		if math.IsInf(upper, 1) {
+			return lower
+		}
+		return lower + (rank-float64(cum))/float64(c)*(upper-lower)
+	}
+
+	// We should never get here, as rank <= count.
+	return d.lowerBounds[len(d.lowerBounds)-1]
+}
+
 // StackdriverTypedValue returns a Stackdriver typed value corresponding to the
 // receiver distribution. This routine is used by stackdriver surfacer.
 func (d *Distribution) StackdriverTypedValue() *monitoring.TypedValue {
@@ -17,6 +17,7 @@ package metrics
 import (
 	"math"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -259,3 +260,98 @@ func BenchmarkDictStringer(b *testing.B) {
 		_ = d.String()
 	}
 }
+
+func TestDistPercentile(t *testing.T) {
+	// Linear buckets of width 10: 0, 10, 20, ..., 990.
+	var lb []float64
+	for i := 0; i < 100; i++ {
+		lb = append(lb, float64(i*10))
+	}
+
+	for _, test := range []struct {
+		desc    string
+		samples []float64
+		bounds  []float64
+	}{
+		{
+			desc:    "uniform_linear_buckets",
+			samples: samplesFunc(1000, func(i int) float64 { return float64(i) }),
+			bounds:  lb,
+		},
+		{
+			desc:    "skewed_linear_buckets",
+			samples: samplesFunc(1000, func(i int) float64 { return float64(i*i) / 1000 }),
+			bounds:  lb,
+		},
+		{
+			desc:    "uniform_exponential_buckets",
+			samples: samplesFunc(1000, func(i int) float64 { return float64(i) }),
+			bounds:  []float64{0, 1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := NewDistribution(test.bounds)
+			for _, s := range test.samples {
+				d.AddSample(s)
+			}
+
+			sorted := append([]float64{}, test.samples...)
+			sort.Float64s(sorted)
+
+			for _, p := range []float64{1, 50, 90, 99, 100} {
+				// Exact percentile, using the nearest-rank method.
+				rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+				exact := sorted[rank]
+
+				// Estimate should be within the bucket containing the exact value.
+				bi := d.bucketIndex(exact)
+				lower, upper := d.lowerBounds[bi], math.Inf(1)
+				if bi+1 < len(d.lowerBounds) {
+					upper = d.lowerBounds[bi+1]
+				}
+
+				got := d.Percentile(p)
+				if got < lower || got > upper {
+					t.Errorf("Percentile(%v)=%v, exact=%v, want within bucket [%v, %v]", p, got, exact, lower, upper)
+				}
+
+				// Estimation should be deterministic.
+				if got2 := d.Percentile(p); got2 != got {
+					t.Errorf("Percentile(%v) not deterministic: %v, %v", p, got, got2)
+				}
+			}
+		})
+	}
+}
+
+func TestDistPercentileEdgeCases(t *testing.T) {
+	d := NewDistribution([]float64{1, 2, 4})
+
+	if got := d.Percentile(50); !math.IsNaN(got) {
+		t.Errorf("Percentile(50) for an empty distribution=%v, want NaN", got)
+	}
+
+	// Samples in the underflow (-Inf, 1) and overflow [4, +Inf) buckets.
+	d.AddSample(0.5)
+	d.AddSample(10)
+	if got := d.Percentile(50); got != 1 {
+		t.Errorf("Percentile(50)=%v, want=1 (upper bound of the underflow bucket)", got)
+	}
+	if got := d.Percentile(100); got != 4 {
+		t.Errorf("Percentile(100)=%v, want=4 (lower bound of the overflow bucket)", got)
+	}
+
+	for _, p := range []float64{0, -1, 101} {
+		if got := d.Percentile(p); !math.IsNaN(got) {
+			t.Errorf("Percentile(%v)=%v, want NaN", p, got)
+		}
+	}
+}
+
+func samplesFunc(n int, f func(i int) float64) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = f(i)
+	}
+	return samples
+}
@@ -17,6 +17,7 @@ package metrics
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -250,6 +251,20 @@ func (em *EventMetrics) String() string {
 	return b.String()
 }
 
+// LabelsKey returns a string key that uniquely identifies the eventmetrics'
+// label set, irrespective of the order in which the labels were added.
+func (em *EventMetrics) LabelsKey() string {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	var labels []string
+	for k, v := range em.labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
 // Key returns a string key that uniquely identifies an eventmetrics.
 func (em *EventMetrics) Key() string {
 	em.mu.RLock()
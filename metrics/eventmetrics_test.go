@@ -212,3 +212,15 @@ func TestAllocsPerRun(t *testing.T) {
 
 	t.Logf("Average allocations per run: ForNew=%v, ForString=%v", newAvg, stringAvg)
 }
+
+func TestLabelsKey(t *testing.T) {
+	em1 := NewEventMetrics(time.Now()).AddLabel("a", "1").AddLabel("b", "2")
+	em2 := NewEventMetrics(time.Now()).AddLabel("b", "2").AddLabel("a", "1")
+	if em1.LabelsKey() != em2.LabelsKey() {
+		t.Errorf("LabelsKey differs for the same labels in different order: %s, %s", em1.LabelsKey(), em2.LabelsKey())
+	}
+	em3 := NewEventMetrics(time.Now()).AddLabel("a", "1").AddLabel("b", "3")
+	if em1.LabelsKey() == em3.LabelsKey() {
+		t.Errorf("LabelsKey is the same for different labels: %s", em1.LabelsKey())
+	}
+}
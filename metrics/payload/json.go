@@ -0,0 +1,116 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// jsonPayloadLines converts a JSON payload to the metric lines of the text
+// format, so that both formats are processed the same way. Lines are sorted
+// by the metric name.
+func (p *Parser) jsonPayloadLines(payload string) []string {
+	if strings.TrimSpace(payload) == "" {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		p.l.Warningf("Error parsing JSON payload: %v", err)
+		return nil
+	}
+
+	var lines []string
+	for name, v := range doc {
+		val, err := jsonValueString(v)
+		if err != nil {
+			p.l.Warningf("Error parsing JSON payload value for the metric %s: %v", name, err)
+			continue
+		}
+		lines = append(lines, name+" "+val)
+	}
+	sort.Strings(lines)
+
+	return lines
+}
+
+// jsonValueString returns the text format string for a JSON value.
+func jsonValueString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case string:
+		return "\"" + v + "\"", nil
+	case []interface{}:
+		if len(v) == 0 {
+			return "", fmt.Errorf("empty list")
+		}
+		samples := make([]string, len(v))
+		for i, s := range v {
+			n, ok := s.(json.Number)
+			if !ok {
+				return "", fmt.Errorf("non-numeric value in the list: %v", s)
+			}
+			samples[i] = n.String()
+		}
+		return strings.Join(samples, ","), nil
+	case map[string]interface{}:
+		return jsonMapString(v)
+	}
+	return "", fmt.Errorf("unsupported value: %v", v)
+}
+
+// jsonMapString returns the text format string for a map value, for example:
+// {"code": {"200": 10, "500": 2}} => "map:code,200:10,500:2".
+func jsonMapString(v map[string]interface{}) (string, error) {
+	if len(v) != 1 {
+		return "", fmt.Errorf("map value should have exactly one key, the map key label, got: %d keys", len(v))
+	}
+
+	for mapKey, mv := range v {
+		m, ok := mv.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("map value for the key label %s is not an object: %v", mapKey, mv)
+		}
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		tokens := []string{"map:" + mapKey}
+		for _, k := range keys {
+			n, ok := m[k].(json.Number)
+			if !ok {
+				return "", fmt.Errorf("non-numeric value for the map key %s: %v", k, m[k])
+			}
+			tokens = append(tokens, k+":"+n.String())
+		}
+		return strings.Join(tokens, ","), nil
+	}
+	return "", nil
+}
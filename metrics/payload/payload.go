@@ -32,6 +32,7 @@ type Parser struct {
 	baseEM      *metrics.EventMetrics
 	distMetrics map[string]*metrics.Distribution
 	aggregate   bool
+	jsonFormat  bool
 	l           *logger.Logger
 }
 
@@ -39,6 +40,7 @@ type Parser struct {
 func NewParser(opts *configpb.OutputMetricsOptions, ptype, probeName string, defaultKind metrics.Kind, l *logger.Logger) (*Parser, error) {
 	parser := &Parser{
 		aggregate:   opts.GetAggregateInCloudprober(),
+		jsonFormat:  opts.GetFormat() == configpb.OutputMetricsOptions_JSON,
 		distMetrics: make(map[string]*metrics.Distribution),
 		l:           l,
 	}
@@ -171,6 +173,15 @@ func (p *Parser) metricValueLabels(line string) (metricName, val string, labels
 	return metricName, value, parseLabels(labelStr)
 }
 
+// payloadLines returns the metric lines in the payload. JSON payloads are
+// converted to the text format lines first.
+func (p *Parser) payloadLines(payload string) []string {
+	if p.jsonFormat {
+		return p.jsonPayloadLines(payload)
+	}
+	return strings.Split(payload, "\n")
+}
+
 func addNewMetric(em *metrics.EventMetrics, metricName, val string) error {
 	// New metric name, make sure it's not disallowed.
 	switch metricName {
@@ -194,7 +205,7 @@ func (p *Parser) PayloadMetrics(payload, target string) []*metrics.EventMetrics
 	payloadTS := time.Now()
 	var results []*metrics.EventMetrics
 
-	for _, line := range strings.Split(payload, "\n") {
+	for _, line := range p.payloadLines(payload) {
 		metricName, val, labels := p.metricValueLabels(line)
 		if metricName == "" {
 			continue
@@ -239,7 +250,7 @@ func (p *Parser) AggregatedPayloadMetrics(em *metrics.EventMetrics, payload, tar
 
 	em.Timestamp = time.Now()
 
-	for _, line := range strings.Split(payload, "\n") {
+	for _, line := range p.payloadLines(payload) {
 		metricName, val, _ := p.metricValueLabels(line)
 		if metricName == "" {
 			continue
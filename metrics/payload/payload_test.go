@@ -24,6 +24,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/metrics"
 	configpb "github.com/cloudprober/cloudprober/metrics/payload/proto"
+	distpb "github.com/cloudprober/cloudprober/metrics/proto"
 )
 
 var (
@@ -212,6 +213,94 @@ func TestNoAggregation(t *testing.T) {
 	testPayloadMetrics(t, p, td)
 }
 
+func jsonParserForTest(t *testing.T, agg bool) *Parser {
+	t.Helper()
+
+	c := &configpb.OutputMetricsOptions{
+		AggregateInCloudprober: proto.Bool(agg),
+		Format:                 configpb.OutputMetricsOptions_JSON.Enum(),
+		DistMetric: map[string]*distpb.Dist{
+			"op_latency": {
+				Buckets: &distpb.Dist_ExplicitBuckets{ExplicitBuckets: "1,10,100"},
+			},
+		},
+	}
+	p, err := NewParser(c, testPtype, testProbe, metrics.CUMULATIVE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestJSONPayloadMetrics(t *testing.T) {
+	p := jsonParserForTest(t, false)
+	baseLabels := "labels=ptype=external,probe=testprobe,dst=test-target"
+
+	for _, test := range []struct {
+		desc    string
+		payload string
+		want    []string
+	}{
+		{
+			desc: "all_types",
+			payload: `{
+				"num_rows{db=dbA}": 120,
+				"op_latency": [3.1, 4, 13],
+				"resp_code": {"code": {"500": 2, "200": 10}},
+				"healthy": true,
+				"version": "v1.2.1"
+			}`,
+			want: []string{
+				baseLabels + " healthy=1.000",
+				baseLabels + ",db=dbA num_rows=120.000",
+				baseLabels + " op_latency=dist:sum:20.1|count:3|lb:-Inf,1,10,100|bc:0,2,1,0",
+				baseLabels + " resp_code=map:code,200:10.000,500:2.000",
+				baseLabels + " version=\"v1.2.1\"",
+			},
+		},
+		{
+			desc:    "bad_values",
+			payload: `{"num_rows": 120, "list": [1, "a"], "map": {"a": 1, "b": 2}, "null": null}`,
+			want:    []string{baseLabels + " num_rows=120.000"},
+		},
+		{
+			desc:    "invalid_json",
+			payload: "num_rows 120",
+		},
+		{
+			desc:    "empty",
+			payload: "\n",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var got []string
+			for _, em := range p.PayloadMetrics(test.payload, testTarget) {
+				// Drop the timestamp.
+				got = append(got, strings.SplitN(em.String(), " ", 2)[1])
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Got metrics:\n%v\nwant:\n%v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJSONAggregatedPayloadMetrics(t *testing.T) {
+	p := jsonParserForTest(t, true)
+
+	td := &testData{10, 30, []float64{3.1, 4.0, 13}, [3][2]string{}}
+	em := p.AggregatedPayloadMetrics(nil, `{"time_to_running": 10, "time_to_ssh": 30, "op_latency": [3.1, 4.0, 13]}`, testTarget)
+	testAggregatedPayloadMetrics(t, em, td, td)
+
+	etd := &testData{
+		varA: 18,
+		varB: 75,
+		lat:  []float64{3.1, 4.0, 13, 6, 14.1, 2.1},
+	}
+	em = p.AggregatedPayloadMetrics(em, `{"time_to_running": 8, "time_to_ssh": 45, "op_latency": [6, 14.1, 2.1]}`, testTarget)
+	testAggregatedPayloadMetrics(t, em, td, etd)
+}
+
 func TestMetricValueLabels(t *testing.T) {
 	tests := []struct {
 		desc    string
@@ -83,6 +83,78 @@ func (OutputMetricsOptions_MetricsKind) EnumDescriptor() ([]byte, []int) {
 	return file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_rawDescGZIP(), []int{0, 0}
 }
 
+// Format of the metrics in the payload.
+type OutputMetricsOptions_Format int32
+
+const (
+	// One metric per line, with an optional set of labels, e.g.:
+	//   num_rows{db=dbA} 120
+	//   op_latency 4.7,5.6,5.9
+	OutputMetricsOptions_TEXT OutputMetricsOptions_Format = 0
+	// A JSON object of metric names to values, e.g.:
+	//   {
+	//     "num_rows{db=dbA}": 120,
+	//     "op_latency": [4.7, 5.6, 5.9],
+	//     "resp_code": {"code": {"200": 10, "500": 2}},
+	//     "version": "v1.2.1"
+	//   }
+	// Numbers are exported as GAUGE or CUMULATIVE metrics, based on
+	// metrics_kind, and lists of numbers as distributions. Lists are
+	// supported only for the metrics configured through dist_metric. A map
+	// value is an object with a single key, the map's key label, pointing
+	// to the object of keys to numbers. Booleans are exported as 0 or 1.
+	OutputMetricsOptions_JSON OutputMetricsOptions_Format = 1
+)
+
+// Enum value maps for OutputMetricsOptions_Format.
+var (
+	OutputMetricsOptions_Format_name = map[int32]string{
+		0: "TEXT",
+		1: "JSON",
+	}
+	OutputMetricsOptions_Format_value = map[string]int32{
+		"TEXT": 0,
+		"JSON": 1,
+	}
+)
+
+func (x OutputMetricsOptions_Format) Enum() *OutputMetricsOptions_Format {
+	p := new(OutputMetricsOptions_Format)
+	*p = x
+	return p
+}
+
+func (x OutputMetricsOptions_Format) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputMetricsOptions_Format) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (OutputMetricsOptions_Format) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_enumTypes[1]
+}
+
+func (x OutputMetricsOptions_Format) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *OutputMetricsOptions_Format) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = OutputMetricsOptions_Format(num)
+	return nil
+}
+
+// Deprecated: Use OutputMetricsOptions_Format.Descriptor instead.
+func (OutputMetricsOptions_Format) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
 type OutputMetricsOptions struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -110,12 +182,14 @@ type OutputMetricsOptions struct {
 	//     explicit_buckets: "1,2,4,8,16,32,64,128,256"
 	//   }
 	// }
-	DistMetric map[string]*proto.Dist `protobuf:"bytes,4,rep,name=dist_metric,json=distMetric" json:"dist_metric,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DistMetric map[string]*proto.Dist       `protobuf:"bytes,4,rep,name=dist_metric,json=distMetric" json:"dist_metric,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Format     *OutputMetricsOptions_Format `protobuf:"varint,5,opt,name=format,enum=cloudprober.metrics.payload.OutputMetricsOptions_Format,def=0" json:"format,omitempty"`
 }
 
 // Default values for OutputMetricsOptions fields.
 const (
 	Default_OutputMetricsOptions_AggregateInCloudprober = bool(false)
+	Default_OutputMetricsOptions_Format                 = OutputMetricsOptions_TEXT
 )
 
 func (x *OutputMetricsOptions) Reset() {
@@ -178,6 +252,13 @@ func (x *OutputMetricsOptions) GetDistMetric() map[string]*proto.Dist {
 	return nil
 }
 
+func (x *OutputMetricsOptions) GetFormat() OutputMetricsOptions_Format {
+	if x != nil && x.Format != nil {
+		return *x.Format
+	}
+	return Default_OutputMetricsOptions_Format
+}
+
 var File_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_rawDesc = []byte{
@@ -191,7 +272,7 @@ var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_r
 	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f,
 	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
 	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x64, 0x69, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x22, 0xdd, 0x03, 0x0a, 0x14, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x4d, 0x65, 0x74, 0x72,
+	0x6f, 0x22, 0xd3, 0x04, 0x0a, 0x14, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x4d, 0x65, 0x74, 0x72,
 	0x69, 0x63, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x60, 0x0a, 0x0c, 0x6d, 0x65,
 	0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
 	0x32, 0x3d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6d,
@@ -211,20 +292,28 @@ var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_r
 	0x74, 0x72, 0x69, 0x63, 0x73, 0x2e, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2e, 0x4f, 0x75,
 	0x74, 0x70, 0x75, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f,
 	0x6e, 0x73, 0x2e, 0x44, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x45, 0x6e, 0x74,
-	0x72, 0x79, 0x52, 0x0a, 0x64, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x1a, 0x58,
-	0x0a, 0x0f, 0x44, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
-	0x6b, 0x65, 0x79, 0x12, 0x2f, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2e, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2e, 0x44, 0x69, 0x73, 0x74, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x37, 0x0a, 0x0b, 0x4d, 0x65, 0x74, 0x72,
-	0x69, 0x63, 0x73, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x0d, 0x0a, 0x09, 0x55, 0x4e, 0x44, 0x45, 0x46,
-	0x49, 0x4e, 0x45, 0x44, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x47, 0x41, 0x55, 0x47, 0x45, 0x10,
-	0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x43, 0x55, 0x4d, 0x55, 0x4c, 0x41, 0x54, 0x49, 0x56, 0x45, 0x10,
-	0x02, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
-	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2f,
-	0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x72, 0x79, 0x52, 0x0a, 0x64, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x56,
+	0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x38,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x73, 0x2e, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2e, 0x4f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x2e, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x3a, 0x04, 0x54, 0x45, 0x58, 0x54, 0x52, 0x06,
+	0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x1a, 0x58, 0x0a, 0x0f, 0x44, 0x69, 0x73, 0x74, 0x4d, 0x65,
+	0x74, 0x72, 0x69, 0x63, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2f, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
+	0x2e, 0x44, 0x69, 0x73, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
+	0x22, 0x37, 0x0a, 0x0b, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x4b, 0x69, 0x6e, 0x64, 0x12,
+	0x0d, 0x0a, 0x09, 0x55, 0x4e, 0x44, 0x45, 0x46, 0x49, 0x4e, 0x45, 0x44, 0x10, 0x00, 0x12, 0x09,
+	0x0a, 0x05, 0x47, 0x41, 0x55, 0x47, 0x45, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x43, 0x55, 0x4d,
+	0x55, 0x4c, 0x41, 0x54, 0x49, 0x56, 0x45, 0x10, 0x02, 0x22, 0x1c, 0x0a, 0x06, 0x46, 0x6f, 0x72,
+	0x6d, 0x61, 0x74, 0x12, 0x08, 0x0a, 0x04, 0x54, 0x45, 0x58, 0x54, 0x10, 0x00, 0x12, 0x08, 0x0a,
+	0x04, 0x4a, 0x53, 0x4f, 0x4e, 0x10, 0x01, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x6d, 0x65,
+	0x74, 0x72, 0x69, 0x63, 0x73, 0x2f, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -239,23 +328,25 @@ func file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_
 	return file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
 var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_goTypes = []interface{}{
 	(OutputMetricsOptions_MetricsKind)(0), // 0: cloudprober.metrics.payload.OutputMetricsOptions.MetricsKind
-	(*OutputMetricsOptions)(nil),          // 1: cloudprober.metrics.payload.OutputMetricsOptions
-	nil,                                   // 2: cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry
-	(*proto.Dist)(nil),                    // 3: cloudprober.metrics.Dist
+	(OutputMetricsOptions_Format)(0),      // 1: cloudprober.metrics.payload.OutputMetricsOptions.Format
+	(*OutputMetricsOptions)(nil),          // 2: cloudprober.metrics.payload.OutputMetricsOptions
+	nil,                                   // 3: cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry
+	(*proto.Dist)(nil),                    // 4: cloudprober.metrics.Dist
 }
 var file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_depIdxs = []int32{
 	0, // 0: cloudprober.metrics.payload.OutputMetricsOptions.metrics_kind:type_name -> cloudprober.metrics.payload.OutputMetricsOptions.MetricsKind
-	2, // 1: cloudprober.metrics.payload.OutputMetricsOptions.dist_metric:type_name -> cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry
-	3, // 2: cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry.value:type_name -> cloudprober.metrics.Dist
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	3, // 1: cloudprober.metrics.payload.OutputMetricsOptions.dist_metric:type_name -> cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry
+	1, // 2: cloudprober.metrics.payload.OutputMetricsOptions.format:type_name -> cloudprober.metrics.payload.OutputMetricsOptions.Format
+	4, // 3: cloudprober.metrics.payload.OutputMetricsOptions.DistMetricEntry.value:type_name -> cloudprober.metrics.Dist
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_init() }
@@ -282,7 +373,7 @@ func file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_metrics_payload_proto_config_proto_rawDesc,
-			NumEnums:      1,
+			NumEnums:      2,
 			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
@@ -0,0 +1,65 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// SuccessCounters returns the probe's "total" and "success" counters from a
+// CUMULATIVE EventMetrics. ok is false if the EventMetrics is not CUMULATIVE
+// or doesn't have numeric total and success counters.
+func SuccessCounters(em *EventMetrics) (total, success int64, ok bool) {
+	if em.Kind != CUMULATIVE {
+		return 0, 0, false
+	}
+	t, ok1 := em.Metric("total").(NumValue)
+	s, ok2 := em.Metric("success").(NumValue)
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return t.Int64(), s.Int64(), true
+}
+
+// SuccessDelta is the change in the total and success counters between two
+// consecutive reports.
+type SuccessDelta struct {
+	Total, Success int64
+
+	// Reset is true if the counters went down, e.g. because the probe was
+	// restarted. Change is then computed from zero.
+	Reset bool
+}
+
+// SuccessTracker keeps track of the total and success counters, e.g. per
+// EventMetrics' label set (see EventMetrics.LabelsKey()), to compute their
+// change between consecutive reports. It's not safe for concurrent use.
+type SuccessTracker struct {
+	last map[string][2]int64
+}
+
+// NewSuccessTracker returns a new SuccessTracker.
+func NewSuccessTracker() *SuccessTracker {
+	return &SuccessTracker{last: make(map[string][2]int64)}
+}
+
+// Update records the latest counters for the key, and returns their change
+// since the last update for the same key.
+func (st *SuccessTracker) Update(key string, total, success int64) SuccessDelta {
+	last := st.last[key]
+	st.last[key] = [2]int64{total, success}
+
+	d := SuccessDelta{Total: total - last[0], Success: success - last[1]}
+	if d.Total < 0 || d.Success < 0 {
+		d = SuccessDelta{Total: total, Success: success, Reset: true}
+	}
+	return d
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuccessCounters(t *testing.T) {
+	em := NewEventMetrics(time.Now()).
+		AddMetric("total", NewInt(10)).
+		AddMetric("success", NewInt(8))
+
+	total, success, ok := SuccessCounters(em)
+	if !ok || total != 10 || success != 8 {
+		t.Errorf("SuccessCounters()=%d, %d, %v, want: 10, 8, true", total, success, ok)
+	}
+
+	em.Kind = GAUGE
+	if _, _, ok := SuccessCounters(em); ok {
+		t.Errorf("SuccessCounters() for a GAUGE EventMetrics, got ok=true")
+	}
+
+	em = NewEventMetrics(time.Now()).AddMetric("total", NewInt(10))
+	if _, _, ok := SuccessCounters(em); ok {
+		t.Errorf("SuccessCounters() without success counter, got ok=true")
+	}
+}
+
+func TestSuccessTracker(t *testing.T) {
+	st := NewSuccessTracker()
+
+	tests := []struct {
+		key            string
+		total, success int64
+		want           SuccessDelta
+	}{
+		{"t1", 2, 1, SuccessDelta{Total: 2, Success: 1}},
+		{"t2", 5, 5, SuccessDelta{Total: 5, Success: 5}},
+		{"t1", 5, 3, SuccessDelta{Total: 3, Success: 2}},
+		{"t1", 5, 3, SuccessDelta{}},
+		// Counters reset.
+		{"t1", 1, 0, SuccessDelta{Total: 1, Success: 0, Reset: true}},
+		{"t2", 6, 5, SuccessDelta{Total: 1, Success: 0}},
+	}
+
+	for i, test := range tests {
+		got := st.Update(test.key, test.total, test.success)
+		if got != test.want {
+			t.Errorf("Update #%d (%s, %d, %d)=%+v, want: %+v", i, test.key, test.total, test.success, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// alertPayload is the JSON payload sent to the alerting webhook.
+type alertPayload struct {
+	Status       string    `json:"status"`
+	Probe        string    `json:"probe"`
+	Target       string    `json:"target"`
+	FailureCount int64     `json:"failure_count"`
+	FailingSince time.Time `json:"failing_since"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// alertState tracks a target's alerting state.
+type alertState struct {
+	consecutiveFailures int64
+	failingSince        time.Time
+	firing              bool
+	lastSent            time.Time
+}
+
+// alerter watches a probe's results and notifies the configured webhook when
+// a target starts failing (after min_failures consecutive failures) and when
+// it recovers.
+type alerter struct {
+	probe          string
+	url            string
+	minFailures    int64
+	resendInterval time.Duration
+	client         *http.Client
+	l              *logger.Logger
+
+	counters *metrics.SuccessTracker
+	states   map[string]*alertState
+
+	// Notifications waiting to be sent, per alert (EventMetrics labels). A
+	// key is present only while its notifications are being sent.
+	mu      sync.Mutex
+	pending map[string][][]byte
+}
+
+func newAlerter(probe string, c *configpb.AlertingConf, l *logger.Logger) *alerter {
+	return &alerter{
+		probe:          probe,
+		url:            c.GetWebhookUrl(),
+		minFailures:    int64(c.GetMinFailures()),
+		resendInterval: time.Duration(c.GetResendIntervalSec()) * time.Second,
+		client:         &http.Client{Timeout: webhookTimeout},
+		l:              l,
+		counters:       metrics.NewSuccessTracker(),
+		states:         make(map[string]*alertState),
+		pending:        make(map[string][][]byte),
+	}
+}
+
+func (a *alerter) post(b []byte) error {
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		a.l.Warningf("Alert webhook returned an error: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendPending sends the key's pending notifications, one by one, until there
+// are none left.
+func (a *alerter) sendPending(key string) {
+	for {
+		a.mu.Lock()
+		q := a.pending[key]
+		if len(q) == 0 {
+			delete(a.pending, key)
+			a.mu.Unlock()
+			return
+		}
+		b := q[0]
+		a.pending[key] = q[1:]
+		a.mu.Unlock()
+
+		if err := a.post(b); err != nil {
+			a.l.Warningf("Error sending alert (probe: %s, key: %s) to the webhook: %v", a.probe, key, err)
+		}
+	}
+}
+
+// notify sends the payload to the webhook in the background, so that slow
+// webhooks don't hold up the metrics. Notifications for the same alert (key)
+// are sent in order, e.g. a "resolved" notification is never sent before the
+// "firing" one that precedes it.
+func (a *alerter) notify(key string, payload *alertPayload) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		a.l.Errorf("Error marshaling alert payload: %v", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	q, sending := a.pending[key]
+	a.pending[key] = append(q, b)
+	if !sending {
+		go a.sendPending(key)
+	}
+}
+
+// update updates the state for the given key (EventMetrics labels) with the
+// new counters and returns the alert payload to send, if any.
+func (a *alerter) update(key, target string, ts time.Time, total, success int64) *alertPayload {
+	as := a.states[key]
+	if as == nil {
+		as = &alertState{}
+		a.states[key] = as
+	}
+
+	d := a.counters.Update(key, total, success)
+	if d.Total == 0 {
+		return nil
+	}
+
+	payload := &alertPayload{
+		Probe:     a.probe,
+		Target:    target,
+		Timestamp: ts,
+	}
+
+	if d.Success > 0 {
+		failures := as.consecutiveFailures
+		as.consecutiveFailures = 0
+		if !as.firing {
+			return nil
+		}
+		as.firing = false
+		payload.Status, payload.FailureCount, payload.FailingSince = "resolved", failures, as.failingSince
+		return payload
+	}
+
+	if as.consecutiveFailures == 0 {
+		as.failingSince = ts
+	}
+	as.consecutiveFailures += d.Total
+	if as.consecutiveFailures < a.minFailures {
+		return nil
+	}
+	if as.firing && (a.resendInterval == 0 || ts.Sub(as.lastSent) < a.resendInterval) {
+		return nil
+	}
+
+	as.firing, as.lastSent = true, ts
+	payload.Status, payload.FailureCount, payload.FailingSince = "firing", as.consecutiveFailures, as.failingSince
+	return payload
+}
+
+// process looks at the total and success counters in the probe's
+// EventMetrics, and sends notifications as required. It returns the
+// EventMetrics unchanged.
+func (a *alerter) process(em *metrics.EventMetrics) *metrics.EventMetrics {
+	if em.Label("warmup") == "true" || em.Label("maintenance") == "true" {
+		return em
+	}
+	total, success, ok := metrics.SuccessCounters(em)
+	if !ok {
+		return em
+	}
+
+	key := em.LabelsKey()
+	if payload := a.update(key, em.Label("dst"), em.Timestamp, total, success); payload != nil {
+		a.l.Infof("Sending %s alert for probe: %s, target: %s", payload.Status, payload.Probe, payload.Target)
+		a.notify(key, payload)
+	}
+	return em
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+// webhookReceiver is a mock webhook that records the received payloads.
+func webhookReceiver(t *testing.T) (*httptest.Server, chan *alertPayload) {
+	t.Helper()
+	payloads := make(chan *alertPayload, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request: %s, content-type: %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		payload := &alertPayload{}
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			t.Errorf("Error decoding payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	return ts, payloads
+}
+
+func TestAlerter(t *testing.T) {
+	ts, payloads := webhookReceiver(t)
+	defer ts.Close()
+
+	a := newAlerter("p1", &configpb.AlertingConf{
+		WebhookUrl:        proto.String(ts.URL),
+		MinFailures:       proto.Int32(2),
+		ResendIntervalSec: proto.Int32(60),
+	}, nil)
+
+	start := time.Now().Truncate(time.Second)
+	var total, success int64
+
+	type wantPayload struct {
+		status       string
+		failureCount int64
+		failingSince time.Time
+	}
+
+	for i, test := range []struct {
+		ok   bool
+		want *wantPayload
+	}{
+		{ok: true},
+		{ok: false},
+		{ok: false, want: &wantPayload{"firing", 2, start.Add(1 * time.Second)}},
+		{ok: false}, // Still failing, not resent before the resend interval.
+		{ok: true, want: &wantPayload{"resolved", 3, start.Add(1 * time.Second)}},
+		{ok: false},
+		{ok: true}, // Recovered before the alert fired.
+	} {
+		total++
+		if test.ok {
+			success++
+		}
+		emTime := start.Add(time.Duration(i) * time.Second)
+		em := metrics.NewEventMetrics(emTime).
+			AddMetric("total", metrics.NewInt(total)).
+			AddMetric("success", metrics.NewInt(success)).
+			AddLabel("probe", "p1").
+			AddLabel("dst", "t1")
+
+		if got := a.process(em); got != em {
+			t.Errorf("Run %d: EventMetrics modified", i)
+		}
+
+		if test.want == nil {
+			select {
+			case p := <-payloads:
+				t.Errorf("Run %d: unexpected notification: %+v", i, p)
+			case <-time.After(50 * time.Millisecond):
+			}
+			continue
+		}
+
+		select {
+		case p := <-payloads:
+			if p.Status != test.want.status || p.Probe != "p1" || p.Target != "t1" || p.FailureCount != test.want.failureCount {
+				t.Errorf("Run %d: got payload %+v, want status=%s, failure_count=%d", i, p, test.want.status, test.want.failureCount)
+			}
+			if !p.FailingSince.Equal(test.want.failingSince) || !p.Timestamp.Equal(emTime) {
+				t.Errorf("Run %d: got failing_since=%v, timestamp=%v, want %v, %v", i, p.FailingSince, p.Timestamp, test.want.failingSince, emTime)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Run %d: timed out waiting for the %s notification", i, test.want.status)
+		}
+	}
+}
+
+func TestAlerterResend(t *testing.T) {
+	ts, payloads := webhookReceiver(t)
+	defer ts.Close()
+
+	a := newAlerter("p1", &configpb.AlertingConf{
+		WebhookUrl:        proto.String(ts.URL),
+		ResendIntervalSec: proto.Int32(60),
+	}, nil)
+
+	start := time.Now()
+
+	// Failures at 0s, 30s and 60s: alert fires at 0s and is resent at 60s.
+	for i, offset := range []time.Duration{0, 30 * time.Second, 60 * time.Second} {
+		em := metrics.NewEventMetrics(start.Add(offset)).
+			AddMetric("total", metrics.NewInt(int64(i+1))).
+			AddMetric("success", metrics.NewInt(0)).
+			AddLabel("dst", "t1")
+		a.process(em)
+	}
+
+	// Notifications for the same alert arrive in order.
+	for _, want := range []int64{1, 3} {
+		select {
+		case p := <-payloads:
+			if p.FailureCount != want {
+				t.Errorf("Got notification with failure count: %d, want: %d", p.FailureCount, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the notification with failure count: %d", want)
+		}
+	}
+	select {
+	case p := <-payloads:
+		t.Errorf("Unexpected notification: %+v", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAlerterOrder(t *testing.T) {
+	var statuses []string
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := &alertPayload{}
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			t.Errorf("Error decoding payload: %v", err)
+		}
+		// Slow down the firing notification, resolved one should still
+		// arrive after it.
+		if payload.Status == "firing" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		statuses = append(statuses, payload.Status)
+		if len(statuses) == 2 {
+			close(done)
+		}
+	}))
+	defer ts.Close()
+
+	a := newAlerter("p1", &configpb.AlertingConf{
+		WebhookUrl: proto.String(ts.URL),
+	}, nil)
+
+	start := time.Now()
+	for i, success := range []int64{0, 1} {
+		a.process(metrics.NewEventMetrics(start.Add(time.Duration(i)*time.Second)).
+			AddMetric("total", metrics.NewInt(int64(i+1))).
+			AddMetric("success", metrics.NewInt(success)).
+			AddLabel("dst", "t1"))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for notifications, got: %v", statuses)
+	}
+	if statuses[0] != "firing" || statuses[1] != "resolved" {
+		t.Errorf("Got notifications: %v, want: [firing resolved]", statuses)
+	}
+}
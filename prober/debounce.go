@@ -0,0 +1,103 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// debounceState tracks a target's results for the failure debounce.
+type debounceState struct {
+	consecutiveFailures int64
+	suppressed          int64 // Failed runs reported as successful so far.
+}
+
+// debounceTracker debounces probe failures: a target's failed runs are
+// reported as successful until the target fails for the configured number of
+// consecutive runs. Raw success counter is exported as "raw_success".
+//
+// Since probes report cumulative counters, possibly covering multiple runs in
+// a single report, the order of results within a report is not known. If a
+// report has both failures and successes, we assume that failures came first,
+// i.e. report ends the failure streak.
+type debounceTracker struct {
+	consecutive int64
+	counters    *metrics.SuccessTracker // Raw counters.
+	states      map[string]*debounceState
+}
+
+func newDebounceTracker(consecutive int) *debounceTracker {
+	return &debounceTracker{
+		consecutive: int64(consecutive),
+		counters:    metrics.NewSuccessTracker(),
+		states:      make(map[string]*debounceState),
+	}
+}
+
+// update updates the target's state with the new counters and returns the
+// total number of suppressed failures so far.
+func (dt *debounceTracker) update(key string, total, success int64) int64 {
+	ds := dt.states[key]
+	if ds == nil {
+		ds = &debounceState{}
+		dt.states[key] = ds
+	}
+
+	d := dt.counters.Update(key, total, success)
+	if d.Reset {
+		*ds = debounceState{}
+	}
+
+	// Failures that come before the streak reaches the threshold are
+	// suppressed.
+	failures := d.Total - d.Success
+	if toSuppress := dt.consecutive - 1 - ds.consecutiveFailures; toSuppress > 0 {
+		if toSuppress > failures {
+			toSuppress = failures
+		}
+		ds.suppressed += toSuppress
+	}
+
+	if d.Success > 0 {
+		ds.consecutiveFailures = 0
+	} else {
+		ds.consecutiveFailures += failures
+	}
+	return ds.suppressed
+}
+
+func (dt *debounceTracker) process(em *metrics.EventMetrics) *metrics.EventMetrics {
+	total, success, ok := metrics.SuccessCounters(em)
+	if !ok {
+		return em
+	}
+
+	suppressed := dt.update(em.LabelsKey(), total, success)
+
+	newEM := metrics.NewEventMetrics(em.Timestamp)
+	newEM.Kind, newEM.LatencyUnit = em.Kind, em.LatencyUnit
+	for _, k := range em.MetricsKeys() {
+		if k != "success" {
+			newEM.AddMetric(k, em.Metric(k).Clone())
+			continue
+		}
+		newEM.AddMetric("success", metrics.NewInt(success+suppressed))
+		newEM.AddMetric("raw_success", metrics.NewInt(success))
+	}
+	for _, k := range em.LabelsKeys() {
+		newEM.AddLabel(k, em.Label(k))
+	}
+	return newEM
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+func debounceTestEM(total, success int64) *metrics.EventMetrics {
+	return metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(total)).
+		AddMetric("success", metrics.NewInt(success)).
+		AddMetric("latency", metrics.NewFloat(1.5)).
+		AddLabel("probe", "p1").
+		AddLabel("dst", "t1")
+}
+
+func TestDebounceTracker(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		results     []bool // Per-run results, reported after each run.
+		wantSuccess []int64
+	}{
+		{
+			desc:        "alternating",
+			results:     []bool{false, true, false, false, true, false, true},
+			wantSuccess: []int64{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			desc:        "sustained",
+			results:     []bool{true, false, false, false, false, true, false},
+			wantSuccess: []int64{1, 2, 3, 3, 3, 4, 5},
+		},
+		{
+			desc:        "sustained_from_start",
+			results:     []bool{false, false, false, false},
+			wantSuccess: []int64{1, 2, 2, 2},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dt := newDebounceTracker(3)
+
+			var total, rawSuccess int64
+			var gotSuccess []int64
+			for _, ok := range test.results {
+				total++
+				if ok {
+					rawSuccess++
+				}
+				em := dt.process(debounceTestEM(total, rawSuccess))
+				gotSuccess = append(gotSuccess, em.Metric("success").(metrics.NumValue).Int64())
+
+				if got := em.Metric("raw_success").(metrics.NumValue).Int64(); got != rawSuccess {
+					t.Errorf("raw_success=%d, want=%d", got, rawSuccess)
+				}
+				if em.Label("dst") != "t1" || em.Metric("latency") == nil {
+					t.Errorf("Labels or metrics missing in: %s", em.String())
+				}
+			}
+
+			if !reflect.DeepEqual(gotSuccess, test.wantSuccess) {
+				t.Errorf("Debounced success=%v, want=%v", gotSuccess, test.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestDebounceTrackerMultipleRunsPerReport(t *testing.T) {
+	dt := newDebounceTracker(3)
+
+	for _, test := range []struct {
+		total, success int64
+		wantSuccess    int64
+	}{
+		// 5 failures: first 2 suppressed.
+		{total: 5, success: 0, wantSuccess: 2},
+		// 2 failures followed by a success: streak continues, no suppression.
+		{total: 8, success: 1, wantSuccess: 3},
+		// 2 failures after success: suppressed.
+		{total: 10, success: 1, wantSuccess: 5},
+		// Counters reset.
+		{total: 1, success: 0, wantSuccess: 1},
+	} {
+		em := dt.process(debounceTestEM(test.total, test.success))
+		if got := em.Metric("success").(metrics.NumValue).Int64(); got != test.wantSuccess {
+			t.Errorf("total=%d, success=%d: debounced success=%d, want=%d", test.total, test.success, got, test.wantSuccess)
+		}
+	}
+}
+
+func TestDebounceTrackerPassThrough(t *testing.T) {
+	dt := newDebounceTracker(3)
+
+	gaugeEM := debounceTestEM(2, 0)
+	gaugeEM.Kind = metrics.GAUGE
+	noSuccessEM := metrics.NewEventMetrics(time.Now()).AddMetric("total", metrics.NewInt(2))
+
+	for _, em := range []*metrics.EventMetrics{gaugeEM, noSuccessEM} {
+		if got := dt.process(em); got != em {
+			t.Errorf("EventMetrics modified: %s", got.String())
+		}
+	}
+}
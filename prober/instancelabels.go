@@ -0,0 +1,82 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"fmt"
+
+	configpb "github.com/cloudprober/cloudprober/config/proto"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// cloudMetadataVars maps the cloud metadata to the sysvars that carry it, on
+// GCE and EC2 respectively.
+var cloudMetadataVars = map[configpb.InstanceLabel_CloudMetadata][]string{
+	configpb.InstanceLabel_ZONE:   {"zone", "EC2_AvailabilityZone"},
+	configpb.InstanceLabel_REGION: {"region", "EC2_Region"},
+}
+
+// instanceLabel is a label added to all the EventMetrics.
+type instanceLabel struct {
+	key, value string
+}
+
+// buildInstanceLabels builds the instance labels from the config, looking up
+// cloud metadata in the given system variables. Labels with cloud metadata
+// that is not available are skipped.
+func buildInstanceLabels(c []*configpb.InstanceLabel, vars map[string]string, l *logger.Logger) ([]instanceLabel, error) {
+	var labels []instanceLabel
+	for _, il := range c {
+		switch il.GetValueSource().(type) {
+		case *configpb.InstanceLabel_Value:
+			labels = append(labels, instanceLabel{il.GetKey(), il.GetValue()})
+		case *configpb.InstanceLabel_CloudMetadata_:
+			var value string
+			for _, v := range cloudMetadataVars[il.GetCloudMetadata()] {
+				if value = vars[v]; value != "" {
+					break
+				}
+			}
+			if value == "" {
+				l.Warningf("Cloud metadata %s not available, not adding the instance label: %s", il.GetCloudMetadata(), il.GetKey())
+				continue
+			}
+			labels = append(labels, instanceLabel{il.GetKey(), value})
+		default:
+			return nil, fmt.Errorf("instance_label %s: neither value nor cloud_metadata is set", il.GetKey())
+		}
+	}
+	return labels, nil
+}
+
+// addInstanceLabels returns a copy of the EventMetrics with the instance
+// labels added. Labels already set on the EventMetrics are not overridden.
+func addInstanceLabels(em *metrics.EventMetrics, labels []instanceLabel) *metrics.EventMetrics {
+	if len(labels) == 0 {
+		return em
+	}
+	existing := make(map[string]bool)
+	for _, k := range em.LabelsKeys() {
+		existing[k] = true
+	}
+	em = em.Clone()
+	for _, il := range labels {
+		if !existing[il.key] {
+			em.AddLabel(il.key, il.value)
+		}
+	}
+	return em
+}
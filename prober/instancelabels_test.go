@@ -0,0 +1,116 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/config/proto"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers"
+	"github.com/golang/protobuf/proto"
+)
+
+func testInstanceLabelsConf() []*configpb.InstanceLabel {
+	return []*configpb.InstanceLabel{
+		{
+			Key:         proto.String("env"),
+			ValueSource: &configpb.InstanceLabel_Value{Value: "prod"},
+		},
+		{
+			Key:         proto.String("zone"),
+			ValueSource: &configpb.InstanceLabel_CloudMetadata_{CloudMetadata: configpb.InstanceLabel_ZONE},
+		},
+		{
+			Key:         proto.String("region"),
+			ValueSource: &configpb.InstanceLabel_CloudMetadata_{CloudMetadata: configpb.InstanceLabel_REGION},
+		},
+	}
+}
+
+func TestBuildInstanceLabels(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		vars map[string]string
+		want []instanceLabel
+	}{
+		{
+			desc: "gce",
+			vars: map[string]string{"zone": "us-east1-b", "region": "us-east1"},
+			want: []instanceLabel{{"env", "prod"}, {"zone", "us-east1-b"}, {"region", "us-east1"}},
+		},
+		{
+			desc: "ec2",
+			vars: map[string]string{"EC2_AvailabilityZone": "us-west-2a", "EC2_Region": "us-west-2"},
+			want: []instanceLabel{{"env", "prod"}, {"zone", "us-west-2a"}, {"region", "us-west-2"}},
+		},
+		{
+			desc: "no_cloud_metadata",
+			vars: map[string]string{"hostname": "host1"},
+			want: []instanceLabel{{"env", "prod"}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := buildInstanceLabels(testInstanceLabelsConf(), test.vars, &logger.Logger{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Got instance labels: %v, want: %v", got, test.want)
+			}
+		})
+	}
+
+	// Label without a value source.
+	if _, err := buildInstanceLabels([]*configpb.InstanceLabel{{Key: proto.String("env")}}, nil, &logger.Logger{}); err == nil {
+		t.Errorf("Expected error for the instance label without a value")
+	}
+}
+
+func TestInstanceLabelsOnEmittedMetrics(t *testing.T) {
+	s := &bufferingSurfacer{unblock: make(chan struct{})}
+	close(s.unblock)
+	pr := &Prober{
+		Surfacers: []*surfacers.SurfacerInfo{{Surfacer: s}},
+		instanceLabels: []instanceLabel{
+			{"env", "prod"},
+			{"region", "us-east1"},
+		},
+	}
+
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(1)).
+		AddLabel("probe", "p1").
+		AddLabel("region", "europe-west1") // Probe label wins.
+	pr.writeEM(em)
+
+	if len(s.buf) != 1 {
+		t.Fatalf("Got %d EventMetrics, want 1", len(s.buf))
+	}
+	got := s.buf[0]
+	for k, want := range map[string]string{"probe": "p1", "env": "prod", "region": "europe-west1"} {
+		if got.Label(k) != want {
+			t.Errorf("Got label %s=%q, want: %q", k, got.Label(k), want)
+		}
+	}
+
+	// Original EventMetrics is not modified.
+	if em.Label("env") != "" {
+		t.Errorf("Instance labels added to the original EventMetrics: %s", em.String())
+	}
+}
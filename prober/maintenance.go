@@ -0,0 +1,172 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// maintenanceTracker keeps track of the probes in maintenance. Its zero value
+// is ready to use.
+type maintenanceTracker struct {
+	mu sync.Mutex
+	// Maintenance expiry time, keyed by the probe name. Zero time means that
+	// maintenance doesn't expire.
+	expiry map[string]time.Time
+}
+
+func (mt *maintenanceTracker) set(probe string, expiry time.Time) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.expiry == nil {
+		mt.expiry = make(map[string]time.Time)
+	}
+	mt.expiry[probe] = expiry
+}
+
+// clear takes the probe out of maintenance. It returns false if probe was not
+// in maintenance.
+func (mt *maintenanceTracker) clear(probe string) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	_, ok := mt.expiry[probe]
+	delete(mt.expiry, probe)
+	return ok
+}
+
+// expireLocked clears the probe's maintenance if it has expired at the given
+// time, and returns true if the probe is still in maintenance.
+func (mt *maintenanceTracker) expireLocked(probe string, ts time.Time) bool {
+	expiry, ok := mt.expiry[probe]
+	if !ok {
+		return false
+	}
+	if !expiry.IsZero() && !ts.Before(expiry) {
+		delete(mt.expiry, probe)
+		return false
+	}
+	return true
+}
+
+// active returns true if the probe is in maintenance at the given time.
+func (mt *maintenanceTracker) active(probe string, ts time.Time) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	return mt.expireLocked(probe, ts)
+}
+
+// list returns the probes in maintenance at the given time, along with their
+// expiry times.
+func (mt *maintenanceTracker) list(ts time.Time) map[string]time.Time {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	result := make(map[string]time.Time)
+	for probe := range mt.expiry {
+		if mt.expireLocked(probe, ts) {
+			result[probe] = mt.expiry[probe]
+		}
+	}
+	return result
+}
+
+// maintenanceCounters tracks the cumulative counters of an EventMetrics
+// series across maintenance windows.
+type maintenanceCounters struct {
+	last *metrics.EventMetrics // Last EventMetrics seen.
+	// Counters accumulated during maintenance windows so far.
+	inMaintenance *metrics.EventMetrics
+}
+
+// maintenanceProcessor labels the EventMetrics generated while the probe is in
+// maintenance with maintenance=true. For the cumulative metrics, results
+// generated in maintenance are exported as a separate series, and are taken
+// out of the regular series, so that failures during maintenance don't count
+// against the probe's success.
+type maintenanceProcessor struct {
+	probe string
+	mt    *maintenanceTracker
+	l     *logger.Logger
+
+	// Keyed by EventMetrics.Key().
+	counters map[string]*maintenanceCounters
+}
+
+func newMaintenanceProcessor(probe string, mt *maintenanceTracker, l *logger.Logger) *maintenanceProcessor {
+	return &maintenanceProcessor{
+		probe:    probe,
+		mt:       mt,
+		l:        l,
+		counters: make(map[string]*maintenanceCounters),
+	}
+}
+
+func (mp *maintenanceProcessor) process(em *metrics.EventMetrics) *metrics.EventMetrics {
+	active := mp.mt.active(mp.probe, em.Timestamp)
+
+	if em.Kind != metrics.CUMULATIVE {
+		if active {
+			return em.Clone().AddLabel("maintenance", "true")
+		}
+		return em
+	}
+
+	mc := mp.counters[em.Key()]
+	if mc == nil {
+		mc = &maintenanceCounters{}
+		mp.counters[em.Key()] = mc
+	}
+	last := mc.last
+	mc.last = em.Clone()
+
+	if !active {
+		if mc.inMaintenance == nil {
+			return em
+		}
+		newEM, err := em.SubtractLast(mc.inMaintenance)
+		if err != nil {
+			mp.l.Warningf("Error subtracting maintenance metrics, exporting metrics as it is: %v", err)
+			return em
+		}
+		newEM.Kind = metrics.CUMULATIVE
+		return newEM
+	}
+
+	// Counters accumulated since the last EventMetrics, all of which we
+	// attribute to the maintenance.
+	delta := em.Clone()
+	if last != nil {
+		var err error
+		if delta, err = em.SubtractLast(last); err != nil {
+			mp.l.Warningf("Error computing maintenance metrics, exporting metrics as it is: %v", err)
+			return em.Clone().AddLabel("maintenance", "true")
+		}
+		delta.Kind = metrics.CUMULATIVE
+	}
+
+	if mc.inMaintenance == nil {
+		mc.inMaintenance = delta
+	} else if err := mc.inMaintenance.Update(delta); err != nil {
+		mp.l.Warningf("Error updating maintenance metrics: %v", err)
+		mc.inMaintenance = delta
+	}
+
+	newEM := mc.inMaintenance.Clone().AddLabel("maintenance", "true")
+	newEM.Timestamp = em.Timestamp
+	return newEM
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	pb "github.com/cloudprober/cloudprober/prober/proto"
+	"github.com/cloudprober/cloudprober/probes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMaintenanceProcessor(t *testing.T) {
+	start := time.Now()
+	mt := &maintenanceTracker{}
+	mp := newMaintenanceProcessor("p1", mt, nil)
+
+	testEM := func(ts time.Time, total, success int64) *metrics.EventMetrics {
+		return metrics.NewEventMetrics(ts).
+			AddMetric("total", metrics.NewInt(total)).
+			AddMetric("success", metrics.NewInt(success)).
+			AddLabel("probe", "p1")
+	}
+
+	for _, test := range []struct {
+		desc                   string
+		em                     *metrics.EventMetrics
+		setMaintenance         bool
+		expiry                 time.Time
+		wantMaintenance        bool
+		wantTotal, wantSuccess int64
+	}{
+		{
+			desc:        "before_maintenance",
+			em:          testEM(start, 10, 10),
+			wantTotal:   10,
+			wantSuccess: 10,
+		},
+		{
+			desc:            "in_maintenance",
+			em:              testEM(start.Add(10*time.Second), 15, 11),
+			setMaintenance:  true,
+			expiry:          start.Add(time.Minute),
+			wantMaintenance: true,
+			wantTotal:       5,
+			wantSuccess:     1,
+		},
+		{
+			desc:            "still_in_maintenance",
+			em:              testEM(start.Add(20*time.Second), 20, 12),
+			wantMaintenance: true,
+			wantTotal:       10,
+			wantSuccess:     2,
+		},
+		{
+			// Maintenance expired, counters exclude the maintenance results.
+			desc:        "after_expiry",
+			em:          testEM(start.Add(time.Minute), 25, 17),
+			wantTotal:   15,
+			wantSuccess: 15,
+		},
+		{
+			desc:            "maintenance_without_expiry",
+			em:              testEM(start.Add(70*time.Second), 30, 17),
+			setMaintenance:  true,
+			wantMaintenance: true,
+			wantTotal:       15,
+			wantSuccess:     2,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.setMaintenance {
+				mt.set("p1", test.expiry)
+			}
+
+			em := mp.process(test.em)
+
+			if got := em.Label("maintenance") == "true"; got != test.wantMaintenance {
+				t.Errorf("%s: maintenance label=%v, want=%v", em.String(), got, test.wantMaintenance)
+			}
+			if em.Kind != metrics.CUMULATIVE {
+				t.Errorf("%s: kind=%v, want CUMULATIVE", em.String(), em.Kind)
+			}
+			total := em.Metric("total").(metrics.NumValue).Int64()
+			success := em.Metric("success").(metrics.NumValue).Int64()
+			if total != test.wantTotal || success != test.wantSuccess {
+				t.Errorf("%s: total=%d, success=%d, want total=%d, success=%d", em.String(), total, success, test.wantTotal, test.wantSuccess)
+			}
+		})
+	}
+
+	// Maintenance expired above should have been cleared.
+	if _, ok := mt.list(start.Add(80 * time.Second))["p1"]; !ok {
+		t.Errorf("Maintenance without expiry not found")
+	}
+	mt.clear("p1")
+	if em := mp.process(testEM(start.Add(80*time.Second), 35, 22)); em.Label("maintenance") != "" {
+		t.Errorf("Got maintenance label after clearing maintenance: %s", em.String())
+	}
+}
+
+func TestMaintenanceGaugeMetrics(t *testing.T) {
+	mt := &maintenanceTracker{}
+	mp := newMaintenanceProcessor("p1", mt, nil)
+	mt.set("p1", time.Time{})
+
+	em := metrics.NewEventMetrics(time.Now()).AddMetric("latency", metrics.NewFloat(1.5))
+	em.Kind = metrics.GAUGE
+
+	if got := mp.process(em); got.Label("maintenance") != "true" {
+		t.Errorf("Gauge metrics in maintenance not labeled: %s", got.String())
+	}
+	if em.Label("maintenance") != "" {
+		t.Errorf("Original EventMetrics modified: %s", em.String())
+	}
+}
+
+func TestMaintenanceAlerting(t *testing.T) {
+	a := newAlerter("p1", nil, nil)
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(10)).
+		AddMetric("success", metrics.NewInt(0)).
+		AddLabel("dst", "t1").
+		AddLabel("maintenance", "true")
+	a.process(em)
+	if len(a.states) != 0 {
+		t.Errorf("Alerter processed metrics in maintenance: %v", a.states)
+	}
+}
+
+func TestMaintenanceService(t *testing.T) {
+	ctx := context.Background()
+	pr := testProber()
+	pr.Probes["p1"] = &probes.ProbeInfo{Name: "p1"}
+	pr.Probes["p2"] = &probes.ProbeInfo{Name: "p2"}
+
+	listMaintenance := func() map[string]int64 {
+		t.Helper()
+		resp, err := pr.ListMaintenance(ctx, &pb.ListMaintenanceRequest{})
+		if err != nil {
+			t.Fatalf("ListMaintenance: unexpected error: %v", err)
+		}
+		result := make(map[string]int64)
+		for _, m := range resp.GetMaintenance() {
+			result[m.GetProbeName()] = m.GetExpiryUnixSec()
+		}
+		return result
+	}
+
+	// Errors.
+	for _, test := range []struct {
+		req      *pb.SetMaintenanceRequest
+		wantCode codes.Code
+	}{
+		{req: &pb.SetMaintenanceRequest{}, wantCode: codes.InvalidArgument},
+		{req: &pb.SetMaintenanceRequest{ProbeName: proto.String("p3")}, wantCode: codes.NotFound},
+		{req: &pb.SetMaintenanceRequest{ProbeName: proto.String("p1"), DurationSec: proto.Int32(-1)}, wantCode: codes.InvalidArgument},
+	} {
+		if _, err := pr.SetMaintenance(ctx, test.req); status.Code(err) != test.wantCode {
+			t.Errorf("SetMaintenance(%v): got error %v, want code %v", test.req, err, test.wantCode)
+		}
+	}
+
+	resp, err := pr.SetMaintenance(ctx, &pb.SetMaintenanceRequest{ProbeName: proto.String("p1")})
+	if err != nil {
+		t.Fatalf("SetMaintenance: unexpected error: %v", err)
+	}
+	if resp.ExpiryUnixSec != nil {
+		t.Errorf("Got expiry %d for maintenance without duration", resp.GetExpiryUnixSec())
+	}
+	resp, err = pr.SetMaintenance(ctx, &pb.SetMaintenanceRequest{ProbeName: proto.String("p2"), DurationSec: proto.Int32(1)})
+	if err != nil {
+		t.Fatalf("SetMaintenance: unexpected error: %v", err)
+	}
+
+	got := listMaintenance()
+	if len(got) != 2 || got["p1"] != 0 || got["p2"] != resp.GetExpiryUnixSec() {
+		t.Errorf("Got maintenance: %v, want: p1 without expiry, p2 with expiry %d", got, resp.GetExpiryUnixSec())
+	}
+	if !pr.maintenance.active("p1", time.Now()) || !pr.maintenance.active("p2", time.Now()) {
+		t.Errorf("Probes not in maintenance after SetMaintenance")
+	}
+
+	// p2's maintenance is cleared automatically once it expires.
+	time.Sleep(1100 * time.Millisecond)
+	if got := listMaintenance(); len(got) != 1 || got["p1"] != 0 {
+		t.Errorf("Got maintenance: %v, want only p1", got)
+	}
+	if pr.maintenance.active("p2", time.Now()) {
+		t.Errorf("Probe p2 in maintenance after expiry")
+	}
+
+	if _, err := pr.ClearMaintenance(ctx, &pb.ClearMaintenanceRequest{ProbeName: proto.String("p1")}); err != nil {
+		t.Errorf("ClearMaintenance: unexpected error: %v", err)
+	}
+	if _, err := pr.ClearMaintenance(ctx, &pb.ClearMaintenanceRequest{ProbeName: proto.String("p1")}); status.Code(err) != codes.NotFound {
+		t.Errorf("ClearMaintenance for probe not in maintenance: got error %v, want NotFound", err)
+	}
+	if got := listMaintenance(); len(got) != 0 {
+		t.Errorf("Got maintenance: %v, want none", got)
+	}
+}
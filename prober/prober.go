@@ -35,6 +35,8 @@ import (
 	"github.com/cloudprober/cloudprober/metrics"
 	spb "github.com/cloudprober/cloudprober/prober/proto"
 	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/external"
 	"github.com/cloudprober/cloudprober/probes/options"
 	probes_configpb "github.com/cloudprober/cloudprober/probes/proto"
 	rdsserver "github.com/cloudprober/cloudprober/rds/server"
@@ -45,6 +47,7 @@ import (
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/targets/lameduck"
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -65,9 +68,34 @@ type Prober struct {
 	// Per-probe cancelFunc map.
 	probeCancelFunc map[string]context.CancelFunc
 
+	// Per-probe channels that are closed once the probe stops running.
+	probeDone map[string]chan struct{}
+
+	// Probes added through the gRPC service. These are not part of the config
+	// and are left alone by ReloadProbes.
+	grpcProbes map[string]bool
+
+	// Context that probes are started with. It's set by Start() and is used
+	// to start the probes added by ReloadProbes.
+	startCtx context.Context
+
 	// dataChan for passing metrics between probes and main goroutine.
 	dataChan chan *metrics.EventMetrics
 
+	// Shutdown signals the main goroutine to stop accepting new metrics and
+	// drain the surfacers through shutdownCh. drainedCh is closed once it's
+	// done.
+	shutdownOnce sync.Once
+	shutdownCh   chan struct{}
+	drainedCh    chan struct{}
+
+	// Probes in maintenance, set through the gRPC service or the web
+	// interface.
+	maintenance maintenanceTracker
+
+	// Labels added to all the EventMetrics, see instance_label in the config.
+	instanceLabels []instanceLabel
+
 	// Used by GetConfig for /config handler.
 	TextConfig string
 }
@@ -83,7 +111,25 @@ func runOnThisHost(runOn string, hostname string) (bool, error) {
 	return r.MatchString(hostname), nil
 }
 
-func (pr *Prober) addProbe(p *probes_configpb.ProbeDef) error {
+// newProbeInfo builds probe options and creates a probe from the given probe
+// definition. It doesn't modify the prober's probes database.
+func (pr *Prober) newProbeInfo(p *probes_configpb.ProbeDef) (*probes.ProbeInfo, error) {
+	opts, err := options.BuildProbeOptions(p, pr.ldLister, pr.c.GetGlobalTargetsOptions(), pr.l)
+	if err != nil {
+		return nil, status.Errorf(codes.Unknown, err.Error())
+	}
+
+	pr.l.Infof("Creating a %s probe: %s", p.GetType(), p.GetName())
+	probeInfo, err := probes.CreateProbe(p, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Unknown, err.Error())
+	}
+	return probeInfo, nil
+}
+
+// addProbe creates the given probe and adds it to the probes database.
+// grpcAdded tells whether the probe is being added through the gRPC service.
+func (pr *Prober) addProbe(p *probes_configpb.ProbeDef, grpcAdded bool) error {
 	pr.mu.Lock()
 	defer pr.mu.Unlock()
 
@@ -100,18 +146,148 @@ func (pr *Prober) addProbe(p *probes_configpb.ProbeDef) error {
 		return status.Errorf(codes.AlreadyExists, "probe %s is already defined", p.GetName())
 	}
 
-	opts, err := options.BuildProbeOptions(p, pr.ldLister, pr.c.GetGlobalTargetsOptions(), pr.l)
+	probeInfo, err := pr.newProbeInfo(p)
 	if err != nil {
-		return status.Errorf(codes.Unknown, err.Error())
+		return err
+	}
+	pr.Probes[p.GetName()] = probeInfo
+	if grpcAdded {
+		pr.grpcProbes[p.GetName()] = true
 	}
 
-	pr.l.Infof("Creating a %s probe: %s", p.GetType(), p.GetName())
-	probeInfo, err := probes.CreateProbe(p, opts)
-	if err != nil {
-		return status.Errorf(codes.Unknown, err.Error())
+	return nil
+}
+
+// closeProbe releases the resources held by a probe that is not going to run
+// anymore, e.g. its targets' background refreshing.
+func closeProbe(p *probes.ProbeInfo) {
+	if p.Options == nil {
+		return
+	}
+	if c, ok := p.Options.Targets.(targets.Closer); ok {
+		c.Close()
+	}
+}
+
+// stopProbe cancels the given probe's context and removes it from the probes
+// database. It should be called with pr.mu held.
+func (pr *Prober) stopProbe(name string) {
+	if cancelF := pr.probeCancelFunc[name]; cancelF != nil {
+		cancelF()
+	}
+	if p := pr.Probes[name]; p != nil {
+		closeProbe(p)
+	}
+	delete(pr.probeCancelFunc, name)
+	delete(pr.probeDone, name)
+	delete(pr.grpcProbes, name)
+	delete(pr.Probes, name)
+	runstats.Default().Remove(name)
+}
+
+// probeStopTimeout is how long ReloadProbes waits for a modified probe's old
+// instance to stop, before starting the new instance.
+const probeStopTimeout = 10 * time.Second
+
+// ReloadProbes updates the running probes to match the given probe
+// definitions, without disturbing the probes that didn't change. Probes are
+// matched by name:
+//   - Probes that are no longer in the config are stopped and removed.
+//   - Probes whose definition changed are stopped and recreated.
+//   - New probes are created and started.
+//   - Probes with identical definitions are left running as they are.
+//   - Probes added through the gRPC service are left running as they are.
+//
+// All new and modified probes are created before any running probe is
+// touched, so an error in the new config leaves the running probes intact.
+// Modified probes are restarted only after their old instance has stopped.
+func (pr *Prober) ReloadProbes(probeDefs []*probes_configpb.ProbeDef) error {
+	newDefs := make(map[string]*probes_configpb.ProbeDef)
+	for _, p := range probeDefs {
+		runHere, err := runOnThisHost(p.GetRunOn(), sysvars.Vars()["hostname"])
+		if err != nil {
+			return err
+		}
+		if !runHere {
+			continue
+		}
+		if newDefs[p.GetName()] != nil {
+			return fmt.Errorf("probe %s is defined more than once", p.GetName())
+		}
+		newDefs[p.GetName()] = p
+	}
+
+	pr.mu.Lock()
+
+	for name := range newDefs {
+		if pr.grpcProbes[name] {
+			pr.mu.Unlock()
+			return fmt.Errorf("probe %s conflicts with a probe added through the gRPC service", name)
+		}
+	}
+
+	var toStop []string
+	for name, probeInfo := range pr.Probes {
+		if pr.grpcProbes[name] {
+			continue
+		}
+		if p := newDefs[name]; p == nil || !proto.Equal(probeInfo.ProbeDef, p) {
+			toStop = append(toStop, name)
+		}
+	}
+
+	newProbes := make(map[string]*probes.ProbeInfo)
+	for name, p := range newDefs {
+		if probeInfo := pr.Probes[name]; probeInfo != nil && proto.Equal(probeInfo.ProbeDef, p) {
+			continue
+		}
+		probeInfo, err := pr.newProbeInfo(p)
+		if err != nil {
+			pr.mu.Unlock()
+			for _, probeInfo := range newProbes {
+				closeProbe(probeInfo)
+			}
+			return fmt.Errorf("error creating probe %s: %v", name, err)
+		}
+		newProbes[name] = probeInfo
 	}
-	pr.Probes[p.GetName()] = probeInfo
 
+	// Probes that are being replaced by a new instance.
+	var replacedDone []chan struct{}
+	for _, name := range toStop {
+		if done := pr.probeDone[name]; done != nil && newProbes[name] != nil {
+			replacedDone = append(replacedDone, done)
+		}
+		pr.l.Infof("Stopping probe: %s", name)
+		pr.stopProbe(name)
+	}
+	for name, probeInfo := range newProbes {
+		pr.Probes[name] = probeInfo
+	}
+	startCtx := pr.startCtx
+
+	pr.mu.Unlock()
+
+	// If prober has not been started yet, new probes will be started along with
+	// the rest of the probes.
+	if startCtx == nil {
+		return nil
+	}
+
+	// Wait for the replaced probes to stop, so that the old and the new
+	// instances don't report the same metrics at the same time.
+	for _, done := range replacedDone {
+		select {
+		case <-done:
+		case <-time.After(probeStopTimeout):
+			pr.l.Warningf("Probe didn't stop within %v, starting its replacement anyway", probeStopTimeout)
+		}
+	}
+
+	for name, probeInfo := range newProbes {
+		pr.l.Infof("Starting probe: %s", name)
+		pr.startProbe(startCtx, probeInfo)
+	}
 	return nil
 }
 
@@ -146,8 +322,17 @@ func (pr *Prober) Init(ctx context.Context, cfg *configpb.ProberConfig, l *logge
 		}
 	}
 
-	// Initialize lameduck lister
 	globalTargetsOpts := pr.c.GetGlobalTargetsOptions()
+	targets.ConfigureGlobalResolver(globalTargetsOpts)
+
+	external.SetMaxConcurrentCommands(int(pr.c.GetMaxConcurrentExternalCommands()))
+
+	var err error
+	if pr.instanceLabels, err = buildInstanceLabels(pr.c.GetInstanceLabel(), sysvars.Vars(), pr.l); err != nil {
+		return err
+	}
+
+	// Initialize lameduck lister
 
 	if globalTargetsOpts.GetLameDuckOptions() != nil {
 		ldLogger, err := logger.NewCloudproberLog("lame-duck")
@@ -165,8 +350,6 @@ func (pr *Prober) Init(ctx context.Context, cfg *configpb.ProberConfig, l *logge
 		}
 	}
 
-	var err error
-
 	// Initialize shared targets
 	for _, st := range pr.c.GetSharedTargets() {
 		tgts, err := targets.New(st.GetTargets(), pr.ldLister, globalTargetsOpts, pr.l, pr.l)
@@ -179,8 +362,10 @@ func (pr *Prober) Init(ctx context.Context, cfg *configpb.ProberConfig, l *logge
 	// Initiliaze probes
 	pr.Probes = make(map[string]*probes.ProbeInfo)
 	pr.probeCancelFunc = make(map[string]context.CancelFunc)
+	pr.probeDone = make(map[string]chan struct{})
+	pr.grpcProbes = make(map[string]bool)
 	for _, p := range pr.c.GetProbe() {
-		if err := pr.addProbe(p); err != nil {
+		if err := pr.addProbe(p, false); err != nil {
 			return err
 		}
 	}
@@ -203,22 +388,28 @@ func (pr *Prober) Init(ctx context.Context, cfg *configpb.ProberConfig, l *logge
 func (pr *Prober) Start(ctx context.Context) {
 	pr.dataChan = make(chan *metrics.EventMetrics, 100000)
 
+	// Probes added or modified by ReloadProbes from now on are started by
+	// ReloadProbes itself.
+	pr.mu.Lock()
+	pr.startCtx = ctx
+	probeInfos := make([]*probes.ProbeInfo, 0, len(pr.Probes))
+	for _, p := range pr.Probes {
+		probeInfos = append(probeInfos, p)
+	}
+	pr.mu.Unlock()
+
+	pr.shutdownCh = make(chan struct{})
+	pr.drainedCh = make(chan struct{})
+
 	go func() {
-		var em *metrics.EventMetrics
 		for {
-			em = <-pr.dataChan
-			var s = em.String()
-			if len(s) > logger.MaxLogEntrySize {
-				glog.Warningf("Metric entry for timestamp %v dropped due to large size: %d", em.Timestamp, len(s))
-				continue
-			}
-
-			// Replicate the surfacer message to every surfacer we have
-			// registered. Note that s.Write() is expected to be
-			// non-blocking to avoid blocking of EventMetrics message
-			// processing.
-			for _, surfacer := range pr.Surfacers {
-				surfacer.Write(context.Background(), em)
+			select {
+			case em := <-pr.dataChan:
+				pr.writeEM(em)
+			case <-pr.shutdownCh:
+				pr.drain()
+				close(pr.drainedCh)
+				return
 			}
 		}
 	}()
@@ -226,17 +417,20 @@ func (pr *Prober) Start(ctx context.Context) {
 	// Start a goroutine to export system variables
 	go sysvars.Start(ctx, pr.dataChan, time.Millisecond*time.Duration(pr.c.GetSysvarsIntervalMsec()), pr.c.GetSysvarsEnvVar())
 
+	// Start a goroutine to export surfacers' own stats, e.g. filtered metrics.
+	go surfacers.ExportStats(ctx, pr.Surfacers, pr.dataChan, time.Millisecond*time.Duration(pr.c.GetSysvarsIntervalMsec()))
+
 	// Start servers, each in its own goroutine
 	for _, s := range pr.Servers {
 		go s.Start(ctx, pr.dataChan)
 	}
 
 	if pr.c.GetDisableJitter() {
-		for name := range pr.Probes {
-			go pr.startProbe(ctx, name)
+		for _, p := range probeInfos {
+			go pr.startProbe(ctx, p)
 		}
 	} else {
-		pr.startProbesWithJitter(ctx)
+		pr.startProbesWithJitter(ctx, probeInfos)
 	}
 	if runconfig.DefaultGRPCServer() != nil {
 		// Start a goroutine to handle starting of the probes added through gRPC.
@@ -247,20 +441,101 @@ func (pr *Prober) Start(ctx context.Context) {
 			for {
 				select {
 				case name := <-pr.grpcStartProbeCh:
-					pr.startProbe(ctx, name)
+					pr.startProbeByName(ctx, name)
 				}
 			}
 		}()
 	}
 }
 
-func (pr *Prober) startProbe(ctx context.Context, name string) {
+// writeEM writes the EventMetrics to all the surfacers, after adding the
+// instance labels to it.
+func (pr *Prober) writeEM(em *metrics.EventMetrics) {
+	var s = em.String()
+	if len(s) > logger.MaxLogEntrySize {
+		glog.Warningf("Metric entry for timestamp %v dropped due to large size: %d", em.Timestamp, len(s))
+		return
+	}
+
+	runstats.Default().Update(em)
+
+	em = addInstanceLabels(em, pr.instanceLabels)
+
+	// Replicate the surfacer message to every surfacer we have
+	// registered. Note that s.Write() is expected to be
+	// non-blocking to avoid blocking of EventMetrics message
+	// processing.
+	for _, surfacer := range pr.Surfacers {
+		surfacer.Write(context.Background(), em)
+	}
+}
+
+// drain writes the metrics that are still queued in the data channel to the
+// surfacers, and then closes the surfacers that buffer data internally, which
+// flushes their buffers.
+func (pr *Prober) drain() {
+	for draining := true; draining; {
+		select {
+		case em := <-pr.dataChan:
+			pr.writeEM(em)
+		default:
+			draining = false
+		}
+	}
+
+	for _, s := range pr.Surfacers {
+		if c, ok := s.Surfacer.(surfacers.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// Shutdown stops accepting new metrics, writes the already queued metrics to
+// the surfacers and flushes them, waiting for up to gracePeriod for that to
+// finish. It returns false if draining didn't finish within the grace period.
+// Probes should be stopped, by canceling the Start context, before calling
+// Shutdown, and Shutdown should be called only once Start has returned.
+func (pr *Prober) Shutdown(gracePeriod time.Duration) bool {
+	pr.shutdownOnce.Do(func() { close(pr.shutdownCh) })
+
+	select {
+	case <-pr.drainedCh:
+		return true
+	case <-time.After(gracePeriod):
+		pr.l.Warningf("Surfacers didn't drain within the shutdown grace period (%v)", gracePeriod)
+		return false
+	}
+}
+
+// startProbe starts the given probe instance. It doesn't do anything if the
+// instance is not in the probes database anymore, e.g. if it was removed or
+// replaced by ReloadProbes while it was waiting to be started, or if it's
+// already running.
+func (pr *Prober) startProbe(ctx context.Context, p *probes.ProbeInfo) {
 	pr.mu.Lock()
 	defer pr.mu.Unlock()
 
+	if p == nil || pr.Probes[p.Name] != p || pr.probeCancelFunc[p.Name] != nil {
+		return
+	}
+
 	probeCtx, cancelFunc := context.WithCancel(ctx)
-	pr.probeCancelFunc[name] = cancelFunc
-	go pr.Probes[name].Start(probeCtx, pr.dataChan)
+	pr.probeCancelFunc[p.Name] = cancelFunc
+	done := make(chan struct{})
+	pr.probeDone[p.Name] = done
+	go func() {
+		defer close(done)
+		runProbe(probeCtx, p, pr.dataChan, &pr.maintenance, pr.l)
+	}()
+}
+
+// startProbeByName starts the probe with the given name, see startProbe.
+func (pr *Prober) startProbeByName(ctx context.Context, name string) {
+	pr.mu.Lock()
+	p := pr.Probes[name]
+	pr.mu.Unlock()
+
+	pr.startProbe(ctx, p)
 }
 
 // startProbesWithJitter try to space out probes over time, as much as possible,
@@ -269,13 +544,13 @@ func (pr *Prober) startProbe(ctx context.Context, name string) {
 // then spread out probes within that interval by introducing a delay of
 // interval / len(probes) between probes. We also introduce a random jitter
 // between different interval buckets.
-func (pr *Prober) startProbesWithJitter(ctx context.Context) {
+func (pr *Prober) startProbesWithJitter(ctx context.Context, probeInfos []*probes.ProbeInfo) {
 	// Seed random number generator.
 	rand.Seed(time.Now().UnixNano())
 
 	// Make interval -> [probe1, probe2, probe3..] map
 	intervalBuckets := make(map[time.Duration][]*probes.ProbeInfo)
-	for _, p := range pr.Probes {
+	for _, p := range probeInfos {
 		intervalBuckets[p.Options.Interval] = append(intervalBuckets[p.Options.Interval], p)
 	}
 
@@ -290,7 +565,7 @@ func (pr *Prober) startProbesWithJitter(ctx context.Context) {
 			// Spread out probes evenly with an interval bucket.
 			for _, p := range probeInfos {
 				pr.l.Info("Starting probe: ", p.Name)
-				go pr.startProbe(ctx, p.Name)
+				go pr.startProbe(ctx, p)
 				time.Sleep(interProbeDelay)
 			}
 		}(interval, probeInfos)
@@ -0,0 +1,417 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	pb "github.com/cloudprober/cloudprober/prober/proto"
+	"github.com/cloudprober/cloudprober/probes"
+	probes_configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/cloudprober/cloudprober/surfacers"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	targetspb "github.com/cloudprober/cloudprober/targets/proto"
+	targetstestdatapb "github.com/cloudprober/cloudprober/targets/testdata"
+	"google.golang.org/protobuf/proto"
+)
+
+// closableTargets are extension targets that record whether they have been
+// closed.
+type closableTargets struct {
+	name   string
+	mu     sync.Mutex
+	closed bool
+}
+
+func (ct *closableTargets) ListEndpoints() []endpoint.Endpoint { return nil }
+
+func (ct *closableTargets) Resolve(name string, ipVer int) (net.IP, error) {
+	return nil, nil
+}
+
+func (ct *closableTargets) Close() {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.closed = true
+}
+
+func (ct *closableTargets) isClosed() bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.closed
+}
+
+var (
+	closableTargetsMu   sync.Mutex
+	closableTargetsList []*closableTargets // All the closable targets created.
+)
+
+func init() {
+	targets.RegisterTargetsType(201, func(conf interface{}, l *logger.Logger) (targets.Targets, error) {
+		closableTargetsMu.Lock()
+		defer closableTargetsMu.Unlock()
+		ct := &closableTargets{name: conf.(*targetstestdatapb.AnotherFancyTargets).GetName()}
+		closableTargetsList = append(closableTargetsList, ct)
+		return ct, nil
+	})
+}
+
+// closableProbeDef returns a test probe definition with closable targets,
+// named after the probe.
+func closableProbeDef(name string) *probes_configpb.ProbeDef {
+	probeDef := testProbeDef(name)
+	probeDef.Targets = &targetspb.TargetsDef{}
+	proto.SetExtension(probeDef.Targets, targetstestdatapb.E_AnotherFancyTargets, &targetstestdatapb.AnotherFancyTargets{Name: proto.String(name)})
+	return probeDef
+}
+
+// closedTargets returns the closed state of all the closable targets created
+// for the given probe, in order of their creation.
+func closedTargets(name string) []bool {
+	closableTargetsMu.Lock()
+	defer closableTargetsMu.Unlock()
+
+	var closed []bool
+	for _, ct := range closableTargetsList {
+		if ct.name == name {
+			closed = append(closed, ct.isClosed())
+		}
+	}
+	return closed
+}
+
+// verifyNoStatusChange verifies that probe's running status doesn't change
+// for a short while.
+func verifyNoStatusChange(t *testing.T, p *testProbe) {
+	t.Helper()
+
+	select {
+	case running := <-p.runningStatusCh:
+		t.Errorf("Unexpected probe running status change: %v", running)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReloadProbes(t *testing.T) {
+	pr := testProber()
+	pr.startCtx = context.Background()
+
+	initialDefs := []*probes_configpb.ProbeDef{
+		testProbeDef("unchanged"),
+		testProbeDef("modified"),
+		testProbeDef("removed"),
+	}
+	if err := pr.ReloadProbes(initialDefs); err != nil {
+		t.Fatalf("Error loading initial probes: %v", err)
+	}
+
+	oldProbes := make(map[string]*testProbe)
+	for _, name := range []string{"unchanged", "modified", "removed"} {
+		oldProbes[name] = pr.Probes[name].Probe.(*testProbe)
+		verifyProbeRunningStatus(t, oldProbes[name], true)
+	}
+
+	modifiedDef := testProbeDef("modified")
+	modifiedDef.IntervalMsec = proto.Int32(5000)
+
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{
+		testProbeDef("unchanged"),
+		modifiedDef,
+		testProbeDef("added"),
+	}); err != nil {
+		t.Fatalf("Error reloading probes: %v", err)
+	}
+
+	if len(pr.Probes) != 3 {
+		t.Errorf("Got %d probes after reload, want 3", len(pr.Probes))
+	}
+
+	// Unchanged probe should be the same instance and should keep running.
+	if pr.Probes["unchanged"].Probe.(*testProbe) != oldProbes["unchanged"] {
+		t.Errorf("Unchanged probe was recreated")
+	}
+	verifyNoStatusChange(t, oldProbes["unchanged"])
+
+	// Removed probe should be stopped.
+	if pr.Probes["removed"] != nil {
+		t.Errorf("Removed probe is still in the probes database")
+	}
+	verifyProbeRunningStatus(t, oldProbes["removed"], false)
+
+	// Modified probe should be stopped before it's recreated with the new
+	// config.
+	select {
+	case running := <-oldProbes["modified"].runningStatusCh:
+		if running {
+			t.Errorf("Modified probe's old instance is still running")
+		}
+	default:
+		t.Errorf("Modified probe's old instance didn't stop before reload returned")
+	}
+	newModified := pr.Probes["modified"].Probe.(*testProbe)
+	if newModified == oldProbes["modified"] {
+		t.Errorf("Modified probe was not recreated")
+	}
+	if !proto.Equal(pr.Probes["modified"].ProbeDef, modifiedDef) {
+		t.Errorf("Modified probe's definition: %v, want: %v", pr.Probes["modified"].ProbeDef, modifiedDef)
+	}
+	verifyProbeRunningStatus(t, newModified, true)
+
+	// Added probe should be running.
+	verifyProbeRunningStatus(t, pr.Probes["added"].Probe.(*testProbe), true)
+}
+
+func TestReloadProbesClosesTargets(t *testing.T) {
+	pr := testProber()
+	pr.startCtx = context.Background()
+
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{
+		closableProbeDef("ct_unchanged"),
+		closableProbeDef("ct_modified"),
+		closableProbeDef("ct_removed"),
+	}); err != nil {
+		t.Fatalf("Error loading initial probes: %v", err)
+	}
+
+	modifiedDef := closableProbeDef("ct_modified")
+	modifiedDef.IntervalMsec = proto.Int32(5000)
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{
+		closableProbeDef("ct_unchanged"),
+		modifiedDef,
+	}); err != nil {
+		t.Fatalf("Error reloading probes: %v", err)
+	}
+
+	for name, want := range map[string][]bool{
+		"ct_unchanged": {false},
+		"ct_modified":  {true, false},
+		"ct_removed":   {true},
+	} {
+		if got := closedTargets(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("Probe %s: targets closed=%v, want=%v", name, got, want)
+		}
+	}
+}
+
+func TestReloadProbesKeepsGRPCProbes(t *testing.T) {
+	pr := testProber()
+	pr.startCtx = context.Background()
+
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("config_probe")}); err != nil {
+		t.Fatalf("Error loading initial probes: %v", err)
+	}
+	if _, err := pr.AddProbe(context.Background(), &pb.AddProbeRequest{ProbeConfig: testProbeDef("grpc_probe")}); err != nil {
+		t.Fatalf("Error adding probe through gRPC: %v", err)
+	}
+	p := pr.Probes["grpc_probe"].Probe.(*testProbe)
+	verifyProbeRunningStatus(t, p, true)
+
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("config_probe2")}); err != nil {
+		t.Fatalf("Error reloading probes: %v", err)
+	}
+	if pr.Probes["grpc_probe"] == nil || pr.Probes["config_probe"] != nil {
+		t.Errorf("Unexpected probes after reload: %v", pr.Probes)
+	}
+	verifyNoStatusChange(t, p)
+
+	// Config can't define a probe with the same name as a gRPC-added probe.
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("grpc_probe")}); err == nil {
+		t.Errorf("Expected error for a probe conflicting with a gRPC-added probe")
+	}
+
+	// Once removed through gRPC, probe name can be used in the config.
+	if _, err := pr.RemoveProbe(context.Background(), &pb.RemoveProbeRequest{ProbeName: proto.String("grpc_probe")}); err != nil {
+		t.Fatalf("Error removing probe: %v", err)
+	}
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("grpc_probe")}); err != nil {
+		t.Errorf("Error reloading probes: %v", err)
+	}
+}
+
+func TestReloadProbesError(t *testing.T) {
+	pr := testProber()
+	pr.startCtx = context.Background()
+
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("probe1")}); err != nil {
+		t.Fatalf("Error loading initial probes: %v", err)
+	}
+	p := pr.Probes["probe1"].Probe.(*testProbe)
+	verifyProbeRunningStatus(t, p, true)
+
+	// Duplicate probe names should result in an error, leaving the running
+	// probes untouched.
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("probe2"), testProbeDef("probe2")}); err == nil {
+		t.Errorf("Expected error for duplicate probe names")
+	}
+
+	// A bad probe definition should result in an error, leaving the running
+	// probes untouched.
+	badDef := testProbeDef("probe2")
+	badDef.Interval = proto.String("bad-interval")
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{badDef}); err == nil {
+		t.Errorf("Expected error for bad probe definition")
+	}
+
+	if pr.Probes["probe1"] == nil || pr.Probes["probe2"] != nil {
+		t.Errorf("Probes database changed after failed reloads: %v", pr.Probes)
+	}
+	verifyNoStatusChange(t, p)
+
+	// Probes created before hitting the bad probe definition should be
+	// discarded, releasing their targets. Probes are created in random order,
+	// so we try a few times to make sure that some get created.
+	for i := 0; i < 10; i++ {
+		if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{closableProbeDef("ct_discarded"), badDef}); err == nil {
+			t.Errorf("Expected error for bad probe definition")
+		}
+	}
+	closed := closedTargets("ct_discarded")
+	if len(closed) == 0 {
+		t.Errorf("Probe ct_discarded was never created")
+	}
+	for _, c := range closed {
+		if !c {
+			t.Errorf("Discarded probe's targets were not closed: %v", closed)
+			break
+		}
+	}
+}
+
+func TestStartProbeAfterReload(t *testing.T) {
+	pr := testProber()
+
+	// Probes added before the prober is started are not started right away.
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{testProbeDef("modified"), testProbeDef("removed")}); err != nil {
+		t.Fatalf("Error loading initial probes: %v", err)
+	}
+	oldModified, oldRemoved := pr.Probes["modified"], pr.Probes["removed"]
+
+	// Reload the probes while the old instances are waiting to be started,
+	// e.g. by startProbesWithJitter.
+	pr.startCtx = context.Background()
+	modifiedDef := testProbeDef("modified")
+	modifiedDef.IntervalMsec = proto.Int32(5000)
+	if err := pr.ReloadProbes([]*probes_configpb.ProbeDef{modifiedDef}); err != nil {
+		t.Fatalf("Error reloading probes: %v", err)
+	}
+	newModified := pr.Probes["modified"]
+	verifyProbeRunningStatus(t, newModified.Probe.(*testProbe), true)
+
+	// Old instances should not be started, and the new instance should not be
+	// started again.
+	for _, p := range []*probes.ProbeInfo{oldModified, oldRemoved, newModified} {
+		pr.startProbe(pr.startCtx, p)
+	}
+	for _, p := range []*probes.ProbeInfo{oldModified, oldRemoved, newModified} {
+		verifyNoStatusChange(t, p.Probe.(*testProbe))
+	}
+
+	// New instance should still be stoppable.
+	pr.mu.Lock()
+	pr.stopProbe("modified")
+	pr.mu.Unlock()
+	verifyProbeRunningStatus(t, newModified.Probe.(*testProbe), false)
+}
+
+// bufferingSurfacer simulates a surfacer that buffers data internally and
+// writes it out on Close.
+type bufferingSurfacer struct {
+	unblock    chan struct{} // Writes block until it's closed.
+	closeDelay time.Duration
+
+	mu      sync.Mutex
+	buf     []*metrics.EventMetrics
+	written []*metrics.EventMetrics
+}
+
+func (s *bufferingSurfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
+	<-s.unblock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, em)
+}
+
+func (s *bufferingSurfacer) Close() {
+	time.Sleep(s.closeDelay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written, s.buf = append(s.written, s.buf...), nil
+}
+
+func TestShutdown(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		closeDelay  time.Duration
+		wantDrained bool
+	}{
+		{desc: "drained", wantDrained: true},
+		{desc: "grace_period_exceeded", closeDelay: 5 * time.Second},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := &bufferingSurfacer{unblock: make(chan struct{}), closeDelay: test.closeDelay}
+			pr := &Prober{
+				Probes:    make(map[string]*probes.ProbeInfo),
+				Surfacers: []*surfacers.SurfacerInfo{{Surfacer: s}},
+			}
+
+			ctx, cancelF := context.WithCancel(context.Background())
+			pr.Start(ctx)
+
+			// Queue metrics while the surfacer is blocked.
+			numEMs := 100
+			for i := 0; i < numEMs; i++ {
+				pr.dataChan <- metrics.NewEventMetrics(time.Now()).
+					AddMetric("total", metrics.NewInt(int64(i))).
+					AddLabel("probe", "p1")
+			}
+
+			cancelF()
+			drainedCh := make(chan bool)
+			go func() { drainedCh <- pr.Shutdown(time.Second) }()
+			close(s.unblock)
+
+			if drained := <-drainedCh; drained != test.wantDrained {
+				t.Fatalf("Shutdown()=%v, want=%v", drained, test.wantDrained)
+			}
+			if !test.wantDrained {
+				return
+			}
+
+			// New metrics are not accepted after shutdown.
+			pr.dataChan <- metrics.NewEventMetrics(time.Now()).AddLabel("probe", "p1")
+			time.Sleep(50 * time.Millisecond)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if len(s.written) != numEMs || len(s.buf) != 0 {
+				t.Errorf("Got written=%d, buffered=%d, want written=%d, buffered=0", len(s.written), len(s.buf), numEMs)
+			}
+			for i, em := range s.written {
+				if got := em.Metric("total").(metrics.NumValue).Int64(); got != int64(i) {
+					t.Errorf("Metrics written out of order, got total=%d at index %d", got, i)
+					break
+				}
+			}
+		})
+	}
+}
@@ -335,6 +335,457 @@ func (x *ListProbesResponse) GetProbe() []*Probe {
 	return nil
 }
 
+type SetMaintenanceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProbeName *string `protobuf:"bytes,1,opt,name=probe_name,json=probeName" json:"probe_name,omitempty"`
+	// Maintenance duration in seconds. Maintenance is cleared automatically
+	// once it expires. If not set, maintenance lasts until it's cleared.
+	DurationSec *int32 `protobuf:"varint,2,opt,name=duration_sec,json=durationSec" json:"duration_sec,omitempty"`
+}
+
+func (x *SetMaintenanceRequest) Reset() {
+	*x = SetMaintenanceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetMaintenanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMaintenanceRequest) ProtoMessage() {}
+
+func (x *SetMaintenanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMaintenanceRequest.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceRequest) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SetMaintenanceRequest) GetProbeName() string {
+	if x != nil && x.ProbeName != nil {
+		return *x.ProbeName
+	}
+	return ""
+}
+
+func (x *SetMaintenanceRequest) GetDurationSec() int32 {
+	if x != nil && x.DurationSec != nil {
+		return *x.DurationSec
+	}
+	return 0
+}
+
+type SetMaintenanceResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Maintenance expiry time, if any.
+	ExpiryUnixSec *int64 `protobuf:"varint,1,opt,name=expiry_unix_sec,json=expiryUnixSec" json:"expiry_unix_sec,omitempty"`
+}
+
+func (x *SetMaintenanceResponse) Reset() {
+	*x = SetMaintenanceResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetMaintenanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMaintenanceResponse) ProtoMessage() {}
+
+func (x *SetMaintenanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMaintenanceResponse.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceResponse) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetMaintenanceResponse) GetExpiryUnixSec() int64 {
+	if x != nil && x.ExpiryUnixSec != nil {
+		return *x.ExpiryUnixSec
+	}
+	return 0
+}
+
+type ClearMaintenanceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProbeName *string `protobuf:"bytes,1,opt,name=probe_name,json=probeName" json:"probe_name,omitempty"`
+}
+
+func (x *ClearMaintenanceRequest) Reset() {
+	*x = ClearMaintenanceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearMaintenanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearMaintenanceRequest) ProtoMessage() {}
+
+func (x *ClearMaintenanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearMaintenanceRequest.ProtoReflect.Descriptor instead.
+func (*ClearMaintenanceRequest) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ClearMaintenanceRequest) GetProbeName() string {
+	if x != nil && x.ProbeName != nil {
+		return *x.ProbeName
+	}
+	return ""
+}
+
+type ClearMaintenanceResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ClearMaintenanceResponse) Reset() {
+	*x = ClearMaintenanceResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearMaintenanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearMaintenanceResponse) ProtoMessage() {}
+
+func (x *ClearMaintenanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearMaintenanceResponse.ProtoReflect.Descriptor instead.
+func (*ClearMaintenanceResponse) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{10}
+}
+
+type ListMaintenanceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListMaintenanceRequest) Reset() {
+	*x = ListMaintenanceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMaintenanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMaintenanceRequest) ProtoMessage() {}
+
+func (x *ListMaintenanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMaintenanceRequest.ProtoReflect.Descriptor instead.
+func (*ListMaintenanceRequest) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{11}
+}
+
+type Maintenance struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProbeName *string `protobuf:"bytes,1,opt,name=probe_name,json=probeName" json:"probe_name,omitempty"`
+	// Not set if maintenance doesn't expire.
+	ExpiryUnixSec *int64 `protobuf:"varint,2,opt,name=expiry_unix_sec,json=expiryUnixSec" json:"expiry_unix_sec,omitempty"`
+}
+
+func (x *Maintenance) Reset() {
+	*x = Maintenance{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Maintenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Maintenance) ProtoMessage() {}
+
+func (x *Maintenance) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Maintenance.ProtoReflect.Descriptor instead.
+func (*Maintenance) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Maintenance) GetProbeName() string {
+	if x != nil && x.ProbeName != nil {
+		return *x.ProbeName
+	}
+	return ""
+}
+
+func (x *Maintenance) GetExpiryUnixSec() int64 {
+	if x != nil && x.ExpiryUnixSec != nil {
+		return *x.ExpiryUnixSec
+	}
+	return 0
+}
+
+type ListMaintenanceResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Maintenance []*Maintenance `protobuf:"bytes,1,rep,name=maintenance" json:"maintenance,omitempty"`
+}
+
+func (x *ListMaintenanceResponse) Reset() {
+	*x = ListMaintenanceResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMaintenanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMaintenanceResponse) ProtoMessage() {}
+
+func (x *ListMaintenanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMaintenanceResponse.ProtoReflect.Descriptor instead.
+func (*ListMaintenanceResponse) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListMaintenanceResponse) GetMaintenance() []*Maintenance {
+	if x != nil {
+		return x.Maintenance
+	}
+	return nil
+}
+
+type TriggerProbeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProbeName *string `protobuf:"bytes,1,opt,name=probe_name,json=probeName" json:"probe_name,omitempty"`
+	Target    *string `protobuf:"bytes,2,opt,name=target" json:"target,omitempty"`
+}
+
+func (x *TriggerProbeRequest) Reset() {
+	*x = TriggerProbeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TriggerProbeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerProbeRequest) ProtoMessage() {}
+
+func (x *TriggerProbeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerProbeRequest.ProtoReflect.Descriptor instead.
+func (*TriggerProbeRequest) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TriggerProbeRequest) GetProbeName() string {
+	if x != nil && x.ProbeName != nil {
+		return *x.ProbeName
+	}
+	return ""
+}
+
+func (x *TriggerProbeRequest) GetTarget() string {
+	if x != nil && x.Target != nil {
+		return *x.Target
+	}
+	return ""
+}
+
+type TriggerProbeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success *bool `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
+	// Run's latency in milliseconds, set only if the run succeeded.
+	LatencyMsec *float64 `protobuf:"fixed64,2,opt,name=latency_msec,json=latencyMsec" json:"latency_msec,omitempty"`
+	// Reason of the failure, set only if the run failed.
+	Error *string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (x *TriggerProbeResponse) Reset() {
+	*x = TriggerProbeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TriggerProbeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerProbeResponse) ProtoMessage() {}
+
+func (x *TriggerProbeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerProbeResponse.ProtoReflect.Descriptor instead.
+func (*TriggerProbeResponse) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TriggerProbeResponse) GetSuccess() bool {
+	if x != nil && x.Success != nil {
+		return *x.Success
+	}
+	return false
+}
+
+func (x *TriggerProbeResponse) GetLatencyMsec() float64 {
+	if x != nil && x.LatencyMsec != nil {
+		return *x.LatencyMsec
+	}
+	return 0
+}
+
+func (x *TriggerProbeResponse) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
 var File_github_com_cloudprober_cloudprober_prober_proto_service_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDesc = []byte{
@@ -368,26 +819,89 @@ var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDesc =
 	0x6f, 0x62, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x28, 0x0a, 0x05,
 	0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x63, 0x6c,
 	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52,
-	0x05, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x32, 0xfd, 0x01, 0x0a, 0x0b, 0x43, 0x6c, 0x6f, 0x75, 0x64,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x12, 0x49, 0x0a, 0x08, 0x41, 0x64, 0x64, 0x50, 0x72, 0x6f,
-	0x62, 0x65, 0x12, 0x1c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2e, 0x41, 0x64, 0x64, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x1d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x41,
-	0x64, 0x64, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x00, 0x12, 0x52, 0x0a, 0x0b, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65,
-	0x12, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x52,
-	0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4f, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f,
-	0x62, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x05, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x22, 0x59, 0x0a, 0x15, 0x53, 0x65, 0x74, 0x4d, 0x61, 0x69,
+	0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x21,
+	0x0a, 0x0c, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65,
+	0x63, 0x22, 0x40, 0x0a, 0x16, 0x53, 0x65, 0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61,
+	0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x0f, 0x65,
+	0x78, 0x70, 0x69, 0x72, 0x79, 0x5f, 0x75, 0x6e, 0x69, 0x78, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x55, 0x6e, 0x69, 0x78,
+	0x53, 0x65, 0x63, 0x22, 0x38, 0x0a, 0x17, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x4d, 0x61, 0x69, 0x6e,
+	0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d,
+	0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x1a, 0x0a,
+	0x18, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x18, 0x0a, 0x16, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0x54, 0x0a, 0x0b, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e,
+	0x63, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x26, 0x0a, 0x0f, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x5f, 0x75, 0x6e, 0x69, 0x78,
+	0x5f, 0x73, 0x65, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x69,
+	0x72, 0x79, 0x55, 0x6e, 0x69, 0x78, 0x53, 0x65, 0x63, 0x22, 0x55, 0x0a, 0x17, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3a, 0x0a, 0x0b, 0x6d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61,
+	0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61,
+	0x6e, 0x63, 0x65, 0x52, 0x0b, 0x6d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x22, 0x4c, 0x0a, 0x13, 0x54, 0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22, 0x69,
+	0x0a, 0x14, 0x54, 0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73,
+	0x12, 0x21, 0x0a, 0x0c, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6d, 0x73, 0x65, 0x63,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4d,
+	0x73, 0x65, 0x63, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x32, 0xf4, 0x04, 0x0a, 0x0b, 0x43, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x12, 0x49, 0x0a, 0x08, 0x41, 0x64, 0x64,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x1c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x41, 0x64, 0x64, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x41, 0x64, 0x64, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x0b, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72,
+	0x6f, 0x62, 0x65, 0x12, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4f, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5b, 0x0a, 0x0e, 0x53, 0x65, 0x74,
+	0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x22, 0x2e, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x74, 0x4d, 0x61, 0x69,
+	0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x23, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x53, 0x65,
+	0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x61, 0x0a, 0x10, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x4d,
+	0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x24, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x4d, 0x61,
+	0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x25, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x43,
+	0x6c, 0x65, 0x61, 0x72, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5e, 0x0a, 0x0f, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d,
+	0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x24, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x55, 0x0a, 0x0c, 0x54, 0x72, 0x69,
+	0x67, 0x67, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x54, 0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x54, 0x72, 0x69, 0x67, 0x67, 0x65,
+	0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -402,32 +916,50 @@ func file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescG
 	return file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_goTypes = []interface{}{
-	(*AddProbeRequest)(nil),     // 0: cloudprober.AddProbeRequest
-	(*AddProbeResponse)(nil),    // 1: cloudprober.AddProbeResponse
-	(*RemoveProbeRequest)(nil),  // 2: cloudprober.RemoveProbeRequest
-	(*RemoveProbeResponse)(nil), // 3: cloudprober.RemoveProbeResponse
-	(*ListProbesRequest)(nil),   // 4: cloudprober.ListProbesRequest
-	(*Probe)(nil),               // 5: cloudprober.Probe
-	(*ListProbesResponse)(nil),  // 6: cloudprober.ListProbesResponse
-	(*proto.ProbeDef)(nil),      // 7: cloudprober.probes.ProbeDef
+	(*AddProbeRequest)(nil),          // 0: cloudprober.AddProbeRequest
+	(*AddProbeResponse)(nil),         // 1: cloudprober.AddProbeResponse
+	(*RemoveProbeRequest)(nil),       // 2: cloudprober.RemoveProbeRequest
+	(*RemoveProbeResponse)(nil),      // 3: cloudprober.RemoveProbeResponse
+	(*ListProbesRequest)(nil),        // 4: cloudprober.ListProbesRequest
+	(*Probe)(nil),                    // 5: cloudprober.Probe
+	(*ListProbesResponse)(nil),       // 6: cloudprober.ListProbesResponse
+	(*SetMaintenanceRequest)(nil),    // 7: cloudprober.SetMaintenanceRequest
+	(*SetMaintenanceResponse)(nil),   // 8: cloudprober.SetMaintenanceResponse
+	(*ClearMaintenanceRequest)(nil),  // 9: cloudprober.ClearMaintenanceRequest
+	(*ClearMaintenanceResponse)(nil), // 10: cloudprober.ClearMaintenanceResponse
+	(*ListMaintenanceRequest)(nil),   // 11: cloudprober.ListMaintenanceRequest
+	(*Maintenance)(nil),              // 12: cloudprober.Maintenance
+	(*ListMaintenanceResponse)(nil),  // 13: cloudprober.ListMaintenanceResponse
+	(*TriggerProbeRequest)(nil),      // 14: cloudprober.TriggerProbeRequest
+	(*TriggerProbeResponse)(nil),     // 15: cloudprober.TriggerProbeResponse
+	(*proto.ProbeDef)(nil),           // 16: cloudprober.probes.ProbeDef
 }
 var file_github_com_cloudprober_cloudprober_prober_proto_service_proto_depIdxs = []int32{
-	7, // 0: cloudprober.AddProbeRequest.probe_config:type_name -> cloudprober.probes.ProbeDef
-	7, // 1: cloudprober.Probe.config:type_name -> cloudprober.probes.ProbeDef
-	5, // 2: cloudprober.ListProbesResponse.probe:type_name -> cloudprober.Probe
-	0, // 3: cloudprober.Cloudprober.AddProbe:input_type -> cloudprober.AddProbeRequest
-	2, // 4: cloudprober.Cloudprober.RemoveProbe:input_type -> cloudprober.RemoveProbeRequest
-	4, // 5: cloudprober.Cloudprober.ListProbes:input_type -> cloudprober.ListProbesRequest
-	1, // 6: cloudprober.Cloudprober.AddProbe:output_type -> cloudprober.AddProbeResponse
-	3, // 7: cloudprober.Cloudprober.RemoveProbe:output_type -> cloudprober.RemoveProbeResponse
-	6, // 8: cloudprober.Cloudprober.ListProbes:output_type -> cloudprober.ListProbesResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	16, // 0: cloudprober.AddProbeRequest.probe_config:type_name -> cloudprober.probes.ProbeDef
+	16, // 1: cloudprober.Probe.config:type_name -> cloudprober.probes.ProbeDef
+	5,  // 2: cloudprober.ListProbesResponse.probe:type_name -> cloudprober.Probe
+	12, // 3: cloudprober.ListMaintenanceResponse.maintenance:type_name -> cloudprober.Maintenance
+	0,  // 4: cloudprober.Cloudprober.AddProbe:input_type -> cloudprober.AddProbeRequest
+	2,  // 5: cloudprober.Cloudprober.RemoveProbe:input_type -> cloudprober.RemoveProbeRequest
+	4,  // 6: cloudprober.Cloudprober.ListProbes:input_type -> cloudprober.ListProbesRequest
+	7,  // 7: cloudprober.Cloudprober.SetMaintenance:input_type -> cloudprober.SetMaintenanceRequest
+	9,  // 8: cloudprober.Cloudprober.ClearMaintenance:input_type -> cloudprober.ClearMaintenanceRequest
+	11, // 9: cloudprober.Cloudprober.ListMaintenance:input_type -> cloudprober.ListMaintenanceRequest
+	14, // 10: cloudprober.Cloudprober.TriggerProbe:input_type -> cloudprober.TriggerProbeRequest
+	1,  // 11: cloudprober.Cloudprober.AddProbe:output_type -> cloudprober.AddProbeResponse
+	3,  // 12: cloudprober.Cloudprober.RemoveProbe:output_type -> cloudprober.RemoveProbeResponse
+	6,  // 13: cloudprober.Cloudprober.ListProbes:output_type -> cloudprober.ListProbesResponse
+	8,  // 14: cloudprober.Cloudprober.SetMaintenance:output_type -> cloudprober.SetMaintenanceResponse
+	10, // 15: cloudprober.Cloudprober.ClearMaintenance:output_type -> cloudprober.ClearMaintenanceResponse
+	13, // 16: cloudprober.Cloudprober.ListMaintenance:output_type -> cloudprober.ListMaintenanceResponse
+	15, // 17: cloudprober.Cloudprober.TriggerProbe:output_type -> cloudprober.TriggerProbeResponse
+	11, // [11:18] is the sub-list for method output_type
+	4,  // [4:11] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_prober_proto_service_proto_init() }
@@ -520,6 +1052,114 @@ func file_github_com_cloudprober_cloudprober_prober_proto_service_proto_init() {
 				return nil
 			}
 		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetMaintenanceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetMaintenanceResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClearMaintenanceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClearMaintenanceResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMaintenanceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Maintenance); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMaintenanceResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TriggerProbeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_prober_proto_service_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TriggerProbeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -527,7 +1167,7 @@ func file_github_com_cloudprober_cloudprober_prober_proto_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_prober_proto_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -560,6 +1200,23 @@ type CloudproberClient interface {
 	RemoveProbe(ctx context.Context, in *RemoveProbeRequest, opts ...grpc.CallOption) (*RemoveProbeResponse, error)
 	// ListProbes lists active probes.
 	ListProbes(ctx context.Context, in *ListProbesRequest, opts ...grpc.CallOption) (*ListProbesResponse, error)
+	// SetMaintenance puts a probe in maintenance, optionally until an expiry.
+	// Probe keeps running in maintenance, but its metrics are labeled with
+	// maintenance=true, kept out of the regular counters, and don't trigger
+	// alerts.
+	SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*SetMaintenanceResponse, error)
+	// ClearMaintenance takes a probe out of maintenance.
+	ClearMaintenance(ctx context.Context, in *ClearMaintenanceRequest, opts ...grpc.CallOption) (*ClearMaintenanceResponse, error)
+	// ListMaintenance lists the probes currently in maintenance.
+	ListMaintenance(ctx context.Context, in *ListMaintenanceRequest, opts ...grpc.CallOption) (*ListMaintenanceResponse, error)
+	// TriggerProbe runs a probe once for one of its targets, out of the
+	// probe's regular schedule, and returns the run's outcome, e.g. for
+	// troubleshooting. On-demand runs don't affect the probe's metrics.
+	// HTTP, DNS, TCP, TLS, NTP, SMTP, FTP, LDAP, MQTT, SNMP and traceroute
+	// probes support on-demand runs; for other probes, it returns UNIMPLEMENTED.
+	// PING and UDP probes can't run on demand, as they send and receive packets
+	// for all their targets over shared sockets.
+	TriggerProbe(ctx context.Context, in *TriggerProbeRequest, opts ...grpc.CallOption) (*TriggerProbeResponse, error)
 }
 
 type cloudproberClient struct {
@@ -597,6 +1254,42 @@ func (c *cloudproberClient) ListProbes(ctx context.Context, in *ListProbesReques
 	return out, nil
 }
 
+func (c *cloudproberClient) SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*SetMaintenanceResponse, error) {
+	out := new(SetMaintenanceResponse)
+	err := c.cc.Invoke(ctx, "/cloudprober.Cloudprober/SetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudproberClient) ClearMaintenance(ctx context.Context, in *ClearMaintenanceRequest, opts ...grpc.CallOption) (*ClearMaintenanceResponse, error) {
+	out := new(ClearMaintenanceResponse)
+	err := c.cc.Invoke(ctx, "/cloudprober.Cloudprober/ClearMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudproberClient) ListMaintenance(ctx context.Context, in *ListMaintenanceRequest, opts ...grpc.CallOption) (*ListMaintenanceResponse, error) {
+	out := new(ListMaintenanceResponse)
+	err := c.cc.Invoke(ctx, "/cloudprober.Cloudprober/ListMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudproberClient) TriggerProbe(ctx context.Context, in *TriggerProbeRequest, opts ...grpc.CallOption) (*TriggerProbeResponse, error) {
+	out := new(TriggerProbeResponse)
+	err := c.cc.Invoke(ctx, "/cloudprober.Cloudprober/TriggerProbe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CloudproberServer is the server API for Cloudprober service.
 type CloudproberServer interface {
 	// AddProbe adds a probe to cloudprober. Error is returned if probe is already
@@ -606,6 +1299,23 @@ type CloudproberServer interface {
 	RemoveProbe(context.Context, *RemoveProbeRequest) (*RemoveProbeResponse, error)
 	// ListProbes lists active probes.
 	ListProbes(context.Context, *ListProbesRequest) (*ListProbesResponse, error)
+	// SetMaintenance puts a probe in maintenance, optionally until an expiry.
+	// Probe keeps running in maintenance, but its metrics are labeled with
+	// maintenance=true, kept out of the regular counters, and don't trigger
+	// alerts.
+	SetMaintenance(context.Context, *SetMaintenanceRequest) (*SetMaintenanceResponse, error)
+	// ClearMaintenance takes a probe out of maintenance.
+	ClearMaintenance(context.Context, *ClearMaintenanceRequest) (*ClearMaintenanceResponse, error)
+	// ListMaintenance lists the probes currently in maintenance.
+	ListMaintenance(context.Context, *ListMaintenanceRequest) (*ListMaintenanceResponse, error)
+	// TriggerProbe runs a probe once for one of its targets, out of the
+	// probe's regular schedule, and returns the run's outcome, e.g. for
+	// troubleshooting. On-demand runs don't affect the probe's metrics.
+	// HTTP, DNS, TCP, TLS, NTP, SMTP, FTP, LDAP, MQTT, SNMP and traceroute
+	// probes support on-demand runs; for other probes, it returns UNIMPLEMENTED.
+	// PING and UDP probes can't run on demand, as they send and receive packets
+	// for all their targets over shared sockets.
+	TriggerProbe(context.Context, *TriggerProbeRequest) (*TriggerProbeResponse, error)
 }
 
 // UnimplementedCloudproberServer can be embedded to have forward compatible implementations.
@@ -621,6 +1331,18 @@ func (*UnimplementedCloudproberServer) RemoveProbe(context.Context, *RemoveProbe
 func (*UnimplementedCloudproberServer) ListProbes(context.Context, *ListProbesRequest) (*ListProbesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListProbes not implemented")
 }
+func (*UnimplementedCloudproberServer) SetMaintenance(context.Context, *SetMaintenanceRequest) (*SetMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (*UnimplementedCloudproberServer) ClearMaintenance(context.Context, *ClearMaintenanceRequest) (*ClearMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearMaintenance not implemented")
+}
+func (*UnimplementedCloudproberServer) ListMaintenance(context.Context, *ListMaintenanceRequest) (*ListMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMaintenance not implemented")
+}
+func (*UnimplementedCloudproberServer) TriggerProbe(context.Context, *TriggerProbeRequest) (*TriggerProbeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerProbe not implemented")
+}
 
 func RegisterCloudproberServer(s *grpc.Server, srv CloudproberServer) {
 	s.RegisterService(&_Cloudprober_serviceDesc, srv)
@@ -680,6 +1402,78 @@ func _Cloudprober_ListProbes_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Cloudprober_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudproberServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudprober.Cloudprober/SetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudproberServer).SetMaintenance(ctx, req.(*SetMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cloudprober_ClearMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudproberServer).ClearMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudprober.Cloudprober/ClearMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudproberServer).ClearMaintenance(ctx, req.(*ClearMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cloudprober_ListMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudproberServer).ListMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudprober.Cloudprober/ListMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudproberServer).ListMaintenance(ctx, req.(*ListMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cloudprober_TriggerProbe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerProbeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudproberServer).TriggerProbe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudprober.Cloudprober/TriggerProbe",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudproberServer).TriggerProbe(ctx, req.(*TriggerProbeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Cloudprober_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "cloudprober.Cloudprober",
 	HandlerType: (*CloudproberServer)(nil),
@@ -696,6 +1490,22 @@ var _Cloudprober_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListProbes",
 			Handler:    _Cloudprober_ListProbes_Handler,
 		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _Cloudprober_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "ClearMaintenance",
+			Handler:    _Cloudprober_ClearMaintenance_Handler,
+		},
+		{
+			MethodName: "ListMaintenance",
+			Handler:    _Cloudprober_ListMaintenance_Handler,
+		},
+		{
+			MethodName: "TriggerProbe",
+			Handler:    _Cloudprober_TriggerProbe_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "github.com/cloudprober/cloudprober/prober/proto/service.proto",
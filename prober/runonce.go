@@ -0,0 +1,205 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/surfacers"
+)
+
+// targetResult captures the latest total and success counters reported for a
+// probe target.
+type targetResult struct {
+	total, success int64
+}
+
+// runTracker tracks probe results in the run-once mode.
+type runTracker struct {
+	count   int64
+	results map[string]map[string]*targetResult // probe -> target -> result
+}
+
+// update records the total and success counters from the given EventMetrics.
+// EventMetrics without these counters (e.g. payload metrics) are ignored.
+func (rt *runTracker) update(em *metrics.EventMetrics) {
+	total, ok1 := em.Metric("total").(metrics.NumValue)
+	success, ok2 := em.Metric("success").(metrics.NumValue)
+	probe, dst := em.Label("probe"), em.Label("dst")
+	// Warm-up results are not counted towards the runs.
+	if !ok1 || !ok2 || probe == "" || em.Label("warmup") == "true" {
+		return
+	}
+
+	if rt.results[probe] == nil {
+		rt.results[probe] = make(map[string]*targetResult)
+	}
+	rt.results[probe][dst] = &targetResult{total: total.Int64(), success: success.Int64()}
+}
+
+// done returns true if all the probe's targets have been probed count times.
+func (rt *runTracker) done(p *probes.ProbeInfo) bool {
+	eps := p.Options.Targets.ListEndpoints()
+	if len(eps) == 0 {
+		return false
+	}
+	for _, ep := range eps {
+		res := rt.results[p.Name][ep.Name]
+		if res == nil || res.total < rt.count {
+			return false
+		}
+	}
+	return true
+}
+
+// success verifies the probe's results and returns false if any of the runs
+// failed or if a target didn't get probed count times.
+func (rt *runTracker) success(p *probes.ProbeInfo, l *logger.Logger) bool {
+	if len(rt.results[p.Name]) == 0 {
+		l.Errorf("Probe %s: no results reported", p.Name)
+		return false
+	}
+
+	ok := true
+	for dst, res := range rt.results[p.Name] {
+		if res.total < rt.count {
+			l.Errorf("Probe %s, target %s: only %d out of %d runs completed", p.Name, dst, res.total, rt.count)
+			ok = false
+		}
+		if res.success < res.total {
+			l.Errorf("Probe %s, target %s: %d out of %d runs failed", p.Name, dst, res.total-res.success, res.total)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// runDeadline returns the maximum time a probe is allowed to run for in the
+// run-once mode: initial delay and warm-up window, followed by count
+// intervals, a timeout for the last run and a stats export interval for the
+// probes that aggregate results before exporting them.
+func runDeadline(p *probes.ProbeInfo, count int) time.Duration {
+	return p.Options.InitialDelay + p.Options.WarmupWindow + time.Duration(count)*p.Options.Interval + p.Options.Timeout + p.Options.StatsExportInterval
+}
+
+// RunOnce runs all the probes for the given number of cycles, instead of
+// running them indefinitely. A probe is stopped once all its targets have
+// been probed count times, or if it fails to do that within its deadline.
+// After all probes have stopped, RunOnce drains the remaining metrics to the
+// surfacers and closes them.
+//
+// It returns true if all the probe runs were successful.
+func (pr *Prober) RunOnce(ctx context.Context, count int) bool {
+	dataChan := make(chan *metrics.EventMetrics, 100000)
+	rt := &runTracker{
+		count:   int64(count),
+		results: make(map[string]map[string]*targetResult),
+	}
+
+	var wg sync.WaitGroup
+	cancelFuncs := make(map[string]context.CancelFunc)
+	var maxDeadline time.Duration
+
+	for name, p := range pr.Probes {
+		deadline := runDeadline(p, count)
+		if deadline > maxDeadline {
+			maxDeadline = deadline
+		}
+
+		probeCtx, cancelFunc := context.WithTimeout(ctx, deadline)
+		cancelFuncs[name] = cancelFunc
+
+		wg.Add(1)
+		go func(p *probes.ProbeInfo) {
+			defer wg.Done()
+			runProbe(probeCtx, p, dataChan, &pr.maintenance, pr.l)
+		}(p)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	writeEM := func(em *metrics.EventMetrics) {
+		rt.update(em)
+		pr.writeEM(em)
+	}
+
+	// Probes stop at the next tick after their context is canceled. If a probe
+	// doesn't stop even after its deadline and another interval, we give up on
+	// it.
+	giveUp := time.After(maxDeadline + maxInterval(pr.Probes))
+
+	for waiting := true; waiting; {
+		select {
+		case em := <-dataChan:
+			writeEM(em)
+			if p := pr.Probes[em.Label("probe")]; p != nil && cancelFuncs[p.Name] != nil && rt.done(p) {
+				pr.l.Infof("Probe %s: completed %d runs, stopping it", p.Name, count)
+				cancelFuncs[p.Name]()
+				delete(cancelFuncs, p.Name)
+			}
+		case <-stopped:
+			waiting = false
+		case <-giveUp:
+			pr.l.Warning("Timed out waiting for the probes to stop")
+			waiting = false
+		}
+	}
+	for _, cancelFunc := range cancelFuncs {
+		cancelFunc()
+	}
+
+	// Drain the metrics that are still in the channel.
+	for draining := true; draining; {
+		select {
+		case em := <-dataChan:
+			writeEM(em)
+		default:
+			draining = false
+		}
+	}
+
+	for _, s := range pr.Surfacers {
+		if c, ok := s.Surfacer.(surfacers.Closer); ok {
+			c.Close()
+		}
+	}
+
+	success := true
+	for _, p := range pr.Probes {
+		if !rt.success(p, pr.l) {
+			success = false
+		}
+	}
+	return success
+}
+
+func maxInterval(probeInfos map[string]*probes.ProbeInfo) time.Duration {
+	var max time.Duration
+	for _, p := range probeInfos {
+		if p.Options.Interval > max {
+			max = p.Options.Interval
+		}
+	}
+	return max
+}
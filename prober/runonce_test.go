@@ -0,0 +1,187 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/surfacers"
+	"github.com/cloudprober/cloudprober/targets"
+)
+
+// runOnceTestProbe reports total and success counters for a single target
+// on every tick.
+type runOnceTestProbe struct {
+	name     string
+	opts     *options.Options
+	fail     bool // If true, probe runs fail.
+	noReport bool // If true, probe doesn't report anything.
+
+	mu      sync.Mutex
+	runs    int
+	stopped bool
+}
+
+func (p *runOnceTestProbe) Init(name string, opts *options.Options) error {
+	p.name, p.opts = name, opts
+	return nil
+}
+
+func (p *runOnceTestProbe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.stopped = true
+			p.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		if p.noReport {
+			continue
+		}
+
+		p.mu.Lock()
+		p.runs++
+		total, success := int64(p.runs), int64(p.runs)
+		p.mu.Unlock()
+		if p.fail {
+			success = 0
+		}
+
+		dataChan <- metrics.NewEventMetrics(time.Now()).
+			AddMetric("total", metrics.NewInt(total)).
+			AddMetric("success", metrics.NewInt(success)).
+			AddLabel("probe", p.name).
+			AddLabel("dst", "target1")
+	}
+}
+
+type runOnceTestSurfacer struct {
+	mu     sync.Mutex
+	ems    []*metrics.EventMetrics
+	closed bool
+}
+
+func (s *runOnceTestSurfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		panic("write after close")
+	}
+	s.ems = append(s.ems, em)
+}
+
+func (s *runOnceTestSurfacer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func runOnceTestProber(t *testing.T, testProbes ...*runOnceTestProbe) (*Prober, *runOnceTestSurfacer) {
+	t.Helper()
+
+	s := &runOnceTestSurfacer{}
+	pr := &Prober{
+		Probes:         make(map[string]*probes.ProbeInfo),
+		Surfacers:      []*surfacers.SurfacerInfo{{Surfacer: s}},
+		instanceLabels: []instanceLabel{{"env", "test"}},
+	}
+
+	for _, p := range testProbes {
+		opts := &options.Options{
+			Targets:             targets.StaticTargets("target1"),
+			Interval:            50 * time.Millisecond,
+			Timeout:             20 * time.Millisecond,
+			StatsExportInterval: 50 * time.Millisecond,
+		}
+		if err := p.Init(p.name, opts); err != nil {
+			t.Fatalf("Error initializing probe %s: %v", p.name, err)
+		}
+		pr.Probes[p.name] = &probes.ProbeInfo{Probe: p, Name: p.name, Options: opts}
+	}
+	return pr, s
+}
+
+func TestRunOnce(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		probes      []*runOnceTestProbe
+		wantSuccess bool
+	}{
+		{
+			desc:        "success",
+			probes:      []*runOnceTestProbe{{name: "p1"}, {name: "p2"}},
+			wantSuccess: true,
+		},
+		{
+			desc:   "one_probe_fails",
+			probes: []*runOnceTestProbe{{name: "p1"}, {name: "p2", fail: true}},
+		},
+		{
+			desc:   "no_results",
+			probes: []*runOnceTestProbe{{name: "p1"}, {name: "p2", noReport: true}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			pr, s := runOnceTestProber(t, test.probes...)
+
+			if got := pr.RunOnce(context.Background(), 2); got != test.wantSuccess {
+				t.Errorf("RunOnce()=%v, want=%v", got, test.wantSuccess)
+			}
+
+			for _, p := range test.probes {
+				p.mu.Lock()
+				if !p.stopped {
+					t.Errorf("Probe %s didn't stop", p.name)
+				}
+				if !p.noReport && p.runs != 2 {
+					t.Errorf("Probe %s ran %d times, want 2", p.name, p.runs)
+				}
+				p.mu.Unlock()
+			}
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if !s.closed {
+				t.Errorf("Surfacer was not closed")
+			}
+			// All reported metrics should have been surfaced before closing the
+			// surfacer.
+			wantEMs := 0
+			for _, p := range test.probes {
+				wantEMs += p.runs
+			}
+			if len(s.ems) != wantEMs {
+				t.Errorf("Surfacer got %d EventMetrics, want %d", len(s.ems), wantEMs)
+			}
+			for _, em := range s.ems {
+				if em.Label("env") != "test" {
+					t.Errorf("Instance label missing from the EventMetrics: %s", em.String())
+				}
+			}
+		})
+	}
+}
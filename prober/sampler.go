@@ -0,0 +1,51 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// resultSampler exports only a fraction (rate) of the probe's results, picked
+// at random. Cumulative metrics are exported as it is, as skipping some of
+// them doesn't affect the accuracy of the later ones. Gauge metrics are
+// labeled with the sample rate to mark them as sampled.
+type resultSampler struct {
+	rate      float64
+	rateLabel string
+	randF     func() float64 // Returns a number in [0, 1), overridden in tests.
+}
+
+func newResultSampler(rate float64) *resultSampler {
+	return &resultSampler{
+		rate:      rate,
+		rateLabel: strconv.FormatFloat(rate, 'g', 4, 64),
+		randF:     rand.Float64,
+	}
+}
+
+// process returns nil for the EventMetrics that are not sampled.
+func (rs *resultSampler) process(em *metrics.EventMetrics) *metrics.EventMetrics {
+	if rs.randF() >= rs.rate {
+		return nil
+	}
+	if em.Kind == metrics.GAUGE {
+		return em.Clone().AddLabel("sample_rate", rs.rateLabel)
+	}
+	return em
+}
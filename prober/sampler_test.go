@@ -0,0 +1,74 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+func TestResultSampler(t *testing.T) {
+	rs := newResultSampler(0.25)
+	// Cycle through 0, 0.1, ..., 0.9; 0, 0.1 and 0.2 are sampled.
+	var i int
+	rs.randF = func() float64 {
+		i++
+		return float64((i-1)%10) / 10
+	}
+
+	var cumulative, gauge []*metrics.EventMetrics
+	for j := 0; j < 100; j++ {
+		if em := rs.process(debounceTestEM(1, 1)); em != nil {
+			cumulative = append(cumulative, em)
+		}
+	}
+	for j := 0; j < 100; j++ {
+		gaugeEM := debounceTestEM(1, 1)
+		gaugeEM.Kind = metrics.GAUGE
+		if em := rs.process(gaugeEM); em != nil {
+			gauge = append(gauge, em)
+		}
+	}
+
+	if len(cumulative) != 30 || len(gauge) != 30 {
+		t.Errorf("Got %d cumulative and %d gauge EMs, want 30 each", len(cumulative), len(gauge))
+	}
+	for _, em := range cumulative {
+		if em.Label("sample_rate") != "" {
+			t.Errorf("Unexpected sample_rate label in cumulative EM: %s", em.String())
+		}
+	}
+	for _, em := range gauge {
+		if em.Label("sample_rate") != "0.25" {
+			t.Errorf("Got sample_rate label=%q in gauge EM, want: 0.25", em.Label("sample_rate"))
+		}
+	}
+}
+
+func TestResultSamplerRandom(t *testing.T) {
+	rs := newResultSampler(0.1)
+	var n int
+	for i := 0; i < 1000; i++ {
+		if rs.process(metrics.NewEventMetrics(time.Now())) != nil {
+			n++
+		}
+	}
+	// Expected count is 100, allow for plenty of randomness.
+	if n < 50 || n > 150 {
+		t.Errorf("Got %d sampled results out of 1000, want ~100", n)
+	}
+}
@@ -16,8 +16,12 @@ package prober
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	pb "github.com/cloudprober/cloudprober/prober/proto"
+	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -31,7 +35,7 @@ func (pr *Prober) AddProbe(ctx context.Context, req *pb.AddProbeRequest) (*pb.Ad
 		return &pb.AddProbeResponse{}, status.Errorf(codes.InvalidArgument, "probe config cannot be nil")
 	}
 
-	if err := pr.addProbe(p); err != nil {
+	if err := pr.addProbe(p, true); err != nil {
 		return &pb.AddProbeResponse{}, err
 	}
 
@@ -58,8 +62,8 @@ func (pr *Prober) RemoveProbe(ctx context.Context, req *pb.RemoveProbeRequest) (
 		return &pb.RemoveProbeResponse{}, status.Errorf(codes.NotFound, "probe %s not found", name)
 	}
 
-	pr.probeCancelFunc[name]()
-	delete(pr.Probes, name)
+	pr.stopProbe(name)
+	pr.maintenance.clear(name)
 
 	return &pb.RemoveProbeResponse{}, nil
 }
@@ -80,3 +84,122 @@ func (pr *Prober) ListProbes(ctx context.Context, req *pb.ListProbesRequest) (*p
 
 	return resp, nil
 }
+
+// SetMaintenance gRPC method puts the given probe in maintenance. Probe's
+// metrics generated in maintenance are labeled with maintenance=true and don't
+// trigger alerts.
+func (pr *Prober) SetMaintenance(ctx context.Context, req *pb.SetMaintenanceRequest) (*pb.SetMaintenanceResponse, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	name := req.GetProbeName()
+
+	if name == "" {
+		return &pb.SetMaintenanceResponse{}, status.Errorf(codes.InvalidArgument, "probe name cannot be empty")
+	}
+
+	if pr.Probes[name] == nil {
+		return &pb.SetMaintenanceResponse{}, status.Errorf(codes.NotFound, "probe %s not found", name)
+	}
+
+	if req.GetDurationSec() < 0 {
+		return &pb.SetMaintenanceResponse{}, status.Errorf(codes.InvalidArgument, "maintenance duration cannot be negative: %d", req.GetDurationSec())
+	}
+
+	resp := &pb.SetMaintenanceResponse{}
+	var expiry time.Time
+	if req.GetDurationSec() > 0 {
+		expiry = time.Now().Add(time.Duration(req.GetDurationSec()) * time.Second)
+		resp.ExpiryUnixSec = proto.Int64(expiry.Unix())
+	}
+
+	pr.l.Infof("Setting maintenance for probe %s, expiry: %v", name, expiry)
+	pr.maintenance.set(name, expiry)
+
+	return resp, nil
+}
+
+// ClearMaintenance gRPC method takes the given probe out of maintenance.
+func (pr *Prober) ClearMaintenance(ctx context.Context, req *pb.ClearMaintenanceRequest) (*pb.ClearMaintenanceResponse, error) {
+	name := req.GetProbeName()
+
+	if name == "" {
+		return &pb.ClearMaintenanceResponse{}, status.Errorf(codes.InvalidArgument, "probe name cannot be empty")
+	}
+
+	if !pr.maintenance.clear(name) {
+		return &pb.ClearMaintenanceResponse{}, status.Errorf(codes.NotFound, "probe %s is not in maintenance", name)
+	}
+	pr.l.Infof("Cleared maintenance for probe %s", name)
+
+	return &pb.ClearMaintenanceResponse{}, nil
+}
+
+// ListMaintenance gRPC method returns the probes currently in maintenance,
+// sorted by name. Expired maintenance windows are cleared.
+func (pr *Prober) ListMaintenance(ctx context.Context, req *pb.ListMaintenanceRequest) (*pb.ListMaintenanceResponse, error) {
+	resp := &pb.ListMaintenanceResponse{}
+
+	for name, expiry := range pr.maintenance.list(time.Now()) {
+		m := &pb.Maintenance{ProbeName: proto.String(name)}
+		if !expiry.IsZero() {
+			m.ExpiryUnixSec = proto.Int64(expiry.Unix())
+		}
+		resp.Maintenance = append(resp.Maintenance, m)
+	}
+	sort.Slice(resp.Maintenance, func(i, j int) bool {
+		return resp.Maintenance[i].GetProbeName() < resp.Maintenance[j].GetProbeName()
+	})
+
+	return resp, nil
+}
+
+// TriggerProbe gRPC method runs the given probe once for the given target, out
+// of the probe's regular schedule, and returns the run's outcome. On-demand
+// runs don't affect the probe's metrics, and are supported only by the probes
+// implementing the probes.OnDemandProbe interface.
+func (pr *Prober) TriggerProbe(ctx context.Context, req *pb.TriggerProbeRequest) (*pb.TriggerProbeResponse, error) {
+	name, targetName := req.GetProbeName(), req.GetTarget()
+
+	if name == "" || targetName == "" {
+		return &pb.TriggerProbeResponse{}, status.Errorf(codes.InvalidArgument, "probe name and target cannot be empty")
+	}
+
+	pr.mu.Lock()
+	p := pr.Probes[name]
+	pr.mu.Unlock()
+
+	if p == nil {
+		return &pb.TriggerProbeResponse{}, status.Errorf(codes.NotFound, "probe %s not found", name)
+	}
+
+	odp, ok := p.Probe.(probes.OnDemandProbe)
+	if !ok {
+		return &pb.TriggerProbeResponse{}, status.Errorf(codes.Unimplemented, "probe %s (type: %s) doesn't support on-demand runs, see TriggerProbe's documentation for the supported probe types", name, p.Type)
+	}
+
+	var target *endpoint.Endpoint
+	for _, ep := range p.Options.Targets.ListEndpoints() {
+		if ep.Name == targetName {
+			target = &ep
+			break
+		}
+	}
+	if target == nil {
+		return &pb.TriggerProbeResponse{}, status.Errorf(codes.NotFound, "target %s not found for probe %s", targetName, name)
+	}
+
+	pr.l.Infof("Running probe %s on demand for target %s", name, targetName)
+	latency, err := odp.RunOnDemand(ctx, *target)
+	if err != nil {
+		return &pb.TriggerProbeResponse{
+			Success: proto.Bool(false),
+			Error:   proto.String(err.Error()),
+		}, nil
+	}
+
+	return &pb.TriggerProbeResponse{
+		Success:     proto.Bool(true),
+		LatencyMsec: proto.Float64(float64(latency) / float64(time.Millisecond)),
+	}, nil
+}
@@ -16,8 +16,10 @@ package prober
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,7 +29,11 @@ import (
 	"github.com/cloudprober/cloudprober/probes/options"
 	probes_configpb "github.com/cloudprober/cloudprober/probes/proto"
 	testdatapb "github.com/cloudprober/cloudprober/probes/testdata"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 	targetspb "github.com/cloudprober/cloudprober/targets/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -35,6 +41,8 @@ func testProber() *Prober {
 	pr := &Prober{
 		Probes:           make(map[string]*probes.ProbeInfo),
 		probeCancelFunc:  make(map[string]context.CancelFunc),
+		probeDone:        make(map[string]chan struct{}),
+		grpcProbes:       make(map[string]bool),
 		grpcStartProbeCh: make(chan string),
 	}
 
@@ -43,7 +51,7 @@ func testProber() *Prober {
 		for {
 			select {
 			case name := <-pr.grpcStartProbeCh:
-				pr.startProbe(context.Background(), name)
+				pr.startProbeByName(context.Background(), name)
 			}
 		}
 	}()
@@ -63,7 +71,9 @@ type testProbe struct {
 
 func (p *testProbe) Init(name string, opts *options.Options) error {
 	p.intialized = true
-	p.runningStatusCh = make(chan bool)
+	// Buffered, so that stopping the probe doesn't block on the test reading
+	// the status.
+	p.runningStatusCh = make(chan bool, 2)
 	return nil
 }
 
@@ -222,6 +232,100 @@ func TestRemoveProbes(t *testing.T) {
 	verifyProbeRunningStatus(t, p, false)
 }
 
+// onDemandTestProbe is a test probe that supports on-demand runs. Runs for
+// the targets with the "fail" prefix fail.
+type onDemandTestProbe struct {
+	testProbe
+	runs int
+}
+
+func (p *onDemandTestProbe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	p.runs++
+	if strings.HasPrefix(target.Name, "fail") {
+		return 0, errors.New("connection refused")
+	}
+	return 25 * time.Millisecond, nil
+}
+
+func TestTriggerProbe(t *testing.T) {
+	ctx := context.Background()
+	pr := testProber()
+
+	odp := &onDemandTestProbe{}
+	pr.Probes["p1"] = &probes.ProbeInfo{
+		Probe:   odp,
+		Name:    "p1",
+		Options: &options.Options{Targets: targets.StaticTargets("ok-host,fail-host")},
+	}
+	pr.Probes["p2"] = &probes.ProbeInfo{
+		Probe:   &testProbe{},
+		Name:    "p2",
+		Type:    "EXTENSION",
+		Options: &options.Options{Targets: targets.StaticTargets("ok-host")},
+	}
+
+	for _, test := range []struct {
+		desc     string
+		req      *pb.TriggerProbeRequest
+		wantCode codes.Code
+		wantResp *pb.TriggerProbeResponse
+	}{
+		{
+			desc:     "empty_request",
+			req:      &pb.TriggerProbeRequest{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			desc:     "unknown_probe",
+			req:      &pb.TriggerProbeRequest{ProbeName: proto.String("p3"), Target: proto.String("ok-host")},
+			wantCode: codes.NotFound,
+		},
+		{
+			desc:     "unknown_target",
+			req:      &pb.TriggerProbeRequest{ProbeName: proto.String("p1"), Target: proto.String("other-host")},
+			wantCode: codes.NotFound,
+		},
+		{
+			desc:     "unsupported_probe",
+			req:      &pb.TriggerProbeRequest{ProbeName: proto.String("p2"), Target: proto.String("ok-host")},
+			wantCode: codes.Unimplemented,
+		},
+		{
+			desc: "success",
+			req:  &pb.TriggerProbeRequest{ProbeName: proto.String("p1"), Target: proto.String("ok-host")},
+			wantResp: &pb.TriggerProbeResponse{
+				Success:     proto.Bool(true),
+				LatencyMsec: proto.Float64(25),
+			},
+		},
+		{
+			desc: "failure",
+			req:  &pb.TriggerProbeRequest{ProbeName: proto.String("p1"), Target: proto.String("fail-host")},
+			wantResp: &pb.TriggerProbeResponse{
+				Success: proto.Bool(false),
+				Error:   proto.String("connection refused"),
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			resp, err := pr.TriggerProbe(ctx, test.req)
+			if status.Code(err) != test.wantCode {
+				t.Fatalf("TriggerProbe(%v): got error %v, want code %v", test.req, err, test.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if !proto.Equal(resp, test.wantResp) {
+				t.Errorf("TriggerProbe(%v): got response %v, want %v", test.req, resp, test.wantResp)
+			}
+		})
+	}
+
+	if odp.runs != 2 {
+		t.Errorf("Got %d on-demand runs, want 2", odp.runs)
+	}
+}
+
 func init() {
 	// Register extension probe.
 	probes.RegisterProbeType(200, func() probes.Probe {
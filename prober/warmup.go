@@ -0,0 +1,136 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"runtime/pprof"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+)
+
+// warmupTracker labels the EventMetrics generated during a probe's warm-up
+// window, and rebases the cumulative metrics generated after the warm-up
+// window, so that warm-up results are not counted in them.
+type warmupTracker struct {
+	end time.Time
+	l   *logger.Logger
+
+	// Last EventMetrics seen during the warm-up window, keyed by
+	// EventMetrics.Key().
+	baseline map[string]*metrics.EventMetrics
+}
+
+func newWarmupTracker(end time.Time, l *logger.Logger) *warmupTracker {
+	return &warmupTracker{
+		end:      end,
+		l:        l,
+		baseline: make(map[string]*metrics.EventMetrics),
+	}
+}
+
+func (wt *warmupTracker) process(em *metrics.EventMetrics) *metrics.EventMetrics {
+	if em.Timestamp.Before(wt.end) {
+		if em.Kind == metrics.CUMULATIVE {
+			wt.baseline[em.Key()] = em.Clone()
+		}
+		return em.Clone().AddLabel("warmup", "true")
+	}
+
+	base := wt.baseline[em.Key()]
+	if base == nil {
+		return em
+	}
+
+	newEM, err := em.SubtractLast(base)
+	if err != nil {
+		wt.l.Warningf("Error subtracting warm-up metrics, exporting metrics as it is: %v", err)
+		return em
+	}
+	newEM.Kind = metrics.CUMULATIVE
+	return newEM
+}
+
+// runProbe runs the probe after its initial delay, if any. If probe has a
+// warm-up window, failure debounce, alerting or result sampling configured,
+// probe's metrics are passed through a warmupTracker, a debounceTracker, a
+// maintenanceProcessor (if mt is not nil), an alerter and a resultSampler, in
+// that order, before being written to the dataChan. Processors return nil for
+// the metrics that should not be exported. runProbe returns when the probe's
+// Start returns.
+//
+// runProbe is expected to run in its own goroutine, which it labels with the
+// probe's name, for runstats to attribute the probe's goroutines to it.
+func runProbe(ctx context.Context, p *probes.ProbeInfo, dataChan chan *metrics.EventMetrics, mt *maintenanceTracker, l *logger.Logger) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(runstats.ProbeLabel, p.Name)))
+
+	if p.Options.InitialDelay > 0 {
+		select {
+		case <-time.After(p.Options.InitialDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	var processors []func(*metrics.EventMetrics) *metrics.EventMetrics
+	if p.Options.WarmupWindow != 0 {
+		processors = append(processors, newWarmupTracker(time.Now().Add(p.Options.WarmupWindow), l).process)
+	}
+	if p.Options.FailureDebounce != 0 {
+		processors = append(processors, newDebounceTracker(p.Options.FailureDebounce).process)
+	}
+	if mt != nil {
+		processors = append(processors, newMaintenanceProcessor(p.Name, mt, l).process)
+	}
+	if p.Options.Alerting != nil {
+		processors = append(processors, newAlerter(p.Name, p.Options.Alerting, l).process)
+	}
+	if r := p.Options.ResultSampleRate; r > 0 && r < 1 {
+		processors = append(processors, newResultSampler(r).process)
+	}
+
+	if len(processors) == 0 {
+		p.Start(ctx, dataChan)
+		return
+	}
+
+	probeDataChan := make(chan *metrics.EventMetrics, 1000)
+
+	// Forward probe's metrics until probe's context is canceled. Metrics
+	// written after that are buffered in the channel and dropped.
+	go func() {
+		for {
+			select {
+			case em := <-probeDataChan:
+				for _, process := range processors {
+					if em = process(em); em == nil {
+						break
+					}
+				}
+				if em != nil {
+					dataChan <- em
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	p.Start(ctx, probeDataChan)
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+)
+
+func TestWarmupTracker(t *testing.T) {
+	start := time.Now()
+	wt := newWarmupTracker(start.Add(time.Minute), nil)
+
+	testEM := func(ts time.Time, total, success int64) *metrics.EventMetrics {
+		return metrics.NewEventMetrics(ts).
+			AddMetric("total", metrics.NewInt(total)).
+			AddMetric("success", metrics.NewInt(success)).
+			AddLabel("probe", "p1")
+	}
+
+	for _, test := range []struct {
+		em                     *metrics.EventMetrics
+		wantWarmup             bool
+		wantTotal, wantSuccess int64
+	}{
+		{
+			em:          testEM(start, 5, 1),
+			wantWarmup:  true,
+			wantTotal:   5,
+			wantSuccess: 1,
+		},
+		{
+			em:          testEM(start.Add(30*time.Second), 10, 4),
+			wantWarmup:  true,
+			wantTotal:   10,
+			wantSuccess: 4,
+		},
+		{
+			em:          testEM(start.Add(time.Minute), 15, 9),
+			wantTotal:   5,
+			wantSuccess: 5,
+		},
+		{
+			em:          testEM(start.Add(90*time.Second), 20, 14),
+			wantTotal:   10,
+			wantSuccess: 10,
+		},
+	} {
+		em := wt.process(test.em)
+
+		if got := em.Label("warmup") == "true"; got != test.wantWarmup {
+			t.Errorf("%s: warmup label=%v, want=%v", em.String(), got, test.wantWarmup)
+		}
+		if em.Kind != metrics.CUMULATIVE {
+			t.Errorf("%s: kind=%v, want CUMULATIVE", em.String(), em.Kind)
+		}
+		total := em.Metric("total").(metrics.NumValue).Int64()
+		success := em.Metric("success").(metrics.NumValue).Int64()
+		if total != test.wantTotal || success != test.wantSuccess {
+			t.Errorf("%s: total=%d, success=%d, want total=%d, success=%d", em.String(), total, success, test.wantTotal, test.wantSuccess)
+		}
+	}
+
+	// Original EventMetrics should not be modified.
+	if em := testEM(start, 5, 1); em.Label("warmup") != "" {
+		t.Errorf("Original EventMetrics modified: %s", em.String())
+	}
+}
+
+func TestRunProbeInitialDelay(t *testing.T) {
+	initialDelay := 300 * time.Millisecond
+	p := &runOnceTestProbe{name: "p1"}
+	opts := &options.Options{
+		Targets:      targets.StaticTargets("target1"),
+		Interval:     50 * time.Millisecond,
+		InitialDelay: initialDelay,
+		WarmupWindow: 200 * time.Millisecond,
+	}
+	p.Init("p1", opts)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	dataChan := make(chan *metrics.EventMetrics, 100)
+	start := time.Now()
+	go runProbe(ctx, &probes.ProbeInfo{Probe: p, Name: "p1", Options: opts}, dataChan, nil, nil)
+
+	em := <-dataChan
+	if elapsed := time.Since(start); elapsed < initialDelay {
+		t.Errorf("First probe cycle after %v, before the initial delay: %v", elapsed, initialDelay)
+	}
+	if em.Label("warmup") != "true" {
+		t.Errorf("First probe cycle's metrics don't have warmup label: %s", em.String())
+	}
+
+	// Wait for the warm-up window to get over.
+	for {
+		em = <-dataChan
+		if em.Label("warmup") == "" {
+			break
+		}
+		if time.Since(start) > initialDelay+time.Second {
+			t.Fatalf("Warm-up window didn't end")
+		}
+	}
+	if total := em.Metric("total").(metrics.NumValue).Int64(); total != 1 {
+		t.Errorf("First post warm-up total=%d, want=1", total)
+	}
+}
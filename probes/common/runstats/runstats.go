@@ -0,0 +1,324 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package runstats implements a registry of probes' runtime stats, for
+monitoring cloudprober itself: number of goroutines, duration of the last
+probe run, consecutive failures, and time since the last successful run.
+
+Probes record their runs using RecordRun, and the prober feeds probes'
+results to the registry using Update. Goroutines are attributed to probes
+through the "probe" pprof label, which is set by the prober for each probe's
+goroutine (and inherited by the goroutines it starts).
+*/
+package runstats
+
+import (
+	"bytes"
+	"regexp"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// ProbeLabel is the pprof label used to attribute goroutines to probes.
+const ProbeLabel = "probe"
+
+// Stats captures a probe's runtime stats.
+type Stats struct {
+	Probe      string `json:"probe"`
+	Goroutines int    `json:"goroutines"`
+
+	LastRun             time.Time `json:"last_run"`
+	LastRunDurationMsec float64   `json:"last_run_duration_msec"`
+
+	// ConsecutiveFailures is the number of failed runs since the last
+	// successful run, for the target with the most consecutive failures.
+	ConsecutiveFailures int64 `json:"consecutive_failures"`
+
+	// LastSuccess is the time of the most recent successful run across all
+	// targets. TimeSinceLastSuccessSec is -1 if probe has not succeeded yet.
+	LastSuccess             time.Time `json:"last_success"`
+	TimeSinceLastSuccessSec float64   `json:"time_since_last_success_sec"`
+}
+
+type targetState struct {
+	consecutiveFailures int64
+}
+
+type probeState struct {
+	lastRun         time.Time
+	lastRunDuration time.Duration
+	lastSuccess     time.Time
+	counters        *metrics.SuccessTracker
+	targets         map[string]*targetState // Keyed by EventMetrics' labels.
+
+	// Targets change counters, exported only if recorded.
+	targetsChanges               bool
+	targetsAdded, targetsRemoved int64
+}
+
+// Registry keeps track of probes' runtime stats.
+type Registry struct {
+	mu     sync.Mutex
+	probes map[string]*probeState
+
+	goroutines func() map[string]int // Overridden in tests.
+}
+
+// NewRegistry returns a new registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		probes:     make(map[string]*probeState),
+		goroutines: goroutinesByProbe,
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the default registry, shared by the probes and the prober.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// RecordRun records a probe run that started at the given time and just
+// finished.
+func RecordRun(probe string, start time.Time) {
+	defaultRegistry.RecordRun(probe, start, time.Since(start))
+}
+
+// LastRun returns the start and end times of the probe's last recorded run,
+// or zero times if no run has been recorded yet.
+func LastRun(probe string) (start, end time.Time) {
+	return defaultRegistry.LastRun(probe)
+}
+
+func (r *Registry) state(probe string) *probeState {
+	ps := r.probes[probe]
+	if ps == nil {
+		ps = &probeState{
+			counters: metrics.NewSuccessTracker(),
+			targets:  make(map[string]*targetState),
+		}
+		r.probes[probe] = ps
+	}
+	return ps
+}
+
+// RecordRun records a probe run.
+func (r *Registry) RecordRun(probe string, start time.Time, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ps := r.state(probe)
+	ps.lastRun, ps.lastRunDuration = start, d
+}
+
+// LastRun returns the start and end times of the probe's last recorded run,
+// or zero times if no run has been recorded yet.
+func (r *Registry) LastRun(probe string) (start, end time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ps := r.probes[probe]
+	if ps == nil || ps.lastRun.IsZero() {
+		return time.Time{}, time.Time{}
+	}
+	return ps.lastRun, ps.lastRun.Add(ps.lastRunDuration)
+}
+
+// Update updates a probe's results using the "total" and "success" counters
+// in the given EventMetrics. Since probes report cumulative results at the
+// stats export interval, a report may cover more than one run: a report with
+// at least one success resets the consecutive failures counter, otherwise all
+// the runs in it are counted as consecutive failures.
+func (r *Registry) Update(em *metrics.EventMetrics) {
+	probe := em.Label("probe")
+	if probe == "" {
+		return
+	}
+	total, success, ok := metrics.SuccessCounters(em)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ps := r.state(probe)
+	key := em.LabelsKey()
+	ts := ps.targets[key]
+	if ts == nil {
+		ts = &targetState{}
+		ps.targets[key] = ts
+	}
+
+	d := ps.counters.Update(key, total, success)
+	if d.Success > 0 {
+		ts.consecutiveFailures = 0
+		if em.Timestamp.After(ps.lastSuccess) {
+			ps.lastSuccess = em.Timestamp
+		}
+		return
+	}
+	ts.consecutiveFailures += d.Total
+}
+
+// RecordTargetsChange records a target's addition to, or removal from, the
+// probe's targets.
+func (r *Registry) RecordTargetsChange(probe string, added bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ps := r.state(probe)
+	ps.targetsChanges = true
+	if added {
+		ps.targetsAdded++
+	} else {
+		ps.targetsRemoved++
+	}
+}
+
+// Remove removes a probe's stats, e.g. when the probe is stopped.
+func (r *Registry) Remove(probe string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.probes, probe)
+}
+
+// Snapshot returns the current stats for all probes, sorted by the probe
+// name.
+func (r *Registry) Snapshot() []*Stats {
+	goroutines := r.goroutines()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var result []*Stats
+	for name, ps := range r.probes {
+		s := &Stats{
+			Probe:                   name,
+			Goroutines:              goroutines[name],
+			LastRun:                 ps.lastRun,
+			LastRunDurationMsec:     float64(ps.lastRunDuration) / float64(time.Millisecond),
+			LastSuccess:             ps.lastSuccess,
+			TimeSinceLastSuccessSec: -1,
+		}
+		if !ps.lastSuccess.IsZero() {
+			s.TimeSinceLastSuccessSec = now.Sub(ps.lastSuccess).Seconds()
+		}
+		for _, ts := range ps.targets {
+			if ts.consecutiveFailures > s.ConsecutiveFailures {
+				s.ConsecutiveFailures = ts.consecutiveFailures
+			}
+		}
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Probe < result[j].Probe })
+	return result
+}
+
+// EventMetrics returns the probes' stats as GAUGE EventMetrics, one per
+// probe, for export as cloudprober's self-metrics.
+func (r *Registry) EventMetrics(ts time.Time) []*metrics.EventMetrics {
+	var ems []*metrics.EventMetrics
+	for _, s := range r.Snapshot() {
+		em := metrics.NewEventMetrics(ts).
+			AddMetric("goroutines", metrics.NewInt(int64(s.Goroutines))).
+			AddMetric("last_run_duration_msec", metrics.NewFloat(s.LastRunDurationMsec)).
+			AddMetric("consecutive_failures", metrics.NewInt(s.ConsecutiveFailures)).
+			AddMetric("time_since_last_success_sec", metrics.NewFloat(s.TimeSinceLastSuccessSec)).
+			AddLabel("ptype", "sysvars").
+			AddLabel("probe", s.Probe)
+		em.Kind = metrics.GAUGE
+		ems = append(ems, em)
+	}
+	return append(ems, r.targetsChangesEventMetrics(ts)...)
+}
+
+// targetsChangesEventMetrics returns the targets change counters as
+// CUMULATIVE EventMetrics, for the probes that record them.
+func (r *Registry) targetsChangesEventMetrics(ts time.Time) []*metrics.EventMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var probes []string
+	for name, ps := range r.probes {
+		if ps.targetsChanges {
+			probes = append(probes, name)
+		}
+	}
+	sort.Strings(probes)
+
+	var ems []*metrics.EventMetrics
+	for _, name := range probes {
+		ps := r.probes[name]
+		ems = append(ems, metrics.NewEventMetrics(ts).
+			AddMetric("targets_added_total", metrics.NewInt(ps.targetsAdded)).
+			AddMetric("targets_removed_total", metrics.NewInt(ps.targetsRemoved)).
+			AddLabel("ptype", "sysvars").
+			AddLabel("probe", name))
+	}
+	return ems
+}
+
+var (
+	countRegex = regexp.MustCompile(`^(\d+) @`)
+	labelRegex = regexp.MustCompile(`"` + ProbeLabel + `":("(?:[^"\\]|\\.)*")`)
+)
+
+// goroutinesByProbe returns the number of goroutines per probe, using the
+// goroutine profile's "probe" labels.
+func goroutinesByProbe() map[string]int {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+	return parseGoroutineProfile(buf.String())
+}
+
+// parseGoroutineProfile parses the goroutine profile in the debug=1 format.
+// In this format, each group of identical goroutines starts with a line
+// containing the goroutines count, optionally followed by a labels line:
+//
+//	2 @ 0x43a8c5 0x44a0e5 ...
+//	# labels: {"probe":"probe1"}
+func parseGoroutineProfile(profile string) map[string]int {
+	result := make(map[string]int)
+	count := 0
+	for _, line := range strings.Split(profile, "\n") {
+		if m := countRegex.FindStringSubmatch(line); m != nil {
+			count, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if !strings.HasPrefix(line, "# labels:") {
+			continue
+		}
+		m := labelRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if probe, err := strconv.Unquote(m[1]); err == nil {
+			result[probe] += count
+		}
+	}
+	return result
+}
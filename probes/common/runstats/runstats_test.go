@@ -0,0 +1,198 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runstats
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// mockProbe reports cumulative total and success counters for a target, the
+// way probes do.
+type mockProbe struct {
+	name           string
+	total, success int64
+}
+
+func (mp *mockProbe) run(r *Registry, ts time.Time, results ...bool) {
+	for _, ok := range results {
+		r.RecordRun(mp.name, ts, 10*time.Millisecond)
+		mp.total++
+		if ok {
+			mp.success++
+		}
+	}
+	r.Update(metrics.NewEventMetrics(ts).
+		AddMetric("total", metrics.NewInt(mp.total)).
+		AddMetric("success", metrics.NewInt(mp.success)).
+		AddLabel("probe", mp.name).
+		AddLabel("dst", "target1"))
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.goroutines = func() map[string]int { return map[string]int{"p1": 3} }
+	mp := &mockProbe{name: "p1"}
+	start := time.Now().Add(-time.Minute)
+
+	for _, test := range []struct {
+		desc                    string
+		results                 []bool
+		wantConsecutiveFailures int64
+		wantNeverSucceeded      bool
+	}{
+		{desc: "failures_before_first_success", results: []bool{false, false}, wantConsecutiveFailures: 2, wantNeverSucceeded: true},
+		{desc: "success", results: []bool{false, true}, wantConsecutiveFailures: 0},
+		{desc: "failure", results: []bool{false}, wantConsecutiveFailures: 1},
+		{desc: "more_failures", results: []bool{false, false, false}, wantConsecutiveFailures: 4},
+		{desc: "success_resets", results: []bool{true}, wantConsecutiveFailures: 0},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			start = start.Add(time.Second)
+			mp.run(r, start, test.results...)
+
+			stats := r.Snapshot()
+			if len(stats) != 1 {
+				t.Fatalf("Got stats for %d probes, want 1", len(stats))
+			}
+			s := stats[0]
+			if s.Probe != "p1" || s.Goroutines != 3 || s.LastRunDurationMsec != 10 || !s.LastRun.Equal(start) {
+				t.Errorf("Got stats: %+v", s)
+			}
+			if s.ConsecutiveFailures != test.wantConsecutiveFailures {
+				t.Errorf("ConsecutiveFailures=%d, want=%d", s.ConsecutiveFailures, test.wantConsecutiveFailures)
+			}
+			if test.wantNeverSucceeded {
+				if s.TimeSinceLastSuccessSec != -1 {
+					t.Errorf("TimeSinceLastSuccessSec=%f, want=-1", s.TimeSinceLastSuccessSec)
+				}
+				return
+			}
+			if s.TimeSinceLastSuccessSec < 0 {
+				t.Errorf("TimeSinceLastSuccessSec=%f, want >= 0", s.TimeSinceLastSuccessSec)
+			}
+		})
+	}
+
+	ems := r.EventMetrics(time.Now())
+	if len(ems) != 1 {
+		t.Fatalf("Got %d EventMetrics, want 1", len(ems))
+	}
+	if ems[0].Kind != metrics.GAUGE || ems[0].Label("probe") != "p1" || ems[0].Metric("consecutive_failures").(metrics.NumValue).Int64() != 0 {
+		t.Errorf("Unexpected EventMetrics: %s", ems[0].String())
+	}
+
+	r.Remove("p1")
+	if len(r.Snapshot()) != 0 {
+		t.Errorf("Got stats after removing the probe: %v", r.Snapshot())
+	}
+}
+
+func TestLastRun(t *testing.T) {
+	r := NewRegistry()
+	if start, end := r.LastRun("p1"); !start.IsZero() || !end.IsZero() {
+		t.Errorf("Got last run (%v, %v) before any runs, want zero times", start, end)
+	}
+
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	r.RecordRun("p1", start, 250*time.Millisecond)
+	gotStart, gotEnd := r.LastRun("p1")
+	if !gotStart.Equal(start) || !gotEnd.Equal(start.Add(250*time.Millisecond)) {
+		t.Errorf("Got last run (%v, %v), want (%v, %v)", gotStart, gotEnd, start, start.Add(250*time.Millisecond))
+	}
+}
+
+func TestMultipleTargets(t *testing.T) {
+	r := NewRegistry()
+	r.goroutines = func() map[string]int { return nil }
+
+	// One target keeps failing, other keeps succeeding.
+	for i := int64(1); i <= 3; i++ {
+		for dst, success := range map[string]int64{"t1": 0, "t2": i} {
+			r.Update(metrics.NewEventMetrics(time.Now()).
+				AddMetric("total", metrics.NewInt(i)).
+				AddMetric("success", metrics.NewInt(success)).
+				AddLabel("probe", "p1").
+				AddLabel("dst", dst))
+		}
+	}
+
+	s := r.Snapshot()[0]
+	if s.ConsecutiveFailures != 3 || s.TimeSinceLastSuccessSec < 0 {
+		t.Errorf("Got stats: %+v, want consecutive failures=3 and a last success", s)
+	}
+}
+
+func TestTargetsChanges(t *testing.T) {
+	r := NewRegistry()
+	r.goroutines = func() map[string]int { return nil }
+
+	r.RecordRun("p1", time.Now(), time.Millisecond)
+	for _, added := range []bool{true, true, false} {
+		r.RecordTargetsChange("p2", added)
+	}
+
+	ems := r.EventMetrics(time.Now())
+	if len(ems) != 3 {
+		t.Fatalf("Got %d EventMetrics, want 3 (2 GAUGE, 1 CUMULATIVE)", len(ems))
+	}
+	em := ems[2]
+	if em.Kind != metrics.CUMULATIVE || em.Label("probe") != "p2" {
+		t.Fatalf("Unexpected targets changes EventMetrics: %s", em.String())
+	}
+	for name, want := range map[string]int64{"targets_added_total": 2, "targets_removed_total": 1} {
+		if got := em.Metric(name).(metrics.NumValue).Int64(); got != want {
+			t.Errorf("%s=%d, want=%d", name, got, want)
+		}
+	}
+}
+
+func TestGoroutinesByProbe(t *testing.T) {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for probe, n := range map[string]int{"probe1": 2, `probe "2"`: 3} {
+		labels := pprof.WithLabels(context.Background(), pprof.Labels(ProbeLabel, probe))
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pprof.SetGoroutineLabels(labels)
+				<-stop
+			}()
+		}
+	}
+
+	// Wait for the goroutines to set their labels.
+	var got map[string]int
+	for i := 0; i < 100; i++ {
+		got = goroutinesByProbe()
+		if got["probe1"] == 2 && got[`probe "2"`] == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	if got["probe1"] != 2 || got[`probe "2"`] != 3 {
+		t.Errorf("goroutinesByProbe()=%v, want probe1: 2, probe \"2\": 3", got)
+	}
+}
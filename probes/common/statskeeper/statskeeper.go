@@ -20,9 +20,12 @@ package statskeeper
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 )
@@ -37,6 +40,79 @@ type ProbeResult interface {
 	Target() string
 }
 
+// RawLatencyResult is implemented by the probe results that can report the
+// latency of the single probe run they represent. It's used to export raw
+// latency samples, see Options.ExportRawLatency.
+type RawLatencyResult interface {
+	// RawLatency returns the latency of the probe run, and false if there is
+	// no latency to report, e.g. for failed probe runs.
+	RawLatency() (time.Duration, bool)
+}
+
+// labelsKey returns a key for the EventMetrics' labels and kind. Probe results
+// can carry labels to report more than one set of results per target, e.g.
+// per DNS query type. Probes can also report gauge results, e.g. NTP server's
+// clock offset, next to their regular cumulative results. Results are
+// aggregated per target, labels and kind.
+func labelsKey(em *metrics.EventMetrics) string {
+	var keys []string
+	if em.Kind == metrics.GAUGE {
+		keys = append(keys, "gauge")
+	}
+	for _, k := range em.LabelsKeys() {
+		keys = append(keys, k+"="+em.Label(k))
+	}
+	return strings.Join(keys, ",")
+}
+
+// exportEM returns a copy of the target's aggregated EventMetrics for export,
+// with the standard labels (ptype, probe, dst) added before the result's
+// labels.
+func exportEM(em *metrics.EventMetrics, ts time.Time, ptype, name, target string) *metrics.EventMetrics {
+	out := metrics.NewEventMetrics(ts).
+		AddLabel("ptype", ptype).
+		AddLabel("probe", name).
+		AddLabel("dst", target)
+	for _, k := range em.LabelsKeys() {
+		out.AddLabel(k, em.Label(k))
+	}
+	for _, k := range em.MetricsKeys() {
+		out.AddMetric(k, em.Metric(k).Clone())
+	}
+	out.Kind = em.Kind
+	return out
+}
+
+// rawLatencyEM returns a GAUGE EventMetrics carrying the raw latency of the
+// given probe result, or nil if the result has no latency to report.
+func rawLatencyEM(result ProbeResult, em *metrics.EventMetrics, ptype, name string, opts *options.Options) *metrics.EventMetrics {
+	rlr, ok := result.(RawLatencyResult)
+	if !ok {
+		return nil
+	}
+	latency, ok := rlr.RawLatency()
+	if !ok {
+		return nil
+	}
+	return opts.RawLatencyEM(em.Timestamp, ptype, name, result.Target(), latency, em)
+}
+
+// addSLOMetrics adds the SLO counters to the given probe result's
+// EventMetrics. Result counts as good if it has a latency to report, i.e. it
+// succeeded, and its latency is under the objective.
+func addSLOMetrics(result ProbeResult, em *metrics.EventMetrics, opts *options.Options) {
+	rlr, ok := result.(RawLatencyResult)
+	if !ok || em.Kind == metrics.GAUGE {
+		return
+	}
+	var good int64
+	if latency, ok := rlr.RawLatency(); ok && opts.MeetsLatencyObjective(latency) {
+		good = 1
+	}
+	em.AddMetric("slo_good_total", metrics.NewInt(good)).
+		AddMetric("slo_total", metrics.NewInt(1))
+}
+
 // StatsKeeper manages and outputs probe results.
 //
 // Typical StatsKeeper usage pattern is that the probes start a StatsKeeper
@@ -49,7 +125,9 @@ type ProbeResult interface {
 // indefinitely, across multiple probe runs, and should not stop during normal
 // program execution.
 //
-// If we get a new result on resultsChan, update the probe statistics.
+// If we get a new result on resultsChan, update the probe statistics. If
+// raw latency export is enabled, also export the result's latency right away.
+// If SLO is configured, result's SLO counters are updated as well.
 // If we get a timer tick on doExport, export probe data for all targets.
 // If context is canceled, return.
 //
@@ -58,7 +136,8 @@ type ProbeResult interface {
 // arguments. We do that as the list of targets is usually dynamic and is
 // updated on a regular basis.
 func StatsKeeper(ctx context.Context, ptype, name string, opts *options.Options, targetsFunc func() []endpoint.Endpoint, resultsChan <-chan ProbeResult, dataChan chan<- *metrics.EventMetrics) {
-	targetMetrics := make(map[string]*metrics.EventMetrics)
+	// Target -> result labels key -> aggregated metrics.
+	targetMetrics := make(map[string]map[string]*metrics.EventMetrics)
 	exportTicker := time.NewTicker(opts.StatsExportInterval)
 	defer exportTicker.Stop()
 
@@ -67,23 +146,42 @@ func StatsKeeper(ctx context.Context, ptype, name string, opts *options.Options,
 		case result := <-resultsChan:
 			// result is a ProbeResult
 			t := result.Target()
+			em := result.Metrics()
+			key := labelsKey(em)
+			if opts.SLOLatencyObjective > 0 {
+				addSLOMetrics(result, em, opts)
+			}
+			if opts.ExportRawLatency {
+				if rawEM := rawLatencyEM(result, em, ptype, name, opts); rawEM != nil {
+					if opts.LogMetrics != nil {
+						opts.LogMetrics(rawEM)
+					}
+					dataChan <- rawEM
+				}
+			}
 			if targetMetrics[t] == nil {
-				targetMetrics[t] = result.Metrics()
+				targetMetrics[t] = make(map[string]*metrics.EventMetrics)
+			}
+			if targetMetrics[t][key] == nil {
+				targetMetrics[t][key] = em
 				continue
 			}
-			err := targetMetrics[t].Update(result.Metrics())
+			err := targetMetrics[t][key].Update(em)
 			if err != nil {
 				opts.Logger.Errorf("Error adding metrics from the probe result for the target: %s. Err: %v", t, err)
 			}
 		case ts := <-exportTicker.C:
+			start, end := runstats.LastRun(name)
+			ts = opts.MetricTimestamp(ts, start, end)
 			for _, t := range targetsFunc() {
-				em := targetMetrics[t.Name]
-				if em != nil {
-					em.AddLabel("ptype", ptype)
-					em.AddLabel("probe", name)
-					em.AddLabel("dst", t.Name)
-					em.Timestamp = ts
+				var keys []string
+				for key := range targetMetrics[t.Name] {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
 
+				for _, key := range keys {
+					em := exportEM(targetMetrics[t.Name][key], ts, ptype, name, t.Name)
 					em.LatencyUnit = opts.LatencyUnit
 
 					for _, al := range opts.AdditionalLabels {
@@ -93,7 +191,7 @@ func StatsKeeper(ctx context.Context, ptype, name string, opts *options.Options,
 					if opts.LogMetrics != nil {
 						opts.LogMetrics(em)
 					}
-					dataChan <- em.Clone()
+					dataChan <- em
 				}
 			}
 		case <-ctx.Done():
@@ -16,11 +16,14 @@ package statskeeper
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/probes/options"
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 )
 
@@ -112,3 +115,269 @@ func TestStatsKeeper(t *testing.T) {
 		}
 	}
 }
+
+// labeledResult is a probe result that carries a label, e.g. query type.
+type labeledResult struct {
+	probeRunResult
+	label string
+}
+
+func (lr labeledResult) Metrics() *metrics.EventMetrics {
+	return lr.probeRunResult.Metrics().AddLabel("type", lr.label)
+}
+
+func TestStatsKeeperLabeledResults(t *testing.T) {
+	targets := []endpoint.Endpoint{{Name: "target1"}}
+	resultsChan := make(chan ProbeResult, 10)
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	opts := &options.Options{
+		StatsExportInterval: 500 * time.Millisecond,
+	}
+	go StatsKeeper(ctx, "test", "testProbe", opts, func() []endpoint.Endpoint { return targets }, resultsChan, dataChan)
+
+	// 2 results for label "a", 1 for label "b".
+	for _, label := range []string{"a", "b", "a"} {
+		lr := labeledResult{probeRunResult: newProbeRunResult("target1"), label: label}
+		lr.sent.Inc()
+		resultsChan <- lr
+	}
+
+	wantSent := map[string]int64{"a": 2, "b": 1}
+	for i := 0; i < len(wantSent); i++ {
+		var em *metrics.EventMetrics
+		select {
+		case em = <-dataChan:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the EventMetrics")
+		}
+
+		wantLabels := []string{"ptype", "probe", "dst", "type"}
+		if !reflect.DeepEqual(em.LabelsKeys(), wantLabels) {
+			t.Errorf("Got labels: %v, want: %v", em.LabelsKeys(), wantLabels)
+		}
+		label := em.Label("type")
+		if got := em.Metric("sent").(metrics.NumValue).Int64(); got != wantSent[label] {
+			t.Errorf("Label %s: sent=%d, want=%d", label, got, wantSent[label])
+		}
+	}
+}
+
+// gaugeResult is a gauge probe result, reported along with the regular
+// results.
+type gaugeResult struct {
+	target string
+	value  int64
+}
+
+func (gr gaugeResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).AddMetric("value", metrics.NewInt(gr.value))
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+func (gr gaugeResult) Target() string {
+	return gr.target
+}
+
+func TestStatsKeeperGaugeResults(t *testing.T) {
+	targets := []endpoint.Endpoint{{Name: "target1"}}
+	resultsChan := make(chan ProbeResult, 10)
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	opts := &options.Options{
+		StatsExportInterval: 500 * time.Millisecond,
+	}
+	go StatsKeeper(ctx, "test", "testProbe", opts, func() []endpoint.Endpoint { return targets }, resultsChan, dataChan)
+
+	for _, v := range []int64{5, 7} {
+		prr := newProbeRunResult("target1")
+		prr.sent.Inc()
+		resultsChan <- prr
+		resultsChan <- gaugeResult{target: "target1", value: v}
+	}
+
+	var gotSent, gotValue int64
+	for i := 0; i < 2; i++ {
+		var em *metrics.EventMetrics
+		select {
+		case em = <-dataChan:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the EventMetrics")
+		}
+
+		switch em.Kind {
+		case metrics.CUMULATIVE:
+			gotSent = em.Metric("sent").(metrics.NumValue).Int64()
+		case metrics.GAUGE:
+			gotValue = em.Metric("value").(metrics.NumValue).Int64()
+		}
+	}
+	if gotSent != 2 || gotValue != 7 {
+		t.Errorf("Got sent=%d, value=%d, want sent=2, value=7", gotSent, gotValue)
+	}
+}
+
+// rawLatencyResult is a probe result that reports its raw latency.
+type rawLatencyResult struct {
+	probeRunResult
+	latency time.Duration
+}
+
+func (rr rawLatencyResult) RawLatency() (time.Duration, bool) {
+	return rr.latency, rr.latency != 0
+}
+
+func TestStatsKeeperExportRawLatency(t *testing.T) {
+	targets := []endpoint.Endpoint{{Name: "target1"}, {Name: "target2"}}
+	resultsChan := make(chan ProbeResult, 10)
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	opts := &options.Options{
+		StatsExportInterval: time.Hour,
+		LatencyMetricName:   "latency",
+		LatencyUnit:         time.Millisecond,
+		ExportRawLatency:    true,
+	}
+	go StatsKeeper(ctx, "test", "testProbe", opts, func() []endpoint.Endpoint { return targets }, resultsChan, dataChan)
+
+	// Last result is a failed run, without latency.
+	latencies := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 0}
+	for i, latency := range latencies {
+		resultsChan <- rawLatencyResult{
+			probeRunResult: newProbeRunResult(targets[i%2].Name),
+			latency:        latency,
+		}
+	}
+
+	// We should get one raw EventMetrics per successful result, in order.
+	for i, latency := range latencies[:3] {
+		var em *metrics.EventMetrics
+		select {
+		case em = <-dataChan:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the raw latency EventMetrics")
+		}
+
+		if em.Kind != metrics.GAUGE {
+			t.Errorf("Got kind: %v, want: GAUGE", em.Kind)
+		}
+		if got, want := em.Label("dst"), targets[i%2].Name; got != want {
+			t.Errorf("Got dst: %s, want: %s", got, want)
+		}
+		wantLatency := float64(latency / time.Millisecond)
+		if got := em.Metric("latency_raw").(metrics.NumValue).Float64(); got != wantLatency {
+			t.Errorf("Got latency_raw: %f, want: %f", got, wantLatency)
+		}
+	}
+
+	select {
+	case em := <-dataChan:
+		t.Errorf("Got unexpected EventMetrics: %s", em.String())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStatsKeeperSLO(t *testing.T) {
+	targets := []endpoint.Endpoint{{Name: "target1"}}
+	resultsChan := make(chan ProbeResult, 10)
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	opts := &options.Options{
+		StatsExportInterval: 500 * time.Millisecond,
+		SLOLatencyObjective: 200 * time.Millisecond,
+	}
+	go StatsKeeper(ctx, "test", "testProbe", opts, func() []endpoint.Endpoint { return targets }, resultsChan, dataChan)
+
+	// Fast success, slow success, failure (no latency) and another fast
+	// success.
+	for _, latency := range []time.Duration{50 * time.Millisecond, 300 * time.Millisecond, 0, 100 * time.Millisecond} {
+		rr := rawLatencyResult{probeRunResult: newProbeRunResult("target1"), latency: latency}
+		rr.sent.Inc()
+		if latency != 0 {
+			rr.rcvd.Inc()
+		}
+		resultsChan <- rr
+	}
+
+	var em *metrics.EventMetrics
+	select {
+	case em = <-dataChan:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for the EventMetrics")
+	}
+
+	// Regular metrics are not affected.
+	wantValues := map[string]int64{
+		"sent":           4,
+		"rcvd":           3,
+		"slo_good_total": 2,
+		"slo_total":      4,
+	}
+	for key, want := range wantValues {
+		if got := em.Metric(key).(metrics.NumValue).Int64(); got != want {
+			t.Errorf("%s: got=%d, want=%d", key, got, want)
+		}
+	}
+}
+
+func TestStatsKeeperTimestampSource(t *testing.T) {
+	cycleStart := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	cycleEnd := cycleStart.Add(300 * time.Millisecond)
+
+	for _, test := range []struct {
+		source configpb.ProbeDef_TimestampSource
+		want   time.Time // Zero for the export time.
+	}{
+		{source: configpb.ProbeDef_EMIT},
+		{source: configpb.ProbeDef_CYCLE_START, want: cycleStart},
+		{source: configpb.ProbeDef_CYCLE_END, want: cycleEnd},
+	} {
+		t.Run(test.source.String(), func(t *testing.T) {
+			probeName := "testProbe_" + test.source.String()
+			runstats.Default().RecordRun(probeName, cycleStart, cycleEnd.Sub(cycleStart))
+			defer runstats.Default().Remove(probeName)
+
+			targets := []endpoint.Endpoint{{Name: "target1"}}
+			resultsChan := make(chan ProbeResult, 10)
+			dataChan := make(chan *metrics.EventMetrics, 10)
+			ctx, cancelFunc := context.WithCancel(context.Background())
+			defer cancelFunc()
+
+			opts := &options.Options{
+				StatsExportInterval: 100 * time.Millisecond,
+				TimestampSource:     test.source,
+			}
+			go StatsKeeper(ctx, "test", probeName, opts, func() []endpoint.Endpoint { return targets }, resultsChan, dataChan)
+
+			rr := newProbeRunResult("target1")
+			rr.sent.Inc()
+			resultsChan <- rr
+
+			var em *metrics.EventMetrics
+			select {
+			case em = <-dataChan:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out waiting for the EventMetrics")
+			}
+
+			if test.want.IsZero() {
+				if time.Since(em.Timestamp) > time.Minute {
+					t.Errorf("Got timestamp=%v, want the export time", em.Timestamp)
+				}
+				return
+			}
+			if !em.Timestamp.Equal(test.want) {
+				t.Errorf("Got timestamp=%v, want=%v", em.Timestamp, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
+	"github.com/miekg/dns"
+)
+
+// answerMatcher checks the answer records against the expected answers.
+type answerMatcher struct {
+	qtype  uint16 // 0 if it applies to all query types.
+	rdata  map[string]bool
+	exact  bool
+	re     *regexp.Regexp
+	minTTL uint32
+}
+
+func newAnswerMatcher(c *configpb.ExpectedAnswers) (*answerMatcher, error) {
+	if len(c.GetRdata()) == 0 && c.RdataRegex == nil && c.MinTtl == nil {
+		return nil, errors.New("expected_answers: one of rdata, rdata_regex or min_ttl should be configured")
+	}
+
+	am := &answerMatcher{
+		qtype:  uint16(c.GetQueryType()),
+		exact:  c.GetMatch() == configpb.ExpectedAnswers_EXACT,
+		minTTL: c.GetMinTtl(),
+	}
+	if len(c.GetRdata()) > 0 {
+		am.rdata = make(map[string]bool)
+		for _, rdata := range c.GetRdata() {
+			am.rdata[strings.ToLower(rdata)] = true
+		}
+	}
+	if c.RdataRegex != nil {
+		re, err := regexp.Compile(c.GetRdataRegex())
+		if err != nil {
+			return nil, fmt.Errorf("expected_answers: invalid rdata_regex (%s): %v", c.GetRdataRegex(), err)
+		}
+		am.re = re
+	}
+	return am, nil
+}
+
+// answerRdata returns the record's RDATA in the zone file format.
+func answerRdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// check checks the answer records of the query type, and returns the
+// mismatch reason and details, if they don't match the expected answers.
+func (am *answerMatcher) check(answers []dns.RR, qtype uint16) (string, string) {
+	if am.qtype != 0 && am.qtype != qtype {
+		return "", ""
+	}
+
+	got := make(map[string]bool)
+	for _, rr := range answers {
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+		rdata := answerRdata(rr)
+
+		if rr.Header().Ttl < am.minTTL {
+			return "low_ttl", fmt.Sprintf("TTL %d for %s is less than %d", rr.Header().Ttl, rdata, am.minTTL)
+		}
+		if am.re != nil && !am.re.MatchString(rdata) {
+			return "rdata_mismatch", fmt.Sprintf("%s doesn't match the regex %s", rdata, am.re.String())
+		}
+		if am.rdata != nil && !am.rdata[strings.ToLower(rdata)] {
+			return "unexpected_record", fmt.Sprintf("%s is not an expected answer", rdata)
+		}
+		got[strings.ToLower(rdata)] = true
+	}
+
+	if am.exact {
+		for rdata := range am.rdata {
+			if !got[rdata] {
+				return "missing_record", fmt.Sprintf("expected answer %s is missing", rdata)
+			}
+		}
+	}
+	return "", ""
+}
+
+// checkAnswers checks the response's answers against all the expected
+// answers, and returns the first mismatch reason and details, if any.
+func (p *Probe) checkAnswers(resp *dns.Msg, qtype uint16) (string, string) {
+	for _, am := range p.answerMatchers {
+		if reason, details := am.check(resp.Answer, qtype); reason != "" {
+			return reason, details
+		}
+	}
+	return "", ""
+}
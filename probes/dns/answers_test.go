@@ -0,0 +1,194 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/golang/protobuf/proto"
+	"github.com/miekg/dns"
+)
+
+// answersClient answers all queries with the configured records.
+type answersClient struct {
+	mockClient
+	answers []string
+}
+
+func (ac *answersClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	out := new(dns.Msg)
+	out.SetReply(in)
+	for _, s := range ac.answers {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		out.Answer = append(out.Answer, rr)
+	}
+	return out, time.Millisecond, nil
+}
+
+func TestExpectedAnswers(t *testing.T) {
+	answers := []string{
+		"www.example.com. 300 IN CNAME web.example.com.",
+		"web.example.com. 300 IN A 192.168.0.1",
+		"web.example.com. 30 IN A 192.168.0.2",
+	}
+
+	for _, test := range []struct {
+		desc       string
+		expected   []*configpb.ExpectedAnswers
+		wantReason string
+	}{
+		{
+			desc: "exact",
+			expected: []*configpb.ExpectedAnswers{
+				{Rdata: []string{"192.168.0.2", "192.168.0.1"}},
+			},
+		},
+		{
+			desc: "exact_missing",
+			expected: []*configpb.ExpectedAnswers{
+				{Rdata: []string{"192.168.0.1", "192.168.0.2", "192.168.0.3"}},
+			},
+			wantReason: "missing_record",
+		},
+		{
+			desc: "exact_unexpected",
+			expected: []*configpb.ExpectedAnswers{
+				{Rdata: []string{"192.168.0.1"}},
+			},
+			wantReason: "unexpected_record",
+		},
+		{
+			desc: "subset",
+			expected: []*configpb.ExpectedAnswers{
+				{
+					Rdata: []string{"192.168.0.1", "192.168.0.2", "192.168.0.3"},
+					Match: configpb.ExpectedAnswers_SUBSET.Enum(),
+				},
+			},
+		},
+		{
+			desc: "subset_unexpected",
+			expected: []*configpb.ExpectedAnswers{
+				{
+					Rdata: []string{"192.168.0.1", "192.168.0.3"},
+					Match: configpb.ExpectedAnswers_SUBSET.Enum(),
+				},
+			},
+			wantReason: "unexpected_record",
+		},
+		{
+			desc: "regex",
+			expected: []*configpb.ExpectedAnswers{
+				{RdataRegex: proto.String(`^192\.168\.0\.`)},
+			},
+		},
+		{
+			desc: "regex_mismatch",
+			expected: []*configpb.ExpectedAnswers{
+				{RdataRegex: proto.String(`^192\.168\.0\.1$`)},
+			},
+			wantReason: "rdata_mismatch",
+		},
+		{
+			desc: "min_ttl",
+			expected: []*configpb.ExpectedAnswers{
+				{MinTtl: proto.Uint32(30)},
+			},
+		},
+		{
+			desc: "low_ttl",
+			expected: []*configpb.ExpectedAnswers{
+				{MinTtl: proto.Uint32(60)},
+			},
+			wantReason: "low_ttl",
+		},
+		{
+			desc: "other_query_type",
+			expected: []*configpb.ExpectedAnswers{
+				{QueryType: configpb.QueryType_AAAA.Enum(), Rdata: []string{"2001:db8::1"}},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			if err := p.Init("dns_answers_test", &options.Options{
+				Targets:  targets.StaticTargets("8.8.8.8"),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					ResolvedDomain:  proto.String("www.example.com."),
+					QueryType:       configpb.QueryType_A.Enum(),
+					ExpectedAnswers: test.expected,
+				},
+			}); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+			p.client = &answersClient{answers: answers}
+
+			resultsChan := make(chan statskeeper.ProbeResult, 1)
+			p.runProbe(context.Background(), resultsChan, nil)
+			result := (<-resultsChan).(probeRunResult)
+
+			var wantSuccess, wantCount int64 = 1, 0
+			if test.wantReason != "" {
+				wantSuccess, wantCount = 0, 1
+			}
+			if result.success.Int64() != wantSuccess {
+				t.Errorf("Got success=%d, want=%d", result.success.Int64(), wantSuccess)
+			}
+			for _, reason := range []string{"missing_record", "unexpected_record", "rdata_mismatch", "low_ttl"} {
+				var want int64
+				if reason == test.wantReason {
+					want = wantCount
+				}
+				got := int64(0)
+				if v := result.unexpectedAnswer.GetKey(reason); v != nil {
+					got = v.Int64()
+				}
+				if got != want {
+					t.Errorf("Got unexpected_answer[%s]=%d, want=%d", reason, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpectedAnswersInitErrors(t *testing.T) {
+	for _, expected := range []*configpb.ExpectedAnswers{
+		{},
+		{Match: configpb.ExpectedAnswers_SUBSET.Enum()},
+		{RdataRegex: proto.String("192.168.(")},
+	} {
+		p := &Probe{}
+		err := p.Init("dns_test", &options.Options{
+			Targets:   targets.StaticTargets("8.8.8.8"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: &configpb.ProbeConf{ExpectedAnswers: []*configpb.ExpectedAnswers{expected}},
+		})
+		if err == nil {
+			t.Errorf("Init(expected_answers=%v): expected error", expected)
+		}
+	}
+}
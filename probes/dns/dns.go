@@ -13,9 +13,10 @@
 // limitations under the License.
 
 /*
-Package dns implements a DNS prober. It sends UDP DNS queries to a list of
+Package dns implements a DNS prober. It sends DNS queries to a list of
 targets and reports statistics on queries sent, queries received, and latency
-experienced.
+experienced. Queries are sent over UDP by default, and can also be sent over
+TCP, TLS (DNS-over-TLS) or HTTPS (DNS-over-HTTPS).
 
 This prober uses the DNS library in /third_party/golang/dns/dns to construct,
 send, and receive DNS messages. Every message is sent on a different UDP port.
@@ -25,18 +26,22 @@ package dns
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
 	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
 	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/validators"
 	"github.com/miekg/dns"
@@ -47,7 +52,7 @@ import (
 type Client interface {
 	Exchange(*dns.Msg, string) (*dns.Msg, time.Duration, error)
 	setReadTimeout(time.Duration)
-	setSourceIP(net.IP)
+	setSourceIP(net.IP, string)
 }
 
 // ClientImpl is a concrete DNS client that can be instantiated.
@@ -61,9 +66,13 @@ func (c *clientImpl) setReadTimeout(d time.Duration) {
 }
 
 // setSourceIP allows write-access to the underlying ReadTimeout variable.
-func (c *clientImpl) setSourceIP(ip net.IP) {
+func (c *clientImpl) setSourceIP(ip net.IP, zone string) {
+	var localAddr net.Addr = &net.UDPAddr{IP: ip, Zone: zone}
+	if c.Net == "tcp" {
+		localAddr = &net.TCPAddr{IP: ip, Zone: zone}
+	}
 	c.Dialer = &net.Dialer{
-		LocalAddr: &net.UDPAddr{IP: ip},
+		LocalAddr: localAddr,
 	}
 }
 
@@ -76,8 +85,22 @@ type Probe struct {
 
 	// book-keeping params
 	targets []endpoint.Endpoint
-	msg     *dns.Msg
+	queries []*query
 	client  Client
+	port    int
+
+	// Trust anchors for the DNSSEC chain validation.
+	trustAnchors []*dns.DS
+
+	answerMatchers []*answerMatcher
+}
+
+// query is a DNS query sent to each target in every probe cycle.
+type query struct {
+	// Query type label for the results. It's set only if multiple query types
+	// are configured through the query_types field.
+	qtype string
+	msg   *dns.Msg
 }
 
 // probeRunResult captures the results of a single probe run. The way we work with
@@ -92,16 +115,58 @@ type probeRunResult struct {
 	timeouts          metrics.Int
 	validationFailure *metrics.Map
 	latencyMetricName string
+
+	// Set only if probe is configured with multiple query types.
+	queryType string
+	answers   metrics.Int
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// DNSSEC metrics are exported only if require_dnssec is enabled.
+	dnssecValidated metrics.Int
+	dnssecFailure   *metrics.Map
+
+	// unexpectedAnswer is exported only if expected_answers are configured.
+	unexpectedAnswer *metrics.Map
+
+	// Update to the DNSSEC signature expiry gauge, reported as a separate
+	// result. It's set only for the LOCAL DNSSEC validation.
+	dnssecUpdate *dnssecResult
+
+	// Update to the TLS handshake gauge, reported as a separate result. It's
+	// set only for the queries sent over the encrypted transports.
+	tlsUpdate *tlsResult
 }
 
 // Metrics converts probeRunResult into metrics.EventMetrics object
 func (prr probeRunResult) Metrics() *metrics.EventMetrics {
-	return metrics.NewEventMetrics(time.Now()).
+	em := metrics.NewEventMetrics(time.Now()).
 		AddMetric("total", &prr.total).
 		AddMetric("success", &prr.success).
 		AddMetric(prr.latencyMetricName, prr.latency).
 		AddMetric("timeouts", &prr.timeouts).
 		AddMetric("validation_failure", prr.validationFailure)
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	if prr.queryType != "" {
+		em.AddMetric("answers", &prr.answers)
+		em.AddLabel("query_type", prr.queryType)
+	}
+	if prr.dnssecFailure != nil {
+		em.AddMetric("dnssec_validated", &prr.dnssecValidated)
+		em.AddMetric("dnssec_failure", prr.dnssecFailure)
+	}
+	if prr.unexpectedAnswer != nil {
+		em.AddMetric("unexpected_answer", prr.unexpectedAnswer)
+	}
+	return em
 }
 
 // Target returns the p.target.
@@ -109,6 +174,11 @@ func (prr probeRunResult) Target() string {
 	return prr.target
 }
 
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
 func (p *Probe) updateTargets() {
 	p.targets = p.opts.Targets.ListEndpoints()
 
@@ -119,6 +189,33 @@ func (p *Probe) updateTargets() {
 	}
 }
 
+// ednsClientSubnet returns the EDNS0 Client Subnet option for the config.
+func ednsClientSubnet(c *configpb.EDNSClientSubnet) (*dns.EDNS0_SUBNET, error) {
+	ip := net.ParseIP(c.GetAddress())
+	if ip == nil {
+		return nil, fmt.Errorf("invalid edns_client_subnet address: %s", c.GetAddress())
+	}
+
+	ecs := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	prefix, maxPrefix := uint32(24), uint32(32)
+	if ip4 := ip.To4(); ip4 != nil {
+		ecs.Family, ip = 1, ip4
+	} else {
+		ecs.Family, prefix, maxPrefix = 2, 56, 128
+	}
+
+	if c.Prefix != nil {
+		prefix = c.GetPrefix()
+	}
+	if prefix > maxPrefix {
+		return nil, fmt.Errorf("edns_client_subnet prefix (%d) out of range [0, %d] for %s", prefix, maxPrefix, c.GetAddress())
+	}
+
+	ecs.SourceNetmask = uint8(prefix)
+	ecs.Address = ip.Mask(net.CIDRMask(int(prefix), int(maxPrefix)))
+	return ecs, nil
+}
+
 // Init initializes the probe with the given params.
 func (p *Probe) Init(name string, opts *options.Options) error {
 	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
@@ -137,16 +234,77 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 	// (although the documentation doesn't explicitly say so). It uses locks
 	// internally and the underlying net.Conn declares that multiple goroutines
 	// may invoke methods on a net.Conn simultaneously.
-	p.msg = new(dns.Msg)
-	queryType := p.c.GetQueryType()
-	if queryType == configpb.QueryType_NONE || int32(queryType) >= int32(dns.TypeReserved) {
-		return fmt.Errorf("dns_probe(%v): invalid query type %v", name, queryType)
+	var ecs *dns.EDNS0_SUBNET
+	if p.c.GetEdnsClientSubnet() != nil {
+		var err error
+		if ecs, err = ednsClientSubnet(p.c.GetEdnsClientSubnet()); err != nil {
+			return fmt.Errorf("dns_probe(%v): %v", name, err)
+		}
 	}
-	p.msg.SetQuestion(dns.Fqdn(p.c.GetResolvedDomain()), uint16(queryType))
 
-	p.client = new(clientImpl)
+	localDNSSEC := p.c.GetDnssecValidation() == configpb.ProbeConf_LOCAL
+	if !p.c.GetRequireDnssec() && localDNSSEC {
+		return fmt.Errorf("dns_probe(%v): dnssec_validation is set without require_dnssec", name)
+	}
+	if len(p.c.GetDnssecTrustAnchor()) > 0 && !localDNSSEC {
+		return fmt.Errorf("dns_probe(%v): dnssec_trust_anchor is supported only for the LOCAL dnssec_validation", name)
+	}
+	if localDNSSEC {
+		anchors := p.c.GetDnssecTrustAnchor()
+		if len(anchors) == 0 {
+			anchors = rootTrustAnchors
+		}
+		var err error
+		if p.trustAnchors, err = parseTrustAnchors(anchors); err != nil {
+			return fmt.Errorf("dns_probe(%v): %v", name, err)
+		}
+	}
+
+	p.answerMatchers = nil
+	for _, c := range p.c.GetExpectedAnswers() {
+		am, err := newAnswerMatcher(c)
+		if err != nil {
+			return fmt.Errorf("dns_probe(%v): %v", name, err)
+		}
+		p.answerMatchers = append(p.answerMatchers, am)
+	}
+
+	queryTypes := p.c.GetQueryTypes()
+	if len(queryTypes) == 0 {
+		queryTypes = []configpb.QueryType{p.c.GetQueryType()}
+	}
+
+	p.queries = nil
+	for _, queryType := range queryTypes {
+		if queryType == configpb.QueryType_NONE || int32(queryType) >= int32(dns.TypeReserved) {
+			return fmt.Errorf("dns_probe(%v): invalid query type %v", name, queryType)
+		}
+
+		q := &query{msg: new(dns.Msg)}
+		if len(p.c.GetQueryTypes()) > 0 {
+			q.qtype = queryType.String()
+		}
+		q.msg.SetQuestion(dns.Fqdn(p.c.GetResolvedDomain()), uint16(queryType))
+		q.msg.Question[0].Qclass = uint16(p.c.GetQueryClass())
+
+		if ecs != nil || p.c.GetRequireDnssec() {
+			q.msg.SetEdns0(dns.DefaultMsgSize, p.c.GetRequireDnssec())
+		}
+		if ecs != nil {
+			opt := q.msg.IsEdns0()
+			opt.Option = append(opt.Option, ecs)
+		}
+		if p.c.GetRequireDnssec() && !localDNSSEC {
+			q.msg.AuthenticatedData = true
+		}
+		p.queries = append(p.queries, q)
+	}
+
+	if err := p.initClient(); err != nil {
+		return fmt.Errorf("dns_probe(%v): %v", name, err)
+	}
 	if p.opts.SourceIP != nil {
-		p.client.setSourceIP(p.opts.SourceIP)
+		p.client.setSourceIP(p.opts.SourceIP, p.opts.SourceIPZone)
 	}
 	// Use ReadTimeout because DialTimeout for UDP is not the RTT.
 	p.client.setReadTimeout(p.opts.Timeout)
@@ -154,22 +312,63 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 	return nil
 }
 
-// Return true if the underlying error indicates a dns.Client timeout.
-// In our case, we're using the ReadTimeout- time until response is read.
+// initClient initializes the DNS client and the port for the configured
+// transport.
+func (p *Probe) initClient() error {
+	transport := p.c.GetTransport()
+	if p.c.GetTlsConfig() != nil && transport != configpb.ProbeConf_TLS && transport != configpb.ProbeConf_HTTPS {
+		return fmt.Errorf("tls_config is supported only for the TLS and HTTPS transports, transport: %v", transport)
+	}
+
+	var tlsConfig *tls.Config
+	if transport == configpb.ProbeConf_TLS || transport == configpb.ProbeConf_HTTPS {
+		tlsConfig = &tls.Config{}
+		if p.c.GetTlsConfig() != nil {
+			if err := tlsconfig.UpdateTLSConfig(tlsConfig, p.c.GetTlsConfig(), false); err != nil {
+				return fmt.Errorf("error configuring TLS: %v", err)
+			}
+		}
+	}
+
+	switch transport {
+	case configpb.ProbeConf_UDP:
+		p.client, p.port = new(clientImpl), 53
+	case configpb.ProbeConf_TCP:
+		p.client, p.port = &clientImpl{dns.Client{Net: "tcp"}}, 53
+	case configpb.ProbeConf_TLS:
+		p.client, p.port = newDoTClient(tlsConfig), 853
+	case configpb.ProbeConf_HTTPS:
+		p.client, p.port = newDoHClient(tlsConfig, p.c.GetDohPath()), 443
+	default:
+		return fmt.Errorf("unknown transport: %v", transport)
+	}
+
+	if p.c.Port != nil {
+		p.port = int(p.c.GetPort())
+	}
+	return nil
+}
+
+// Return true if the underlying error indicates a client timeout, e.g. for
+// dns.Client, ReadTimeout - time until response is read.
 func isClientTimeout(err error) bool {
-	e, ok := err.(*net.OpError)
-	return ok && e != nil && e.Timeout()
+	var e interface{ Timeout() bool }
+	return errors.As(err, &e) && e.Timeout()
 }
 
 // validateResponse checks status code and answer section for correctness and
 // returns true if the response is valid. In case of validation failures, it
 // also updates the result structure.
-func (p *Probe) validateResponse(resp *dns.Msg, target string, result *probeRunResult) bool {
+func (p *Probe) validateResponse(resp *dns.Msg, qtype uint16, target string, result *probeRunResult) bool {
 	if resp == nil || resp.Rcode != dns.RcodeSuccess {
 		p.l.Warningf("Target(%s): error in response %v", target, resp)
 		return false
 	}
 
+	if p.c.GetRequireDnssec() && !p.validateDNSSEC(resp, target, result) {
+		return false
+	}
+
 	// Validate number of answers in response.
 	// TODO: Move this logic to validators.
 	minAnswers := p.c.GetMinAnswers()
@@ -179,6 +378,12 @@ func (p *Probe) validateResponse(resp *dns.Msg, target string, result *probeRunR
 		return false
 	}
 
+	if reason, details := p.checkAnswers(resp, qtype); reason != "" {
+		p.l.Warningf("Target(%s): unexpected answer: %s.\n\tAnswerBlock: %v", target, details, resp.Answer)
+		result.unexpectedAnswer.IncKey(reason)
+		return false
+	}
+
 	if p.opts.Validators != nil {
 		answers := []string{}
 		for _, rr := range resp.Answer {
@@ -198,76 +403,227 @@ func (p *Probe) validateResponse(resp *dns.Msg, target string, result *probeRunR
 	return true
 }
 
+// validateDNSSEC checks that the response is DNSSEC validated. For the
+// RESOLVER validation, AD flag should be set and the answer section should
+// contain RRSIG records. For the LOCAL validation, the chain of trust is
+// validated by validateDNSSECChain. It also updates the result structure.
+func (p *Probe) validateDNSSEC(resp *dns.Msg, target string, result *probeRunResult) bool {
+	if p.c.GetDnssecValidation() == configpb.ProbeConf_LOCAL {
+		expiresIn, err := p.validateDNSSECChain(resp, target)
+		result.dnssecUpdate = &dnssecResult{target: result.target, queryType: result.queryType, expiresIn: expiresIn}
+		if err != nil {
+			p.l.Warningf("Target(%s): DNSSEC chain validation failed: %v", target, err)
+			result.dnssecFailure.IncKey("chain_invalid")
+			return false
+		}
+		result.dnssecValidated.Inc()
+		return true
+	}
+
+	if !resp.AuthenticatedData {
+		p.l.Warningf("Target(%s): DNSSEC validation failed: AD flag not set in the response", target)
+		result.dnssecFailure.IncKey("ad_not_set")
+		return false
+	}
+
+	for _, rr := range resp.Answer {
+		if _, ok := rr.(*dns.RRSIG); ok {
+			result.dnssecValidated.Inc()
+			return true
+		}
+	}
+	p.l.Warningf("Target(%s): DNSSEC validation failed: no RRSIG records in the answer section", target)
+	result.dnssecFailure.IncKey("no_rrsig")
+	return false
+}
+
 // resolveFunc resolves the given host for the IP version.
 // This type is mainly used for testing. For all other cases, a nil function
 // should be passed to the runProbe function.
 type resolveFunc func(host string, ipVer int) (net.IP, error)
 
-func (p *Probe) runProbe(resultsChan chan<- statskeeper.ProbeResult, resolveF resolveFunc) {
-	// Refresh the list of targets to probe.
-	p.updateTargets()
+func (p *Probe) newResult(target string, q *query) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		queryType:         q.qtype,
+		latencyMetricName: p.opts.LatencyMetricName,
+		validationFailure: validators.ValidationFailureMap(p.opts.Validators),
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+	}
 
-	wg := sync.WaitGroup{}
-	for _, target := range p.targets {
-		wg.Add(1)
+	if p.opts.LatencyDist != nil {
+		result.latency = p.opts.LatencyDist.Clone()
+	} else {
+		result.latency = metrics.NewFloat(0)
+	}
 
-		// Launch a separate goroutine for each target.
-		// Write probe results to the "resultsChan" channel.
-		go func(target endpoint.Endpoint, resultsChan chan<- statskeeper.ProbeResult) {
-			defer wg.Done()
+	if p.c.GetRequireDnssec() {
+		result.dnssecFailure = metrics.NewMap("reason", metrics.NewInt(0))
+	}
+	if len(p.answerMatchers) > 0 {
+		result.unexpectedAnswer = metrics.NewMap("reason", metrics.NewInt(0))
+	}
+	return result
+}
 
-			result := probeRunResult{
-				target:            target.Name,
-				latencyMetricName: p.opts.LatencyMetricName,
-				validationFailure: validators.ValidationFailureMap(p.opts.Validators),
+// runQuery sends a query to the target and returns the result.
+func (p *Probe) runQuery(q *query, target, fullTarget string) probeRunResult {
+	result := p.newResult(target, q)
+	result.total.Inc()
+
+	var resp *dns.Msg
+	var latency time.Duration
+	var err error
+	if ec, ok := p.client.(encryptedClient); ok {
+		var state *tlsConnState
+		resp, latency, state, err = ec.exchangeTLS(q.msg, fullTarget, target)
+		if state != nil {
+			result.tlsUpdate = &tlsResult{
+				target:    target,
+				queryType: result.queryType,
+				state:     state,
+				latency:   state.handshakeLatency.Seconds() / p.opts.LatencyUnit.Seconds(),
 			}
+		}
+	} else {
+		resp, latency, err = p.client.Exchange(q.msg, fullTarget)
+	}
+
+	if err != nil {
+		if isClientTimeout(err) {
+			p.l.Warningf("Target(%s): client.Exchange: Timeout error: %v", fullTarget, err)
+			result.timeouts.Inc()
+		} else {
+			p.l.Warningf("Target(%s): client.Exchange: %v", fullTarget, err)
+		}
+	} else if p.validateResponse(resp, q.msg.Question[0].Qtype, fullTarget, &result) {
+		result.success.Inc()
+		result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+		result.rawLatency = latency
+		result.answers.IncBy(metrics.NewInt(int64(len(resp.Answer))))
+	}
 
-			if p.opts.LatencyDist != nil {
-				result.latency = p.opts.LatencyDist.Clone()
-			} else {
-				result.latency = metrics.NewFloat(0)
+	// Reset the signature expiry gauge if the response couldn't be validated,
+	// e.g. on timeouts.
+	if p.c.GetDnssecValidation() == configpb.ProbeConf_LOCAL && result.dnssecUpdate == nil {
+		result.dnssecUpdate = &dnssecResult{target: target, queryType: result.queryType}
+	}
+	return result
+}
+
+// failureReason describes the failure of a query.
+func (prr *probeRunResult) failureReason() string {
+	var reasons []string
+	if prr.timeouts.Int64() > 0 {
+		reasons = append(reasons, "timeout")
+	}
+	for _, m := range []*metrics.Map{prr.dnssecFailure, prr.unexpectedAnswer, prr.validationFailure} {
+		if m == nil {
+			continue
+		}
+		for _, k := range m.Keys() {
+			if m.GetKey(k).Int64() > 0 {
+				reasons = append(reasons, k)
 			}
+		}
+	}
+	if len(reasons) == 0 {
+		return "query failed or got an invalid response"
+	}
+	return strings.Join(reasons, ",")
+}
 
-			result.total.Inc()
+// RunOnDemand runs the probe's queries once for the given target, out of the
+// regular schedule. Its result is not included in the probe's metrics. The
+// run succeeds only if all the queries succeed, and its latency is the
+// highest of the queries' latencies.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	port := strconv.Itoa(p.port)
+	fullTarget := net.JoinHostPort(target.Name, port)
+	if p.c.GetResolveFirst() {
+		ip, err := p.opts.ResolveTarget(target.Name, p.opts.Targets.Resolve)
+		if err != nil {
+			return 0, fmt.Errorf("error resolving the target: %v", err)
+		}
+		fullTarget = net.JoinHostPort(ip.String(), port)
+	}
 
-			fullTarget := net.JoinHostPort(target.Name, "53")
-			if p.c.GetResolveFirst() {
-				if resolveF == nil {
-					resolveF = p.opts.Targets.Resolve
-				}
-				ip, err := resolveF(target.Name, p.opts.IPVersion)
-				if err != nil {
-					p.l.Warningf("Target(%s): Resolve error: %v", target.Name, err)
-					resultsChan <- result
-					return
-				}
-				fullTarget = net.JoinHostPort(ip.String(), "53")
+	var latency time.Duration
+	for _, q := range p.queries {
+		result := p.runQuery(q, target.Name, fullTarget)
+		if result.success.Int64() == 0 {
+			if q.qtype != "" {
+				return 0, fmt.Errorf("query type %s: %s", q.qtype, result.failureReason())
 			}
+			return 0, errors.New(result.failureReason())
+		}
+		if result.rawLatency > latency {
+			latency = result.rawLatency
+		}
+	}
+	return latency, nil
+}
 
-			resp, latency, err := p.client.Exchange(p.msg, fullTarget)
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult, resolveF resolveFunc) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
 
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	if resolveF == nil && p.c.GetResolveFirst() {
+		resolveF = p.opts.Targets.Resolve
+	}
+
+	// Probe each target in a separate goroutine (bounded by
+	// max_concurrent_probes). Queries to a target are sent one after another,
+	// and a result is written to the "resultsChan" channel for each query.
+	probeF := func(target endpoint.Endpoint) {
+		port := strconv.Itoa(p.port)
+		fullTarget := net.JoinHostPort(target.Name, port)
+		if p.c.GetResolveFirst() {
+			ip, err := p.opts.ResolveTarget(target.Name, resolveF)
 			if err != nil {
-				if isClientTimeout(err) {
-					p.l.Warningf("Target(%s): client.Exchange: Timeout error: %v", fullTarget, err)
-					result.timeouts.Inc()
-				} else {
-					p.l.Warningf("Target(%s): client.Exchange: %v", fullTarget, err)
+				p.l.Warningf("Target(%s): Resolve error: %v", target.Name, err)
+				for _, q := range p.queries {
+					result := p.newResult(target.Name, q)
+					result.total.Inc()
+					resultsChan <- result
 				}
-			} else if p.validateResponse(resp, fullTarget, &result) {
-				result.success.Inc()
-				result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+				return
 			}
+			fullTarget = net.JoinHostPort(ip.String(), port)
+		}
+
+		for _, q := range p.queries {
+			result := p.runQuery(q, target.Name, fullTarget)
 			resultsChan <- result
-		}(target, resultsChan)
+			if result.dnssecUpdate != nil {
+				resultsChan <- *result.dnssecUpdate
+			}
+			if result.tlsUpdate != nil {
+				resultsChan <- *result.tlsUpdate
+			}
+		}
 	}
 
-	// Wait until all probes are done.
-	wg.Wait()
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		for _, q := range p.queries {
+			result := p.newResult(target.Name, q)
+			result.skipped.Inc()
+			resultsChan <- result
+		}
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
 }
 
 // Start starts and runs the probe indefinitely.
 func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
-	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets)*len(p.queries))
 
 	// This function is used by StatsKeeper to get the latest list of targets.
 	// TODO(manugarg): Make p.targets mutex protected as it's read and written by concurrent goroutines.
@@ -277,7 +633,7 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 
 	go statskeeper.StatsKeeper(ctx, "dns", p.name, p.opts, targetsFunc, resultsChan, dataChan)
 
-	ticker := time.NewTicker(p.opts.Interval)
+	ticker := p.opts.NewTicker()
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -287,6 +643,13 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 			return
 		default:
 		}
-		p.runProbe(resultsChan, nil)
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan, nil)
+		runstats.RecordRun(p.name, start)
 	}
 }
@@ -15,17 +15,23 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
 	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/validators"
 	validatorpb "github.com/cloudprober/cloudprober/validators/proto"
 	"github.com/miekg/dns"
@@ -69,14 +75,14 @@ func (*mockClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Dura
 	return out, time.Millisecond, nil
 }
 func (*mockClient) setReadTimeout(time.Duration) {}
-func (*mockClient) setSourceIP(net.IP)           {}
+func (*mockClient) setSourceIP(net.IP, string)   {}
 
 func runProbe(t *testing.T, testName string, p *Probe, resolveF resolveFunc, total, success int64) {
 	p.client = new(mockClient)
 	p.targets = p.opts.Targets.ListEndpoints()
 
 	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
-	p.runProbe(resultsChan, resolveF)
+	p.runProbe(context.Background(), resultsChan, resolveF)
 
 	// The resultsChan output iterates through p.targets in the same order.
 	for _, target := range p.targets {
@@ -221,3 +227,442 @@ func TestValidator(t *testing.T) {
 		runProbe(t, tst.name, p, nil, 1, tst.successCt)
 	}
 }
+
+// slowClient is a mock client that takes a while to respond and keeps track
+// of the number of concurrent queries.
+type slowClient struct {
+	mockClient
+	delay time.Duration
+
+	mu                    sync.Mutex
+	inFlight, maxInFlight int
+}
+
+func (sc *slowClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	sc.mu.Lock()
+	sc.inFlight++
+	if sc.inFlight > sc.maxInFlight {
+		sc.maxInFlight = sc.inFlight
+	}
+	sc.mu.Unlock()
+
+	time.Sleep(sc.delay)
+
+	sc.mu.Lock()
+	sc.inFlight--
+	sc.mu.Unlock()
+
+	out := &dns.Msg{}
+	out.SetReply(in)
+	return out, sc.delay, nil
+}
+
+func TestMaxConcurrentProbes(t *testing.T) {
+	p := &Probe{}
+	opts := &options.Options{
+		Targets:             targets.StaticTargets("8.8.8.8,8.8.4.4,1.1.1.1,1.0.0.1"),
+		Interval:            150 * time.Millisecond,
+		Timeout:             time.Second,
+		ProbeConf:           &configpb.ProbeConf{},
+		MaxConcurrentProbes: 1,
+	}
+	if err := p.Init("dns_test", opts); err != nil {
+		t.Fatalf("Error creating probe: %v", err)
+	}
+	sc := &slowClient{delay: 100 * time.Millisecond}
+	p.client = sc
+
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+	p.runProbe(context.Background(), resultsChan, nil)
+	close(resultsChan)
+
+	if sc.maxInFlight != 1 {
+		t.Errorf("Max in-flight queries=%d, want=1", sc.maxInFlight)
+	}
+
+	// With each query taking 100ms, only 2 targets get a slot within the
+	// 150ms interval.
+	var total, success, skipped int64
+	for r := range resultsChan {
+		result := r.(probeRunResult)
+		total += result.total.Int64()
+		success += result.success.Int64()
+		skipped += result.skipped.Int64()
+
+		em := result.Metrics()
+		if em.Metric("skipped_targets") == nil {
+			t.Errorf("skipped_targets metric missing from result: %s", em.String())
+		}
+	}
+	if total != 2 || success != 2 || skipped != 2 {
+		t.Errorf("Got (total, success, skipped)=(%d, %d, %d), want (2, 2, 2)", total, success, skipped)
+	}
+}
+
+// ecsEchoClient is a mock DNS server that echoes the EDNS0 Client Subnet
+// option from the query in its response.
+type ecsEchoClient struct {
+	mockClient
+
+	mu  sync.Mutex
+	ecs *dns.EDNS0_SUBNET // ECS option from the last response.
+}
+
+func (ec *ecsEchoClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	// Go through the wire format, as a real server would.
+	b, err := in.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+	query := new(dns.Msg)
+	if err := query.Unpack(b); err != nil {
+		return nil, 0, err
+	}
+
+	out, _, err := ec.mockClient.Exchange(query, fullTarget)
+	if err != nil {
+		return nil, 0, err
+	}
+	if opt := query.IsEdns0(); opt != nil {
+		out.SetEdns0(opt.UDPSize(), false)
+		for _, o := range opt.Option {
+			if ecs, ok := o.(*dns.EDNS0_SUBNET); ok {
+				ecs.SourceScope = ecs.SourceNetmask
+				out.IsEdns0().Option = append(out.IsEdns0().Option, ecs)
+
+				ec.mu.Lock()
+				ec.ecs = ecs
+				ec.mu.Unlock()
+			}
+		}
+	}
+	return out, time.Millisecond, nil
+}
+
+func TestEDNSClientSubnet(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		address    string
+		prefix     *uint32
+		wantErr    bool
+		wantFamily uint16
+		wantPrefix uint8
+		wantAddr   string
+	}{
+		{desc: "ipv4", address: "203.0.113.17", prefix: proto.Uint32(24), wantFamily: 1, wantPrefix: 24, wantAddr: "203.0.113.0"},
+		{desc: "ipv4_default_prefix", address: "198.51.100.10", wantFamily: 1, wantPrefix: 24, wantAddr: "198.51.100.0"},
+		{desc: "ipv6_default_prefix", address: "2001:db8:1:2:3::1", wantFamily: 2, wantPrefix: 56, wantAddr: "2001:db8:1::"},
+		{desc: "ipv6_full_prefix", address: "2001:db8::1", prefix: proto.Uint32(128), wantFamily: 2, wantPrefix: 128, wantAddr: "2001:db8::1"},
+		{desc: "ipv4_bad_prefix", address: "203.0.113.0", prefix: proto.Uint32(33), wantErr: true},
+		{desc: "ipv6_bad_prefix", address: "2001:db8::", prefix: proto.Uint32(129), wantErr: true},
+		{desc: "bad_address", address: "203.0.113", wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			opts := &options.Options{
+				Targets:  targets.StaticTargets("8.8.8.8"),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					QueryType:  configpb.QueryType_A.Enum(),
+					MinAnswers: proto.Uint32(1),
+					EdnsClientSubnet: &configpb.EDNSClientSubnet{
+						Address: proto.String(test.address),
+						Prefix:  test.prefix,
+					},
+				},
+			}
+			err := p.Init("dns_ecs_test", opts)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Init() error: %v, wantErr: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			ec := &ecsEchoClient{}
+			p.client = ec
+			resultsChan := make(chan statskeeper.ProbeResult, 1)
+			p.runProbe(context.Background(), resultsChan, nil)
+
+			result := (<-resultsChan).(probeRunResult)
+			if result.total.Int64() != 1 || result.success.Int64() != 1 {
+				t.Errorf("Got (total, success)=(%d, %d), want (1, 1)", result.total.Int64(), result.success.Int64())
+			}
+
+			if ec.ecs == nil {
+				t.Fatalf("No ECS option in the query")
+			}
+			if ec.ecs.Family != test.wantFamily || ec.ecs.SourceNetmask != test.wantPrefix || !ec.ecs.Address.Equal(net.ParseIP(test.wantAddr)) {
+				t.Errorf("Got ECS option: family=%d, prefix=%d, address=%s, want: family=%d, prefix=%d, address=%s", ec.ecs.Family, ec.ecs.SourceNetmask, ec.ecs.Address, test.wantFamily, test.wantPrefix, test.wantAddr)
+			}
+		})
+	}
+}
+
+// multiTypeClient is a mock DNS server that returns different answers per
+// query type: 2 answers for A, 1 for AAAA, SERVFAIL for MX, and server's
+// version for CH TXT queries.
+type multiTypeClient struct {
+	mockClient
+}
+
+func (*multiTypeClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	out := new(dns.Msg)
+	out.SetReply(in)
+	q := in.Question[0]
+
+	var answers []string
+	switch {
+	case q.Qclass == dns.ClassCHAOS && q.Qtype == dns.TypeTXT:
+		answers = []string{q.Name + ` 0 CH TXT "test-1.0"`}
+	case q.Qclass != dns.ClassINET:
+		out.Rcode = dns.RcodeRefused
+	case q.Qtype == dns.TypeA:
+		answers = []string{q.Name + " 300 IN A 192.168.0.1", q.Name + " 300 IN A 192.168.0.2"}
+	case q.Qtype == dns.TypeAAAA:
+		answers = []string{q.Name + " 300 IN AAAA 2001:db8::1"}
+	default:
+		out.Rcode = dns.RcodeServerFailure
+	}
+
+	for _, s := range answers {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		out.Answer = append(out.Answer, rr)
+	}
+	return out, time.Millisecond, nil
+}
+
+func TestMultipleQueryTypes(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		conf        *configpb.ProbeConf
+		wantResults map[string][3]int64 // query_type -> total, success, answers
+	}{
+		{
+			desc: "multiple_types",
+			conf: &configpb.ProbeConf{
+				QueryTypes: []configpb.QueryType{configpb.QueryType_A, configpb.QueryType_AAAA, configpb.QueryType_MX},
+			},
+			wantResults: map[string][3]int64{
+				"A":    {1, 1, 2},
+				"AAAA": {1, 1, 1},
+				"MX":   {1, 0, 0},
+			},
+		},
+		{
+			desc: "chaos_class",
+			conf: &configpb.ProbeConf{
+				ResolvedDomain: proto.String("version.bind"),
+				QueryTypes:     []configpb.QueryType{configpb.QueryType_TXT},
+				QueryClass:     configpb.QueryClass_CH.Enum(),
+			},
+			wantResults: map[string][3]int64{
+				"TXT": {1, 1, 1},
+			},
+		},
+		{
+			desc: "wrong_class",
+			conf: &configpb.ProbeConf{
+				QueryTypes: []configpb.QueryType{configpb.QueryType_A},
+				QueryClass: configpb.QueryClass_HS.Enum(),
+			},
+			wantResults: map[string][3]int64{
+				"A": {1, 0, 0},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			opts := &options.Options{
+				Targets:   targets.StaticTargets("8.8.8.8"),
+				Interval:  2 * time.Second,
+				Timeout:   time.Second,
+				ProbeConf: test.conf,
+			}
+			if err := p.Init("dns_multi_type_test", opts); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+			p.client = &multiTypeClient{}
+
+			resultsChan := make(chan statskeeper.ProbeResult, len(p.queries))
+			p.runProbe(context.Background(), resultsChan, nil)
+			close(resultsChan)
+
+			gotResults := make(map[string][3]int64)
+			for r := range resultsChan {
+				em := r.(probeRunResult).Metrics()
+				qtype := em.Label("query_type")
+				gotResults[qtype] = [3]int64{
+					em.Metric("total").(metrics.NumValue).Int64(),
+					em.Metric("success").(metrics.NumValue).Int64(),
+					em.Metric("answers").(metrics.NumValue).Int64(),
+				}
+			}
+			if !reflect.DeepEqual(gotResults, test.wantResults) {
+				t.Errorf("Got results (total, success, answers): %v, want: %v", gotResults, test.wantResults)
+			}
+		})
+	}
+}
+
+// dnssecClient is a mock validating resolver. It returns signed responses
+// (AD flag and RRSIG records) for "signed.example.", unsigned responses for
+// "unsigned.example.", and responses with the AD flag but without RRSIG
+// records for "nosig.example.". It records whether DO bit was set in the
+// query.
+type dnssecClient struct {
+	mockClient
+	do bool
+}
+
+func (dc *dnssecClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	if opt := in.IsEdns0(); opt != nil {
+		dc.do = opt.Do()
+	}
+
+	out := new(dns.Msg)
+	out.SetReply(in)
+	q := in.Question[0]
+
+	answers := []string{q.Name + " 300 IN A 192.168.0.1"}
+	switch q.Name {
+	case "signed.example.":
+		out.AuthenticatedData = true
+		answers = append(answers, q.Name+" 300 IN RRSIG A 13 2 300 20300101000000 20200101000000 12345 example. c2lnbmF0dXJl")
+	case "nosig.example.":
+		out.AuthenticatedData = true
+	}
+
+	for _, s := range answers {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		out.Answer = append(out.Answer, rr)
+	}
+	return out, time.Millisecond, nil
+}
+
+func TestRequireDNSSEC(t *testing.T) {
+	for _, test := range []struct {
+		domain            string
+		requireDNSSEC     bool
+		wantSuccess       int64
+		wantDNSSECFailure string
+	}{
+		{
+			domain:            "signed.example",
+			requireDNSSEC:     true,
+			wantSuccess:       1,
+			wantDNSSECFailure: "map:reason",
+		},
+		{
+			domain:            "unsigned.example",
+			requireDNSSEC:     true,
+			wantDNSSECFailure: "map:reason,ad_not_set:1",
+		},
+		{
+			domain:            "nosig.example",
+			requireDNSSEC:     true,
+			wantDNSSECFailure: "map:reason,no_rrsig:1",
+		},
+		{
+			domain:      "unsigned.example",
+			wantSuccess: 1,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s_require_dnssec_%v", test.domain, test.requireDNSSEC), func(t *testing.T) {
+			p := &Probe{}
+			opts := &options.Options{
+				Targets:  targets.StaticTargets("8.8.8.8"),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					ResolvedDomain: proto.String(test.domain),
+					QueryType:      configpb.QueryType_A.Enum(),
+					RequireDnssec:  proto.Bool(test.requireDNSSEC),
+				},
+			}
+			if err := p.Init("dns_dnssec_test", opts); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+
+			dc := &dnssecClient{}
+			p.client = dc
+			resultsChan := make(chan statskeeper.ProbeResult, 1)
+			p.runProbe(context.Background(), resultsChan, nil)
+
+			result := (<-resultsChan).(probeRunResult)
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got (total, success)=(%d, %d), want (1, %d)", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+			if dc.do != test.requireDNSSEC {
+				t.Errorf("Got DO bit=%v, want=%v", dc.do, test.requireDNSSEC)
+			}
+
+			em := result.Metrics()
+			if !test.requireDNSSEC {
+				if em.Metric("dnssec_validated") != nil || em.Metric("dnssec_failure") != nil {
+					t.Errorf("Got DNSSEC metrics without require_dnssec: %s", em.String())
+				}
+				return
+			}
+			if got := em.Metric("dnssec_validated").(metrics.NumValue).Int64(); got != test.wantSuccess {
+				t.Errorf("Got dnssec_validated=%d, want=%d", got, test.wantSuccess)
+			}
+			if got := em.Metric("dnssec_failure").String(); got != test.wantDNSSECFailure {
+				t.Errorf("Got dnssec_failure=%s, want=%s", got, test.wantDNSSECFailure)
+			}
+		})
+	}
+}
+
+func TestRunOnDemand(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		conf    *configpb.ProbeConf
+		wantErr string
+	}{
+		{
+			desc: "success",
+			conf: &configpb.ProbeConf{},
+		},
+		{
+			desc:    "too_few_answers",
+			conf:    &configpb.ProbeConf{MinAnswers: proto.Uint32(2)},
+			wantErr: "invalid response",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			opts := &options.Options{
+				Targets:   targets.StaticTargets("8.8.8.8"),
+				Interval:  2 * time.Second,
+				Timeout:   time.Second,
+				ProbeConf: test.conf,
+			}
+			if err := p.Init("dns_on_demand_test", opts); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+			p.client = new(mockClient)
+
+			latency, err := p.RunOnDemand(context.Background(), endpoint.Endpoint{Name: "8.8.8.8"})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("RunOnDemand(): got error %v, want: %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunOnDemand(): unexpected error: %v", err)
+			}
+			if latency != time.Millisecond {
+				t.Errorf("RunOnDemand(): got latency %v, want: %v", latency, time.Millisecond)
+			}
+		})
+	}
+}
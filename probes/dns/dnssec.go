@@ -0,0 +1,304 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/miekg/dns"
+)
+
+// rootTrustAnchors are the DS records of the root zone's key signing keys
+// (KSK-2017 and KSK-2024), used if no trust anchors are configured.
+var rootTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBB683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// parseTrustAnchors parses the trust anchors, DS records in the presentation
+// format.
+func parseTrustAnchors(anchors []string) ([]*dns.DS, error) {
+	var dsRRs []*dns.DS
+	for _, s := range anchors {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNSSEC trust anchor (%s): %v", s, err)
+		}
+		ds, ok := rr.(*dns.DS)
+		if !ok {
+			return nil, fmt.Errorf("invalid DNSSEC trust anchor (%s): not a DS record", s)
+		}
+		dsRRs = append(dsRRs, ds)
+	}
+	return dsRRs, nil
+}
+
+// dnssecValidator validates the DNSSEC chain of trust for the responses: from
+// the answer RRsets, through the DNSKEY and DS RRsets of the zones above them,
+// to a trust anchor. Records are queried from the target itself.
+type dnssecValidator struct {
+	client  Client
+	target  string
+	anchors []*dns.DS
+	now     time.Time
+}
+
+// query queries the target for the RRset, with the DO bit set. Resolvers'
+// own validation is disabled (CD bit), as we validate the records ourselves.
+func (v *dnssecValidator) query(name string, qtype uint16) ([]dns.RR, []*dns.RRSIG, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(dns.DefaultMsgSize, true)
+	msg.CheckingDisabled = true
+
+	resp, _, err := v.client.Exchange(msg, v.target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying %s %s: %v", name, dns.TypeToString[qtype], err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, nil, fmt.Errorf("error querying %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[resp.Rcode])
+	}
+
+	rrset, sigs := splitRRset(resp.Answer, name, qtype)
+	if len(rrset) == 0 {
+		return nil, nil, fmt.Errorf("no %s records for %s", dns.TypeToString[qtype], name)
+	}
+	return rrset, sigs, nil
+}
+
+// splitRRset returns the RRset with the given name and type, and the
+// signatures covering it, from the records.
+func splitRRset(rrs []dns.RR, name string, qtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var rrset []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			if sig.TypeCovered == qtype {
+				sigs = append(sigs, sig)
+			}
+			continue
+		}
+		if rr.Header().Rrtype == qtype {
+			rrset = append(rrset, rr)
+		}
+	}
+	return rrset, sigs
+}
+
+// verifyRRset verifies the RRset using its signatures and the keys, and
+// returns the expiration time of the verified signature.
+func (v *dnssecValidator) verifyRRset(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) (time.Time, error) {
+	for _, sig := range sigs {
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm || !strings.EqualFold(key.Header().Name, sig.SignerName) {
+				continue
+			}
+			if err := sig.Verify(key, rrset); err != nil {
+				continue
+			}
+			if !sig.ValidityPeriod(v.now) {
+				return time.Time{}, fmt.Errorf("signature for %s %s (key tag: %d) is expired or not yet valid", sig.Header().Name, dns.TypeToString[sig.TypeCovered], sig.KeyTag)
+			}
+			return time.Unix(int64(sig.Expiration), 0), nil
+		}
+	}
+	h := rrset[0].Header()
+	return time.Time{}, fmt.Errorf("no valid signature for %s %s", h.Name, dns.TypeToString[h.Rrtype])
+}
+
+// matchingKeys returns the keys that match any of the DS records.
+func matchingKeys(keys []*dns.DNSKEY, dsRRs []*dns.DS) []*dns.DNSKEY {
+	var matched []*dns.DNSKEY
+	for _, key := range keys {
+		for _, ds := range dsRRs {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm || !strings.EqualFold(key.Header().Name, ds.Header().Name) {
+				continue
+			}
+			if kds := key.ToDS(ds.DigestType); kds != nil && strings.EqualFold(kds.Digest, ds.Digest) {
+				matched = append(matched, key)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// anchorsFor returns the trust anchors for the zone, if any.
+func (v *dnssecValidator) anchorsFor(zone string) []*dns.DS {
+	var dsRRs []*dns.DS
+	for _, ds := range v.anchors {
+		if strings.EqualFold(ds.Header().Name, zone) {
+			dsRRs = append(dsRRs, ds)
+		}
+	}
+	return dsRRs
+}
+
+// validateChain validates the chain of trust for the RRset, and returns the
+// earliest expiration time of the signatures in the chain.
+func (v *dnssecValidator) validateChain(rrset []dns.RR, sigs []*dns.RRSIG) (time.Time, error) {
+	var expiry time.Time
+	updateExpiry := func(t time.Time) {
+		if expiry.IsZero() || t.Before(expiry) {
+			expiry = t
+		}
+	}
+
+	for {
+		if len(sigs) == 0 {
+			h := rrset[0].Header()
+			return time.Time{}, fmt.Errorf("no RRSIG records for %s %s", h.Name, dns.TypeToString[h.Rrtype])
+		}
+		zone := dns.Fqdn(sigs[0].SignerName)
+		if !dns.IsSubDomain(zone, rrset[0].Header().Name) {
+			return time.Time{}, fmt.Errorf("signer %s is not a parent of %s", zone, rrset[0].Header().Name)
+		}
+
+		keyRRs, keySigs, err := v.query(zone, dns.TypeDNSKEY)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var keys []*dns.DNSKEY
+		for _, rr := range keyRRs {
+			keys = append(keys, rr.(*dns.DNSKEY))
+		}
+
+		t, err := v.verifyRRset(rrset, sigs, keys)
+		if err != nil {
+			return time.Time{}, err
+		}
+		updateExpiry(t)
+
+		// Zone's DNSKEY RRset should be signed by a key that is either a
+		// trust anchor, or is vouched for by a DS record in the parent zone.
+		dsRRs := v.anchorsFor(zone)
+		anchored := len(dsRRs) > 0
+
+		var dsSet []dns.RR
+		var dsSigs []*dns.RRSIG
+		if !anchored {
+			if zone == "." {
+				return time.Time{}, errors.New("no trust anchor for the root zone")
+			}
+			if dsSet, dsSigs, err = v.query(zone, dns.TypeDS); err != nil {
+				return time.Time{}, err
+			}
+			for _, rr := range dsSet {
+				dsRRs = append(dsRRs, rr.(*dns.DS))
+			}
+		}
+
+		sepKeys := matchingKeys(keys, dsRRs)
+		if len(sepKeys) == 0 {
+			return time.Time{}, fmt.Errorf("no DNSKEY for %s matches its DS records or trust anchors", zone)
+		}
+		if t, err = v.verifyRRset(keyRRs, keySigs, sepKeys); err != nil {
+			return time.Time{}, err
+		}
+		updateExpiry(t)
+
+		if anchored {
+			return expiry, nil
+		}
+
+		// Continue with the DS RRset, signed by the parent zone.
+		rrset, sigs = dsSet, dsSigs
+	}
+}
+
+// validate validates all the RRsets in the answer, and returns the earliest
+// expiration time of the signatures involved.
+func (v *dnssecValidator) validate(answer []dns.RR) (time.Time, error) {
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+	var keys []rrsetKey
+	seen := make(map[rrsetKey]bool)
+	for _, rr := range answer {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			continue
+		}
+		k := rrsetKey{strings.ToLower(rr.Header().Name), rr.Header().Rrtype}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return time.Time{}, errors.New("no answers to validate")
+	}
+
+	var expiry time.Time
+	for _, k := range keys {
+		rrset, sigs := splitRRset(answer, k.name, k.rtype)
+		t, err := v.validateChain(rrset, sigs)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if expiry.IsZero() || t.Before(expiry) {
+			expiry = t
+		}
+	}
+	return expiry, nil
+}
+
+// dnssecResult is a DNSSEC signature expiry gauge update for a target: the
+// time left till the earliest signature expiry in the chain of trust.
+type dnssecResult struct {
+	target    string
+	queryType string
+	expiresIn time.Duration
+}
+
+// Metrics converts dnssecResult into metrics.EventMetrics object.
+func (dr dnssecResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("dnssec_sig_expiry_seconds", metrics.NewInt(int64(dr.expiresIn/time.Second)))
+	if dr.queryType != "" {
+		em.AddLabel("query_type", dr.queryType)
+	}
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the dr.target.
+func (dr dnssecResult) Target() string {
+	return dr.target
+}
+
+// validateDNSSECChain validates the response's DNSSEC chain of trust, and
+// returns the time left till the earliest signature expiry in the chain.
+func (p *Probe) validateDNSSECChain(resp *dns.Msg, fullTarget string) (time.Duration, error) {
+	v := &dnssecValidator{
+		client:  p.client,
+		target:  fullTarget,
+		anchors: p.trustAnchors,
+		now:     time.Now(),
+	}
+
+	expiry, err := v.validate(resp.Answer)
+	if err != nil {
+		return 0, err
+	}
+	return expiry.Sub(v.now), nil
+}
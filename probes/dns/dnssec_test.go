@@ -0,0 +1,309 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/golang/protobuf/proto"
+	"github.com/miekg/dns"
+)
+
+// testZone is a DNSSEC signed zone, with a single key that signs all its
+// records.
+type testZone struct {
+	key    *dns.DNSKEY
+	signer crypto.Signer
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	t.Helper()
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("Error generating key for %s: %v", name, err)
+	}
+	return &testZone{key: key, signer: priv.(crypto.Signer)}
+}
+
+func (z *testZone) sign(t *testing.T, rrset []dns.RR, expiration time.Time) *dns.RRSIG {
+	t.Helper()
+
+	h := rrset[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: h.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: h.Ttl},
+		TypeCovered: h.Rrtype,
+		Algorithm:   z.key.Algorithm,
+		Labels:      uint8(dns.CountLabel(h.Name)),
+		OrigTtl:     h.Ttl,
+		Expiration:  uint32(expiration.Unix()),
+		Inception:   uint32(time.Now().Add(-time.Hour).Unix()),
+		KeyTag:      z.key.KeyTag(),
+		SignerName:  z.key.Hdr.Name,
+	}
+	if err := sig.Sign(z.signer, rrset); err != nil {
+		t.Fatalf("Error signing %s: %v", h.Name, err)
+	}
+	return sig
+}
+
+// dnssecChainClient serves the records of a signed test hierarchy: root zone
+// and example. zone, with an A record for www.example.
+type dnssecChainClient struct {
+	records map[string][]dns.RR
+	do      bool
+}
+
+func rrsetKey(name string, qtype uint16) string {
+	return strings.ToLower(name) + "/" + dns.TypeToString[qtype]
+}
+
+func (c *dnssecChainClient) Exchange(in *dns.Msg, fullTarget string) (*dns.Msg, time.Duration, error) {
+	q := in.Question[0]
+	if q.Qtype == dns.TypeA {
+		c.do = in.IsEdns0() != nil && in.IsEdns0().Do()
+	}
+	out := new(dns.Msg)
+	out.SetReply(in)
+	rrs, ok := c.records[rrsetKey(q.Name, q.Qtype)]
+	if !ok {
+		out.Rcode = dns.RcodeNameError
+	}
+	out.Answer = rrs
+	return out, time.Millisecond, nil
+}
+func (*dnssecChainClient) setReadTimeout(time.Duration) {}
+func (*dnssecChainClient) setSourceIP(net.IP, string)   {}
+
+type chainOpts struct {
+	answerExpiry time.Time
+	noAnswerSig  bool
+	wrongDS      bool
+}
+
+// newDNSSECChainClient builds the signed test hierarchy, and returns its
+// client and the root zone's trust anchor.
+func newDNSSECChainClient(t *testing.T, o chainOpts) (*dnssecChainClient, string) {
+	t.Helper()
+
+	expiry := time.Now().Add(30 * 24 * time.Hour)
+	if o.answerExpiry.IsZero() {
+		o.answerExpiry = expiry
+	}
+	root, example := newTestZone(t, "."), newTestZone(t, "example.")
+	c := &dnssecChainClient{records: make(map[string][]dns.RR)}
+	add := func(z *testZone, exp time.Time, noSig bool, rrs ...dns.RR) {
+		h := rrs[0].Header()
+		if !noSig {
+			rrs = append(rrs, z.sign(t, rrs, exp))
+		}
+		c.records[rrsetKey(h.Name, h.Rrtype)] = rrs
+	}
+
+	add(root, expiry, false, root.key)
+	add(example, expiry, false, example.key)
+
+	dsKey := example.key
+	if o.wrongDS {
+		dsKey = newTestZone(t, "example.").key
+	}
+	add(root, expiry, false, dsKey.ToDS(dns.SHA256))
+
+	a, err := dns.NewRR("www.example. 300 IN A 192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	add(example, o.answerExpiry, o.noAnswerSig, a)
+
+	return c, root.key.ToDS(dns.SHA256).String()
+}
+
+func TestDNSSECValidator(t *testing.T) {
+	answerExpiry := time.Now().Add(24 * time.Hour)
+
+	for _, test := range []struct {
+		desc        string
+		opts        chainOpts
+		otherAnchor bool
+		wantErr     bool
+	}{
+		{
+			desc: "valid",
+			opts: chainOpts{answerExpiry: answerExpiry},
+		},
+		{
+			desc:    "expired_signature",
+			opts:    chainOpts{answerExpiry: time.Now().Add(-time.Minute)},
+			wantErr: true,
+		},
+		{
+			desc:    "no_rrsig",
+			opts:    chainOpts{noAnswerSig: true},
+			wantErr: true,
+		},
+		{
+			desc:    "ds_mismatch",
+			opts:    chainOpts{wrongDS: true},
+			wantErr: true,
+		},
+		{
+			desc:        "untrusted_root",
+			otherAnchor: true,
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c, anchor := newDNSSECChainClient(t, test.opts)
+			if test.otherAnchor {
+				anchor = newTestZone(t, ".").key.ToDS(dns.SHA256).String()
+			}
+			anchors, err := parseTrustAnchors([]string{anchor})
+			if err != nil {
+				t.Fatalf("Error parsing trust anchor: %v", err)
+			}
+			v := &dnssecValidator{client: c, target: "8.8.8.8:53", anchors: anchors, now: time.Now()}
+
+			expiry, err := v.validate(c.records[rrsetKey("www.example.", dns.TypeA)])
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Got error: %v, want error: %v", err, test.wantErr)
+			}
+			if err == nil && expiry.Unix() != answerExpiry.Unix() {
+				t.Errorf("Got expiry=%v, want=%v", expiry, answerExpiry)
+			}
+		})
+	}
+}
+
+func TestParseTrustAnchors(t *testing.T) {
+	if _, err := parseTrustAnchors(rootTrustAnchors); err != nil {
+		t.Errorf("Error parsing root trust anchors: %v", err)
+	}
+	for _, anchor := range []string{"invalid", "example. IN A 192.168.0.1"} {
+		if _, err := parseTrustAnchors([]string{anchor}); err == nil {
+			t.Errorf("parseTrustAnchors(%s): expected error", anchor)
+		}
+	}
+}
+
+func TestDNSSECInitErrors(t *testing.T) {
+	for _, conf := range []*configpb.ProbeConf{
+		{
+			DnssecValidation: configpb.ProbeConf_LOCAL.Enum(),
+		},
+		{
+			RequireDnssec:     proto.Bool(true),
+			DnssecTrustAnchor: rootTrustAnchors,
+		},
+		{
+			RequireDnssec:     proto.Bool(true),
+			DnssecValidation:  configpb.ProbeConf_LOCAL.Enum(),
+			DnssecTrustAnchor: []string{"invalid"},
+		},
+	} {
+		p := &Probe{}
+		err := p.Init("dns_test", &options.Options{
+			Targets:   targets.StaticTargets("8.8.8.8"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: conf,
+		})
+		if err == nil {
+			t.Errorf("Init(%v): expected error", conf)
+		}
+	}
+}
+
+func TestLocalDNSSECValidationProbe(t *testing.T) {
+	for _, test := range []struct {
+		desc              string
+		opts              chainOpts
+		wantValid         bool
+		wantDNSSECFailure string
+	}{
+		{desc: "valid", wantValid: true, wantDNSSECFailure: "map:reason"},
+		{desc: "invalid", opts: chainOpts{wrongDS: true}, wantDNSSECFailure: "map:reason,chain_invalid:1"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c, anchor := newDNSSECChainClient(t, test.opts)
+
+			p := &Probe{}
+			if err := p.Init("dns_dnssec_test", &options.Options{
+				Targets:  targets.StaticTargets("8.8.8.8"),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					ResolvedDomain:    proto.String("www.example."),
+					QueryType:         configpb.QueryType_A.Enum(),
+					RequireDnssec:     proto.Bool(true),
+					DnssecValidation:  configpb.ProbeConf_LOCAL.Enum(),
+					DnssecTrustAnchor: []string{anchor},
+				},
+			}); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+			p.client = c
+
+			resultsChan := make(chan statskeeper.ProbeResult, 2)
+			p.runProbe(context.Background(), resultsChan, nil)
+
+			result := (<-resultsChan).(probeRunResult)
+			var wantSuccess int64
+			if test.wantValid {
+				wantSuccess = 1
+			}
+			if result.total.Int64() != 1 || result.success.Int64() != wantSuccess {
+				t.Errorf("Got (total, success)=(%d, %d), want (1, %d)", result.total.Int64(), result.success.Int64(), wantSuccess)
+			}
+			if !c.do {
+				t.Error("DO bit not set in the query")
+			}
+			em := result.Metrics()
+			if got := em.Metric("dnssec_validated").(metrics.NumValue).Int64(); got != wantSuccess {
+				t.Errorf("Got dnssec_validated=%d, want=%d", got, wantSuccess)
+			}
+			if got := em.Metric("dnssec_failure").String(); got != test.wantDNSSECFailure {
+				t.Errorf("Got dnssec_failure=%s, want=%s", got, test.wantDNSSECFailure)
+			}
+
+			em = (<-resultsChan).Metrics()
+			if em.Kind != metrics.GAUGE {
+				t.Errorf("Got DNSSEC metrics kind=%v, want GAUGE", em.Kind)
+			}
+			expirySec := em.Metric("dnssec_sig_expiry_seconds").(metrics.NumValue).Int64()
+			if test.wantValid && (expirySec <= 29*24*3600 || expirySec > 30*24*3600) {
+				t.Errorf("Got dnssec_sig_expiry_seconds=%d, want ~30 days", expirySec)
+			}
+			if !test.wantValid && expirySec != 0 {
+				t.Errorf("Got dnssec_sig_expiry_seconds=%d, want=0", expirySec)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@
 package proto
 
 import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -191,6 +192,257 @@ func (QueryType) EnumDescriptor() ([]byte, []int) {
 	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{0}
 }
 
+// DNS classes from https://www.iana.org/assignments/dns-parameters
+type QueryClass int32
+
+const (
+	QueryClass_IN QueryClass = 1
+	QueryClass_CH QueryClass = 3
+	QueryClass_HS QueryClass = 4
+)
+
+// Enum value maps for QueryClass.
+var (
+	QueryClass_name = map[int32]string{
+		1: "IN",
+		3: "CH",
+		4: "HS",
+	}
+	QueryClass_value = map[string]int32{
+		"IN": 1,
+		"CH": 3,
+		"HS": 4,
+	}
+)
+
+func (x QueryClass) Enum() *QueryClass {
+	p := new(QueryClass)
+	*p = x
+	return p
+}
+
+func (x QueryClass) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (QueryClass) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (QueryClass) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[1]
+}
+
+func (x QueryClass) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *QueryClass) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = QueryClass(num)
+	return nil
+}
+
+// Deprecated: Use QueryClass.Descriptor instead.
+func (QueryClass) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{1}
+}
+
+type ProbeConf_DNSSECValidation int32
+
+const (
+	// Rely on the target, which should be a validating resolver. Queries are
+	// sent with the AD bit set too, and responses must have the AD
+	// (authenticated data) flag set ("ad_not_set" failure reason) and contain
+	// RRSIG records in the answer section ("no_rrsig").
+	ProbeConf_RESOLVER ProbeConf_DNSSECValidation = 0
+	// Validate the chain of trust for the responses ourselves: all the RRsets
+	// in the answer section are validated, i.e. their RRSIG records are
+	// verified with the signer zone's DNSKEY records, which in turn are
+	// verified through the DS records in the parent zone, up to a trust
+	// anchor. DNSKEY and DS records are queried from the target. Only
+	// positive answers can be validated ("chain_invalid" failure reason).
+	//
+	// Time left till the earliest expiry of the signatures in the chain is
+	// exported per target as the "dnssec_sig_expiry_seconds" gauge (0 if
+	// validation fails).
+	ProbeConf_LOCAL ProbeConf_DNSSECValidation = 1
+)
+
+// Enum value maps for ProbeConf_DNSSECValidation.
+var (
+	ProbeConf_DNSSECValidation_name = map[int32]string{
+		0: "RESOLVER",
+		1: "LOCAL",
+	}
+	ProbeConf_DNSSECValidation_value = map[string]int32{
+		"RESOLVER": 0,
+		"LOCAL":    1,
+	}
+)
+
+func (x ProbeConf_DNSSECValidation) Enum() *ProbeConf_DNSSECValidation {
+	p := new(ProbeConf_DNSSECValidation)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_DNSSECValidation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_DNSSECValidation) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[2].Descriptor()
+}
+
+func (ProbeConf_DNSSECValidation) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[2]
+}
+
+func (x ProbeConf_DNSSECValidation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_DNSSECValidation) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_DNSSECValidation(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_DNSSECValidation.Descriptor instead.
+func (ProbeConf_DNSSECValidation) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf_Transport int32
+
+const (
+	ProbeConf_UDP   ProbeConf_Transport = 0
+	ProbeConf_TCP   ProbeConf_Transport = 1
+	ProbeConf_TLS   ProbeConf_Transport = 2 // DNS-over-TLS (RFC 7858).
+	ProbeConf_HTTPS ProbeConf_Transport = 3 // DNS-over-HTTPS (RFC 8484).
+)
+
+// Enum value maps for ProbeConf_Transport.
+var (
+	ProbeConf_Transport_name = map[int32]string{
+		0: "UDP",
+		1: "TCP",
+		2: "TLS",
+		3: "HTTPS",
+	}
+	ProbeConf_Transport_value = map[string]int32{
+		"UDP":   0,
+		"TCP":   1,
+		"TLS":   2,
+		"HTTPS": 3,
+	}
+)
+
+func (x ProbeConf_Transport) Enum() *ProbeConf_Transport {
+	p := new(ProbeConf_Transport)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Transport) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Transport) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[3].Descriptor()
+}
+
+func (ProbeConf_Transport) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[3]
+}
+
+func (x ProbeConf_Transport) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Transport) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Transport(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Transport.Descriptor instead.
+func (ProbeConf_Transport) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+type ExpectedAnswers_Match int32
+
+const (
+	// Answer records should be exactly the expected records.
+	ExpectedAnswers_EXACT ExpectedAnswers_Match = 0
+	// Answer records should be a subset of the expected records, e.g. when
+	// a resolver returns a few records out of a larger pool.
+	ExpectedAnswers_SUBSET ExpectedAnswers_Match = 1
+)
+
+// Enum value maps for ExpectedAnswers_Match.
+var (
+	ExpectedAnswers_Match_name = map[int32]string{
+		0: "EXACT",
+		1: "SUBSET",
+	}
+	ExpectedAnswers_Match_value = map[string]int32{
+		"EXACT":  0,
+		"SUBSET": 1,
+	}
+)
+
+func (x ExpectedAnswers_Match) Enum() *ExpectedAnswers_Match {
+	p := new(ExpectedAnswers_Match)
+	*p = x
+	return p
+}
+
+func (x ExpectedAnswers_Match) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ExpectedAnswers_Match) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[4].Descriptor()
+}
+
+func (ExpectedAnswers_Match) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes[4]
+}
+
+func (x ExpectedAnswers_Match) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ExpectedAnswers_Match) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ExpectedAnswers_Match(num)
+	return nil
+}
+
+// Deprecated: Use ExpectedAnswers_Match.Descriptor instead.
+func (ExpectedAnswers_Match) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{1, 0}
+}
+
 type ProbeConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -200,6 +452,16 @@ type ProbeConf struct {
 	ResolvedDomain *string `protobuf:"bytes,1,opt,name=resolved_domain,json=resolvedDomain,def=www.google.com." json:"resolved_domain,omitempty"`
 	// DNS Query Type
 	QueryType *QueryType `protobuf:"varint,3,opt,name=query_type,json=queryType,enum=cloudprober.probes.dns.QueryType,def=15" json:"query_type,omitempty"`
+	// Query types to use in each probe cycle. If specified, query_type is
+	// ignored, all the query types are queried in each probe cycle, and results
+	// are reported per query type, with an additional label: query_type. In
+	// addition to the regular metrics, "answers" counter (total number of
+	// answers received) is also exported in this mode. Example:
+	//   query_types: [A, AAAA, MX]
+	QueryTypes []QueryType `protobuf:"varint,7,rep,name=query_types,json=queryTypes,enum=cloudprober.probes.dns.QueryType" json:"query_types,omitempty"`
+	// DNS query class. Use CH along with TXT query type for server info
+	// queries, e.g. "version.bind".
+	QueryClass *QueryClass `protobuf:"varint,8,opt,name=query_class,json=queryClass,enum=cloudprober.probes.dns.QueryClass,def=1" json:"query_class,omitempty"`
 	// Minimum number of answers expected. Default behavior is to return success
 	// if DNS response status is NOERROR.
 	MinAnswers *uint32 `protobuf:"varint,4,opt,name=min_answers,json=minAnswers,def=0" json:"min_answers,omitempty"`
@@ -207,14 +469,69 @@ type ProbeConf struct {
 	// we hand over the target directly to the DNS client. Otherwise, we resolve
 	// the target first to an IP address.
 	ResolveFirst *bool `protobuf:"varint,5,opt,name=resolve_first,json=resolveFirst,def=0" json:"resolve_first,omitempty"`
+	// EDNS0 Client Subnet (RFC 7871) option to include in the queries. This is
+	// useful to monitor what a geo-aware resolver or a CDN's DNS returns for the
+	// clients in a given network, e.g.:
+	//   edns_client_subnet {
+	//     address: "203.0.113.0"
+	//     prefix: 24
+	//   }
+	EdnsClientSubnet *EDNSClientSubnet `protobuf:"bytes,6,opt,name=edns_client_subnet,json=ednsClientSubnet" json:"edns_client_subnet,omitempty"`
+	// Require DNSSEC validated responses. If set, queries are sent with the DO
+	// (DNSSEC OK) bit set, and responses are validated as per
+	// dnssec_validation. Responses failing the validation fail the probe, and
+	// are counted in the "dnssec_failure" metric, with the failure reason as
+	// the "reason" label. Validated responses are counted in the
+	// "dnssec_validated" metric.
+	RequireDnssec *bool `protobuf:"varint,9,opt,name=require_dnssec,json=requireDnssec,def=0" json:"require_dnssec,omitempty"`
+	// How to validate the responses for require_dnssec.
+	DnssecValidation *ProbeConf_DNSSECValidation `protobuf:"varint,10,opt,name=dnssec_validation,json=dnssecValidation,enum=cloudprober.probes.dns.ProbeConf_DNSSECValidation,def=0" json:"dnssec_validation,omitempty"`
+	// Trust anchors for the LOCAL dnssec_validation, as DS records in the
+	// presentation format, e.g.:
+	//   ". IN DS 20326 8 2 E06D44B8...F8EC8D"
+	// Default is to use the root zone's key signing keys.
+	DnssecTrustAnchor []string `protobuf:"bytes,11,rep,name=dnssec_trust_anchor,json=dnssecTrustAnchor" json:"dnssec_trust_anchor,omitempty"`
+	// Transport to send the queries over. For the encrypted transports, TLS and
+	// HTTPS, a new connection is set up for every query, and probe's latency
+	// includes the connection setup. TLS handshake latency is exported
+	// separately, per target, as the "tls_handshake_latency" gauge, with the
+	// negotiated TLS version and application protocol (ALPN) as the
+	// "tls_version" and "negotiated_protocol" labels.
+	Transport *ProbeConf_Transport `protobuf:"varint,12,opt,name=transport,enum=cloudprober.probes.dns.ProbeConf_Transport,def=0" json:"transport,omitempty"`
+	// Port to send the queries to. Default is 53 for UDP and TCP, 853 for TLS,
+	// and 443 for HTTPS.
+	Port *int32 `protobuf:"varint,13,opt,name=port" json:"port,omitempty"`
+	// TLS config for the TLS and HTTPS transports. Target's name is used as the
+	// TLS server name, unless server_name is configured here.
+	TlsConfig *proto.TLSConfig `protobuf:"bytes,14,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	// URL path for the DNS-over-HTTPS queries. Queries are sent as POST
+	// requests, with the "application/dns-message" content type.
+	DohPath *string `protobuf:"bytes,15,opt,name=doh_path,json=dohPath,def=/dns-query" json:"doh_path,omitempty"`
+	// Expected answers. Answer records of the query type (e.g. A records for A
+	// queries, ignoring CNAME records in the chain) are checked against all the
+	// applicable expected answers. Responses that don't match fail the probe,
+	// and are counted in the "unexpected_answer" metric, with the mismatch
+	// reason as the "reason" label: "missing_record", "unexpected_record",
+	// "rdata_mismatch" or "low_ttl". Example:
+	//   expected_answers {
+	//     rdata: ["192.168.0.1", "192.168.0.2"]
+	//     match: SUBSET
+	//     min_ttl: 60
+	//   }
+	ExpectedAnswers []*ExpectedAnswers `protobuf:"bytes,16,rep,name=expected_answers,json=expectedAnswers" json:"expected_answers,omitempty"`
 }
 
 // Default values for ProbeConf fields.
 const (
-	Default_ProbeConf_ResolvedDomain = string("www.google.com.")
-	Default_ProbeConf_QueryType      = QueryType_MX
-	Default_ProbeConf_MinAnswers     = uint32(0)
-	Default_ProbeConf_ResolveFirst   = bool(false)
+	Default_ProbeConf_ResolvedDomain   = string("www.google.com.")
+	Default_ProbeConf_QueryType        = QueryType_MX
+	Default_ProbeConf_QueryClass       = QueryClass_IN
+	Default_ProbeConf_MinAnswers       = uint32(0)
+	Default_ProbeConf_ResolveFirst     = bool(false)
+	Default_ProbeConf_RequireDnssec    = bool(false)
+	Default_ProbeConf_DnssecValidation = ProbeConf_RESOLVER
+	Default_ProbeConf_Transport        = ProbeConf_UDP
+	Default_ProbeConf_DohPath          = string("/dns-query")
 )
 
 func (x *ProbeConf) Reset() {
@@ -263,6 +580,20 @@ func (x *ProbeConf) GetQueryType() QueryType {
 	return Default_ProbeConf_QueryType
 }
 
+func (x *ProbeConf) GetQueryTypes() []QueryType {
+	if x != nil {
+		return x.QueryTypes
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetQueryClass() QueryClass {
+	if x != nil && x.QueryClass != nil {
+		return *x.QueryClass
+	}
+	return Default_ProbeConf_QueryClass
+}
+
 func (x *ProbeConf) GetMinAnswers() uint32 {
 	if x != nil && x.MinAnswers != nil {
 		return *x.MinAnswers
@@ -277,6 +608,220 @@ func (x *ProbeConf) GetResolveFirst() bool {
 	return Default_ProbeConf_ResolveFirst
 }
 
+func (x *ProbeConf) GetEdnsClientSubnet() *EDNSClientSubnet {
+	if x != nil {
+		return x.EdnsClientSubnet
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetRequireDnssec() bool {
+	if x != nil && x.RequireDnssec != nil {
+		return *x.RequireDnssec
+	}
+	return Default_ProbeConf_RequireDnssec
+}
+
+func (x *ProbeConf) GetDnssecValidation() ProbeConf_DNSSECValidation {
+	if x != nil && x.DnssecValidation != nil {
+		return *x.DnssecValidation
+	}
+	return Default_ProbeConf_DnssecValidation
+}
+
+func (x *ProbeConf) GetDnssecTrustAnchor() []string {
+	if x != nil {
+		return x.DnssecTrustAnchor
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetTransport() ProbeConf_Transport {
+	if x != nil && x.Transport != nil {
+		return *x.Transport
+	}
+	return Default_ProbeConf_Transport
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetDohPath() string {
+	if x != nil && x.DohPath != nil {
+		return *x.DohPath
+	}
+	return Default_ProbeConf_DohPath
+}
+
+func (x *ProbeConf) GetExpectedAnswers() []*ExpectedAnswers {
+	if x != nil {
+		return x.ExpectedAnswers
+	}
+	return nil
+}
+
+type ExpectedAnswers struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Query type these expectations apply to. Useful along with query_types.
+	// If not specified, expectations apply to all the query types.
+	QueryType *QueryType `protobuf:"varint,1,opt,name=query_type,json=queryType,enum=cloudprober.probes.dns.QueryType" json:"query_type,omitempty"`
+	// Expected answer records' RDATA, in the zone file format, e.g.
+	// "192.168.0.1" for A records and "10 mail.example.com." for MX records.
+	// Matching is case-insensitive.
+	Rdata []string               `protobuf:"bytes,2,rep,name=rdata" json:"rdata,omitempty"`
+	Match *ExpectedAnswers_Match `protobuf:"varint,3,opt,name=match,enum=cloudprober.probes.dns.ExpectedAnswers_Match,def=0" json:"match,omitempty"`
+	// Regex that the RDATA of all the answer records should match, e.g.
+	// "^192\\.168\\." for A records.
+	RdataRegex *string `protobuf:"bytes,4,opt,name=rdata_regex,json=rdataRegex" json:"rdata_regex,omitempty"`
+	// Minimum TTL (in seconds) for all the answer records.
+	MinTtl *uint32 `protobuf:"varint,5,opt,name=min_ttl,json=minTtl" json:"min_ttl,omitempty"`
+}
+
+// Default values for ExpectedAnswers fields.
+const (
+	Default_ExpectedAnswers_Match = ExpectedAnswers_EXACT
+)
+
+func (x *ExpectedAnswers) Reset() {
+	*x = ExpectedAnswers{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExpectedAnswers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpectedAnswers) ProtoMessage() {}
+
+func (x *ExpectedAnswers) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpectedAnswers.ProtoReflect.Descriptor instead.
+func (*ExpectedAnswers) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExpectedAnswers) GetQueryType() QueryType {
+	if x != nil && x.QueryType != nil {
+		return *x.QueryType
+	}
+	return QueryType_NONE
+}
+
+func (x *ExpectedAnswers) GetRdata() []string {
+	if x != nil {
+		return x.Rdata
+	}
+	return nil
+}
+
+func (x *ExpectedAnswers) GetMatch() ExpectedAnswers_Match {
+	if x != nil && x.Match != nil {
+		return *x.Match
+	}
+	return Default_ExpectedAnswers_Match
+}
+
+func (x *ExpectedAnswers) GetRdataRegex() string {
+	if x != nil && x.RdataRegex != nil {
+		return *x.RdataRegex
+	}
+	return ""
+}
+
+func (x *ExpectedAnswers) GetMinTtl() uint32 {
+	if x != nil && x.MinTtl != nil {
+		return *x.MinTtl
+	}
+	return 0
+}
+
+type EDNSClientSubnet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Client subnet's address, IPv4 or IPv6.
+	Address *string `protobuf:"bytes,1,req,name=address" json:"address,omitempty"`
+	// Source prefix length. It should be within [0, 32] for IPv4 addresses and
+	// within [0, 128] for IPv6 addresses. If not specified, 24 is used for IPv4
+	// and 56 for IPv6, as recommended by the RFC.
+	Prefix *uint32 `protobuf:"varint,2,opt,name=prefix" json:"prefix,omitempty"`
+}
+
+func (x *EDNSClientSubnet) Reset() {
+	*x = EDNSClientSubnet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EDNSClientSubnet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EDNSClientSubnet) ProtoMessage() {}
+
+func (x *EDNSClientSubnet) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EDNSClientSubnet.ProtoReflect.Descriptor instead.
+func (*EDNSClientSubnet) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EDNSClientSubnet) GetAddress() string {
+	if x != nil && x.Address != nil {
+		return *x.Address
+	}
+	return ""
+}
+
+func (x *EDNSClientSubnet) GetPrefix() uint32 {
+	if x != nil && x.Prefix != nil {
+		return *x.Prefix
+	}
+	return 0
+}
+
 var File_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDesc = []byte{
@@ -285,51 +830,130 @@ var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDes
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x64, 0x6e, 0x73, 0x2f,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x16, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x22, 0xdb, 0x01, 0x0a, 0x09, 0x50,
-	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x38, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x6f,
-	0x6c, 0x76, 0x65, 0x64, 0x5f, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x3a, 0x0f, 0x77, 0x77, 0x77, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6f,
-	0x6d, 0x2e, 0x52, 0x0e, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x44, 0x6f, 0x6d, 0x61,
-	0x69, 0x6e, 0x12, 0x44, 0x0a, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63,
+	0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0x89, 0x08, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66,
+	0x12, 0x38, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x5f, 0x64, 0x6f, 0x6d,
+	0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x0f, 0x77, 0x77, 0x77, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x52, 0x0e, 0x72, 0x65, 0x73, 0x6f,
+	0x6c, 0x76, 0x65, 0x64, 0x44, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x44, 0x0a, 0x0a, 0x71, 0x75,
+	0x65, 0x72, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70,
+	0x65, 0x3a, 0x02, 0x4d, 0x58, 0x52, 0x09, 0x71, 0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65,
+	0x12, 0x42, 0x0a, 0x0b, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18,
+	0x07, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x54,
+	0x79, 0x70, 0x65, 0x73, 0x12, 0x47, 0x0a, 0x0b, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x63, 0x6c,
+	0x61, 0x73, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64,
+	0x6e, 0x73, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x3a, 0x02, 0x49,
+	0x4e, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x12, 0x22, 0x0a,
+	0x0b, 0x6d, 0x69, 0x6e, 0x5f, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0d, 0x3a, 0x01, 0x30, 0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72,
+	0x73, 0x12, 0x2a, 0x0a, 0x0d, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x5f, 0x66, 0x69, 0x72,
+	0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52,
+	0x0c, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x46, 0x69, 0x72, 0x73, 0x74, 0x12, 0x56, 0x0a,
+	0x12, 0x65, 0x64, 0x6e, 0x73, 0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x75, 0x62,
+	0x6e, 0x65, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64,
+	0x6e, 0x73, 0x2e, 0x45, 0x44, 0x4e, 0x53, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x75, 0x62,
+	0x6e, 0x65, 0x74, 0x52, 0x10, 0x65, 0x64, 0x6e, 0x73, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53,
+	0x75, 0x62, 0x6e, 0x65, 0x74, 0x12, 0x2c, 0x0a, 0x0e, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65,
+	0x5f, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x63, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05, 0x66,
+	0x61, 0x6c, 0x73, 0x65, 0x52, 0x0d, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x44, 0x6e, 0x73,
+	0x73, 0x65, 0x63, 0x12, 0x69, 0x0a, 0x11, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x63, 0x5f, 0x76, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x32,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e,
+	0x66, 0x2e, 0x44, 0x4e, 0x53, 0x53, 0x45, 0x43, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x3a, 0x08, 0x52, 0x45, 0x53, 0x4f, 0x4c, 0x56, 0x45, 0x52, 0x52, 0x10, 0x64, 0x6e,
+	0x73, 0x73, 0x65, 0x63, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2e,
+	0x0a, 0x13, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x63, 0x5f, 0x74, 0x72, 0x75, 0x73, 0x74, 0x5f, 0x61,
+	0x6e, 0x63, 0x68, 0x6f, 0x72, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x64, 0x6e, 0x73,
+	0x73, 0x65, 0x63, 0x54, 0x72, 0x75, 0x73, 0x74, 0x41, 0x6e, 0x63, 0x68, 0x6f, 0x72, 0x12, 0x4e,
+	0x0a, 0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x2b, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x3a, 0x03,
+	0x55, 0x44, 0x50, 0x52, 0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f,
+	0x72, 0x74, 0x12, 0x3f, 0x0a, 0x0a, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x18, 0x0e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54,
+	0x4c, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x12, 0x25, 0x0a, 0x08, 0x64, 0x6f, 0x68, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18,
+	0x0f, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x0a, 0x2f, 0x64, 0x6e, 0x73, 0x2d, 0x71, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x07, 0x64, 0x6f, 0x68, 0x50, 0x61, 0x74, 0x68, 0x12, 0x52, 0x0a, 0x10, 0x65, 0x78,
+	0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x18, 0x10,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x45, 0x78,
+	0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x52, 0x0f, 0x65,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x22, 0x2b,
+	0x0a, 0x10, 0x44, 0x4e, 0x53, 0x53, 0x45, 0x43, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x53, 0x4f, 0x4c, 0x56, 0x45, 0x52, 0x10, 0x00,
+	0x12, 0x09, 0x0a, 0x05, 0x4c, 0x4f, 0x43, 0x41, 0x4c, 0x10, 0x01, 0x22, 0x31, 0x0a, 0x09, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x07, 0x0a, 0x03, 0x55, 0x44, 0x50, 0x10,
+	0x00, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x43, 0x50, 0x10, 0x01, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x4c,
+	0x53, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x48, 0x54, 0x54, 0x50, 0x53, 0x10, 0x03, 0x22, 0x8f,
+	0x02, 0x0a, 0x0f, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x41, 0x6e, 0x73, 0x77, 0x65,
+	0x72, 0x73, 0x12, 0x40, 0x0a, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
 	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e,
-	0x51, 0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x3a, 0x02, 0x4d, 0x58, 0x52, 0x09, 0x71,
-	0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x22, 0x0a, 0x0b, 0x6d, 0x69, 0x6e, 0x5f,
-	0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x3a, 0x01, 0x30,
-	0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x73, 0x12, 0x2a, 0x0a, 0x0d,
-	0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x5f, 0x66, 0x69, 0x72, 0x73, 0x74, 0x18, 0x05, 0x20,
-	0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x0c, 0x72, 0x65, 0x73, 0x6f,
-	0x6c, 0x76, 0x65, 0x46, 0x69, 0x72, 0x73, 0x74, 0x2a, 0xa4, 0x03, 0x0a, 0x09, 0x51, 0x75, 0x65,
-	0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00,
-	0x12, 0x05, 0x0a, 0x01, 0x41, 0x10, 0x01, 0x12, 0x06, 0x0a, 0x02, 0x4e, 0x53, 0x10, 0x02, 0x12,
-	0x09, 0x0a, 0x05, 0x43, 0x4e, 0x41, 0x4d, 0x45, 0x10, 0x05, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x4f,
-	0x41, 0x10, 0x06, 0x12, 0x07, 0x0a, 0x03, 0x50, 0x54, 0x52, 0x10, 0x0c, 0x12, 0x06, 0x0a, 0x02,
-	0x4d, 0x58, 0x10, 0x0f, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x58, 0x54, 0x10, 0x10, 0x12, 0x06, 0x0a,
-	0x02, 0x52, 0x50, 0x10, 0x11, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x46, 0x53, 0x44, 0x42, 0x10, 0x12,
-	0x12, 0x07, 0x0a, 0x03, 0x53, 0x49, 0x47, 0x10, 0x18, 0x12, 0x07, 0x0a, 0x03, 0x4b, 0x45, 0x59,
-	0x10, 0x19, 0x12, 0x08, 0x0a, 0x04, 0x41, 0x41, 0x41, 0x41, 0x10, 0x1c, 0x12, 0x07, 0x0a, 0x03,
-	0x4c, 0x4f, 0x43, 0x10, 0x1d, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x52, 0x56, 0x10, 0x21, 0x12, 0x09,
-	0x0a, 0x05, 0x4e, 0x41, 0x50, 0x54, 0x52, 0x10, 0x23, 0x12, 0x06, 0x0a, 0x02, 0x4b, 0x58, 0x10,
-	0x24, 0x12, 0x08, 0x0a, 0x04, 0x43, 0x45, 0x52, 0x54, 0x10, 0x25, 0x12, 0x09, 0x0a, 0x05, 0x44,
-	0x4e, 0x41, 0x4d, 0x45, 0x10, 0x27, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x50, 0x4c, 0x10, 0x2a, 0x12,
-	0x06, 0x0a, 0x02, 0x44, 0x53, 0x10, 0x2b, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x53, 0x48, 0x46, 0x50,
-	0x10, 0x2c, 0x12, 0x0c, 0x0a, 0x08, 0x49, 0x50, 0x53, 0x45, 0x43, 0x4b, 0x45, 0x59, 0x10, 0x2d,
-	0x12, 0x09, 0x0a, 0x05, 0x52, 0x52, 0x53, 0x49, 0x47, 0x10, 0x2e, 0x12, 0x08, 0x0a, 0x04, 0x4e,
-	0x53, 0x45, 0x43, 0x10, 0x2f, 0x12, 0x0a, 0x0a, 0x06, 0x44, 0x4e, 0x53, 0x4b, 0x45, 0x59, 0x10,
-	0x30, 0x12, 0x09, 0x0a, 0x05, 0x44, 0x48, 0x43, 0x49, 0x44, 0x10, 0x31, 0x12, 0x09, 0x0a, 0x05,
-	0x4e, 0x53, 0x45, 0x43, 0x33, 0x10, 0x32, 0x12, 0x0e, 0x0a, 0x0a, 0x4e, 0x53, 0x45, 0x43, 0x33,
-	0x50, 0x41, 0x52, 0x41, 0x4d, 0x10, 0x33, 0x12, 0x08, 0x0a, 0x04, 0x54, 0x4c, 0x53, 0x41, 0x10,
-	0x34, 0x12, 0x07, 0x0a, 0x03, 0x48, 0x49, 0x50, 0x10, 0x37, 0x12, 0x07, 0x0a, 0x03, 0x43, 0x44,
-	0x53, 0x10, 0x3b, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x44, 0x4e, 0x53, 0x4b, 0x45, 0x59, 0x10, 0x3c,
-	0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x50, 0x45, 0x4e, 0x50, 0x47, 0x50, 0x4b, 0x45, 0x59, 0x10, 0x3d,
-	0x12, 0x09, 0x0a, 0x04, 0x54, 0x4b, 0x45, 0x59, 0x10, 0xf9, 0x01, 0x12, 0x09, 0x0a, 0x04, 0x54,
-	0x53, 0x49, 0x47, 0x10, 0xfa, 0x01, 0x12, 0x08, 0x0a, 0x03, 0x55, 0x52, 0x49, 0x10, 0x80, 0x02,
-	0x12, 0x08, 0x0a, 0x03, 0x43, 0x41, 0x41, 0x10, 0x81, 0x02, 0x12, 0x08, 0x0a, 0x02, 0x54, 0x41,
-	0x10, 0x80, 0x80, 0x02, 0x12, 0x09, 0x0a, 0x03, 0x44, 0x4c, 0x56, 0x10, 0x81, 0x80, 0x02, 0x42,
-	0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
-	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x64, 0x6e, 0x73,
-	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x71, 0x75, 0x65, 0x72, 0x79,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x05, 0x72, 0x64, 0x61, 0x74, 0x61, 0x12, 0x4a, 0x0a, 0x05, 0x6d, 0x61,
+	0x74, 0x63, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64,
+	0x6e, 0x73, 0x2e, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x41, 0x6e, 0x73, 0x77, 0x65,
+	0x72, 0x73, 0x2e, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x3a, 0x05, 0x45, 0x58, 0x41, 0x43, 0x54, 0x52,
+	0x05, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x64, 0x61, 0x74, 0x61, 0x5f,
+	0x72, 0x65, 0x67, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x64, 0x61,
+	0x74, 0x61, 0x52, 0x65, 0x67, 0x65, 0x78, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x69, 0x6e, 0x5f, 0x74,
+	0x74, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x6d, 0x69, 0x6e, 0x54, 0x74, 0x6c,
+	0x22, 0x1e, 0x0a, 0x05, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x58, 0x41,
+	0x43, 0x54, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x55, 0x42, 0x53, 0x45, 0x54, 0x10, 0x01,
+	0x22, 0x44, 0x0a, 0x10, 0x45, 0x44, 0x4e, 0x53, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x75,
+	0x62, 0x6e, 0x65, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18,
+	0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x2a, 0xa4, 0x03, 0x0a, 0x09, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x05,
+	0x0a, 0x01, 0x41, 0x10, 0x01, 0x12, 0x06, 0x0a, 0x02, 0x4e, 0x53, 0x10, 0x02, 0x12, 0x09, 0x0a,
+	0x05, 0x43, 0x4e, 0x41, 0x4d, 0x45, 0x10, 0x05, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x4f, 0x41, 0x10,
+	0x06, 0x12, 0x07, 0x0a, 0x03, 0x50, 0x54, 0x52, 0x10, 0x0c, 0x12, 0x06, 0x0a, 0x02, 0x4d, 0x58,
+	0x10, 0x0f, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x58, 0x54, 0x10, 0x10, 0x12, 0x06, 0x0a, 0x02, 0x52,
+	0x50, 0x10, 0x11, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x46, 0x53, 0x44, 0x42, 0x10, 0x12, 0x12, 0x07,
+	0x0a, 0x03, 0x53, 0x49, 0x47, 0x10, 0x18, 0x12, 0x07, 0x0a, 0x03, 0x4b, 0x45, 0x59, 0x10, 0x19,
+	0x12, 0x08, 0x0a, 0x04, 0x41, 0x41, 0x41, 0x41, 0x10, 0x1c, 0x12, 0x07, 0x0a, 0x03, 0x4c, 0x4f,
+	0x43, 0x10, 0x1d, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x52, 0x56, 0x10, 0x21, 0x12, 0x09, 0x0a, 0x05,
+	0x4e, 0x41, 0x50, 0x54, 0x52, 0x10, 0x23, 0x12, 0x06, 0x0a, 0x02, 0x4b, 0x58, 0x10, 0x24, 0x12,
+	0x08, 0x0a, 0x04, 0x43, 0x45, 0x52, 0x54, 0x10, 0x25, 0x12, 0x09, 0x0a, 0x05, 0x44, 0x4e, 0x41,
+	0x4d, 0x45, 0x10, 0x27, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x50, 0x4c, 0x10, 0x2a, 0x12, 0x06, 0x0a,
+	0x02, 0x44, 0x53, 0x10, 0x2b, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x53, 0x48, 0x46, 0x50, 0x10, 0x2c,
+	0x12, 0x0c, 0x0a, 0x08, 0x49, 0x50, 0x53, 0x45, 0x43, 0x4b, 0x45, 0x59, 0x10, 0x2d, 0x12, 0x09,
+	0x0a, 0x05, 0x52, 0x52, 0x53, 0x49, 0x47, 0x10, 0x2e, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x53, 0x45,
+	0x43, 0x10, 0x2f, 0x12, 0x0a, 0x0a, 0x06, 0x44, 0x4e, 0x53, 0x4b, 0x45, 0x59, 0x10, 0x30, 0x12,
+	0x09, 0x0a, 0x05, 0x44, 0x48, 0x43, 0x49, 0x44, 0x10, 0x31, 0x12, 0x09, 0x0a, 0x05, 0x4e, 0x53,
+	0x45, 0x43, 0x33, 0x10, 0x32, 0x12, 0x0e, 0x0a, 0x0a, 0x4e, 0x53, 0x45, 0x43, 0x33, 0x50, 0x41,
+	0x52, 0x41, 0x4d, 0x10, 0x33, 0x12, 0x08, 0x0a, 0x04, 0x54, 0x4c, 0x53, 0x41, 0x10, 0x34, 0x12,
+	0x07, 0x0a, 0x03, 0x48, 0x49, 0x50, 0x10, 0x37, 0x12, 0x07, 0x0a, 0x03, 0x43, 0x44, 0x53, 0x10,
+	0x3b, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x44, 0x4e, 0x53, 0x4b, 0x45, 0x59, 0x10, 0x3c, 0x12, 0x0e,
+	0x0a, 0x0a, 0x4f, 0x50, 0x45, 0x4e, 0x50, 0x47, 0x50, 0x4b, 0x45, 0x59, 0x10, 0x3d, 0x12, 0x09,
+	0x0a, 0x04, 0x54, 0x4b, 0x45, 0x59, 0x10, 0xf9, 0x01, 0x12, 0x09, 0x0a, 0x04, 0x54, 0x53, 0x49,
+	0x47, 0x10, 0xfa, 0x01, 0x12, 0x08, 0x0a, 0x03, 0x55, 0x52, 0x49, 0x10, 0x80, 0x02, 0x12, 0x08,
+	0x0a, 0x03, 0x43, 0x41, 0x41, 0x10, 0x81, 0x02, 0x12, 0x08, 0x0a, 0x02, 0x54, 0x41, 0x10, 0x80,
+	0x80, 0x02, 0x12, 0x09, 0x0a, 0x03, 0x44, 0x4c, 0x56, 0x10, 0x81, 0x80, 0x02, 0x2a, 0x24, 0x0a,
+	0x0a, 0x51, 0x75, 0x65, 0x72, 0x79, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x12, 0x06, 0x0a, 0x02, 0x49,
+	0x4e, 0x10, 0x01, 0x12, 0x06, 0x0a, 0x02, 0x43, 0x48, 0x10, 0x03, 0x12, 0x06, 0x0a, 0x02, 0x48,
+	0x53, 0x10, 0x04, 0x42, 0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73,
+	0x2f, 0x64, 0x6e, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -344,19 +968,35 @@ func file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDe
 	return file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_goTypes = []interface{}{
-	(QueryType)(0),    // 0: cloudprober.probes.dns.QueryType
-	(*ProbeConf)(nil), // 1: cloudprober.probes.dns.ProbeConf
+	(QueryType)(0),                  // 0: cloudprober.probes.dns.QueryType
+	(QueryClass)(0),                 // 1: cloudprober.probes.dns.QueryClass
+	(ProbeConf_DNSSECValidation)(0), // 2: cloudprober.probes.dns.ProbeConf.DNSSECValidation
+	(ProbeConf_Transport)(0),        // 3: cloudprober.probes.dns.ProbeConf.Transport
+	(ExpectedAnswers_Match)(0),      // 4: cloudprober.probes.dns.ExpectedAnswers.Match
+	(*ProbeConf)(nil),               // 5: cloudprober.probes.dns.ProbeConf
+	(*ExpectedAnswers)(nil),         // 6: cloudprober.probes.dns.ExpectedAnswers
+	(*EDNSClientSubnet)(nil),        // 7: cloudprober.probes.dns.EDNSClientSubnet
+	(*proto.TLSConfig)(nil),         // 8: cloudprober.tlsconfig.TLSConfig
 }
 var file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_depIdxs = []int32{
-	0, // 0: cloudprober.probes.dns.ProbeConf.query_type:type_name -> cloudprober.probes.dns.QueryType
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: cloudprober.probes.dns.ProbeConf.query_type:type_name -> cloudprober.probes.dns.QueryType
+	0,  // 1: cloudprober.probes.dns.ProbeConf.query_types:type_name -> cloudprober.probes.dns.QueryType
+	1,  // 2: cloudprober.probes.dns.ProbeConf.query_class:type_name -> cloudprober.probes.dns.QueryClass
+	7,  // 3: cloudprober.probes.dns.ProbeConf.edns_client_subnet:type_name -> cloudprober.probes.dns.EDNSClientSubnet
+	2,  // 4: cloudprober.probes.dns.ProbeConf.dnssec_validation:type_name -> cloudprober.probes.dns.ProbeConf.DNSSECValidation
+	3,  // 5: cloudprober.probes.dns.ProbeConf.transport:type_name -> cloudprober.probes.dns.ProbeConf.Transport
+	8,  // 6: cloudprober.probes.dns.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	6,  // 7: cloudprober.probes.dns.ProbeConf.expected_answers:type_name -> cloudprober.probes.dns.ExpectedAnswers
+	0,  // 8: cloudprober.probes.dns.ExpectedAnswers.query_type:type_name -> cloudprober.probes.dns.QueryType
+	4,  // 9: cloudprober.probes.dns.ExpectedAnswers.match:type_name -> cloudprober.probes.dns.ExpectedAnswers.Match
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_init() }
@@ -377,14 +1017,38 @@ func file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_init(
 				return nil
 			}
 		}
+		file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExpectedAnswers); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EDNSClientSubnet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_dns_proto_config_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   1,
+			NumEnums:      5,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
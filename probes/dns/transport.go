@@ -0,0 +1,267 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/miekg/dns"
+)
+
+// tlsConnState captures the TLS connection details for a query sent over an
+// encrypted transport.
+type tlsConnState struct {
+	handshakeLatency time.Duration
+	version          string
+	protocol         string
+}
+
+func newTLSConnState(cs tls.ConnectionState, handshakeLatency time.Duration) *tlsConnState {
+	state := &tlsConnState{
+		handshakeLatency: handshakeLatency,
+		version:          tlsconfig.VersionName(cs.Version),
+		protocol:         cs.NegotiatedProtocol,
+	}
+	// Servers are not required to support ALPN.
+	if state.protocol == "" {
+		state.protocol = "none"
+	}
+	return state
+}
+
+// encryptedClient is a Client for the encrypted transports. Along with the
+// response, it returns the TLS connection details. Queries are sent to addr,
+// while serverName is used for the server's certificate verification.
+type encryptedClient interface {
+	Client
+	exchangeTLS(m *dns.Msg, addr, serverName string) (*dns.Msg, time.Duration, *tlsConnState, error)
+}
+
+// tlsConfigForServer returns the TLS config for the server, setting its
+// server name unless it's configured explicitly.
+func tlsConfigForServer(base *tls.Config, serverName string) *tls.Config {
+	tlsConfig := base.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = serverName
+	}
+	return tlsConfig
+}
+
+func hostFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// dotClient sends the queries over TLS (RFC 7858), over a new connection for
+// every query.
+type dotClient struct {
+	tlsConfig *tls.Config
+	dialer    *net.Dialer
+	timeout   time.Duration
+}
+
+func newDoTClient(tlsConfig *tls.Config) *dotClient {
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{"dot"}
+	}
+	return &dotClient{tlsConfig: tlsConfig, dialer: &net.Dialer{}}
+}
+
+func (c *dotClient) setReadTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+func (c *dotClient) setSourceIP(ip net.IP, zone string) {
+	c.dialer.LocalAddr = &net.TCPAddr{IP: ip, Zone: zone}
+}
+
+// Exchange implements the Client interface.
+func (c *dotClient) Exchange(m *dns.Msg, addr string) (*dns.Msg, time.Duration, error) {
+	resp, rtt, _, err := c.exchangeTLS(m, addr, hostFromAddr(addr))
+	return resp, rtt, err
+}
+
+func (c *dotClient) exchangeTLS(m *dns.Msg, addr, serverName string) (*dns.Msg, time.Duration, *tlsConnState, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	tlsConn := tls.Client(conn, tlsConfigForServer(c.tlsConfig, serverName))
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, 0, nil, err
+	}
+	state := newTLSConnState(tlsConn.ConnectionState(), time.Since(start))
+
+	co := &dns.Conn{Conn: tlsConn}
+	if err := co.WriteMsg(m); err != nil {
+		return nil, 0, state, err
+	}
+	resp, err := co.ReadMsg()
+	if err == nil && resp.Id != m.Id {
+		err = dns.ErrId
+	}
+	return resp, time.Since(start), state, err
+}
+
+// dohAddrKey is the context key for a DNS-over-HTTPS request's server
+// address, if it's different from the URL's host, e.g. if the target was
+// resolved first.
+type dohAddrKey struct{}
+
+// dohClient sends the queries over HTTPS (RFC 8484). Keep-alives are
+// disabled, so that every query sets up a new connection.
+type dohClient struct {
+	client *http.Client
+	dialer *net.Dialer
+	path   string
+}
+
+func newDoHClient(tlsConfig *tls.Config, path string) *dohClient {
+	c := &dohClient{dialer: &net.Dialer{}, path: path}
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if a, ok := ctx.Value(dohAddrKey{}).(string); ok {
+					addr = a
+				}
+				return c.dialer.DialContext(ctx, network, addr)
+			},
+			TLSClientConfig:   tlsConfig,
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+	}
+	return c
+}
+
+func (c *dohClient) setReadTimeout(d time.Duration) {
+	c.client.Timeout = d
+}
+
+func (c *dohClient) setSourceIP(ip net.IP, zone string) {
+	c.dialer.LocalAddr = &net.TCPAddr{IP: ip, Zone: zone}
+}
+
+// Exchange implements the Client interface.
+func (c *dohClient) Exchange(m *dns.Msg, addr string) (*dns.Msg, time.Duration, error) {
+	resp, rtt, _, err := c.exchangeTLS(m, addr, hostFromAddr(addr))
+	return resp, rtt, err
+}
+
+func (c *dohClient) exchangeTLS(m *dns.Msg, addr, serverName string) (*dns.Msg, time.Duration, *tlsConnState, error) {
+	// DNS message ID should be 0 for DoH queries, for HTTP caching.
+	query := m.Copy()
+	query.Id = 0
+	b, err := query.Pack()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	url := "https://" + net.JoinHostPort(serverName, port) + c.path
+
+	var state *tlsConnState
+	var handshakeStart time.Time
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() { handshakeStart = time.Now() },
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			if err == nil {
+				state = newTLSConnState(cs, time.Since(handshakeStart))
+			}
+		},
+	}
+	ctx := httptrace.WithClientTrace(context.WithValue(context.Background(), dohAddrKey{}, addr), trace)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, state, err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, 0, state, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, 0, state, fmt.Errorf("DoH request failed with status: %s", httpResp.Status)
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, 0, state, fmt.Errorf("error parsing DoH response: %v", err)
+	}
+	resp.Id = m.Id
+	return resp, time.Since(start), state, nil
+}
+
+// tlsResult is a TLS handshake gauge update for a target. It's reported only
+// for the queries sent over the encrypted transports.
+type tlsResult struct {
+	target    string
+	queryType string
+	state     *tlsConnState
+	latency   float64 // Handshake latency in the probe's latency unit.
+}
+
+// Metrics converts tlsResult into metrics.EventMetrics object.
+func (tr tlsResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("tls_handshake_latency", metrics.NewFloat(tr.latency)).
+		AddLabel("tls_version", tr.state.version).
+		AddLabel("negotiated_protocol", tr.state.protocol)
+	if tr.queryType != "" {
+		em.AddLabel("query_type", tr.queryType)
+	}
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the tr.target.
+func (tr tlsResult) Target() string {
+	return tr.target
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/dns/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/golang/protobuf/proto"
+	"github.com/miekg/dns"
+)
+
+func testDNSReply(req *dns.Msg) *dns.Msg {
+	out := new(dns.Msg)
+	out.SetReply(req)
+	a, _ := dns.NewRR(req.Question[0].Name + " 300 IN A 192.168.0.1")
+	out.Answer = []dns.RR{a}
+	return out
+}
+
+// startDNSServer starts a DNS server on the listener, serving the TCP and TLS
+// transports.
+func startDNSServer(t *testing.T, ln net.Listener) int {
+	t.Helper()
+
+	srv := &dns.Server{
+		Listener: ln,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			w.WriteMsg(testDNSReply(req))
+		}),
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// startDoHServer starts a DNS-over-HTTPS server, with HTTP/2 enabled.
+func startDoHServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/dns-query" || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if err := req.Unpack(b); err != nil || req.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp, _ := testDNSReply(req).Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(resp)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestTransports(t *testing.T) {
+	dohServer := startDoHServer(t)
+
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	tcpPort := startDNSServer(t, tcpLn)
+
+	tlsLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	tlsPort := startDNSServer(t, tls.NewListener(tlsLn, &tls.Config{
+		Certificates: dohServer.TLS.Certificates,
+		NextProtos:   []string{"dot"},
+	}))
+
+	tlsConf := &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)}
+
+	for _, test := range []struct {
+		transport    configpb.ProbeConf_Transport
+		port         int
+		tlsConf      *tlsconfigpb.TLSConfig
+		wantProtocol string
+	}{
+		{
+			transport: configpb.ProbeConf_TCP,
+			port:      tcpPort,
+		},
+		{
+			transport:    configpb.ProbeConf_TLS,
+			port:         tlsPort,
+			tlsConf:      tlsConf,
+			wantProtocol: "dot",
+		},
+		{
+			transport:    configpb.ProbeConf_HTTPS,
+			port:         dohServer.Listener.Addr().(*net.TCPAddr).Port,
+			tlsConf:      tlsConf,
+			wantProtocol: "h2",
+		},
+	} {
+		t.Run(test.transport.String(), func(t *testing.T) {
+			p := &Probe{}
+			if err := p.Init("dns_transport_test", &options.Options{
+				Targets:     targets.StaticTargets("127.0.0.1"),
+				Interval:    2 * time.Second,
+				Timeout:     time.Second,
+				LatencyUnit: time.Millisecond,
+				ProbeConf: &configpb.ProbeConf{
+					ResolvedDomain: proto.String("www.example.com."),
+					QueryType:      configpb.QueryType_A.Enum(),
+					Transport:      test.transport.Enum(),
+					Port:           proto.Int32(int32(test.port)),
+					TlsConfig:      test.tlsConf,
+				},
+			}); err != nil {
+				t.Fatalf("Error creating probe: %v", err)
+			}
+
+			resultsChan := make(chan statskeeper.ProbeResult, 2)
+			p.runProbe(context.Background(), resultsChan, nil)
+			close(resultsChan)
+
+			result := (<-resultsChan).(probeRunResult)
+			if result.total.Int64() != 1 || result.success.Int64() != 1 {
+				t.Errorf("Got (total, success)=(%d, %d), want (1, 1)", result.total.Int64(), result.success.Int64())
+			}
+
+			r, ok := <-resultsChan
+			if test.wantProtocol == "" {
+				if ok {
+					t.Errorf("Unexpected TLS result for transport %v: %v", test.transport, r.Metrics())
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("No TLS result for transport %v", test.transport)
+			}
+			em := r.Metrics()
+			if em.Label("tls_version") != "TLS 1.3" || em.Label("negotiated_protocol") != test.wantProtocol {
+				t.Errorf("Got labels: tls_version=%s, negotiated_protocol=%s, want: TLS 1.3, %s", em.Label("tls_version"), em.Label("negotiated_protocol"), test.wantProtocol)
+			}
+			if got := em.Metric("tls_handshake_latency").(*metrics.Float).Float64(); got <= 0 {
+				t.Errorf("Got tls_handshake_latency=%v, want > 0", got)
+			}
+		})
+	}
+}
+
+func TestTransportInitErrors(t *testing.T) {
+	for _, conf := range []*configpb.ProbeConf{
+		{
+			TlsConfig: &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+		},
+		{
+			Transport: configpb.ProbeConf_TLS.Enum(),
+			TlsConfig: &tlsconfigpb.TLSConfig{CaCertFile: proto.String("/non-existent/ca.pem")},
+		},
+	} {
+		p := &Probe{}
+		err := p.Init("dns_test", &options.Options{
+			Targets:   targets.StaticTargets("8.8.8.8"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: conf,
+		})
+		if err == nil {
+			t.Errorf("Init(%v): expected error", conf)
+		}
+	}
+}
+
+func TestTransportPort(t *testing.T) {
+	for transport, wantPort := range map[configpb.ProbeConf_Transport]int{
+		configpb.ProbeConf_UDP:   53,
+		configpb.ProbeConf_TCP:   53,
+		configpb.ProbeConf_TLS:   853,
+		configpb.ProbeConf_HTTPS: 443,
+	} {
+		p := &Probe{}
+		if err := p.Init("dns_test", &options.Options{
+			Targets:   targets.StaticTargets("8.8.8.8"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: &configpb.ProbeConf{Transport: transport.Enum()},
+		}); err != nil {
+			t.Fatalf("Error creating probe: %v", err)
+		}
+		if p.port != wantPort {
+			t.Errorf("Transport %v: got port=%d, want=%d", transport, p.port, wantPort)
+		}
+	}
+}
@@ -19,7 +19,9 @@ External probe type executes an external process for actual probing. These probe
 can have two modes: "once" and "server". In "once" mode, the external process is
 started for each probe run cycle, while in "server" mode, external process is
 started only if it's not running already and Cloudprober communicates with it
-over stdin/stdout for each probe cycle.
+over stdin/stdout for each probe cycle. Alternatively, "server" mode probes can
+connect to a long-running external probe server over gRPC, instead of starting
+the process themselves.
 
 TODO(manugarg): Add a way to test this program. Write another program that
 implements the probe server protocol and use that for testing.
@@ -37,19 +39,23 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/metrics/payload"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	configpb "github.com/cloudprober/cloudprober/probes/external/proto"
 	serverpb "github.com/cloudprober/cloudprober/probes/external/proto"
 	"github.com/cloudprober/cloudprober/probes/external/serverutils"
 	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/validators"
 	"github.com/google/shlex"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -70,6 +76,8 @@ var (
 
 type result struct {
 	total, success    int64
+	skipped           int64
+	queued, dropped   int64
 	latency           metrics.Value
 	validationFailure *metrics.Map
 	payloadMetrics    *metrics.EventMetrics
@@ -97,6 +105,22 @@ type Probe struct {
 	results    map[string]*result // probe results keyed by targets
 	dataChan   chan *metrics.EventMetrics
 
+	// gRPC client and the current probe stream, used only if the probe
+	// connects to the external probe server over gRPC.
+	grpcConn   *grpc.ClientConn
+	grpcClient serverpb.ExternalProberClient
+	grpcMu     sync.Mutex
+	grpcStream serverpb.ExternalProber_ProbeClient
+
+	// Command arguments and options using the text/template syntax, keyed by
+	// their raw value.
+	templates       map[string]*template.Template
+	templateNeedsIP bool
+
+	// Pool limiting the number of commands running at the same time. It's
+	// either shared by all external probes or specific to this probe.
+	pool *cmdPool
+
 	// default payload metrics that we clone from to build per-target payload
 	// metrics.
 	payloadParser *payload.Parser
@@ -137,16 +161,27 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 	p.c = c
 	p.replyChan = make(chan *serverpb.ProbeReply)
 
-	cmdParts, err := shlex.Split(p.c.GetCommand())
-	if err != nil {
-		return fmt.Errorf("error parsing command line (%s): %v", p.c.GetCommand(), err)
+	// Command is not used if the probe connects to the external probe server
+	// over gRPC.
+	if p.c.GetGrpcServer() == nil {
+		cmdParts, err := shlex.Split(p.c.GetCommand())
+		if err != nil {
+			return fmt.Errorf("error parsing command line (%s): %v", p.c.GetCommand(), err)
+		}
+		if len(cmdParts) == 0 {
+			return fmt.Errorf("command is required")
+		}
+		p.cmdName = cmdParts[0]
+		p.cmdArgs = cmdParts[1:len(cmdParts)]
 	}
-	p.cmdName = cmdParts[0]
-	p.cmdArgs = cmdParts[1:len(cmdParts)]
 
 	// Figure out labels we are interested in
 	p.updateLabelKeys()
 
+	if err := p.parseTemplates(); err != nil {
+		return err
+	}
+
 	switch p.c.GetMode() {
 	case configpb.ProbeConf_ONCE:
 		p.mode = "once"
@@ -156,8 +191,38 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 		return fmt.Errorf("invalid mode: %s", p.c.GetMode())
 	}
 
+	if p.mode == "server" && p.opts.MaxConcurrentProbes > 0 {
+		return fmt.Errorf("max_concurrent_probes is not supported in the SERVER mode, use max_inflight_requests instead")
+	}
+	if p.mode != "server" && (p.c.MaxInflightRequests != nil || p.c.MaxQueuedRequests != nil || p.c.RequestTimeoutMsec != nil) {
+		return fmt.Errorf("max_inflight_requests, max_queued_requests and request_timeout_msec are supported only in the SERVER mode")
+	}
+	if p.c.GetMaxInflightRequests() < 0 || p.c.GetMaxQueuedRequests() < 0 {
+		return fmt.Errorf("max_inflight_requests (%d) and max_queued_requests (%d) can't be negative", p.c.GetMaxInflightRequests(), p.c.GetMaxQueuedRequests())
+	}
+	if p.c.MaxQueuedRequests != nil && p.c.GetMaxInflightRequests() == 0 {
+		return fmt.Errorf("max_queued_requests requires max_inflight_requests")
+	}
+	if p.c.RequestTimeoutMsec != nil {
+		if timeout := p.requestTimeout(); timeout <= 0 || timeout > p.opts.Timeout {
+			return fmt.Errorf("request_timeout_msec (%d) should be positive and not more than the probe timeout (%v)", p.c.GetRequestTimeoutMsec(), p.opts.Timeout)
+		}
+	}
+
+	if p.c.GetGrpcServer() != nil {
+		if p.mode != "server" {
+			return fmt.Errorf("grpc_server is supported only in the SERVER mode")
+		}
+		if err := p.initGRPCClient(); err != nil {
+			return err
+		}
+	}
+
 	p.results = make(map[string]*result)
 
+	// Probe's own limit, if any, applies within the global limit.
+	p.pool = newCmdPool(int(p.c.GetMaxConcurrentCommands()), getGlobalPool())
+
 	if !p.c.GetOutputAsMetrics() {
 		return nil
 	}
@@ -167,6 +232,7 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 		defaultKind = metrics.GAUGE
 	}
 
+	var err error
 	p.payloadParser, err = payload.NewParser(p.c.GetOutputMetricsOptions(), "external", p.name, metrics.Kind(defaultKind), p.l)
 	if err != nil {
 		return fmt.Errorf("error initializing payload metrics: %v", err)
@@ -351,6 +417,15 @@ func (p *Probe) defaultMetrics(target string, result *result) *metrics.EventMetr
 		em.AddMetric("validation_failure", result.validationFailure)
 	}
 
+	if p.opts.MaxConcurrentProbes > 0 || p.pool != nil {
+		em.AddMetric("skipped_targets", metrics.NewInt(result.skipped))
+	}
+
+	if p.c.GetMaxInflightRequests() > 0 {
+		em.AddMetric("queued_requests", metrics.NewInt(result.queued)).
+			AddMetric("dropped_requests", metrics.NewInt(result.dropped))
+	}
+
 	return p.withAdditionalLabels(em, target)
 }
 
@@ -366,9 +441,9 @@ func (p *Probe) labels(ep endpoint.Endpoint) map[string]string {
 		labels["port"] = strconv.Itoa(ep.Port)
 	}
 	if p.labelKeys["address"] {
-		addr, err := p.opts.Targets.Resolve(ep.Name, p.opts.IPVersion)
+		addr, err := p.opts.ResolveTarget(ep.Name, nil)
 		if err != nil {
-			p.l.Warningf("Targets.Resolve(%v, %v) failed: %v ", ep.Name, p.opts.IPVersion, err)
+			p.l.Warningf("Resolving target %v (IP version: %v) failed: %v ", ep.Name, p.opts.IPVersion, err)
 		} else if !addr.IsUnspecified() {
 			labels["address"] = addr.String()
 		}
@@ -385,13 +460,14 @@ func (p *Probe) labels(ep endpoint.Endpoint) map[string]string {
 func (p *Probe) sendRequest(requestID int32, ep endpoint.Endpoint) error {
 	req := &serverpb.ProbeRequest{
 		RequestId: proto.Int32(requestID),
-		TimeLimit: proto.Int32(int32(p.opts.Timeout / time.Millisecond)),
+		TimeLimit: proto.Int32(int32(p.requestTimeout() / time.Millisecond)),
 		Options:   []*serverpb.ProbeRequest_Option{},
 	}
+	data := p.templateData(ep)
 	for _, opt := range p.c.GetOptions() {
-		value, found := substituteLabels(opt.GetValue(), p.labels(ep))
-		if !found {
-			p.l.Warningf("Missing substitution in option %q", value)
+		value, err := p.expandArg(opt.GetValue(), ep, data)
+		if err != nil {
+			return err
 		}
 		req.Options = append(req.Options, &serverpb.ProbeRequest_Option{
 			Name:  opt.Name,
@@ -400,9 +476,21 @@ func (p *Probe) sendRequest(requestID int32, ep endpoint.Endpoint) error {
 	}
 
 	p.l.Debugf("Sending a probe request %v to the external probe server for target %v", requestID, ep.Name)
+	if p.grpcClient != nil {
+		return p.sendGRPCRequest(req)
+	}
 	return serverutils.WriteMessage(req, p.cmdStdin)
 }
 
+// requestTimeout returns the timeout for the requests to the external probe
+// server.
+func (p *Probe) requestTimeout() time.Duration {
+	if p.c.RequestTimeoutMsec != nil {
+		return time.Duration(p.c.GetRequestTimeoutMsec()) * time.Millisecond
+	}
+	return p.opts.Timeout
+}
+
 type requestInfo struct {
 	target    string
 	timestamp time.Time
@@ -454,92 +542,172 @@ func (p *Probe) processProbeResult(ps *probeStatus, result *result) {
 }
 
 func (p *Probe) runServerProbe(ctx, startCtx context.Context) {
-	requests := make(map[int32]requestInfo)
-	var requestsMu sync.RWMutex
-	doneChan := make(chan struct{})
-
-	if err := p.startCmdIfNotRunning(startCtx); err != nil {
+	if p.grpcClient != nil {
+		// If the stream can't be opened, requests below fail, so that
+		// server's unavailability shows up in the metrics.
+		if err := p.openStreamIfClosed(ctx, startCtx); err != nil {
+			p.l.Error(err.Error())
+		}
+	} else if err := p.startCmdIfNotRunning(startCtx); err != nil {
 		p.l.Error(err.Error())
 		return
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	maxInflight := int(p.c.GetMaxInflightRequests())
+	maxQueued := int(p.c.GetMaxQueuedRequests())
+	timeout := p.requestTimeout()
 
-		// Read probe replies until we have no outstanding requests or context has
-		// run out.
-		for {
-			_, ok := <-doneChan
-			if !ok {
-				// It is safe to access requests without lock here as it won't be accessed
-				// by the send loop after doneChan is closed.
-				p.l.Debugf("Number of outstanding requests: %d", len(requests))
-				if len(requests) == 0 {
-					return
-				}
-			}
-			select {
-			case <-ctx.Done():
-				p.l.Error(ctx.Err().Error())
-				return
-			case rep := <-p.replyChan:
-				requestsMu.Lock()
-				reqInfo, ok := requests[rep.GetRequestId()]
-				if ok {
-					delete(requests, rep.GetRequestId())
-				}
-				requestsMu.Unlock()
-				if !ok {
-					// Not our reply, could be from the last timed out probe.
-					p.l.Warningf("Got a reply that doesn't match any outstading request: Request id from reply: %v. Ignoring.", rep.GetRequestId())
-					continue
-				}
-				success := true
-				if rep.GetErrorMessage() != "" {
-					p.l.Errorf("Probe for target %v failed with error message: %s", reqInfo.target, rep.GetErrorMessage())
-					success = false
-				}
-				p.processProbeResult(&probeStatus{
-					target:  reqInfo.target,
-					success: success,
-					latency: time.Since(reqInfo.timestamp),
-					payload: rep.GetPayload(),
-				}, p.results[reqInfo.target])
-			}
-		}
-	}()
+	fail := func(target string) {
+		p.processProbeResult(&probeStatus{
+			target:  target,
+			success: false,
+		}, p.results[target])
+	}
 
-	// Send probe requests
-	for _, target := range p.targets {
+	// In-flight requests, and their IDs in the order they were sent, which is
+	// also the order of their deadlines.
+	requests := make(map[int32]requestInfo)
+	var order []int32
+
+	send := func(target endpoint.Endpoint) {
 		p.requestID++
-		p.results[target.Name].total++
-		requestsMu.Lock()
+		now := time.Now()
+		if err := p.sendRequest(p.requestID, target); err != nil {
+			p.l.Errorf("Error sending probe request for target %s: %v", target.Name, err)
+			fail(target.Name)
+			return
+		}
 		requests[p.requestID] = requestInfo{
 			target:    target.Name,
-			timestamp: time.Now(),
+			timestamp: now,
 		}
-		requestsMu.Unlock()
-		p.sendRequest(p.requestID, target)
+		order = append(order, p.requestID)
 		time.Sleep(TimeBetweenRequests)
 	}
 
-	// Send signal to receiver loop that we are done sending request.
-	close(doneChan)
-
-	// Wait for receiver goroutine to exit.
-	wg.Wait()
+	// expire fails the requests that have timed out, and returns the time
+	// until the next request's deadline.
+	expire := func() time.Duration {
+		for len(order) > 0 {
+			reqInfo, ok := requests[order[0]]
+			if !ok {
+				order = order[1:]
+				continue
+			}
+			if d := time.Until(reqInfo.timestamp.Add(timeout)); d > 0 {
+				return d
+			}
+			p.l.Warningf("Target(%s): request %d timed out", reqInfo.target, order[0])
+			delete(requests, order[0])
+			order = order[1:]
+			fail(reqInfo.target)
+		}
+		return timeout
+	}
+
+	// Requests waiting for an in-flight slot, in order.
+	var queue []endpoint.Endpoint
+
+	// admit sends the target's request if an in-flight slot is free. Otherwise,
+	// the request waits in the queue, or is dropped if the queue is full.
+	// Decision is based on the current number of in-flight and queued requests,
+	// so replies, timeouts and send failures free up the slots for the targets
+	// after them.
+	admit := func(target endpoint.Endpoint) {
+		result := p.results[target.Name]
+		result.total++
+		if maxInflight == 0 || len(requests) < maxInflight {
+			send(target)
+			return
+		}
+		if maxQueued > 0 && len(queue) >= maxQueued {
+			p.l.Warningf("Target(%s): request queue is full, dropping the request", target.Name)
+			result.dropped++
+			fail(target.Name)
+			return
+		}
+		result.queued++
+		queue = append(queue, target)
+	}
 
-	// Handle requests that we have not yet received replies for: "requests" will
-	// contain only outstanding requests by this point.
-	requestsMu.Lock()
-	defer requestsMu.Unlock()
-	for _, req := range requests {
+	handleReply := func(rep *serverpb.ProbeReply) {
+		reqInfo, ok := requests[rep.GetRequestId()]
+		if !ok {
+			// Not our reply, could be from the last timed out probe.
+			p.l.Warningf("Got a reply that doesn't match any outstading request: Request id from reply: %v. Ignoring.", rep.GetRequestId())
+			return
+		}
+		delete(requests, rep.GetRequestId())
+		success := true
+		if rep.GetErrorMessage() != "" {
+			p.l.Errorf("Probe for target %v failed with error message: %s", reqInfo.target, rep.GetErrorMessage())
+			success = false
+		}
 		p.processProbeResult(&probeStatus{
-			target:  req.target,
-			success: false,
-		}, p.results[req.target])
+			target:  reqInfo.target,
+			success: success,
+			latency: time.Since(reqInfo.timestamp),
+			payload: rep.GetPayload(),
+		}, p.results[reqInfo.target])
+	}
+
+	// Send the requests and read the replies, until all the requests are done
+	// or context has run out. Replies are read while sending the requests, so
+	// that the external probe server doesn't block on writing the replies, and
+	// so that the in-flight requests count is current when admitting a target.
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	targets := p.targets
+	for len(targets) > 0 || len(queue) > 0 || len(requests) > 0 {
+		select {
+		case rep := <-p.replyChan:
+			handleReply(rep)
+			continue
+		default:
+		}
+
+		if maxInflight > 0 {
+			expire()
+		}
+		// Queued requests go before the targets that are not admitted yet.
+		if len(queue) > 0 && len(requests) < maxInflight {
+			send(queue[0])
+			queue = queue[1:]
+			continue
+		}
+		if len(targets) > 0 {
+			admit(targets[0])
+			targets = targets[1:]
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(expire())
+		if len(requests) == 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			p.l.Error(ctx.Err().Error())
+			// Handle requests that we have not yet received replies for, and
+			// the ones that we have not sent yet.
+			for _, req := range requests {
+				fail(req.target)
+			}
+			for _, target := range queue {
+				fail(target.Name)
+			}
+			return
+		case rep := <-p.replyChan:
+			handleReply(rep)
+		case <-timer.C:
+		}
 	}
 }
 
@@ -550,45 +718,69 @@ var runCommand = func(ctx context.Context, cmd string, args []string) ([]byte, e
 }
 
 func (p *Probe) runOnceProbe(ctx context.Context) {
-	var wg sync.WaitGroup
-
-	for _, target := range p.targets {
-		wg.Add(1)
-		go func(target endpoint.Endpoint, result *result) {
-			defer wg.Done()
-			args := make([]string, len(p.cmdArgs))
-			for i, arg := range p.cmdArgs {
-				res, found := substituteLabels(arg, p.labels(target))
-				if !found {
-					p.l.Warningf("Substitution not found in %q", arg)
-				}
-				args[i] = res
+	skip := func(target endpoint.Endpoint) {
+		result := p.results[target.Name]
+		result.skipped++
+		em := p.defaultMetrics(target.Name, result)
+		p.opts.LogMetrics(em)
+		p.dataChan <- em
+	}
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.results[target.Name]
+		data := p.templateData(target)
+		args := make([]string, len(p.cmdArgs))
+		for i, arg := range p.cmdArgs {
+			res, err := p.expandArg(arg, target, data)
+			if err != nil {
+				p.l.Error(err.Error())
+				result.total++
+				p.processProbeResult(&probeStatus{target: target.Name, success: false}, result)
+				return
 			}
+			args[i] = res
+		}
 
-			p.l.Infof("Running external command: %s %s", p.cmdName, strings.Join(args, " "))
-			result.total++
-			startTime := time.Now()
-			b, err := runCommand(ctx, p.cmdName, args)
+		// Commands that don't get a slot in the commands pool before the probe
+		// times out are skipped for this run.
+		if !p.pool.acquire(ctx) {
+			p.l.Warningf("Target(%s): no free command slot before timeout, skipping", target.Name)
+			skip(target)
+			return
+		}
+		defer p.pool.release()
 
-			success := true
-			if err != nil {
-				success = false
-				if exitErr, ok := err.(*exec.ExitError); ok {
-					p.l.Errorf("external probe process died with the status: %s. Stderr: %s", exitErr.Error(), exitErr.Stderr)
-				} else {
-					p.l.Errorf("Error executing the external program. Err: %v", err)
-				}
+		p.l.Infof("Running external command: %s %s", p.cmdName, strings.Join(args, " "))
+		result.total++
+		startTime := time.Now()
+		b, err := runCommand(ctx, p.cmdName, args)
+
+		success := true
+		if err != nil {
+			success = false
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				p.l.Errorf("external probe process died with the status: %s. Stderr: %s", exitErr.Error(), exitErr.Stderr)
+			} else {
+				p.l.Errorf("Error executing the external program. Err: %v", err)
 			}
+		}
 
-			p.processProbeResult(&probeStatus{
-				target:  target.Name,
-				success: success,
-				latency: time.Since(startTime),
-				payload: string(b),
-			}, result)
-		}(target, p.results[target.Name])
+		p.processProbeResult(&probeStatus{
+			target:  target.Name,
+			success: success,
+			latency: time.Since(startTime),
+			payload: string(b),
+		}, result)
 	}
-	wg.Wait()
+
+	// Targets that don't get a concurrency slot before the probe times out are
+	// skipped for this run.
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot before timeout, skipping", target.Name)
+		skip(target)
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
 }
 
 func (p *Probe) updateTargets() {
@@ -634,7 +826,7 @@ func (p *Probe) runProbe(startCtx context.Context) {
 func (p *Probe) Start(startCtx context.Context, dataChan chan *metrics.EventMetrics) {
 	p.dataChan = dataChan
 
-	ticker := time.NewTicker(p.opts.Interval)
+	ticker := p.opts.NewTicker()
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -645,6 +837,13 @@ func (p *Probe) Start(startCtx context.Context, dataChan chan *metrics.EventMetr
 		default:
 		}
 
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
 		p.runProbe(startCtx)
+		runstats.RecordRun(p.name, start)
 	}
 }
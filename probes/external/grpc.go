@@ -0,0 +1,143 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	serverpb "github.com/cloudprober/cloudprober/probes/external/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+)
+
+// initGRPCClient sets up the gRPC client for the external probe server. The
+// connection is established, and re-established if it breaks, in the
+// background.
+func (p *Probe) initGRPCClient() error {
+	c := p.c.GetGrpcServer()
+	if c.GetAddress() == "" {
+		return errors.New("grpc_server: address is required")
+	}
+
+	creds := grpc.WithInsecure()
+	if c.GetTlsConfig() != nil {
+		tlsConfig := &tls.Config{}
+		if err := tlsconfig.UpdateTLSConfig(tlsConfig, c.GetTlsConfig(), false); err != nil {
+			return fmt.Errorf("grpc_server: %v", err)
+		}
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.Dial(c.GetAddress(), creds)
+	if err != nil {
+		return fmt.Errorf("grpc_server: error connecting to %s: %v", c.GetAddress(), err)
+	}
+	p.grpcConn = conn
+	p.grpcClient = serverpb.NewExternalProberClient(conn)
+	return nil
+}
+
+// waitForReady waits for the connection to the external probe server to be
+// ready, until the context is done.
+func (p *Probe) waitForReady(ctx context.Context) error {
+	for {
+		state := p.grpcConn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !p.grpcConn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("external probe server (%s) is not ready, connection state: %v", p.c.GetGrpcServer().GetAddress(), state)
+		}
+	}
+}
+
+// openStreamIfClosed opens the probe stream to the external probe server, if
+// it's not open already, e.g. because the server restarted. Connection is
+// waited for until ctx is done, while the stream itself lasts until startCtx
+// is done.
+func (p *Probe) openStreamIfClosed(ctx, startCtx context.Context) error {
+	p.grpcMu.Lock()
+	defer p.grpcMu.Unlock()
+
+	if p.grpcStream != nil {
+		return nil
+	}
+
+	if err := p.waitForReady(ctx); err != nil {
+		return err
+	}
+	stream, err := p.grpcClient.Probe(startCtx)
+	if err != nil {
+		return fmt.Errorf("error opening probe stream to the external probe server (%s): %v", p.c.GetGrpcServer().GetAddress(), err)
+	}
+	p.l.Infof("Opened probe stream to the external probe server: %s", p.c.GetGrpcServer().GetAddress())
+	p.grpcStream = stream
+
+	go p.readGRPCReplies(startCtx, stream)
+	return nil
+}
+
+// closeStream forgets the stream, if it's still the current stream, so that
+// a new stream is opened in the next probe run.
+func (p *Probe) closeStream(stream serverpb.ExternalProber_ProbeClient) {
+	p.grpcMu.Lock()
+	defer p.grpcMu.Unlock()
+	if p.grpcStream == stream {
+		p.grpcStream = nil
+	}
+}
+
+// readGRPCReplies reads the probe replies from the stream and puts them on
+// the probe's replyChan, until the stream breaks.
+func (p *Probe) readGRPCReplies(startCtx context.Context, stream serverpb.ExternalProber_ProbeClient) {
+	defer p.closeStream(stream)
+
+	for {
+		rep, err := stream.Recv()
+		if err != nil {
+			// Spare logging error message if stopped explicitly.
+			if startCtx.Err() == nil {
+				p.l.Errorf("External probe server stream closed. Err: %v", err)
+			}
+			return
+		}
+		select {
+		case p.replyChan <- rep:
+		case <-startCtx.Done():
+			return
+		}
+	}
+}
+
+// sendGRPCRequest sends the probe request over the current stream.
+func (p *Probe) sendGRPCRequest(req *serverpb.ProbeRequest) error {
+	p.grpcMu.Lock()
+	stream := p.grpcStream
+	p.grpcMu.Unlock()
+
+	if stream == nil {
+		return errors.New("no probe stream to the external probe server")
+	}
+	if err := stream.Send(req); err != nil {
+		p.closeStream(stream)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/external/proto"
+	serverpb "github.com/cloudprober/cloudprober/probes/external/proto"
+	"github.com/cloudprober/cloudprober/probes/external/serverutils"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// testGRPCProbeFunc replies to the probe requests based on their "action"
+// option.
+func testGRPCProbeFunc(req *serverpb.ProbeRequest, reply *serverpb.ProbeReply) {
+	var action string
+	for _, opt := range req.GetOptions() {
+		if opt.GetName() == "action" {
+			action = opt.GetValue()
+		}
+	}
+	switch action {
+	case "payload":
+		reply.Payload = proto.String("p90 45\n")
+	case "payload_with_error":
+		reply.Payload = proto.String("p90 45\n")
+		reply.ErrorMessage = proto.String("error")
+	case "slow":
+		time.Sleep(200 * time.Millisecond)
+	case "timeout":
+		time.Sleep(time.Duration(req.GetTimeLimit())*time.Millisecond + 100*time.Millisecond)
+	}
+}
+
+// startGRPCProbeServer starts an external probe gRPC server on the address.
+func startGRPCProbeServer(t *testing.T, addr string) (*grpc.Server, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Error listening on %s: %v", addr, err)
+	}
+	s := grpc.NewServer()
+	serverpb.RegisterExternalProberServer(s, serverutils.GRPCServer(testGRPCProbeFunc))
+	go s.Serve(ln)
+	return s, ln.Addr().String()
+}
+
+// createGRPCTestProbe creates a SERVER mode probe connecting to the gRPC
+// server at addr, with the given base config.
+func createGRPCTestProbe(t *testing.T, addr string, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	if c == nil {
+		c = &configpb.ProbeConf{}
+	}
+	c.Mode = configpb.ProbeConf_SERVER.Enum()
+	c.Options = []*configpb.ProbeConf_Option{
+		{
+			Name:  proto.String("action"),
+			Value: proto.String(""),
+		},
+	}
+	c.GrpcServer = &configpb.ProbeConf_GRPCServer{
+		Address: proto.String(addr),
+	}
+
+	p := &Probe{
+		dataChan: make(chan *metrics.EventMetrics, 20),
+	}
+	if err := p.Init("testProbe", &options.Options{
+		ProbeConf:         c,
+		Timeout:           time.Second,
+		LogMetrics:        func(em *metrics.EventMetrics) {},
+		LatencyMetricName: "latency",
+	}); err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	return p
+}
+
+func TestProbeGRPCServer(t *testing.T) {
+	s, addr := startGRPCProbeServer(t, "127.0.0.1:0")
+	defer func() { s.Stop() }()
+
+	p := createGRPCTestProbe(t, addr, nil)
+	total, success := make(map[string]int64), make(map[string]int64)
+
+	tgts := []string{"target1", "target2"}
+	for _, tgt := range tgts {
+		total[tgt]++
+		success[tgt]++
+	}
+	t.Run("nopayload", func(t *testing.T) {
+		runAndVerifyServerProbe(t, p, "nopayload", tgts, total, success, 2)
+	})
+
+	tgts = []string{"target1"}
+	for _, tgt := range tgts {
+		total[tgt]++
+		success[tgt]++
+	}
+	t.Run("payload", func(t *testing.T) {
+		// 2 metrics per target
+		runAndVerifyServerProbe(t, p, "payload", tgts, total, success, 1*2)
+	})
+
+	tgts = []string{"target1", "target2"}
+	for _, tgt := range tgts {
+		total[tgt]++
+	}
+	t.Run("payload_with_error", func(t *testing.T) {
+		// 2 targets, 2 EMs per target
+		runAndVerifyServerProbe(t, p, "payload_with_error", tgts, total, success, 2*2)
+	})
+
+	for _, tgt := range tgts {
+		total[tgt]++
+	}
+	t.Run("timeout", func(t *testing.T) {
+		// 2 targets, 1 EM per target
+		runAndVerifyServerProbe(t, p, "timeout", tgts, total, success, 2*1)
+	})
+
+	// Restart the server. Requests fail while the server is down, and the
+	// stream is re-opened once it's back.
+	s.Stop()
+	for _, tgt := range tgts {
+		total[tgt]++
+	}
+	t.Run("server_down", func(t *testing.T) {
+		runAndVerifyServerProbe(t, p, "nopayload", tgts, total, success, 2*1)
+	})
+
+	s, _ = startGRPCProbeServer(t, addr)
+	for _, tgt := range tgts {
+		total[tgt]++
+		success[tgt]++
+	}
+	t.Run("server_restarted", func(t *testing.T) {
+		// Connection is re-established with a backoff.
+		p.opts.Timeout = 3 * time.Second
+		runAndVerifyServerProbe(t, p, "nopayload", tgts, total, success, 2*1)
+	})
+}
+
+func TestGRPCServerInitErrors(t *testing.T) {
+	for _, c := range []*configpb.ProbeConf{
+		{
+			GrpcServer: &configpb.ProbeConf_GRPCServer{Address: proto.String("localhost:9313")},
+		},
+		{
+			Mode:       configpb.ProbeConf_SERVER.Enum(),
+			GrpcServer: &configpb.ProbeConf_GRPCServer{},
+		},
+		{
+			Mode: configpb.ProbeConf_SERVER.Enum(),
+		},
+	} {
+		p := &Probe{}
+		if err := p.Init("testProbe", &options.Options{ProbeConf: c}); err == nil {
+			t.Errorf("Init(%v): expected error", c)
+		}
+	}
+}
+
+func TestServerRequestLimits(t *testing.T) {
+	s, addr := startGRPCProbeServer(t, "127.0.0.1:0")
+	defer s.Stop()
+
+	tgts := []string{"t1", "t2", "t3", "t4"}
+
+	t.Run("inflight_and_queue", func(t *testing.T) {
+		p := createGRPCTestProbe(t, addr, &configpb.ProbeConf{
+			MaxInflightRequests: proto.Int32(2),
+			MaxQueuedRequests:   proto.Int32(1),
+		})
+		// t3 waits in the queue, and t4 is dropped.
+		total := map[string]int64{"t1": 1, "t2": 1, "t3": 1, "t4": 1}
+		success := map[string]int64{"t1": 1, "t2": 1, "t3": 1}
+		runAndVerifyServerProbe(t, p, "slow", tgts, total, success, 4)
+
+		for _, tgt := range tgts {
+			var wantQueued, wantDropped int64
+			switch tgt {
+			case "t3":
+				wantQueued = 1
+			case "t4":
+				wantDropped = 1
+			}
+			r := p.results[tgt]
+			if r.queued != wantQueued || r.dropped != wantDropped {
+				t.Errorf("Target(%s): got queued=%d, dropped=%d, want %d, %d", tgt, r.queued, r.dropped, wantQueued, wantDropped)
+			}
+		}
+
+		em := p.defaultMetrics("t4", p.results["t4"])
+		if em.Metric("queued_requests") == nil || em.Metric("dropped_requests").(metrics.NumValue).Int64() != 1 {
+			t.Errorf("Unexpected request metrics: %s", em.String())
+		}
+	})
+
+	t.Run("send_failures_free_slots", func(t *testing.T) {
+		// Nothing listens on this address, so requests fail to send and don't
+		// take up the in-flight slots: no request is queued or dropped.
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Error listening: %v", err)
+		}
+		ln.Close()
+
+		p := createGRPCTestProbe(t, ln.Addr().String(), &configpb.ProbeConf{
+			MaxInflightRequests: proto.Int32(1),
+			MaxQueuedRequests:   proto.Int32(1),
+		})
+		p.opts.Timeout = 100 * time.Millisecond
+		runAndVerifyServerProbe(t, p, "", tgts, map[string]int64{"t1": 1, "t2": 1, "t3": 1, "t4": 1}, map[string]int64{}, 4)
+
+		for _, tgt := range tgts {
+			if r := p.results[tgt]; r.queued != 0 || r.dropped != 0 {
+				t.Errorf("Target(%s): got queued=%d, dropped=%d, want 0, 0", tgt, r.queued, r.dropped)
+			}
+		}
+	})
+
+	t.Run("request_timeout", func(t *testing.T) {
+		p := createGRPCTestProbe(t, addr, &configpb.ProbeConf{
+			MaxInflightRequests: proto.Int32(1),
+			RequestTimeoutMsec:  proto.Int32(100),
+		})
+		p.opts.Timeout = 2 * time.Second
+
+		// Requests time out one after another, well before the probe timeout.
+		start := time.Now()
+		runAndVerifyServerProbe(t, p, "slow", tgts, map[string]int64{"t1": 1, "t2": 1, "t3": 1, "t4": 1}, map[string]int64{}, 4)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Probe run took %v, want less than a second", elapsed)
+		}
+	})
+}
+
+func TestServerRequestLimitsInitErrors(t *testing.T) {
+	for _, c := range []*configpb.ProbeConf{
+		{
+			Command:             proto.String("./testCommand"),
+			MaxInflightRequests: proto.Int32(2),
+		},
+		{
+			Command:           proto.String("./testCommand"),
+			Mode:              configpb.ProbeConf_SERVER.Enum(),
+			MaxQueuedRequests: proto.Int32(2),
+		},
+		{
+			Command:            proto.String("./testCommand"),
+			Mode:               configpb.ProbeConf_SERVER.Enum(),
+			RequestTimeoutMsec: proto.Int32(2000),
+		},
+	} {
+		p := &Probe{}
+		if err := p.Init("testProbe", &options.Options{ProbeConf: c, Timeout: time.Second}); err == nil {
+			t.Errorf("Init(%v): expected error", c)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"context"
+	"sync"
+)
+
+// cmdPool limits the number of external commands running at the same time.
+// A command must also get a slot in the parent pool, if any, e.g. the global
+// pool. A nil cmdPool doesn't limit the commands.
+type cmdPool struct {
+	sem    chan struct{}
+	parent *cmdPool
+}
+
+// newCmdPool returns a new pool of the given size within the parent pool. If
+// size is 0 or less, only the parent pool's limit applies.
+func newCmdPool(size int, parent *cmdPool) *cmdPool {
+	if size <= 0 {
+		return parent
+	}
+	return &cmdPool{sem: make(chan struct{}, size), parent: parent}
+}
+
+// acquire waits for a free slot in the pool. It returns false if ctx is done
+// before a slot becomes available.
+func (cp *cmdPool) acquire(ctx context.Context) bool {
+	if cp == nil {
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case cp.sem <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	if !cp.parent.acquire(ctx) {
+		<-cp.sem
+		return false
+	}
+	return true
+}
+
+// release frees up a slot acquired using acquire.
+func (cp *cmdPool) release() {
+	if cp == nil {
+		return
+	}
+	cp.parent.release()
+	<-cp.sem
+}
+
+var (
+	globalPoolMu sync.RWMutex
+	globalPool   *cmdPool
+)
+
+// SetMaxConcurrentCommands sets the process-wide limit on the number of
+// external commands running at the same time, shared by all external probes.
+// A value of 0 or less removes the limit. It should be called before the
+// probes are initialized.
+func SetMaxConcurrentCommands(n int) {
+	globalPoolMu.Lock()
+	defer globalPoolMu.Unlock()
+	globalPool = newCmdPool(n, nil)
+}
+
+func getGlobalPool() *cmdPool {
+	globalPoolMu.RLock()
+	defer globalPoolMu.RUnlock()
+	return globalPool
+}
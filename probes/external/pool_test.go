@@ -0,0 +1,171 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/external/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+)
+
+// concurrencyTracker is a mock command runner that keeps track of the maximum
+// number of commands running at the same time.
+type concurrencyTracker struct {
+	running, max int64
+	cmdDuration  time.Duration
+}
+
+func (ct *concurrencyTracker) runCommand(ctx context.Context, cmd string, args []string) ([]byte, error) {
+	n := atomic.AddInt64(&ct.running, 1)
+	defer atomic.AddInt64(&ct.running, -1)
+
+	for {
+		max := atomic.LoadInt64(&ct.max)
+		if n <= max || atomic.CompareAndSwapInt64(&ct.max, max, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(ct.cmdDuration):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func testPoolProbe(t *testing.T, name string, numTargets int, timeout time.Duration, maxConcurrentCommands *int32) *Probe {
+	t.Helper()
+
+	var tgts []string
+	for i := 0; i < numTargets; i++ {
+		tgts = append(tgts, fmt.Sprintf("%s-target%d", name, i))
+	}
+
+	p := &Probe{
+		dataChan: make(chan *metrics.EventMetrics, 10*numTargets),
+	}
+	err := p.Init(name, &options.Options{
+		ProbeConf: &configpb.ProbeConf{
+			Command:               proto.String("/test/cmd @target@"),
+			OutputAsMetrics:       proto.Bool(false),
+			MaxConcurrentCommands: maxConcurrentCommands,
+		},
+		Targets:           targets.StaticTargets(strings.Join(tgts, ",")),
+		Timeout:           timeout,
+		LogMetrics:        func(em *metrics.EventMetrics) {},
+		LatencyMetricName: "latency",
+	})
+	if err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	return p
+}
+
+func TestCmdPoolLimit(t *testing.T) {
+	oldRunCommand := runCommand
+	defer func() { runCommand = oldRunCommand }()
+	defer SetMaxConcurrentCommands(0)
+
+	for _, test := range []struct {
+		desc       string
+		globalMax  int
+		probeMax   *int32
+		numProbes  int
+		wantMaxCmd int64
+	}{
+		{desc: "global_limit_across_probes", globalMax: 3, numProbes: 3, wantMaxCmd: 3},
+		{desc: "probe_limit_within_global", globalMax: 3, probeMax: proto.Int32(2), numProbes: 1, wantMaxCmd: 2},
+		{desc: "global_limit_over_probe_limit", globalMax: 2, probeMax: proto.Int32(5), numProbes: 2, wantMaxCmd: 2},
+		{desc: "probe_limit_zero", globalMax: 3, probeMax: proto.Int32(0), numProbes: 2, wantMaxCmd: 3},
+		{desc: "no_limit", numProbes: 2, wantMaxCmd: 20},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ct := &concurrencyTracker{cmdDuration: 20 * time.Millisecond}
+			runCommand = ct.runCommand
+			SetMaxConcurrentCommands(test.globalMax)
+
+			var probes []*Probe
+			for i := 0; i < test.numProbes; i++ {
+				probes = append(probes, testPoolProbe(t, fmt.Sprintf("probe%d", i), 10, 5*time.Second, test.probeMax))
+			}
+
+			var wg sync.WaitGroup
+			for _, p := range probes {
+				wg.Add(1)
+				go func(p *Probe) {
+					defer wg.Done()
+					p.runProbe(context.Background())
+				}(p)
+			}
+			wg.Wait()
+
+			if len(probes[0].results) != 10 {
+				t.Fatalf("Got results for %d targets, want 10", len(probes[0].results))
+			}
+			if ct.max > test.wantMaxCmd {
+				t.Errorf("Max concurrently running commands=%d, want <= %d", ct.max, test.wantMaxCmd)
+			}
+			for _, p := range probes {
+				for target, res := range p.results {
+					if res.total != 1 || res.success != 1 || res.skipped != 0 {
+						t.Errorf("Probe %s, target %s: total=%d, success=%d, skipped=%d, want=1,1,0", p.name, target, res.total, res.success, res.skipped)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCmdPoolSkipped(t *testing.T) {
+	oldRunCommand := runCommand
+	defer func() { runCommand = oldRunCommand }()
+
+	// Commands don't finish before the probe timeout, so only one of the
+	// targets gets to run.
+	ct := &concurrencyTracker{cmdDuration: time.Hour}
+	runCommand = ct.runCommand
+
+	p := testPoolProbe(t, "probe", 3, 100*time.Millisecond, proto.Int32(1))
+	p.runProbe(context.Background())
+
+	var total, skipped int64
+	for _, res := range p.results {
+		total += res.total
+		skipped += res.skipped
+	}
+	if total != 1 || skipped != 2 {
+		t.Errorf("Got total=%d, skipped=%d, want total=1, skipped=2", total, skipped)
+	}
+	if ct.max != 1 {
+		t.Errorf("Max concurrently running commands=%d, want=1", ct.max)
+	}
+
+	// Skipped targets export skipped_targets metric.
+	em := <-p.dataChan
+	if em.Metric("skipped_targets") == nil {
+		t.Errorf("skipped_targets metric missing in: %s", em.String())
+	}
+}
@@ -10,6 +10,10 @@
 package proto
 
 import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -248,11 +252,16 @@ var file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_r
 	0x12, 0x23, 0x0a, 0x0d, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
 	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x4d, 0x65,
 	0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x42,
-	0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
-	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x65, 0x78, 0x74,
-	0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x32,
+	0x53, 0x0a, 0x0e, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x12, 0x41, 0x0a, 0x05, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x19, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00,
+	0x28, 0x01, 0x30, 0x01, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x73, 0x2f, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -275,8 +284,10 @@ var file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_g
 }
 var file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_depIdxs = []int32{
 	2, // 0: cloudprober.ProbeRequest.options:type_name -> cloudprober.ProbeRequest.Option
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
+	0, // 1: cloudprober.ExternalProber.Probe:input_type -> cloudprober.ProbeRequest
+	1, // 2: cloudprober.ExternalProber.Probe:output_type -> cloudprober.ProbeReply
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
 	1, // [1:1] is the sub-list for extension type_name
 	1, // [1:1] is the sub-list for extension extendee
 	0, // [0:1] is the sub-list for field type_name
@@ -333,7 +344,7 @@ func file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_
 			NumEnums:      0,
 			NumMessages:   3,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_goTypes,
 		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_depIdxs,
@@ -344,3 +355,115 @@ func file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_
 	file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_goTypes = nil
 	file_github_com_cloudprober_cloudprober_probes_external_proto_server_proto_depIdxs = nil
 }
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// ExternalProberClient is the client API for ExternalProber service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ExternalProberClient interface {
+	Probe(ctx context.Context, opts ...grpc.CallOption) (ExternalProber_ProbeClient, error)
+}
+
+type externalProberClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExternalProberClient(cc grpc.ClientConnInterface) ExternalProberClient {
+	return &externalProberClient{cc}
+}
+
+func (c *externalProberClient) Probe(ctx context.Context, opts ...grpc.CallOption) (ExternalProber_ProbeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ExternalProber_serviceDesc.Streams[0], "/cloudprober.ExternalProber/Probe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &externalProberProbeClient{stream}
+	return x, nil
+}
+
+type ExternalProber_ProbeClient interface {
+	Send(*ProbeRequest) error
+	Recv() (*ProbeReply, error)
+	grpc.ClientStream
+}
+
+type externalProberProbeClient struct {
+	grpc.ClientStream
+}
+
+func (x *externalProberProbeClient) Send(m *ProbeRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *externalProberProbeClient) Recv() (*ProbeReply, error) {
+	m := new(ProbeReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ExternalProberServer is the server API for ExternalProber service.
+type ExternalProberServer interface {
+	Probe(ExternalProber_ProbeServer) error
+}
+
+// UnimplementedExternalProberServer can be embedded to have forward compatible implementations.
+type UnimplementedExternalProberServer struct {
+}
+
+func (*UnimplementedExternalProberServer) Probe(ExternalProber_ProbeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Probe not implemented")
+}
+
+func RegisterExternalProberServer(s *grpc.Server, srv ExternalProberServer) {
+	s.RegisterService(&_ExternalProber_serviceDesc, srv)
+}
+
+func _ExternalProber_Probe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExternalProberServer).Probe(&externalProberProbeServer{stream})
+}
+
+type ExternalProber_ProbeServer interface {
+	Send(*ProbeReply) error
+	Recv() (*ProbeRequest, error)
+	grpc.ServerStream
+}
+
+type externalProberProbeServer struct {
+	grpc.ServerStream
+}
+
+func (x *externalProberProbeServer) Send(m *ProbeReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *externalProberProbeServer) Recv() (*ProbeRequest, error) {
+	m := new(ProbeRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _ExternalProber_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cloudprober.ExternalProber",
+	HandlerType: (*ExternalProberServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Probe",
+			Handler:       _ExternalProber_Probe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "github.com/cloudprober/cloudprober/probes/external/proto/server.proto",
+}
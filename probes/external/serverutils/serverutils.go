@@ -23,6 +23,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -139,21 +140,80 @@ func Serve(probeFunc func(*serverpb.ProbeRequest, *serverpb.ProbeReply)) {
 			log.Fatalf("Failed reading request: %v", err)
 		}
 		go func() {
-			reply := &serverpb.ProbeReply{
-				RequestId: request.RequestId,
-			}
-			done := make(chan bool, 1)
-			timeout := time.After(time.Duration(*request.TimeLimit) * time.Millisecond)
-			go func() {
-				probeFunc(request, reply)
-				done <- true
-			}()
-			select {
-			case <-done:
+			if reply, ok := runProbe(request, probeFunc); ok {
 				repliesChan <- reply
-			case <-timeout:
-				// drop the request on the floor.
-				fmt.Fprintf(os.Stderr, "Timeout for request %v\n", *reply.RequestId)
+			}
+		}()
+	}
+}
+
+// runProbe runs the probe function for the request, and returns its reply. It
+// returns false if the probe doesn't finish within the request's time limit.
+func runProbe(request *serverpb.ProbeRequest, probeFunc func(*serverpb.ProbeRequest, *serverpb.ProbeReply)) (*serverpb.ProbeReply, bool) {
+	reply := &serverpb.ProbeReply{
+		RequestId: request.RequestId,
+	}
+	done := make(chan bool, 1)
+	timeout := time.After(time.Duration(*request.TimeLimit) * time.Millisecond)
+	go func() {
+		probeFunc(request, reply)
+		done <- true
+	}()
+	select {
+	case <-done:
+		return reply, true
+	case <-timeout:
+		// drop the request on the floor.
+		fmt.Fprintf(os.Stderr, "Timeout for request %v\n", *reply.RequestId)
+		return nil, false
+	}
+}
+
+type grpcServer struct {
+	probeFunc func(*serverpb.ProbeRequest, *serverpb.ProbeReply)
+}
+
+// GRPCServer returns an implementation of the ExternalProber gRPC service,
+// for the external probe servers that cloudprober connects to over gRPC.
+// Similar to Serve, it runs the probe function for the incoming requests
+// concurrently, and drops the requests that exceed their time limit. Example
+// usage:
+//	s := grpc.NewServer()
+//	serverpb.RegisterExternalProberServer(s, serverutils.GRPCServer(func(req *serverpb.ProbeRequest, reply *serverpb.ProbeReply) {
+//		...
+//	}))
+//	s.Serve(lis)
+func GRPCServer(probeFunc func(*serverpb.ProbeRequest, *serverpb.ProbeReply)) serverpb.ExternalProberServer {
+	return &grpcServer{probeFunc: probeFunc}
+}
+
+// Probe implements the ExternalProber service.
+func (s *grpcServer) Probe(stream serverpb.ExternalProber_ProbeServer) error {
+	// Replies are sent from the probes' goroutines, and stream doesn't
+	// support concurrent sends.
+	var sendMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply, ok := runProbe(request, s.probeFunc)
+			if !ok {
+				return
+			}
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			if err := stream.Send(reply); err != nil {
+				fmt.Fprintf(os.Stderr, "Error sending reply for request %v: %v\n", reply.GetRequestId(), err)
 			}
 		}()
 	}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// templateData is the data available to the command arguments and options
+// templates, e.g. {{.Target}}, {{.IP}}, {{.Labels.region}}.
+type templateData struct {
+	Probe  string
+	Target string
+	IP     string
+	Port   int
+	Labels map[string]string
+}
+
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// parseTemplates parses the command arguments and options that use the
+// text/template syntax. Templates are validated by executing them with empty
+// data: references to unknown fields fail here, while references to target
+// labels can only be checked when a target is probed.
+func (p *Probe) parseTemplates() error {
+	p.templates = make(map[string]*template.Template)
+
+	var strs []string
+	strs = append(strs, p.cmdArgs...)
+	for _, opt := range p.c.GetOptions() {
+		strs = append(strs, opt.GetValue())
+	}
+
+	for _, s := range strs {
+		if !isTemplate(s) || p.templates[s] != nil {
+			continue
+		}
+		tmpl, err := template.New(s).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return fmt.Errorf("error parsing template %q: %v", s, err)
+		}
+
+		validateTmpl, err := tmpl.Clone()
+		if err != nil {
+			return err
+		}
+		if err := validateTmpl.Option("missingkey=zero").Execute(ioutil.Discard, &templateData{}); err != nil {
+			return fmt.Errorf("invalid template %q: %v", s, err)
+		}
+
+		if strings.Contains(s, ".IP") {
+			p.templateNeedsIP = true
+		}
+		p.templates[s] = tmpl
+	}
+	return nil
+}
+
+func (p *Probe) templateData(ep endpoint.Endpoint) *templateData {
+	data := &templateData{
+		Probe:  p.name,
+		Target: ep.Name,
+		Port:   ep.Port,
+		Labels: ep.Labels,
+	}
+	if data.Labels == nil {
+		data.Labels = make(map[string]string)
+	}
+
+	if p.templateNeedsIP {
+		addr, err := p.opts.ResolveTarget(ep.Name, nil)
+		if err != nil {
+			p.l.Warningf("Resolving target %v (IP version: %v) failed: %v ", ep.Name, p.opts.IPVersion, err)
+		} else if !addr.IsUnspecified() {
+			data.IP = addr.String()
+		}
+	}
+	return data
+}
+
+// expandArg expands an argument or option value for the given target: first
+// as a template, if it's one, and then for the @label@ substitutions.
+func (p *Probe) expandArg(s string, ep endpoint.Endpoint, data *templateData) (string, error) {
+	if tmpl := p.templates[s]; tmpl != nil {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("error executing template %q for target %s: %v", s, ep.Name, err)
+		}
+		s = b.String()
+	}
+
+	res, found := substituteLabels(s, p.labels(ep))
+	if !found {
+		p.l.Warningf("Substitution not found in %q", s)
+	}
+	return res, nil
+}
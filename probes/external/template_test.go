@@ -0,0 +1,119 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/external/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+)
+
+func testTemplateProbe(cmd string) (*Probe, error) {
+	p := &Probe{
+		dataChan: make(chan *metrics.EventMetrics, 20),
+	}
+	return p, p.Init("testProbe", &options.Options{
+		ProbeConf: &configpb.ProbeConf{
+			Command:         proto.String(cmd),
+			OutputAsMetrics: proto.Bool(false),
+		},
+		Targets:           targets.StaticTargets("10.0.0.1:8080,10.0.0.2:9090"),
+		Timeout:           time.Second,
+		LogMetrics:        func(em *metrics.EventMetrics) {},
+		LatencyMetricName: "latency",
+	})
+}
+
+func TestTemplateArgs(t *testing.T) {
+	oldRunCommand := runCommand
+	defer func() { runCommand = oldRunCommand }()
+
+	var mu sync.Mutex
+	gotArgs := make(map[string]string)
+	runCommand = func(ctx context.Context, cmd string, args []string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotArgs[args[0]] = strings.Join(args[1:], " ")
+		return nil, nil
+	}
+
+	p, err := testTemplateProbe("/test/cmd @target@ --ip={{.IP}} --port={{.Port}} --region={{.Labels.region}} --probe={{.Probe}}")
+	if err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	p.updateTargets()
+	regions := map[string]string{"10.0.0.1": "us-east1", "10.0.0.2": "eu-west1"}
+	for i := range p.targets {
+		p.targets[i].Labels = map[string]string{"region": regions[p.targets[i].Name]}
+	}
+
+	p.runOnceProbe(context.Background())
+
+	wantArgs := map[string]string{
+		"10.0.0.1": "--ip=10.0.0.1 --port=8080 --region=us-east1 --probe=testProbe",
+		"10.0.0.2": "--ip=10.0.0.2 --port=9090 --region=eu-west1 --probe=testProbe",
+	}
+	for target, want := range wantArgs {
+		if gotArgs[target] != want {
+			t.Errorf("Target %s: got args %q, want %q", target, gotArgs[target], want)
+		}
+		if res := p.results[target]; res.total != 1 || res.success != 1 {
+			t.Errorf("Target %s: total=%d, success=%d, want=1,1", target, res.total, res.success)
+		}
+	}
+
+	// Targets without the region label fail.
+	for i := range p.targets {
+		p.targets[i].Labels = nil
+	}
+	gotArgs = make(map[string]string)
+	p.runOnceProbe(context.Background())
+
+	if len(gotArgs) != 0 {
+		t.Errorf("Commands ran for targets without the required label: %v", gotArgs)
+	}
+	for target := range wantArgs {
+		if res := p.results[target]; res.total != 2 || res.success != 1 {
+			t.Errorf("Target %s: total=%d, success=%d, want=2,1", target, res.total, res.success)
+		}
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	for _, test := range []struct {
+		cmd     string
+		wantErr bool
+	}{
+		{cmd: "/test/cmd {{.Target}} {{.Labels.region}}"},
+		{cmd: "/test/cmd @target@ @address@"},
+		{cmd: "/test/cmd {{.Region}}", wantErr: true},
+		{cmd: "/test/cmd {{.Target", wantErr: true},
+	} {
+		t.Run(test.cmd, func(t *testing.T) {
+			_, err := testTemplateProbe(test.cmd)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Init error: %v, want error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package ftp implements an FTP and SFTP prober. It logs into the targets and,
+if configured, lists a directory or fetches a file. It reports statistics on
+probe runs, successful runs and latency, along with the login latency, the
+transfer latency and bytes, and the reason of the failures. For every
+completed transfer, it also reports the transfer throughput.
+
+Probes for all targets run in parallel.
+*/
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/file"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/ftp/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"golang.org/x/crypto/ssh"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonConnect  = "connect"
+	reasonLogin    = "login"
+	reasonTransfer = "transfer"
+)
+
+// session is a logged in FTP or SFTP session.
+type session interface {
+	list(ctx context.Context, path string, maxBytes int64) (int64, error)
+	retrieve(ctx context.Context, path string, maxBytes int64) (int64, error)
+	close()
+}
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets   []endpoint.Endpoint
+	dialF     func(context.Context, string, string) (net.Conn, error)
+	sshConfig *ssh.ClientConfig // Used only for SFTP.
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	loginLatency      metrics.Value
+	latencyMetricName string
+
+	// transferLatency and transferBytes are exported only if an operation is
+	// configured.
+	transferLatency metrics.Value
+	transferBytes   metrics.Int
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+
+	// Throughput of the transfer, reported as a separate result. nil if there
+	// was no completed transfer.
+	throughput *throughputResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures).
+		AddMetric("login_latency", prr.loginLatency)
+	if prr.transferLatency != nil {
+		em.AddMetric("transfer_latency", prr.transferLatency).
+			AddMetric("transfer_bytes", &prr.transferBytes)
+	}
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// throughputResult is a gauge update for the last transfer's throughput.
+type throughputResult struct {
+	target         string
+	bytesPerSecond float64
+}
+
+// Metrics converts throughputResult into metrics.EventMetrics object
+func (tr throughputResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("transfer_throughput", metrics.NewFloat(tr.bytesPerSecond))
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the tr.target.
+func (tr throughputResult) Target() string {
+	return tr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// initSSHConfig builds the SSH client config for SFTP.
+func (p *Probe) initSSHConfig() error {
+	p.sshConfig = &ssh.ClientConfig{
+		User: p.c.GetUsername(),
+	}
+
+	if p.c.GetPrivateKeyFile() != "" {
+		b, err := file.ReadFile(p.c.GetPrivateKeyFile())
+		if err != nil {
+			return fmt.Errorf("error reading private key file (%s): %v", p.c.GetPrivateKeyFile(), err)
+		}
+		signer, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return fmt.Errorf("error parsing private key file (%s): %v", p.c.GetPrivateKeyFile(), err)
+		}
+		p.sshConfig.Auth = append(p.sshConfig.Auth, ssh.PublicKeys(signer))
+	}
+	if p.c.Password != nil {
+		p.sshConfig.Auth = append(p.sshConfig.Auth, ssh.Password(p.c.GetPassword()))
+	}
+
+	if p.c.GetHostKey() == "" {
+		p.l.Warningf("ftp_probe(%s): host_key is not configured, server's host key will not be verified", p.name)
+		p.sshConfig.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+		return nil
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(p.c.GetHostKey()))
+	if err != nil {
+		return fmt.Errorf("invalid host_key (%s): %v", p.c.GetHostKey(), err)
+	}
+	p.sshConfig.HostKeyCallback = ssh.FixedHostKey(key)
+	p.sshConfig.HostKeyAlgorithms = []string{key.Type()}
+	return nil
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no ftp config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("ftp_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if p.c.GetMaxTransferBytes() <= 0 {
+		return fmt.Errorf("ftp_probe(%s): max_transfer_bytes should be positive: %d", name, p.c.GetMaxTransferBytes())
+	}
+
+	if p.c.GetProtocol() == configpb.ProbeConf_SFTP {
+		if err := p.initSSHConfig(); err != nil {
+			return fmt.Errorf("ftp_probe(%s): %v", name, err)
+		}
+	} else if p.c.GetPrivateKeyFile() != "" || p.c.GetHostKey() != "" {
+		return fmt.Errorf("ftp_probe(%s): private_key_file and host_key are supported only for SFTP", name)
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+		loginLatency:      p.newLatencyValue(),
+	}
+
+	if p.c.GetOperation() != configpb.ProbeConf_NONE {
+		result.transferLatency = p.newLatencyValue()
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// login connects to the target and logs in.
+func (p *Probe) login(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) (session, string, error) {
+	defaultPort := 21
+	if p.c.GetProtocol() == configpb.ProbeConf_SFTP {
+		defaultPort = 22
+	}
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(target.Name, strconv.Itoa(port))
+
+	conn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		return nil, reasonConnect, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	options.RecordIPVersionUsed(result.ipVersionUsed, conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if p.c.GetProtocol() == configpb.ProbeConf_SFTP {
+		s, err := newSFTPConn(conn, addr, p.sshConfig)
+		if err != nil {
+			conn.Close()
+			return nil, reasonLogin, fmt.Errorf("SFTP login failed: %w", err)
+		}
+		return s, "", nil
+	}
+
+	s, err := newFTPConn(conn, p.dialF)
+	if err == nil {
+		err = s.login(p.c.GetUsername(), p.c.GetPassword())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, reasonLogin, fmt.Errorf("FTP login failed: %w", err)
+	}
+	return s, "", nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	failed := func(reason string, err error) {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reason)
+	}
+
+	start := time.Now()
+	s, reason, err := p.login(ctx, target, result)
+	if err != nil {
+		failed(reason, err)
+		return
+	}
+	defer s.close()
+	result.loginLatency.AddFloat64(time.Since(start).Seconds() / p.opts.LatencyUnit.Seconds())
+
+	if op := p.c.GetOperation(); op != configpb.ProbeConf_NONE {
+		transferStart := time.Now()
+		var n int64
+		if op == configpb.ProbeConf_LIST {
+			n, err = s.list(ctx, p.c.GetPath(), p.c.GetMaxTransferBytes())
+		} else {
+			n, err = s.retrieve(ctx, p.c.GetPath(), p.c.GetMaxTransferBytes())
+		}
+		if err != nil {
+			failed(reasonTransfer, fmt.Errorf("%s %s failed: %w", op, p.c.GetPath(), err))
+			return
+		}
+
+		transferLatency := time.Since(transferStart)
+		result.transferLatency.AddFloat64(transferLatency.Seconds() / p.opts.LatencyUnit.Seconds())
+		result.transferBytes.AddInt64(n)
+		result.throughput = &throughputResult{
+			target:         target.Name,
+			bytesPerSecond: float64(n) / transferLatency.Seconds(),
+		}
+	}
+
+	latency := time.Since(start)
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		if result.throughput != nil {
+			resultsChan <- *result.throughput
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "ftp", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/ftp/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+// testFiles are the files served by the test servers.
+var testFiles = map[string]string{
+	"hello.txt": "hello world",
+	"large.bin": strings.Repeat("x", 100000),
+}
+
+// testListing returns the directory listing for testFiles, in the ls -l
+// format.
+func testListing() string {
+	var names []string
+	for name := range testFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("-rw-r--r-- 1 ftp ftp %d Jan 01 00:00 %s\r\n", len(testFiles[name]), name))
+	}
+	return strings.Join(lines, "")
+}
+
+// startFTPServer starts a minimal FTP server, serving testFiles. It accepts
+// anonymous logins and user:pass. If noEPSV is set, it doesn't support EPSV.
+func startFTPServer(t *testing.T, noEPSV bool) endpoint.Endpoint {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFTP(conn, noEPSV)
+		}
+	}()
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+}
+
+func serveFTP(conn net.Conn, noEPSV bool) {
+	defer conn.Close()
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 test FTP")
+
+	var user string
+	var dataLn net.Listener
+	defer func() {
+		if dataLn != nil {
+			dataLn.Close()
+		}
+	}()
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg := line, ""
+		if i := strings.Index(line, " "); i != -1 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+
+		switch cmd {
+		case "USER":
+			user = arg
+			if user == "anonymous" {
+				tc.PrintfLine("230 Logged in")
+			} else {
+				tc.PrintfLine("331 Password required")
+			}
+		case "PASS":
+			if user == "user" && arg == "pass" {
+				tc.PrintfLine("230 Logged in")
+			} else {
+				tc.PrintfLine("530 Login incorrect")
+			}
+		case "TYPE":
+			tc.PrintfLine("200 Type set")
+		case "EPSV", "PASV":
+			if cmd == "EPSV" && noEPSV {
+				tc.PrintfLine("502 Command not implemented")
+				continue
+			}
+			if dataLn, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+			port := dataLn.Addr().(*net.TCPAddr).Port
+			if cmd == "EPSV" {
+				tc.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", port)
+			} else {
+				// Address in the PASV response should be ignored by the client.
+				tc.PrintfLine("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&0xff)
+			}
+		case "LIST", "RETR":
+			content := testListing()
+			if cmd == "RETR" {
+				var ok bool
+				if content, ok = testFiles[arg]; !ok {
+					tc.PrintfLine("550 No such file")
+					continue
+				}
+			}
+			if dataLn == nil {
+				tc.PrintfLine("425 Use EPSV or PASV first")
+				continue
+			}
+			tc.PrintfLine("150 Opening data connection")
+			data, err := dataLn.Accept()
+			dataLn.Close()
+			dataLn = nil
+			if err != nil {
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+			_, err = data.Write([]byte(content))
+			data.Close()
+			if err != nil {
+				tc.PrintfLine("426 Transfer aborted")
+				continue
+			}
+			tc.PrintfLine("226 Transfer complete")
+		case "QUIT":
+			tc.PrintfLine("221 Bye")
+			return
+		default:
+			tc.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "ftp_test", testutils.Options("127.0.0.1", time.Second, c))
+	return p
+}
+
+// probeTest is a test case for running the probe, shared by the FTP and SFTP
+// tests.
+type probeTest struct {
+	desc       string
+	conf       *configpb.ProbeConf
+	wantReason string
+	wantBytes  int64 // Ignored if there is no operation.
+}
+
+func runProbeTest(t *testing.T, target endpoint.Endpoint, test probeTest) {
+	t.Helper()
+
+	p := testProbe(t, test.conf)
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(context.Background(), target, &result)
+
+	if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+		t.Fatal(err)
+	}
+
+	if test.wantReason != "" {
+		return
+	}
+
+	em := result.Metrics()
+	if em.Metric("login_latency") == nil {
+		t.Errorf("Metric login_latency missing: %s", em.String())
+	}
+
+	if test.conf.GetOperation() == configpb.ProbeConf_NONE {
+		if em.Metric("transfer_bytes") != nil || result.throughput != nil {
+			t.Errorf("Got transfer metrics without an operation: %s", em.String())
+		}
+		return
+	}
+	if result.transferBytes.Int64() != test.wantBytes {
+		t.Errorf("Got transfer_bytes=%d, want=%d", result.transferBytes.Int64(), test.wantBytes)
+	}
+	if result.throughput == nil || result.throughput.bytesPerSecond <= 0 {
+		t.Errorf("Got throughput=%v, want a positive throughput", result.throughput)
+	}
+}
+
+func TestFTPProbe(t *testing.T) {
+	target := startFTPServer(t, false)
+
+	retrieve := func(path string) *configpb.ProbeConf {
+		return &configpb.ProbeConf{
+			Operation: configpb.ProbeConf_RETRIEVE.Enum(),
+			Path:      proto.String(path),
+		}
+	}
+
+	for _, test := range []probeTest{
+		{
+			desc: "anonymous_login",
+			conf: &configpb.ProbeConf{},
+		},
+		{
+			desc: "login",
+			conf: &configpb.ProbeConf{Username: proto.String("user"), Password: proto.String("pass")},
+		},
+		{
+			desc:       "login_failure",
+			conf:       &configpb.ProbeConf{Username: proto.String("user"), Password: proto.String("wrong")},
+			wantReason: reasonLogin,
+		},
+		{
+			desc:      "list",
+			conf:      &configpb.ProbeConf{Operation: configpb.ProbeConf_LIST.Enum()},
+			wantBytes: int64(len(testListing())),
+		},
+		{
+			desc:      "retrieve",
+			conf:      retrieve("hello.txt"),
+			wantBytes: 11,
+		},
+		{
+			desc: "retrieve_truncated",
+			conf: &configpb.ProbeConf{
+				Operation:        configpb.ProbeConf_RETRIEVE.Enum(),
+				Path:             proto.String("large.bin"),
+				MaxTransferBytes: proto.Int64(1000),
+			},
+			wantBytes: 1000,
+		},
+		{
+			desc:       "retrieve_missing",
+			conf:       retrieve("missing.txt"),
+			wantReason: reasonTransfer,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			runProbeTest(t, target, test)
+		})
+	}
+}
+
+func TestFTPPassiveFallback(t *testing.T) {
+	target := startFTPServer(t, true)
+
+	runProbeTest(t, target, probeTest{
+		conf: &configpb.ProbeConf{
+			Operation: configpb.ProbeConf_RETRIEVE.Enum(),
+			Path:      proto.String("large.bin"),
+		},
+		wantBytes: 100000,
+	})
+}
+
+func TestConnectFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+	ln.Close()
+
+	for _, protocol := range []configpb.ProbeConf_ProtocolType{configpb.ProbeConf_FTP, configpb.ProbeConf_SFTP} {
+		t.Run(protocol.String(), func(t *testing.T) {
+			runProbeTest(t, target, probeTest{
+				conf:       &configpb.ProbeConf{Protocol: protocol.Enum()},
+				wantReason: reasonConnect,
+			})
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "invalid_max_transfer_bytes", Conf: &configpb.ProbeConf{MaxTransferBytes: proto.Int64(0)}},
+		{Desc: "ftp_host_key", Conf: &configpb.ProbeConf{HostKey: proto.String("ssh-ed25519 AAAA")}},
+		{
+			Desc: "invalid_host_key",
+			Conf: &configpb.ProbeConf{Protocol: configpb.ProbeConf_SFTP.Enum(), HostKey: proto.String("invalid")},
+		},
+		{
+			Desc: "missing_private_key_file",
+			Conf: &configpb.ProbeConf{Protocol: configpb.ProbeConf_SFTP.Enum(), PrivateKeyFile: proto.String("/does/not/exist")},
+		},
+	})
+}
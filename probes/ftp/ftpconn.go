@@ -0,0 +1,191 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ftpConn is a minimal FTP client, enough to log in, and to list or retrieve
+// a path over a passive mode data connection.
+type ftpConn struct {
+	conn  net.Conn
+	tc    *textproto.Conn
+	dialF func(context.Context, string, string) (net.Conn, error)
+
+	// broken is set if the control connection is not in a state to send
+	// more commands, e.g. after a truncated transfer.
+	broken bool
+}
+
+// newFTPConn reads the server greeting on the given control connection.
+func newFTPConn(conn net.Conn, dialF func(context.Context, string, string) (net.Conn, error)) (*ftpConn, error) {
+	c := &ftpConn{
+		conn:  conn,
+		tc:    textproto.NewConn(conn),
+		dialF: dialF,
+	}
+	if _, _, err := c.tc.ReadResponse(220); err != nil {
+		return nil, fmt.Errorf("error reading greeting: %w", err)
+	}
+	return c, nil
+}
+
+// cmd sends a command and reads its response. A single digit expectCode
+// matches the response code's first digit.
+func (c *ftpConn) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	if err := c.tc.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.tc.ReadResponse(expectCode)
+}
+
+func (c *ftpConn) login(username, password string) error {
+	code, msg, err := c.cmd(0, "USER %s", username)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case 230:
+		return nil
+	case 331:
+		_, _, err = c.cmd(2, "PASS %s", password)
+		return err
+	default:
+		return &textproto.Error{Code: code, Msg: msg}
+	}
+}
+
+// parseEPSV parses the port from an EPSV response, e.g.
+// "Entering Extended Passive Mode (|||6446|)".
+func parseEPSV(msg string) (int, error) {
+	start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+	if start == -1 || end < start+4 {
+		return 0, fmt.Errorf("invalid EPSV response: %s", msg)
+	}
+	return strconv.Atoi(msg[start+4 : end])
+}
+
+// parsePASV parses the port from a PASV response, e.g.
+// "Entering Passive Mode (192,168,1,2,25,46)". The address in the response
+// is ignored, as it's often wrong behind NAT; data connections go to the
+// control connection's host.
+func parsePASV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start == -1 || end < start {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	return p1<<8 | p2, nil
+}
+
+// openData opens a passive mode data connection, trying EPSV first.
+func (c *ftpConn) openData(ctx context.Context) (net.Conn, error) {
+	var port int
+	code, msg, err := c.cmd(229, "EPSV")
+	if err == nil {
+		port, err = parseEPSV(msg)
+	} else if code/100 == 5 {
+		// EPSV not supported, fallback to PASV.
+		if _, msg, err = c.cmd(227, "PASV"); err == nil {
+			port, err = parsePASV(msg)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(c.conn.RemoteAddr().String())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.dialF(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening data connection: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// transfer runs a data transfer command (LIST or RETR) for the path, and
+// reads up to maxBytes from the data connection. It returns the number of
+// bytes read.
+func (c *ftpConn) transfer(ctx context.Context, command, path string, maxBytes int64) (int64, error) {
+	if command == "RETR" {
+		if _, _, err := c.cmd(2, "TYPE I"); err != nil {
+			return 0, err
+		}
+	}
+
+	data, err := c.openData(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer data.Close()
+
+	if _, _, err := c.cmd(1, "%s %s", command, path); err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(ioutil.Discard, io.LimitReader(data, maxBytes+1))
+	if err != nil {
+		return n, err
+	}
+	if n > maxBytes {
+		// We stop reading mid-transfer. Server will abort the transfer once we
+		// close the data connection, but its reply on the control connection
+		// is unpredictable, so we don't reuse it.
+		c.broken = true
+		return maxBytes, nil
+	}
+
+	data.Close()
+	if _, _, err := c.tc.ReadResponse(2); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (c *ftpConn) list(ctx context.Context, path string, maxBytes int64) (int64, error) {
+	return c.transfer(ctx, "LIST", path, maxBytes)
+}
+
+func (c *ftpConn) retrieve(ctx context.Context, path string, maxBytes int64) (int64, error) {
+	return c.transfer(ctx, "RETR", path, maxBytes)
+}
+
+func (c *ftpConn) close() {
+	if !c.broken {
+		c.cmd(2, "QUIT")
+	}
+	c.conn.Close()
+}
@@ -0,0 +1,384 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/ftp/proto/config.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf_ProtocolType int32
+
+const (
+	ProbeConf_FTP  ProbeConf_ProtocolType = 0
+	ProbeConf_SFTP ProbeConf_ProtocolType = 1
+)
+
+// Enum value maps for ProbeConf_ProtocolType.
+var (
+	ProbeConf_ProtocolType_name = map[int32]string{
+		0: "FTP",
+		1: "SFTP",
+	}
+	ProbeConf_ProtocolType_value = map[string]int32{
+		"FTP":  0,
+		"SFTP": 1,
+	}
+)
+
+func (x ProbeConf_ProtocolType) Enum() *ProbeConf_ProtocolType {
+	p := new(ProbeConf_ProtocolType)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_ProtocolType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_ProtocolType) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_ProtocolType) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_ProtocolType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_ProtocolType) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_ProtocolType(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_ProtocolType.Descriptor instead.
+func (ProbeConf_ProtocolType) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+// Operation to perform after logging in. With NONE, probe runs succeed once
+// logged in.
+type ProbeConf_Operation int32
+
+const (
+	ProbeConf_NONE     ProbeConf_Operation = 0
+	ProbeConf_LIST     ProbeConf_Operation = 1 // List the directory at path.
+	ProbeConf_RETRIEVE ProbeConf_Operation = 2 // Fetch the file at path.
+)
+
+// Enum value maps for ProbeConf_Operation.
+var (
+	ProbeConf_Operation_name = map[int32]string{
+		0: "NONE",
+		1: "LIST",
+		2: "RETRIEVE",
+	}
+	ProbeConf_Operation_value = map[string]int32{
+		"NONE":     0,
+		"LIST":     1,
+		"RETRIEVE": 2,
+	}
+)
+
+func (x ProbeConf_Operation) Enum() *ProbeConf_Operation {
+	p := new(ProbeConf_Operation)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Operation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Operation) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeConf_Operation) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeConf_Operation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Operation) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Operation(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Operation.Descriptor instead.
+func (ProbeConf_Operation) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Protocol *ProbeConf_ProtocolType `protobuf:"varint,1,opt,name=protocol,enum=cloudprober.probes.ftp.ProbeConf_ProtocolType,def=0" json:"protocol,omitempty"`
+	// Port to connect to. If not specified, target's port is used, and if the
+	// target doesn't have a port either, 21 (FTP) or 22 (SFTP) is used.
+	Port *int32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	// Credentials to log in with.
+	Username *string `protobuf:"bytes,3,opt,name=username,def=anonymous" json:"username,omitempty"`
+	Password *string `protobuf:"bytes,4,opt,name=password" json:"password,omitempty"`
+	// Private key (PEM) to authenticate with, for SFTP. Can be used along with
+	// or instead of password.
+	PrivateKeyFile *string `protobuf:"bytes,5,opt,name=private_key_file,json=privateKeyFile" json:"private_key_file,omitempty"`
+	// Server's public host key, for SFTP, in the authorized_keys format, e.g.
+	// "ssh-ed25519 AAAAC3Nz...". If not specified, the server's host key is not
+	// verified.
+	HostKey   *string              `protobuf:"bytes,6,opt,name=host_key,json=hostKey" json:"host_key,omitempty"`
+	Operation *ProbeConf_Operation `protobuf:"varint,7,opt,name=operation,enum=cloudprober.probes.ftp.ProbeConf_Operation,def=0" json:"operation,omitempty"`
+	// Path to list or fetch. Relative paths are relative to the login
+	// directory.
+	Path *string `protobuf:"bytes,8,opt,name=path,def=." json:"path,omitempty"`
+	// Maximum number of bytes to transfer per probe run. Transfers are
+	// truncated beyond this size, e.g. to guard against a large file at path.
+	MaxTransferBytes *int64 `protobuf:"varint,9,opt,name=max_transfer_bytes,json=maxTransferBytes,def=1048576" json:"max_transfer_bytes,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Protocol         = ProbeConf_FTP
+	Default_ProbeConf_Username         = string("anonymous")
+	Default_ProbeConf_Operation        = ProbeConf_NONE
+	Default_ProbeConf_Path             = string(".")
+	Default_ProbeConf_MaxTransferBytes = int64(1048576)
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetProtocol() ProbeConf_ProtocolType {
+	if x != nil && x.Protocol != nil {
+		return *x.Protocol
+	}
+	return Default_ProbeConf_Protocol
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetUsername() string {
+	if x != nil && x.Username != nil {
+		return *x.Username
+	}
+	return Default_ProbeConf_Username
+}
+
+func (x *ProbeConf) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetPrivateKeyFile() string {
+	if x != nil && x.PrivateKeyFile != nil {
+		return *x.PrivateKeyFile
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetHostKey() string {
+	if x != nil && x.HostKey != nil {
+		return *x.HostKey
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetOperation() ProbeConf_Operation {
+	if x != nil && x.Operation != nil {
+		return *x.Operation
+	}
+	return Default_ProbeConf_Operation
+}
+
+func (x *ProbeConf) GetPath() string {
+	if x != nil && x.Path != nil {
+		return *x.Path
+	}
+	return Default_ProbeConf_Path
+}
+
+func (x *ProbeConf) GetMaxTransferBytes() int64 {
+	if x != nil && x.MaxTransferBytes != nil {
+		return *x.MaxTransferBytes
+	}
+	return Default_ProbeConf_MaxTransferBytes
+}
+
+var File_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x66, 0x74, 0x70, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x16, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x66, 0x74, 0x70, 0x22, 0xe9, 0x03, 0x0a, 0x09, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x4f, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2e, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e,
+	0x66, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x50, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x54, 0x79, 0x70, 0x65, 0x3a, 0x03, 0x46, 0x54, 0x50, 0x52,
+	0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x25, 0x0a,
+	0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x3a,
+	0x09, 0x61, 0x6e, 0x6f, 0x6e, 0x79, 0x6d, 0x6f, 0x75, 0x73, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64,
+	0x12, 0x28, 0x0a, 0x10, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x5f,
+	0x66, 0x69, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x68, 0x6f,
+	0x73, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f,
+	0x73, 0x74, 0x4b, 0x65, 0x79, 0x12, 0x4f, 0x0a, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2b, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x66, 0x74,
+	0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x4f, 0x70, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x52, 0x09, 0x6f, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x15, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x3a, 0x01, 0x2e, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x35, 0x0a,
+	0x12, 0x6d, 0x61, 0x78, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x5f, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x3a, 0x07, 0x31, 0x30, 0x34, 0x38, 0x35,
+	0x37, 0x36, 0x52, 0x10, 0x6d, 0x61, 0x78, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x42,
+	0x79, 0x74, 0x65, 0x73, 0x22, 0x21, 0x0a, 0x0c, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x07, 0x0a, 0x03, 0x46, 0x54, 0x50, 0x10, 0x00, 0x12, 0x08, 0x0a,
+	0x04, 0x53, 0x46, 0x54, 0x50, 0x10, 0x01, 0x22, 0x2d, 0x0a, 0x09, 0x4f, 0x70, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x08,
+	0x0a, 0x04, 0x4c, 0x49, 0x53, 0x54, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x54, 0x52,
+	0x49, 0x45, 0x56, 0x45, 0x10, 0x02, 0x42, 0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2f, 0x66, 0x74, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_ProtocolType)(0), // 0: cloudprober.probes.ftp.ProbeConf.ProtocolType
+	(ProbeConf_Operation)(0),    // 1: cloudprober.probes.ftp.ProbeConf.Operation
+	(*ProbeConf)(nil),           // 2: cloudprober.probes.ftp.ProbeConf
+}
+var file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.ftp.ProbeConf.protocol:type_name -> cloudprober.probes.ftp.ProbeConf.ProtocolType
+	1, // 1: cloudprober.probes.ftp.ProbeConf.operation:type_name -> cloudprober.probes.ftp.ProbeConf.Operation
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_ftp_proto_config_proto_depIdxs = nil
+}
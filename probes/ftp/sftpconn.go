@@ -0,0 +1,308 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP (version 3) protocol constants, from draft-ietf-secsh-filexfer-02,
+// and client limits.
+const (
+	sshFxpInit    = 1
+	sshFxpVersion = 2
+	sshFxpOpen    = 3
+	sshFxpClose   = 4
+	sshFxpRead    = 5
+	sshFxpOpendir = 11
+	sshFxpReaddir = 12
+	sshFxpStatus  = 101
+	sshFxpHandle  = 102
+	sshFxpData    = 103
+	sshFxpName    = 104
+	sshFxfRead    = 1
+	sshFxOK       = 0
+	sshFxEOF      = 1
+	sftpVersion   = 3
+	sftpReadSize  = 32768
+	sftpMaxPacket = 256 * 1024
+)
+
+// sftpConn is a minimal SFTP client, enough to list or retrieve a path.
+type sftpConn struct {
+	client  *ssh.Client
+	session *ssh.Session
+	w       io.WriteCloser
+	r       io.Reader
+	nextID  uint32
+}
+
+// newSFTPConn logs in over the given connection and starts the SFTP
+// subsystem.
+func newSFTPConn(conn net.Conn, addr string, config *ssh.ClientConfig) (*sftpConn, error) {
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	c := &sftpConn{client: ssh.NewClient(sshConn, chans, reqs)}
+
+	if err := c.start(); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sftpConn) start() (err error) {
+	if c.session, err = c.client.NewSession(); err != nil {
+		return err
+	}
+	if c.w, err = c.session.StdinPipe(); err != nil {
+		return err
+	}
+	if c.r, err = c.session.StdoutPipe(); err != nil {
+		return err
+	}
+	if err := c.session.RequestSubsystem("sftp"); err != nil {
+		return fmt.Errorf("error starting sftp subsystem: %w", err)
+	}
+
+	// INIT is the only packet without a request id.
+	if err := c.send(sshFxpInit, uint32(sftpVersion)); err != nil {
+		return err
+	}
+	typ, _, err := readPacket(c.r)
+	if err != nil {
+		return err
+	}
+	if typ != sshFxpVersion {
+		return fmt.Errorf("unexpected packet type in response to INIT: %d", typ)
+	}
+	return nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// marshalPacket returns a packet with the given fields. Fields can be uint32,
+// uint64, string or []byte.
+func marshalPacket(typ byte, fields ...interface{}) ([]byte, error) {
+	b := []byte{0, 0, 0, 0, typ}
+	for _, f := range fields {
+		switch v := f.(type) {
+		case uint32:
+			b = appendUint32(b, v)
+		case uint64:
+			b = appendUint32(b, uint32(v>>32))
+			b = appendUint32(b, uint32(v))
+		case string:
+			b = appendUint32(b, uint32(len(v)))
+			b = append(b, v...)
+		case []byte:
+			b = appendUint32(b, uint32(len(v)))
+			b = append(b, v...)
+		default:
+			return nil, fmt.Errorf("unsupported field type: %T", f)
+		}
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b, nil
+}
+
+func (c *sftpConn) send(typ byte, fields ...interface{}) error {
+	b, err := marshalPacket(typ, fields...)
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(b)
+	return err
+}
+
+// readPacket reads a packet, and returns its type and payload.
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(lenBuf[:])
+	if length == 0 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("invalid packet length: %d", length)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, err
+	}
+	return b[0], b[1:], nil
+}
+
+// request sends a request and returns the response's type and payload, after
+// the request id.
+func (c *sftpConn) request(typ byte, fields ...interface{}) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	if err := c.send(typ, append([]interface{}{id}, fields...)...); err != nil {
+		return 0, nil, err
+	}
+
+	respType, payload, err := readPacket(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(payload) < 4 || binary.BigEndian.Uint32(payload) != id {
+		return 0, nil, fmt.Errorf("unexpected response for the request id %d", id)
+	}
+	return respType, payload[4:], nil
+}
+
+// readString reads a length-prefixed string from b, and returns the rest.
+func readString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("short packet")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, nil, errors.New("short packet")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+// statusError returns the error for a STATUS response's payload, nil for OK
+// and io.EOF for EOF.
+func statusError(payload []byte) error {
+	if len(payload) < 4 {
+		return errors.New("short status packet")
+	}
+	code := binary.BigEndian.Uint32(payload)
+	switch code {
+	case sshFxOK:
+		return nil
+	case sshFxEOF:
+		return io.EOF
+	}
+	msg, _, _ := readString(payload[4:])
+	return fmt.Errorf("sftp error (code: %d): %s", code, string(msg))
+}
+
+// responseError returns an error for an unexpected response.
+func responseError(typ byte, payload []byte) error {
+	if typ == sshFxpStatus {
+		if err := statusError(payload); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return fmt.Errorf("unexpected response type: %d", typ)
+}
+
+func (c *sftpConn) handle(typ byte, fields ...interface{}) ([]byte, error) {
+	respType, payload, err := c.request(typ, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if respType != sshFxpHandle {
+		return nil, responseError(respType, payload)
+	}
+	h, _, err := readString(payload)
+	return h, err
+}
+
+func (c *sftpConn) closeHandle(h []byte) error {
+	respType, payload, err := c.request(sshFxpClose, h)
+	if err != nil {
+		return err
+	}
+	if respType != sshFxpStatus {
+		return responseError(respType, payload)
+	}
+	return statusError(payload)
+}
+
+// list reads the directory entries for the path, up to maxBytes of the
+// NAME responses. It returns the number of bytes read.
+func (c *sftpConn) list(ctx context.Context, path string, maxBytes int64) (int64, error) {
+	h, err := c.handle(sshFxpOpendir, path)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for n < maxBytes {
+		respType, payload, err := c.request(sshFxpReaddir, h)
+		if err != nil {
+			return n, err
+		}
+		if respType == sshFxpStatus && statusError(payload) == io.EOF {
+			break
+		}
+		if respType != sshFxpName {
+			return n, responseError(respType, payload)
+		}
+		n += int64(len(payload))
+	}
+	if n > maxBytes {
+		n = maxBytes
+	}
+
+	return n, c.closeHandle(h)
+}
+
+// retrieve reads the file at the path, up to maxBytes. It returns the number
+// of bytes read.
+func (c *sftpConn) retrieve(ctx context.Context, path string, maxBytes int64) (int64, error) {
+	// Open for reading, with no attributes (flags: 0).
+	h, err := c.handle(sshFxpOpen, path, uint32(sshFxfRead), uint32(0))
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for n < maxBytes {
+		size := maxBytes - n
+		if size > sftpReadSize {
+			size = sftpReadSize
+		}
+		respType, payload, err := c.request(sshFxpRead, h, uint64(n), uint32(size))
+		if err != nil {
+			return n, err
+		}
+		if respType == sshFxpStatus && statusError(payload) == io.EOF {
+			break
+		}
+		if respType != sshFxpData {
+			return n, responseError(respType, payload)
+		}
+		data, _, err := readString(payload)
+		if err != nil {
+			return n, err
+		}
+		n += int64(len(data))
+	}
+
+	return n, c.closeHandle(h)
+}
+
+func (c *sftpConn) close() {
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.client.Close()
+}
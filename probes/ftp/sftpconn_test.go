@@ -0,0 +1,312 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	configpb "github.com/cloudprober/cloudprober/probes/ftp/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/proto"
+)
+
+func newSigner(t *testing.T) (*ecdsa.PrivateKey, ssh.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, signer
+}
+
+// startSFTPServer starts an SSH server with a minimal SFTP subsystem, serving
+// testFiles. It accepts user:pass, and the given client key. It returns the
+// server's address and host key.
+func startSFTPServer(t *testing.T, clientKey ssh.PublicKey) (endpoint.Endpoint, ssh.PublicKey) {
+	t.Helper()
+
+	_, hostSigner := newSigner(t)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "user" && string(pass) == "pass" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if c.User() == "user" && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}, hostSigner.PublicKey()
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer ch.Close()
+			for req := range reqs {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					serveSFTP(ch)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// nameFields returns the fields of the NAME response for testFiles, after the
+// request id: count, and filename, longname and attributes (none) for each
+// file.
+func nameFields() []interface{} {
+	fields := []interface{}{uint32(len(testFiles))}
+	for _, line := range strings.Split(strings.TrimSpace(testListing()), "\r\n") {
+		f := strings.Fields(line)
+		fields = append(fields, f[len(f)-1], line, uint32(0))
+	}
+	return fields
+}
+
+// serveSFTP implements the SFTP requests used by the probe, for testFiles in
+// the login directory.
+func serveSFTP(rw io.ReadWriter) {
+	reply := func(typ byte, fields ...interface{}) {
+		b, _ := marshalPacket(typ, fields...)
+		rw.Write(b)
+	}
+	status := func(id, code uint32) {
+		reply(sshFxpStatus, id, code, "", "")
+	}
+	const sshFxNoSuchFile = 2
+
+	dirRead := make(map[string]bool)
+	for {
+		typ, payload, err := readPacket(rw)
+		if err != nil {
+			return
+		}
+		if typ == sshFxpInit {
+			reply(sshFxpVersion, uint32(sftpVersion))
+			continue
+		}
+		id := binary.BigEndian.Uint32(payload)
+		arg, rest, _ := readString(payload[4:])
+
+		switch typ {
+		case sshFxpOpendir:
+			if string(arg) != "." {
+				status(id, sshFxNoSuchFile)
+				continue
+			}
+			reply(sshFxpHandle, id, "dir")
+		case sshFxpReaddir:
+			if dirRead[string(arg)] {
+				status(id, sshFxEOF)
+				continue
+			}
+			dirRead[string(arg)] = true
+			reply(sshFxpName, append([]interface{}{id}, nameFields()...)...)
+		case sshFxpOpen:
+			if _, ok := testFiles[string(arg)]; !ok {
+				status(id, sshFxNoSuchFile)
+				continue
+			}
+			reply(sshFxpHandle, id, "file:"+string(arg))
+		case sshFxpRead:
+			content := testFiles[strings.TrimPrefix(string(arg), "file:")]
+			offset := binary.BigEndian.Uint64(rest)
+			length := uint64(binary.BigEndian.Uint32(rest[8:]))
+			if offset >= uint64(len(content)) {
+				status(id, sshFxEOF)
+				continue
+			}
+			if offset+length > uint64(len(content)) {
+				length = uint64(len(content)) - offset
+			}
+			reply(sshFxpData, id, content[offset:offset+length])
+		case sshFxpClose:
+			status(id, sshFxOK)
+		default:
+			status(id, 8) // SSH_FX_OP_UNSUPPORTED
+		}
+	}
+}
+
+// writeKeyFile writes the private key to a PEM file and returns its path.
+func writeKeyFile(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(t.TempDir(), "id_ecdsa")
+	if err := ioutil.WriteFile(fname, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func TestSFTPProbe(t *testing.T) {
+	clientKey, clientSigner := newSigner(t)
+	target, hostKey := startSFTPServer(t, clientSigner.PublicKey())
+	_, otherSigner := newSigner(t)
+
+	conf := func(operation configpb.ProbeConf_Operation, path string) *configpb.ProbeConf {
+		return &configpb.ProbeConf{
+			Protocol:  configpb.ProbeConf_SFTP.Enum(),
+			Username:  proto.String("user"),
+			Password:  proto.String("pass"),
+			HostKey:   proto.String(string(ssh.MarshalAuthorizedKey(hostKey))),
+			Operation: operation.Enum(),
+			Path:      proto.String(path),
+		}
+	}
+
+	wrongPassword := conf(configpb.ProbeConf_NONE, ".")
+	wrongPassword.Password = proto.String("wrong")
+
+	wrongHostKey := conf(configpb.ProbeConf_NONE, ".")
+	wrongHostKey.HostKey = proto.String(string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey())))
+
+	noHostKey := conf(configpb.ProbeConf_NONE, ".")
+	noHostKey.HostKey = nil
+
+	keyAuth := conf(configpb.ProbeConf_NONE, ".")
+	keyAuth.Password = nil
+	keyAuth.PrivateKeyFile = proto.String(writeKeyFile(t, clientKey))
+
+	truncated := conf(configpb.ProbeConf_RETRIEVE, "large.bin")
+	truncated.MaxTransferBytes = proto.Int64(1000)
+
+	// Listing size is the NAME response's payload size, after the request id.
+	namePacket, _ := marshalPacket(sshFxpName, nameFields()...)
+	listingSize := int64(len(namePacket) - 5)
+
+	for _, test := range []probeTest{
+		{
+			desc: "login",
+			conf: conf(configpb.ProbeConf_NONE, "."),
+		},
+		{
+			desc: "login_key",
+			conf: keyAuth,
+		},
+		{
+			desc: "login_no_host_key",
+			conf: noHostKey,
+		},
+		{
+			desc:       "login_failure",
+			conf:       wrongPassword,
+			wantReason: reasonLogin,
+		},
+		{
+			desc:       "host_key_mismatch",
+			conf:       wrongHostKey,
+			wantReason: reasonLogin,
+		},
+		{
+			desc:      "list",
+			conf:      conf(configpb.ProbeConf_LIST, "."),
+			wantBytes: listingSize,
+		},
+		{
+			desc:      "retrieve",
+			conf:      conf(configpb.ProbeConf_RETRIEVE, "hello.txt"),
+			wantBytes: 11,
+		},
+		{
+			desc:      "retrieve_large",
+			conf:      conf(configpb.ProbeConf_RETRIEVE, "large.bin"),
+			wantBytes: 100000,
+		},
+		{
+			desc:      "retrieve_truncated",
+			conf:      truncated,
+			wantBytes: 1000,
+		},
+		{
+			desc:       "retrieve_missing",
+			conf:       conf(configpb.ProbeConf_RETRIEVE, "missing.txt"),
+			wantReason: reasonTransfer,
+		},
+		{
+			desc:       "list_missing",
+			conf:       conf(configpb.ProbeConf_LIST, "missing"),
+			wantReason: reasonTransfer,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			runProbeTest(t, target, test)
+		})
+	}
+}
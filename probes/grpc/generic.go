@@ -0,0 +1,185 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudprober/cloudprober/common/file"
+	"google.golang.org/grpc"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// genericMethod is a unary method called in the GENERIC mode, along with the
+// serialized request message.
+type genericMethod struct {
+	desc protoreflect.MethodDescriptor
+	req  []byte
+}
+
+// parseMethodName splits a full method name, e.g. "/pkg.Service/Method",
+// into the service and method names.
+func parseMethodName(name string) (string, string, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid method name (%q), should be a full method name, e.g. /pkg.Service/Method", name)
+	}
+	return parts[1], parts[2], nil
+}
+
+// newGenericMethod looks up the configured method in the given files, and
+// builds its request message from the configured JSON.
+func (p *Probe) newGenericMethod(files *protoregistry.Files) (*genericMethod, error) {
+	service, method, err := parseMethodName(p.c.GetGenericMethod())
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %v", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in the service %s", method, service)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is not a unary method", p.c.GetGenericMethod())
+	}
+
+	req := dynamicpb.NewMessage(md.Input())
+	if err := protojson.Unmarshal([]byte(p.c.GetGenericRequest()), req); err != nil {
+		return nil, fmt.Errorf("invalid generic_request for %s: %v", md.Input().FullName(), err)
+	}
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return &genericMethod{desc: md, req: b}, nil
+}
+
+// genericMethodFromFile resolves the method using the descriptor set file.
+func (p *Probe) genericMethodFromFile() (*genericMethod, error) {
+	b, err := file.ReadFile(p.c.GetDescriptorSetFile())
+	if err != nil {
+		return nil, fmt.Errorf("error reading descriptor_set_file (%s): %v", p.c.GetDescriptorSetFile(), err)
+	}
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, fds); err != nil {
+		return nil, fmt.Errorf("error parsing descriptor_set_file (%s): %v", p.c.GetDescriptorSetFile(), err)
+	}
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor_set_file (%s): %v", p.c.GetDescriptorSetFile(), err)
+	}
+	return p.newGenericMethod(files)
+}
+
+// genericMethodFromReflection resolves the method using the server
+// reflection service on the connection. It fetches the file that defines the
+// method's service, and all its dependencies.
+func (p *Probe) genericMethodFromReflection(ctx context.Context, conn *grpc.ClientConn) (*genericMethod, error) {
+	service, _, err := parseMethodName(p.c.GetGenericMethod())
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection error: %v", err)
+	}
+	defer stream.CloseSend()
+
+	// Files received and files requested, by name.
+	received, requested := make(map[string]bool), make(map[string]bool)
+	var fds descriptorpb.FileDescriptorSet
+
+	reqs := []*rpb.ServerReflectionRequest{
+		{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service}},
+	}
+	for len(reqs) > 0 {
+		req := reqs[0]
+		reqs = reqs[1:]
+
+		if err := stream.Send(req); err != nil {
+			return nil, fmt.Errorf("server reflection error: %v", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("server reflection error: %v", err)
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return nil, fmt.Errorf("server reflection error for %v: %s", req.GetMessageRequest(), errResp.GetErrorMessage())
+		}
+
+		// Servers may send the dependencies along with the requested file.
+		// We ask for the missing ones by their names.
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fdp := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fdp); err != nil {
+				return nil, fmt.Errorf("invalid file descriptor from the server: %v", err)
+			}
+			if received[fdp.GetName()] {
+				continue
+			}
+			received[fdp.GetName()] = true
+			fds.File = append(fds.File, fdp)
+		}
+		for _, fdp := range fds.File {
+			for _, dep := range fdp.GetDependency() {
+				if received[dep] || requested[dep] {
+					continue
+				}
+				requested[dep] = true
+				reqs = append(reqs, &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				})
+			}
+		}
+	}
+
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptors from the server: %v", err)
+	}
+	return p.newGenericMethod(files)
+}
+
+// genericCall calls the method, and returns the response converted to JSON.
+func (p *Probe) genericCall(ctx context.Context, conn *grpc.ClientConn, gm *genericMethod, opts []grpc.CallOption) ([]byte, error) {
+	var respBytes []byte
+	if err := conn.Invoke(ctx, p.c.GetGenericMethod(), &gm.req, &respBytes, append(opts, grpc.ForceCodec(rawCodec{}))...); err != nil {
+		return nil, err
+	}
+
+	resp := dynamicpb.NewMessage(gm.desc.Output())
+	if err := proto.Unmarshal(respBytes, resp); err != nil {
+		return nil, fmt.Errorf("error parsing %s response: %v", gm.desc.Output().FullName(), err)
+	}
+	return protojson.Marshal(resp)
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/grpc/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	spb "github.com/cloudprober/cloudprober/servers/grpc/proto"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/validators"
+	validatorpb "github.com/cloudprober/cloudprober/validators/proto"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testGenericMethod = "/cloudprober.servers.grpc.Prober/BlobWrite"
+
+// startGenericServer starts an in-process cloudprober gRPC server, with
+// server reflection if enabled, and returns its address.
+func startGenericServer(t *testing.T, withReflection bool) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	srv := grpc.NewServer()
+	spb.RegisterProberServer(srv, &Server{msg: make([]byte, 1024)})
+	if withReflection {
+		reflection.Register(srv)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return ln.Addr().String()
+}
+
+// writeDescriptorSet writes the cloudprober gRPC server's descriptors to a
+// file and returns its path.
+func writeDescriptorSet(t *testing.T) string {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(spb.File_github_com_cloudprober_cloudprober_servers_grpc_proto_grpcservice_proto),
+		},
+	}
+	b, err := protov2.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(t.TempDir(), "grpcservice.protoset")
+	if err := ioutil.WriteFile(fname, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func genericProbe(t *testing.T, addr string, conf *configpb.ProbeConf, vs []*validators.Validator) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	if err := p.Init("grpc-generic", &options.Options{
+		Targets:     targets.StaticTargets(addr),
+		Interval:    100 * time.Millisecond,
+		Timeout:     time.Second,
+		ProbeConf:   conf,
+		Validators:  vs,
+		LatencyUnit: time.Millisecond,
+		LogMetrics:  func(*metrics.EventMetrics) {},
+	}); err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	return p
+}
+
+func genericConf(method, req string) *configpb.ProbeConf {
+	return &configpb.ProbeConf{
+		Mode:           configpb.ProbeConf_GENERIC.Enum(),
+		GenericMethod:  proto.String(method),
+		GenericRequest: proto.String(req),
+	}
+}
+
+func TestGenericCall(t *testing.T) {
+	for _, test := range []struct {
+		desc           string
+		method         string
+		req            string
+		reflection     bool
+		descriptorSet  bool
+		wantSize       int
+		wantResolveErr bool
+	}{
+		{
+			desc:       "reflection",
+			method:     testGenericMethod,
+			req:        `{"blob": "aGVsbG8="}`, // "hello"
+			reflection: true,
+			wantSize:   5,
+		},
+		{
+			desc:          "descriptor_set",
+			method:        testGenericMethod,
+			req:           `{"blob": "aGVsbG8="}`,
+			descriptorSet: true,
+			wantSize:      5,
+		},
+		{
+			desc:       "empty_request",
+			method:     testGenericMethod,
+			req:        "{}",
+			reflection: true,
+		},
+		{
+			desc:           "no_reflection",
+			method:         testGenericMethod,
+			req:            "{}",
+			wantResolveErr: true,
+		},
+		{
+			desc:           "unknown_method",
+			method:         "/cloudprober.servers.grpc.Prober/Unknown",
+			req:            "{}",
+			reflection:     true,
+			wantResolveErr: true,
+		},
+		{
+			desc:           "invalid_request",
+			method:         testGenericMethod,
+			req:            `{"unknown_field": 1}`,
+			reflection:     true,
+			wantResolveErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			addr := startGenericServer(t, test.reflection)
+			conf := genericConf(test.method, test.req)
+			if test.descriptorSet {
+				conf.DescriptorSetFile = proto.String(writeDescriptorSet(t))
+			}
+			p := genericProbe(t, addr, conf, nil)
+
+			conn, err := grpc.Dial(addr, p.dialOpts...)
+			if err != nil {
+				t.Fatalf("Error connecting to %s: %v", addr, err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			gm := p.genericMethod
+			if gm == nil {
+				gm, err = p.genericMethodFromReflection(ctx, conn)
+			}
+			if (err != nil) != test.wantResolveErr {
+				t.Fatalf("Got error resolving the method: %v, want error: %v", err, test.wantResolveErr)
+			}
+			if err != nil {
+				return
+			}
+
+			respJSON, err := p.genericCall(ctx, conn, gm, nil)
+			if err != nil {
+				t.Fatalf("Error calling %s: %v", test.method, err)
+			}
+			var resp struct {
+				Size int `json:"size"`
+			}
+			if err := json.Unmarshal(respJSON, &resp); err != nil {
+				t.Fatalf("Error parsing the response (%s): %v", respJSON, err)
+			}
+			if resp.Size != test.wantSize {
+				t.Errorf("Got size=%d, want=%d, response: %s", resp.Size, test.wantSize, respJSON)
+			}
+		})
+	}
+}
+
+func TestGenericProbeLoop(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		regex       string
+		wantSuccess bool
+	}{
+		{
+			desc:        "validation_success",
+			regex:       `"size":\s*5`,
+			wantSuccess: true,
+		},
+		{
+			desc:  "validation_failure",
+			regex: `"size":\s*6`,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			vs, err := validators.Init([]*validatorpb.Validator{
+				{
+					Name: proto.String("size"),
+					Type: &validatorpb.Validator_Regex{Regex: test.regex},
+				},
+			}, &logger.Logger{})
+			if err != nil {
+				t.Fatalf("Error initializing validators: %v", err)
+			}
+
+			addr := startGenericServer(t, true)
+			p := genericProbe(t, addr, genericConf(testGenericMethod, `{"blob": "aGVsbG8="}`), vs)
+
+			result := p.newResult(addr)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				p.oneTargetLoop(ctx, addr, 0, result)
+				close(done)
+			}()
+			time.Sleep(350 * time.Millisecond)
+			cancel()
+			<-done
+
+			result.Lock()
+			defer result.Unlock()
+
+			total, success := result.total.Int64(), result.success.Int64()
+			failures := result.validationFailure.GetKey("size").Int64()
+			if total < 2 {
+				t.Fatalf("Got total=%d, want >= 2", total)
+			}
+			if test.wantSuccess {
+				if success != total || failures != 0 {
+					t.Errorf("Got total=%d, success=%d, validation failures=%d, want all successful", total, success, failures)
+				}
+				return
+			}
+			if success != 0 || failures != total {
+				t.Errorf("Got total=%d, success=%d, validation failures=%d, want all failing validation", total, success, failures)
+			}
+		})
+	}
+}
+
+func TestGenericConfigErrors(t *testing.T) {
+	descriptorSet := writeDescriptorSet(t)
+
+	for _, test := range []struct {
+		desc string
+		conf *configpb.ProbeConf
+	}{
+		{desc: "no_method", conf: &configpb.ProbeConf{Mode: configpb.ProbeConf_GENERIC.Enum()}},
+		{desc: "invalid_method", conf: genericConf("cloudprober.servers.grpc.Prober/BlobWrite", "{}")},
+		{
+			desc: "missing_descriptor_set",
+			conf: &configpb.ProbeConf{
+				Mode:              configpb.ProbeConf_GENERIC.Enum(),
+				GenericMethod:     proto.String(testGenericMethod),
+				DescriptorSetFile: proto.String(filepath.Join(t.TempDir(), "missing")),
+			},
+		},
+		{
+			desc: "unknown_service",
+			conf: &configpb.ProbeConf{
+				Mode:              configpb.ProbeConf_GENERIC.Enum(),
+				GenericMethod:     proto.String("/pkg.Service/Method"),
+				DescriptorSetFile: proto.String(descriptorSet),
+			},
+		},
+		{
+			desc: "invalid_request",
+			conf: &configpb.ProbeConf{
+				Mode:              configpb.ProbeConf_GENERIC.Enum(),
+				GenericMethod:     proto.String(testGenericMethod),
+				GenericRequest:    proto.String("{"),
+				DescriptorSetFile: proto.String(descriptorSet),
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			if err := p.Init("grpc-generic", &options.Options{
+				Targets:   targets.StaticTargets("localhost:9313"),
+				ProbeConf: test.conf,
+			}); err == nil {
+				t.Errorf("Init(%v): expected error", test.conf)
+			}
+		})
+	}
+}
@@ -15,33 +15,39 @@
 /*
 Package grpc implements a gRPC probe.
 
-This probes a cloudprober gRPC server and reports success rate, latency, and
-validation failures.
+This probes a cloudprober gRPC server, or calls an arbitrary method in the
+GENERIC mode, and reports success rate, latency, and validation failures.
 */
 package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/common/oauth"
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	configpb "github.com/cloudprober/cloudprober/probes/grpc/proto"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/sysvars"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/cloudprober/cloudprober/validators"
 
 	pb "github.com/cloudprober/cloudprober/servers/grpc/proto"
 	spb "github.com/cloudprober/cloudprober/servers/grpc/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/alts"
 	"google.golang.org/grpc/credentials/local"
 	grpcoauth "google.golang.org/grpc/credentials/oauth"
@@ -77,6 +83,15 @@ type Probe struct {
 
 	// Results by target.
 	results map[string]*probeRunResult
+
+	// Method to call in the GENERIC mode, if resolved using the descriptor
+	// set file. Otherwise, it's resolved using server reflection, for every
+	// connection.
+	genericMethod *genericMethod
+
+	// Slots for the in-flight probe requests, if max_concurrent_probes is
+	// configured.
+	slots chan struct{}
 }
 
 // probeRunResult captures the metrics for a single target. Multiple threads
@@ -89,6 +104,20 @@ type probeRunResult struct {
 	success       metrics.Int
 	latency       metrics.Value
 	connectErrors metrics.Int
+
+	// Server-streaming mode only.
+	firstMsgLatency     metrics.Value
+	interMsgLatency     metrics.Value
+	streamMessages      metrics.Int
+	streamCompletions   metrics.Int
+	messagesBeforeError metrics.Int
+
+	// Validation failures, if validators are configured (GENERIC mode only).
+	validationFailure *metrics.Map
+
+	// Probe cycles skipped for want of a free slot, exported only if
+	// max_concurrent_probes is configured.
+	skipped metrics.Int
 }
 
 func (p *Probe) setupDialOpts() error {
@@ -109,7 +138,19 @@ func (p *Probe) setupDialOpts() error {
 		p.dialOpts = append(p.dialOpts, grpc.WithTransportCredentials(alts.NewClientCreds(altsOpts)))
 	}
 
-	if oauthCfg == nil && altsCfg == nil {
+	tlsCfg := p.c.GetTlsConfig()
+	if tlsCfg != nil {
+		if altsCfg != nil {
+			return errors.New("only one of alts_config and tls_config can be set")
+		}
+		tlsConfig := &tls.Config{}
+		if err := tlsconfig.UpdateTLSConfig(tlsConfig, tlsCfg, false); err != nil {
+			return err
+		}
+		p.dialOpts = append(p.dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if oauthCfg == nil && altsCfg == nil && tlsCfg == nil {
 		p.dialOpts = append(p.dialOpts, grpc.WithTransportCredentials(local.NewCredentials()))
 	}
 	p.dialOpts = append(p.dialOpts, grpc.WithDefaultServiceConfig(loadBalancingPolicy))
@@ -130,6 +171,32 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 	}
 	p.targets = p.opts.Targets.ListEndpoints()
 
+	if p.c.GetMode() == configpb.ProbeConf_SERVER_STREAM {
+		if !strings.HasPrefix(p.c.GetStreamMethod(), "/") {
+			return fmt.Errorf("stream_method (%q) should be a full method name, e.g. /pkg.Service/Method", p.c.GetStreamMethod())
+		}
+		if p.c.GetStreamMessageCount() <= 0 {
+			return fmt.Errorf("stream_message_count (%d) should be positive", p.c.GetStreamMessageCount())
+		}
+	}
+
+	if p.c.GetMode() == configpb.ProbeConf_GENERIC {
+		if _, _, err := parseMethodName(p.c.GetGenericMethod()); err != nil {
+			return fmt.Errorf("generic_method: %v", err)
+		}
+		if p.c.GetDescriptorSetFile() != "" {
+			gm, err := p.genericMethodFromFile()
+			if err != nil {
+				return err
+			}
+			p.genericMethod = gm
+		}
+	}
+
+	if p.opts.MaxConcurrentProbes > 0 {
+		p.slots = make(chan struct{}, p.opts.MaxConcurrentProbes)
+	}
+
 	p.cancelFuncs = make(map[string]context.CancelFunc)
 	p.src = sysvars.Vars()["hostname"]
 	if err := p.setupDialOpts(); err != nil {
@@ -245,10 +312,13 @@ func (p *Probe) oneTargetLoop(ctx context.Context, tgt string, index int, result
 	timeout := p.opts.Timeout
 	method := p.c.GetMethod()
 
+	// Method for the GENERIC mode, resolved on the first call if needed.
+	gm := p.genericMethod
+
 	msgSize := p.c.GetBlobSize()
 	msg := make([]byte, msgSize)
 	probeutils.PatternPayload(msg, []byte(msgPattern))
-	ticker := time.NewTicker(p.opts.Interval)
+	ticker := p.opts.NewTicker()
 	for {
 		select {
 		case <-ctx.Done():
@@ -258,6 +328,19 @@ func (p *Probe) oneTargetLoop(ctx context.Context, tgt string, index int, result
 		case <-ticker.C:
 		}
 
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		if !p.acquireSlot(ctx) {
+			p.l.Warningf("ProbeId(%s): no free concurrency slot within the probe interval, skipping", msgPattern)
+			result.Lock()
+			result.skipped.Inc()
+			result.Unlock()
+			continue
+		}
+
 		reqCtx, cancelFunc := context.WithTimeout(ctx, timeout)
 		var success int64
 		var delta time.Duration
@@ -268,18 +351,30 @@ func (p *Probe) oneTargetLoop(ctx context.Context, tgt string, index int, result
 			grpc.WaitForReady(true),
 			grpc.Peer(&peer),
 		}
-		switch method {
-		case configpb.ProbeConf_ECHO:
+		var msgGaps []time.Duration
+		var respJSON []byte
+		streamMode := p.c.GetMode() == configpb.ProbeConf_SERVER_STREAM
+		switch {
+		case streamMode:
+			msgGaps, err = p.streamCall(reqCtx, conn, opts)
+		case p.c.GetMode() == configpb.ProbeConf_GENERIC:
+			if gm == nil {
+				gm, err = p.genericMethodFromReflection(reqCtx, conn)
+			}
+			if err == nil {
+				respJSON, err = p.genericCall(reqCtx, conn, gm, opts)
+			}
+		case method == configpb.ProbeConf_ECHO:
 			req := &pb.EchoMessage{
 				Blob: []byte(msg),
 			}
 			_, err = client.Echo(reqCtx, req, opts...)
-		case configpb.ProbeConf_READ:
+		case method == configpb.ProbeConf_READ:
 			req := &pb.BlobReadRequest{
 				Size: proto.Int32(msgSize),
 			}
 			_, err = client.BlobRead(reqCtx, req, opts...)
-		case configpb.ProbeConf_WRITE:
+		case method == configpb.ProbeConf_WRITE:
 			req := &pb.BlobWriteRequest{
 				Blob: []byte(msg),
 			}
@@ -288,6 +383,16 @@ func (p *Probe) oneTargetLoop(ctx context.Context, tgt string, index int, result
 			p.l.Criticalf("Method %v not implemented", method)
 		}
 		cancelFunc()
+		p.releaseSlot()
+		runstats.RecordRun(p.name, start)
+		if err == nil && respJSON != nil && p.opts.Validators != nil {
+			result.Lock()
+			failedValidations := validators.RunValidators(p.opts.Validators, &validators.Input{ResponseBody: respJSON}, result.validationFailure, p.l)
+			result.Unlock()
+			if len(failedValidations) > 0 {
+				err = fmt.Errorf("failed validations: %s", strings.Join(failedValidations, ","))
+			}
+		}
 		if err != nil {
 			peerAddr := "unknown"
 			if peer.Addr != nil {
@@ -298,26 +403,67 @@ func (p *Probe) oneTargetLoop(ctx context.Context, tgt string, index int, result
 			success = 1
 			delta = time.Since(start)
 		}
-		// TODO(ls692): add validators for probe result.
 		result.Lock()
 		result.total.Inc()
 		result.success.AddInt64(success)
 		result.latency.AddFloat64(delta.Seconds() / p.opts.LatencyUnit.Seconds())
+		if streamMode {
+			result.recordStream(msgGaps, err, p.opts.LatencyUnit)
+		}
 		result.Unlock()
 	}
 }
 
-func (p *Probe) newResult(tgt string) *probeRunResult {
-	var latencyValue metrics.Value
+// acquireSlot waits for a free slot to send a probe request, if
+// max_concurrent_probes is configured. As the request has to finish within
+// the interval, it waits for at most the interval minus the timeout, and
+// returns false if it doesn't get a slot in time.
+func (p *Probe) acquireSlot(ctx context.Context) bool {
+	if p.slots == nil {
+		return true
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(p.opts.Interval - p.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (p *Probe) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
 	if p.opts.LatencyDist != nil {
-		latencyValue = p.opts.LatencyDist.Clone()
-	} else {
-		latencyValue = metrics.NewFloat(0)
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(tgt string) *probeRunResult {
+	result := &probeRunResult{
+		target:          tgt,
+		latency:         p.newLatencyValue(),
+		firstMsgLatency: p.newLatencyValue(),
+		interMsgLatency: p.newLatencyValue(),
 	}
-	return &probeRunResult{
-		target:  tgt,
-		latency: latencyValue,
+	if p.c.GetMode() == configpb.ProbeConf_GENERIC && p.opts.Validators != nil {
+		result.validationFailure = validators.ValidationFailureMap(p.opts.Validators)
 	}
+	return result
 }
 
 // Start starts and runs the probe indefinitely.
@@ -340,6 +486,9 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 		default:
 		}
 
+		start, end := runstats.LastRun(p.name)
+		ts = p.opts.MetricTimestamp(ts, start, end)
+
 		// Output results.
 		for targetName, result := range p.results {
 			result.Lock()
@@ -351,6 +500,15 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 				AddLabel("ptype", "grpc").
 				AddLabel("probe", p.name).
 				AddLabel("dst", targetName)
+			if p.c.GetMode() == configpb.ProbeConf_SERVER_STREAM {
+				result.addStreamMetrics(em)
+			}
+			if result.validationFailure != nil {
+				em.AddMetric("validation_failure", result.validationFailure.Clone())
+			}
+			if p.slots != nil {
+				em.AddMetric("skipped_targets", result.skipped.Clone())
+			}
 			result.Unlock()
 			em.LatencyUnit = p.opts.LatencyUnit
 			for _, al := range p.opts.AdditionalLabels {
@@ -242,6 +242,45 @@ func TestConnectFailures(t *testing.T) {
 	wg.Wait()
 }
 
+func TestMaxConcurrentProbes(t *testing.T) {
+	cfg, err := probeCfg("localhost:9", "", 1000, 1)
+	if err != nil {
+		t.Fatalf("Error unmarshalling config: %v", err)
+	}
+
+	p := &Probe{}
+	if err := p.Init("grpc-maxconcurrent", &options.Options{
+		Targets:             targets.StaticTargets("localhost:9"),
+		Interval:            300 * time.Millisecond,
+		Timeout:             100 * time.Millisecond,
+		MaxConcurrentProbes: 1,
+		ProbeConf:           cfg.GetGrpcProbe(),
+		Logger:              &logger.Logger{},
+	}); err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	ctx := context.Background()
+	if !p.acquireSlot(ctx) {
+		t.Fatal("Didn't get a free slot")
+	}
+
+	// Second request waits for interval - timeout, and is skipped.
+	start := time.Now()
+	if p.acquireSlot(ctx) {
+		t.Fatal("Got a slot, while the only slot is in use")
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Waited for a slot for %v, want around 200ms", elapsed)
+	}
+
+	// Slot freed while waiting.
+	time.AfterFunc(50*time.Millisecond, p.releaseSlot)
+	if !p.acquireSlot(ctx) {
+		t.Error("Didn't get the slot freed while waiting")
+	}
+}
+
 func TestProbeTimeouts(t *testing.T) {
 	addr, err := globalGRPCServer()
 	if err != nil {
@@ -8,6 +8,7 @@ package proto
 
 import (
 	proto "github.com/cloudprober/cloudprober/common/oauth/proto"
+	proto1 "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -80,6 +81,84 @@ func (ProbeConf_MethodType) EnumDescriptor() ([]byte, []int) {
 	return file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawDescGZIP(), []int{0, 0}
 }
 
+type ProbeConf_Mode int32
+
+const (
+	// Unary calls to the cloudprober gRPC server, using the method above.
+	ProbeConf_UNARY ProbeConf_Mode = 0
+	// Server-streaming calls to stream_method. Probe sends stream_request and
+	// reads stream_message_count messages from the stream, exporting the
+	// following metrics in addition to the regular ones (latency measures
+	// the time to read all the messages):
+	//   first_message_latency: time to the first message.
+	//   inter_message_latency: time between the subsequent messages.
+	//   stream_messages: number of messages received.
+	//   stream_completions: streams that delivered all the messages.
+	//   messages_before_error: messages received on the streams that failed
+	//                          midway, or ended prematurely.
+	// Latency metrics use the probe's latency unit and distribution. Note
+	// that the streams are closed after stream_message_count messages.
+	ProbeConf_SERVER_STREAM ProbeConf_Mode = 1
+	// Unary calls to any method, generic_method, with the request message
+	// built from generic_request. Method's service and message definitions
+	// are fetched from the server through gRPC server reflection, unless
+	// descriptor_set_file is configured. Response, converted to JSON, is
+	// checked by the probe's validators, if any; validation failures are
+	// exported as the "validation_failure" metric.
+	ProbeConf_GENERIC ProbeConf_Mode = 2
+)
+
+// Enum value maps for ProbeConf_Mode.
+var (
+	ProbeConf_Mode_name = map[int32]string{
+		0: "UNARY",
+		1: "SERVER_STREAM",
+		2: "GENERIC",
+	}
+	ProbeConf_Mode_value = map[string]int32{
+		"UNARY":         0,
+		"SERVER_STREAM": 1,
+		"GENERIC":       2,
+	}
+)
+
+func (x ProbeConf_Mode) Enum() *ProbeConf_Mode {
+	p := new(ProbeConf_Mode)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeConf_Mode) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeConf_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Mode) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Mode(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Mode.Descriptor instead.
+func (ProbeConf_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
 type ProbeConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -102,14 +181,42 @@ type ProbeConf struct {
 	// Example URI scheme: "google-c2p:///"
 	// See https://github.com/grpc/grpc/blob/master/doc/naming.md for more details
 	UriScheme *string `protobuf:"bytes,8,opt,name=uri_scheme,json=uriScheme" json:"uri_scheme,omitempty"`
+	// If tls_config is provided, gRPC client uses TLS for authentication and
+	// encryption. Client certificate, if configured, is reloaded when the
+	// certificate files change. It cannot be used along with alts_config.
+	TlsConfig *proto1.TLSConfig `protobuf:"bytes,9,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	Mode      *ProbeConf_Mode   `protobuf:"varint,10,opt,name=mode,enum=cloudprober.probes.grpc.ProbeConf_Mode,def=0" json:"mode,omitempty"`
+	// Full name of the server-streaming method, e.g. "/pkg.Service/Method".
+	StreamMethod *string `protobuf:"bytes,11,opt,name=stream_method,json=streamMethod" json:"stream_method,omitempty"`
+	// Request message to send to stream_method, serialized in the protobuf
+	// wire format. Default is an empty message.
+	StreamRequest []byte `protobuf:"bytes,12,opt,name=stream_request,json=streamRequest" json:"stream_request,omitempty"`
+	// Number of messages to read from the stream.
+	StreamMessageCount *int32 `protobuf:"varint,13,opt,name=stream_message_count,json=streamMessageCount,def=10" json:"stream_message_count,omitempty"`
+	// Full name of the unary method to call in the GENERIC mode, e.g.
+	// "/pkg.Service/Method".
+	GenericMethod *string `protobuf:"bytes,14,opt,name=generic_method,json=genericMethod" json:"generic_method,omitempty"`
+	// Request message to send to generic_method, in the protobuf JSON format,
+	// e.g. '{"name": "cloudprober"}'. Default is an empty message.
+	GenericRequest *string `protobuf:"bytes,15,opt,name=generic_request,json=genericRequest,def={}" json:"generic_request,omitempty"`
+	// File containing the serialized FileDescriptorSet with generic_method's
+	// service and message definitions, along with their dependencies, e.g.
+	// generated by:
+	//   protoc --include_imports --descriptor_set_out=<file> <proto files>
+	// If not set, definitions are fetched using server reflection, once for
+	// every connection.
+	DescriptorSetFile *string `protobuf:"bytes,16,opt,name=descriptor_set_file,json=descriptorSetFile" json:"descriptor_set_file,omitempty"`
 }
 
 // Default values for ProbeConf fields.
 const (
-	Default_ProbeConf_Method    = ProbeConf_ECHO
-	Default_ProbeConf_BlobSize  = int32(1024)
-	Default_ProbeConf_NumConns  = int32(2)
-	Default_ProbeConf_KeepAlive = bool(true)
+	Default_ProbeConf_Method             = ProbeConf_ECHO
+	Default_ProbeConf_BlobSize           = int32(1024)
+	Default_ProbeConf_NumConns           = int32(2)
+	Default_ProbeConf_KeepAlive          = bool(true)
+	Default_ProbeConf_Mode               = ProbeConf_UNARY
+	Default_ProbeConf_StreamMessageCount = int32(10)
+	Default_ProbeConf_GenericRequest     = string("{}")
 )
 
 func (x *ProbeConf) Reset() {
@@ -200,6 +307,62 @@ func (x *ProbeConf) GetUriScheme() string {
 	return ""
 }
 
+func (x *ProbeConf) GetTlsConfig() *proto1.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetMode() ProbeConf_Mode {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return Default_ProbeConf_Mode
+}
+
+func (x *ProbeConf) GetStreamMethod() string {
+	if x != nil && x.StreamMethod != nil {
+		return *x.StreamMethod
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetStreamRequest() []byte {
+	if x != nil {
+		return x.StreamRequest
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetStreamMessageCount() int32 {
+	if x != nil && x.StreamMessageCount != nil {
+		return *x.StreamMessageCount
+	}
+	return Default_ProbeConf_StreamMessageCount
+}
+
+func (x *ProbeConf) GetGenericMethod() string {
+	if x != nil && x.GenericMethod != nil {
+		return *x.GenericMethod
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetGenericRequest() string {
+	if x != nil && x.GenericRequest != nil {
+		return *x.GenericRequest
+	}
+	return Default_ProbeConf_GenericRequest
+}
+
+func (x *ProbeConf) GetDescriptorSetFile() string {
+	if x != nil && x.DescriptorSetFile != nil {
+		return *x.DescriptorSetFile
+	}
+	return ""
+}
+
 // ALTS is a gRPC security method supported by some Google services.
 // If enabled, peers, with the help of a handshaker service (e.g. metadata
 // server of GCE instances), use credentials attached to the service accounts
@@ -278,47 +441,79 @@ var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawDe
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
 	0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2f, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x22, 0xcf, 0x04, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x3c,
-	0x0a, 0x0c, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52,
-	0x0b, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x4e, 0x0a, 0x0b,
-	0x61, 0x6c, 0x74, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x50, 0x72, 0x6f, 0x62,
-	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x41, 0x4c, 0x54, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x52, 0x0a, 0x61, 0x6c, 0x74, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x4b, 0x0a, 0x06,
-	0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2d, 0x2e, 0x63,
-	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x73, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66,
-	0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x54, 0x79, 0x70, 0x65, 0x3a, 0x04, 0x45, 0x43, 0x48,
-	0x4f, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x21, 0x0a, 0x09, 0x62, 0x6c, 0x6f,
-	0x62, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x04, 0x31, 0x30,
-	0x32, 0x34, 0x52, 0x08, 0x62, 0x6c, 0x6f, 0x62, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1e, 0x0a, 0x09,
-	0x6e, 0x75, 0x6d, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x3a,
-	0x01, 0x32, 0x52, 0x08, 0x6e, 0x75, 0x6d, 0x43, 0x6f, 0x6e, 0x6e, 0x73, 0x12, 0x23, 0x0a, 0x0a,
-	0x6b, 0x65, 0x65, 0x70, 0x5f, 0x61, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08,
-	0x3a, 0x04, 0x74, 0x72, 0x75, 0x65, 0x52, 0x09, 0x6b, 0x65, 0x65, 0x70, 0x41, 0x6c, 0x69, 0x76,
-	0x65, 0x12, 0x30, 0x0a, 0x14, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x69, 0x6d,
-	0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x12, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d,
-	0x73, 0x65, 0x63, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x72, 0x69, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d,
-	0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x75, 0x72, 0x69, 0x53, 0x63, 0x68, 0x65,
-	0x6d, 0x65, 0x1a, 0x80, 0x01, 0x0a, 0x0a, 0x41, 0x4c, 0x54, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69,
-	0x67, 0x12, 0x34, 0x0a, 0x16, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x73, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x03, 0x28,
-	0x09, 0x52, 0x14, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3c, 0x0a, 0x1a, 0x68, 0x61, 0x6e, 0x64, 0x73,
-	0x68, 0x61, 0x6b, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x61, 0x64,
-	0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x18, 0x68, 0x61, 0x6e,
-	0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x41, 0x64,
-	0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x2b, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x45, 0x43, 0x48, 0x4f, 0x10, 0x01, 0x12, 0x08, 0x0a,
-	0x04, 0x52, 0x45, 0x41, 0x44, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x57, 0x52, 0x49, 0x54, 0x45,
-	0x10, 0x03, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f,
-	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f,
-	0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x46, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x8d, 0x08, 0x0a, 0x09, 0x50, 0x72, 0x6f,
+	0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x3c, 0x0a, 0x0c, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x5f,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6f, 0x61, 0x75, 0x74, 0x68,
+	0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x0b, 0x6f, 0x61, 0x75, 0x74, 0x68, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x12, 0x4e, 0x0a, 0x0b, 0x61, 0x6c, 0x74, 0x73, 0x5f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x41, 0x4c,
+	0x54, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x0a, 0x61, 0x6c, 0x74, 0x73, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x12, 0x4b, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x54,
+	0x79, 0x70, 0x65, 0x3a, 0x04, 0x45, 0x43, 0x48, 0x4f, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f,
+	0x64, 0x12, 0x21, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x62, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x3a, 0x04, 0x31, 0x30, 0x32, 0x34, 0x52, 0x08, 0x62, 0x6c, 0x6f, 0x62,
+	0x53, 0x69, 0x7a, 0x65, 0x12, 0x1e, 0x0a, 0x09, 0x6e, 0x75, 0x6d, 0x5f, 0x63, 0x6f, 0x6e, 0x6e,
+	0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x32, 0x52, 0x08, 0x6e, 0x75, 0x6d, 0x43,
+	0x6f, 0x6e, 0x6e, 0x73, 0x12, 0x23, 0x0a, 0x0a, 0x6b, 0x65, 0x65, 0x70, 0x5f, 0x61, 0x6c, 0x69,
+	0x76, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x04, 0x74, 0x72, 0x75, 0x65, 0x52, 0x09,
+	0x6b, 0x65, 0x65, 0x70, 0x41, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x30, 0x0a, 0x14, 0x63, 0x6f, 0x6e,
+	0x6e, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65,
+	0x63, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x1d, 0x0a, 0x0a, 0x75,
+	0x72, 0x69, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x75, 0x72, 0x69, 0x53, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x12, 0x3f, 0x0a, 0x0a, 0x74, 0x6c,
+	0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x42, 0x0a, 0x04, 0x6d,
+	0x6f, 0x64, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x4d, 0x6f,
+	0x64, 0x65, 0x3a, 0x05, 0x55, 0x4e, 0x41, 0x52, 0x59, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12,
+	0x23, 0x0a, 0x0d, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65,
+	0x74, 0x68, 0x6f, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x34, 0x0a, 0x14, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x02, 0x31, 0x30, 0x52, 0x12, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x25, 0x0a, 0x0e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x5f, 0x6d, 0x65, 0x74,
+	0x68, 0x6f, 0x64, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x67, 0x65, 0x6e, 0x65, 0x72,
+	0x69, 0x63, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x2b, 0x0a, 0x0f, 0x67, 0x65, 0x6e, 0x65,
+	0x72, 0x69, 0x63, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x09, 0x3a, 0x02, 0x7b, 0x7d, 0x52, 0x0e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x6f, 0x72, 0x5f, 0x73, 0x65, 0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x10, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x11, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x53, 0x65,
+	0x74, 0x46, 0x69, 0x6c, 0x65, 0x1a, 0x80, 0x01, 0x0a, 0x0a, 0x41, 0x4c, 0x54, 0x53, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x12, 0x34, 0x0a, 0x16, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x14, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3c, 0x0a, 0x1a, 0x68, 0x61,
+	0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x18,
+	0x68, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x2b, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x45, 0x43, 0x48, 0x4f, 0x10, 0x01,
+	0x12, 0x08, 0x0a, 0x04, 0x52, 0x45, 0x41, 0x44, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x57, 0x52,
+	0x49, 0x54, 0x45, 0x10, 0x03, 0x22, 0x31, 0x0a, 0x04, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x09, 0x0a,
+	0x05, 0x55, 0x4e, 0x41, 0x52, 0x59, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x53, 0x45, 0x52, 0x56,
+	0x45, 0x52, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x47,
+	0x45, 0x4e, 0x45, 0x52, 0x49, 0x43, 0x10, 0x02, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -333,23 +528,27 @@ func file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawD
 	return file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
 var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_goTypes = []interface{}{
 	(ProbeConf_MethodType)(0),    // 0: cloudprober.probes.grpc.ProbeConf.MethodType
-	(*ProbeConf)(nil),            // 1: cloudprober.probes.grpc.ProbeConf
-	(*ProbeConf_ALTSConfig)(nil), // 2: cloudprober.probes.grpc.ProbeConf.ALTSConfig
-	(*proto.Config)(nil),         // 3: cloudprober.oauth.Config
+	(ProbeConf_Mode)(0),          // 1: cloudprober.probes.grpc.ProbeConf.Mode
+	(*ProbeConf)(nil),            // 2: cloudprober.probes.grpc.ProbeConf
+	(*ProbeConf_ALTSConfig)(nil), // 3: cloudprober.probes.grpc.ProbeConf.ALTSConfig
+	(*proto.Config)(nil),         // 4: cloudprober.oauth.Config
+	(*proto1.TLSConfig)(nil),     // 5: cloudprober.tlsconfig.TLSConfig
 }
 var file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_depIdxs = []int32{
-	3, // 0: cloudprober.probes.grpc.ProbeConf.oauth_config:type_name -> cloudprober.oauth.Config
-	2, // 1: cloudprober.probes.grpc.ProbeConf.alts_config:type_name -> cloudprober.probes.grpc.ProbeConf.ALTSConfig
+	4, // 0: cloudprober.probes.grpc.ProbeConf.oauth_config:type_name -> cloudprober.oauth.Config
+	3, // 1: cloudprober.probes.grpc.ProbeConf.alts_config:type_name -> cloudprober.probes.grpc.ProbeConf.ALTSConfig
 	0, // 2: cloudprober.probes.grpc.ProbeConf.method:type_name -> cloudprober.probes.grpc.ProbeConf.MethodType
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	5, // 3: cloudprober.probes.grpc.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	1, // 4: cloudprober.probes.grpc.ProbeConf.mode:type_name -> cloudprober.probes.grpc.ProbeConf.Mode
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_init() }
@@ -388,7 +587,7 @@ func file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_init
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_grpc_proto_config_proto_rawDesc,
-			NumEnums:      1,
+			NumEnums:      2,
 			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
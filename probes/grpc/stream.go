@@ -0,0 +1,120 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"google.golang.org/grpc"
+)
+
+// rawCodec passes the messages through as serialized bytes, so that we can
+// call any method without knowing its message types. It's named
+// "proto" to keep the content-type that the servers expect.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec: unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+var streamDesc = &grpc.StreamDesc{ServerStreams: true}
+
+// streamCall calls the server-streaming method and reads
+// stream_message_count messages from the stream. It returns the time to
+// each message since the previous one (or since the start, for the first
+// message), even if the stream fails midway.
+func (p *Probe) streamCall(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption) ([]time.Duration, error) {
+	// Cancel the stream when we are done reading, as server may have more
+	// messages to send.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	last := time.Now()
+	stream, err := conn.NewStream(ctx, streamDesc, p.c.GetStreamMethod(), append(opts, grpc.ForceCodec(rawCodec{}))...)
+	if err != nil {
+		return nil, err
+	}
+	req := p.c.GetStreamRequest()
+	if err := stream.SendMsg(&req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var gaps []time.Duration
+	var msg []byte
+	for len(gaps) < int(p.c.GetStreamMessageCount()) {
+		if err := stream.RecvMsg(&msg); err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("stream ended after %d messages", len(gaps))
+			}
+			return gaps, err
+		}
+		now := time.Now()
+		gaps = append(gaps, now.Sub(last))
+		last = now
+	}
+	return gaps, nil
+}
+
+// recordStream records a stream call's result. Caller should hold the lock.
+func (result *probeRunResult) recordStream(gaps []time.Duration, err error, latencyUnit time.Duration) {
+	for i, gap := range gaps {
+		v := gap.Seconds() / latencyUnit.Seconds()
+		if i == 0 {
+			result.firstMsgLatency.AddFloat64(v)
+		} else {
+			result.interMsgLatency.AddFloat64(v)
+		}
+	}
+	result.streamMessages.AddInt64(int64(len(gaps)))
+	if err != nil {
+		result.messagesBeforeError.AddInt64(int64(len(gaps)))
+		return
+	}
+	result.streamCompletions.Inc()
+}
+
+// addStreamMetrics adds the stream metrics to the EventMetrics. Caller should
+// hold the lock.
+func (result *probeRunResult) addStreamMetrics(em *metrics.EventMetrics) {
+	em.AddMetric("first_message_latency", result.firstMsgLatency.Clone()).
+		AddMetric("inter_message_latency", result.interMsgLatency.Clone()).
+		AddMetric("stream_messages", result.streamMessages.Clone()).
+		AddMetric("stream_completions", result.streamCompletions.Clone()).
+		AddMetric("messages_before_error", result.messagesBeforeError.Clone())
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/grpc/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	pb "github.com/cloudprober/cloudprober/servers/grpc/proto"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testStreamMethod = "/cloudprober.test.Streamer/Stream"
+
+// streamServer implements a server-streaming method that reads a
+// BlobReadRequest and sends back blobs of the requested size. It sends
+// numMsgs messages, and then fails the stream if failAtEnd is set.
+type streamServer struct {
+	numMsgs   int
+	failAtEnd bool
+	delay     time.Duration
+}
+
+func (s *streamServer) stream(_ interface{}, stream grpc.ServerStream) error {
+	req := &pb.BlobReadRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	for i := 0; i < s.numMsgs; i++ {
+		time.Sleep(s.delay)
+		if err := stream.SendMsg(&pb.BlobReadResponse{Blob: make([]byte, req.GetSize())}); err != nil {
+			return err
+		}
+	}
+	if s.failAtEnd {
+		return status.Error(codes.Unavailable, "stream broken")
+	}
+	return nil
+}
+
+// startStreamServer starts an in-process gRPC server with the streaming
+// method and returns its address.
+func startStreamServer(t *testing.T, s *streamServer) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "cloudprober.test.Streamer",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{StreamName: "Stream", Handler: s.stream, ServerStreams: true},
+		},
+	}, s)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return ln.Addr().String()
+}
+
+func streamProbe(t *testing.T, addr string, conf *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	if err := p.Init("grpc-stream", &options.Options{
+		Targets:     targets.StaticTargets(addr),
+		Interval:    100 * time.Millisecond,
+		Timeout:     time.Second,
+		ProbeConf:   conf,
+		LatencyUnit: time.Millisecond,
+		LogMetrics:  func(*metrics.EventMetrics) {},
+	}); err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	return p
+}
+
+func streamConf(count int32) *configpb.ProbeConf {
+	req, _ := proto.Marshal(&pb.BlobReadRequest{Size: proto.Int32(16)})
+	return &configpb.ProbeConf{
+		Mode:               configpb.ProbeConf_SERVER_STREAM.Enum(),
+		StreamMethod:       proto.String(testStreamMethod),
+		StreamRequest:      req,
+		StreamMessageCount: proto.Int32(count),
+	}
+}
+
+func TestStreamCall(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		server   *streamServer
+		wantMsgs int
+		wantErr  string
+	}{
+		{
+			desc:     "complete",
+			server:   &streamServer{numMsgs: 10, delay: 5 * time.Millisecond},
+			wantMsgs: 5,
+		},
+		{
+			desc:     "error_midway",
+			server:   &streamServer{numMsgs: 3, failAtEnd: true},
+			wantMsgs: 3,
+			wantErr:  "stream broken",
+		},
+		{
+			desc:     "ended_early",
+			server:   &streamServer{numMsgs: 2},
+			wantMsgs: 2,
+			wantErr:  "stream ended after 2 messages",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			addr := startStreamServer(t, test.server)
+			p := streamProbe(t, addr, streamConf(5))
+
+			conn, err := grpc.Dial(addr, p.dialOpts...)
+			if err != nil {
+				t.Fatalf("Error connecting to the server: %v", err)
+			}
+			defer conn.Close()
+
+			gaps, err := p.streamCall(context.Background(), conn, nil)
+			if len(gaps) != test.wantMsgs {
+				t.Errorf("Got %d messages, want %d", len(gaps), test.wantMsgs)
+			}
+			if (err != nil) != (test.wantErr != "") || (err != nil && !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("Got error: %v, want error: %q", err, test.wantErr)
+			}
+
+			result := p.newResult(addr)
+			result.recordStream(gaps, err, p.opts.LatencyUnit)
+			wantCompletions, wantBeforeError := int64(1), int64(0)
+			if test.wantErr != "" {
+				wantCompletions, wantBeforeError = 0, int64(test.wantMsgs)
+			}
+			if result.streamMessages.Int64() != int64(test.wantMsgs) || result.streamCompletions.Int64() != wantCompletions || result.messagesBeforeError.Int64() != wantBeforeError {
+				t.Errorf("Got stream_messages=%d, stream_completions=%d, messages_before_error=%d; want %d, %d, %d", result.streamMessages.Int64(), result.streamCompletions.Int64(), result.messagesBeforeError.Int64(), test.wantMsgs, wantCompletions, wantBeforeError)
+			}
+		})
+	}
+}
+
+func TestStreamProbeLoop(t *testing.T) {
+	addr := startStreamServer(t, &streamServer{numMsgs: 3, delay: 10 * time.Millisecond})
+	p := streamProbe(t, addr, streamConf(3))
+
+	result := p.newResult(addr)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.oneTargetLoop(ctx, addr, 0, result)
+		close(done)
+	}()
+	time.Sleep(550 * time.Millisecond)
+	cancel()
+	<-done
+
+	result.Lock()
+	defer result.Unlock()
+
+	em := metrics.NewEventMetrics(time.Now())
+	result.addStreamMetrics(em)
+
+	completions := result.streamCompletions.Int64()
+	if completions < 2 || result.success.Int64() != completions {
+		t.Errorf("Got stream_completions=%d, success=%d, want >= 2 and equal", completions, result.success.Int64())
+	}
+	if got := em.Metric("stream_messages").(metrics.NumValue).Int64(); got != 3*completions {
+		t.Errorf("Got stream_messages=%d, want=%d", got, 3*completions)
+	}
+	// First message takes at least 10ms, and so does every other message.
+	if got := em.Metric("first_message_latency").(metrics.NumValue).Float64(); got < 10*float64(completions) {
+		t.Errorf("Got first_message_latency=%f ms, want >= %d ms", got, 10*completions)
+	}
+	if got := em.Metric("inter_message_latency").(metrics.NumValue).Float64(); got < 2*10*float64(completions) {
+		t.Errorf("Got inter_message_latency=%f ms, want >= %d ms", got, 2*10*completions)
+	}
+}
+
+func TestStreamConfigErrors(t *testing.T) {
+	for _, conf := range []*configpb.ProbeConf{
+		{Mode: configpb.ProbeConf_SERVER_STREAM.Enum()},
+		{Mode: configpb.ProbeConf_SERVER_STREAM.Enum(), StreamMethod: proto.String("pkg.Service/Method")},
+		{Mode: configpb.ProbeConf_SERVER_STREAM.Enum(), StreamMethod: proto.String(testStreamMethod), StreamMessageCount: proto.Int32(0)},
+	} {
+		p := &Probe{}
+		if err := p.Init("grpc-stream", &options.Options{
+			Targets:   targets.StaticTargets("localhost:9313"),
+			ProbeConf: conf,
+		}); err == nil {
+			t.Errorf("Init(%v): expected error", conf)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/iputils"
+)
+
+// happyEyeballsDialer connects to dual-stack hosts by racing the IP address
+// families (RFC 8305). Dialing the preferred family starts right away, and
+// dialing the other family starts after the head start delay or as soon as the
+// preferred family fails, whichever is earlier. First connection to succeed is
+// used.
+type happyEyeballsDialer struct {
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+	lookupIP func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	// IP address families to dial, in the order of preference.
+	families []int
+	delay    time.Duration
+}
+
+// newHappyEyeballsDialer returns a dialer that uses the dial function for
+// individual connection attempts. ipVer and fallbackIPVer are the probe's IP
+// version and fallback IP version: if IP version is set without a fallback,
+// only that family is dialed.
+func newHappyEyeballsDialer(dial func(context.Context, string, string) (net.Conn, error), ipVer, fallbackIPVer int, delay time.Duration) *happyEyeballsDialer {
+	families := []int{6, 4}
+	if ipVer != 0 {
+		families = []int{ipVer}
+		if fallbackIPVer != 0 {
+			families = append(families, fallbackIPVer)
+		}
+	}
+
+	return &happyEyeballsDialer{
+		dial:     dial,
+		lookupIP: net.DefaultResolver.LookupIPAddr,
+		families: families,
+		delay:    delay,
+	}
+}
+
+// addrsByFamily resolves host and returns its addresses (joined with port),
+// grouped by the address family in the order of preference. Families without
+// any address are skipped.
+func (d *happyEyeballsDialer) addrsByFamily(ctx context.Context, host, port string) ([][]string, error) {
+	ipAddrs, err := d.lookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]string
+	for _, family := range d.families {
+		var addrs []string
+		for _, ipAddr := range ipAddrs {
+			if iputils.IPVersion(ipAddr.IP) != family {
+				continue
+			}
+			ip := ipAddr.IP.String()
+			if ipAddr.Zone != "" {
+				ip += "%" + ipAddr.Zone
+			}
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+		if len(addrs) > 0 {
+			groups = append(groups, addrs)
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no address of the configured IP version found for the host: %s", host)
+	}
+	return groups, nil
+}
+
+// dialSerial tries the given addresses one by one, and returns the first
+// connection that succeeds.
+func (d *happyEyeballsDialer) dialSerial(ctx context.Context, network string, addrs []string) (net.Conn, error) {
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := d.dial(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// DialContext connects to the address on the named network. It can be used
+// as http.Transport's DialContext.
+func (d *happyEyeballsDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to race for IP addresses.
+	if net.ParseIP(host) != nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	groups, err := d.addrsByFamily(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 1 {
+		return d.dialSerial(ctx, network, groups[0])
+	}
+
+	// Cancels the attempt that loses the race.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan dialResult, len(groups))
+	startDial := func(addrs []string) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, addrs)
+			results <- dialResult{conn, err}
+		}()
+	}
+
+	startDial(groups[0])
+	pending, fallbackStarted := 1, false
+	fallbackTimer := time.NewTimer(d.delay)
+	defer fallbackTimer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-fallbackTimer.C:
+			if !fallbackStarted {
+				startDial(groups[1])
+				pending, fallbackStarted = pending+1, true
+			}
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// Close the losing connection, in case it still succeeds.
+				if pending > 0 {
+					go func() {
+						if res := <-results; res.conn != nil {
+							res.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				startDial(groups[1])
+				pending, fallbackStarted = pending+1, true
+				continue
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	dualStackHost = "dualstack.example.com"
+	testIPv4      = "192.0.2.1"
+	testIPv6      = "2001:db8::1"
+)
+
+// remoteAddrConn overrides a connection's remote address.
+type remoteAddrConn struct {
+	net.Conn
+	raddr net.Addr
+}
+
+func (c *remoteAddrConn) RemoteAddr() net.Addr { return c.raddr }
+
+// dualStackMock mocks a dual-stack host: both of its addresses connect to the
+// same local server, after the configured per-family delay.
+type dualStackMock struct {
+	serverAddr string
+	delay      map[string]time.Duration // Dial delay, keyed by IP.
+	fail       map[string]bool          // Dial failures, keyed by IP.
+
+	mu     sync.Mutex
+	dialed map[string]int
+}
+
+func (m *dualStackMock) lookupIP(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if host != dualStackHost {
+		return nil, errors.New("unknown host: " + host)
+	}
+	return []net.IPAddr{{IP: net.ParseIP(testIPv4)}, {IP: net.ParseIP(testIPv6)}}, nil
+}
+
+func (m *dualStackMock) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, _ := net.SplitHostPort(addr)
+
+	m.mu.Lock()
+	m.dialed[host]++
+	m.mu.Unlock()
+
+	select {
+	case <-time.After(m.delay[host]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if m.fail[host] {
+		return nil, errors.New("connection refused")
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, m.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	portNum, _ := strconv.Atoi(port)
+	return &remoteAddrConn{conn, &net.TCPAddr{IP: net.ParseIP(host), Port: portNum}}, nil
+}
+
+func (m *dualStackMock) dialCount(ip string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dialed[ip]
+}
+
+func newDualStackMock(serverAddr string) *dualStackMock {
+	return &dualStackMock{
+		serverAddr: serverAddr,
+		delay:      make(map[string]time.Duration),
+		fail:       make(map[string]bool),
+		dialed:     make(map[string]int),
+	}
+}
+
+func TestHappyEyeballsProbe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+
+	slow := 500 * time.Millisecond
+
+	for _, test := range []struct {
+		desc          string
+		delay         map[string]time.Duration
+		ipVer         int
+		fallback      int
+		wantIPVersion string
+		wantNoDial    string
+	}{
+		{
+			desc:          "ipv6_slow",
+			delay:         map[string]time.Duration{testIPv6: slow},
+			wantIPVersion: "4",
+		},
+		{
+			desc:          "ipv4_slow",
+			delay:         map[string]time.Duration{testIPv4: slow},
+			wantIPVersion: "6",
+		},
+		{
+			desc:          "both_fast",
+			wantIPVersion: "6",
+		},
+		{
+			desc:          "ipv4_only_slow",
+			delay:         map[string]time.Duration{testIPv4: slow},
+			ipVer:         4,
+			wantIPVersion: "4",
+			wantNoDial:    testIPv6,
+		},
+		{
+			desc:          "prefer_ipv4",
+			ipVer:         4,
+			fallback:      6,
+			wantIPVersion: "4",
+		},
+		{
+			desc:          "prefer_ipv4_slow",
+			delay:         map[string]time.Duration{testIPv4: slow},
+			ipVer:         4,
+			fallback:      6,
+			wantIPVersion: "6",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			opts := options.DefaultOptions()
+			opts.Targets = targets.StaticTargets(dualStackHost)
+			opts.Timeout = 2 * time.Second
+			opts.IPVersion, opts.FallbackIPVersion = test.ipVer, test.fallback
+			opts.ProbeConf = &configpb.ProbeConf{
+				Port:                   proto.Int32(int32(portNum)),
+				DialMode:               configpb.ProbeConf_HAPPY_EYEBALLS.Enum(),
+				HappyEyeballsDelayMsec: proto.Int32(50),
+			}
+			if err := p.Init("http_test", opts); err != nil {
+				t.Fatalf("Error initializing probe: %v", err)
+			}
+
+			mock := newDualStackMock(ts.Listener.Addr().String())
+			for ip, d := range test.delay {
+				mock.delay[ip] = d
+			}
+			p.heDialer.dial, p.heDialer.lookupIP = mock.dial, mock.lookupIP
+
+			target := endpoint.Endpoint{Name: dualStackHost}
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.success != 1 {
+				t.Fatalf("Got success=%d, want=1", result.success)
+			}
+			wantIPVersionUsed := "map:ip_version," + test.wantIPVersion + ":1"
+			if result.ipVersionUsed.String() != wantIPVersionUsed {
+				t.Errorf("Got IP version used=%s, want=%s", result.ipVersionUsed.String(), wantIPVersionUsed)
+			}
+			if test.wantNoDial != "" && mock.dialCount(test.wantNoDial) != 0 {
+				t.Errorf("Got %d dials to %s, want none", mock.dialCount(test.wantNoDial), test.wantNoDial)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 10)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			if got := em.Metric("ip_version_used"); got == nil || got.String() != wantIPVersionUsed {
+				t.Errorf("Got ip_version_used metric=%v, want=%s", got, wantIPVersionUsed)
+			}
+		})
+	}
+}
+
+func TestHappyEyeballsFallbackOnFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Long head start: fallback should start as soon as IPv6 fails.
+	d := newHappyEyeballsDialer(nil, 0, 0, 10*time.Second)
+	mock := newDualStackMock(ln.Addr().String())
+	mock.fail[testIPv6] = true
+	d.dial, d.lookupIP = mock.dial, mock.lookupIP
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort(dualStackHost, "80"))
+	if err != nil {
+		t.Fatalf("Error dialing: %v", err)
+	}
+	conn.Close()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Dial took %v, expected immediate fallback to IPv4", elapsed)
+	}
+	if got := options.AddrIPVersion(conn.RemoteAddr()); got != 4 {
+		t.Errorf("Got IP version=%d, want=4", got)
+	}
+
+	// Both families failing.
+	mock.fail[testIPv4] = true
+	if _, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort(dualStackHost, "80")); err == nil {
+		t.Error("Expected error when all addresses fail")
+	}
+}
+
+func TestHappyEyeballsInitError(t *testing.T) {
+	opts := options.DefaultOptions()
+	opts.Targets = targets.StaticTargets(dualStackHost)
+	opts.ProbeConf = &configpb.ProbeConf{
+		DialMode:     configpb.ProbeConf_HAPPY_EYEBALLS.Enum(),
+		ResolveFirst: proto.Bool(true),
+	}
+	if err := (&Probe{}).Init("http_test", opts); err == nil {
+		t.Error("Expected error for dial_mode HAPPY_EYEBALLS with resolve_first")
+	}
+}
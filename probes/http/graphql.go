@@ -0,0 +1,78 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// graphQLRequest is the JSON request body of a GraphQL query.
+type graphQLRequest struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+// graphQLResponse is the part of the GraphQL response that we look at.
+type graphQLResponse struct {
+	Errors []json.RawMessage `json:"errors"`
+}
+
+// initGraphQL sets up the request body and method for the GraphQL mode.
+func (p *Probe) initGraphQL() error {
+	gc := p.c.GetGraphql()
+
+	if p.c.GetBody() != "" || p.c.GetBodyFile() != "" || len(p.c.GetSteps()) > 0 {
+		return errors.New("graphql cannot be configured along with body, body_file or steps")
+	}
+	if p.c.Method != nil && p.c.GetMethod().String() != http.MethodPost {
+		return fmt.Errorf("graphql requires method POST, got: %s", p.c.GetMethod().String())
+	}
+
+	gqlReq := &graphQLRequest{
+		Query:         gc.GetQuery(),
+		OperationName: gc.GetOperationName(),
+	}
+	if gc.GetVariables() != "" {
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(gc.GetVariables()), &vars); err != nil {
+			return fmt.Errorf("graphql variables must be a JSON object: %v", err)
+		}
+		gqlReq.Variables = json.RawMessage(gc.GetVariables())
+	}
+
+	body, err := json.Marshal(gqlReq)
+	if err != nil {
+		return fmt.Errorf("error creating graphql request body: %v", err)
+	}
+
+	p.requestBody = body
+	p.method = http.MethodPost
+	p.graphQL = true
+	return nil
+}
+
+// graphQLErrors returns the number of errors in a GraphQL response body. It
+// returns an error if response body is not a JSON object.
+func graphQLErrors(respBody []byte) (int, error) {
+	resp := &graphQLResponse{}
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return 0, fmt.Errorf("invalid graphql response: %v", err)
+	}
+	return len(resp.Errors), nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/golang/protobuf/proto"
+)
+
+// graphQLServer is a mock GraphQL server. It returns a response based on the
+// requested operation, and sends the decoded requests on the returned channel.
+func graphQLServer(t *testing.T) (*httptest.Server, chan map[string]interface{}) {
+	t.Helper()
+	reqs := make(chan map[string]interface{}, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request: %s, content-type: %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		req := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Error decoding request: %v", err)
+		}
+		reqs <- req
+
+		w.Header().Set("Content-Type", "application/json")
+		switch req["operationName"] {
+		case "Errors":
+			w.Write([]byte(`{"data": null, "errors": [{"message": "not found"}, {"message": "forbidden"}]}`))
+		case "Invalid":
+			w.Write([]byte(`<html>internal error</html>`))
+		default:
+			w.Write([]byte(`{"data": {"user": {"name": "cloudprober"}}, "errors": []}`))
+		}
+	}))
+	return ts, reqs
+}
+
+func TestProbeGraphQL(t *testing.T) {
+	ts, reqs := graphQLServer(t)
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	query := "query Success($id: ID!) { user(id: $id) { name } }"
+
+	for _, test := range []struct {
+		operation   string
+		wantSuccess int64
+		wantErrors  int64
+	}{
+		{operation: "Success", wantSuccess: 2},
+		{operation: "Errors", wantErrors: 4},
+		{operation: "Invalid"},
+	} {
+		t.Run(test.operation, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Graphql: &configpb.ProbeConf_GraphQL{
+						Query:         proto.String(query),
+						Variables:     proto.String(`{"id": "1000"}`),
+						OperationName: proto.String(test.operation),
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			req := p.httpRequestForTarget(target, nil)
+			for i := 0; i < 2; i++ {
+				p.runProbe(context.Background(), target, req, result)
+			}
+
+			wantReq := map[string]interface{}{
+				"query":         query,
+				"variables":     map[string]interface{}{"id": "1000"},
+				"operationName": test.operation,
+			}
+			if gotReq := <-reqs; !reflect.DeepEqual(gotReq, wantReq) {
+				t.Errorf("Server got request: %v, want: %v", gotReq, wantReq)
+			}
+			<-reqs
+
+			if result.total != 2 || result.success != test.wantSuccess || result.graphQLErrors != test.wantErrors {
+				t.Errorf("Got total=%d, success=%d, graphql_errors=%d, want total=2, success=%d, graphql_errors=%d", result.total, result.success, result.graphQLErrors, test.wantSuccess, test.wantErrors)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			if got := em.Metric("graphql_errors").(metrics.NumValue).Int64(); got != test.wantErrors {
+				t.Errorf("Exported graphql_errors=%d, want=%d", got, test.wantErrors)
+			}
+		})
+	}
+}
+
+func TestInitGraphQLErrors(t *testing.T) {
+	gc := &configpb.ProbeConf_GraphQL{
+		Query: proto.String("{ user { name } }"),
+	}
+
+	for _, test := range []struct {
+		desc    string
+		conf    *configpb.ProbeConf
+		wantErr bool
+	}{
+		{
+			desc: "default_method",
+			conf: &configpb.ProbeConf{Graphql: gc},
+		},
+		{
+			desc: "post_method",
+			conf: &configpb.ProbeConf{Graphql: gc, Method: configpb.ProbeConf_POST.Enum()},
+		},
+		{
+			desc:    "get_method",
+			conf:    &configpb.ProbeConf{Graphql: gc, Method: configpb.ProbeConf_GET.Enum()},
+			wantErr: true,
+		},
+		{
+			desc:    "with_body",
+			conf:    &configpb.ProbeConf{Graphql: gc, Body: proto.String("{}")},
+			wantErr: true,
+		},
+		{
+			desc: "invalid_variables",
+			conf: &configpb.ProbeConf{Graphql: &configpb.ProbeConf_GraphQL{
+				Query:     gc.Query,
+				Variables: proto.String(`["id"]`),
+			}},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:   targets.StaticTargets("test.com"),
+				Interval:  2 * time.Second,
+				Timeout:   time.Second,
+				ProbeConf: test.conf,
+			})
+			if (err != nil) != test.wantErr {
+				t.Errorf("Got error: %v, want error: %v", err, test.wantErr)
+			}
+			if err == nil && (p.method != http.MethodPost || !p.graphQL) {
+				t.Errorf("Got method=%s, graphQL=%v, want POST, true", p.method, p.graphQL)
+			}
+		})
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	for _, test := range []struct {
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{body: `{"data": {"a": 1}}`},
+		{body: `{"data": {"a": 1}, "errors": []}`},
+		{body: `{"errors": [{"message": "error"}]}`, want: 1},
+		{body: `not json`, wantErr: true},
+		{body: `["errors"]`, wantErr: true},
+	} {
+		got, err := graphQLErrors([]byte(test.body))
+		if (err != nil) != test.wantErr {
+			t.Errorf("graphQLErrors(%s): got error: %v, want error: %v", test.body, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("graphQLErrors(%s)=%d, want=%d", test.body, got, test.want)
+		}
+	}
+}
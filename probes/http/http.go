@@ -19,7 +19,9 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"net"
@@ -33,12 +35,18 @@ import (
 
 	"github.com/cloudprober/cloudprober/common/oauth"
 	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
 	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/validators"
+	httpvalidator "github.com/cloudprober/cloudprober/validators/http"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
 	"golang.org/x/oauth2"
 )
 
@@ -67,12 +75,13 @@ type Probe struct {
 	client *http.Client
 
 	// book-keeping params
-	targets     []endpoint.Endpoint
-	protocol    string
-	method      string
-	url         string
-	oauthTS     oauth2.TokenSource
-	bearerToken string
+	targets       []endpoint.Endpoint
+	protocol      string
+	method        string
+	url           string
+	oauthTS       oauth2.TokenSource
+	bearerTokenMu sync.Mutex
+	bearerToken   string
 
 	// Run counter, used to decide when to update targets or export
 	// stats.
@@ -91,36 +100,145 @@ type Probe struct {
 	waitGroup   sync.WaitGroup
 
 	requestBody []byte
+	bodyFile    string
+	graphQL     bool
+
+	// Whether the request fields use the target based tokens, and whether
+	// they use the target's IP address.
+	targetTokens  bool
+	targetIPToken bool
+
+	// Client certificate and key files, if they use the target based tokens.
+	clientCertConfig *tlsconfigpb.TLSConfig
+
+	// Status codes that count as success, if configured.
+	successCodes httpvalidator.StatusCodeRanges
+
+	// Response header required for success, if configured.
+	successHeader *httpvalidator.HeaderMatcher
+
+	// Dialer racing the IP address families, for the HAPPY_EYEBALLS dial mode.
+	heDialer *happyEyeballsDialer
+
+	// Synthetic transaction steps, if configured.
+	steps []*step
+
+	// Request retries, and the status codes to retry on, if configured.
+	retries    int
+	retryCodes httpvalidator.StatusCodeRanges
+
+	// Slots for the in-flight per-target probes, if max_concurrent_probes is
+	// configured.
+	slots chan struct{}
 }
 
 type probeResult struct {
 	total, success, timeouts int64
+	connectTimeouts          int64
+	proxyErrors              int64
 	connEvent                int64
 	latency                  metrics.Value
+	ttfb                     metrics.Value
+	dnsLatency               metrics.Value
+	connectLatency           metrics.Value
+	tlsLatency               metrics.Value
+	bodyReadLatency          metrics.Value
+	proxyConnectLatency      metrics.Value
 	respCodes                *metrics.Map
 	respBodies               *metrics.Map
 	validationFailure        *metrics.Map
+	httpProtocol             string
+	ipVersionUsed            *metrics.Map
+	graphQLErrors            int64
+	retries                  int64
+	unexpectedStatus         *metrics.Map
+	headerMismatch           int64
+	rawOutcome               *metrics.Map
+	redirects                int64
+	oauthRefreshFailures     int64
+	finalHost                *metrics.Map
+	skipped                  int64
+	sloGood                  int64
+
+	// Latencies of the successful requests (or transactions) since the last
+	// probe run, if export_raw_latency is enabled.
+	rawLatencies []time.Duration
+
+	// Synthetic transaction results.
+	stepLatency  []metrics.Value
+	stepFailures *metrics.Map
+
+	// Server certificate from the last HTTPS response, if export_cert_metrics
+	// is enabled.
+	cert *certInfo
 }
 
-func (p *Probe) updateOauthToken() {
-	if p.oauthTS == nil {
-		return
+// certInfo captures the details of a server certificate.
+type certInfo struct {
+	notAfter   time.Time
+	issuer     string
+	chainDepth int
+}
+
+func newCertInfo(state *tls.ConnectionState) *certInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	issuer := leaf.Issuer.CommonName
+	if issuer == "" {
+		issuer = leaf.Issuer.String()
+	}
+	return &certInfo{
+		notAfter:   leaf.NotAfter,
+		issuer:     issuer,
+		chainDepth: len(state.PeerCertificates),
 	}
+}
 
+// oauthToken returns the current OAuth token. Token source caches the token
+// till it expires, so this is cheap enough to call for every request. If we
+// fail to get a token, we return the last token along with the error.
+func (p *Probe) oauthToken() (string, error) {
 	tok, err := p.oauthTS.Token()
+
+	p.bearerTokenMu.Lock()
+	defer p.bearerTokenMu.Unlock()
+
 	if err != nil {
-		p.l.Error("Error getting OAuth token: ", err.Error(), ". Skipping updating the token.")
-	} else {
-		if tok.AccessToken != "" {
-			p.bearerToken = tok.AccessToken
-		} else {
-			idToken, ok := tok.Extra("id_token").(string)
-			if ok {
-				p.bearerToken = idToken
-			}
+		return p.bearerToken, err
+	}
+
+	bearerToken := tok.AccessToken
+	if bearerToken == "" {
+		if idToken, ok := tok.Extra("id_token").(string); ok {
+			bearerToken = idToken
 		}
-		p.l.Debug("Got OAuth token, len: ", strconv.FormatInt(int64(len(p.bearerToken)), 10), ", expirationTime: ", tok.Expiry.String())
 	}
+	if bearerToken != p.bearerToken {
+		p.l.Debug("Got OAuth token, len: ", strconv.FormatInt(int64(len(bearerToken)), 10), ", expirationTime: ", tok.Expiry.String())
+		p.bearerToken = bearerToken
+	}
+	return p.bearerToken, nil
+}
+
+// requestWithOAuthToken returns a copy of the request with the current OAuth
+// token in the Authorization header. It returns the request as it is if
+// OAuth is not configured, and sets the error if refreshing the token failed.
+func (p *Probe) requestWithOAuthToken(req *http.Request, targetName string) (*http.Request, error) {
+	if p.oauthTS == nil {
+		return req, nil
+	}
+	tok, err := p.oauthToken()
+	if err != nil {
+		p.l.Warning("Target:", targetName, ", error refreshing OAuth token: ", err.Error())
+	}
+	if tok == "" {
+		return req, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return req, err
 }
 
 // Init initializes the probe with the given params.
@@ -146,34 +264,112 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 
 	p.requestBody = []byte(p.c.GetBody())
 
+	if p.c.GetBodyFile() != "" {
+		if err := p.initBodyFile(); err != nil {
+			return err
+		}
+	}
+
+	if p.c.GetGraphql() != nil {
+		if err := p.initGraphQL(); err != nil {
+			return err
+		}
+	}
+
+	if p.c.GetRequestCompression() != configpb.ProbeConf_NONE && len(p.requestBody) == 0 && p.bodyFile == "" {
+		return errors.New("request_compression is set, but there is no request body to compress")
+	}
+
+	if p.c.GetSuccessStatusCodes() != "" {
+		codes, err := httpvalidator.ParseStatusCodeRanges(p.c.GetSuccessStatusCodes())
+		if err != nil {
+			return fmt.Errorf("invalid success_status_codes (%s): %v", p.c.GetSuccessStatusCodes(), err)
+		}
+		p.successCodes = codes
+	}
+
+	if sh := p.c.GetSuccessHeader(); sh != nil {
+		hm, err := httpvalidator.NewHeaderMatcher(sh.GetName(), sh.GetRegex())
+		if err != nil {
+			return fmt.Errorf("invalid success_header: %v", err)
+		}
+		p.successHeader = hm
+	}
+
+	steps, err := parseSteps(p.c.GetSteps(), p.successCodes)
+	if err != nil {
+		return err
+	}
+	p.steps = steps
+
+	if p.c.GetExpectFailure() && len(p.steps) > 0 {
+		return fmt.Errorf("expect_failure is not supported with steps")
+	}
+
+	if err := p.initRetries(); err != nil {
+		return err
+	}
+
+	if p.c.GetMaxRedirects() < 0 {
+		return fmt.Errorf("invalid max_redirects (%d), cannot be negative", p.c.GetMaxRedirects())
+	}
+
 	// Create a transport for our use. This is mostly based on
 	// http.DefaultTransport with some timeouts changed.
 	// TODO(manugarg): Considering cloning DefaultTransport once
 	// https://github.com/golang/go/issues/26013 is fixed.
+	// Note that requests are bounded by the target's timeout through their
+	// context. Dialer and TLS handshake timeouts are set to the max allowed
+	// target timeout, i.e. the probe interval.
+	maxTimeout := p.opts.Timeout
+	if p.opts.Interval > maxTimeout {
+		maxTimeout = p.opts.Interval
+	}
 	dialer := &net.Dialer{
-		Timeout:   p.opts.Timeout,
+		Timeout:   maxTimeout,
 		KeepAlive: 30 * time.Second, // TCP keep-alive
 	}
 
 	if p.opts.SourceIP != nil {
 		dialer.LocalAddr = &net.TCPAddr{
-			IP: p.opts.SourceIP,
+			IP:   p.opts.SourceIP,
+			Zone: p.opts.SourceIPZone,
+		}
+	}
+
+	if algo := p.c.GetTcpCongestion(); algo != "" {
+		if probeutils.TCPCongestionSupported {
+			dialer.Control = probeutils.TCPCongestionControl(algo)
+		} else {
+			p.l.Warning("tcp_congestion is not supported on this platform, ignoring it")
 		}
 	}
 
+	// Connection setup is bounded by the connect timeout, if configured.
+	dialContext := p.opts.DialContextFunc(dialer)
+
+	if p.c.GetDialMode() == configpb.ProbeConf_HAPPY_EYEBALLS {
+		if p.c.GetResolveFirst() {
+			return errors.New("dial_mode HAPPY_EYEBALLS cannot be used with resolve_first")
+		}
+		delay := time.Duration(p.c.GetHappyEyeballsDelayMsec()) * time.Millisecond
+		p.heDialer = newHappyEyeballsDialer(dialContext, p.opts.IPVersion, p.opts.FallbackIPVersion, delay)
+		dialContext = p.heDialer.DialContext
+	}
+
 	transport := &http.Transport{
 		Proxy:               http.ProxyFromEnvironment,
-		DialContext:         dialer.DialContext,
+		DialContext:         dialContext,
 		MaxIdleConns:        256, // http.DefaultTransport.MaxIdleConns: 100.
-		TLSHandshakeTimeout: p.opts.Timeout,
+		TLSHandshakeTimeout: maxTimeout,
 	}
 
 	if p.c.GetProxyUrl() != "" {
-		url, err := url.Parse(p.c.GetProxyUrl())
+		proxy, err := proxyFunc(p.c.GetProxyUrl())
 		if err != nil {
-			return fmt.Errorf("error parsing proxy URL (%s): %v", p.c.GetProxyUrl(), err)
+			return err
 		}
-		transport.Proxy = http.ProxyURL(url)
+		transport.Proxy = proxy
 	}
 
 	if p.c.GetDisableCertValidation() || p.c.GetTlsConfig() != nil {
@@ -187,12 +383,28 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 		}
 
 		if p.c.GetTlsConfig() != nil {
-			if err := tlsconfig.UpdateTLSConfig(transport.TLSClientConfig, p.c.GetTlsConfig(), false); err != nil {
+			tlsConf := p.c.GetTlsConfig()
+			// Client certificates with target tokens are loaded per target.
+			if hasTargetTokens(tlsConf.GetTlsCertFile()) || hasTargetTokens(tlsConf.GetTlsKeyFile()) {
+				if p.c.GetProtocol() != configpb.ProbeConf_HTTPS {
+					return errors.New("per-target client certificates (tls_config with target tokens) require protocol HTTPS")
+				}
+				p.clientCertConfig = &tlsconfigpb.TLSConfig{
+					TlsCertFile:           tlsConf.TlsCertFile,
+					TlsKeyFile:            tlsConf.TlsKeyFile,
+					CertReloadIntervalSec: tlsConf.CertReloadIntervalSec,
+				}
+				tlsConf = proto.Clone(tlsConf).(*tlsconfigpb.TLSConfig)
+				tlsConf.TlsCertFile, tlsConf.TlsKeyFile = nil, nil
+			}
+			if err := tlsconfig.UpdateTLSConfig(transport.TLSClientConfig, tlsConf, false); err != nil {
 				return err
 			}
 		}
 	}
 
+	p.initTargetTokens()
+
 	// If HTTP keep-alives are not enabled (default), disable HTTP keep-alive in
 	// transport.
 	if !p.c.GetKeepAlive() {
@@ -211,18 +423,52 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 			return err
 		}
 		p.oauthTS = oauthTS
-		p.updateOauthToken() // This is also called periodically.
+		if _, err := p.oauthToken(); err != nil {
+			p.l.Error("Error getting OAuth token: ", err.Error())
+		}
 	}
 
-	if p.c.GetDisableHttp2() {
+	if p.c.GetDisableHttp2() || p.c.GetHttpProtocol() == configpb.ProbeConf_HTTP1 {
 		// HTTP/2 is enabled by default if server supports it. Setting TLSNextProto
 		// to an empty dict is the only to disable it.
 		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
 	}
 
+	var roundTripper http.RoundTripper = transport
+
+	switch p.c.GetHttpProtocol() {
+	case configpb.ProbeConf_HTTP2:
+		if p.c.GetProtocol() != configpb.ProbeConf_HTTPS || p.c.GetDisableHttp2() {
+			return fmt.Errorf("http_protocol HTTP2 requires protocol HTTPS and disable_http2 unset")
+		}
+		// Setting a custom dialer or TLS config disables HTTP/2 unless we ask
+		// for it explicitly.
+		transport.ForceAttemptHTTP2 = true
+
+	case configpb.ProbeConf_H2C:
+		if p.c.GetProtocol() != configpb.ProbeConf_HTTP || p.c.GetDisableHttp2() || p.c.GetProxyUrl() != "" {
+			return fmt.Errorf("http_protocol H2C requires protocol HTTP, and disable_http2 and proxy_url unset")
+		}
+		// http2.Transport uses DialTLS for all connections; we dial plain TCP
+		// connections here for HTTP/2 with prior knowledge.
+		roundTripper = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialContext(context.Background(), network, addr)
+			},
+		}
+	}
+
+	// With sni_label, or per-target client certificates, we route requests
+	// through per-TLS-params transports.
+	if (p.c.GetSniLabel() != "" || p.clientCertConfig != nil) && p.c.GetProtocol() == configpb.ProbeConf_HTTPS {
+		roundTripper = newTLSParamsRoundTripper(transport, p.clientCertConfig)
+	}
+
 	// Clients are safe for concurrent use by multiple goroutines.
 	p.client = &http.Client{
-		Transport: transport,
+		Transport:     roundTripper,
+		CheckRedirect: p.checkRedirect,
 	}
 
 	p.statsExportFrequency = p.opts.StatsExportInterval.Nanoseconds() / p.opts.Interval.Nanoseconds()
@@ -230,6 +476,10 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 		p.statsExportFrequency = 1
 	}
 
+	if p.opts.MaxConcurrentProbes > 0 {
+		p.slots = make(chan struct{}, p.opts.MaxConcurrentProbes)
+	}
+
 	p.targets = p.opts.Targets.ListEndpoints()
 	p.cancelFuncs = make(map[string]context.CancelFunc, len(p.targets))
 
@@ -257,14 +507,43 @@ func isClientTimeout(err error) bool {
 
 // httpRequest executes an HTTP request and updates the provided result struct.
 func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *probeResult, resultMu *sync.Mutex) {
+	req, oauthErr := p.requestWithOAuthToken(req, targetName)
+
+	if p.bodyFile != "" {
+		var err error
+		if req, err = p.requestWithFileBody(req); err != nil {
+			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: error opening body_file: ", err.Error())
+			if resultMu != nil {
+				resultMu.Lock()
+				defer resultMu.Unlock()
+			}
+			result.total++
+			return
+		}
+	}
 
-	if len(p.requestBody) >= largeBodyThreshold {
+	if p.c.GetRequestCompression() == configpb.ProbeConf_GZIP {
+		var err error
+		if req, err = p.requestWithGzipBody(req); err != nil {
+			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: error compressing request body: ", err.Error())
+			if resultMu != nil {
+				resultMu.Lock()
+				defer resultMu.Unlock()
+			}
+			result.total++
+			return
+		}
+	} else if body := bodyBytes(req); len(body) >= largeBodyThreshold {
 		req = req.Clone(req.Context())
-		req.Body = ioutil.NopCloser(bytes.NewReader(p.requestBody))
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
 	}
 
+	var trace *httptrace.ClientTrace
 	if p.c.GetKeepAlive() {
-		trace := &httptrace.ClientTrace{
+		trace = &httptrace.ClientTrace{
 			ConnectDone: func(_, addr string, err error) {
 				result.connEvent++
 				if err != nil {
@@ -274,11 +553,45 @@ func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *prob
 				p.l.Info("Established a new connection to: ", addr)
 			},
 		}
+	}
+
+	// Record the IP version of the connection used, if it can vary.
+	var connIPVersion int
+	if result.ipVersionUsed != nil {
+		if trace == nil {
+			trace = &httptrace.ClientTrace{}
+		}
+		trace.GotConn = func(info httptrace.GotConnInfo) { connIPVersion = options.AddrIPVersion(info.Conn.RemoteAddr()) }
+	}
+
+	// Time to first byte is measured from the time request is written to the
+	// time first response byte is received.
+	var wroteRequest, gotFirstByte time.Time
+	if p.exportTTFB() {
+		if trace == nil {
+			trace = &httptrace.ClientTrace{}
+		}
+		trace.WroteRequest = func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() }
+		trace.GotFirstResponseByte = func() { gotFirstByte = time.Now() }
+	}
+
+	// Connection setup timings, for the latency breakdown and the proxy
+	// connect latency.
+	var ct *connTimings
+	if p.c.GetExportLatencyBreakdown() || result.proxyConnectLatency != nil {
+		if trace == nil {
+			trace = &httptrace.ClientTrace{}
+		}
+		ct = &connTimings{}
+		ct.addHooks(trace)
+	}
+
+	if trace != nil {
 		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
 	}
 
 	start := time.Now()
-	resp, err := p.client.Do(req)
+	resp, retries, err := p.doWithRetries(req, targetName)
 	latency := time.Since(start)
 
 	if resultMu != nil {
@@ -288,8 +601,47 @@ func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *prob
 	}
 
 	result.total++
+	result.retries += retries
+	if oauthErr != nil {
+		result.oauthRefreshFailures++
+	}
+	if connIPVersion != 0 {
+		result.ipVersionUsed.IncKey(strconv.Itoa(connIPVersion))
+	}
+
+	// With expect_failure, raw outcome is determined at the end: any early
+	// return is an expected failure.
+	var rawSuccess bool
+	if result.rawOutcome != nil {
+		defer func() {
+			if rawSuccess {
+				result.rawOutcome.IncKey("success")
+				return
+			}
+			result.rawOutcome.IncKey("failure")
+			result.success++
+		}()
+	}
+
+	if result.ttfb != nil && !gotFirstByte.IsZero() && !wroteRequest.IsZero() {
+		result.ttfb.AddFloat64(gotFirstByte.Sub(wroteRequest).Seconds() / p.opts.LatencyUnit.Seconds())
+	}
+
+	if ct != nil {
+		ct.record(result, p.opts.LatencyUnit)
+	}
 
 	if err != nil {
+		if p.c.GetProxyUrl() != "" && isProxyError(err) {
+			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: proxy error: ", err.Error())
+			result.proxyErrors++
+			return
+		}
+		if options.IsConnectTimeout(err) {
+			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: connect timeout error: ", err.Error())
+			result.connectTimeouts++
+			return
+		}
 		if isClientTimeout(err) {
 			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: timeout error: ", err.Error())
 			result.timeouts++
@@ -299,11 +651,42 @@ func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *prob
 		return
 	}
 
+	if p.c.GetHttpProtocol() == configpb.ProbeConf_HTTP2 && resp.ProtoMajor != 2 {
+		p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: server didn't negotiate HTTP/2, got: ", resp.Proto)
+		resp.Body.Close()
+		return
+	}
+	result.httpProtocol = resp.Proto
+
+	if result.finalHost != nil {
+		result.redirects += numRedirects(resp)
+		result.finalHost.IncKey(requestHost(resp.Request))
+	}
+
+	if p.c.GetExportCertMetrics() {
+		if ci := newCertInfo(resp.TLS); ci != nil {
+			result.cert = ci
+		}
+	}
+
+	// For plain HTTP targets, proxy's authentication failures come back as
+	// responses.
+	if p.c.GetProxyUrl() != "" && resp.StatusCode == http.StatusProxyAuthRequired {
+		p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: proxy error: ", resp.Status)
+		resp.Body.Close()
+		result.proxyErrors++
+		return
+	}
+
+	bodyReadStart := time.Now()
 	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: ", err.Error())
 		return
 	}
+	if result.bodyReadLatency != nil {
+		result.bodyReadLatency.AddFloat64(time.Since(bodyReadStart).Seconds() / p.opts.LatencyUnit.Seconds())
+	}
 
 	p.l.Debug("Target:", targetName, ", URL:", req.URL.String(), ", response: ", string(respBody))
 
@@ -311,6 +694,31 @@ func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *prob
 	resp.Body.Close()
 	result.respCodes.IncKey(strconv.FormatInt(int64(resp.StatusCode), 10))
 
+	if p.successCodes != nil && !p.successCodes.Match(resp.StatusCode) {
+		p.l.Debug("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: unexpected status code: ", strconv.Itoa(resp.StatusCode))
+		result.unexpectedStatus.IncKey(strconv.Itoa(resp.StatusCode))
+		return
+	}
+
+	if p.successHeader != nil && !p.successHeader.Match(resp.Header) {
+		p.l.Debug("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: success header missing or not matching")
+		result.headerMismatch++
+		return
+	}
+
+	if p.graphQL {
+		numErrors, err := graphQLErrors(respBody)
+		if err != nil {
+			p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: ", err.Error())
+			return
+		}
+		if numErrors > 0 {
+			p.l.Debug("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: graphql errors: ", strconv.Itoa(numErrors))
+			result.graphQLErrors += int64(numErrors)
+			return
+		}
+	}
+
 	if p.opts.Validators != nil {
 		failedValidations := validators.RunValidators(p.opts.Validators, &validators.Input{Response: resp, ResponseBody: respBody}, result.validationFailure, p.l)
 
@@ -322,15 +730,32 @@ func (p *Probe) doHTTPRequest(req *http.Request, targetName string, result *prob
 		}
 	}
 
-	result.success++
-	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	if result.rawOutcome != nil {
+		p.l.Warning("Target:", targetName, ", URL:", req.URL.String(), ", http.doHTTPRequest: request succeeded while expecting failure")
+		rawSuccess = true
+		return
+	}
+
+	p.recordSuccess(result, latency)
 	if result.respBodies != nil && len(respBody) <= maxResponseSizeForMetrics {
 		result.respBodies.IncKey(string(respBody))
 	}
 }
 
+// recordSuccess records a successful request's, or transaction's, latency.
+func (p *Probe) recordSuccess(result *probeResult, latency time.Duration) {
+	result.success++
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	if p.opts.SLOLatencyObjective > 0 && p.opts.MeetsLatencyObjective(latency) {
+		result.sloGood++
+	}
+	if p.opts.ExportRawLatency {
+		result.rawLatencies = append(result.rawLatencies, latency)
+	}
+}
+
 func (p *Probe) runProbe(ctx context.Context, target endpoint.Endpoint, req *http.Request, result *probeResult) {
-	reqCtx, cancelReqCtx := context.WithTimeout(ctx, p.opts.Timeout)
+	reqCtx, cancelReqCtx := context.WithTimeout(withTLSParamsFrom(ctx, req), p.opts.TargetTimeout(target))
 	defer cancelReqCtx()
 
 	if p.c.GetRequestsPerProbe() == 1 {
@@ -355,11 +780,100 @@ func (p *Probe) runProbe(ctx context.Context, target endpoint.Endpoint, req *htt
 	wg.Wait()
 }
 
+// failureReason describes the failures in a probe run's result.
+func (result *probeResult) failureReason() string {
+	var reasons []string
+	if result.timeouts > 0 {
+		reasons = append(reasons, "timeout")
+	}
+	if result.connectTimeouts > 0 {
+		reasons = append(reasons, "connect timeout")
+	}
+	if result.proxyErrors > 0 {
+		reasons = append(reasons, "proxy error")
+	}
+	if result.unexpectedStatus != nil && len(result.unexpectedStatus.Keys()) > 0 {
+		reasons = append(reasons, "unexpected status code: "+strings.Join(result.unexpectedStatus.Keys(), ","))
+	}
+	if result.headerMismatch > 0 {
+		reasons = append(reasons, "success header missing or not matching")
+	}
+	if result.graphQLErrors > 0 {
+		reasons = append(reasons, "graphql errors")
+	}
+	if result.validationFailure != nil {
+		var failed []string
+		for _, k := range result.validationFailure.Keys() {
+			if result.validationFailure.GetKey(k).Int64() > 0 {
+				failed = append(failed, k)
+			}
+		}
+		if len(failed) > 0 {
+			reasons = append(reasons, "failed validations: "+strings.Join(failed, ","))
+		}
+	}
+	if result.stepFailures != nil && len(result.stepFailures.Keys()) > 0 {
+		reasons = append(reasons, "failed steps: "+strings.Join(result.stepFailures.Keys(), ","))
+	}
+	if result.rawOutcome != nil && result.rawOutcome.GetKey("success").Int64() > 0 {
+		reasons = append(reasons, "request succeeded while expecting failure")
+	}
+	if len(reasons) == 0 {
+		return "request failed"
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics. With multiple
+// requests per probe, the run succeeds only if all the requests succeed.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	req := p.httpRequestForTarget(target, nil)
+	if req == nil {
+		return 0, fmt.Errorf("couldn't create HTTP request for the target: %s", target.Name)
+	}
+
+	result := p.newResult()
+	start := time.Now()
+	if len(p.steps) > 0 {
+		p.runTransaction(ctx, target, req, result)
+	} else {
+		p.runProbe(ctx, target, req, result)
+	}
+	latency := time.Since(start)
+
+	if result.total == 0 || result.success < result.total {
+		return 0, errors.New(result.failureReason())
+	}
+	return latency, nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+// exportTTFB returns true if TTFB should be exported, by itself or as part of
+// the latency breakdown.
+func (p *Probe) exportTTFB() bool {
+	return p.c.GetExportTtfb() || p.c.GetExportLatencyBreakdown()
+}
+
 func (p *Probe) newResult() *probeResult {
 	result := &probeResult{
 		respCodes: metrics.NewMap("code", metrics.NewInt(0)),
 	}
 
+	if p.successCodes != nil {
+		result.unexpectedStatus = metrics.NewMap("code", metrics.NewInt(0))
+	}
+
+	if p.c.GetExpectFailure() {
+		result.rawOutcome = metrics.NewMap("outcome", metrics.NewInt(0))
+	}
+
 	if p.opts.Validators != nil {
 		result.validationFailure = validators.ValidationFailureMap(p.opts.Validators)
 	}
@@ -374,6 +888,42 @@ func (p *Probe) newResult() *probeResult {
 		result.respBodies = metrics.NewMap("resp", metrics.NewInt(0))
 	}
 
+	if p.c.GetProxyUrl() != "" {
+		result.proxyConnectLatency = p.newLatencyValue()
+	}
+
+	if p.c.GetExportRedirectMetrics() {
+		result.finalHost = metrics.NewMap("host", metrics.NewInt(0))
+	}
+
+	// With happy eyeballs, IP version used varies even without a fallback.
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	if result.ipVersionUsed == nil && p.heDialer != nil {
+		result.ipVersionUsed = metrics.NewMap("ip_version", metrics.NewInt(0))
+	}
+
+	if p.exportTTFB() {
+		result.ttfb = p.newLatencyValue()
+	}
+
+	if p.c.GetExportLatencyBreakdown() {
+		result.dnsLatency = p.newLatencyValue()
+		result.connectLatency = p.newLatencyValue()
+		result.tlsLatency = p.newLatencyValue()
+		result.bodyReadLatency = p.newLatencyValue()
+	}
+
+	if len(p.steps) > 0 {
+		result.stepFailures = metrics.NewMap("step", metrics.NewInt(0))
+		for range p.steps {
+			if p.opts.LatencyDist != nil {
+				result.stepLatency = append(result.stepLatency, p.opts.LatencyDist.Clone())
+			} else {
+				result.stepLatency = append(result.stepLatency, metrics.NewFloat(0))
+			}
+		}
+	}
+
 	return result
 }
 
@@ -388,26 +938,136 @@ func (p *Probe) exportMetrics(ts time.Time, result *probeResult, targetName stri
 		AddLabel("probe", p.name).
 		AddLabel("dst", targetName)
 
+	if p.c.GetHttpProtocol() != configpb.ProbeConf_AUTO {
+		httpProtocol := result.httpProtocol
+		if httpProtocol == "" {
+			httpProtocol = "unknown"
+		}
+		em.AddLabel("http_protocol", httpProtocol)
+	}
+
+	if result.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", result.ipVersionUsed)
+	}
+
 	if result.respBodies != nil {
 		em.AddMetric("resp-body", result.respBodies)
 	}
 
+	if result.ttfb != nil {
+		em.AddMetric("ttfb", result.ttfb)
+	}
+
+	if p.c.GetExportLatencyBreakdown() {
+		em.AddMetric("dns_latency", result.dnsLatency).
+			AddMetric("connect_latency", result.connectLatency).
+			AddMetric("tls_handshake_latency", result.tlsLatency).
+			AddMetric("body_read_latency", result.bodyReadLatency)
+	}
+
 	if p.c.GetKeepAlive() {
 		em.AddMetric("connect_event", metrics.NewInt(result.connEvent))
 	}
 
+	if p.opts.ConnectTimeout > 0 {
+		em.AddMetric("connect_timeouts", metrics.NewInt(result.connectTimeouts))
+	}
+
+	if p.c.GetProxyUrl() != "" {
+		em.AddMetric("proxy_errors", metrics.NewInt(result.proxyErrors)).
+			AddMetric("proxy_connect_latency", result.proxyConnectLatency)
+	}
+
+	if p.graphQL {
+		em.AddMetric("graphql_errors", metrics.NewInt(result.graphQLErrors))
+	}
+
+	if p.retries > 0 {
+		em.AddMetric("http_retries_total", metrics.NewInt(result.retries))
+	}
+
+	if p.oauthTS != nil {
+		em.AddMetric("oauth_refresh_failures", metrics.NewInt(result.oauthRefreshFailures))
+	}
+
+	if p.slots != nil {
+		em.AddMetric("skipped_targets", metrics.NewInt(result.skipped))
+	}
+
+	if result.finalHost != nil {
+		em.AddMetric("redirects", metrics.NewInt(result.redirects)).
+			AddMetric("final_host", result.finalHost)
+	}
+
+	if p.opts.SLOLatencyObjective > 0 {
+		em.AddMetric("slo_good_total", metrics.NewInt(result.sloGood)).
+			AddMetric("slo_total", metrics.NewInt(result.total))
+	}
+
 	em.LatencyUnit = p.opts.LatencyUnit
 
 	for _, al := range p.opts.AdditionalLabels {
 		em.AddLabel(al.KeyValueForTarget(targetName))
 	}
 
+	if result.unexpectedStatus != nil {
+		em.AddMetric("unexpected_status", result.unexpectedStatus)
+	}
+
+	if p.successHeader != nil {
+		em.AddMetric("header_mismatch", metrics.NewInt(result.headerMismatch))
+	}
+
 	if result.validationFailure != nil {
 		em.AddMetric("validation_failure", result.validationFailure)
 	}
 
+	if result.stepFailures != nil {
+		em.AddMetric("step_failures", result.stepFailures)
+	}
+
+	if result.rawOutcome != nil {
+		em.AddMetric("raw_outcome", result.rawOutcome)
+	}
+
 	p.opts.LogMetrics(em)
 	dataChan <- em
+
+	for _, sem := range p.stepLatencyEMs(em, result) {
+		p.opts.LogMetrics(sem)
+		dataChan <- sem
+	}
+
+	if result.cert != nil {
+		cem := p.certEM(em, result.cert)
+		p.opts.LogMetrics(cem)
+		dataChan <- cem
+	}
+}
+
+// exportRawLatencies exports the latencies recorded in the last probe run, as
+// separate GAUGE EventMetrics, if export_raw_latency is enabled.
+func (p *Probe) exportRawLatencies(result *probeResult, targetName string, dataChan chan *metrics.EventMetrics) {
+	for _, latency := range result.rawLatencies {
+		em := p.opts.RawLatencyEM(time.Now(), "http", p.name, targetName, latency, nil)
+		p.opts.LogMetrics(em)
+		dataChan <- em
+	}
+	result.rawLatencies = result.rawLatencies[:0]
+}
+
+// certEM returns the server certificate's gauges, with the labels of the
+// probe's EventMetrics.
+func (p *Probe) certEM(em *metrics.EventMetrics, ci *certInfo) *metrics.EventMetrics {
+	cem := metrics.NewEventMetrics(em.Timestamp).
+		AddMetric("cert_expiry_seconds", metrics.NewInt(int64(ci.notAfter.Sub(em.Timestamp)/time.Second))).
+		AddMetric("cert_chain_depth", metrics.NewInt(int64(ci.chainDepth)))
+	for _, k := range em.LabelsKeys() {
+		cem.AddLabel(k, em.Label(k))
+	}
+	cem.AddLabel("issuer", ci.issuer)
+	cem.Kind = metrics.GAUGE
+	return cem
 }
 
 func (p *Probe) startForTarget(ctx context.Context, target endpoint.Endpoint, dataChan chan *metrics.EventMetrics) {
@@ -422,36 +1082,102 @@ func (p *Probe) startForTarget(ctx context.Context, target endpoint.Endpoint, da
 	result := p.newResult()
 	req := p.httpRequestForTarget(target, nil)
 
-	ticker := time.NewTicker(p.opts.Interval)
+	ticker := p.opts.NewTicker()
 	defer ticker.Stop()
 
-	for ts := time.Now(); true; ts = <-ticker.C {
+	// Last probe cycle's start and end, for the metrics' timestamp.
+	var cycleStart, cycleEnd time.Time
+
+	// Without a schedule, first cycle runs right away. With a schedule, it
+	// waits for the first tick, so that cycles run only at the scheduled
+	// times.
+	ts := time.Now()
+	if p.opts.Schedule != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case ts = <-ticker.C:
+		}
+	}
+
+	for ; true; ts = <-ticker.C {
 		// Don't run another probe if context is canceled already.
 		if ctxDone(ctx) {
 			return
 		}
 
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
 		// If request is nil (most likely because target resolving failed or it
 		// was an invalid target), skip this probe cycle. Note that request
 		// creation gets retried at a regular interval (stats export interval).
 		if req != nil {
-			p.runProbe(ctx, target, req, result)
+			if p.acquireSlot(ctx) {
+				start := time.Now()
+				if len(p.steps) > 0 {
+					p.runTransaction(ctx, target, req, result)
+				} else {
+					p.runProbe(ctx, target, req, result)
+				}
+				p.releaseSlot()
+				runstats.RecordRun(p.name, start)
+				cycleStart, cycleEnd = start, time.Now()
+				p.exportRawLatencies(result, target.Name, dataChan)
+			} else {
+				result.skipped++
+			}
 		}
 
 		// Export stats if it's the time to do so.
 		runCnt++
 		if (runCnt % p.statsExportFrequency) == 0 {
-			p.exportMetrics(ts, result, target.Name, dataChan)
+			p.exportMetrics(p.opts.MetricTimestamp(ts, cycleStart, cycleEnd), result, target.Name, dataChan)
 
-			// If we are resolving first, this is also a good time to recreate HTTP
-			// request in case target's IP has changed.
-			if p.c.GetResolveFirst() {
+			// If we are resolving first, or using the target's IP in the
+			// request, this is also a good time to recreate HTTP request in case
+			// target's IP has changed.
+			if p.c.GetResolveFirst() || p.targetIPToken {
 				req = p.httpRequestForTarget(target, nil)
 			}
 		}
 	}
 }
 
+// acquireSlot waits for a free slot to run the probe for a target, if
+// max_concurrent_probes is configured. As the probe has to finish within the
+// interval, it waits for at most the interval minus the timeout, and returns
+// false if it doesn't get a slot in time.
+func (p *Probe) acquireSlot(ctx context.Context) bool {
+	if p.slots == nil {
+		return true
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(p.opts.Interval - p.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (p *Probe) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
 func (p *Probe) gapBetweenTargets() time.Duration {
 	interTargetGap := time.Duration(p.c.GetIntervalBetweenTargetsMsec()) * time.Millisecond
 
@@ -572,7 +1298,6 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 		case <-ctx.Done():
 			return
 		case <-targetsUpdateTicker.C:
-			p.updateOauthToken()
 			p.updateTargetsAndStartProbes(ctx, dataChan)
 		}
 	}
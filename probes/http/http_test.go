@@ -16,24 +16,46 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	oauthpb "github.com/cloudprober/cloudprober/common/oauth/proto"
 	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/metrics/testutils"
 	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/targets"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
 )
 
 // The Transport is mocked instead of the Client because Client is not an
@@ -377,3 +399,1917 @@ func TestUpdateTargetsAndStartProbes(t *testing.T) {
 	cancelF()
 	p.wait()
 }
+
+func TestProbeTTFB(t *testing.T) {
+	headerDelay, bodyDelay := 50*time.Millisecond, 100*time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			// Close the connection without sending any response.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		time.Sleep(headerDelay)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(bodyDelay)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, relURL := range []string{"/", "/fail"} {
+		t.Run(relURL, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:     targets.StaticTargets(host),
+				Interval:    2 * time.Second,
+				Timeout:     time.Second,
+				LatencyUnit: time.Millisecond,
+				ProbeConf: &configpb.ProbeConf{
+					RelativeUrl: proto.String(relURL),
+					ExportTtfb:  proto.Bool(true),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			ttfb := result.ttfb.(*metrics.Float).Float64()
+
+			if relURL == "/fail" {
+				if result.total != 1 || result.success != 0 {
+					t.Errorf("Got total=%d, success=%d, want total=1, success=0", result.total, result.success)
+				}
+				if ttfb != 0 {
+					t.Errorf("Got ttfb=%v for failed request, want=0", ttfb)
+				}
+				return
+			}
+
+			if result.success != 1 {
+				t.Fatalf("Got success=%d, want=1", result.success)
+			}
+			// TTFB should include the header delay but not the body delay.
+			if ttfb < float64(headerDelay/time.Millisecond) || ttfb >= float64((headerDelay+bodyDelay)/time.Millisecond) {
+				t.Errorf("Got ttfb=%vms, want >= %v and < %v", ttfb, headerDelay, headerDelay+bodyDelay)
+			}
+		})
+	}
+}
+
+func TestProbeLatencyBreakdown(t *testing.T) {
+	bodyDelay := 20 * time.Millisecond
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(bodyDelay)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	_, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	// Use a hostname, so that the DNS resolution is traced.
+	target := endpoint.Endpoint{Name: "localhost", Port: port}
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:     targets.StaticTargets(target.Name),
+		Interval:    2 * time.Second,
+		Timeout:     time.Second,
+		LatencyUnit: time.Millisecond,
+		LatencyDist: metrics.NewDistribution([]float64{1, 10, 100}),
+		LogMetrics:  func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			Protocol:               configpb.ProbeConf_HTTPS.Enum(),
+			KeepAlive:              proto.Bool(true),
+			ExportLatencyBreakdown: proto.Bool(true),
+			TlsConfig: &tlsconfigpb.TLSConfig{
+				DisableCertValidation: proto.Bool(true),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	// Second run reuses the connection: there is no connection setup.
+	result := p.newResult()
+	for i := 0; i < 2; i++ {
+		p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+	}
+	if result.success != 2 {
+		t.Fatalf("Got success=%d, want=2", result.success)
+	}
+
+	dataChan := make(chan *metrics.EventMetrics, 1)
+	p.exportMetrics(time.Now(), result, target.Name, dataChan)
+	em := <-dataChan
+
+	for name, wantCount := range map[string]int64{
+		"dns_latency":           1,
+		"connect_latency":       1,
+		"tls_handshake_latency": 1,
+		"ttfb":                  2,
+		"body_read_latency":     2,
+	} {
+		d, ok := em.Metric(name).(*metrics.Distribution)
+		if !ok {
+			t.Errorf("Metric %s: %v, want a distribution", name, em.Metric(name))
+			continue
+		}
+		if got := d.Data().Count; got != wantCount {
+			t.Errorf("Metric %s: got count=%d, want=%d", name, got, wantCount)
+		}
+	}
+
+	// Body read includes the body delay.
+	if got := result.bodyReadLatency.(*metrics.Distribution).Data().Sum; got < 2*float64(bodyDelay/time.Millisecond) {
+		t.Errorf("Got body_read_latency sum=%vms, want >= %v", got, 2*bodyDelay)
+	}
+}
+
+func TestProbeHTTPProtocol(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	h1Server := httptest.NewServer(handler)
+	defer h1Server.Close()
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cServer.Close()
+
+	h1TLSServer := httptest.NewTLSServer(handler)
+	defer h1TLSServer.Close()
+
+	h2TLSServer := httptest.NewUnstartedServer(handler)
+	h2TLSServer.EnableHTTP2 = true
+	h2TLSServer.StartTLS()
+	defer h2TLSServer.Close()
+
+	for _, test := range []struct {
+		desc         string
+		server       *httptest.Server
+		protocol     configpb.ProbeConf_ProtocolType
+		httpProtocol configpb.ProbeConf_HTTPProtocol
+		wantSuccess  bool
+		wantProtocol string
+	}{
+		{
+			desc:         "h2c_server_h2c",
+			server:       h2cServer,
+			httpProtocol: configpb.ProbeConf_H2C,
+			wantSuccess:  true,
+			wantProtocol: "HTTP/2.0",
+		},
+		{
+			desc:         "h2c_server_http1",
+			server:       h2cServer,
+			httpProtocol: configpb.ProbeConf_HTTP1,
+			wantSuccess:  true,
+			wantProtocol: "HTTP/1.1",
+		},
+		{
+			desc:         "http1_server_h2c",
+			server:       h1Server,
+			httpProtocol: configpb.ProbeConf_H2C,
+		},
+		{
+			desc:         "h2_tls_server_http2",
+			server:       h2TLSServer,
+			protocol:     configpb.ProbeConf_HTTPS,
+			httpProtocol: configpb.ProbeConf_HTTP2,
+			wantSuccess:  true,
+			wantProtocol: "HTTP/2.0",
+		},
+		{
+			desc:         "h2_tls_server_http1",
+			server:       h2TLSServer,
+			protocol:     configpb.ProbeConf_HTTPS,
+			httpProtocol: configpb.ProbeConf_HTTP1,
+			wantSuccess:  true,
+			wantProtocol: "HTTP/1.1",
+		},
+		{
+			desc:         "http1_tls_server_http2",
+			server:       h1TLSServer,
+			protocol:     configpb.ProbeConf_HTTPS,
+			httpProtocol: configpb.ProbeConf_HTTP2,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			host, portStr, _ := net.SplitHostPort(test.server.Listener.Addr().String())
+			port, _ := strconv.Atoi(portStr)
+			target := endpoint.Endpoint{Name: host, Port: port}
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Protocol:     test.protocol.Enum(),
+					HttpProtocol: test.httpProtocol.Enum(),
+					TlsConfig: &tlsconfigpb.TLSConfig{
+						DisableCertValidation: proto.Bool(true),
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			start := time.Now()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			// Mismatched servers should fail quickly, instead of hanging till
+			// timeout.
+			if elapsed := time.Since(start); elapsed >= time.Second {
+				t.Errorf("Probe took %v, longer than the timeout", elapsed)
+			}
+
+			if result.total != 1 || (result.success == 1) != test.wantSuccess {
+				t.Fatalf("Got total=%d, success=%d, want success: %v", result.total, result.success, test.wantSuccess)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+
+			wantLabel := test.wantProtocol
+			if !test.wantSuccess {
+				wantLabel = "unknown"
+			}
+			if got := em.Label("http_protocol"); got != wantLabel {
+				t.Errorf("http_protocol label=%q, want=%q", got, wantLabel)
+			}
+		})
+	}
+}
+
+func TestInitHTTPProtocolErrors(t *testing.T) {
+	for _, c := range []*configpb.ProbeConf{
+		{Protocol: configpb.ProbeConf_HTTP.Enum(), HttpProtocol: configpb.ProbeConf_HTTP2.Enum()},
+		{Protocol: configpb.ProbeConf_HTTPS.Enum(), HttpProtocol: configpb.ProbeConf_H2C.Enum()},
+		{HttpProtocol: configpb.ProbeConf_H2C.Enum(), ProxyUrl: proto.String("http://proxy:3128")},
+	} {
+		p := &Probe{}
+		err := p.Init("http_test", &options.Options{
+			Targets:   targets.StaticTargets("test.com"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: c,
+		})
+		if err == nil {
+			t.Errorf("Expected error for config: %v", c)
+		}
+	}
+}
+
+func TestProbeCertMetrics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	cert := tlsServer.Certificate()
+
+	for _, test := range []struct {
+		desc     string
+		server   *httptest.Server
+		protocol configpb.ProbeConf_ProtocolType
+		wantCert bool
+	}{
+		{
+			desc:     "https",
+			server:   tlsServer,
+			protocol: configpb.ProbeConf_HTTPS,
+			wantCert: true,
+		},
+		{
+			desc:   "http",
+			server: server,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			host, portStr, _ := net.SplitHostPort(test.server.Listener.Addr().String())
+			port, _ := strconv.Atoi(portStr)
+			target := endpoint.Endpoint{Name: host, Port: port}
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Protocol:          test.protocol.Enum(),
+					ExportCertMetrics: proto.Bool(true),
+					TlsConfig: &tlsconfigpb.TLSConfig{
+						DisableCertValidation: proto.Bool(true),
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			if result.success != 1 {
+				t.Fatalf("Got success=%d, want=1", result.success)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 2)
+			ts := time.Now()
+			p.exportMetrics(ts, result, target.Name, dataChan)
+			<-dataChan
+
+			if !test.wantCert {
+				if len(dataChan) != 0 {
+					t.Errorf("Got unexpected certificate metrics: %s", (<-dataChan).String())
+				}
+				return
+			}
+			if len(dataChan) != 1 {
+				t.Fatalf("Got %d certificate metrics, want=1", len(dataChan))
+			}
+			cem := <-dataChan
+
+			if cem.Kind != metrics.GAUGE {
+				t.Errorf("Certificate metrics kind=%v, want=GAUGE", cem.Kind)
+			}
+			if got, want := cem.Metric("cert_expiry_seconds").(metrics.NumValue).Int64(), int64(cert.NotAfter.Sub(ts)/time.Second); got != want {
+				t.Errorf("cert_expiry_seconds=%d, want=%d", got, want)
+			}
+			if got := cem.Metric("cert_chain_depth").(metrics.NumValue).Int64(); got != 1 {
+				t.Errorf("cert_chain_depth=%d, want=1", got)
+			}
+			if got, want := cem.Label("issuer"), cert.Issuer.String(); got != want {
+				t.Errorf("issuer label=%q, want=%q", got, want)
+			}
+			if got := cem.Label("dst"); got != host {
+				t.Errorf("dst label=%q, want=%q", got, host)
+			}
+		})
+	}
+}
+
+// countingListener counts the connections accepted by the wrapped listener.
+type countingListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns int
+}
+
+func (cl *countingListener) Accept() (net.Conn, error) {
+	conn, err := cl.Listener.Accept()
+	if err == nil {
+		cl.mu.Lock()
+		cl.conns++
+		cl.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (cl *countingListener) count() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.conns
+}
+
+func TestProbeKeepAlive(t *testing.T) {
+	const numRuns = 3
+
+	for _, test := range []struct {
+		keepAlive bool
+		https     bool
+		wantConns int
+	}{
+		{keepAlive: true, wantConns: 1},
+		{keepAlive: false, wantConns: numRuns},
+		{keepAlive: true, https: true, wantConns: 1},
+		{keepAlive: false, https: true, wantConns: numRuns},
+	} {
+		t.Run(fmt.Sprintf("keep_alive:%v,https:%v", test.keepAlive, test.https), func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+			cl := &countingListener{Listener: ts.Listener}
+			ts.Listener = cl
+			protocol := configpb.ProbeConf_HTTP
+			if test.https {
+				protocol = configpb.ProbeConf_HTTPS
+				ts.StartTLS()
+			} else {
+				ts.Start()
+			}
+			defer ts.Close()
+
+			host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+			port, _ := strconv.Atoi(portStr)
+			target := endpoint.Endpoint{Name: host, Port: port}
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Protocol:  protocol.Enum(),
+					KeepAlive: proto.Bool(test.keepAlive),
+					TlsConfig: &tlsconfigpb.TLSConfig{
+						DisableCertValidation: proto.Bool(true),
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			for i := 0; i < numRuns; i++ {
+				p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			}
+
+			if result.success != numRuns {
+				t.Errorf("Got success=%d, want=%d", result.success, numRuns)
+			}
+			if got := cl.count(); got != test.wantConns {
+				t.Errorf("Got connections=%d, want=%d", got, test.wantConns)
+			}
+			if test.keepAlive && result.connEvent != int64(test.wantConns) {
+				t.Errorf("Got connect events=%d, want=%d", result.connEvent, test.wantConns)
+			}
+		})
+	}
+}
+
+func TestProbeHostAndSNIFromLabels(t *testing.T) {
+	// Server routes requests based on SNI: Host header should match the server
+	// name, with "default.example.com" as the default virtual host. Mismatched
+	// requests get "421 Misdirected Request".
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sni := r.TLS.ServerName
+		if sni == "" {
+			sni = "default.example.com"
+		}
+		if r.Host != sni {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			return
+		}
+		w.Write([]byte(sni))
+	}))
+	ts.StartTLS()
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:    targets.StaticTargets(host),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			Protocol:  configpb.ProbeConf_HTTPS.Enum(),
+			KeepAlive: proto.Bool(true),
+			Headers: []*configpb.ProbeConf_Header{
+				{Name: proto.String("Host"), Value: proto.String("default.example.com")},
+			},
+			HostHeaderLabel: proto.String("host"),
+			SniLabel:        proto.String("sni"),
+			TlsConfig: &tlsconfigpb.TLSConfig{
+				DisableCertValidation: proto.Bool(true),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	for _, test := range []struct {
+		labels   map[string]string
+		wantCode string
+	}{
+		{
+			labels:   map[string]string{"host": "a.example.com", "sni": "a.example.com"},
+			wantCode: "200",
+		},
+		{
+			labels:   map[string]string{"host": "b.example.com", "sni": "b.example.com"},
+			wantCode: "200",
+		},
+		{
+			// No labels: static Host header, no SNI.
+			wantCode: "200",
+		},
+		{
+			labels:   map[string]string{"host": "b.example.com", "sni": "a.example.com"},
+			wantCode: "421",
+		},
+		{
+			labels:   map[string]string{"host": "c.example.com"},
+			wantCode: "421",
+		},
+	} {
+		t.Run(fmt.Sprintf("%v", test.labels), func(t *testing.T) {
+			target := endpoint.Endpoint{Name: host, Port: port, Labels: test.labels}
+
+			// Run twice to make sure that connections are not reused across
+			// server names.
+			result := p.newResult()
+			for i := 0; i < 2; i++ {
+				p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			}
+
+			if got := result.respCodes.GetKey(test.wantCode); got == nil || got.Int64() != 2 {
+				t.Errorf("Want 2 responses with code %s, got response codes: %s", test.wantCode, result.respCodes.String())
+			}
+		})
+	}
+}
+
+// writeClientKeyPair generates a self-signed client certificate with the
+// given common name, and writes it and its key to the given files.
+func writeClientKeyPair(t *testing.T, cn, certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error marshaling key: %v", err)
+	}
+	for f, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: certDER},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := ioutil.WriteFile(f, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("Error writing file %s: %v", f, err)
+		}
+	}
+}
+
+func TestProbePerTargetClientCert(t *testing.T) {
+	// Server responds with the client certificate's common name.
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	certDir := t.TempDir()
+	for _, id := range []string{"alice", "bob"} {
+		writeClientKeyPair(t, id, filepath.Join(certDir, id+".crt"), filepath.Join(certDir, id+".key"))
+	}
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:    targets.StaticTargets(host),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			Protocol:                configpb.ProbeConf_HTTPS.Enum(),
+			KeepAlive:               proto.Bool(true),
+			ExportResponseAsMetrics: proto.Bool(true),
+			TlsConfig: &tlsconfigpb.TLSConfig{
+				DisableCertValidation: proto.Bool(true),
+				TlsCertFile:           proto.String(filepath.Join(certDir, "@target.label.identity@.crt")),
+				TlsKeyFile:            proto.String(filepath.Join(certDir, "@target.label.identity@.key")),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	for _, test := range []struct {
+		identity    string
+		wantSuccess int64
+	}{
+		{identity: "alice", wantSuccess: 2},
+		{identity: "bob", wantSuccess: 2},
+		{identity: "carol"}, // No certificate files.
+	} {
+		t.Run(test.identity, func(t *testing.T) {
+			target := endpoint.Endpoint{Name: host, Port: port, Labels: map[string]string{"identity": test.identity}}
+
+			// Run twice to make sure that connections are not reused across
+			// client identities.
+			result := p.newResult()
+			for i := 0; i < 2; i++ {
+				p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			}
+
+			if result.success != test.wantSuccess {
+				t.Errorf("Got success=%d, want=%d", result.success, test.wantSuccess)
+			}
+			if test.wantSuccess == 0 {
+				return
+			}
+			if got := result.respBodies.GetKey(test.identity); got == nil || got.Int64() != 2 {
+				t.Errorf("Want 2 responses for %s, got responses: %s", test.identity, result.respBodies.String())
+			}
+		})
+	}
+}
+
+func TestInitPerTargetClientCertErrors(t *testing.T) {
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:  targets.StaticTargets("test.com"),
+		Interval: 2 * time.Second,
+		Timeout:  time.Second,
+		ProbeConf: &configpb.ProbeConf{
+			TlsConfig: &tlsconfigpb.TLSConfig{
+				TlsCertFile: proto.String("/certs/@target.name@.crt"),
+				TlsKeyFile:  proto.String("/certs/@target.name@.key"),
+			},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for per-target client certificates with HTTP, got nil")
+	}
+}
+
+func TestProbePortFromLabel(t *testing.T) {
+	// Two servers, responding with different status codes, so that we can
+	// tell which one served the request.
+	var ports []int
+	for _, code := range []int{200, 202} {
+		code := code
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		defer ts.Close()
+
+		_, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+		port, _ := strconv.Atoi(portStr)
+		ports = append(ports, port)
+	}
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:    targets.StaticTargets("127.0.0.1"),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			Port:      proto.Int32(int32(ports[0])),
+			PortLabel: proto.String("port"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	for _, test := range []struct {
+		labels   map[string]string
+		wantCode string
+	}{
+		{
+			labels:   map[string]string{"port": strconv.Itoa(ports[0])},
+			wantCode: "200",
+		},
+		{
+			labels:   map[string]string{"port": strconv.Itoa(ports[1])},
+			wantCode: "202",
+		},
+		{
+			// Invalid port label: configured port is used.
+			labels:   map[string]string{"port": "99999"},
+			wantCode: "200",
+		},
+		{
+			// No label: configured port is used.
+			wantCode: "200",
+		},
+	} {
+		t.Run(fmt.Sprintf("%v", test.labels), func(t *testing.T) {
+			target := endpoint.Endpoint{Name: "127.0.0.1", Labels: test.labels}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if got := result.respCodes.GetKey(test.wantCode); got == nil || got.Int64() != 1 {
+				t.Errorf("Want 1 response with code %s, got response codes: %s", test.wantCode, result.respCodes.String())
+			}
+		})
+	}
+}
+
+func TestProbeWithBodyFile(t *testing.T) {
+	// Body larger than the default buffer sizes, to make sure that it's
+	// streamed in multiple reads.
+	testBody := bytes.Repeat([]byte("cloudprober-0123456789"), 10000)
+	bodyFile, err := ioutil.TempFile("", "http_body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bodyFile.Name())
+	bodyFile.Write(testBody)
+	bodyFile.Close()
+
+	type reqInfo struct {
+		body             []byte
+		contentLength    int64
+		transferEncoding []string
+	}
+	reqs := make(chan reqInfo, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		reqs <- reqInfo{b, r.ContentLength, r.TransferEncoding}
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, chunked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("chunked:%v", chunked), func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:  targets.StaticTargets(host),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					Method:        configpb.ProbeConf_POST.Enum(),
+					BodyFile:      proto.String(bodyFile.Name()),
+					ChunkedUpload: proto.Bool(chunked),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			req := p.httpRequestForTarget(target, nil)
+
+			// Every run should send the complete file.
+			for i := 0; i < 2; i++ {
+				p.runProbe(context.Background(), target, req, result)
+
+				ri := <-reqs
+				if !bytes.Equal(ri.body, testBody) {
+					t.Errorf("Run %d: server got body of length %d, want %d", i, len(ri.body), len(testBody))
+				}
+				wantContentLength, wantTE := int64(len(testBody)), []string(nil)
+				if chunked {
+					wantContentLength, wantTE = -1, []string{"chunked"}
+				}
+				if ri.contentLength != wantContentLength || !reflect.DeepEqual(ri.transferEncoding, wantTE) {
+					t.Errorf("Run %d: got content-length=%d, transfer-encoding=%v, want %d, %v", i, ri.contentLength, ri.transferEncoding, wantContentLength, wantTE)
+				}
+			}
+			if result.success != 2 {
+				t.Errorf("Got success=%d, want=2", result.success)
+			}
+		})
+	}
+}
+
+func TestProbeWithGzipBody(t *testing.T) {
+	largeBody := bytes.Repeat([]byte("cloudprober-0123456789"), 10000)
+	bodyFile, err := ioutil.TempFile("", "http_body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bodyFile.Name())
+	bodyFile.Write(largeBody)
+	bodyFile.Close()
+
+	type reqInfo struct {
+		body            []byte
+		contentEncoding string
+		contentLength   int64
+		rawLength       int
+		gzipErr         error
+	}
+	reqs := make(chan reqInfo, 10)
+	var failNext int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := ioutil.ReadAll(r.Body)
+		ri := reqInfo{contentEncoding: r.Header.Get("Content-Encoding"), contentLength: r.ContentLength, rawLength: len(raw)}
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err == nil {
+			ri.body, err = ioutil.ReadAll(zr)
+		}
+		ri.gzipErr = err
+		reqs <- ri
+		if atomic.CompareAndSwapInt32(&failNext, 1, 0) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc     string
+		conf     *configpb.ProbeConf
+		wantBody []byte
+		retry    bool
+	}{
+		{
+			desc:     "small_body",
+			conf:     &configpb.ProbeConf{Body: proto.String("test-body")},
+			wantBody: []byte("test-body"),
+		},
+		{
+			desc:     "large_body",
+			conf:     &configpb.ProbeConf{Body: proto.String(string(largeBody))},
+			wantBody: largeBody,
+		},
+		{
+			desc:     "body_file",
+			conf:     &configpb.ProbeConf{BodyFile: proto.String(bodyFile.Name())},
+			wantBody: largeBody,
+		},
+		{
+			desc:     "retry",
+			conf:     &configpb.ProbeConf{Body: proto.String(string(largeBody)), Retries: proto.Int32(1)},
+			wantBody: largeBody,
+			retry:    true,
+		},
+		{
+			desc:     "retry_body_file",
+			conf:     &configpb.ProbeConf{BodyFile: proto.String(bodyFile.Name()), Retries: proto.Int32(1)},
+			wantBody: largeBody,
+			retry:    true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			test.conf.Method = configpb.ProbeConf_PUT.Enum()
+			test.conf.RequestCompression = configpb.ProbeConf_GZIP.Enum()
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:   targets.StaticTargets(host),
+				Interval:  2 * time.Second,
+				Timeout:   time.Second,
+				ProbeConf: test.conf,
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			req := p.httpRequestForTarget(target, nil)
+
+			wantReqs := 2
+			if test.retry {
+				atomic.StoreInt32(&failNext, 1)
+				wantReqs = 3
+			}
+			for i := 0; i < 2; i++ {
+				p.runProbe(context.Background(), target, req, result)
+			}
+
+			for i := 0; i < wantReqs; i++ {
+				ri := <-reqs
+				if ri.contentEncoding != "gzip" {
+					t.Errorf("Request %d: got Content-Encoding=%q, want gzip", i, ri.contentEncoding)
+				}
+				if ri.gzipErr != nil {
+					t.Fatalf("Request %d: error decompressing the body: %v", i, ri.gzipErr)
+				}
+				if !bytes.Equal(ri.body, test.wantBody) {
+					t.Errorf("Request %d: server got decompressed body of length %d, want %d", i, len(ri.body), len(test.wantBody))
+				}
+				// Content-Length is the compressed size, unless the body file
+				// is streamed.
+				wantContentLength := int64(ri.rawLength)
+				if test.conf.BodyFile != nil {
+					wantContentLength = -1
+				}
+				if ri.contentLength != wantContentLength {
+					t.Errorf("Request %d: got Content-Length=%d, want=%d", i, ri.contentLength, wantContentLength)
+				}
+			}
+			if result.success != 2 {
+				t.Errorf("Got success=%d, want=2", result.success)
+			}
+		})
+	}
+
+	// Compression without a body.
+	err = (&Probe{}).Init("http_test", &options.Options{
+		Targets:  targets.StaticTargets(host),
+		Interval: 2 * time.Second,
+		Timeout:  time.Second,
+		ProbeConf: &configpb.ProbeConf{
+			RequestCompression: configpb.ProbeConf_GZIP.Enum(),
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for request_compression without a body")
+	}
+}
+
+func TestInitBodyFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "http_body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	validFile := dir + "/body"
+	if err := ioutil.WriteFile(validFile, []byte("body"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc string
+		conf *configpb.ProbeConf
+	}{
+		{
+			desc: "missing_file",
+			conf: &configpb.ProbeConf{BodyFile: proto.String(dir + "/missing")},
+		},
+		{
+			desc: "directory",
+			conf: &configpb.ProbeConf{BodyFile: proto.String(dir)},
+		},
+		{
+			desc: "body_and_body_file",
+			conf: &configpb.ProbeConf{BodyFile: proto.String(validFile), Body: proto.String("body")},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:   targets.StaticTargets("test.com"),
+				Interval:  2 * time.Second,
+				ProbeConf: test.conf,
+			})
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestProbeTargetTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:   targets.StaticTargets(host),
+		Interval:  2 * time.Second,
+		Timeout:   100 * time.Millisecond,
+		ProbeConf: &configpb.ProbeConf{},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	for _, test := range []struct {
+		timeoutLabel string
+		wantSuccess  int64
+	}{
+		{wantSuccess: 0},
+		{timeoutLabel: "1000", wantSuccess: 1},
+		{timeoutLabel: "invalid", wantSuccess: 0},
+	} {
+		t.Run(test.timeoutLabel, func(t *testing.T) {
+			target := endpoint.Endpoint{Name: host, Port: port}
+			if test.timeoutLabel != "" {
+				target.Labels = map[string]string{options.TargetTimeoutLabel: test.timeoutLabel}
+			}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			if result.total != 1 || result.success != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total, result.success, test.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestProbeMaxConcurrentProbes(t *testing.T) {
+	p := &Probe{}
+	if err := p.Init("http_test", &options.Options{
+		Targets:             targets.StaticTargets("test.com"),
+		Interval:            300 * time.Millisecond,
+		Timeout:             100 * time.Millisecond,
+		MaxConcurrentProbes: 1,
+		ProbeConf:           &configpb.ProbeConf{},
+		LogMetrics:          func(_ *metrics.EventMetrics) {},
+	}); err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	ctx := context.Background()
+	if !p.acquireSlot(ctx) {
+		t.Fatal("Didn't get a free slot")
+	}
+
+	// Second probe waits for interval - timeout, and is skipped.
+	start := time.Now()
+	if p.acquireSlot(ctx) {
+		t.Fatal("Got a slot, while the only slot is in use")
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Waited for a slot for %v, want around 200ms", elapsed)
+	}
+
+	// Slot freed while waiting.
+	time.AfterFunc(50*time.Millisecond, p.releaseSlot)
+	if !p.acquireSlot(ctx) {
+		t.Error("Didn't get the slot freed while waiting")
+	}
+
+	dataChan := make(chan *metrics.EventMetrics, 1)
+	result := p.newResult()
+	result.skipped = 2
+	p.exportMetrics(time.Now(), result, "test.com", dataChan)
+	em := <-dataChan
+	if got := em.Metric("skipped_targets").(metrics.NumValue).Int64(); got != 2 {
+		t.Errorf("Got skipped_targets=%d, want=2", got)
+	}
+}
+
+func TestProbeConnectTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc                string
+		url                 string
+		dialDelay           time.Duration
+		wantSuccess         int64
+		wantTimeouts        int64
+		wantConnectTimeouts int64
+	}{
+		{desc: "fast", url: "/", wantSuccess: 1},
+		{desc: "slow_response", url: "/slow", wantTimeouts: 1},
+		{desc: "slow_connect", url: "/", dialDelay: 100 * time.Millisecond, wantConnectTimeouts: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:        targets.StaticTargets(host),
+				Interval:       2 * time.Second,
+				Timeout:        200 * time.Millisecond,
+				ConnectTimeout: 50 * time.Millisecond,
+				LogMetrics:     func(*metrics.EventMetrics) {},
+				ProbeConf:      &configpb.ProbeConf{RelativeUrl: proto.String(test.url)},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			// Simulate a slow handshake by delaying the connection setup.
+			dialer := &net.Dialer{
+				Control: func(_, _ string, _ syscall.RawConn) error {
+					time.Sleep(test.dialDelay)
+					return nil
+				},
+			}
+			p.client.Transport.(*http.Transport).DialContext = p.opts.DialContextFunc(dialer)
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			if result.total != 1 || result.success != test.wantSuccess || result.timeouts != test.wantTimeouts || result.connectTimeouts != test.wantConnectTimeouts {
+				t.Errorf("Got total=%d, success=%d, timeouts=%d, connect_timeouts=%d, want total=1, success=%d, timeouts=%d, connect_timeouts=%d", result.total, result.success, result.timeouts, result.connectTimeouts, test.wantSuccess, test.wantTimeouts, test.wantConnectTimeouts)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			if em := <-dataChan; em.Metric("connect_timeouts") == nil {
+				t.Errorf("connect_timeouts metric missing in EventMetrics: %s", em.String())
+			}
+		})
+	}
+}
+
+func TestProbeSuccessStatusCodes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc                 string
+		successStatusCodes   string
+		codes                []int
+		wantSuccess          int64
+		wantUnexpectedStatus string
+	}{
+		{
+			desc:        "unset",
+			codes:       []int{200, 404, 500},
+			wantSuccess: 3,
+		},
+		{
+			desc:                 "ranges",
+			successStatusCodes:   "200-299, 301,404",
+			codes:                []int{200, 204, 301, 302, 404, 500, 500},
+			wantSuccess:          4,
+			wantUnexpectedStatus: "map:code,302:1,500:2",
+		},
+		{
+			desc:                 "single_code",
+			successStatusCodes:   "404",
+			codes:                []int{404, 200},
+			wantSuccess:          1,
+			wantUnexpectedStatus: "map:code,200:1",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					SuccessStatusCodes: proto.String(test.successStatusCodes),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			for _, code := range test.codes {
+				p.url = "/" + strconv.Itoa(code)
+				p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+			}
+
+			if result.success != test.wantSuccess {
+				t.Errorf("Got success=%d, want=%d", result.success, test.wantSuccess)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			var gotUnexpectedStatus string
+			if m := em.Metric("unexpected_status"); m != nil {
+				gotUnexpectedStatus = m.String()
+			}
+			if gotUnexpectedStatus != test.wantUnexpectedStatus {
+				t.Errorf("Got unexpected_status=%q, want=%q", gotUnexpectedStatus, test.wantUnexpectedStatus)
+			}
+		})
+	}
+}
+
+func TestProbeSuccessHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Path is of the form /<code>/<x-health header value>.
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			w.Header().Set("X-Health", parts[1])
+		}
+		code, _ := strconv.Atoi(parts[0])
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc               string
+		successStatusCodes string
+		header             *configpb.ProbeConf_SuccessHeader
+		path               string
+		wantSuccess        int64
+		wantHeaderMismatch int64
+	}{
+		{
+			desc:        "match",
+			header:      &configpb.ProbeConf_SuccessHeader{Name: proto.String("x-health"), Regex: proto.String("^ok$")},
+			path:        "/200/ok",
+			wantSuccess: 1,
+		},
+		{
+			desc:               "mismatch",
+			header:             &configpb.ProbeConf_SuccessHeader{Name: proto.String("x-health"), Regex: proto.String("^ok$")},
+			path:               "/200/degraded",
+			wantHeaderMismatch: 1,
+		},
+		{
+			desc:               "absent",
+			header:             &configpb.ProbeConf_SuccessHeader{Name: proto.String("X-Health")},
+			path:               "/200/",
+			wantHeaderMismatch: 1,
+		},
+		{
+			desc:        "present_no_regex",
+			header:      &configpb.ProbeConf_SuccessHeader{Name: proto.String("X-Health")},
+			path:        "/200/anything",
+			wantSuccess: 1,
+		},
+		{
+			desc:               "header_match_status_mismatch",
+			successStatusCodes: "200",
+			header:             &configpb.ProbeConf_SuccessHeader{Name: proto.String("X-Health"), Regex: proto.String("ok")},
+			path:               "/503/ok",
+		},
+		{
+			desc:               "status_match_header_mismatch",
+			successStatusCodes: "200",
+			header:             &configpb.ProbeConf_SuccessHeader{Name: proto.String("X-Health"), Regex: proto.String("ok")},
+			path:               "/200/bad",
+			wantHeaderMismatch: 1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					SuccessStatusCodes: proto.String(test.successStatusCodes),
+					SuccessHeader:      test.header,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.url = test.path
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.total != 1 || result.success != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total, result.success, test.wantSuccess)
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			if got := em.Metric("header_mismatch").(*metrics.Int).Int64(); got != test.wantHeaderMismatch {
+				t.Errorf("Got header_mismatch=%d, want=%d", got, test.wantHeaderMismatch)
+			}
+		})
+	}
+}
+
+func TestInitSuccessHeaderErrors(t *testing.T) {
+	for _, header := range []*configpb.ProbeConf_SuccessHeader{
+		{Name: proto.String("")},
+		{Name: proto.String("X-Health"), Regex: proto.String("(ok")},
+	} {
+		p := &Probe{}
+		err := p.Init("http_test", &options.Options{
+			Targets:  targets.StaticTargets("test.com"),
+			Interval: 2 * time.Second,
+			Timeout:  time.Second,
+			ProbeConf: &configpb.ProbeConf{
+				SuccessHeader: header,
+			},
+		})
+		if err == nil {
+			t.Errorf("success_header=%v: expected error", header)
+		}
+	}
+}
+
+func TestProbeExpectFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	openPort, _ := strconv.Atoi(portStr)
+
+	closedTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	_, portStr, _ = net.SplitHostPort(closedTS.Listener.Addr().String())
+	closedPort, _ := strconv.Atoi(portStr)
+	closedTS.Close()
+
+	for _, test := range []struct {
+		desc           string
+		port           int
+		wantSuccess    int64
+		wantRawOutcome string
+	}{
+		{
+			desc:           "closed_port",
+			port:           closedPort,
+			wantSuccess:    1,
+			wantRawOutcome: "map:outcome,failure:1",
+		},
+		{
+			desc:           "open_port",
+			port:           openPort,
+			wantRawOutcome: "map:outcome,success:1",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets("127.0.0.1"),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Port:          proto.Int32(int32(test.port)),
+					ExpectFailure: proto.Bool(true),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			target := endpoint.Endpoint{Name: "127.0.0.1"}
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.total != 1 || result.success != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total, result.success, test.wantSuccess)
+			}
+			if got := result.rawOutcome.String(); got != test.wantRawOutcome {
+				t.Errorf("Got raw_outcome=%s, want=%s", got, test.wantRawOutcome)
+			}
+		})
+	}
+}
+
+func TestProbeRetries(t *testing.T) {
+	var mu sync.Mutex
+	var reqCount, failFirst, failCode int
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		reqCount++
+		bodies = append(bodies, string(b))
+		if reqCount <= failFirst {
+			w.WriteHeader(failCode)
+		}
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc          string
+		method        configpb.ProbeConf_Method
+		allowNonIdemp bool
+		retries       int32
+		failFirst     int
+		failCode      int
+		wantSuccess   int64
+		wantRetries   int64
+		wantRequests  int
+	}{
+		{
+			desc:         "recovers_within_retries",
+			retries:      3,
+			failFirst:    2,
+			failCode:     http.StatusServiceUnavailable,
+			wantSuccess:  1,
+			wantRetries:  2,
+			wantRequests: 3,
+		},
+		{
+			desc:         "retries_exhausted",
+			retries:      1,
+			failFirst:    5,
+			failCode:     http.StatusBadGateway,
+			wantRetries:  1,
+			wantRequests: 2,
+		},
+		{
+			desc:         "non_retryable_status",
+			retries:      3,
+			failFirst:    5,
+			failCode:     http.StatusNotFound,
+			wantRequests: 1,
+		},
+		{
+			desc:         "non_idempotent",
+			method:       configpb.ProbeConf_POST,
+			retries:      3,
+			failFirst:    1,
+			failCode:     http.StatusServiceUnavailable,
+			wantRequests: 1,
+		},
+		{
+			desc:          "non_idempotent_allowed",
+			method:        configpb.ProbeConf_POST,
+			allowNonIdemp: true,
+			retries:       3,
+			failFirst:     1,
+			failCode:      http.StatusServiceUnavailable,
+			wantSuccess:   1,
+			wantRetries:   1,
+			wantRequests:  2,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			mu.Lock()
+			reqCount, failFirst, failCode, bodies = 0, test.failFirst, test.failCode, nil
+			mu.Unlock()
+
+			// Send a body with POST requests, to verify that it's sent with
+			// every attempt.
+			var body string
+			if test.method == configpb.ProbeConf_POST {
+				body = "test-body"
+			}
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf: &configpb.ProbeConf{
+					Method:             test.method.Enum(),
+					Body:               proto.String(body),
+					SuccessStatusCodes: proto.String("200-299"),
+					Retries:            proto.Int32(test.retries),
+					RetryNonIdempotent: proto.Bool(test.allowNonIdemp),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.total != 1 || result.success != test.wantSuccess || result.retries != test.wantRetries {
+				t.Errorf("Got total=%d, success=%d, retries=%d, want total=1, success=%d, retries=%d", result.total, result.success, result.retries, test.wantSuccess, test.wantRetries)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if reqCount != test.wantRequests {
+				t.Errorf("Got %d requests, want %d", reqCount, test.wantRequests)
+			}
+			for _, b := range bodies {
+				if b != body {
+					t.Errorf("Got request body=%q, want=%q", b, body)
+				}
+			}
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			m := em.Metric("http_retries_total")
+			if (m != nil) != (p.retries > 0) {
+				t.Errorf("Got http_retries_total=%v, want it exported=%v", m, p.retries > 0)
+			}
+		})
+	}
+}
+
+func TestProbeRedirects(t *testing.T) {
+	var mu sync.Mutex
+	var reqCount int
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqCount++
+		mu.Unlock()
+
+		// /hops/N redirects to /hops/N-1, till N is 0. /cross redirects to
+		// the same server through a different host name.
+		if r.URL.Path == "/cross" {
+			_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+			http.Redirect(w, r, "http://localhost:"+port+"/hops/0", http.StatusFound)
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+		if n > 0 {
+			http.Redirect(w, r, "/hops/"+strconv.Itoa(n-1), http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc          string
+		url           string
+		noFollow      bool
+		maxRedirects  int32
+		noCrossHost   bool
+		wantSuccess   int64
+		wantRedirects int64
+		wantHost      string
+		wantRequests  int
+	}{
+		{
+			desc:          "followed",
+			url:           "/hops/3",
+			wantSuccess:   1,
+			wantRedirects: 3,
+			wantHost:      host,
+			wantRequests:  4,
+		},
+		{
+			desc:         "not_followed",
+			url:          "/hops/3",
+			noFollow:     true,
+			wantHost:     host,
+			wantRequests: 1,
+		},
+		{
+			desc:         "too_many_redirects",
+			url:          "/hops/3",
+			maxRedirects: 2,
+			wantRequests: 3,
+		},
+		{
+			desc:          "cross_host",
+			url:           "/cross",
+			wantSuccess:   1,
+			wantRedirects: 1,
+			wantHost:      "localhost",
+			wantRequests:  2,
+		},
+		{
+			desc:         "cross_host_not_allowed",
+			url:          "/cross",
+			noCrossHost:  true,
+			wantRequests: 1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			mu.Lock()
+			reqCount = 0
+			mu.Unlock()
+
+			conf := &configpb.ProbeConf{
+				RelativeUrl:             proto.String(test.url),
+				SuccessStatusCodes:      proto.String("200-299"),
+				FollowRedirects:         proto.Bool(!test.noFollow),
+				AllowCrossHostRedirects: proto.Bool(!test.noCrossHost),
+				ExportRedirectMetrics:   proto.Bool(true),
+				// Redirect policy failures should not be retried.
+				Retries: proto.Int32(2),
+			}
+			if test.maxRedirects != 0 {
+				conf.MaxRedirects = proto.Int32(test.maxRedirects)
+			}
+
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:    targets.StaticTargets(host),
+				Interval:   2 * time.Second,
+				Timeout:    time.Second,
+				LogMetrics: func(*metrics.EventMetrics) {},
+				ProbeConf:  conf,
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.total != 1 || result.success != test.wantSuccess || result.redirects != test.wantRedirects {
+				t.Errorf("Got total=%d, success=%d, redirects=%d, want total=1, success=%d, redirects=%d", result.total, result.success, result.redirects, test.wantSuccess, test.wantRedirects)
+			}
+
+			mu.Lock()
+			if reqCount != test.wantRequests {
+				t.Errorf("Got %d requests, want %d", reqCount, test.wantRequests)
+			}
+			mu.Unlock()
+
+			dataChan := make(chan *metrics.EventMetrics, 1)
+			p.exportMetrics(time.Now(), result, target.Name, dataChan)
+			em := <-dataChan
+			if em.Metric("redirects") == nil {
+				t.Errorf("redirects metric not exported: %s", em.String())
+			}
+			finalHost := em.Metric("final_host").(*metrics.Map)
+			var gotHosts []string
+			for _, k := range finalHost.Keys() {
+				if finalHost.GetKey(k).Int64() > 0 {
+					gotHosts = append(gotHosts, k)
+				}
+			}
+			var wantHosts []string
+			if test.wantHost != "" {
+				wantHosts = []string{test.wantHost}
+			}
+			if !reflect.DeepEqual(gotHosts, wantHosts) {
+				t.Errorf("Got final hosts=%v, want=%v", gotHosts, wantHosts)
+			}
+		})
+	}
+}
+
+func TestInitMaxRedirectsError(t *testing.T) {
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:   targets.StaticTargets("test.com"),
+		Interval:  2 * time.Second,
+		Timeout:   time.Second,
+		ProbeConf: &configpb.ProbeConf{MaxRedirects: proto.Int32(-1)},
+	})
+	if err == nil {
+		t.Error("Expected error for negative max_redirects, got nil")
+	}
+}
+
+func TestProbeOAuthToken(t *testing.T) {
+	var mu sync.Mutex
+	var tokenErr bool
+	var issued int
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if tokenErr {
+			http.Error(w, `{"error": "server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		issued++
+		w.Header().Set("Content-Type", "application/json")
+		// Tokens expiring within 10s are refreshed, i.e. for every request.
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 5}`, issued)
+	}))
+	defer tokenServer.Close()
+
+	var gotAuth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:    targets.StaticTargets(host),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			OauthConfig: &oauthpb.Config{
+				Type: &oauthpb.Config_ClientCredentials{
+					ClientCredentials: &oauthpb.ClientCredentials{
+						TokenUrl:     proto.String(tokenServer.URL),
+						ClientId:     proto.String("test-client"),
+						ClientSecret: proto.String("test-secret"),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	result := p.newResult()
+	req := p.httpRequestForTarget(target, nil)
+	p.runProbe(context.Background(), target, req, result)
+	p.runProbe(context.Background(), target, req, result)
+
+	// Token refresh fails now, requests should use the last token.
+	mu.Lock()
+	tokenErr = true
+	mu.Unlock()
+	p.runProbe(context.Background(), target, req, result)
+
+	// Token from Init is refreshed for the first request.
+	wantAuth := []string{"Bearer token-2", "Bearer token-3", "Bearer token-3"}
+	mu.Lock()
+	if !reflect.DeepEqual(gotAuth, wantAuth) {
+		t.Errorf("Got Authorization headers=%v, want=%v", gotAuth, wantAuth)
+	}
+	mu.Unlock()
+
+	if result.total != 3 || result.success != 3 || result.oauthRefreshFailures != 1 {
+		t.Errorf("Got total=%d, success=%d, oauthRefreshFailures=%d, want total=3, success=3, oauthRefreshFailures=1", result.total, result.success, result.oauthRefreshFailures)
+	}
+
+	dataChan := make(chan *metrics.EventMetrics, 1)
+	p.exportMetrics(time.Now(), result, target.Name, dataChan)
+	em := <-dataChan
+	if m := em.Metric("oauth_refresh_failures"); m == nil || m.(metrics.NumValue).Int64() != 1 {
+		t.Errorf("Got oauth_refresh_failures=%v, want=1", m)
+	}
+}
+
+func TestInitSuccessStatusCodesErrors(t *testing.T) {
+	for _, codes := range []string{"200-", "abc", "300-200", "200,,404"} {
+		p := &Probe{}
+		err := p.Init("http_test", &options.Options{
+			Targets:  targets.StaticTargets("test.com"),
+			Interval: 2 * time.Second,
+			Timeout:  time.Second,
+			ProbeConf: &configpb.ProbeConf{
+				SuccessStatusCodes: proto.String(codes),
+			},
+		})
+		if err == nil {
+			t.Errorf("success_status_codes=%q: expected error", codes)
+		}
+	}
+}
+
+func TestRunOnDemand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	p := &Probe{}
+	err := p.Init("http_test", &options.Options{
+		Targets:    targets.StaticTargets(host),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf: &configpb.ProbeConf{
+			SuccessStatusCodes: proto.String("200"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	for _, test := range []struct {
+		code    int
+		wantErr string
+	}{
+		{code: 200},
+		{code: 503, wantErr: "unexpected status code: 503"},
+	} {
+		t.Run(strconv.Itoa(test.code), func(t *testing.T) {
+			p.url = "/" + strconv.Itoa(test.code)
+			latency, err := p.RunOnDemand(context.Background(), target)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("RunOnDemand(): got error %v, want: %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunOnDemand(): unexpected error: %v", err)
+			}
+			if latency <= 0 {
+				t.Errorf("RunOnDemand(): got latency %v, want > 0", latency)
+			}
+		})
+	}
+}
+
+func TestStartForTargetWithSchedule(t *testing.T) {
+	var numReqs int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&numReqs, 1)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	schedule, err := options.NewSchedule("* * * * *", time.UTC)
+	if err != nil {
+		t.Fatalf("Error creating schedule: %v", err)
+	}
+
+	p := &Probe{}
+	err = p.Init("http_test", &options.Options{
+		Targets:             targets.StaticTargets(host),
+		Interval:            time.Hour,
+		Timeout:             time.Second,
+		StatsExportInterval: time.Hour,
+		Schedule:            schedule,
+		LogMetrics:          func(*metrics.EventMetrics) {},
+		ProbeConf:           &configpb.ProbeConf{},
+	})
+	if err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	// With a schedule, probe should wait for the first tick (an hour away)
+	// instead of running at the start.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.startForTarget(ctx, target, make(chan *metrics.EventMetrics, 10))
+
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt64(&numReqs); n != 0 {
+		t.Errorf("Got %d requests before the first tick, want 0", n)
+	}
+}
+
+func TestProbeRawLatencyAndSLO(t *testing.T) {
+	p := &Probe{}
+	if err := p.Init("http_test", &options.Options{
+		Targets:             targets.StaticTargets("test.com"),
+		Interval:            2 * time.Second,
+		Timeout:             time.Second,
+		LatencyUnit:         time.Millisecond,
+		LatencyMetricName:   "latency",
+		ExportRawLatency:    true,
+		SLOLatencyObjective: time.Second,
+		ProbeConf:           &configpb.ProbeConf{RequestsPerProbe: proto.Int32(2)},
+		LogMetrics:          func(*metrics.EventMetrics) {},
+	}); err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+	p.client.Transport = newTestTransport()
+
+	// Second target's requests fail.
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	for _, target := range []endpoint.Endpoint{{Name: "test.com"}, {Name: "fail-test.com"}} {
+		result := p.newResult()
+		p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+		p.exportRawLatencies(result, target.Name, dataChan)
+
+		var wantRaw, wantGood int64
+		if target.Name == "test.com" {
+			wantRaw, wantGood = 2, 2
+		}
+		for i := int64(0); i < wantRaw; i++ {
+			em := <-dataChan
+			if em.Kind != metrics.GAUGE || em.Metric("latency_raw") == nil || em.Label("dst") != target.Name {
+				t.Errorf("Got unexpected raw latency EventMetrics: %s", em.String())
+			}
+		}
+
+		p.exportMetrics(time.Now(), result, target.Name, dataChan)
+		em := <-dataChan
+		if got := em.Metric("slo_good_total").(metrics.NumValue).Int64(); got != wantGood {
+			t.Errorf("Target(%s): got slo_good_total=%d, want=%d", target.Name, got, wantGood)
+		}
+		if got := em.Metric("slo_total").(metrics.NumValue).Int64(); got != 2 {
+			t.Errorf("Target(%s): got slo_total=%d, want=2", target.Name, got)
+		}
+	}
+}
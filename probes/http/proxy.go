@@ -0,0 +1,66 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyFunc returns the transport's Proxy function for the proxy_url. HTTP
+// and HTTPS proxies are used through CONNECT for the HTTPS targets, and SOCKS5
+// proxies through the transport's dialer. Credentials in the URL are used to
+// authenticate with the proxy.
+func proxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing proxy URL (%s): %v", proxyURL, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy URL scheme (%s), should be one of http, https and socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL (%s) has no host", u.Redacted())
+	}
+
+	return http.ProxyURL(u), nil
+}
+
+// isProxyError returns true if the error returned by http.Client indicates
+// that we couldn't connect to, or authenticate with, the proxy, as opposed to
+// the target failures.
+func isProxyError(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		// CONNECT failures are returned as the status text.
+		return strings.HasSuffix(err.Error(), http.StatusText(http.StatusProxyAuthRequired))
+	}
+
+	// Errors connecting to the proxy are wrapped as the proxyconnect errors.
+	if opErr.Op == "proxyconnect" {
+		return true
+	}
+
+	// SOCKS handshake errors, of which only the authentication errors are
+	// proxy errors; others are the proxy's failures to reach the target.
+	return strings.HasPrefix(opErr.Op, "socks") && strings.Contains(opErr.Err.Error(), "authentication")
+}
@@ -0,0 +1,325 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/golang/protobuf/proto"
+)
+
+// Target name that doesn't resolve, so that requests can succeed only through
+// the proxies, which send all the requests to the backend.
+const proxyTestTarget = "cloudprober-test.invalid"
+
+// testProxy is an HTTP proxy that sends all requests, including CONNECT
+// tunnels, to the backend.
+type testProxy struct {
+	backend  string
+	auth     string // Expected Proxy-Authorization header, if not empty.
+	requests int32
+}
+
+func (tp *testProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if tp.auth != "" && r.Header.Get("Proxy-Authorization") != tp.auth {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	atomic.AddInt32(&tp.requests, 1)
+
+	if r.Method == http.MethodConnect {
+		conn, err := net.Dial("tcp", tp.backend)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		clientConn, _, _ := w.(http.Hijacker).Hijack()
+		pipe(clientConn, conn)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.URL.Host = tp.backend
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func pipe(c1, c2 net.Conn) {
+	go func() {
+		io.Copy(c1, c2)
+		c1.Close()
+	}()
+	io.Copy(c2, c1)
+	c2.Close()
+}
+
+// startSOCKS5Proxy starts a SOCKS5 proxy that connects all the clients to the
+// backend. If user is not empty, proxy requires username/password
+// authentication.
+func startSOCKS5Proxy(t *testing.T, backend, user, password string, requests *int32) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	readBytes := func(c net.Conn, n int) []byte {
+		b := make([]byte, n)
+		io.ReadFull(c, b)
+		return b
+	}
+
+	handle := func(c net.Conn) {
+		defer c.Close()
+
+		// Greeting: version, number of methods, methods.
+		hdr := readBytes(c, 2)
+		readBytes(c, int(hdr[1]))
+		if user == "" {
+			c.Write([]byte{5, 0})
+		} else {
+			c.Write([]byte{5, 2})
+			// Username/password: version, ulen, user, plen, password.
+			ulen := readBytes(c, 2)[1]
+			u := string(readBytes(c, int(ulen)))
+			pw := string(readBytes(c, int(readBytes(c, 1)[0])))
+			if u != user || pw != password {
+				c.Write([]byte{1, 1})
+				return
+			}
+			c.Write([]byte{1, 0})
+		}
+
+		// Request: version, command, reserved, address type, address, port.
+		req := readBytes(c, 4)
+		switch req[3] {
+		case 1:
+			readBytes(c, 4)
+		case 3:
+			readBytes(c, int(readBytes(c, 1)[0]))
+		case 4:
+			readBytes(c, 16)
+		}
+		readBytes(c, 2)
+
+		conn, err := net.Dial("tcp", backend)
+		if err != nil {
+			c.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		atomic.AddInt32(requests, 1)
+		c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		pipe(c, conn)
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(c)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func runProxyTestProbe(t *testing.T, conf *configpb.ProbeConf) *probeResult {
+	t.Helper()
+
+	p := &Probe{}
+	if err := p.Init("http_proxy_test", &options.Options{
+		Targets:    targets.StaticTargets(proxyTestTarget),
+		Interval:   2 * time.Second,
+		Timeout:    time.Second,
+		LogMetrics: func(*metrics.EventMetrics) {},
+		ProbeConf:  conf,
+	}); err != nil {
+		t.Fatalf("Error while initializing probe: %v", err)
+	}
+
+	target := endpoint.Endpoint{Name: proxyTestTarget}
+	result := p.newResult()
+	p.runProbe(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+	dataChan := make(chan *metrics.EventMetrics, 1)
+	p.exportMetrics(time.Now(), result, target.Name, dataChan)
+	em := <-dataChan
+	if got := em.Metric("proxy_errors").(*metrics.Int).Int64(); got != result.proxyErrors {
+		t.Errorf("Got proxy_errors metric=%d, want=%d", got, result.proxyErrors)
+	}
+	return result
+}
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestProbeThroughProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	tlsBackend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsBackend.Close()
+
+	httpProxy := &testProxy{backend: backend.Listener.Addr().String()}
+	httpProxyServer := httptest.NewServer(httpProxy)
+	defer httpProxyServer.Close()
+
+	authProxy := &testProxy{backend: tlsBackend.Listener.Addr().String(), auth: basicAuth("user", "pass")}
+	authProxyServer := httptest.NewServer(authProxy)
+	defer authProxyServer.Close()
+
+	var socksRequests int32
+	socksProxy := startSOCKS5Proxy(t, backend.Listener.Addr().String(), "user", "pass", &socksRequests)
+
+	for _, test := range []struct {
+		desc     string
+		conf     *configpb.ProbeConf
+		requests *int32
+	}{
+		{
+			desc: "http_proxy",
+			conf: &configpb.ProbeConf{
+				ProxyUrl: proto.String(httpProxyServer.URL),
+			},
+			requests: &httpProxy.requests,
+		},
+		{
+			desc: "http_proxy_connect_with_auth",
+			conf: &configpb.ProbeConf{
+				Protocol:  configpb.ProbeConf_HTTPS.Enum(),
+				ProxyUrl:  proto.String("http://user:pass@" + authProxyServer.Listener.Addr().String()),
+				TlsConfig: &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+			},
+			requests: &authProxy.requests,
+		},
+		{
+			desc: "socks5_proxy_with_auth",
+			conf: &configpb.ProbeConf{
+				ProxyUrl: proto.String("socks5://user:pass@" + socksProxy),
+			},
+			requests: &socksRequests,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			before := atomic.LoadInt32(test.requests)
+			result := runProxyTestProbe(t, test.conf)
+
+			if result.total != 1 || result.success != 1 || result.proxyErrors != 0 {
+				t.Errorf("Got total=%d, success=%d, proxy_errors=%d, want 1, 1, 0", result.total, result.success, result.proxyErrors)
+			}
+			if got := atomic.LoadInt32(test.requests) - before; got != 1 {
+				t.Errorf("Got %d requests through the proxy, want 1", got)
+			}
+			if got := result.proxyConnectLatency.(*metrics.Float).Float64(); got <= 0 {
+				t.Errorf("Got proxy connect latency=%v, want > 0", got)
+			}
+		})
+	}
+}
+
+func TestProbeProxyErrors(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	authProxyServer := httptest.NewServer(&testProxy{backend: backend.Listener.Addr().String(), auth: basicAuth("user", "pass")})
+	defer authProxyServer.Close()
+
+	var socksRequests int32
+	socksProxy := startSOCKS5Proxy(t, backend.Listener.Addr().String(), "user", "pass", &socksRequests)
+
+	// Find an unused port.
+	ln, _ := net.Listen("tcp", "localhost:0")
+	closedProxy := ln.Addr().String()
+	ln.Close()
+
+	for _, test := range []struct {
+		desc     string
+		protocol configpb.ProbeConf_ProtocolType
+		proxyURL string
+	}{
+		{
+			desc:     "proxy_down",
+			proxyURL: "http://" + closedProxy,
+		},
+		{
+			desc:     "http_proxy_bad_auth",
+			proxyURL: "http://user:wrong@" + authProxyServer.Listener.Addr().String(),
+		},
+		{
+			desc:     "http_proxy_connect_bad_auth",
+			protocol: configpb.ProbeConf_HTTPS,
+			proxyURL: "http://user:wrong@" + authProxyServer.Listener.Addr().String(),
+		},
+		{
+			desc:     "socks5_proxy_down",
+			proxyURL: "socks5://" + closedProxy,
+		},
+		{
+			desc:     "socks5_proxy_bad_auth",
+			proxyURL: "socks5://user:wrong@" + socksProxy,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			result := runProxyTestProbe(t, &configpb.ProbeConf{
+				Protocol: test.protocol.Enum(),
+				ProxyUrl: proto.String(test.proxyURL),
+			})
+
+			if result.total != 1 || result.success != 0 || result.proxyErrors != 1 {
+				t.Errorf("Got total=%d, success=%d, proxy_errors=%d, want 1, 0, 1", result.total, result.success, result.proxyErrors)
+			}
+		})
+	}
+}
+
+func TestInitProxyURLErrors(t *testing.T) {
+	for _, proxyURL := range []string{"ftp://proxy:21", "http://", "http://[::1"} {
+		p := &Probe{}
+		err := p.Init("http_test", &options.Options{
+			Targets:   targets.StaticTargets("test.com"),
+			Interval:  2 * time.Second,
+			Timeout:   time.Second,
+			ProbeConf: &configpb.ProbeConf{ProxyUrl: proto.String(proxyURL)},
+		})
+		if err == nil {
+			t.Errorf("Init(proxy_url=%s): expected error", proxyURL)
+		}
+	}
+}
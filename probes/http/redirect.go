@@ -0,0 +1,71 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// redirectPolicyError is returned for the redirects that are not allowed by
+// the probe's redirect options. Requests failing with it are not retried.
+type redirectPolicyError struct {
+	msg string
+}
+
+func (e *redirectPolicyError) Error() string {
+	return e.msg
+}
+
+// requestHost returns the request's host, without the port. For the resolved
+// targets, URL carries the IP address, and Host field carries the target name.
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// checkRedirect implements the http.Client's redirect policy, based on the
+// probe's redirect options. via contains the requests made so far, oldest
+// first.
+func (p *Probe) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !p.c.GetFollowRedirects() {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > int(p.c.GetMaxRedirects()) {
+		return &redirectPolicyError{fmt.Sprintf("stopped after %d redirects", p.c.GetMaxRedirects())}
+	}
+	if !p.c.GetAllowCrossHostRedirects() {
+		if from, to := requestHost(via[0]), requestHost(req); from != to {
+			return &redirectPolicyError{fmt.Sprintf("cross-host redirect from %s to %s not allowed", from, to)}
+		}
+	}
+	return nil
+}
+
+// numRedirects returns the number of redirects followed to get the response.
+func numRedirects(resp *http.Response) int64 {
+	var n int64
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		n++
+	}
+	return n
+}
@@ -15,12 +15,18 @@
 package http
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
 	"strings"
 
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 )
 
@@ -41,6 +47,134 @@ func (rb *requestBody) Read(p []byte) (int, error) {
 	return copy(p, rb.b), io.EOF
 }
 
+// Close implements the io.Closer interface. It makes the requestBody the
+// request's Body as it is, so that we can get the body back from the request.
+func (rb *requestBody) Close() error {
+	return nil
+}
+
+// bodyBytes returns the request's body, as created for its target, if any.
+func bodyBytes(req *http.Request) []byte {
+	if rb, ok := req.Body.(*requestBody); ok {
+		return rb.b
+	}
+	return nil
+}
+
+// initBodyFile verifies that the configured body file can be read.
+func (p *Probe) initBodyFile() error {
+	if p.c.GetBody() != "" {
+		return errors.New("only one of body and body_file can be configured")
+	}
+	f, err := os.Open(p.c.GetBodyFile())
+	if err != nil {
+		return fmt.Errorf("error opening body_file: %v", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading body_file: %v", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("body_file (%s) is not a regular file", p.c.GetBodyFile())
+	}
+	p.bodyFile = p.c.GetBodyFile()
+	return nil
+}
+
+// requestWithFileBody returns a copy of the request with its body streamed
+// from the body file. Request's GetBody opens the file again, so that the
+// request can be retried.
+func (p *Probe) requestWithFileBody(req *http.Request) (*http.Request, error) {
+	f, err := os.Open(p.bodyFile)
+	if err != nil {
+		return req, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return req, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = f
+	req.GetBody = func() (io.ReadCloser, error) {
+		return os.Open(p.bodyFile)
+	}
+
+	// Unknown content length (-1) results in chunked transfer encoding.
+	req.ContentLength = -1
+	if !p.c.GetChunkedUpload() {
+		req.ContentLength = fi.Size()
+		if fi.Size() == 0 {
+			f.Close()
+			req.Body, req.GetBody = http.NoBody, nil
+		}
+	}
+	return req, nil
+}
+
+// requestWithGzipBody returns a copy of the request with its body gzipped, and
+// the Content-Encoding header set accordingly. Body is compressed afresh for
+// every request, and request's GetBody compresses it again, so that the
+// request can be retried. Body file is compressed while it's streamed, using
+// the chunked transfer encoding.
+func (p *Probe) requestWithGzipBody(req *http.Request) (*http.Request, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if p.bodyFile == "" {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(bodyBytes(req)); err != nil {
+			return req, err
+		}
+		if err := zw.Close(); err != nil {
+			return req, err
+		}
+		b := buf.Bytes()
+
+		req.ContentLength = int64(len(b))
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+		return req, nil
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		f, err := os.Open(p.bodyFile)
+		if err != nil {
+			return nil, err
+		}
+		return gzipStream(f), nil
+	}
+	body, err := getBody()
+	if err != nil {
+		return req, err
+	}
+	req.Body, req.GetBody = body, getBody
+	req.ContentLength = -1
+	return req, nil
+}
+
+// gzipStream returns a reader that streams the gzipped content of r, and
+// closes r once it's done. Closing the returned reader stops the compression.
+func gzipStream(r io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer r.Close()
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
 // resolveFunc resolves the given host for the IP version.
 // This type is mainly used for testing. For all other cases, a nil function
 // should be passed to the httpRequestForTarget function.
@@ -91,25 +225,31 @@ func relURLForTarget(target endpoint.Endpoint, probeURL string) string {
 
 func (p *Probe) httpRequestForTarget(target endpoint.Endpoint, resolveF resolveFunc) *http.Request {
 	// Prepare HTTP.Request for Client.Do
-	port := int(p.c.GetPort())
+	// Port from the target's port label, if any, overrides the configured port.
 	// If port is not configured explicitly, use target's port if available.
-	if port == 0 {
-		port = target.Port
-	}
+	port := probeutils.TargetPort(target, p.c.GetPortLabel(), int(p.c.GetPort()), p.l)
 
 	urlHost := urlHostForTarget(target)
 
-	if p.c.GetResolveFirst() {
-		if resolveF == nil {
-			resolveF = p.opts.Targets.Resolve
-		}
-
-		ip, err := resolveF(target.Name, p.opts.IPVersion)
+	var ip string
+	if p.c.GetResolveFirst() || p.targetIPToken {
+		resolvedIP, err := p.opts.ResolveTarget(target.Name, resolveF)
 		if err != nil {
 			p.l.Error("target: ", target.Name, ", resolve error: ", err.Error())
 			return nil
 		}
-		urlHost = ip.String()
+		ip = resolvedIP.String()
+	}
+	if p.c.GetResolveFirst() {
+		urlHost = ip
+	}
+
+	// expand substitutes the target based tokens, if any, in request fields.
+	expand := func(s string) string {
+		if !p.targetTokens {
+			return s
+		}
+		return substituteTargetTokens(s, target, ip)
 	}
 
 	// Put square brackets around literal IPv6 hosts. This is the same logic as
@@ -119,12 +259,16 @@ func (p *Probe) httpRequestForTarget(target endpoint.Endpoint, resolveF resolveF
 		urlHost = "[" + urlHost + "]"
 	}
 
-	url := fmt.Sprintf("%s://%s%s", p.protocol, hostWithPort(urlHost, port), relURLForTarget(target, p.url))
+	url := fmt.Sprintf("%s://%s%s", p.protocol, hostWithPort(urlHost, port), expand(relURLForTarget(target, p.url)))
 
 	// Prepare request body
 	var body io.Reader
 	if len(p.requestBody) > 0 {
-		body = &requestBody{p.requestBody}
+		b := p.requestBody
+		if p.targetTokens {
+			b = []byte(expand(string(b)))
+		}
+		body = &requestBody{b}
 	}
 	req, err := http.NewRequest(p.method, url, body)
 	if err != nil {
@@ -132,21 +276,39 @@ func (p *Probe) httpRequestForTarget(target endpoint.Endpoint, resolveF resolveF
 		return nil
 	}
 
+	// Configured headers can override the GraphQL content type.
+	if p.graphQL {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
 	var probeHostHeader string
 	for _, header := range p.c.GetHeaders() {
 		if header.GetName() == "Host" {
-			probeHostHeader = header.GetValue()
+			probeHostHeader = expand(header.GetValue())
 			continue
 		}
-		req.Header.Set(header.GetName(), header.GetValue())
+		req.Header.Set(header.GetName(), expand(header.GetValue()))
+	}
+
+	// Target's host label, if configured, overrides probe level Host header.
+	if l := p.c.GetHostHeaderLabel(); l != "" && target.Labels[l] != "" {
+		probeHostHeader = target.Labels[l]
 	}
 
 	// Host header is set by http.NewRequest based on the URL, update it based
 	// on various conditions.
 	req.Host = hostHeaderForTarget(target, probeHostHeader, port)
 
-	if p.bearerToken != "" {
-		req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+	var tp tlsParams
+	if l := p.c.GetSniLabel(); l != "" {
+		tp.serverName = target.Labels[l]
+	}
+	if p.clientCertConfig != nil {
+		tp.certFile = expand(p.clientCertConfig.GetTlsCertFile())
+		tp.keyFile = expand(p.clientCertConfig.GetTlsKeyFile())
+	}
+	if tp != (tlsParams{}) {
+		req = withTLSParams(req, tp)
 	}
 
 	return req
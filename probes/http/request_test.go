@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
@@ -309,3 +310,82 @@ func TestRequestHostAndURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestWithTargetTokens(t *testing.T) {
+	target := endpoint.Endpoint{
+		Name:   "test-target.com",
+		Port:   8080,
+		Labels: map[string]string{"tenant": "t1"},
+	}
+	resolveF := func(target string, ipVer int) (net.IP, error) {
+		return net.ParseIP("10.1.1.1"), nil
+	}
+
+	for _, test := range []struct {
+		desc       string
+		url        string
+		header     string
+		body       string
+		wantURL    string
+		wantHeader string
+		wantBody   string
+	}{
+		{
+			desc:       "no_tokens",
+			url:        "/api/health",
+			header:     "static",
+			body:       "user@example.com",
+			wantURL:    "http://test-target.com:8080/api/health",
+			wantHeader: "static",
+			wantBody:   "user@example.com",
+		},
+		{
+			desc:       "label_and_name",
+			url:        "/api/@target.label.tenant@/health",
+			header:     "@target.name@:@target.port@",
+			body:       `{"tenant": "@target.label.tenant@", "email": "user@example.com"}`,
+			wantURL:    "http://test-target.com:8080/api/t1/health",
+			wantHeader: "test-target.com:8080",
+			wantBody:   `{"tenant": "t1", "email": "user@example.com"}`,
+		},
+		{
+			desc:       "ip_and_missing_label",
+			url:        "/@target.label.missing@",
+			header:     "@target.ip@",
+			body:       "@target.unknown@",
+			wantURL:    "http://test-target.com:8080/",
+			wantHeader: "10.1.1.1",
+			wantBody:   "@target.unknown@",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			if err := p.Init("http_test", &options.Options{
+				Targets:  targets.StaticTargets(target.Name),
+				Interval: 2 * time.Second,
+				Timeout:  time.Second,
+				ProbeConf: &configpb.ProbeConf{
+					RelativeUrl: proto.String(test.url),
+					Method:      configpb.ProbeConf_POST.Enum(),
+					Headers: []*configpb.ProbeConf_Header{
+						{Name: proto.String("X-Test"), Value: proto.String(test.header)},
+					},
+					Body: proto.String(test.body),
+				},
+			}); err != nil {
+				t.Fatalf("Error initializing probe: %v", err)
+			}
+
+			req := p.httpRequestForTarget(target, resolveF)
+			if req.URL.String() != test.wantURL {
+				t.Errorf("Got URL=%s, want=%s", req.URL.String(), test.wantURL)
+			}
+			if got := req.Header.Get("X-Test"); got != test.wantHeader {
+				t.Errorf("Got header=%q, want=%q", got, test.wantHeader)
+			}
+			if got := string(bodyBytes(req)); got != test.wantBody {
+				t.Errorf("Got body=%q, want=%q", got, test.wantBody)
+			}
+		})
+	}
+}
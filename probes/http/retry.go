@@ -0,0 +1,111 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	httpvalidator "github.com/cloudprober/cloudprober/validators/http"
+)
+
+// idempotentMethods are the methods that are safe to retry, as defined by
+// RFC 7231.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// initRetries initializes request retries, if configured. Retries are not
+// enabled for the non-idempotent methods, unless explicitly allowed.
+func (p *Probe) initRetries() error {
+	if p.c.GetRetries() < 0 {
+		return fmt.Errorf("invalid retries (%d), cannot be negative", p.c.GetRetries())
+	}
+	if p.c.GetRetries() == 0 {
+		return nil
+	}
+
+	if !idempotentMethods[p.method] && !p.c.GetRetryNonIdempotent() {
+		p.l.Warningf("Not retrying %s requests as %s is not an idempotent method, set retry_non_idempotent to retry them anyway", p.method, p.method)
+		return nil
+	}
+
+	codes, err := httpvalidator.ParseStatusCodeRanges(p.c.GetRetryOnStatus())
+	if err != nil {
+		return fmt.Errorf("invalid retry_on_status (%s): %v", p.c.GetRetryOnStatus(), err)
+	}
+	p.retries = int(p.c.GetRetries())
+	p.retryCodes = codes
+	return nil
+}
+
+// shouldRetry tells whether a request should be retried, given the outcome of
+// its last attempt.
+func (p *Probe) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	// Retries share the request's timeout budget.
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		var rpErr *redirectPolicyError
+		return !isClientTimeout(err) && !errors.As(err, &rpErr)
+	}
+	return p.retryCodes.Match(resp.StatusCode)
+}
+
+// doWithRetries sends the request, retrying it up to the configured number of
+// times, and returns the last attempt's response and error, along with the
+// number of retries.
+func (p *Probe) doWithRetries(req *http.Request, targetName string) (*http.Response, int64, error) {
+	var retries int64
+	for {
+		resp, err := p.client.Do(req)
+		if retries >= int64(p.retries) || !p.shouldRetry(req, resp, err) {
+			return resp, retries, err
+		}
+
+		// Rewind the request body, if it can't be read again as it is.
+		var body io.ReadCloser
+		if req.GetBody != nil {
+			var bodyErr error
+			if body, bodyErr = req.GetBody(); bodyErr != nil {
+				p.l.Warningf("Target: %s, URL: %s, not retrying, error resetting request body: %v", targetName, req.URL.String(), bodyErr)
+				return resp, retries, err
+			}
+		}
+
+		if err != nil {
+			p.l.Warningf("Target: %s, URL: %s, retrying failed request: %v", targetName, req.URL.String(), err)
+		} else {
+			p.l.Warningf("Target: %s, URL: %s, retrying request that got: %s", targetName, req.URL.String(), resp.Status)
+			// Drain the body to let the connection be reused.
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		retries++
+	}
+}
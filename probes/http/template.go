@@ -0,0 +1,74 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// targetTokenRegex matches the target based substitution tokens, the same
+// ones as supported by the additional labels, plus the target's IP address:
+// @target.name@, @target.port@, @target.ip@ and @target.label.<key>@.
+// Everything else, e.g. an email address in the body, is left as it is.
+var targetTokenRegex = regexp.MustCompile(`@target\.(name|port|ip|label\.[^@\s]+)@`)
+
+// hasTargetTokens tells whether the string has any target based tokens.
+func hasTargetTokens(s string) bool {
+	return targetTokenRegex.MatchString(s)
+}
+
+// substituteTargetTokens replaces the target based tokens in the string with
+// the target's values. Missing labels are replaced with empty strings.
+func substituteTargetTokens(s string, target endpoint.Endpoint, ip string) string {
+	if !strings.Contains(s, "@target.") {
+		return s
+	}
+	return targetTokenRegex.ReplaceAllStringFunc(s, func(tok string) string {
+		switch key := targetTokenRegex.FindStringSubmatch(tok)[1]; key {
+		case "name":
+			return target.Name
+		case "port":
+			return strconv.Itoa(target.Port)
+		case "ip":
+			return ip
+		default:
+			return target.Labels[strings.TrimPrefix(key, "label.")]
+		}
+	})
+}
+
+// initTargetTokens checks whether the request fields use the target based
+// tokens.
+func (p *Probe) initTargetTokens() {
+	fields := []string{p.url, string(p.requestBody)}
+	for _, h := range p.c.GetHeaders() {
+		fields = append(fields, h.GetValue())
+	}
+	if p.clientCertConfig != nil {
+		fields = append(fields, p.clientCertConfig.GetTlsCertFile(), p.clientCertConfig.GetTlsKeyFile())
+	}
+	for _, f := range fields {
+		if hasTargetTokens(f) {
+			p.targetTokens = true
+		}
+		if strings.Contains(f, "@target.ip@") {
+			p.targetIPToken = true
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// connTimings records the timings of a request's connection setup phases:
+// DNS resolution, TCP connect, TLS handshake and, with a proxy, connecting
+// through the proxy. Trace hooks may be called concurrently, e.g. with the
+// happy eyeballs dialer, so we record the first start and the first
+// successful completion of every phase.
+type connTimings struct {
+	mu                        sync.Mutex
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn                   time.Time
+}
+
+func (ct *connTimings) setFirst(t *time.Time) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// addHooks adds the connection setup hooks to the trace, preserving the
+// existing ConnectDone and GotConn hooks.
+func (ct *connTimings) addHooks(trace *httptrace.ClientTrace) {
+	trace.DNSStart = func(httptrace.DNSStartInfo) { ct.setFirst(&ct.dnsStart) }
+	trace.DNSDone = func(info httptrace.DNSDoneInfo) {
+		if info.Err == nil {
+			ct.setFirst(&ct.dnsDone)
+		}
+	}
+
+	connectDone := trace.ConnectDone
+	trace.ConnectStart = func(_, _ string) { ct.setFirst(&ct.connectStart) }
+	trace.ConnectDone = func(network, addr string, err error) {
+		if err == nil {
+			ct.setFirst(&ct.connectDone)
+		}
+		if connectDone != nil {
+			connectDone(network, addr, err)
+		}
+	}
+
+	trace.TLSHandshakeStart = func() { ct.setFirst(&ct.tlsStart) }
+	trace.TLSHandshakeDone = func(_ tls.ConnectionState, err error) {
+		if err == nil {
+			ct.setFirst(&ct.tlsDone)
+		}
+	}
+
+	gotConn := trace.GotConn
+	trace.GotConn = func(info httptrace.GotConnInfo) {
+		if !info.Reused {
+			ct.setFirst(&ct.gotConn)
+		}
+		if gotConn != nil {
+			gotConn(info)
+		}
+	}
+}
+
+// record adds the timings of the completed phases to the result, for the
+// metrics that are enabled. Phases that didn't happen, e.g. because of a
+// reused connection, are skipped. Caller should hold the result's lock, if
+// any.
+func (ct *connTimings) record(result *probeResult, latencyUnit time.Duration) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	// With a proxy, connection is ready once we are connected to the proxy
+	// and, for HTTPS targets and SOCKS5 proxies, the tunnel to the target is
+	// established, i.e. when the TLS handshake with the target starts.
+	proxyDone := ct.gotConn
+	if !ct.tlsStart.IsZero() {
+		proxyDone = ct.tlsStart
+	}
+
+	for _, phase := range []struct {
+		start, done time.Time
+		v           metrics.Value
+	}{
+		{ct.dnsStart, ct.dnsDone, result.dnsLatency},
+		{ct.connectStart, ct.connectDone, result.connectLatency},
+		{ct.tlsStart, ct.tlsDone, result.tlsLatency},
+		{ct.connectStart, proxyDone, result.proxyConnectLatency},
+	} {
+		if phase.v == nil || phase.start.IsZero() || phase.done.Before(phase.start) {
+			continue
+		}
+		phase.v.AddFloat64(phase.done.Sub(phase.start).Seconds() / latencyUnit.Seconds())
+	}
+}
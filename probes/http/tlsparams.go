@@ -0,0 +1,118 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sync"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+// tlsParams are the per-target TLS settings: the TLS server name (from
+// sni_label), and the client certificate files (from tls_config's cert and
+// key files with target tokens).
+type tlsParams struct {
+	serverName        string
+	certFile, keyFile string
+}
+
+// tlsParamsContextKey is the request context key for the request's tlsParams.
+type tlsParamsContextKey struct{}
+
+// withTLSParams returns a copy of the request that carries the TLS params.
+func withTLSParams(req *http.Request, tp tlsParams) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), tlsParamsContextKey{}, tp))
+}
+
+// withTLSParamsFrom returns a copy of ctx that carries the TLS params from the
+// given request's context, if any. Probe runs replace the request context, so
+// the TLS params need to be carried over to the new context.
+func withTLSParamsFrom(ctx context.Context, req *http.Request) context.Context {
+	if tp, ok := req.Context().Value(tlsParamsContextKey{}).(tlsParams); ok {
+		return context.WithValue(ctx, tlsParamsContextKey{}, tp)
+	}
+	return ctx
+}
+
+// tlsParamsRoundTripper sends requests using per-TLS-params transports. Since
+// transports pool connections by the target address, using a single transport
+// would mean reusing connections across server names and client identities.
+type tlsParamsRoundTripper struct {
+	base *http.Transport
+
+	// Client certificate config, used with the target's cert and key files.
+	certConfig *tlsconfigpb.TLSConfig
+
+	mu         sync.Mutex
+	transports map[tlsParams]*http.Transport
+}
+
+func newTLSParamsRoundTripper(base *http.Transport, certConfig *tlsconfigpb.TLSConfig) *tlsParamsRoundTripper {
+	return &tlsParamsRoundTripper{
+		base:       base,
+		certConfig: certConfig,
+		transports: make(map[tlsParams]*http.Transport),
+	}
+}
+
+// transportFor returns the transport for the TLS params, creating it if
+// required. Transports are not cached if the client certificate fails to
+// load, so that it's tried again with the next request.
+func (rt *tlsParamsRoundTripper) transportFor(tp tlsParams) (*http.Transport, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if t := rt.transports[tp]; t != nil {
+		return t, nil
+	}
+
+	t := rt.base.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	if tp.serverName != "" {
+		t.TLSClientConfig.ServerName = tp.serverName
+	}
+	if tp.certFile != "" {
+		c := proto.Clone(rt.certConfig).(*tlsconfigpb.TLSConfig)
+		c.TlsCertFile, c.TlsKeyFile = proto.String(tp.certFile), proto.String(tp.keyFile)
+		if err := tlsconfig.UpdateTLSConfig(t.TLSClientConfig, c, false); err != nil {
+			return nil, err
+		}
+	}
+	rt.transports[tp] = t
+	return t, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rt *tlsParamsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	tp, _ := req.Context().Value(tlsParamsContextKey{}).(tlsParams)
+	if tp == (tlsParams{}) {
+		return rt.base.RoundTrip(req)
+	}
+	t, err := rt.transportFor(tp)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.RoundTrip(req)
+}
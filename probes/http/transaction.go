@@ -0,0 +1,310 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	httpvalidator "github.com/cloudprober/cloudprober/validators/http"
+)
+
+// extractor extracts a named value from a step's response body or headers.
+type extractor struct {
+	name     string
+	re       *regexp.Regexp
+	jsonPath []string
+	header   string
+}
+
+// step is a parsed synthetic transaction step.
+type step struct {
+	c            *configpb.ProbeConf_Step
+	method       string
+	okCodes      map[int]bool
+	defaultCodes httpvalidator.StatusCodeRanges // Used instead of 2xx if okCodes is nil.
+	extractors   []*extractor
+}
+
+var stepVarRe = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+func parseSteps(stepsConf []*configpb.ProbeConf_Step, defaultCodes httpvalidator.StatusCodeRanges) ([]*step, error) {
+	var steps []*step
+	for i, sc := range stepsConf {
+		if sc.GetRelativeUrl() == "" || sc.GetRelativeUrl()[0] != '/' {
+			return nil, fmt.Errorf("step %d: invalid relative URL: %q, must begin with '/'", i, sc.GetRelativeUrl())
+		}
+
+		s := &step{
+			c:            sc,
+			method:       sc.GetMethod().String(),
+			defaultCodes: defaultCodes,
+		}
+
+		if len(sc.GetExpectedStatusCode()) > 0 {
+			s.okCodes = make(map[int]bool)
+			for _, code := range sc.GetExpectedStatusCode() {
+				s.okCodes[int(code)] = true
+			}
+		}
+
+		for _, ec := range sc.GetExtract() {
+			e := &extractor{name: ec.GetName()}
+			switch ec.Source.(type) {
+			case *configpb.ProbeConf_Step_Extract_Regex:
+				re, err := regexp.Compile(ec.GetRegex())
+				if err != nil {
+					return nil, fmt.Errorf("step %d: error compiling regex for %s: %v", i, e.name, err)
+				}
+				e.re = re
+			case *configpb.ProbeConf_Step_Extract_JsonPath:
+				e.jsonPath = strings.Split(ec.GetJsonPath(), ".")
+			case *configpb.ProbeConf_Step_Extract_Header:
+				if ec.GetHeader() == "" {
+					return nil, fmt.Errorf("step %d: empty header name for %s", i, e.name)
+				}
+				e.header = ec.GetHeader()
+			default:
+				return nil, fmt.Errorf("step %d: no extraction source for %s", i, e.name)
+			}
+			s.extractors = append(s.extractors, e)
+		}
+
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+func (s *step) statusOK(code int) bool {
+	if s.okCodes == nil {
+		if s.defaultCodes != nil {
+			return s.defaultCodes.Match(code)
+		}
+		return code >= 200 && code < 300
+	}
+	return s.okCodes[code]
+}
+
+// lookupJSONPath walks the decoded JSON value v along the given path.
+func lookupJSONPath(v interface{}, path []string) (string, error) {
+	for _, key := range path {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			next, ok := val[key]
+			if !ok {
+				return "", fmt.Errorf("key %s not found", key)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(val) {
+				return "", fmt.Errorf("invalid array index %s", key)
+			}
+			v = val[i]
+		default:
+			return "", fmt.Errorf("can't look up %s in a non-container value", key)
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case json.Number, bool:
+		return fmt.Sprint(val), nil
+	}
+	return "", fmt.Errorf("value at the path is not a scalar")
+}
+
+func (e *extractor) extract(header http.Header, body []byte) (string, error) {
+	if e.header != "" {
+		if v := header.Values(e.header); len(v) > 0 {
+			return v[0], nil
+		}
+		return "", fmt.Errorf("header %s not found", e.header)
+	}
+
+	if e.re != nil {
+		m := e.re.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("regex %s didn't match", e.re.String())
+		}
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("error parsing response as JSON: %v", err)
+	}
+	return lookupJSONPath(v, e.jsonPath)
+}
+
+// expandVars replaces ${name} in s with the values extracted by the previous
+// steps. Unknown variables are left as is.
+func expandVars(s string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return s
+	}
+	return stepVarRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[2:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// request builds the step's request from the target's base request, which
+// carries the target's URL host, Host header, probe level headers and TLS
+// server name.
+func (s *step) request(ctx context.Context, base *http.Request, vars map[string]string) (*http.Request, error) {
+	u, err := base.URL.Parse(expandVars(s.c.GetRelativeUrl(), vars))
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if s.c.GetBody() != "" {
+		body = strings.NewReader(expandVars(s.c.GetBody(), vars))
+	}
+
+	req, err := http.NewRequest(s.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(withTLSParamsFrom(ctx, base))
+
+	req.Host = base.Host
+	for k, v := range base.Header {
+		req.Header[k] = v
+	}
+	for _, h := range s.c.GetHeaders() {
+		if h.GetName() == "Host" {
+			req.Host = expandVars(h.GetValue(), vars)
+			continue
+		}
+		req.Header.Set(h.GetName(), expandVars(h.GetValue(), vars))
+	}
+	return req, nil
+}
+
+// runStep runs a transaction step and adds the values extracted from its
+// response to vars.
+func (p *Probe) runStep(ctx context.Context, client *http.Client, s *step, base *http.Request, vars map[string]string) error {
+	req, err := s.request(ctx, base, vars)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if !s.statusOK(resp.StatusCode) {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	for _, e := range s.extractors {
+		v, err := e.extract(resp.Header, respBody)
+		if err != nil {
+			return fmt.Errorf("error extracting %s: %v", e.name, err)
+		}
+		vars[e.name] = v
+	}
+	return nil
+}
+
+// runTransaction runs the synthetic transaction steps in order, sharing a
+// cookie jar between them, and stops at the first failing step.
+func (p *Probe) runTransaction(ctx context.Context, target endpoint.Endpoint, base *http.Request, result *probeResult) {
+	reqCtx, cancelReqCtx := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancelReqCtx()
+
+	// Each transaction starts with a new cookie jar, so that transactions
+	// don't interfere with each other.
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Transport:     p.client.Transport,
+		CheckRedirect: p.client.CheckRedirect,
+		Jar:           jar,
+	}
+
+	result.total++
+	vars := make(map[string]string)
+
+	base, err := p.requestWithOAuthToken(base, target.Name)
+	if err != nil {
+		result.oauthRefreshFailures++
+	}
+
+	start := time.Now()
+	for i, s := range p.steps {
+		stepStart := time.Now()
+		if err := p.runStep(reqCtx, client, s, base, vars); err != nil {
+			p.l.Warning("Target:", target.Name, ", step: ", strconv.Itoa(i), ", URL: ", s.c.GetRelativeUrl(), ", http.runTransaction: ", err.Error())
+			if options.IsConnectTimeout(err) {
+				result.connectTimeouts++
+			} else if isClientTimeout(err) {
+				result.timeouts++
+			}
+			result.stepFailures.IncKey(strconv.Itoa(i))
+			return
+		}
+		result.stepLatency[i].AddFloat64(time.Since(stepStart).Seconds() / p.opts.LatencyUnit.Seconds())
+	}
+
+	p.recordSuccess(result, time.Since(start))
+}
+
+// stepLatencyEMs returns the per-step latency EventMetrics, with the same
+// labels as the given target's EventMetrics and the step index as an
+// additional label.
+func (p *Probe) stepLatencyEMs(em *metrics.EventMetrics, result *probeResult) []*metrics.EventMetrics {
+	var ems []*metrics.EventMetrics
+	for i, latency := range result.stepLatency {
+		sem := metrics.NewEventMetrics(em.Timestamp).
+			AddMetric("step_latency", latency)
+		for _, k := range em.LabelsKeys() {
+			sem.AddLabel(k, em.Label(k))
+		}
+		sem.AddLabel("step", strconv.Itoa(i))
+		sem.LatencyUnit = p.opts.LatencyUnit
+		ems = append(ems, sem)
+	}
+	return ems
+}
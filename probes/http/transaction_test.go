@@ -0,0 +1,242 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/http/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/golang/protobuf/proto"
+)
+
+// loginServer simulates a login flow: /login sets a session cookie and
+// returns a token and a CSRF token (as a header), /data requires both the
+// cookie and the token, and /logout requires the cookie, the CSRF token and
+// the item id returned by /data.
+func loginServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+		w.Header().Set("X-CSRF-Token", "c1")
+		w.Write([]byte(`{"auth": {"token": "t123", "ttl": 60}}`))
+	})
+
+	loggedIn := func(r *http.Request) bool {
+		c, err := r.Cookie("session")
+		return err == nil && c.Value == "s1"
+	}
+
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		if !loggedIn(r) || r.Header.Get("Authorization") != "Bearer t123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("item id=42"))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if !loggedIn(r) || r.Header.Get("X-CSRF-Token") != "c1" || r.URL.Query().Get("id") != "42" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(mux)
+}
+
+func testSteps(dataURL string) []*configpb.ProbeConf_Step {
+	return []*configpb.ProbeConf_Step{
+		{
+			Method:      configpb.ProbeConf_POST.Enum(),
+			RelativeUrl: proto.String("/login"),
+			Body:        proto.String("user=u1"),
+			Extract: []*configpb.ProbeConf_Step_Extract{
+				{
+					Name:   proto.String("token"),
+					Source: &configpb.ProbeConf_Step_Extract_JsonPath{JsonPath: "auth.token"},
+				},
+				{
+					Name:   proto.String("csrf"),
+					Source: &configpb.ProbeConf_Step_Extract_Header{Header: "X-Csrf-Token"},
+				},
+			},
+		},
+		{
+			RelativeUrl: proto.String(dataURL),
+			Headers: []*configpb.ProbeConf_Header{
+				{Name: proto.String("Authorization"), Value: proto.String("Bearer ${token}")},
+			},
+			Extract: []*configpb.ProbeConf_Step_Extract{
+				{
+					Name:   proto.String("id"),
+					Source: &configpb.ProbeConf_Step_Extract_Regex{Regex: `id=(\d+)`},
+				},
+			},
+		},
+		{
+			Method:      configpb.ProbeConf_POST.Enum(),
+			RelativeUrl: proto.String("/logout?id=${id}"),
+			Headers: []*configpb.ProbeConf_Header{
+				{Name: proto.String("X-CSRF-Token"), Value: proto.String("${csrf}")},
+			},
+			ExpectedStatusCode: []int32{204},
+		},
+	}
+}
+
+func TestRunTransaction(t *testing.T) {
+	ts := loginServer()
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	target := endpoint.Endpoint{Name: host, Port: port}
+
+	for _, test := range []struct {
+		desc        string
+		dataURL     string
+		wantSuccess int64
+		wantFailed  string
+	}{
+		{desc: "success", dataURL: "/data", wantSuccess: 1},
+		{desc: "data_step_fails", dataURL: "/missing", wantFailed: "1"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{}
+			err := p.Init("http_test", &options.Options{
+				Targets:     targets.StaticTargets(host),
+				Interval:    2 * time.Second,
+				Timeout:     time.Second,
+				LatencyUnit: time.Millisecond,
+				ProbeConf: &configpb.ProbeConf{
+					Steps: testSteps(test.dataURL),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error while initializing probe: %v", err)
+			}
+
+			result := p.newResult()
+			p.runTransaction(context.Background(), target, p.httpRequestForTarget(target, nil), result)
+
+			if result.total != 1 || result.success != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total, result.success, test.wantSuccess)
+			}
+
+			for i := range p.steps {
+				var failures int64
+				if v := result.stepFailures.GetKey(strconv.Itoa(i)); v != nil {
+					failures = v.Int64()
+				}
+				wantFailures := int64(0)
+				if strconv.Itoa(i) == test.wantFailed {
+					wantFailures = 1
+				}
+				if failures != wantFailures {
+					t.Errorf("Step %d: got failures=%d, want=%d", i, failures, wantFailures)
+				}
+			}
+
+			// Per-step latency EventMetrics carry the step label.
+			em := metrics.NewEventMetrics(time.Now()).AddLabel("probe", "http_test").AddLabel("dst", host)
+			ems := p.stepLatencyEMs(em, result)
+			if len(ems) != 3 || ems[2].Label("step") != "2" || ems[2].Label("dst") != host {
+				t.Errorf("Unexpected step latency EventMetrics: %v", ems)
+			}
+		})
+	}
+}
+
+func TestParseStepsErrors(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		steps []*configpb.ProbeConf_Step
+	}{
+		{
+			desc:  "bad_relative_url",
+			steps: []*configpb.ProbeConf_Step{{RelativeUrl: proto.String("login")}},
+		},
+		{
+			desc: "bad_regex",
+			steps: []*configpb.ProbeConf_Step{{
+				RelativeUrl: proto.String("/login"),
+				Extract: []*configpb.ProbeConf_Step_Extract{
+					{Name: proto.String("v"), Source: &configpb.ProbeConf_Step_Extract_Regex{Regex: "("}},
+				},
+			}},
+		},
+		{
+			desc: "empty_header",
+			steps: []*configpb.ProbeConf_Step{{
+				RelativeUrl: proto.String("/login"),
+				Extract: []*configpb.ProbeConf_Step_Extract{
+					{Name: proto.String("v"), Source: &configpb.ProbeConf_Step_Extract_Header{}},
+				},
+			}},
+		},
+		{
+			desc: "no_extraction_source",
+			steps: []*configpb.ProbeConf_Step{{
+				RelativeUrl: proto.String("/login"),
+				Extract:     []*configpb.ProbeConf_Step_Extract{{Name: proto.String("v")}},
+			}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := parseSteps(test.steps, nil); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	e := &extractor{}
+	body := []byte(`{"data": {"items": [{"id": 7}, {"id": "x", "ok": true}]}}`)
+	for path, want := range map[string]string{
+		"data.items.0.id": "7",
+		"data.items.1.id": "x",
+		"data.items.1.ok": "true",
+		"data.items.2.id": "",
+		"data.missing":    "",
+		"data.items":      "",
+	} {
+		e.jsonPath = strings.Split(path, ".")
+		got, err := e.extract(nil, body)
+		if want == "" {
+			if err == nil {
+				t.Errorf("Path %s: expected error, got value %q", path, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("Path %s: got %q (err: %v), want %q", path, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package ldap implements an LDAP prober. It connects to the targets, over LDAP
+or LDAPS, binds with simple or SASL authentication and, if configured, runs a
+search. It reports statistics on probe runs, successful runs and latency,
+along with the bind and search latencies, and the reason of the failures. For
+every completed search, it also reports the number of entries returned.
+
+Probes for all targets run in parallel.
+*/
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/ldap/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonConnect       = "connect"
+	reasonBind          = "bind"
+	reasonSearch        = "search"
+	reasonTooFewResults = "too_few_results"
+)
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets   []endpoint.Endpoint
+	dialF     func(context.Context, string, string) (net.Conn, error)
+	tlsConfig *tls.Config // nil for LDAP.
+	bindReq   []byte
+	filter    []byte // Encoded search filter.
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	bindLatency       metrics.Value
+	latencyMetricName string
+
+	// searchLatency is exported only if search is configured.
+	searchLatency metrics.Value
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+
+	// Number of entries returned by the search, reported as a separate
+	// result. nil if there was no completed search.
+	searchResults *searchResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures).
+		AddMetric("bind_latency", prr.bindLatency)
+	if prr.searchLatency != nil {
+		em.AddMetric("search_latency", prr.searchLatency)
+	}
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// searchResult is a gauge update for the number of entries returned by the
+// last search.
+type searchResult struct {
+	target  string
+	entries int64
+}
+
+// Metrics converts searchResult into metrics.EventMetrics object
+func (sr searchResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("search_results", metrics.NewInt(sr.entries))
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the sr.target.
+func (sr searchResult) Target() string {
+	return sr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no ldap config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("ldap_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	switch p.c.GetAuthType() {
+	case configpb.ProbeConf_SIMPLE:
+		p.bindReq = simpleBindRequest(p.c.GetBindDn(), p.c.GetPassword())
+	case configpb.ProbeConf_SASL_PLAIN:
+		if p.c.GetBindDn() == "" {
+			return fmt.Errorf("ldap_probe(%s): SASL_PLAIN requires bind_dn", name)
+		}
+		p.bindReq = saslBindRequest("PLAIN", []byte("\x00"+p.c.GetBindDn()+"\x00"+p.c.GetPassword()))
+	case configpb.ProbeConf_SASL_EXTERNAL:
+		p.bindReq = saslBindRequest("EXTERNAL", nil)
+	}
+
+	if s := p.c.GetSearch(); s != nil {
+		filter, err := parseFilter(s.GetFilter())
+		if err != nil {
+			return fmt.Errorf("ldap_probe(%s): %v", name, err)
+		}
+		p.filter = filter
+
+		if s.GetSizeLimit() < 0 || s.GetMinResults() < 0 {
+			return fmt.Errorf("ldap_probe(%s): size_limit (%d) and min_results (%d) can't be negative", name, s.GetSizeLimit(), s.GetMinResults())
+		}
+		if s.GetSizeLimit() > 0 && s.GetMinResults() > s.GetSizeLimit() {
+			return fmt.Errorf("ldap_probe(%s): min_results (%d) can't be more than size_limit (%d)", name, s.GetMinResults(), s.GetSizeLimit())
+		}
+	}
+
+	if p.c.GetProtocol() == configpb.ProbeConf_LDAPS {
+		p.tlsConfig = &tls.Config{}
+		if p.c.GetTlsConfig() != nil {
+			if err := tlsconfig.UpdateTLSConfig(p.tlsConfig, p.c.GetTlsConfig(), false); err != nil {
+				return fmt.Errorf("ldap_probe(%s): error configuring TLS: %v", name, err)
+			}
+		}
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+		bindLatency:       p.newLatencyValue(),
+	}
+
+	if p.c.GetSearch() != nil {
+		result.searchLatency = p.newLatencyValue()
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// conn is an LDAP client connection.
+type conn struct {
+	net.Conn
+	r      *bufio.Reader
+	nextID int64
+}
+
+// send sends a request and returns its message id.
+func (c *conn) send(op []byte) (int64, error) {
+	c.nextID++
+	_, err := c.Write(encodeMessage(c.nextID, op))
+	return c.nextID, err
+}
+
+// recv reads the next message for the given message id.
+func (c *conn) recv(id int64) (*message, error) {
+	content, err := readMessage(c.r)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := parseMessage(content)
+	if err != nil {
+		return nil, err
+	}
+	if msg.id != id {
+		return nil, fmt.Errorf("unexpected message id: %d, want: %d", msg.id, id)
+	}
+	return msg, nil
+}
+
+// bind sends the bind request and waits for the response.
+func (c *conn) bind(req []byte) error {
+	id, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	msg, err := c.recv(id)
+	if err != nil {
+		return err
+	}
+	if msg.opTag != tagBindResponse {
+		return fmt.Errorf("unexpected response to bind: 0x%x", msg.opTag)
+	}
+	result, err := parseResult(msg.opValue)
+	if err != nil {
+		return err
+	}
+	if result.code != resultSuccess {
+		return result
+	}
+	return nil
+}
+
+// search sends the search request and returns the number of entries
+// returned.
+func (c *conn) search(req []byte) (int64, error) {
+	id, err := c.send(req)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries int64
+	for {
+		msg, err := c.recv(id)
+		if err != nil {
+			return entries, err
+		}
+
+		switch msg.opTag {
+		case tagSearchResultEntry:
+			entries++
+		case tagSearchResultReference:
+			// We don't follow the referrals.
+		case tagSearchResultDone:
+			result, err := parseResult(msg.opValue)
+			if err != nil {
+				return entries, err
+			}
+			if result.code != resultSuccess && result.code != resultSizeLimitExceeded {
+				return entries, result
+			}
+			return entries, nil
+		default:
+			return entries, fmt.Errorf("unexpected response to search: 0x%x", msg.opTag)
+		}
+	}
+}
+
+// connect connects to the target, completing the TLS handshake for LDAPS.
+func (p *Probe) connect(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) (*conn, error) {
+	defaultPort := 389
+	if p.tlsConfig != nil {
+		defaultPort = 636
+	}
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(target.Name, strconv.Itoa(port))
+
+	netConn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	options.RecordIPVersionUsed(result.ipVersionUsed, netConn)
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	if p.tlsConfig != nil {
+		tlsConfig := p.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = target.Name
+		}
+		tlsConn := tls.Client(netConn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s failed: %w", addr, err)
+		}
+		netConn = tlsConn
+	}
+
+	return &conn{Conn: netConn, r: bufio.NewReader(netConn)}, nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	failed := func(reason string, err error) {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reason)
+	}
+
+	start := time.Now()
+	c, err := p.connect(ctx, target, result)
+	if err != nil {
+		failed(reasonConnect, err)
+		return
+	}
+	defer c.Close()
+
+	bindStart := time.Now()
+	if err := c.bind(p.bindReq); err != nil {
+		failed(reasonBind, fmt.Errorf("bind failed: %w", err))
+		return
+	}
+	result.bindLatency.AddFloat64(time.Since(bindStart).Seconds() / p.opts.LatencyUnit.Seconds())
+
+	if s := p.c.GetSearch(); s != nil {
+		searchStart := time.Now()
+		entries, err := c.search(searchRequest(s.GetBaseDn(), int64(s.GetScope()), p.filter, int64(s.GetSizeLimit())))
+		if err != nil {
+			failed(reasonSearch, fmt.Errorf("search failed: %w", err))
+			return
+		}
+		result.searchLatency.AddFloat64(time.Since(searchStart).Seconds() / p.opts.LatencyUnit.Seconds())
+		result.searchResults = &searchResult{target: target.Name, entries: entries}
+
+		if entries < int64(s.GetMinResults()) {
+			failed(reasonTooFewResults, fmt.Errorf("search returned %d entries, want at least %d", entries, s.GetMinResults()))
+			return
+		}
+	}
+
+	// Server closes the connection on unbind, without a response.
+	c.send(unbindRequest())
+
+	latency := time.Since(start)
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		if result.searchResults != nil {
+			resultsChan <- *result.searchResults
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "ldap", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	configpb "github.com/cloudprober/cloudprober/probes/ldap/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testBaseDN     = "dc=example,dc=com"
+	testAdminDN    = "cn=admin,dc=example,dc=com"
+	testPassword   = "secret"
+	testNumEntries = 3
+
+	resultNoSuchObject       = 32
+	resultInvalidCredentials = 49
+	resultUnwillingToPerform = 53
+)
+
+// startServer starts a minimal LDAP server. It accepts anonymous binds,
+// simple binds for testAdminDN, SASL PLAIN for u:alice, and SASL EXTERNAL.
+// Searches under testBaseDN return testNumEntries entries.
+func startServer(t *testing.T, useTLS bool) endpoint.Endpoint {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if useTLS {
+		// Borrow httptest's certificate (valid for 127.0.0.1).
+		hs := httptest.NewTLSServer(http.NotFoundHandler())
+		ln = tls.NewListener(ln, &tls.Config{Certificates: hs.TLS.Certificates})
+		hs.Close()
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+}
+
+func resultOp(tag byte, code int64, diagnostic string) []byte {
+	return berConstructed(tag, berInt(tagEnumerated, code), berString(tagOctetString, ""), berString(tagOctetString, diagnostic))
+}
+
+// bindResult returns the result code for the bind request.
+func bindResult(opValue []byte) int64 {
+	_, _, rest, _ := parseTLV(opValue) // version
+	_, name, rest, _ := parseTLV(rest)
+	authTag, auth, _, _ := parseTLV(rest)
+
+	switch authTag {
+	case tagAuthSimple:
+		if (string(name) == "" && string(auth) == "") || (string(name) == testAdminDN && string(auth) == testPassword) {
+			return resultSuccess
+		}
+		return resultInvalidCredentials
+	case tagAuthSASL:
+		_, mech, rest, _ := parseTLV(auth)
+		_, creds, _, _ := parseTLV(rest)
+		switch string(mech) {
+		case "EXTERNAL":
+			return resultSuccess
+		case "PLAIN":
+			if string(creds) == "\x00u:alice\x00"+testPassword {
+				return resultSuccess
+			}
+			return resultInvalidCredentials
+		}
+	}
+	return resultUnwillingToPerform
+}
+
+func serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		content, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		msg, err := parseMessage(content)
+		if err != nil {
+			return
+		}
+
+		switch msg.opTag {
+		case tagBindRequest:
+			conn.Write(encodeMessage(msg.id, resultOp(tagBindResponse, bindResult(msg.opValue), "")))
+
+		case tagSearchRequest:
+			_, baseDN, rest, _ := parseTLV(msg.opValue)
+			_, _, rest, _ = parseTLV(rest) // scope
+			_, _, rest, _ = parseTLV(rest) // derefAliases
+			_, sizeLimit, _, _ := parseTLV(rest)
+
+			if string(baseDN) != testBaseDN {
+				conn.Write(encodeMessage(msg.id, resultOp(tagSearchResultDone, resultNoSuchObject, "no such object")))
+				continue
+			}
+			code := int64(resultSuccess)
+			for i := 0; i < testNumEntries; i++ {
+				if limit := parseInt(sizeLimit); limit > 0 && int64(i) >= limit {
+					code = resultSizeLimitExceeded
+					break
+				}
+				entry := berConstructed(tagSearchResultEntry,
+					berString(tagOctetString, fmt.Sprintf("cn=user%d,%s", i, testBaseDN)),
+					berConstructed(tagSequence))
+				conn.Write(encodeMessage(msg.id, entry))
+			}
+			conn.Write(encodeMessage(msg.id, resultOp(tagSearchResultDone, code, "")))
+
+		case tagUnbindRequest:
+			return
+		}
+	}
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "ldap_test", testutils.Options("127.0.0.1", time.Second, c))
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	search := func(baseDN string, sizeLimit, minResults int32) *configpb.ProbeConf_Search {
+		return &configpb.ProbeConf_Search{
+			BaseDn:     proto.String(baseDN),
+			Filter:     proto.String("(&(objectClass=person)(cn=user*))"),
+			SizeLimit:  proto.Int32(sizeLimit),
+			MinResults: proto.Int32(minResults),
+		}
+	}
+	simpleBind := func(s *configpb.ProbeConf_Search) *configpb.ProbeConf {
+		return &configpb.ProbeConf{BindDn: proto.String(testAdminDN), Password: proto.String(testPassword), Search: s}
+	}
+
+	for _, test := range []struct {
+		desc        string
+		conf        *configpb.ProbeConf
+		useTLS      bool
+		wantReason  string
+		wantEntries int64 // -1 if no search.
+	}{
+		{
+			desc:        "anonymous_bind",
+			conf:        &configpb.ProbeConf{},
+			wantEntries: -1,
+		},
+		{
+			desc:        "simple_bind",
+			conf:        simpleBind(nil),
+			wantEntries: -1,
+		},
+		{
+			desc:       "simple_bind_failure",
+			conf:       &configpb.ProbeConf{BindDn: proto.String(testAdminDN), Password: proto.String("wrong")},
+			wantReason: reasonBind,
+		},
+		{
+			desc: "sasl_plain",
+			conf: &configpb.ProbeConf{
+				AuthType: configpb.ProbeConf_SASL_PLAIN.Enum(),
+				BindDn:   proto.String("u:alice"),
+				Password: proto.String(testPassword),
+			},
+			wantEntries: -1,
+		},
+		{
+			desc: "sasl_plain_failure",
+			conf: &configpb.ProbeConf{
+				AuthType: configpb.ProbeConf_SASL_PLAIN.Enum(),
+				BindDn:   proto.String("u:alice"),
+				Password: proto.String("wrong"),
+			},
+			wantReason: reasonBind,
+		},
+		{
+			desc:        "sasl_external",
+			conf:        &configpb.ProbeConf{AuthType: configpb.ProbeConf_SASL_EXTERNAL.Enum()},
+			wantEntries: -1,
+		},
+		{
+			desc:        "search",
+			conf:        simpleBind(search(testBaseDN, 100, 1)),
+			wantEntries: testNumEntries,
+		},
+		{
+			desc:        "search_size_limit",
+			conf:        simpleBind(search(testBaseDN, 2, 0)),
+			wantEntries: 2,
+		},
+		{
+			desc:       "search_failure",
+			conf:       simpleBind(search("dc=other", 100, 0)),
+			wantReason: reasonSearch,
+		},
+		{
+			desc:       "too_few_results",
+			conf:       simpleBind(search(testBaseDN, 100, 5)),
+			wantReason: reasonTooFewResults,
+		},
+		{
+			desc: "ldaps_search",
+			conf: &configpb.ProbeConf{
+				Protocol:  configpb.ProbeConf_LDAPS.Enum(),
+				TlsConfig: &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+				BindDn:    proto.String(testAdminDN),
+				Password:  proto.String(testPassword),
+				Search:    search(testBaseDN, 100, 1),
+			},
+			useTLS:      true,
+			wantEntries: testNumEntries,
+		},
+		{
+			desc:       "ldaps_cert_failure",
+			conf:       &configpb.ProbeConf{Protocol: configpb.ProbeConf_LDAPS.Enum()},
+			useTLS:     true,
+			wantReason: reasonConnect,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			target := startServer(t, test.useTLS)
+
+			p := testProbe(t, test.conf)
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantReason != "" {
+				return
+			}
+
+			em := result.Metrics()
+			if em.Metric("bind_latency") == nil {
+				t.Errorf("Metric bind_latency missing: %s", em.String())
+			}
+			if test.wantEntries == -1 {
+				if em.Metric("search_latency") != nil || result.searchResults != nil {
+					t.Errorf("Got search metrics without a search: %s", em.String())
+				}
+				return
+			}
+			if em.Metric("search_latency") == nil {
+				t.Errorf("Metric search_latency missing: %s", em.String())
+			}
+			if result.searchResults == nil || result.searchResults.entries != test.wantEntries {
+				t.Errorf("Got search results: %v, want %d entries", result.searchResults, test.wantEntries)
+			}
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "sasl_plain_without_bind_dn", Conf: &configpb.ProbeConf{AuthType: configpb.ProbeConf_SASL_PLAIN.Enum()}},
+		{
+			Desc: "invalid_filter",
+			Conf: &configpb.ProbeConf{Search: &configpb.ProbeConf_Search{BaseDn: proto.String(testBaseDN), Filter: proto.String("cn=foo")}},
+		},
+		{
+			Desc: "min_results_over_size_limit",
+			Conf: &configpb.ProbeConf{Search: &configpb.ProbeConf_Search{BaseDn: proto.String(testBaseDN), SizeLimit: proto.Int32(1), MinResults: proto.Int32(2)}},
+		},
+	})
+}
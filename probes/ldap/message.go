@@ -0,0 +1,432 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// This file implements the subset of the LDAPv3 protocol (RFC 4511) messages,
+// and their BER encoding, used by the probe.
+
+// BER tags.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+)
+
+// LDAP protocol operation tags.
+const (
+	tagBindRequest           = 0x60
+	tagBindResponse          = 0x61
+	tagUnbindRequest         = 0x42
+	tagSearchRequest         = 0x63
+	tagSearchResultEntry     = 0x64
+	tagSearchResultDone      = 0x65
+	tagSearchResultReference = 0x73
+
+	tagAuthSimple = 0x80
+	tagAuthSASL   = 0xa3
+)
+
+// LDAP result codes used by the probe.
+const (
+	resultSuccess           = 0
+	resultSizeLimitExceeded = 4
+)
+
+// maxMessageSize limits the size of the messages read from the server.
+const maxMessageSize = 16 * 1024 * 1024
+
+// berTLV encodes a BER element with the given tag and content.
+func berTLV(tag byte, content []byte) []byte {
+	b := []byte{tag}
+	n := len(content)
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, content...)
+}
+
+func berConstructed(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, c := range children {
+		content = append(content, c...)
+	}
+	return berTLV(tag, content)
+}
+
+func berInt(tag byte, v int64) []byte {
+	// Minimal two's complement encoding.
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if (v < 0x80 && v >= -0x80) || len(b) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return berTLV(tag, b)
+}
+
+func berString(tag byte, s string) []byte {
+	return berTLV(tag, []byte(s))
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return berTLV(tagBoolean, []byte{0xff})
+	}
+	return berTLV(tagBoolean, []byte{0})
+}
+
+// parseTLV parses a BER element from b, and returns its tag and content, along
+// with the rest of b.
+func parseTLV(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	tag, n, b := b[0], int(b[1]), b[2:]
+	if n&0x80 != 0 {
+		numBytes := n & 0x7f
+		if numBytes == 0 || numBytes > 4 || len(b) < numBytes {
+			return 0, nil, nil, errors.New("invalid BER length")
+		}
+		n = 0
+		for _, c := range b[:numBytes] {
+			n = n<<8 | int(c)
+		}
+		b = b[numBytes:]
+	}
+	if n < 0 || len(b) < n {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	return tag, b[:n], b[n:], nil
+}
+
+func parseInt(content []byte) int64 {
+	var v int64
+	for i, c := range content {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// readMessage reads a BER element from r, and returns its content.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != tagSequence {
+		return nil, fmt.Errorf("unexpected message tag: 0x%x", header[0])
+	}
+
+	n := int(header[1])
+	if n&0x80 != 0 {
+		numBytes := n & 0x7f
+		if numBytes == 0 || numBytes > 4 {
+			return nil, errors.New("invalid BER length")
+		}
+		lenBytes := make([]byte, numBytes)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, err
+		}
+		n = 0
+		for _, c := range lenBytes {
+			n = n<<8 | int(c)
+		}
+	}
+	if n < 0 || n > maxMessageSize {
+		return nil, fmt.Errorf("invalid message length: %d", n)
+	}
+
+	content := make([]byte, n)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// message is an LDAP message received from the server.
+type message struct {
+	id      int64
+	opTag   byte
+	opValue []byte
+}
+
+func parseMessage(content []byte) (*message, error) {
+	tag, idBytes, rest, err := parseTLV(content)
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagInteger {
+		return nil, fmt.Errorf("unexpected message id tag: 0x%x", tag)
+	}
+	opTag, opValue, _, err := parseTLV(rest)
+	if err != nil {
+		return nil, err
+	}
+	return &message{id: parseInt(idBytes), opTag: opTag, opValue: opValue}, nil
+}
+
+// ldapResult is the LDAPResult part of the responses.
+type ldapResult struct {
+	code       int64
+	matchedDN  string
+	diagnostic string
+}
+
+func (r *ldapResult) Error() string {
+	if r.diagnostic != "" {
+		return fmt.Sprintf("LDAP result code %d: %s", r.code, r.diagnostic)
+	}
+	return fmt.Sprintf("LDAP result code %d", r.code)
+}
+
+func parseResult(opValue []byte) (*ldapResult, error) {
+	var fields [3][]byte
+	rest := opValue
+	for i := range fields {
+		var err error
+		if _, fields[i], rest, err = parseTLV(rest); err != nil {
+			return nil, fmt.Errorf("invalid LDAP result: %v", err)
+		}
+	}
+	return &ldapResult{
+		code:       parseInt(fields[0]),
+		matchedDN:  string(fields[1]),
+		diagnostic: string(fields[2]),
+	}, nil
+}
+
+func encodeMessage(id int64, op []byte) []byte {
+	return berConstructed(tagSequence, berInt(tagInteger, id), op)
+}
+
+func simpleBindRequest(dn, password string) []byte {
+	return berConstructed(tagBindRequest,
+		berInt(tagInteger, 3),
+		berString(tagOctetString, dn),
+		berString(tagAuthSimple, password))
+}
+
+// saslBindRequest returns a SASL bind request. Credentials are omitted if
+// nil.
+func saslBindRequest(mechanism string, credentials []byte) []byte {
+	sasl := [][]byte{berString(tagOctetString, mechanism)}
+	if credentials != nil {
+		sasl = append(sasl, berTLV(tagOctetString, credentials))
+	}
+	return berConstructed(tagBindRequest,
+		berInt(tagInteger, 3),
+		berString(tagOctetString, ""),
+		berConstructed(tagAuthSASL, sasl...))
+}
+
+func unbindRequest() []byte {
+	return berTLV(tagUnbindRequest, nil)
+}
+
+// searchRequest returns a search request, asking for no attributes (OID
+// 1.1), as we are only interested in the number of entries.
+func searchRequest(baseDN string, scope int64, filter []byte, sizeLimit int64) []byte {
+	return berConstructed(tagSearchRequest,
+		berString(tagOctetString, baseDN),
+		berInt(tagEnumerated, scope),
+		berInt(tagEnumerated, 0), // derefAliases: neverDerefAliases
+		berInt(tagInteger, sizeLimit),
+		berInt(tagInteger, 0), // timeLimit: bounded by the probe timeout.
+		berBool(false),
+		filter,
+		berConstructed(tagSequence, berString(tagOctetString, "1.1")))
+}
+
+// Filter tags.
+const (
+	tagFilterAnd        = 0xa0
+	tagFilterOr         = 0xa1
+	tagFilterNot        = 0xa2
+	tagFilterEquality   = 0xa3
+	tagFilterSubstrings = 0xa4
+	tagFilterGreater    = 0xa5
+	tagFilterLess       = 0xa6
+	tagFilterPresent    = 0x87
+	tagFilterApprox     = 0xa8
+
+	tagSubstringInitial = 0x80
+	tagSubstringAny     = 0x81
+	tagSubstringFinal   = 0x82
+)
+
+// parseFilter parses a string search filter (RFC 4515) into its BER
+// encoding. Extensible match filters are not supported.
+func parseFilter(s string) ([]byte, error) {
+	b, rest, err := parseFilterComp(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", s, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected trailing characters: %s", s, rest)
+	}
+	return b, nil
+}
+
+// parseFilterComp parses a parenthesized filter from the beginning of s, and
+// returns its encoding, along with the rest of s.
+func parseFilterComp(s string) ([]byte, string, error) {
+	if len(s) < 3 || s[0] != '(' {
+		return nil, "", errors.New("filter should be enclosed in parentheses")
+	}
+	s = s[1:]
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(tagFilterAnd)
+		if s[0] == '|' {
+			tag = tagFilterOr
+		}
+		s = s[1:]
+		var children [][]byte
+		for len(s) > 0 && s[0] == '(' {
+			child, rest, err := parseFilterComp(s)
+			if err != nil {
+				return nil, "", err
+			}
+			children, s = append(children, child), rest
+		}
+		if len(children) == 0 || len(s) == 0 || s[0] != ')' {
+			return nil, "", errors.New("invalid filter list")
+		}
+		return berConstructed(tag, children...), s[1:], nil
+
+	case '!':
+		child, rest, err := parseFilterComp(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", errors.New("missing closing parenthesis")
+		}
+		return berConstructed(tagFilterNot, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end == -1 {
+		return nil, "", errors.New("missing closing parenthesis")
+	}
+	b, err := parseFilterItem(s[:end])
+	return b, s[end+1:], err
+}
+
+// parseFilterItem parses a simple filter item, e.g. cn=foo*.
+func parseFilterItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("invalid filter item: %s", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	tag := byte(tagFilterEquality)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = tagFilterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = tagFilterLess, attr[:len(attr)-1]
+	case '~':
+		tag, attr = tagFilterApprox, attr[:len(attr)-1]
+	case ':':
+		return nil, fmt.Errorf("extensible match filters are not supported: %s", item)
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("invalid filter item: %s", item)
+	}
+
+	if tag == tagFilterEquality && value == "*" {
+		return berString(tagFilterPresent, attr), nil
+	}
+
+	if tag == tagFilterEquality && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		var subs [][]byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			v, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			subTag := byte(tagSubstringAny)
+			if i == 0 {
+				subTag = tagSubstringInitial
+			} else if i == len(parts)-1 {
+				subTag = tagSubstringFinal
+			}
+			subs = append(subs, berString(subTag, v))
+		}
+		return berConstructed(tagFilterSubstrings,
+			berString(tagOctetString, attr),
+			berConstructed(tagSequence, subs...)), nil
+	}
+
+	v, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return berConstructed(tag, berString(tagOctetString, attr), berString(tagOctetString, v)), nil
+}
+
+// unescapeFilterValue unescapes the \XX escapes in a filter value.
+func unescapeFilterValue(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("invalid escape in filter value: %s", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in filter value: %s", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
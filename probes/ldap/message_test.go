@@ -0,0 +1,117 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBERInt(t *testing.T) {
+	for _, test := range []struct {
+		v    int64
+		want string
+	}{
+		{v: 0, want: "020100"},
+		{v: 127, want: "02017f"},
+		{v: 128, want: "02020080"},
+		{v: 256, want: "02020100"},
+		{v: -1, want: "0201ff"},
+		{v: -129, want: "0202ff7f"},
+	} {
+		b := berInt(tagInteger, test.v)
+		if got := hex.EncodeToString(b); got != test.want {
+			t.Errorf("berInt(%d)=%s, want=%s", test.v, got, test.want)
+		}
+		if _, content, _, _ := parseTLV(b); parseInt(content) != test.v {
+			t.Errorf("parseInt(%x)=%d, want=%d", content, parseInt(content), test.v)
+		}
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	for _, size := range []int{10, 200, 70000} {
+		msg := encodeMessage(5, berString(tagOctetString, strings.Repeat("x", size)))
+		content, err := readMessage(bufio.NewReader(bytes.NewReader(msg)))
+		if err != nil {
+			t.Fatalf("Error reading message of size %d: %v", size, err)
+		}
+		m, err := parseMessage(content)
+		if err != nil {
+			t.Fatalf("Error parsing message of size %d: %v", size, err)
+		}
+		if m.id != 5 || m.opTag != tagOctetString || len(m.opValue) != size {
+			t.Errorf("Got message id=%d, tag=0x%x, value length=%d, want id=5, tag=0x4, value length=%d", m.id, m.opTag, len(m.opValue), size)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	ava := func(tag byte, attr, value string) []byte {
+		return berConstructed(tag, berString(tagOctetString, attr), berString(tagOctetString, value))
+	}
+	substrings := func(attr string, subs ...[]byte) []byte {
+		return berConstructed(tagFilterSubstrings, berString(tagOctetString, attr), berConstructed(tagSequence, subs...))
+	}
+
+	for _, test := range []struct {
+		filter  string
+		want    []byte
+		wantErr bool
+	}{
+		{filter: "(cn=foo)", want: ava(tagFilterEquality, "cn", "foo")},
+		{filter: "(objectClass=*)", want: berString(tagFilterPresent, "objectClass")},
+		{filter: "(uidNumber>=1000)", want: ava(tagFilterGreater, "uidNumber", "1000")},
+		{filter: "(uidNumber<=1000)", want: ava(tagFilterLess, "uidNumber", "1000")},
+		{filter: "(cn~=foo)", want: ava(tagFilterApprox, "cn", "foo")},
+		{filter: `(cn=a\2ab\29)`, want: ava(tagFilterEquality, "cn", "a*b)")},
+		{
+			filter: "(cn=ab*cd*ef)",
+			want:   substrings("cn", berString(tagSubstringInitial, "ab"), berString(tagSubstringAny, "cd"), berString(tagSubstringFinal, "ef")),
+		},
+		{
+			filter: "(cn=*cd*)",
+			want:   substrings("cn", berString(tagSubstringAny, "cd")),
+		},
+		{
+			filter: "(&(objectClass=person)(|(cn=foo)(!(cn=bar))))",
+			want: berConstructed(tagFilterAnd,
+				ava(tagFilterEquality, "objectClass", "person"),
+				berConstructed(tagFilterOr,
+					ava(tagFilterEquality, "cn", "foo"),
+					berConstructed(tagFilterNot, ava(tagFilterEquality, "cn", "bar")))),
+		},
+		{filter: "cn=foo", wantErr: true},
+		{filter: "(cn=foo", wantErr: true},
+		{filter: "(cn=foo))", wantErr: true},
+		{filter: "(&)", wantErr: true},
+		{filter: "(=foo)", wantErr: true},
+		{filter: "(cn:caseExactMatch:=foo)", wantErr: true},
+		{filter: `(cn=foo\2)`, wantErr: true},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			got, err := parseFilter(test.filter)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseFilter(%q) error: %v, want error: %v", test.filter, err, test.wantErr)
+			}
+			if !bytes.Equal(got, test.want) {
+				t.Errorf("parseFilter(%q)=%x, want=%x", test.filter, got, test.want)
+			}
+		})
+	}
+}
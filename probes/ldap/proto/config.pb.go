@@ -0,0 +1,554 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/ldap/proto/config.proto
+
+package proto
+
+import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf_ProtocolType int32
+
+const (
+	ProbeConf_LDAP  ProbeConf_ProtocolType = 0
+	ProbeConf_LDAPS ProbeConf_ProtocolType = 1
+)
+
+// Enum value maps for ProbeConf_ProtocolType.
+var (
+	ProbeConf_ProtocolType_name = map[int32]string{
+		0: "LDAP",
+		1: "LDAPS",
+	}
+	ProbeConf_ProtocolType_value = map[string]int32{
+		"LDAP":  0,
+		"LDAPS": 1,
+	}
+)
+
+func (x ProbeConf_ProtocolType) Enum() *ProbeConf_ProtocolType {
+	p := new(ProbeConf_ProtocolType)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_ProtocolType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_ProtocolType) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_ProtocolType) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_ProtocolType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_ProtocolType) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_ProtocolType(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_ProtocolType.Descriptor instead.
+func (ProbeConf_ProtocolType) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf_AuthType int32
+
+const (
+	// Simple bind with bind_dn and password. Anonymous bind if both are
+	// empty.
+	ProbeConf_SIMPLE ProbeConf_AuthType = 0
+	// SASL PLAIN with bind_dn as the authentication identity, e.g.
+	// "u:alice", and password.
+	ProbeConf_SASL_PLAIN ProbeConf_AuthType = 1
+	// SASL EXTERNAL, e.g. with the TLS client certificate.
+	ProbeConf_SASL_EXTERNAL ProbeConf_AuthType = 2
+)
+
+// Enum value maps for ProbeConf_AuthType.
+var (
+	ProbeConf_AuthType_name = map[int32]string{
+		0: "SIMPLE",
+		1: "SASL_PLAIN",
+		2: "SASL_EXTERNAL",
+	}
+	ProbeConf_AuthType_value = map[string]int32{
+		"SIMPLE":        0,
+		"SASL_PLAIN":    1,
+		"SASL_EXTERNAL": 2,
+	}
+)
+
+func (x ProbeConf_AuthType) Enum() *ProbeConf_AuthType {
+	p := new(ProbeConf_AuthType)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_AuthType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_AuthType) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeConf_AuthType) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeConf_AuthType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_AuthType) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_AuthType(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_AuthType.Descriptor instead.
+func (ProbeConf_AuthType) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+type ProbeConf_Search_Scope int32
+
+const (
+	ProbeConf_Search_BASE      ProbeConf_Search_Scope = 0
+	ProbeConf_Search_ONE_LEVEL ProbeConf_Search_Scope = 1
+	ProbeConf_Search_SUBTREE   ProbeConf_Search_Scope = 2
+)
+
+// Enum value maps for ProbeConf_Search_Scope.
+var (
+	ProbeConf_Search_Scope_name = map[int32]string{
+		0: "BASE",
+		1: "ONE_LEVEL",
+		2: "SUBTREE",
+	}
+	ProbeConf_Search_Scope_value = map[string]int32{
+		"BASE":      0,
+		"ONE_LEVEL": 1,
+		"SUBTREE":   2,
+	}
+)
+
+func (x ProbeConf_Search_Scope) Enum() *ProbeConf_Search_Scope {
+	p := new(ProbeConf_Search_Scope)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Search_Scope) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Search_Scope) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[2].Descriptor()
+}
+
+func (ProbeConf_Search_Scope) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes[2]
+}
+
+func (x ProbeConf_Search_Scope) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Search_Scope) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Search_Scope(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Search_Scope.Descriptor instead.
+func (ProbeConf_Search_Scope) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP(), []int{0, 0, 0}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Protocol *ProbeConf_ProtocolType `protobuf:"varint,1,opt,name=protocol,enum=cloudprober.probes.ldap.ProbeConf_ProtocolType,def=0" json:"protocol,omitempty"`
+	// Port to connect to. If not specified, target's port is used, and if the
+	// target doesn't have a port either, 389 (LDAP) or 636 (LDAPS) is used.
+	Port *int32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	// TLS config for LDAPS. If server_name is not set, target's name is used.
+	// Client certificate, if configured, can be used with SASL EXTERNAL.
+	TlsConfig *proto.TLSConfig    `protobuf:"bytes,3,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	AuthType  *ProbeConf_AuthType `protobuf:"varint,4,opt,name=auth_type,json=authType,enum=cloudprober.probes.ldap.ProbeConf_AuthType,def=0" json:"auth_type,omitempty"`
+	BindDn    *string             `protobuf:"bytes,5,opt,name=bind_dn,json=bindDn" json:"bind_dn,omitempty"`
+	Password  *string             `protobuf:"bytes,6,opt,name=password" json:"password,omitempty"`
+	Search    *ProbeConf_Search   `protobuf:"bytes,7,opt,name=search" json:"search,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Protocol = ProbeConf_LDAP
+	Default_ProbeConf_AuthType = ProbeConf_SIMPLE
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetProtocol() ProbeConf_ProtocolType {
+	if x != nil && x.Protocol != nil {
+		return *x.Protocol
+	}
+	return Default_ProbeConf_Protocol
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetAuthType() ProbeConf_AuthType {
+	if x != nil && x.AuthType != nil {
+		return *x.AuthType
+	}
+	return Default_ProbeConf_AuthType
+}
+
+func (x *ProbeConf) GetBindDn() string {
+	if x != nil && x.BindDn != nil {
+		return *x.BindDn
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetSearch() *ProbeConf_Search {
+	if x != nil {
+		return x.Search
+	}
+	return nil
+}
+
+// Search to run after the bind. If not configured, probe runs succeed once
+// bound.
+type ProbeConf_Search struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BaseDn *string                 `protobuf:"bytes,1,req,name=base_dn,json=baseDn" json:"base_dn,omitempty"`
+	Scope  *ProbeConf_Search_Scope `protobuf:"varint,2,opt,name=scope,enum=cloudprober.probes.ldap.ProbeConf_Search_Scope,def=2" json:"scope,omitempty"`
+	// Search filter, in the string representation (RFC 4515). Extensible
+	// match filters are not supported.
+	Filter *string `protobuf:"bytes,3,opt,name=filter,def=(objectClass=*)" json:"filter,omitempty"`
+	// Maximum number of entries to return. Search is considered successful
+	// if the server returns only these many entries because of the limit.
+	SizeLimit *int32 `protobuf:"varint,4,opt,name=size_limit,json=sizeLimit,def=100" json:"size_limit,omitempty"`
+	// Minimum number of entries expected. Probe runs fail if the search
+	// returns fewer entries.
+	MinResults *int32 `protobuf:"varint,5,opt,name=min_results,json=minResults,def=0" json:"min_results,omitempty"`
+}
+
+// Default values for ProbeConf_Search fields.
+const (
+	Default_ProbeConf_Search_Scope      = ProbeConf_Search_SUBTREE
+	Default_ProbeConf_Search_Filter     = string("(objectClass=*)")
+	Default_ProbeConf_Search_SizeLimit  = int32(100)
+	Default_ProbeConf_Search_MinResults = int32(0)
+)
+
+func (x *ProbeConf_Search) Reset() {
+	*x = ProbeConf_Search{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf_Search) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf_Search) ProtoMessage() {}
+
+func (x *ProbeConf_Search) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf_Search.ProtoReflect.Descriptor instead.
+func (*ProbeConf_Search) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *ProbeConf_Search) GetBaseDn() string {
+	if x != nil && x.BaseDn != nil {
+		return *x.BaseDn
+	}
+	return ""
+}
+
+func (x *ProbeConf_Search) GetScope() ProbeConf_Search_Scope {
+	if x != nil && x.Scope != nil {
+		return *x.Scope
+	}
+	return Default_ProbeConf_Search_Scope
+}
+
+func (x *ProbeConf_Search) GetFilter() string {
+	if x != nil && x.Filter != nil {
+		return *x.Filter
+	}
+	return Default_ProbeConf_Search_Filter
+}
+
+func (x *ProbeConf_Search) GetSizeLimit() int32 {
+	if x != nil && x.SizeLimit != nil {
+		return *x.SizeLimit
+	}
+	return Default_ProbeConf_Search_SizeLimit
+}
+
+func (x *ProbeConf_Search) GetMinResults() int32 {
+	if x != nil && x.MinResults != nil {
+		return *x.MinResults
+	}
+	return Default_ProbeConf_Search_MinResults
+}
+
+var File_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6c, 0x64, 0x61, 0x70,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x17, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61, 0x70, 0x1a, 0x46, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf1, 0x05, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
+	0x6e, 0x66, 0x12, 0x51, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x2f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61, 0x70, 0x2e, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f,
+	0x6c, 0x54, 0x79, 0x70, 0x65, 0x3a, 0x04, 0x4c, 0x44, 0x41, 0x50, 0x52, 0x08, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x3f, 0x0a, 0x0a, 0x74, 0x6c, 0x73,
+	0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52,
+	0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x50, 0x0a, 0x09, 0x61, 0x75,
+	0x74, 0x68, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2b, 0x2e,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e,
+	0x66, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x54, 0x79, 0x70, 0x65, 0x3a, 0x06, 0x53, 0x49, 0x4d, 0x50,
+	0x4c, 0x45, 0x52, 0x08, 0x61, 0x75, 0x74, 0x68, 0x54, 0x79, 0x70, 0x65, 0x12, 0x17, 0x0a, 0x07,
+	0x62, 0x69, 0x6e, 0x64, 0x5f, 0x64, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x62,
+	0x69, 0x6e, 0x64, 0x44, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72,
+	0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72,
+	0x64, 0x12, 0x41, 0x0a, 0x06, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x29, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62,
+	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x06, 0x73, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x1a, 0x91, 0x02, 0x0a, 0x06, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x12,
+	0x17, 0x0a, 0x07, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x6e, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09,
+	0x52, 0x06, 0x62, 0x61, 0x73, 0x65, 0x44, 0x6e, 0x12, 0x4e, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61,
+	0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x3a, 0x07, 0x53, 0x55, 0x42, 0x54, 0x52, 0x45,
+	0x45, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x27, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x74,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x0f, 0x28, 0x6f, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x3d, 0x2a, 0x29, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65,
+	0x72, 0x12, 0x22, 0x0a, 0x0a, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x03, 0x31, 0x30, 0x30, 0x52, 0x09, 0x73, 0x69, 0x7a, 0x65,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x22, 0x0a, 0x0b, 0x6d, 0x69, 0x6e, 0x5f, 0x72, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x30, 0x52, 0x0a, 0x6d,
+	0x69, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x2d, 0x0a, 0x05, 0x53, 0x63, 0x6f,
+	0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x42, 0x41, 0x53, 0x45, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09,
+	0x4f, 0x4e, 0x45, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x53,
+	0x55, 0x42, 0x54, 0x52, 0x45, 0x45, 0x10, 0x02, 0x22, 0x23, 0x0a, 0x0c, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x63, 0x6f, 0x6c, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x4c, 0x44, 0x41, 0x50,
+	0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x4c, 0x44, 0x41, 0x50, 0x53, 0x10, 0x01, 0x22, 0x39, 0x0a,
+	0x08, 0x41, 0x75, 0x74, 0x68, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x49, 0x4d,
+	0x50, 0x4c, 0x45, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x41, 0x53, 0x4c, 0x5f, 0x50, 0x4c,
+	0x41, 0x49, 0x4e, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x53, 0x41, 0x53, 0x4c, 0x5f, 0x45, 0x58,
+	0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x10, 0x02, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6c, 0x64, 0x61, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_ProtocolType)(0), // 0: cloudprober.probes.ldap.ProbeConf.ProtocolType
+	(ProbeConf_AuthType)(0),     // 1: cloudprober.probes.ldap.ProbeConf.AuthType
+	(ProbeConf_Search_Scope)(0), // 2: cloudprober.probes.ldap.ProbeConf.Search.Scope
+	(*ProbeConf)(nil),           // 3: cloudprober.probes.ldap.ProbeConf
+	(*ProbeConf_Search)(nil),    // 4: cloudprober.probes.ldap.ProbeConf.Search
+	(*proto.TLSConfig)(nil),     // 5: cloudprober.tlsconfig.TLSConfig
+}
+var file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.ldap.ProbeConf.protocol:type_name -> cloudprober.probes.ldap.ProbeConf.ProtocolType
+	5, // 1: cloudprober.probes.ldap.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	1, // 2: cloudprober.probes.ldap.ProbeConf.auth_type:type_name -> cloudprober.probes.ldap.ProbeConf.AuthType
+	4, // 3: cloudprober.probes.ldap.ProbeConf.search:type_name -> cloudprober.probes.ldap.ProbeConf.Search
+	2, // 4: cloudprober.probes.ldap.ProbeConf.Search.scope:type_name -> cloudprober.probes.ldap.ProbeConf.Search.Scope
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf_Search); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDesc,
+			NumEnums:      3,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_ldap_proto_config_proto_depIdxs = nil
+}
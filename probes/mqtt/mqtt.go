@@ -0,0 +1,512 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package mqtt implements an MQTT round-trip prober. It connects to the target
+brokers, over TCP or TLS, using MQTT 3.1.1 or 5, subscribes to a topic and
+publishes a message to the same topic, and waits for the broker to deliver the
+message back. It reports statistics on probe runs, successful runs and
+latency, along with the connect, publish and delivery latencies, and the
+reason of the failures. Messages not delivered back within the timeout are
+reported as failures with the reason "lost".
+
+Probes for all targets run in parallel.
+*/
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/mqtt/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonConnect   = "connect"
+	reasonSubscribe = "subscribe"
+	reasonPublish   = "publish"
+	reasonReceive   = "receive"
+	reasonLost      = "lost"
+)
+
+// Packet identifiers. There is only one outstanding SUBSCRIBE and PUBLISH per
+// connection.
+const (
+	subscribeID = 1
+	publishID   = 2
+)
+
+const keepAliveSec = 60
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets   []endpoint.Endpoint
+	dialF     func(context.Context, string, string) (net.Conn, error)
+	tlsConfig *tls.Config // nil for TCP.
+	level     byte
+	qos       byte
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	connectLatency    metrics.Value
+	deliveryLatency   metrics.Value
+	latencyMetricName string
+
+	// publishLatency is exported only for QoS 1.
+	publishLatency metrics.Value
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures).
+		AddMetric("connect_latency", prr.connectLatency)
+	if prr.publishLatency != nil {
+		em.AddMetric("publish_latency", prr.publishLatency)
+	}
+	em.AddMetric("delivery_latency", prr.deliveryLatency)
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no mqtt config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("mqtt_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if p.c.GetQos() != 0 && p.c.GetQos() != 1 {
+		return fmt.Errorf("mqtt_probe(%s): invalid qos: %d, only 0 and 1 are supported", name, p.c.GetQos())
+	}
+	p.qos = byte(p.c.GetQos())
+
+	if p.c.GetTopic() == "" || strings.ContainsAny(p.c.GetTopic(), "+#") {
+		return fmt.Errorf("mqtt_probe(%s): invalid topic: %q, it should be non-empty and without wildcards", name, p.c.GetTopic())
+	}
+
+	if p.c.GetPayloadSize() < 0 || p.c.GetPayloadSize() > maxPacketSize/2 {
+		return fmt.Errorf("mqtt_probe(%s): invalid payload_size: %d", name, p.c.GetPayloadSize())
+	}
+
+	p.level = level311
+	if p.c.GetVersion() == configpb.ProbeConf_MQTT_5 {
+		p.level = level5
+	}
+	if p.level == level311 && p.c.GetPassword() != "" && p.c.GetUsername() == "" {
+		return fmt.Errorf("mqtt_probe(%s): MQTT 3.1.1 doesn't allow password without username", name)
+	}
+
+	if p.c.GetTransport() == configpb.ProbeConf_TLS {
+		p.tlsConfig = &tls.Config{}
+		if p.c.GetTlsConfig() != nil {
+			if err := tlsconfig.UpdateTLSConfig(p.tlsConfig, p.c.GetTlsConfig(), false); err != nil {
+				return fmt.Errorf("mqtt_probe(%s): error configuring TLS: %v", name, err)
+			}
+		}
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+		connectLatency:    p.newLatencyValue(),
+		deliveryLatency:   p.newLatencyValue(),
+	}
+
+	if p.qos > 0 {
+		result.publishLatency = p.newLatencyValue()
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// payload returns a unique payload for a probe run, padded to the configured
+// size.
+func (p *Probe) payload(target string) []byte {
+	b := []byte(fmt.Sprintf("cloudprober %s %s %d", p.name, target, time.Now().UnixNano()))
+	if pad := int(p.c.GetPayloadSize()) - len(b); pad > 0 {
+		b = append(b, bytes.Repeat([]byte{'.'}, pad)...)
+	}
+	return b
+}
+
+// conn is an MQTT client connection.
+type conn struct {
+	net.Conn
+	r     *bufio.Reader
+	level byte
+}
+
+func (c *conn) send(pkt *packet) error {
+	_, err := c.Write(pkt.marshal())
+	return err
+}
+
+func (c *conn) subscribe(topic string, qos byte) error {
+	if err := c.send(subscribePacket(c.level, subscribeID, topic, qos)); err != nil {
+		return err
+	}
+	pkt, err := readPacket(c.r)
+	if err != nil {
+		return err
+	}
+	id, code, err := parseSuback(c.level, pkt)
+	if err != nil {
+		return err
+	}
+	if id != subscribeID {
+		return fmt.Errorf("unexpected SUBACK packet id: %d, want: %d", id, subscribeID)
+	}
+	if code >= 0x80 {
+		return fmt.Errorf("broker rejected the subscription, code: 0x%x", code)
+	}
+	return nil
+}
+
+// connect connects to the target, completing the TLS handshake for the TLS
+// transport, and the MQTT connection handshake.
+func (p *Probe) connect(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) (*conn, error) {
+	defaultPort := 1883
+	if p.tlsConfig != nil {
+		defaultPort = 8883
+	}
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(target.Name, strconv.Itoa(port))
+
+	netConn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	options.RecordIPVersionUsed(result.ipVersionUsed, netConn)
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	if p.tlsConfig != nil {
+		tlsConfig := p.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = target.Name
+		}
+		tlsConn := tls.Client(netConn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s failed: %w", addr, err)
+		}
+		netConn = tlsConn
+	}
+
+	c := &conn{Conn: netConn, r: bufio.NewReader(netConn), level: p.level}
+	err = c.send(connectPacket(p.level, p.c.GetClientId(), p.c.GetUsername(), p.c.GetPassword(), keepAliveSec))
+	if err == nil {
+		var pkt *packet
+		if pkt, err = readPacket(c.r); err == nil {
+			var code byte
+			if code, err = parseConnack(pkt); err == nil && code != 0 {
+				err = fmt.Errorf("broker refused the connection, code: 0x%x", code)
+			}
+		}
+	}
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("MQTT connect to %s failed: %w", addr, err)
+	}
+	return c, nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	failed := func(reason string, err error) {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reason)
+	}
+
+	start := time.Now()
+	c, err := p.connect(ctx, target, result)
+	if err != nil {
+		failed(reasonConnect, err)
+		return
+	}
+	defer c.Close()
+	result.connectLatency.AddFloat64(time.Since(start).Seconds() / p.opts.LatencyUnit.Seconds())
+
+	topic := p.c.GetTopic()
+	if err := c.subscribe(topic, p.qos); err != nil {
+		failed(reasonSubscribe, fmt.Errorf("subscribe to %s failed: %w", topic, err))
+		return
+	}
+
+	payload := p.payload(target.Name)
+	publishStart := time.Now()
+	if err := c.send(publishPacket(p.level, publishID, topic, p.qos, payload)); err != nil {
+		failed(reasonPublish, fmt.Errorf("publish failed: %w", err))
+		return
+	}
+
+	// Broker may deliver the message back before acknowledging it, so we
+	// wait for both together.
+	acked, delivered := p.qos == 0, false
+	for !acked || !delivered {
+		pkt, err := readPacket(c.r)
+		if err != nil {
+			switch {
+			case !acked:
+				failed(reasonPublish, fmt.Errorf("error waiting for PUBACK: %w", err))
+			case isTimeout(err):
+				// Lost messages are not counted as timeouts.
+				p.l.Warningf("Target(%s): message not delivered back within the timeout", target.Name)
+				result.failures.IncKey(reasonLost)
+			default:
+				failed(reasonReceive, fmt.Errorf("error waiting for the message: %w", err))
+			}
+			return
+		}
+
+		switch pkt.typ {
+		case packetPuback:
+			id, code, err := parsePuback(pkt)
+			if err == nil && id != publishID {
+				err = fmt.Errorf("unexpected PUBACK packet id: %d, want: %d", id, publishID)
+			}
+			if err != nil {
+				failed(reasonPublish, err)
+				return
+			}
+			if code >= 0x80 {
+				failed(reasonPublish, fmt.Errorf("broker rejected the message, code: 0x%x", code))
+				return
+			}
+			acked = true
+			result.publishLatency.AddFloat64(time.Since(publishStart).Seconds() / p.opts.LatencyUnit.Seconds())
+
+		case packetPublish:
+			msg, err := parsePublish(p.level, pkt)
+			if err != nil {
+				failed(reasonReceive, fmt.Errorf("invalid PUBLISH packet: %w", err))
+				return
+			}
+			if msg.qos > 0 {
+				if err := c.send(pubackPacket(msg.id)); err != nil {
+					failed(reasonReceive, fmt.Errorf("error acknowledging the message: %w", err))
+					return
+				}
+			}
+			// Ignore messages published by others.
+			if !delivered && bytes.Equal(msg.payload, payload) {
+				delivered = true
+				result.deliveryLatency.AddFloat64(time.Since(publishStart).Seconds() / p.opts.LatencyUnit.Seconds())
+			}
+
+		default:
+			p.l.Debugf("Target(%s): ignoring packet of type %d", target.Name, pkt.typ)
+		}
+	}
+
+	if err := c.send(disconnectPacket()); err != nil {
+		p.l.Debugf("Target(%s): DISCONNECT failed: %v", target.Name, err)
+	}
+
+	latency := time.Since(start)
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "mqtt", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	configpb "github.com/cloudprober/cloudprober/probes/mqtt/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testUsername = "alice"
+	testPassword = "secret"
+)
+
+type testBroker struct {
+	useTLS bool
+
+	// Broker behavior.
+	dropMessages bool // Acknowledge but don't deliver messages.
+	rejectTopic  bool // Reject subscriptions.
+	othersFirst  bool // Deliver someone else's message first.
+}
+
+// start starts a minimal MQTT broker that delivers the messages published on
+// a connection back to the same connection, if it's subscribed.
+func (tb *testBroker) start(t *testing.T) endpoint.Endpoint {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.useTLS {
+		// Borrow httptest's certificate (valid for 127.0.0.1).
+		hs := httptest.NewTLSServer(http.NotFoundHandler())
+		ln = tls.NewListener(ln, &tls.Config{Certificates: hs.TLS.Certificates})
+		hs.Close()
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go tb.serve(conn)
+		}
+	}()
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+}
+
+func (tb *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	send := func(typ, flags byte, body []byte) {
+		conn.Write((&packet{typ: typ, flags: flags, body: body}).marshal())
+	}
+
+	var level byte
+	var subscribed string
+	for {
+		pkt, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch pkt.typ {
+		case packetConnect:
+			_, b, _ := readString(pkt.body)
+			level, b = b[0], b[1:]
+			flags := b[0]
+			b = b[3:] // flags and keep alive
+			if level == level5 {
+				b, _ = skipProperties(b)
+			}
+			_, b, _ = readString(b) // client id
+			var username, password string
+			if flags&0x80 != 0 {
+				username, b, _ = readString(b)
+			}
+			if flags&0x40 != 0 {
+				password, _, _ = readString(b)
+			}
+
+			code := byte(0)
+			if username != "" && (username != testUsername || password != testPassword) {
+				code = 4 // Bad user name or password.
+				if level == level5 {
+					code = 0x86
+				}
+			}
+			body := []byte{0, code}
+			if level == level5 {
+				body = appendVarInt(body, 0)
+			}
+			send(packetConnack, 0, body)
+			if code != 0 {
+				return
+			}
+
+		case packetSubscribe:
+			id, b := pkt.body[:2], pkt.body[2:]
+			if level == level5 {
+				b, _ = skipProperties(b)
+			}
+			topic, b, _ := readString(b)
+			code := b[0] & 0x03
+			if tb.rejectTopic {
+				code = 0x80
+			} else {
+				subscribed = topic
+			}
+			body := append([]byte{}, id...)
+			if level == level5 {
+				body = appendVarInt(body, 0)
+			}
+			send(packetSuback, 0, append(body, code))
+
+		case packetPublish:
+			msg, err := parsePublish(level, pkt)
+			if err != nil {
+				return
+			}
+			if tb.othersFirst {
+				conn.Write(publishPacket(level, 100, msg.topic, msg.qos, []byte("someone else")).marshal())
+			}
+			if !tb.dropMessages && msg.topic == subscribed {
+				conn.Write(publishPacket(level, 101, msg.topic, msg.qos, msg.payload).marshal())
+			}
+			if msg.qos > 0 {
+				conn.Write(pubackPacket(msg.id).marshal())
+			}
+
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "mqtt_test", testutils.Options("127.0.0.1", time.Second, c))
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	v5 := configpb.ProbeConf_MQTT_5.Enum()
+
+	for _, test := range []struct {
+		desc       string
+		conf       *configpb.ProbeConf
+		broker     testBroker
+		wantReason string
+	}{
+		{
+			desc: "v311",
+			conf: &configpb.ProbeConf{},
+		},
+		{
+			desc: "v5",
+			conf: &configpb.ProbeConf{Version: v5},
+		},
+		{
+			desc: "v311_qos0",
+			conf: &configpb.ProbeConf{Qos: proto.Int32(0)},
+		},
+		{
+			desc: "v5_qos0",
+			conf: &configpb.ProbeConf{Version: v5, Qos: proto.Int32(0)},
+		},
+		{
+			desc: "v5_auth",
+			conf: &configpb.ProbeConf{Version: v5, Username: proto.String(testUsername), Password: proto.String(testPassword)},
+		},
+		{
+			desc:       "v311_auth_failure",
+			conf:       &configpb.ProbeConf{Username: proto.String(testUsername), Password: proto.String("wrong")},
+			wantReason: reasonConnect,
+		},
+		{
+			desc:       "v5_auth_failure",
+			conf:       &configpb.ProbeConf{Version: v5, Username: proto.String(testUsername), Password: proto.String("wrong")},
+			wantReason: reasonConnect,
+		},
+		{
+			desc:   "others_messages",
+			conf:   &configpb.ProbeConf{Version: v5, PayloadSize: proto.Int32(1024)},
+			broker: testBroker{othersFirst: true},
+		},
+		{
+			desc:       "subscribe_failure",
+			conf:       &configpb.ProbeConf{},
+			broker:     testBroker{rejectTopic: true},
+			wantReason: reasonSubscribe,
+		},
+		{
+			desc:       "lost",
+			conf:       &configpb.ProbeConf{Version: v5},
+			broker:     testBroker{dropMessages: true},
+			wantReason: reasonLost,
+		},
+		{
+			desc: "tls",
+			conf: &configpb.ProbeConf{
+				Version:   v5,
+				Transport: configpb.ProbeConf_TLS.Enum(),
+				TlsConfig: &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+			},
+			broker: testBroker{useTLS: true},
+		},
+		{
+			desc:       "tls_cert_failure",
+			conf:       &configpb.ProbeConf{Transport: configpb.ProbeConf_TLS.Enum()},
+			broker:     testBroker{useTLS: true},
+			wantReason: reasonConnect,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			target := test.broker.start(t)
+
+			p := testProbe(t, test.conf)
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			// Lost messages are not counted as timeouts.
+			if result.timeouts.Int64() != 0 {
+				t.Errorf("Got timeouts=%d, want=0", result.timeouts.Int64())
+			}
+
+			if test.wantReason != "" {
+				return
+			}
+
+			em := result.Metrics()
+			for _, m := range []string{"connect_latency", "delivery_latency"} {
+				if em.Metric(m) == nil {
+					t.Errorf("Metric %s missing: %s", m, em.String())
+				}
+			}
+			if gotPublish, wantPublish := em.Metric("publish_latency") != nil, test.conf.GetQos() > 0; gotPublish != wantPublish {
+				t.Errorf("Got publish_latency: %v, want: %v (metrics: %s)", gotPublish, wantPublish, em.String())
+			}
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "invalid_qos", Conf: &configpb.ProbeConf{Qos: proto.Int32(2)}},
+		{Desc: "wildcard_topic", Conf: &configpb.ProbeConf{Topic: proto.String("cloudprober/#")}},
+		{Desc: "empty_topic", Conf: &configpb.ProbeConf{Topic: proto.String("")}},
+		{Desc: "invalid_payload_size", Conf: &configpb.ProbeConf{PayloadSize: proto.Int32(-1)}},
+		{Desc: "password_without_username", Conf: &configpb.ProbeConf{Password: proto.String(testPassword)}},
+	})
+}
@@ -0,0 +1,281 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// This file implements the subset of the MQTT 3.1.1 and 5 control packets
+// used by the probe.
+
+// Control packet types.
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetSubscribe  = 8
+	packetSuback     = 9
+	packetDisconnect = 14
+)
+
+// Protocol levels.
+const (
+	level311 = 4
+	level5   = 5
+)
+
+// maxPacketSize limits the size of the packets read from the broker.
+const maxPacketSize = 1024 * 1024
+
+// packet is a control packet, with its fixed header's type and flags, and
+// the rest of the packet.
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// appendVarInt appends a variable byte integer, used for the remaining length
+// and the properties length.
+func appendVarInt(b []byte, v int) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+// readVarInt reads a variable byte integer from b, and returns it along with
+// the rest of b.
+func readVarInt(b []byte) (int, []byte, error) {
+	var v int
+	for i := 0; i < 4 && i < len(b); i++ {
+		v |= int(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return v, b[i+1:], nil
+		}
+	}
+	return 0, nil, errors.New("invalid variable byte integer")
+}
+
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("short packet")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("short packet")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// skipProperties skips the MQTT 5 properties at the beginning of b.
+func skipProperties(b []byte) ([]byte, error) {
+	n, b, err := readVarInt(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < n {
+		return nil, errors.New("short packet")
+	}
+	return b[n:], nil
+}
+
+// marshal returns the wire format of the packet.
+func (p *packet) marshal() []byte {
+	b := appendVarInt([]byte{p.typ<<4 | p.flags}, len(p.body))
+	return append(b, p.body...)
+}
+
+func readPacket(r *bufio.Reader) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var length int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return nil, errors.New("invalid remaining length")
+		}
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length |= int(c&0x7f) << (7 * i)
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	if length > maxPacketSize {
+		return nil, fmt.Errorf("packet too large: %d bytes", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &packet{typ: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// connectPacket returns a CONNECT packet, for a clean session.
+func connectPacket(level byte, clientID, username, password string, keepAliveSec uint16) *packet {
+	flags := byte(0x02) // Clean session (clean start in MQTT 5).
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+
+	b := appendString(nil, "MQTT")
+	b = append(b, level, flags)
+	b = appendUint16(b, keepAliveSec)
+	if level == level5 {
+		b = appendVarInt(b, 0) // No properties.
+	}
+	b = appendString(b, clientID)
+	if username != "" {
+		b = appendString(b, username)
+	}
+	if password != "" {
+		b = appendString(b, password)
+	}
+	return &packet{typ: packetConnect, body: b}
+}
+
+// parseConnack returns the CONNACK packet's return code (reason code in
+// MQTT 5).
+func parseConnack(p *packet) (byte, error) {
+	if p.typ != packetConnack || len(p.body) < 2 {
+		return 0, fmt.Errorf("unexpected packet, type: %d, length: %d, want CONNACK", p.typ, len(p.body))
+	}
+	return p.body[1], nil
+}
+
+func subscribePacket(level byte, id uint16, topic string, qos byte) *packet {
+	b := appendUint16(nil, id)
+	if level == level5 {
+		b = appendVarInt(b, 0)
+	}
+	b = appendString(b, topic)
+	b = append(b, qos)
+	return &packet{typ: packetSubscribe, flags: 0x02, body: b}
+}
+
+// parseSuback returns the SUBACK packet's packet id, and the return code
+// (reason code in MQTT 5) for the first topic.
+func parseSuback(level byte, p *packet) (uint16, byte, error) {
+	if p.typ != packetSuback || len(p.body) < 3 {
+		return 0, 0, fmt.Errorf("unexpected packet, type: %d, length: %d, want SUBACK", p.typ, len(p.body))
+	}
+	id, b := binary.BigEndian.Uint16(p.body), p.body[2:]
+	if level == level5 {
+		var err error
+		if b, err = skipProperties(b); err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(b) < 1 {
+		return 0, 0, errors.New("short SUBACK packet")
+	}
+	return id, b[0], nil
+}
+
+// publishPacket returns a PUBLISH packet. Packet id is used only for QoS > 0.
+func publishPacket(level byte, id uint16, topic string, qos byte, payload []byte) *packet {
+	b := appendString(nil, topic)
+	if qos > 0 {
+		b = appendUint16(b, id)
+	}
+	if level == level5 {
+		b = appendVarInt(b, 0)
+	}
+	b = append(b, payload...)
+	return &packet{typ: packetPublish, flags: qos << 1, body: b}
+}
+
+// publishMessage is a received PUBLISH packet.
+type publishMessage struct {
+	topic   string
+	qos     byte
+	id      uint16 // Only for QoS > 0.
+	payload []byte
+}
+
+func parsePublish(level byte, p *packet) (*publishMessage, error) {
+	topic, b, err := readString(p.body)
+	if err != nil {
+		return nil, err
+	}
+	msg := &publishMessage{topic: topic, qos: (p.flags >> 1) & 0x03}
+	if msg.qos > 0 {
+		if len(b) < 2 {
+			return nil, errors.New("short PUBLISH packet")
+		}
+		msg.id, b = binary.BigEndian.Uint16(b), b[2:]
+	}
+	if level == level5 {
+		if b, err = skipProperties(b); err != nil {
+			return nil, err
+		}
+	}
+	msg.payload = b
+	return msg, nil
+}
+
+func pubackPacket(id uint16) *packet {
+	// MQTT 5 allows omitting the reason code for success.
+	return &packet{typ: packetPuback, body: appendUint16(nil, id)}
+}
+
+// parsePuback returns the PUBACK packet's packet id and reason code. Reason
+// code is always 0 (success) for MQTT 3.1.1.
+func parsePuback(p *packet) (uint16, byte, error) {
+	if p.typ != packetPuback || len(p.body) < 2 {
+		return 0, 0, fmt.Errorf("unexpected packet, type: %d, length: %d, want PUBACK", p.typ, len(p.body))
+	}
+	var reason byte
+	if len(p.body) > 2 {
+		reason = p.body[2]
+	}
+	return binary.BigEndian.Uint16(p.body), reason, nil
+}
+
+func disconnectPacket() *packet {
+	// MQTT 5 allows omitting the reason code for normal disconnection.
+	return &packet{typ: packetDisconnect}
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVarInt(t *testing.T) {
+	for _, test := range []struct {
+		v    int
+		want string
+	}{
+		{v: 0, want: "00"},
+		{v: 127, want: "7f"},
+		{v: 128, want: "8001"},
+		{v: 16383, want: "ff7f"},
+		{v: 16384, want: "808001"},
+		{v: 268435455, want: "ffffff7f"},
+	} {
+		b := appendVarInt(nil, test.v)
+		if got := hex.EncodeToString(b); got != test.want {
+			t.Errorf("appendVarInt(%d)=%s, want=%s", test.v, got, test.want)
+		}
+		if got, rest, err := readVarInt(b); err != nil || got != test.v || len(rest) != 0 {
+			t.Errorf("readVarInt(%x)=%d, %x, %v, want=%d", b, got, rest, err, test.v)
+		}
+	}
+}
+
+func TestPublishRoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 300)
+
+	for _, level := range []byte{level311, level5} {
+		for _, qos := range []byte{0, 1} {
+			pkt := publishPacket(level, 7, "a/b", qos, payload)
+			got, err := readPacket(bufio.NewReader(bytes.NewReader(pkt.marshal())))
+			if err != nil {
+				t.Fatalf("level=%d, qos=%d: error reading packet: %v", level, qos, err)
+			}
+			msg, err := parsePublish(level, got)
+			if err != nil {
+				t.Fatalf("level=%d, qos=%d: error parsing packet: %v", level, qos, err)
+			}
+
+			wantID := uint16(0)
+			if qos > 0 {
+				wantID = 7
+			}
+			if msg.topic != "a/b" || msg.qos != qos || msg.id != wantID || !bytes.Equal(msg.payload, payload) {
+				t.Errorf("level=%d, qos=%d: got message: topic=%s, qos=%d, id=%d, payload length=%d", level, qos, msg.topic, msg.qos, msg.id, len(msg.payload))
+			}
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/mqtt/proto/config.proto
+
+package proto
+
+import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf_ProtocolVersion int32
+
+const (
+	ProbeConf_MQTT_3_1_1 ProbeConf_ProtocolVersion = 0
+	ProbeConf_MQTT_5     ProbeConf_ProtocolVersion = 1
+)
+
+// Enum value maps for ProbeConf_ProtocolVersion.
+var (
+	ProbeConf_ProtocolVersion_name = map[int32]string{
+		0: "MQTT_3_1_1",
+		1: "MQTT_5",
+	}
+	ProbeConf_ProtocolVersion_value = map[string]int32{
+		"MQTT_3_1_1": 0,
+		"MQTT_5":     1,
+	}
+)
+
+func (x ProbeConf_ProtocolVersion) Enum() *ProbeConf_ProtocolVersion {
+	p := new(ProbeConf_ProtocolVersion)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_ProtocolVersion) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_ProtocolVersion) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_ProtocolVersion) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_ProtocolVersion) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_ProtocolVersion) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_ProtocolVersion(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_ProtocolVersion.Descriptor instead.
+func (ProbeConf_ProtocolVersion) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf_Transport int32
+
+const (
+	ProbeConf_TCP ProbeConf_Transport = 0
+	ProbeConf_TLS ProbeConf_Transport = 1
+)
+
+// Enum value maps for ProbeConf_Transport.
+var (
+	ProbeConf_Transport_name = map[int32]string{
+		0: "TCP",
+		1: "TLS",
+	}
+	ProbeConf_Transport_value = map[string]int32{
+		"TCP": 0,
+		"TLS": 1,
+	}
+)
+
+func (x ProbeConf_Transport) Enum() *ProbeConf_Transport {
+	p := new(ProbeConf_Transport)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Transport) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Transport) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeConf_Transport) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeConf_Transport) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Transport) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Transport(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Transport.Descriptor instead.
+func (ProbeConf_Transport) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version   *ProbeConf_ProtocolVersion `protobuf:"varint,1,opt,name=version,enum=cloudprober.probes.mqtt.ProbeConf_ProtocolVersion,def=0" json:"version,omitempty"`
+	Transport *ProbeConf_Transport       `protobuf:"varint,2,opt,name=transport,enum=cloudprober.probes.mqtt.ProbeConf_Transport,def=0" json:"transport,omitempty"`
+	// Port to connect to. If not specified, target's port is used, and if the
+	// target doesn't have a port either, 1883 (TCP) or 8883 (TLS) is used.
+	Port *int32 `protobuf:"varint,3,opt,name=port" json:"port,omitempty"`
+	// TLS config, used only for the TLS transport. If server_name is not set,
+	// target's name is used.
+	TlsConfig *proto.TLSConfig `protobuf:"bytes,4,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	// Client identifier. If not specified, the broker assigns one.
+	ClientId *string `protobuf:"bytes,5,opt,name=client_id,json=clientId" json:"client_id,omitempty"`
+	Username *string `protobuf:"bytes,6,opt,name=username" json:"username,omitempty"`
+	Password *string `protobuf:"bytes,7,opt,name=password" json:"password,omitempty"`
+	// Topic to publish to and subscribe to. Probe runs publish a message and
+	// wait for the broker to deliver it back. Messages published to this topic
+	// by others are ignored. Wildcards are not allowed.
+	Topic *string `protobuf:"bytes,8,opt,name=topic,def=cloudprober/probe" json:"topic,omitempty"`
+	// QoS for publishing and subscribing: 0 (at most once) or 1 (at least
+	// once). With QoS 1, publish latency is the time until the broker
+	// acknowledges the message.
+	Qos *int32 `protobuf:"varint,9,opt,name=qos,def=1" json:"qos,omitempty"`
+	// Payload size, in bytes. Payload includes a unique message identifier and
+	// is padded to this size, if required.
+	PayloadSize *int32 `protobuf:"varint,10,opt,name=payload_size,json=payloadSize,def=64" json:"payload_size,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Version     = ProbeConf_MQTT_3_1_1
+	Default_ProbeConf_Transport   = ProbeConf_TCP
+	Default_ProbeConf_Topic       = string("cloudprober/probe")
+	Default_ProbeConf_Qos         = int32(1)
+	Default_ProbeConf_PayloadSize = int32(64)
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetVersion() ProbeConf_ProtocolVersion {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return Default_ProbeConf_Version
+}
+
+func (x *ProbeConf) GetTransport() ProbeConf_Transport {
+	if x != nil && x.Transport != nil {
+		return *x.Transport
+	}
+	return Default_ProbeConf_Transport
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetClientId() string {
+	if x != nil && x.ClientId != nil {
+		return *x.ClientId
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetUsername() string {
+	if x != nil && x.Username != nil {
+		return *x.Username
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetTopic() string {
+	if x != nil && x.Topic != nil {
+		return *x.Topic
+	}
+	return Default_ProbeConf_Topic
+}
+
+func (x *ProbeConf) GetQos() int32 {
+	if x != nil && x.Qos != nil {
+		return *x.Qos
+	}
+	return Default_ProbeConf_Qos
+}
+
+func (x *ProbeConf) GetPayloadSize() int32 {
+	if x != nil && x.PayloadSize != nil {
+		return *x.PayloadSize
+	}
+	return Default_ProbeConf_PayloadSize
+}
+
+var File_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6d, 0x71, 0x74, 0x74,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x17, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6d, 0x71, 0x74, 0x74, 0x1a, 0x46, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x93, 0x04, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
+	0x6e, 0x66, 0x12, 0x58, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x32, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6d, 0x71, 0x74, 0x74, 0x2e, 0x50, 0x72,
+	0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x3a, 0x0a, 0x4d, 0x51, 0x54, 0x54, 0x5f, 0x33, 0x5f,
+	0x31, 0x5f, 0x31, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x4f, 0x0a, 0x09,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x2c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6d, 0x71, 0x74, 0x74, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x3a, 0x03, 0x54,
+	0x43, 0x50, 0x52, 0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72,
+	0x74, 0x12, 0x3f, 0x0a, 0x0a, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c,
+	0x53, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x27, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x11, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63,
+	0x12, 0x13, 0x0a, 0x03, 0x71, 0x6f, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x31,
+	0x52, 0x03, 0x71, 0x6f, 0x73, 0x12, 0x25, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
+	0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x02, 0x36, 0x34, 0x52,
+	0x0b, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x69, 0x7a, 0x65, 0x22, 0x2d, 0x0a, 0x0f,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x0e, 0x0a, 0x0a, 0x4d, 0x51, 0x54, 0x54, 0x5f, 0x33, 0x5f, 0x31, 0x5f, 0x31, 0x10, 0x00, 0x12,
+	0x0a, 0x0a, 0x06, 0x4d, 0x51, 0x54, 0x54, 0x5f, 0x35, 0x10, 0x01, 0x22, 0x1d, 0x0a, 0x09, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x43, 0x50, 0x10,
+	0x00, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x4c, 0x53, 0x10, 0x01, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6d, 0x71, 0x74, 0x74, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_ProtocolVersion)(0), // 0: cloudprober.probes.mqtt.ProbeConf.ProtocolVersion
+	(ProbeConf_Transport)(0),       // 1: cloudprober.probes.mqtt.ProbeConf.Transport
+	(*ProbeConf)(nil),              // 2: cloudprober.probes.mqtt.ProbeConf
+	(*proto.TLSConfig)(nil),        // 3: cloudprober.tlsconfig.TLSConfig
+}
+var file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.mqtt.ProbeConf.version:type_name -> cloudprober.probes.mqtt.ProbeConf.ProtocolVersion
+	1, // 1: cloudprober.probes.mqtt.ProbeConf.transport:type_name -> cloudprober.probes.mqtt.ProbeConf.Transport
+	3, // 2: cloudprober.probes.mqtt.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_mqtt_proto_config_proto_depIdxs = nil
+}
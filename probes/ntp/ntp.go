@@ -0,0 +1,477 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package ntp implements an NTP prober. It sends an NTP client (mode 3) request
+to the targets and parses their responses, as described in RFC 5905. It
+reports statistics on requests, valid responses and round-trip latency, along
+with the reason of the failures. For every valid response, it also reports the
+server's clock offset, round-trip delay and stratum.
+
+Requests to all targets are sent in parallel.
+*/
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	configpb "github.com/cloudprober/cloudprober/probes/ntp/proto"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+const defaultPort = 123
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonDNSTimeout      = "dns_timeout"
+	reasonResolve         = "resolve"
+	reasonNetwork         = "network"
+	reasonTimeout         = "timeout"
+	reasonInvalidResponse = "invalid_response"
+	reasonKissOfDeath     = "kiss_of_death"
+	reasonStratum0        = "stratum_0"
+	reasonUnsynchronized  = "unsynchronized"
+)
+
+// NTP packet constants.
+const (
+	packetLen  = 48
+	modeClient = 3
+	modeServer = 4
+
+	// Leap indicator value for the servers with unsynchronized clocks.
+	leapAlarm = 3
+	// Strata above this are unsynchronized.
+	maxStratum = 15
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets []endpoint.Endpoint
+	dialF   func(context.Context, string, string) (net.Conn, error)
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	latencyMetricName string
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+
+	// Server's clock details, reported as a separate result. nil if the run
+	// didn't succeed.
+	clock *clockResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures)
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// clockResult is a gauge update for the server's clock offset, round-trip
+// delay and stratum.
+type clockResult struct {
+	target  string
+	offset  time.Duration
+	rtt     time.Duration
+	stratum int64
+}
+
+// Metrics converts clockResult into metrics.EventMetrics object
+func (cr clockResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("offset_ms", metrics.NewFloat(float64(cr.offset)/float64(time.Millisecond))).
+		AddMetric("rtt_ms", metrics.NewFloat(float64(cr.rtt)/float64(time.Millisecond))).
+		AddMetric("stratum", metrics.NewInt(cr.stratum))
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the cr.target.
+func (cr clockResult) Target() string {
+	return cr.target
+}
+
+// toNTPTime converts the given time to the 64-bit NTP timestamp format.
+func toNTPTime(t time.Time) uint64 {
+	secs := uint64(t.Unix()+ntpEpochOffset) & 0xffffffff
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// fromNTPTime converts the 64-bit NTP timestamp to time. Timestamps with the
+// most significant bit unset are taken to be in the next NTP era (2036
+// onwards), as suggested by RFC 4330.
+func fromNTPTime(ts uint64) time.Time {
+	secs := int64(ts >> 32)
+	if secs&0x80000000 == 0 {
+		secs += 1 << 32
+	}
+	nsec := int64(((ts & 0xffffffff) * uint64(time.Second)) >> 32)
+	return time.Unix(secs-ntpEpochOffset, nsec)
+}
+
+// response is the parsed NTP server response.
+type response struct {
+	leap     uint8
+	mode     uint8
+	stratum  uint8
+	refID    [4]byte
+	origin   uint64
+	received time.Time
+	transmit time.Time
+}
+
+func parseResponse(b []byte) (*response, error) {
+	if len(b) < packetLen {
+		return nil, fmt.Errorf("short response: %d bytes, want at least %d", len(b), packetLen)
+	}
+	resp := &response{
+		leap:     b[0] >> 6,
+		mode:     b[0] & 0x7,
+		stratum:  b[1],
+		origin:   binary.BigEndian.Uint64(b[24:32]),
+		received: fromNTPTime(binary.BigEndian.Uint64(b[32:40])),
+		transmit: fromNTPTime(binary.BigEndian.Uint64(b[40:48])),
+	}
+	copy(resp.refID[:], b[12:16])
+	return resp, nil
+}
+
+// kissCode returns the kiss code carried by the stratum 0 responses in place
+// of the reference ID, e.g. "RATE" or "DENY", or an empty string if the
+// reference ID is not printable ASCII.
+func (resp *response) kissCode() string {
+	for _, c := range resp.refID {
+		if c < 0x20 || c > 0x7e {
+			return ""
+		}
+	}
+	return string(resp.refID[:])
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no ntp config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("ntp_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+	if p.c.GetVersion() != 3 && p.c.GetVersion() != 4 {
+		return fmt.Errorf("ntp_probe(%s): unsupported NTP version: %d", name, p.c.GetVersion())
+	}
+
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.UDPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+	}
+
+	if p.opts.LatencyDist != nil {
+		result.latency = p.opts.LatencyDist.Clone()
+	} else {
+		result.latency = metrics.NewFloat(0)
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// checkResponse validates the server response for the request sent with the
+// given transmit timestamp. It returns the failure reason, along with the
+// error, if the response is not usable.
+func (p *Probe) checkResponse(resp *response, txTimestamp uint64) (string, error) {
+	if resp.mode != modeServer {
+		return reasonInvalidResponse, fmt.Errorf("unexpected mode in response: %d", resp.mode)
+	}
+	if resp.origin != txTimestamp {
+		return reasonInvalidResponse, fmt.Errorf("response's origin timestamp (%x) doesn't match the request's (%x)", resp.origin, txTimestamp)
+	}
+	if resp.stratum == 0 {
+		if code := resp.kissCode(); code != "" {
+			return reasonKissOfDeath, fmt.Errorf("kiss-o'-death from the server: %s", code)
+		}
+		return reasonStratum0, errors.New("stratum 0 response")
+	}
+	if resp.leap == leapAlarm || resp.stratum > maxStratum {
+		return reasonUnsynchronized, fmt.Errorf("server is not synchronized, leap indicator: %d, stratum: %d", resp.leap, resp.stratum)
+	}
+	return "", nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	ip, err := p.opts.ResolveTarget(target.Name, nil)
+	if err != nil {
+		p.l.Warningf("Target(%s): resolve error: %v", target.Name, err)
+		if options.IsDNSTimeout(err) {
+			result.failures.IncKey(reasonDNSTimeout)
+		} else {
+			result.failures.IncKey(reasonResolve)
+		}
+		return
+	}
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+
+	conn, err := p.dialF(ctx, "udp", addr)
+	if err != nil {
+		p.l.Warningf("Target(%s): error connecting to %s: %v", target.Name, addr, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+			result.failures.IncKey(reasonTimeout)
+		} else {
+			result.failures.IncKey(reasonNetwork)
+		}
+		return
+	}
+	defer conn.Close()
+	options.RecordIPVersionUsed(result.ipVersionUsed, conn)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, packetLen)
+	req[0] = uint8(p.c.GetVersion())<<3 | modeClient
+
+	// We send our transmit timestamp (T1) in the request, and the server
+	// echoes it as the origin timestamp, letting us match the response.
+	t1 := time.Now()
+	txTimestamp := toNTPTime(t1)
+	binary.BigEndian.PutUint64(req[40:48], txTimestamp)
+
+	buf := make([]byte, 1024)
+	n := 0
+	if _, err = conn.Write(req); err == nil {
+		n, err = conn.Read(buf)
+	}
+	t4 := time.Now()
+	if err != nil {
+		p.l.Warningf("Target(%s): NTP request to %s failed: %v", target.Name, addr, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+			result.failures.IncKey(reasonTimeout)
+		} else {
+			result.failures.IncKey(reasonNetwork)
+		}
+		return
+	}
+
+	resp, err := parseResponse(buf[:n])
+	if err != nil {
+		p.l.Warningf("Target(%s): invalid NTP response from %s: %v", target.Name, addr, err)
+		result.failures.IncKey(reasonInvalidResponse)
+		return
+	}
+	if reason, err := p.checkResponse(resp, txTimestamp); err != nil {
+		p.l.Warningf("Target(%s): bad NTP response from %s (%s): %v", target.Name, addr, reason, err)
+		result.failures.IncKey(reason)
+		return
+	}
+
+	// Offset and round-trip delay, as defined by RFC 5905, where T2 and T3
+	// are the server's receive and transmit timestamps. T4-T1 uses the local
+	// monotonic clock.
+	offset := (resp.received.Sub(t1) + resp.transmit.Sub(t4)) / 2
+	rtt := t4.Sub(t1) - resp.transmit.Sub(resp.received)
+	if rtt < 0 {
+		rtt = 0
+	}
+
+	result.success.Inc()
+	result.latency.AddFloat64(rtt.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = rtt
+	result.clock = &clockResult{
+		target:  target.Name,
+		offset:  offset,
+		rtt:     rtt,
+		stratum: int64(resp.stratum),
+	}
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		if result.clock != nil {
+			resultsChan <- *result.clock
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "ntp", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/ntp/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+// mockServer configures the mock NTP responder's replies.
+type mockServer struct {
+	offset      time.Duration // Server clock's offset from the local clock.
+	procTime    time.Duration // Time between the server's receive and transmit.
+	leap        uint8
+	mode        uint8
+	stratum     uint8
+	refID       string
+	wrongOrigin bool
+	noReply     bool
+}
+
+// serve starts a mock NTP responder and returns its port.
+func (ms *mockServer) serve(t *testing.T) int32 {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting mock NTP server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if ms.noReply || n < packetLen {
+				continue
+			}
+			received := time.Now().Add(ms.offset)
+
+			resp := make([]byte, packetLen)
+			resp[0] = ms.leap<<6 | (buf[0] & 0x38) | ms.mode
+			resp[1] = ms.stratum
+			copy(resp[12:16], ms.refID)
+			origin := binary.BigEndian.Uint64(buf[40:48])
+			if ms.wrongOrigin {
+				origin++
+			}
+			binary.BigEndian.PutUint64(resp[24:32], origin)
+			binary.BigEndian.PutUint64(resp[32:40], toNTPTime(received))
+			binary.BigEndian.PutUint64(resp[40:48], toNTPTime(received.Add(ms.procTime)))
+			conn.WriteTo(resp, addr)
+		}
+	}()
+
+	return int32(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "ntp_test", testutils.Options("127.0.0.1", 500*time.Millisecond, c))
+	return p
+}
+
+func TestNTPTime(t *testing.T) {
+	for _, ts := range []time.Time{
+		time.Date(2021, 6, 1, 12, 30, 15, 123456789, time.UTC),
+		time.Date(2036, 2, 7, 6, 28, 16, 0, time.UTC), // NTP era 1 starts.
+		time.Date(2040, 1, 1, 0, 0, 0, 500000000, time.UTC),
+	} {
+		got := fromNTPTime(toNTPTime(ts))
+		if d := got.Sub(ts); d < -time.Nanosecond || d > time.Nanosecond {
+			t.Errorf("fromNTPTime(toNTPTime(%v))=%v, want=%v", ts, got, ts)
+		}
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		server      *mockServer
+		wantReason  string
+		wantTimeout bool
+	}{
+		{
+			desc:   "synchronized",
+			server: &mockServer{mode: modeServer, stratum: 2},
+		},
+		{
+			desc:   "offset",
+			server: &mockServer{mode: modeServer, stratum: 1, offset: 1500 * time.Millisecond, procTime: 20 * time.Millisecond},
+		},
+		{
+			desc:   "negative_offset",
+			server: &mockServer{mode: modeServer, stratum: 3, offset: -2 * time.Second},
+		},
+		{
+			desc:       "kiss_of_death",
+			server:     &mockServer{mode: modeServer, stratum: 0, refID: "RATE"},
+			wantReason: reasonKissOfDeath,
+		},
+		{
+			desc:       "stratum_0",
+			server:     &mockServer{mode: modeServer, stratum: 0, refID: "\x00\x00\x00\x00"},
+			wantReason: reasonStratum0,
+		},
+		{
+			desc:       "unsynchronized",
+			server:     &mockServer{mode: modeServer, stratum: 2, leap: leapAlarm},
+			wantReason: reasonUnsynchronized,
+		},
+		{
+			desc:       "origin_mismatch",
+			server:     &mockServer{mode: modeServer, stratum: 2, wrongOrigin: true},
+			wantReason: reasonInvalidResponse,
+		},
+		{
+			desc:       "wrong_mode",
+			server:     &mockServer{mode: modeClient, stratum: 2},
+			wantReason: reasonInvalidResponse,
+		},
+		{
+			desc:        "no_reply",
+			server:      &mockServer{noReply: true},
+			wantReason:  reasonTimeout,
+			wantTimeout: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			port := test.server.serve(t)
+			p := testProbe(t, &configpb.ProbeConf{Port: proto.Int32(port)})
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantReason != "" {
+				if (result.timeouts.Int64() == 1) != test.wantTimeout {
+					t.Errorf("Got timeouts=%d, want timeout=%v", result.timeouts.Int64(), test.wantTimeout)
+				}
+				if result.clock != nil {
+					t.Errorf("Got clock result for a failed run: %+v", result.clock)
+				}
+				return
+			}
+
+			if result.clock == nil {
+				t.Fatal("Got no clock result")
+			}
+			if result.clock.stratum != int64(test.server.stratum) {
+				t.Errorf("Got stratum=%d, want=%d", result.clock.stratum, test.server.stratum)
+			}
+
+			// Local round trip is fast, but give it some slack on the busy
+			// test machines.
+			slack := 50 * time.Millisecond
+			wantOffset := test.server.offset + test.server.procTime/2
+			if d := result.clock.offset - wantOffset; d < -slack || d > slack {
+				t.Errorf("Got offset=%v, want=%v (+/- %v)", result.clock.offset, wantOffset, slack)
+			}
+			if result.clock.rtt < 0 || result.clock.rtt > slack {
+				t.Errorf("Got rtt=%v, want between 0 and %v", result.clock.rtt, slack)
+			}
+
+			em := result.clock.Metrics()
+			for _, name := range []string{"offset_ms", "rtt_ms", "stratum"} {
+				if em.Metric(name) == nil {
+					t.Errorf("Metric %s missing from the clock result: %s", name, em.String())
+				}
+			}
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "invalid_version", Conf: &configpb.ProbeConf{Version: proto.Int32(2)}},
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/cloudprober/cloudprober/common/iputils"
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// ConnectTimeoutError is returned by the dial function returned by
+// DialContextFunc if the connection setup times out.
+type ConnectTimeoutError struct {
+	Addr string
+	Err  error
+}
+
+func (e *ConnectTimeoutError) Error() string {
+	return fmt.Sprintf("connect timeout (%s): %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying dial error.
+func (e *ConnectTimeoutError) Unwrap() error { return e.Err }
+
+// Timeout is part of the net.Error interface.
+func (e *ConnectTimeoutError) Timeout() bool { return true }
+
+// Temporary is part of the net.Error interface.
+func (e *ConnectTimeoutError) Temporary() bool { return true }
+
+// IsConnectTimeout returns true if the given error (or an error that it
+// wraps) is a ConnectTimeoutError.
+func IsConnectTimeout(err error) bool {
+	var ctErr *ConnectTimeoutError
+	return errors.As(err, &ctErr)
+}
+
+// DialContextFunc returns a dial function, that uses the given dialer and
+// bounds the connection setup by the probe's connect timeout, if configured.
+// Connection setup timing out before the parent context is reported as a
+// ConnectTimeoutError.
+//
+// If IP version fallback is configured (see ip_version_mode), host names are
+// dialed using the preferred IP version first, and if that fails, using the
+// fallback IP version.
+func (opts *Options) DialContextFunc(dialer *net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+	dial := opts.connectTimeoutDialFunc(dialer)
+	if opts.FallbackIPVersion == 0 {
+		return dial
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Nothing to fall back to for IP addresses.
+		if host, _, err := net.SplitHostPort(addr); err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		conn, err := dial(ctx, networkForIPVersion(network, opts.IPVersion), addr)
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+
+		conn, fallbackErr := dial(ctx, networkForIPVersion(network, opts.FallbackIPVersion), addr)
+		if fallbackErr != nil {
+			return nil, fmt.Errorf("%v; fallback to IPv%d failed: %w", err, opts.FallbackIPVersion, fallbackErr)
+		}
+		return conn, nil
+	}
+}
+
+func (opts *Options) connectTimeoutDialFunc(dialer *net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+	if opts.ConnectTimeout == 0 {
+		return dialer.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialCtx, cancel := context.WithTimeout(ctx, opts.ConnectTimeout)
+		defer cancel()
+
+		conn, err := dialer.DialContext(dialCtx, network, addr)
+		if err != nil && ctx.Err() == nil && dialCtx.Err() == context.DeadlineExceeded {
+			return nil, &ConnectTimeoutError{Addr: addr, Err: err}
+		}
+		return conn, err
+	}
+}
+
+// networkForIPVersion returns the network name restricted to the IP version,
+// e.g. "tcp6" for "tcp" and 6.
+func networkForIPVersion(network string, ipVer int) string {
+	switch network {
+	case "tcp", "udp", "ip":
+		return network + strconv.Itoa(ipVer)
+	}
+	return network
+}
+
+// IPVersionUsedMap returns a new map metric to count the IP versions used for
+// the connections, keyed by "ip_version". Probes export it as the
+// "ip_version_used" metric. It returns nil if IP version fallback is not
+// configured, as the IP version used is fixed then.
+func (opts *Options) IPVersionUsedMap() *metrics.Map {
+	if opts.FallbackIPVersion == 0 {
+		return nil
+	}
+	return metrics.NewMap("ip_version", metrics.NewInt(0))
+}
+
+// AddrIPVersion returns the IP version (4 or 6) of the given network address,
+// or 0 if it's not an IP address.
+func AddrIPVersion(addr net.Addr) int {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return iputils.IPVersion(a.IP)
+	case *net.UDPAddr:
+		return iputils.IPVersion(a.IP)
+	case *net.IPAddr:
+		return iputils.IPVersion(a.IP)
+	}
+	return 0
+}
+
+// RecordIPVersionUsed increments the IP version of the connection's remote
+// address in the given map, created by IPVersionUsedMap. It's a no-op for a
+// nil map.
+func RecordIPVersionUsed(m *metrics.Map, conn net.Conn) {
+	if m == nil || conn == nil {
+		return
+	}
+	if ipVer := AddrIPVersion(conn.RemoteAddr()); ipVer != 0 {
+		m.IncKey(strconv.Itoa(ipVer))
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+// slowDialer returns a dialer that waits for the given delay before starting
+// the connection setup, simulating a slow handshake.
+func slowDialer(delay time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Control: func(_, _ string, _ syscall.RawConn) error {
+			time.Sleep(delay)
+			return nil
+		},
+	}
+}
+
+func TestDialContextFunc(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	defer ln.Close()
+
+	for _, test := range []struct {
+		desc               string
+		connectTimeout     time.Duration
+		ctxTimeout         time.Duration
+		dialDelay          time.Duration
+		wantErr            bool
+		wantConnectTimeout bool
+	}{
+		{
+			desc:           "no_connect_timeout",
+			ctxTimeout:     time.Second,
+			dialDelay:      100 * time.Millisecond,
+			connectTimeout: 0,
+		},
+		{
+			desc:           "fast_connect",
+			connectTimeout: 500 * time.Millisecond,
+			ctxTimeout:     time.Second,
+		},
+		{
+			desc:               "slow_connect",
+			connectTimeout:     50 * time.Millisecond,
+			ctxTimeout:         time.Second,
+			dialDelay:          100 * time.Millisecond,
+			wantErr:            true,
+			wantConnectTimeout: true,
+		},
+		{
+			desc:           "overall_timeout_first",
+			connectTimeout: 500 * time.Millisecond,
+			ctxTimeout:     50 * time.Millisecond,
+			dialDelay:      100 * time.Millisecond,
+			wantErr:        true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			opts := &Options{ConnectTimeout: test.connectTimeout}
+			dialF := opts.DialContextFunc(slowDialer(test.dialDelay))
+
+			ctx, cancel := context.WithTimeout(context.Background(), test.ctxTimeout)
+			defer cancel()
+
+			conn, err := dialF(ctx, "tcp", ln.Addr().String())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Got error: %v, want error: %v", err, test.wantErr)
+			}
+			if err == nil {
+				conn.Close()
+			}
+			if IsConnectTimeout(err) != test.wantConnectTimeout {
+				t.Errorf("IsConnectTimeout(%v)=%v, want=%v", err, IsConnectTimeout(err), test.wantConnectTimeout)
+			}
+		})
+	}
+}
+
+func TestConnectTimeoutConfig(t *testing.T) {
+	for _, test := range []struct {
+		connectTimeoutMsec int32
+		want               time.Duration
+		wantError          bool
+	}{
+		{connectTimeoutMsec: 0},
+		{connectTimeoutMsec: 200, want: 200 * time.Millisecond},
+		{connectTimeoutMsec: 1000, want: time.Second},
+		{connectTimeoutMsec: 1500, wantError: true},
+		{connectTimeoutMsec: -1, wantError: true},
+	} {
+		p := &configpb.ProbeDef{
+			Targets:            testTargets,
+			TimeoutMsec:        proto.Int32(1000),
+			ConnectTimeoutMsec: proto.Int32(test.connectTimeoutMsec),
+		}
+		opts, err := BuildProbeOptions(p, nil, nil, nil)
+		if (err != nil) != test.wantError {
+			t.Fatalf("connect_timeout_msec=%d: BuildProbeOptions() error=%v, wantError=%v", test.connectTimeoutMsec, err, test.wantError)
+		}
+		if err == nil && opts.ConnectTimeout != test.want {
+			t.Errorf("connect_timeout_msec=%d: got ConnectTimeout=%v, want=%v", test.connectTimeoutMsec, opts.ConnectTimeout, test.want)
+		}
+	}
+}
+
+func TestDialContextFuncFallback(t *testing.T) {
+	// Listen on IPv4 only, so that dialing localhost over IPv6 fails.
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	for _, test := range []struct {
+		desc            string
+		ipVer, fallback int
+		addr            string
+		wantErr         bool
+		wantIPVersion   string
+	}{
+		{
+			desc:          "prefer_ipv6_fallback_to_ipv4",
+			ipVer:         6,
+			fallback:      4,
+			addr:          net.JoinHostPort("localhost", port),
+			wantIPVersion: "map:ip_version,4:1",
+		},
+		{
+			desc:          "ip_address",
+			ipVer:         6,
+			fallback:      4,
+			addr:          ln.Addr().String(),
+			wantIPVersion: "map:ip_version,4:1",
+		},
+		{
+			desc:    "ipv6_only",
+			ipVer:   6,
+			addr:    net.JoinHostPort("localhost", port),
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			opts := &Options{IPVersion: test.ipVer, FallbackIPVersion: test.fallback}
+			dialF := opts.DialContextFunc(&net.Dialer{})
+			// Without fallback, dial function doesn't restrict the IP version,
+			// so we do it here.
+			network := "tcp"
+			if test.fallback == 0 {
+				network = networkForIPVersion(network, test.ipVer)
+			}
+
+			conn, err := dialF(context.Background(), network, test.addr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Got error: %v, want error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			m := opts.IPVersionUsedMap()
+			RecordIPVersionUsed(m, conn)
+			if m.String() != test.wantIPVersion {
+				t.Errorf("ip_version_used=%s, want=%s", m.String(), test.wantIPVersion)
+			}
+		})
+	}
+}
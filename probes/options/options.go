@@ -18,13 +18,18 @@ Package options provides a shared interface to common probe options.
 package options
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudprober/cloudprober/common/iputils"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	configpb "github.com/cloudprober/cloudprober/probes/proto"
 	"github.com/cloudprober/cloudprober/targets"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
@@ -32,21 +37,47 @@ import (
 	"github.com/cloudprober/cloudprober/validators"
 )
 
+var exportTargetsChanges = flag.Bool("export_targets_changes", false, "Export the number of targets added to and removed from each probe as the targets_added_total and targets_removed_total self-metrics (sysvars).")
+
 // Options encapsulates common probe options.
 type Options struct {
 	Targets             targets.Targets
 	Interval, Timeout   time.Duration
+	ConnectTimeout      time.Duration // Connection setup timeout, 0 if not set.
+	DNSResolveTimeout   time.Duration // Target resolution timeout, 0 if not set.
 	Logger              *logger.Logger
 	ProbeConf           interface{} // Probe-type specific config
 	LatencyDist         *metrics.Distribution
 	LatencyUnit         time.Duration
 	LatencyMetricName   string
+	ExportRawLatency    bool          // Export each result's latency, see ProbeDef.
+	SLOLatencyObjective time.Duration // SLO latency objective, 0 if SLO is not configured.
 	Validators          []*validators.Validator
 	SourceIP            net.IP
+	SourceIPZone        string // IPv6 zone, set only for link-local source IPs.
 	IPVersion           int
+	FallbackIPVersion   int    // IP version to fall back to, 0 if no fallback.
+	IPv6FlowLabel       uint32 // IPv6 flow label, 0 if not set.
 	StatsExportInterval time.Duration
 	LogMetrics          func(*metrics.EventMetrics)
 	AdditionalLabels    []*AdditionalLabel
+	MaxConcurrentProbes int
+	InitialDelay        time.Duration
+	WarmupWindow        time.Duration
+	FailureDebounce     int // Consecutive failures to report, 0 if disabled.
+	Alerting            *configpb.AlertingConf
+	ResultSampleRate    float64   // Fraction of results to export, 1 if not sampled.
+	Schedule            *Schedule // Schedule to gate or trigger probe cycles, if configured.
+	TimestampSource     configpb.ProbeDef_TimestampSource
+}
+
+// Probe types that don't support max_concurrent_probes. PING and UDP probes
+// send packets to all the targets from a single goroutine, and UDP_LISTENER
+// probes don't probe the targets at all.
+var noMaxConcurrentProbes = map[configpb.ProbeDef_Type]bool{
+	configpb.ProbeDef_PING:         true,
+	configpb.ProbeDef_UDP:          true,
+	configpb.ProbeDef_UDP_LISTENER: true,
 }
 
 const defaultStatsExtportIntv = 10 * time.Second
@@ -86,104 +117,253 @@ func ipv(v *configpb.ProbeDef_IPVersion) int {
 	}
 }
 
+// ipVersions returns the IP version and the fallback IP version (0 if there
+// is no fallback), as configured through ip_version or ip_version_mode.
+func ipVersions(p *configpb.ProbeDef) (int, int, error) {
+	if p.IpVersion != nil && p.IpVersionMode != nil {
+		return 0, 0, fmt.Errorf("only one of ip_version (%s) and ip_version_mode (%s) can be configured", p.GetIpVersion(), p.GetIpVersionMode())
+	}
+
+	switch p.GetIpVersionMode() {
+	case configpb.ProbeDef_IPV4_ONLY:
+		return 4, 0, nil
+	case configpb.ProbeDef_IPV6_ONLY:
+		return 6, 0, nil
+	case configpb.ProbeDef_PREFER_IPV6:
+		return 6, 4, nil
+	case configpb.ProbeDef_PREFER_IPV4:
+		return 4, 6, nil
+	default:
+		return ipv(p.IpVersion), 0, nil
+	}
+}
+
 // getSourceFromConfig returns the source IP from the config either directly
 // or by resolving the network interface to an IP, depending on which is provided.
-func getSourceIPFromConfig(p *configpb.ProbeDef, l *logger.Logger) (net.IP, error) {
+// Returned address carries the zone for IPv6 link-local addresses, e.g.
+// fe80::1%eth0.
+func getSourceIPFromConfig(p *configpb.ProbeDef, ipVer int, l *logger.Logger) (*net.IPAddr, error) {
 	switch p.SourceIpConfig.(type) {
 
 	case *configpb.ProbeDef_SourceIp:
-		sourceIP := net.ParseIP(p.GetSourceIp())
-		if sourceIP == nil {
+		ipStr, zone := p.GetSourceIp(), ""
+		if i := strings.LastIndex(ipStr, "%"); i != -1 {
+			ipStr, zone = ipStr[:i], ipStr[i+1:]
+		}
+		sourceIP := net.ParseIP(ipStr)
+		if sourceIP == nil || (zone != "" && iputils.IPVersion(sourceIP) != 6) {
 			return nil, fmt.Errorf("invalid source IP: %s", p.GetSourceIp())
 		}
 
 		// If ip_version is configured, make sure source_ip matches it.
-		if ipv(p.IpVersion) != 0 && iputils.IPVersion(sourceIP) != ipv(p.IpVersion) {
-			return nil, fmt.Errorf("configured source_ip (%s) doesn't match the ip_version (%d)", p.GetSourceIp(), ipv(p.IpVersion))
+		if ipVer != 0 && iputils.IPVersion(sourceIP) != ipVer {
+			return nil, fmt.Errorf("configured source_ip (%s) doesn't match the ip_version (%d)", p.GetSourceIp(), ipVer)
 		}
 
-		return sourceIP, nil
+		return &net.IPAddr{IP: sourceIP, Zone: zone}, nil
 
 	case *configpb.ProbeDef_SourceInterface:
-		return iputils.ResolveIntfAddr(p.GetSourceInterface(), ipv(p.IpVersion))
+		return iputils.ResolveIntfAddr(p.GetSourceInterface(), ipVer)
 
 	default:
 		return nil, fmt.Errorf("unknown source type: %v", p.GetSourceIpConfig())
 	}
 }
 
-// BuildProbeOptions builds probe's options using the provided config and some
-// global params.
-func BuildProbeOptions(p *configpb.ProbeDef, ldLister endpoint.Lister, globalTargetsOpts *targetspb.GlobalTargetsOptions, l *logger.Logger) (*Options, error) {
-	intervalDuration := defaultIntervalPeriod
-	timeoutDuration := defaultTimeoutPeriod
-	var err error
+// FieldError is a probe config error, along with the name of the ProbeDef
+// field that caused it.
+type FieldError struct {
+	Field string
+	Err   error
+}
 
-	if p.GetIntervalMsec() != 0 && p.GetInterval() != "" {
-		return nil, fmt.Errorf("both interval (%s) and interval_msec (%d) are specified", p.GetInterval(), p.GetIntervalMsec())
-	} else if p.GetIntervalMsec() != 0 {
-		intervalDuration = time.Duration(p.GetIntervalMsec()) * time.Millisecond
-	} else if p.GetInterval() != "" {
-		intervalDuration, err = time.ParseDuration(p.GetInterval())
+func (fe *FieldError) Error() string {
+	return fe.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (fe *FieldError) Unwrap() error {
+	return fe.Err
+}
+
+// probeDuration returns the duration configured through either the field,
+// as a duration string, or the field's _msec variant. It returns def if
+// neither of them is set.
+func probeDuration(field, s string, msec int32, def time.Duration) (time.Duration, error) {
+	if msec != 0 && s != "" {
+		return 0, fmt.Errorf("both %s (%s) and %s_msec (%d) are specified", field, s, field, msec)
+	}
+	if msec != 0 {
+		return time.Duration(msec) * time.Millisecond, nil
+	}
+	if s != "" {
+		d, err := time.ParseDuration(s)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse interval (%s): %v", p.GetInterval(), err)
+			return 0, fmt.Errorf("failed to parse %s (%s): %v", field, s, err)
 		}
+		return d, nil
+	}
+	return def, nil
+}
+
+// buildOptions builds the options that can be derived from the probe config
+// alone, i.e. all options except targets. Instead of stopping at the first
+// error, it returns all the config errors it finds, skipping only the checks
+// that depend on a field that has an error.
+func buildOptions(p *configpb.ProbeDef, probeLogger, l *logger.Logger) (*Options, []*FieldError) {
+	var errs []*FieldError
+	fail := func(field string, err error) {
+		errs = append(errs, &FieldError{Field: field, Err: err})
 	}
 
-	if p.GetTimeoutMsec() != 0 && p.GetTimeout() != "" {
-		return nil, fmt.Errorf("both timeout (%s) and timeout_msec (%d) are specified", p.GetTimeout(), p.GetTimeoutMsec())
-	} else if p.GetTimeoutMsec() != 0 {
-		timeoutDuration = time.Duration(p.GetTimeoutMsec()) * time.Millisecond
-	} else if p.GetTimeout() != "" {
-		timeoutDuration, err = time.ParseDuration(p.GetTimeout())
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse timeout (%s): %v", p.GetTimeout(), err)
+	interval, intervalErr := probeDuration("interval", p.GetInterval(), p.GetIntervalMsec(), defaultIntervalPeriod)
+	if intervalErr != nil {
+		fail("interval", intervalErr)
+	}
+
+	timeout, timeoutErr := probeDuration("timeout", p.GetTimeout(), p.GetTimeoutMsec(), defaultTimeoutPeriod)
+	if timeoutErr != nil {
+		fail("timeout", timeoutErr)
+	}
+
+	connectTimeout := time.Duration(p.GetConnectTimeoutMsec()) * time.Millisecond
+	if connectTimeout < 0 || (timeoutErr == nil && connectTimeout > timeout) {
+		fail("connect_timeout_msec", fmt.Errorf("connect_timeout_msec (%d) cannot be negative or larger than the timeout (%v)", p.GetConnectTimeoutMsec(), timeout))
+	}
+
+	dnsResolveTimeout := time.Duration(p.GetDnsResolveTimeoutMsec()) * time.Millisecond
+	if dnsResolveTimeout < 0 || (timeoutErr == nil && dnsResolveTimeout > timeout) {
+		fail("dns_resolve_timeout_msec", fmt.Errorf("dns_resolve_timeout_msec (%d) cannot be negative or larger than the timeout (%v)", p.GetDnsResolveTimeoutMsec(), timeout))
+	}
+
+	if p.GetMaxConcurrentProbes() < 0 {
+		fail("max_concurrent_probes", fmt.Errorf("max_concurrent_probes (%d) cannot be negative", p.GetMaxConcurrentProbes()))
+	}
+	if p.GetMaxConcurrentProbes() > 0 && noMaxConcurrentProbes[p.GetType()] {
+		fail("max_concurrent_probes", fmt.Errorf("max_concurrent_probes is not supported by the %s probes", p.GetType()))
+	}
+
+	if p.GetInitialDelayMsec() < 0 || p.GetWarmupMsec() < 0 {
+		field := "initial_delay_msec"
+		if p.GetInitialDelayMsec() >= 0 {
+			field = "warmup_msec"
 		}
+		fail(field, fmt.Errorf("initial_delay_msec (%d) and warmup_msec (%d) cannot be negative", p.GetInitialDelayMsec(), p.GetWarmupMsec()))
+	}
+
+	if p.GetFailureDebounce() != nil && p.GetFailureDebounce().GetConsecutive() < 1 {
+		fail("failure_debounce", fmt.Errorf("failure_debounce.consecutive (%d) must be at least 1", p.GetFailureDebounce().GetConsecutive()))
+	}
+
+	ipVer, fallbackIPVer, ipVerErr := ipVersions(p)
+	if ipVerErr != nil {
+		fail("ip_version_mode", ipVerErr)
 	}
 
 	opts := &Options{
-		Interval:          intervalDuration,
-		Timeout:           timeoutDuration,
-		IPVersion:         ipv(p.IpVersion),
-		LatencyMetricName: p.GetLatencyMetricName(),
+		Interval:            interval,
+		Timeout:             timeout,
+		ConnectTimeout:      connectTimeout,
+		DNSResolveTimeout:   dnsResolveTimeout,
+		Logger:              probeLogger,
+		IPVersion:           ipVer,
+		FallbackIPVersion:   fallbackIPVer,
+		LatencyMetricName:   p.GetLatencyMetricName(),
+		ExportRawLatency:    p.GetExportRawLatency(),
+		MaxConcurrentProbes: int(p.GetMaxConcurrentProbes()),
+		InitialDelay:        time.Duration(p.GetInitialDelayMsec()) * time.Millisecond,
+		WarmupWindow:        time.Duration(p.GetWarmupMsec()) * time.Millisecond,
+		TimestampSource:     p.GetTimestampSource(),
 	}
 
-	if opts.Logger, err = logger.NewCloudproberLog(p.GetName()); err != nil {
-		return nil, fmt.Errorf("error in initializing logger for the probe (%s): %v", p.GetName(), err)
+	if fl := p.GetIpv6FlowLabel(); fl < 0 || fl > 0xfffff {
+		fail("ipv6_flow_label", fmt.Errorf("invalid ipv6_flow_label (%d), flow label must fit in 20 bits (0-1048575)", fl))
+	} else {
+		opts.IPv6FlowLabel = uint32(fl)
 	}
 
-	if opts.Targets, err = targets.New(p.GetTargets(), ldLister, globalTargetsOpts, l, opts.Logger); err != nil {
-		return nil, err
+	if r := p.GetResultSampleRate(); r <= 0 || r > 1 {
+		fail("result_sample_rate", fmt.Errorf("result_sample_rate (%v) must be in the (0, 1] range", r))
+	} else {
+		opts.ResultSampleRate = float64(r)
+	}
+
+	if slo := p.GetSlo(); slo != nil {
+		d, err := time.ParseDuration(slo.GetLatencyObjective())
+		if err != nil || d <= 0 {
+			fail("slo", fmt.Errorf("invalid slo.latency_objective (%s), must be a positive duration", slo.GetLatencyObjective()))
+		} else {
+			opts.SLOLatencyObjective = d
+		}
+	}
+
+	if p.GetFailureDebounce() != nil {
+		opts.FailureDebounce = int(p.GetFailureDebounce().GetConsecutive())
+	}
+
+	if a := p.GetAlerting(); a != nil {
+		if a.GetMinFailures() < 1 || a.GetResendIntervalSec() < 0 {
+			fail("alerting", fmt.Errorf("alerting: min_failures (%d) must be at least 1 and resend_interval_sec (%d) cannot be negative", a.GetMinFailures(), a.GetResendIntervalSec()))
+		}
+		opts.Alerting = a
+	}
+
+	if sc := p.GetSchedule(); sc != nil {
+		loc := time.Local
+		var err error
+		if sc.GetTimezone() != "" {
+			if loc, err = time.LoadLocation(sc.GetTimezone()); err != nil {
+				fail("schedule", fmt.Errorf("invalid schedule timezone (%s): %v", sc.GetTimezone(), err))
+			}
+		}
+		if err == nil {
+			if opts.Schedule, err = NewSchedule(sc.GetCron(), loc); err != nil {
+				fail("schedule", err)
+			} else if sc.GetMode() == configpb.ProbeDef_Schedule_TRIGGER {
+				opts.Schedule.trigger = true
+				if opts.Schedule.Next(time.Now()).IsZero() {
+					fail("schedule", fmt.Errorf("schedule cron expression (%s) never matches", sc.GetCron()))
+				}
+			}
+		}
 	}
 
 	if latencyDist := p.GetLatencyDistribution(); latencyDist != nil {
-		var d *metrics.Distribution
-		if d, err = metrics.NewDistributionFromProto(latencyDist); err != nil {
-			return nil, fmt.Errorf("error creating distribution from the specification (%v): %v", latencyDist, err)
+		d, err := metrics.NewDistributionFromProto(latencyDist)
+		if err != nil {
+			fail("latency_distribution", fmt.Errorf("error creating distribution from the specification (%v): %v", latencyDist, err))
 		}
 		opts.LatencyDist = d
 	}
 
 	// latency_unit is specified as a human-readable string, e.g. ns, ms, us etc.
+	var err error
 	if opts.LatencyUnit, err = time.ParseDuration("1" + p.GetLatencyUnit()); err != nil {
-		return nil, fmt.Errorf("failed to parse the latency unit (%s): %v", p.GetLatencyUnit(), err)
+		fail("latency_unit", fmt.Errorf("failed to parse the latency unit (%s): %v", p.GetLatencyUnit(), err))
 	}
 
 	if len(p.GetValidator()) > 0 {
-		opts.Validators, err = validators.Init(p.GetValidator(), opts.Logger)
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize validators: %v", err)
+		if opts.Validators, err = validators.Init(p.GetValidator(), opts.Logger); err != nil {
+			fail("validator", fmt.Errorf("failed to initialize validators: %v", err))
 		}
 	}
 
-	if p.GetSourceIpConfig() != nil {
-		opts.SourceIP, err = getSourceIPFromConfig(p, l)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get source address for the probe: %v", err)
+	if p.GetSourceIpConfig() != nil && ipVerErr == nil {
+		field := "source_ip"
+		if p.GetSourceInterface() != "" {
+			field = "source_interface"
 		}
-		// Set IPVersion from SourceIP if not already set.
-		if opts.IPVersion == 0 {
-			opts.IPVersion = iputils.IPVersion(opts.SourceIP)
+		// Source IP fixes the IP version, there is nothing to fall back to.
+		if opts.FallbackIPVersion != 0 {
+			fail("ip_version_mode", fmt.Errorf("ip_version_mode (%s) cannot be used with source_ip or source_interface", p.GetIpVersionMode()))
+		} else if sourceAddr, err := getSourceIPFromConfig(p, opts.IPVersion, l); err != nil {
+			fail(field, fmt.Errorf("failed to get source address for the probe: %v", err))
+		} else {
+			opts.SourceIP, opts.SourceIPZone = sourceAddr.IP, sourceAddr.Zone
+			// Set IPVersion from SourceIP if not already set.
+			if opts.IPVersion == 0 {
+				opts.IPVersion = iputils.IPVersion(opts.SourceIP)
+			}
 		}
 	}
 
@@ -191,8 +371,8 @@ func BuildProbeOptions(p *configpb.ProbeDef, ldLister endpoint.Lister, globalTar
 		opts.StatsExportInterval = defaultStatsExportInterval(p, opts)
 	} else {
 		opts.StatsExportInterval = time.Duration(p.GetStatsExportIntervalMsec()) * time.Millisecond
-		if opts.StatsExportInterval < opts.Interval {
-			return nil, fmt.Errorf("stats_export_interval (%d ms) smaller than probe interval %v", p.GetStatsExportIntervalMsec(), opts.Interval)
+		if intervalErr == nil && opts.StatsExportInterval < opts.Interval {
+			fail("stats_export_interval_msec", fmt.Errorf("stats_export_interval (%d ms) smaller than probe interval %v", p.GetStatsExportIntervalMsec(), opts.Interval))
 		}
 	}
 
@@ -208,6 +388,29 @@ func BuildProbeOptions(p *configpb.ProbeDef, ldLister endpoint.Lister, globalTar
 		}
 	}
 
+	return opts, errs
+}
+
+// BuildProbeOptions builds probe's options using the provided config and some
+// global params.
+func BuildProbeOptions(p *configpb.ProbeDef, ldLister endpoint.Lister, globalTargetsOpts *targetspb.GlobalTargetsOptions, l *logger.Logger) (*Options, error) {
+	probeLogger, err := logger.NewCloudproberLog(p.GetName())
+	if err != nil {
+		return nil, fmt.Errorf("error in initializing logger for the probe (%s): %v", p.GetName(), err)
+	}
+
+	opts, errs := buildOptions(p, probeLogger, l)
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+
+	if opts.Targets, err = targets.New(p.GetTargets(), ldLister, globalTargetsOpts, l, opts.Logger); err != nil {
+		return nil, err
+	}
+	if err = targets.WatchChanges(opts.Targets, p.GetName(), targetsChangeListener(opts.Logger, *exportTargetsChanges)); err != nil {
+		return nil, err
+	}
+
 	return opts, nil
 }
 
@@ -229,3 +432,115 @@ func DefaultOptions() *Options {
 
 	return opts
 }
+
+// ResolveTarget resolves the target name using resolveF (Targets.Resolve if
+// nil) for the configured IP version. If that fails and a fallback IP version
+// is configured, it retries with the fallback IP version. Each resolution is
+// bounded by the DNS resolve timeout, if configured.
+func (opts *Options) ResolveTarget(name string, resolveF func(string, int) (net.IP, error)) (net.IP, error) {
+	if resolveF == nil {
+		resolveF = opts.Targets.Resolve
+	}
+
+	ip, err := opts.resolveWithTimeout(name, opts.IPVersion, resolveF)
+	if err == nil || opts.FallbackIPVersion == 0 {
+		return ip, err
+	}
+
+	ip, fallbackErr := opts.resolveWithTimeout(name, opts.FallbackIPVersion, resolveF)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%v; fallback to IPv%d failed: %v", err, opts.FallbackIPVersion, fallbackErr)
+	}
+	return ip, nil
+}
+
+// MetricTimestamp returns the timestamp to export metrics with, as per the
+// timestamp source: the export time (emit), or the start or the end of the
+// last probe cycle. It falls back to the export time if the cycle time is not
+// known, e.g. if no cycle has finished yet.
+func (opts *Options) MetricTimestamp(emit, cycleStart, cycleEnd time.Time) time.Time {
+	var ts time.Time
+	switch opts.TimestampSource {
+	case configpb.ProbeDef_CYCLE_START:
+		ts = cycleStart
+	case configpb.ProbeDef_CYCLE_END:
+		ts = cycleEnd
+	}
+	if ts.IsZero() {
+		return emit
+	}
+	return ts
+}
+
+// RawLatencyEM returns a GAUGE EventMetrics carrying the latency of a single
+// probe result, for export_raw_latency. Standard labels (ptype, probe, dst)
+// are followed by the labels of the given EventMetrics, if any, e.g. result's
+// labels, and the additional labels for the target.
+func (opts *Options) RawLatencyEM(ts time.Time, ptype, name, target string, latency time.Duration, labels *metrics.EventMetrics) *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(ts).
+		AddMetric(opts.LatencyMetricName+"_raw", metrics.NewFloat(latency.Seconds()/opts.LatencyUnit.Seconds())).
+		AddLabel("ptype", ptype).
+		AddLabel("probe", name).
+		AddLabel("dst", target)
+	if labels != nil {
+		for _, k := range labels.LabelsKeys() {
+			em.AddLabel(k, labels.Label(k))
+		}
+	}
+	for _, al := range opts.AdditionalLabels {
+		em.AddLabel(al.KeyValueForTarget(target))
+	}
+	em.Kind = metrics.GAUGE
+	em.LatencyUnit = opts.LatencyUnit
+	return em
+}
+
+// MeetsLatencyObjective returns true if a successful probe result with the
+// given latency counts as good for the SLO, i.e. if its latency is under the
+// latency objective.
+func (opts *Options) MeetsLatencyObjective(latency time.Duration) bool {
+	return latency < opts.SLOLatencyObjective
+}
+
+// TargetTimeoutLabel is the target label that overrides the probe timeout for
+// that target.
+const TargetTimeoutLabel = "timeout_ms"
+
+// TargetTimeout returns the timeout for the given target. If the target has
+// a valid timeout_ms label, it's used, capped at the probe interval; otherwise
+// probe's timeout is returned.
+func (opts *Options) TargetTimeout(ep endpoint.Endpoint) time.Duration {
+	v, ok := ep.Labels[TargetTimeoutLabel]
+	if !ok {
+		return opts.Timeout
+	}
+
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms <= 0 {
+		opts.Logger.Warningf("Target(%s): invalid %s label value: %q, using the default timeout (%v)", ep.Name, TargetTimeoutLabel, v, opts.Timeout)
+		return opts.Timeout
+	}
+
+	timeout := time.Duration(ms) * time.Millisecond
+	if opts.Interval > 0 && timeout > opts.Interval {
+		return opts.Interval
+	}
+	return timeout
+}
+
+// targetsChangeListener returns a listener that logs the targets change
+// events in JSON, and optionally records them in the probe's runtime stats.
+func targetsChangeListener(l *logger.Logger, recordStats bool) targets.ChangeListener {
+	return func(ev *targets.ChangeEvent) {
+		if recordStats {
+			runstats.Default().RecordTargetsChange(ev.Probe, ev.Change == targets.TargetAdded)
+		}
+
+		b, err := json.Marshal(ev)
+		if err != nil {
+			l.Errorf("Error encoding targets change event (%+v): %v", ev, err)
+			return
+		}
+		l.Info(string(b))
+	}
+}
@@ -16,14 +16,23 @@ package options
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cloudprober/cloudprober/common/iputils"
 	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 	targetspb "github.com/cloudprober/cloudprober/targets/proto"
+	"github.com/cloudprober/cloudprober/validators"
+	validatorspb "github.com/cloudprober/cloudprober/validators/proto"
+	validatorstestpb "github.com/cloudprober/cloudprober/validators/testutils/proto"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -114,6 +123,33 @@ func TestGetSourceIPFromConfig(t *testing.T) {
 			ipVer:      6,
 			want:       "::1",
 		},
+		{
+			name:       "Link-local IPv6 addr for interface retains zone",
+			sourceIntf: "eth1",
+			intf:       "eth1",
+			intfAddrs:  []string{"1.1.1.1", "fe80::1"},
+			ipVer:      6,
+			want:       "fe80::1%eth1",
+		},
+		{
+			name:       "Global IPv6 addr for interface has no zone",
+			sourceIntf: "eth1",
+			intf:       "eth1",
+			intfAddrs:  []string{"2001:db8::1", "fe80::1"},
+			ipVer:      6,
+			want:       "2001:db8::1",
+		},
+		{
+			name:     "Use link-local IPv6 with zone",
+			sourceIP: "fe80::1%eth0",
+			ipVer:    6,
+			want:     "fe80::1%eth0",
+		},
+		{
+			name:      "Zone with IPv4 fails",
+			sourceIP:  "1.1.1.1%eth0",
+			wantError: true,
+		},
 	}
 
 	for _, r := range rows {
@@ -128,7 +164,7 @@ func TestGetSourceIPFromConfig(t *testing.T) {
 			mockInterfaceByName(r.intf, r.intfAddrs)
 		}
 
-		source, err := getSourceIPFromConfig(p, &logger.Logger{})
+		source, err := getSourceIPFromConfig(p, ipv(p.IpVersion), &logger.Logger{})
 
 		if (err != nil) != r.wantError {
 			t.Errorf("Row %q: getSourceIPFromConfig() gave error %q, want error is %v", r.name, err, r.wantError)
@@ -190,6 +226,136 @@ func TestIPVersionFromSourceIP(t *testing.T) {
 	}
 }
 
+func TestIPVersionMode(t *testing.T) {
+	for _, test := range []struct {
+		name                    string
+		ipVersion               *configpb.ProbeDef_IPVersion
+		mode                    *configpb.ProbeDef_IPVersionMode
+		sourceIP                string
+		wantIPVer, wantFallback int
+		wantError               bool
+	}{
+		{name: "unspecified"},
+		{name: "ip_version", ipVersion: configpb.ProbeDef_IPV6.Enum(), wantIPVer: 6},
+		{name: "ipv4_only", mode: configpb.ProbeDef_IPV4_ONLY.Enum(), wantIPVer: 4},
+		{name: "ipv6_only", mode: configpb.ProbeDef_IPV6_ONLY.Enum(), wantIPVer: 6},
+		{name: "prefer_ipv6", mode: configpb.ProbeDef_PREFER_IPV6.Enum(), wantIPVer: 6, wantFallback: 4},
+		{name: "prefer_ipv4", mode: configpb.ProbeDef_PREFER_IPV4.Enum(), wantIPVer: 4, wantFallback: 6},
+		{name: "ipv6_only_source_ip", mode: configpb.ProbeDef_IPV6_ONLY.Enum(), sourceIP: "::1", wantIPVer: 6},
+		{name: "ipv6_only_source_ip_mismatch", mode: configpb.ProbeDef_IPV6_ONLY.Enum(), sourceIP: "1.1.1.1", wantError: true},
+		{name: "prefer_with_source_ip", mode: configpb.ProbeDef_PREFER_IPV6.Enum(), sourceIP: "::1", wantError: true},
+		{name: "both", ipVersion: configpb.ProbeDef_IPV4.Enum(), mode: configpb.ProbeDef_IPV4_ONLY.Enum(), wantError: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets:       testTargets,
+				IpVersion:     test.ipVersion,
+				IpVersionMode: test.mode,
+			}
+			if test.sourceIP != "" {
+				p.SourceIpConfig = &configpb.ProbeDef_SourceIp{SourceIp: test.sourceIP}
+			}
+
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if opts.IPVersion != test.wantIPVer || opts.FallbackIPVersion != test.wantFallback {
+				t.Errorf("Got IPVersion=%d, FallbackIPVersion=%d, want: %d, %d", opts.IPVersion, opts.FallbackIPVersion, test.wantIPVer, test.wantFallback)
+			}
+		})
+	}
+}
+
+// dualStackResolver returns a mock resolve function for hosts with the given
+// IPv4 and IPv6 addresses.
+func dualStackResolver(hosts map[string][2]string) func(string, int) (net.IP, error) {
+	return func(name string, ipVer int) (net.IP, error) {
+		addrs, ok := hosts[name]
+		if !ok {
+			return nil, fmt.Errorf("host not found: %s", name)
+		}
+		addr := map[int]string{4: addrs[0], 6: addrs[1]}[ipVer]
+		if ipVer == 0 {
+			addr = addrs[0]
+		}
+		if addr == "" {
+			return nil, fmt.Errorf("no IPv%d address for %s", ipVer, name)
+		}
+		return net.ParseIP(addr), nil
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	resolveF := dualStackResolver(map[string][2]string{
+		"dual":   {"10.1.1.1", "2001:db8::1"},
+		"v4only": {"10.1.1.2", ""},
+		"v6only": {"", "2001:db8::2"},
+	})
+
+	for _, test := range []struct {
+		ipVer, fallback int
+		target          string
+		wantIP          string
+	}{
+		{ipVer: 6, fallback: 4, target: "dual", wantIP: "2001:db8::1"},
+		{ipVer: 6, fallback: 4, target: "v4only", wantIP: "10.1.1.2"},
+		{ipVer: 4, fallback: 6, target: "dual", wantIP: "10.1.1.1"},
+		{ipVer: 4, fallback: 6, target: "v6only", wantIP: "2001:db8::2"},
+		{ipVer: 6, fallback: 4, target: "unknown"},
+		// Hard filters: no fallback.
+		{ipVer: 6, target: "v4only"},
+		{ipVer: 4, target: "v6only"},
+		{ipVer: 4, target: "dual", wantIP: "10.1.1.1"},
+	} {
+		t.Run(fmt.Sprintf("%d,%d,%s", test.ipVer, test.fallback, test.target), func(t *testing.T) {
+			opts := &Options{IPVersion: test.ipVer, FallbackIPVersion: test.fallback}
+			ip, err := opts.ResolveTarget(test.target, resolveF)
+			if test.wantIP == "" {
+				if err == nil {
+					t.Errorf("Expected error, got IP: %v", ip)
+				}
+				return
+			}
+			if err != nil || ip.String() != test.wantIP {
+				t.Errorf("ResolveTarget(%s)=%v, %v, want=%s", test.target, ip, err, test.wantIP)
+			}
+		})
+	}
+}
+
+func TestTargetTimeout(t *testing.T) {
+	opts := &Options{
+		Interval: 10 * time.Second,
+		Timeout:  time.Second,
+	}
+
+	for _, test := range []struct {
+		label string
+		want  time.Duration
+	}{
+		{want: time.Second},
+		{label: "2500", want: 2500 * time.Millisecond},
+		{label: "500", want: 500 * time.Millisecond},
+		{label: "20000", want: 10 * time.Second}, // Capped at interval.
+		{label: "2s", want: time.Second},
+		{label: "-1", want: time.Second},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			ep := endpoint.Endpoint{Name: "t1"}
+			if test.label != "" {
+				ep.Labels = map[string]string{TargetTimeoutLabel: test.label}
+			}
+			if got := opts.TargetTimeout(ep); got != test.want {
+				t.Errorf("TargetTimeout()=%v, want=%v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestStatsExportInterval(t *testing.T) {
 	rows := []struct {
 		name          string
@@ -318,3 +484,272 @@ func TestDefaultOptions(t *testing.T) {
 		t.Errorf("Got nil default options")
 	}
 }
+
+func TestInitialDelayAndWarmup(t *testing.T) {
+	for _, test := range []struct {
+		initialDelayMsec, warmupMsec int32
+		wantInitialDelay, wantWarmup time.Duration
+		wantError                    bool
+	}{
+		{},
+		{
+			initialDelayMsec: 5000,
+			warmupMsec:       30000,
+			wantInitialDelay: 5 * time.Second,
+			wantWarmup:       30 * time.Second,
+		},
+		{
+			initialDelayMsec: -1,
+			wantError:        true,
+		},
+		{
+			warmupMsec: -1,
+			wantError:  true,
+		},
+	} {
+		t.Run(fmt.Sprintf("%d,%d", test.initialDelayMsec, test.warmupMsec), func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets:          testTargets,
+				InitialDelayMsec: proto.Int32(test.initialDelayMsec),
+				WarmupMsec:       proto.Int32(test.warmupMsec),
+			}
+
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if opts.InitialDelay != test.wantInitialDelay || opts.WarmupWindow != test.wantWarmup {
+				t.Errorf("Got initial delay=%v, warm-up=%v, want: %v, %v", opts.InitialDelay, opts.WarmupWindow, test.wantInitialDelay, test.wantWarmup)
+			}
+		})
+	}
+}
+
+func TestMaxConcurrentProbes(t *testing.T) {
+	for _, test := range []struct {
+		ptype     configpb.ProbeDef_Type
+		max       int32
+		wantError bool
+	}{
+		{ptype: configpb.ProbeDef_HTTP, max: 10},
+		{ptype: configpb.ProbeDef_TCP, max: 10},
+		{ptype: configpb.ProbeDef_HTTP, max: -1, wantError: true},
+		{ptype: configpb.ProbeDef_PING, max: 10, wantError: true},
+		{ptype: configpb.ProbeDef_UDP, max: 10, wantError: true},
+		{ptype: configpb.ProbeDef_GRPC, max: 10},
+		{ptype: configpb.ProbeDef_PING},
+	} {
+		t.Run(fmt.Sprintf("%v,%d", test.ptype, test.max), func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Type:                test.ptype.Enum(),
+				Targets:             testTargets,
+				MaxConcurrentProbes: proto.Int32(test.max),
+			}
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if err == nil && opts.MaxConcurrentProbes != int(test.max) {
+				t.Errorf("Got MaxConcurrentProbes=%d, want=%d", opts.MaxConcurrentProbes, test.max)
+			}
+		})
+	}
+}
+
+func TestIPv6FlowLabel(t *testing.T) {
+	for _, test := range []struct {
+		flowLabel int32
+		wantError bool
+	}{
+		{flowLabel: 0},
+		{flowLabel: 0x12345},
+		{flowLabel: 0xfffff},
+		{flowLabel: 0x100000, wantError: true},
+		{flowLabel: -1, wantError: true},
+	} {
+		t.Run(fmt.Sprintf("%#x", test.flowLabel), func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets:       testTargets,
+				Ipv6FlowLabel: proto.Int32(test.flowLabel),
+			}
+
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if opts.IPv6FlowLabel != uint32(test.flowLabel) {
+				t.Errorf("Got IPv6FlowLabel=%#x, want: %#x", opts.IPv6FlowLabel, test.flowLabel)
+			}
+		})
+	}
+}
+
+func TestResultSampleRate(t *testing.T) {
+	for _, test := range []struct {
+		rate      float32
+		wantError bool
+	}{
+		{rate: 1},
+		{rate: 0.1},
+		{rate: 0, wantError: true},
+		{rate: 1.5, wantError: true},
+		{rate: -0.5, wantError: true},
+	} {
+		t.Run(fmt.Sprintf("%v", test.rate), func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets:          testTargets,
+				ResultSampleRate: proto.Float32(test.rate),
+			}
+
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if opts.ResultSampleRate != float64(test.rate) {
+				t.Errorf("Got ResultSampleRate=%v, want: %v", opts.ResultSampleRate, test.rate)
+			}
+		})
+	}
+}
+
+func TestSLO(t *testing.T) {
+	for _, test := range []struct {
+		objective string
+		want      time.Duration
+		wantError bool
+	}{
+		{objective: "200ms", want: 200 * time.Millisecond},
+		{objective: "1.5s", want: 1500 * time.Millisecond},
+		{objective: "0s", wantError: true},
+		{objective: "-1s", wantError: true},
+		{objective: "fast", wantError: true},
+	} {
+		t.Run(test.objective, func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets: testTargets,
+				Slo: &configpb.ProbeDef_SLO{
+					LatencyObjective: proto.String(test.objective),
+				},
+			}
+
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			if opts.SLOLatencyObjective != test.want {
+				t.Errorf("Got SLOLatencyObjective=%v, want: %v", opts.SLOLatencyObjective, test.want)
+			}
+		})
+	}
+}
+
+func TestCustomValidator(t *testing.T) {
+	validators.RegisterValidator("cloudprober.validators.testutils.test_validator", func(cfg proto.Message, l *logger.Logger) (*validators.Validator, error) {
+		substr := cfg.(*validatorstestpb.TestValidator).GetSubstr()
+		if substr == "" {
+			return nil, errors.New("substr cannot be empty")
+		}
+		return &validators.Validator{
+			Validate: func(input *validators.Input) (bool, error) {
+				return strings.Contains(string(input.ResponseBody), substr), nil
+			},
+		}, nil
+	})
+
+	vc := &validatorspb.Validator{Name: proto.String("custom")}
+	if err := proto.SetExtension(vc, validatorstestpb.E_TestValidator, &validatorstestpb.TestValidator{Substr: proto.String("ok")}); err != nil {
+		t.Fatalf("Error setting validator extension: %v", err)
+	}
+	p := &configpb.ProbeDef{
+		Targets:   testTargets,
+		Validator: []*validatorspb.Validator{vc},
+	}
+
+	opts, err := BuildProbeOptions(p, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildProbeOptions() error: %v", err)
+	}
+	if len(opts.Validators) != 1 || opts.Validators[0].Name != "custom" {
+		t.Fatalf("Got validators: %v, want one validator named custom", opts.Validators)
+	}
+	for body, want := range map[string]bool{"all ok": true, "failed": false} {
+		if got, _ := opts.Validators[0].Validate(&validators.Input{ResponseBody: []byte(body)}); got != want {
+			t.Errorf("Validate(%q)=%v, want=%v", body, got, want)
+		}
+	}
+
+	// Factory error surfaces as a config error.
+	proto.SetExtension(vc, validatorstestpb.E_TestValidator, &validatorstestpb.TestValidator{})
+	if _, err := BuildProbeOptions(p, nil, nil, nil); err == nil {
+		t.Error("BuildProbeOptions(): expected error for invalid custom validator config")
+	}
+}
+
+func TestTargetsChangeListener(t *testing.T) {
+	probe := "test_targets_change_listener"
+	listener := targetsChangeListener(nil, true)
+	for _, change := range []string{targets.TargetAdded, targets.TargetAdded, targets.TargetRemoved} {
+		listener(&targets.ChangeEvent{Probe: probe, Target: "t1", Change: change, Reason: targets.ReasonInitial})
+	}
+
+	var em *metrics.EventMetrics
+	for _, e := range runstats.Default().EventMetrics(time.Now()) {
+		if e.Label("probe") == probe && e.Metric("targets_added_total") != nil {
+			em = e
+		}
+	}
+	if em == nil {
+		t.Fatalf("No targets changes self-metrics for the probe %s", probe)
+	}
+	if em.Metric("targets_added_total").String() != "2" || em.Metric("targets_removed_total").String() != "1" {
+		t.Errorf("Got targets changes self-metrics: %s, want added=2, removed=1", em.String())
+	}
+}
+
+func TestMetricTimestamp(t *testing.T) {
+	emit := time.Date(2021, 6, 1, 12, 0, 10, 0, time.UTC)
+	cycleStart := time.Date(2021, 6, 1, 12, 0, 5, 0, time.UTC)
+	cycleEnd := cycleStart.Add(300 * time.Millisecond)
+
+	for _, test := range []struct {
+		source  configpb.ProbeDef_TimestampSource
+		noCycle bool
+		want    time.Time
+	}{
+		{source: configpb.ProbeDef_EMIT, want: emit},
+		{source: configpb.ProbeDef_CYCLE_START, want: cycleStart},
+		{source: configpb.ProbeDef_CYCLE_END, want: cycleEnd},
+		{source: configpb.ProbeDef_CYCLE_START, noCycle: true, want: emit},
+		{source: configpb.ProbeDef_CYCLE_END, noCycle: true, want: emit},
+	} {
+		t.Run(fmt.Sprintf("%v_nocycle:%v", test.source, test.noCycle), func(t *testing.T) {
+			opts, err := BuildProbeOptions(&configpb.ProbeDef{
+				Targets:         testTargets,
+				TimestampSource: test.source.Enum(),
+			}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("BuildProbeOptions() error: %v", err)
+			}
+
+			start, end := cycleStart, cycleEnd
+			if test.noCycle {
+				start, end = time.Time{}, time.Time{}
+			}
+			if got := opts.MetricTimestamp(emit, start, end); !got.Equal(test.want) {
+				t.Errorf("MetricTimestamp()=%v, want=%v", got, test.want)
+			}
+		})
+	}
+}
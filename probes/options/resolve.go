@@ -0,0 +1,73 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DNSTimeoutError is returned by Resolve and ResolveTarget if the target
+// resolution takes longer than the DNS resolve timeout.
+type DNSTimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *DNSTimeoutError) Error() string {
+	return fmt.Sprintf("dns timeout: resolving %s took longer than %v", e.Name, e.Timeout)
+}
+
+// IsDNSTimeout returns true if the given error (or an error that it wraps) is
+// a DNSTimeoutError.
+func IsDNSTimeout(err error) bool {
+	var dtErr *DNSTimeoutError
+	return errors.As(err, &dtErr)
+}
+
+// Resolve resolves the target name for the given IP version using
+// Targets.Resolve, bounded by the DNS resolve timeout, if configured.
+func (opts *Options) Resolve(name string, ipVer int) (net.IP, error) {
+	return opts.resolveWithTimeout(name, ipVer, opts.Targets.Resolve)
+}
+
+func (opts *Options) resolveWithTimeout(name string, ipVer int, resolveF func(string, int) (net.IP, error)) (net.IP, error) {
+	if opts.DNSResolveTimeout == 0 {
+		return resolveF(name, ipVer)
+	}
+
+	type result struct {
+		ip  net.IP
+		err error
+	}
+	// Resolvers don't take a context, so we leave the slow resolution running
+	// in the background. Its result is discarded.
+	resultCh := make(chan result, 1)
+	go func() {
+		ip, err := resolveF(name, ipVer)
+		resultCh <- result{ip, err}
+	}()
+
+	timer := time.NewTimer(opts.DNSResolveTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-resultCh:
+		return r.ip, r.err
+	case <-timer.C:
+		return nil, &DNSTimeoutError{Name: name, Timeout: opts.DNSResolveTimeout}
+	}
+}
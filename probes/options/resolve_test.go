@@ -0,0 +1,108 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+// slowResolver returns a resolve function that takes the given delay to
+// resolve any name.
+func slowResolver(delay time.Duration) func(string, int) (net.IP, error) {
+	return func(name string, ipVer int) (net.IP, error) {
+		time.Sleep(delay)
+		return net.ParseIP("10.1.1.1"), nil
+	}
+}
+
+func TestResolveWithTimeout(t *testing.T) {
+	for _, test := range []struct {
+		desc           string
+		resolveTimeout time.Duration
+		delay          time.Duration
+		wantDNSTimeout bool
+	}{
+		{
+			desc:           "within_timeout",
+			resolveTimeout: 500 * time.Millisecond,
+			delay:          10 * time.Millisecond,
+		},
+		{
+			desc:           "slow_resolution",
+			resolveTimeout: 50 * time.Millisecond,
+			delay:          2 * time.Second,
+			wantDNSTimeout: true,
+		},
+		{
+			desc:  "no_timeout",
+			delay: 100 * time.Millisecond,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			opts := &Options{IPVersion: 4, DNSResolveTimeout: test.resolveTimeout}
+
+			start := time.Now()
+			ip, err := opts.ResolveTarget("slow.example.com", slowResolver(test.delay))
+			elapsed := time.Since(start)
+
+			if IsDNSTimeout(err) != test.wantDNSTimeout {
+				t.Fatalf("Got err=%v, want DNS timeout=%v", err, test.wantDNSTimeout)
+			}
+			if test.wantDNSTimeout {
+				// Should fail at the resolve timeout, not after the slow resolution.
+				if elapsed >= test.delay {
+					t.Errorf("DNS timeout took %v, resolve timeout: %v", elapsed, test.resolveTimeout)
+				}
+				return
+			}
+			if err != nil || ip.String() != "10.1.1.1" {
+				t.Errorf("Got ip=%v, err=%v, want ip=10.1.1.1", ip, err)
+			}
+		})
+	}
+}
+
+func TestDNSResolveTimeoutConfig(t *testing.T) {
+	for _, test := range []struct {
+		msec      int32
+		wantError bool
+	}{
+		{msec: 0},
+		{msec: 200},
+		{msec: -1, wantError: true},
+		{msec: 2000, wantError: true}, // Larger than the timeout.
+	} {
+		t.Run(fmt.Sprint(test.msec), func(t *testing.T) {
+			p := &configpb.ProbeDef{
+				Targets:               testTargets,
+				TimeoutMsec:           proto.Int32(1000),
+				DnsResolveTimeoutMsec: proto.Int32(test.msec),
+			}
+			opts, err := BuildProbeOptions(p, nil, nil, nil)
+			if (err != nil) != test.wantError {
+				t.Fatalf("BuildProbeOptions() error=%v, wantError=%v", err, test.wantError)
+			}
+			if err == nil && opts.DNSResolveTimeout != time.Duration(test.msec)*time.Millisecond {
+				t.Errorf("Got DNSResolveTimeout=%v, want=%dms", opts.DNSResolveTimeout, test.msec)
+			}
+		})
+	}
+}
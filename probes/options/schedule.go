@@ -0,0 +1,255 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cronField describes a field of the cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Optional value names, starting at min.
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is also Sunday, it's folded into 0 while parsing.
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// Schedule gates probe cycles based on a cron expression. Probe cycles run
+// only if the time matches the expression, with a minute granularity. In the
+// trigger mode, probe cycles run at the matching times, instead of at the
+// probe interval.
+type Schedule struct {
+	fields [5]uint64 // Bitset of the allowed values for each field.
+
+	// If both day of month and day of week are restricted (not "*"), time
+	// matches if either of them matches, as in standard cron.
+	domStar, dowStar bool
+
+	trigger bool
+
+	loc *time.Location
+	now func() time.Time
+}
+
+func (f *cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s: %q", f.name, s)
+	}
+	return v, nil
+}
+
+// parse parses a cron field: comma-separated list of "*", values or ranges,
+// each optionally followed by a step, e.g. "*/15", "1-5", "9-17/2,20".
+func (f *cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangeStr, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			rangeStr = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s: %q", f.name, part)
+			}
+		}
+
+		start, end := f.min, f.max
+		if rangeStr != "*" {
+			bounds := strings.SplitN(rangeStr, "-", 2)
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "N/step" means from N to max.
+				end = f.max
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range in %s: %q", f.name, part)
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// NewSchedule parses a standard 5-field cron expression (minute, hour, day
+// of month, month, day of week) and returns a Schedule. Time is matched in
+// the given location.
+func NewSchedule(cron string, loc *time.Location) (*Schedule, error) {
+	fields := strings.Fields(cron)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields, got %d", cron, len(cronFields), len(fields))
+	}
+
+	s := &Schedule{
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+		loc:     loc,
+		now:     time.Now,
+	}
+	for i, field := range fields {
+		bits, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", cron, err)
+		}
+		s.fields[i] = bits
+	}
+
+	// Fold Sunday(7) into Sunday(0).
+	if s.fields[4]&(1<<7) != 0 {
+		s.fields[4] = s.fields[4]&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+func (s *Schedule) has(field, v int) bool {
+	return s.fields[field]&(1<<uint(v)) != 0
+}
+
+// Matches returns true if the given time matches the schedule.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.loc != nil {
+		t = t.In(s.loc)
+	}
+	if !s.has(0, t.Minute()) || !s.has(1, t.Hour()) || !s.has(3, int(t.Month())) {
+		return false
+	}
+
+	return s.dayMatches(t)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch, dowMatch := s.has(2, t.Day()), s.has(4, int(t.Weekday()))
+	if !s.domStar && !s.dowStar {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// maxNextSearchYears limits the search for the next matching time, for the
+// expressions that never match, e.g. "0 0 30 2 *".
+const maxNextSearchYears = 5
+
+// Next returns the start of the first minute after t that matches the
+// schedule, or zero time if nothing matches in the next few years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := s.loc
+	if loc == nil {
+		loc = t.Location()
+	}
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+
+	for end := t.AddDate(maxNextSearchYears, 0, 0); t.Before(end); {
+		switch {
+		case !s.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !s.has(1, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !s.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Ticker delivers the ticks to run the probe cycles at. Like time.Ticker, it
+// drops the ticks if the probe is not keeping up.
+type Ticker struct {
+	C    <-chan time.Time
+	stop func()
+}
+
+// Stop turns off the ticker.
+func (t *Ticker) Stop() {
+	t.stop()
+}
+
+// newTicker returns a ticker that ticks at the start of every matching
+// minute.
+func (s *Schedule) newTicker() *Ticker {
+	c := make(chan time.Time, 1)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			now := s.now()
+			next := s.Next(now)
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case t := <-timer.C:
+				select {
+				case c <- t:
+				default:
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return &Ticker{C: c, stop: func() { once.Do(func() { close(done) }) }}
+}
+
+// NewTicker returns the ticker for the probe cycles: it ticks at the probe
+// interval, or at the schedule's times if the schedule is in trigger mode.
+func (opts *Options) NewTicker() *Ticker {
+	if opts.Schedule != nil && opts.Schedule.trigger {
+		return opts.Schedule.newTicker()
+	}
+	t := time.NewTicker(opts.Interval)
+	return &Ticker{C: t.C, stop: t.Stop}
+}
+
+// IsScheduled returns true if the probe cycle should run now, i.e. if no
+// schedule is configured, or if the current time matches the schedule. In
+// the trigger mode, ticker already ticks only at the schedule's times.
+func (opts *Options) IsScheduled() bool {
+	return opts.Schedule == nil || opts.Schedule.trigger || opts.Schedule.Matches(opts.Schedule.now())
+}
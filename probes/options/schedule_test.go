@@ -0,0 +1,225 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	// 2021-06-07 is a Monday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2021, time.June, day, hour, min, 0, 0, time.UTC)
+	}
+
+	for _, test := range []struct {
+		cron     string
+		matching []time.Time
+		others   []time.Time
+	}{
+		{
+			cron:     "* * * * *",
+			matching: []time.Time{at(7, 0, 0), at(13, 23, 59)},
+		},
+		{
+			cron:     "* 9-17 * * mon-fri",
+			matching: []time.Time{at(7, 9, 0), at(11, 17, 59)},
+			others:   []time.Time{at(7, 8, 59), at(7, 18, 0), at(12, 10, 0), at(13, 10, 0)},
+		},
+		{
+			cron:     "*/15 10 * * *",
+			matching: []time.Time{at(7, 10, 0), at(7, 10, 45)},
+			others:   []time.Time{at(7, 10, 10), at(7, 11, 0)},
+		},
+		{
+			cron:     "5,30-40/5 0 * jun 0",
+			matching: []time.Time{at(6, 0, 5), at(13, 0, 35)},
+			others:   []time.Time{at(7, 0, 5), at(6, 0, 31), at(6, 0, 45)},
+		},
+		{
+			// Both day of month and day of week are restricted: either matches.
+			cron:     "0 12 1 * 7",
+			matching: []time.Time{at(1, 12, 0), at(6, 12, 0)},
+			others:   []time.Time{at(7, 12, 0)},
+		},
+		{
+			cron:     "0 9/4 * * *",
+			matching: []time.Time{at(7, 9, 0), at(7, 13, 0), at(7, 21, 0)},
+			others:   []time.Time{at(7, 1, 0), at(7, 11, 0)},
+		},
+	} {
+		t.Run(test.cron, func(t *testing.T) {
+			s, err := NewSchedule(test.cron, time.UTC)
+			if err != nil {
+				t.Fatalf("NewSchedule(%q): %v", test.cron, err)
+			}
+			for _, ts := range test.matching {
+				if !s.Matches(ts) {
+					t.Errorf("Matches(%v)=false, want true", ts)
+				}
+			}
+			for _, ts := range test.others {
+				if s.Matches(ts) {
+					t.Errorf("Matches(%v)=true, want false", ts)
+				}
+			}
+		})
+	}
+}
+
+func TestNewScheduleErrors(t *testing.T) {
+	for _, cron := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := NewSchedule(cron, time.UTC); err == nil {
+			t.Errorf("NewSchedule(%q): expected error", cron)
+		}
+	}
+}
+
+func TestIsScheduled(t *testing.T) {
+	p := &configpb.ProbeDef{
+		Targets: testTargets,
+		Schedule: &configpb.ProbeDef_Schedule{
+			Cron:     proto.String("* 9-17 * * mon-fri"),
+			Timezone: proto.String("America/New_York"),
+		},
+	}
+	opts, err := BuildProbeOptions(p, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildProbeOptions() error: %v", err)
+	}
+
+	// Controllable clock: advance it by an hour for every cycle, starting at
+	// Monday 2021-06-07 06:30 New York time.
+	loc, _ := time.LoadLocation("America/New_York")
+	now := time.Date(2021, time.June, 7, 6, 30, 0, 0, loc)
+	opts.Schedule.now = func() time.Time { return now }
+
+	var firedAt []int
+	for i := 0; i < 24; i++ {
+		if opts.IsScheduled() {
+			firedAt = append(firedAt, now.Hour())
+		}
+		now = now.Add(time.Hour)
+	}
+
+	want := []int{9, 10, 11, 12, 13, 14, 15, 16, 17}
+	if len(firedAt) != len(want) {
+		t.Fatalf("Cycles fired at hours: %v, want: %v", firedAt, want)
+	}
+	for i := range want {
+		if firedAt[i] != want[i] {
+			t.Errorf("Cycles fired at hours: %v, want: %v", firedAt, want)
+			break
+		}
+	}
+
+	// Without schedule, all cycles fire.
+	if !(&Options{}).IsScheduled() {
+		t.Error("IsScheduled()=false without schedule")
+	}
+
+	p.Schedule.Timezone = proto.String("Invalid/Zone")
+	if _, err := BuildProbeOptions(p, nil, nil, nil); err == nil {
+		t.Error("BuildProbeOptions(): expected error for invalid timezone")
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2021-06-07 is a Monday.
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2021, month, day, hour, min, 0, 0, time.UTC)
+	}
+	from := at(time.June, 7, 10, 30).Add(15 * time.Second)
+
+	for _, test := range []struct {
+		cron string
+		want time.Time
+	}{
+		{"* * * * *", at(time.June, 7, 10, 31)},
+		{"30 10 * * *", at(time.June, 8, 10, 30)},
+		{"*/15 * * * *", at(time.June, 7, 10, 45)},
+		{"0 2 * * *", at(time.June, 8, 2, 0)},
+		{"0 9 * * sat", at(time.June, 12, 9, 0)},
+		{"0 0 1 jan *", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		t.Run(test.cron, func(t *testing.T) {
+			s, err := NewSchedule(test.cron, time.UTC)
+			if err != nil {
+				t.Fatalf("NewSchedule(%q): %v", test.cron, err)
+			}
+			if got := s.Next(from); !got.Equal(test.want) {
+				t.Errorf("Next(%v)=%v, want: %v", from, got, test.want)
+			}
+		})
+	}
+
+	// Time zone is taken into account: 2:30 in New York is 6:30 UTC in June.
+	loc, _ := time.LoadLocation("America/New_York")
+	s, _ := NewSchedule("30 2 * * *", loc)
+	if got, want := s.Next(from), at(time.June, 8, 6, 30); !got.Equal(want) {
+		t.Errorf("Next(%v)=%v, want: %v", from, got, want)
+	}
+}
+
+func TestTriggerSchedule(t *testing.T) {
+	p := &configpb.ProbeDef{
+		Targets: testTargets,
+		Schedule: &configpb.ProbeDef_Schedule{
+			Cron: proto.String("* * * * *"),
+			Mode: configpb.ProbeDef_Schedule_TRIGGER.Enum(),
+		},
+	}
+	opts, err := BuildProbeOptions(p, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildProbeOptions() error: %v", err)
+	}
+
+	// Shift the clock to just before the next minute.
+	now := time.Now()
+	offset := now.Truncate(time.Minute).Add(time.Minute - 50*time.Millisecond).Sub(now)
+	opts.Schedule.now = func() time.Time { return time.Now().Add(offset) }
+
+	ticker := opts.NewTicker()
+	defer ticker.Stop()
+	select {
+	case <-ticker.C:
+	case <-time.After(time.Second):
+		t.Fatal("No tick at the scheduled time")
+	}
+	if !opts.IsScheduled() {
+		t.Error("IsScheduled()=false in the trigger mode")
+	}
+
+	p.Schedule.Cron = proto.String("0 0 30 2 *")
+	if _, err := BuildProbeOptions(p, nil, nil, nil); err == nil {
+		t.Error("BuildProbeOptions(): expected error for a never matching schedule")
+	}
+}
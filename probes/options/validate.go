@@ -0,0 +1,56 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+
+	proberpb "github.com/cloudprober/cloudprober/config/proto"
+)
+
+// ProbeValidationResult is the validation report for a probe.
+type ProbeValidationResult struct {
+	Probe  string
+	Errors []*FieldError
+}
+
+// ValidateConfig validates the probe options of all the probes in the config,
+// and returns a report for each probe that has errors, in the config order.
+// Unlike BuildProbeOptions, it doesn't stop at the first error, and it
+// doesn't create targets or probes, so it doesn't need access to the targets
+// sources. Note that targets and the probe-type specific configs are not
+// validated. It returns nil if no errors are found.
+func ValidateConfig(cfg *proberpb.ProberConfig) []ProbeValidationResult {
+	var results []ProbeValidationResult
+	seen := make(map[string]bool)
+
+	for _, p := range cfg.GetProbe() {
+		var fieldErrs []*FieldError
+
+		if seen[p.GetName()] {
+			fieldErrs = append(fieldErrs, &FieldError{Field: "name", Err: fmt.Errorf("probe %s is already defined", p.GetName())})
+		}
+		seen[p.GetName()] = true
+
+		_, errs := buildOptions(p, nil, nil)
+		fieldErrs = append(fieldErrs, errs...)
+
+		if len(fieldErrs) != 0 {
+			results = append(results, ProbeValidationResult{Probe: p.GetName(), Errors: fieldErrs})
+		}
+	}
+
+	return results
+}
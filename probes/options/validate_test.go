@@ -0,0 +1,96 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"reflect"
+	"testing"
+
+	proberpb "github.com/cloudprober/cloudprober/config/proto"
+	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	validatorspb "github.com/cloudprober/cloudprober/validators/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+func TestValidateConfig(t *testing.T) {
+	cfg := &proberpb.ProberConfig{
+		Probe: []*configpb.ProbeDef{
+			{
+				Name:    proto.String("good"),
+				Targets: testTargets,
+			},
+			{
+				Name:               proto.String("bad_timeouts"),
+				Targets:            testTargets,
+				Interval:           proto.String("5s"),
+				IntervalMsec:       proto.Int32(5000),
+				Timeout:            proto.String("2x"),
+				ConnectTimeoutMsec: proto.Int32(-1),
+			},
+			{
+				Name:      proto.String("bad_source_and_validator"),
+				Targets:   testTargets,
+				IpVersion: configpb.ProbeDef_IPV6.Enum(),
+				SourceIpConfig: &configpb.ProbeDef_SourceIp{
+					SourceIp: "10.1.1.1",
+				},
+				Validator: []*validatorspb.Validator{
+					{Name: proto.String("no_type")},
+				},
+			},
+			{
+				Name:                    proto.String("good"),
+				Targets:                 testTargets,
+				IntervalMsec:            proto.Int32(20000),
+				StatsExportIntervalMsec: proto.Int32(10000),
+			},
+		},
+	}
+
+	results := ValidateConfig(cfg)
+
+	got := make(map[string][]string)
+	var probes []string
+	for _, r := range results {
+		probes = append(probes, r.Probe)
+		for _, fe := range r.Errors {
+			got[r.Probe] = append(got[r.Probe], fe.Field)
+		}
+	}
+
+	wantProbes := []string{"bad_timeouts", "bad_source_and_validator", "good"}
+	if !reflect.DeepEqual(probes, wantProbes) {
+		t.Errorf("Got failing probes: %v, want: %v", probes, wantProbes)
+	}
+
+	want := map[string][]string{
+		"bad_timeouts":             {"interval", "timeout", "connect_timeout_msec"},
+		"bad_source_and_validator": {"validator", "source_ip"},
+		"good":                     {"name", "stats_export_interval_msec"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got error fields: %v, want: %v", got, want)
+	}
+
+	// BuildProbeOptions reports the first of these errors.
+	_, err := BuildProbeOptions(cfg.GetProbe()[1], nil, nil, nil)
+	if fe, ok := err.(*FieldError); !ok || fe.Field != "interval" {
+		t.Errorf("BuildProbeOptions(): got error %v, want interval error", err)
+	}
+
+	if results := ValidateConfig(&proberpb.ProberConfig{Probe: cfg.GetProbe()[:1]}); results != nil {
+		t.Errorf("ValidateConfig(): got results %v for a valid config, want nil", results)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ping
+
+import (
+	"os"
+	"syscall"
+)
+
+// setDontFragment sets the DF bit on the packets sent through the socket fd,
+// by turning on the path MTU discovery. Kernel then refuses to send packets
+// bigger than the known path MTU (EMSGSIZE).
+func setDontFragment(fd, ipVer int) error {
+	var err error
+	if ipVer == 6 {
+		err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO)
+	} else {
+		err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO)
+	}
+	return os.NewSyscallError("setsockopt", err)
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ping
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestSetDontFragment(t *testing.T) {
+	for _, test := range []struct {
+		ipVer        int
+		family       int
+		level, opt   int
+		wantPMTUDisc int
+	}{
+		{4, syscall.AF_INET, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO},
+		{6, syscall.AF_INET6, syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO},
+	} {
+		s, err := syscall.Socket(test.family, syscall.SOCK_DGRAM, 0)
+		if err != nil {
+			t.Logf("Skipping IPv%d, error creating socket: %v", test.ipVer, err)
+			continue
+		}
+		defer syscall.Close(s)
+
+		if err := setDontFragment(s, test.ipVer); err != nil {
+			t.Fatalf("setDontFragment(_, %d): %v", test.ipVer, err)
+		}
+		got, err := syscall.GetsockoptInt(s, test.level, test.opt)
+		if err != nil {
+			t.Fatalf("Error getting the MTU discover option: %v", err)
+		}
+		if got != test.wantPMTUDisc {
+			t.Errorf("IPv%d: MTU discover option=%d, want=%d (DF set)", test.ipVer, got, test.wantPMTUDisc)
+		}
+	}
+}
+
+// TestListenPacketDontFragment verifies that the probe's ICMP socket has DF
+// set. It requires privileges to create ICMP sockets.
+func TestListenPacketDontFragment(t *testing.T) {
+	for _, datagram := range []bool{true, false} {
+		ipc, err := listenPacket(nil, "", 4, datagram, true)
+		if err != nil {
+			t.Logf("Skipping datagram=%v, error creating ICMP socket: %v", datagram, err)
+			continue
+		}
+		defer ipc.close()
+
+		var sc syscall.Conn = ipc.ipConn
+		if datagram {
+			sc = ipc.udpConn
+		}
+		rc, err := sc.SyscallConn()
+		if err != nil {
+			t.Fatalf("Error getting raw connection: %v", err)
+		}
+
+		var got int
+		var optErr error
+		rc.Control(func(fd uintptr) {
+			got, optErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER)
+		})
+		if optErr != nil || got != syscall.IP_PMTUDISC_DO {
+			t.Errorf("datagram=%v: MTU discover option=%d (err: %v), want=%d", datagram, got, optErr, syscall.IP_PMTUDISC_DO)
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package ping
 
 import (
+	"errors"
 	"net"
 	"strconv"
 	"time"
@@ -29,7 +30,14 @@ type icmpPacketConn struct {
 	c *icmp.PacketConn
 }
 
-func newICMPConn(sourceIP net.IP, ipVer int, datagramSocket bool) (icmpConn, error) {
+func newICMPConn(sourceIP net.IP, zone string, ipVer int, datagramSocket, dontFragment bool, flowLabel uint32) (icmpConn, error) {
+	if dontFragment {
+		return nil, errors.New("dont_fragment is supported only on Linux")
+	}
+	if flowLabel != 0 {
+		return nil, errors.New("ipv6_flow_label is supported only on Linux")
+	}
+
 	network := map[int]string{
 		4: "ip4:icmp",
 		6: "ip6:ipv6-icmp",
@@ -39,7 +47,12 @@ func newICMPConn(sourceIP net.IP, ipVer int, datagramSocket bool) (icmpConn, err
 		network = "udp" + strconv.Itoa(ipVer)
 	}
 
-	c, err := icmp.ListenPacket(network, sourceIP.String())
+	address := sourceIP.String()
+	if zone != "" {
+		address += "%" + zone
+	}
+
+	c, err := icmp.ListenPacket(network, address)
 	if err != nil {
 		return nil, err
 	}
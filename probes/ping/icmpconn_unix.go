@@ -19,6 +19,7 @@ package ping
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -26,6 +27,8 @@ import (
 	"syscall"
 	"time"
 	"unsafe"
+
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 )
 
 // NativeEndian is the machine native endian implementation of ByteOrder.
@@ -35,7 +38,7 @@ const (
 	protocolIP = 0
 )
 
-func sockaddr(sourceIP net.IP, ipVer int) (syscall.Sockaddr, error) {
+func sockaddr(sourceIP net.IP, zone string, ipVer int) (syscall.Sockaddr, error) {
 	a := &net.IPAddr{IP: sourceIP}
 
 	// If sourceIP is unspecified, we bind to the 0 IP address (all).
@@ -51,6 +54,14 @@ func sockaddr(sourceIP net.IP, ipVer int) (syscall.Sockaddr, error) {
 	case 6:
 		sa := &syscall.SockaddrInet6{}
 		copy(sa.Addr[:], a.IP)
+		// Link-local addresses need zone (interface) to be usable.
+		if zone != "" {
+			ifi, err := net.InterfaceByName(zone)
+			if err != nil {
+				return nil, err
+			}
+			sa.ZoneId = uint32(ifi.Index)
+		}
 		return sa, nil
 	default:
 		return nil, net.InvalidAddrError("unexpected family")
@@ -65,7 +76,7 @@ func sockaddr(sourceIP net.IP, ipVer int) (syscall.Sockaddr, error) {
 //      implementation ignores the protocol field entirely.
 //   2. ListenPacket doesn't support setting socket options (we need
 //      SO_TIMESTAMP) in a straightforward way.
-func listenPacket(sourceIP net.IP, ipVer int, datagramSocket bool) (*icmpPacketConn, error) {
+func listenPacket(sourceIP net.IP, zone string, ipVer int, datagramSocket, dontFragment bool) (*icmpPacketConn, error) {
 	var family, proto int
 
 	switch ipVer {
@@ -100,7 +111,14 @@ func listenPacket(sourceIP net.IP, ipVer int, datagramSocket bool) (*icmpPacketC
 		return nil, os.NewSyscallError("setsockopt", err)
 	}
 
-	sa, err := sockaddr(sourceIP, ipVer)
+	if dontFragment {
+		if err := setDontFragment(s, ipVer); err != nil {
+			syscall.Close(s)
+			return nil, err
+		}
+	}
+
+	sa, err := sockaddr(sourceIP, zone, ipVer)
 	if err != nil {
 		syscall.Close(s)
 		return nil, err
@@ -135,6 +153,10 @@ type icmpPacketConn struct {
 	// We use ipConn and udpConn for reading OOB data from the connection.
 	ipConn  *net.IPConn
 	udpConn *net.UDPConn
+
+	// oob is sent with each packet, set only if IPv6 flow label is
+	// configured.
+	oob []byte
 }
 
 func (ipc *icmpPacketConn) read(buf []byte) (n int, addr net.Addr, recvTime time.Time, err error) {
@@ -186,6 +208,10 @@ func (ipc *icmpPacketConn) read(buf []byte) (n int, addr net.Addr, recvTime time
 
 // write writes the ICMP message b to dst.
 func (ipc *icmpPacketConn) write(buf []byte, dst net.Addr) (int, error) {
+	if ipc.oob != nil {
+		n, _, err := ipc.ipConn.WriteMsgIP(buf, ipc.oob, dst.(*net.IPAddr))
+		return n, err
+	}
 	return ipc.c.WriteTo(buf, dst)
 }
 
@@ -200,8 +226,40 @@ func (ipc *icmpPacketConn) setReadDeadline(t time.Time) {
 	ipc.c.SetReadDeadline(t)
 }
 
-func newICMPConn(sourceIP net.IP, ipVer int, datagramSocket bool) (*icmpPacketConn, error) {
-	return listenPacket(sourceIP, ipVer, datagramSocket)
+// setIPv6FlowLabel sets the flow label on the packets sent through the
+// connection. It's supported only for the raw sockets, as kernel ignores the
+// flow label for the ICMP datagram sockets.
+func (ipc *icmpPacketConn) setIPv6FlowLabel(flowLabel uint32) error {
+	if ipc.ipConn == nil {
+		return errors.New("IPv6 flow label requires raw sockets")
+	}
+	rawConn, err := ipc.ipConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		sockErr = probeutils.SetIPv6FlowLabel(int(fd), flowLabel, nil)
+	}); err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return sockErr
+	}
+	ipc.oob = probeutils.IPv6FlowLabelOOB(flowLabel)
+	return nil
+}
+
+func newICMPConn(sourceIP net.IP, zone string, ipVer int, datagramSocket, dontFragment bool, flowLabel uint32) (*icmpPacketConn, error) {
+	ipc, err := listenPacket(sourceIP, zone, ipVer, datagramSocket, dontFragment)
+	if err != nil || flowLabel == 0 {
+		return ipc, err
+	}
+	if err := ipc.setIPv6FlowLabel(flowLabel); err != nil {
+		ipc.close()
+		return nil, err
+	}
+	return ipc, nil
 }
 
 // Find out native endianness when this packages is loaded.
@@ -41,17 +41,21 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/probes/options"
 	configpb "github.com/cloudprober/cloudprober/probes/ping/proto"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/validators"
 	"github.com/cloudprober/cloudprober/validators/integrity"
@@ -70,6 +74,36 @@ type result struct {
 	sent, rcvd        int64
 	latency           metrics.Value
 	validationFailure *metrics.Map
+
+	// Timestamp mode only.
+	unreachable, timestampFailures int64
+	clockOffsetMs                  float64
+	hasClockOffset                 bool
+
+	// Packets that didn't fit in the path MTU (dont_fragment only).
+	fragNeeded int64
+
+	// Packet delivery metrics (export_jitter_metrics only).
+	duplicates, outOfOrder int64
+	jitter, lastRTT        float64
+	rttSamples             int64
+
+	// Replies within the SLO latency objective (slo only).
+	sloGood int64
+
+	// RTTs of the replies since the last probe run (export_raw_latency only).
+	rawLatencies []time.Duration
+}
+
+// updateJitter updates the jitter estimate with the RTT of a reply, as in RFC
+// 3550: J += (|D| - J) / 16, where D is the difference between the RTTs of the
+// consecutive replies.
+func (r *result) updateJitter(rtt float64) {
+	if r.rttSamples > 0 {
+		r.jitter += (math.Abs(rtt-r.lastRTT) - r.jitter) / 16
+	}
+	r.lastRTT = rtt
+	r.rttSamples++
 }
 
 // icmpConn is an interface wrapper for *icmp.PacketConn to allow testing.
@@ -97,7 +131,21 @@ type Probe struct {
 	target2addr       map[string]net.Addr
 	ip2target         map[[16]byte]string
 	useDatagramSocket bool
-	statsExportFreq   int // Export frequency
+	statsExportFreq   int    // Export frequency
+	flowLabel         uint32 // IPv6 flow label, 0 if not set.
+
+	// Payload size for the current run. If payload_size_sweep is configured,
+	// results are kept per payload size, and results points to the current
+	// size's results.
+	payloadSize  int32
+	payloadSizes []int32
+	sizeResults  map[int32]map[string]*result
+
+	// Timestamp mode: send times of the timestamp requests sent in the current
+	// run, as timestamp replies don't carry sender's payload.
+	timestampMode bool
+	sendTimesMu   sync.Mutex
+	sendTimes     map[packetKey]int64
 }
 
 // Init initliazes the probe with the given params.
@@ -122,15 +170,38 @@ func (p *Probe) initInternal() error {
 		p.l = &logger.Logger{}
 	}
 
-	if p.c.GetPayloadSize() < timeBytesSize {
-		return fmt.Errorf("payload_size (%d) cannot be smaller than %d", p.c.GetPayloadSize(), timeBytesSize)
+	if err := checkPayloadSize(p.c.GetPayloadSize()); err != nil {
+		return err
 	}
-	if p.c.GetPayloadSize() > maxPacketSize-icmpHeaderSize {
-		return fmt.Errorf("payload_size (%d) cannot be bigger than %d", p.c.GetPayloadSize(), maxPacketSize-icmpHeaderSize)
+	p.payloadSize = p.c.GetPayloadSize()
+
+	p.timestampMode = p.c.GetMode() == configpb.ProbeConf_TIMESTAMP
+
+	p.results = make(map[string]*result)
+	p.sizeResults, p.payloadSizes = nil, nil
+	if sizes := p.c.GetPayloadSizeSweep(); len(sizes) > 0 {
+		if p.timestampMode {
+			return errors.New("payload_size_sweep is not supported in the timestamp mode")
+		}
+		p.sizeResults = make(map[int32]map[string]*result)
+		for _, size := range sizes {
+			if err := checkPayloadSize(size); err != nil {
+				return fmt.Errorf("payload_size_sweep: %v", err)
+			}
+			if p.sizeResults[size] != nil {
+				return fmt.Errorf("payload_size_sweep: duplicate payload size %d", size)
+			}
+			p.sizeResults[size] = make(map[string]*result)
+		}
+		p.payloadSizes = sizes
+		p.payloadSize, p.results = sizes[0], p.sizeResults[sizes[0]]
 	}
 
-	if err := p.configureIntegrityCheck(); err != nil {
-		return err
+	// Timestamp replies carry only timestamps, no payload to verify.
+	if !p.timestampMode {
+		if err := p.configureIntegrityCheck(); err != nil {
+			return err
+		}
 	}
 
 	p.statsExportFreq = int(p.opts.StatsExportInterval.Nanoseconds() / p.opts.Interval.Nanoseconds())
@@ -145,17 +216,45 @@ func (p *Probe) initInternal() error {
 		p.ipVer = p.opts.IPVersion
 	}
 
-	p.results = make(map[string]*result)
 	p.ip2target = make(map[[16]byte]string)
 	p.target2addr = make(map[string]net.Addr)
 	p.useDatagramSocket = p.c.GetUseDatagramSocket()
 
+	if p.timestampMode {
+		if p.ipVer != 4 {
+			return errors.New("timestamp mode is supported only for IPv4")
+		}
+		if p.useDatagramSocket {
+			return errors.New("timestamp mode requires raw sockets, set use_datagram_socket to false")
+		}
+	}
+
+	if p.opts.IPv6FlowLabel != 0 && p.ipVer == 6 {
+		if !probeutils.IPv6FlowLabelSupported {
+			p.l.Warningf("ipv6_flow_label is not supported on this platform, ignoring it")
+		} else if p.useDatagramSocket {
+			return errors.New("ipv6_flow_label requires raw sockets, set use_datagram_socket to false")
+		} else {
+			p.flowLabel = p.opts.IPv6FlowLabel
+		}
+	}
+
 	// Update targets run peiodically as well.
 	p.updateTargets()
 
 	return nil
 }
 
+func checkPayloadSize(size int32) error {
+	if size < timeBytesSize {
+		return fmt.Errorf("payload_size (%d) cannot be smaller than %d", size, timeBytesSize)
+	}
+	if size > maxPacketSize-icmpHeaderSize {
+		return fmt.Errorf("payload_size (%d) cannot be bigger than %d", size, maxPacketSize-icmpHeaderSize)
+	}
+	return nil
+}
+
 // Adds an integrity validator if data integrity checks are not disabled.
 func (p *Probe) configureIntegrityCheck() error {
 	if p.c.GetDisableIntegrityCheck() {
@@ -186,7 +285,7 @@ func (p *Probe) configureIntegrityCheck() error {
 
 func (p *Probe) listen() error {
 	var err error
-	p.conn, err = newICMPConn(p.opts.SourceIP, p.ipVer, p.useDatagramSocket)
+	p.conn, err = newICMPConn(p.opts.SourceIP, p.opts.SourceIPZone, p.ipVer, p.useDatagramSocket, p.c.GetDontFragment(), p.flowLabel)
 	return err
 }
 
@@ -220,7 +319,17 @@ func (p *Probe) updateTargets() {
 }
 
 func (p *Probe) updateResultForTarget(t string) {
-	if _, ok := p.results[t]; ok {
+	if p.sizeResults == nil {
+		p.updateResultForTargetIn(p.results, t)
+		return
+	}
+	for _, results := range p.sizeResults {
+		p.updateResultForTargetIn(results, t)
+	}
+}
+
+func (p *Probe) updateResultForTargetIn(results map[string]*result, t string) {
+	if _, ok := results[t]; ok {
 		return
 	}
 
@@ -231,7 +340,7 @@ func (p *Probe) updateResultForTarget(t string) {
 		latencyValue = metrics.NewFloat(0)
 	}
 
-	p.results[t] = &result{
+	results[t] = &result{
 		latency:           latencyValue,
 		validationFailure: validators.ValidationFailureMap(p.opts.Validators),
 	}
@@ -252,7 +361,24 @@ func (p *Probe) sendPackets(runID uint16, tracker chan bool) {
 
 	// Allocate a byte buffer of the size: ICMP Header Size (8) + Payload Size
 	// We re-use the same memory space for all outgoing packets.
-	pktbuf := make([]byte, icmpHeaderSize+p.c.GetPayloadSize())
+	pktbuf := make([]byte, icmpHeaderSize+p.payloadSize)
+
+	// In timestamp mode, we send one echo request to each target, as a
+	// reachability check, using the last sequence number of the run.
+	if p.timestampMode {
+		for _, target := range p.targets {
+			if p.target2addr[target.Name] == nil {
+				continue
+			}
+			p.prepareRequestPacket(pktbuf, runID, runID|0x00ff, time.Now().UnixNano())
+			if _, err := p.conn.write(pktbuf, p.target2addr[target.Name]); err != nil {
+				p.l.Warning(err.Error())
+				continue
+			}
+			tracker <- true
+		}
+		pktbuf = make([]byte, timestampPacketSize)
+	}
 
 	for {
 		for _, target := range p.targets {
@@ -260,8 +386,24 @@ func (p *Probe) sendPackets(runID uint16, tracker chan bool) {
 				p.l.Debug("Skipping unresolved target: ", target.Name)
 				continue
 			}
-			p.prepareRequestPacket(pktbuf, runID, seq, time.Now().UnixNano())
+			sendTime := time.Now().UnixNano()
+			if p.timestampMode {
+				prepareTimestampPacket(pktbuf, runID, seq, sendTime)
+				p.sendTimesMu.Lock()
+				p.sendTimes[packetKey{target.Name, seq}] = sendTime
+				p.sendTimesMu.Unlock()
+			} else {
+				p.prepareRequestPacket(pktbuf, runID, seq, sendTime)
+			}
 			if _, err := p.conn.write(pktbuf, p.target2addr[target.Name]); err != nil {
+				// With DF set, kernel refuses to send packets bigger than the
+				// known path MTU.
+				if p.c.GetDontFragment() && errors.Is(err, syscall.EMSGSIZE) {
+					p.l.Debug("Target:", target.Name, " packet bigger than the path MTU: ", err.Error())
+					p.results[target.Name].sent++
+					p.results[target.Name].fragNeeded++
+					continue
+				}
 				p.l.Warning(err.Error())
 				continue
 			}
@@ -295,9 +437,18 @@ type packetKey struct {
 func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 	// Number of expected packets: p.c.GetPacketsPerProbe() * len(p.targets)
 	received := make(map[packetKey]bool, int(p.c.GetPacketsPerProbe())*len(p.targets))
+	// Highest sequence number received per target, to detect reordering.
+	lastSeq := make(map[string]uint16, len(p.targets))
 	outstandingPkts := 0
 	p.conn.setReadDeadline(time.Now().Add(p.opts.Timeout))
 	pktbuf := make([]byte, maxPacketSize)
+
+	var ts *timestampRun
+	if p.timestampMode {
+		ts = newTimestampRun()
+		defer p.updateTimestampResults(ts)
+	}
+
 	for {
 		// To make sure that we have picked up all the packets sent by the sender, we
 		// use a tracker channel. Whenever sender successfully sends a packet, it notifies
@@ -340,6 +491,23 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 			recvTime = time.Now()
 		}
 
+		// ICMP errors come from the routers on the path, match them using the
+		// original packet that they carry.
+		if p.c.GetDontFragment() {
+			if dst, id, seq, ok := fragNeededInfo(p.ipVer, pktbuf[:pktLen]); ok {
+				target := p.ip2target[ipToKey(dst)]
+				key := packetKey{target, seq}
+				if target == "" || !matchPacket(runID, id, seq, p.useDatagramSocket) || received[key] {
+					continue
+				}
+				p.l.Debug("Target:", target, " fragmentation needed, from: ", peer.String())
+				received[key] = true
+				outstandingPkts--
+				p.results[target].fragNeeded++
+				continue
+			}
+		}
+
 		var ip net.IP
 		if p.useDatagramSocket {
 			ip = peer.(*net.UDPAddr).IP
@@ -352,8 +520,9 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 			continue
 		}
 
-		if !validEchoReply(p.ipVer, pktbuf[0]) {
-			p.l.Warning("Not a valid ICMP echo reply packet from: ", target)
+		isEchoReply := validEchoReply(p.ipVer, pktbuf[0])
+		if !isEchoReply && !(p.timestampMode && validTimestampReply(pktbuf[0])) {
+			p.l.Warning("Not a valid ICMP reply packet from: ", target)
 			continue
 		}
 
@@ -366,7 +535,19 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 			data: pktbuf[8:pktLen],
 		}
 
-		rtt := time.Duration(pkt.tsUnix-bytesToTime(pkt.data)) * time.Nanosecond
+		var rtt time.Duration
+		if p.timestampMode && !isEchoReply {
+			p.sendTimesMu.Lock()
+			sendTime, ok := p.sendTimes[packetKey{pkt.target, pkt.seq}]
+			p.sendTimesMu.Unlock()
+			if !ok {
+				p.l.Info("Timestamp reply ", pkt.String(0), " Unknown packet, probably from the last probe run.")
+				continue
+			}
+			rtt = time.Duration(pkt.tsUnix-sendTime) * time.Nanosecond
+		} else {
+			rtt = time.Duration(pkt.tsUnix-bytesToTime(pkt.data)) * time.Nanosecond
+		}
 
 		// check if this packet belongs to this run
 		if !matchPacket(runID, pkt.id, pkt.seq, p.useDatagramSocket) {
@@ -378,6 +559,7 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 		// Check if we have already seen this packet.
 		if received[key] {
 			p.l.Info("Duplicate reply ", pkt.String(rtt), " (DUP)")
+			p.results[pkt.target].duplicates++
 			continue
 		}
 		received[key] = true
@@ -386,9 +568,21 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 		// we were looking for.
 		outstandingPkts--
 
+		// In timestamp mode, echo replies are used only to check reachability.
+		if p.timestampMode && isEchoReply {
+			ts.reachable[pkt.target] = true
+			continue
+		}
+
 		// Update probe result
 		result := p.results[pkt.target]
 
+		if last, ok := lastSeq[pkt.target]; ok && pkt.seq < last {
+			result.outOfOrder++
+		} else {
+			lastSeq[pkt.target] = pkt.seq
+		}
+
 		if p.opts.Validators != nil {
 			failedValidations := validators.RunValidators(p.opts.Validators, &validators.Input{ResponseBody: pkt.data}, result.validationFailure, p.l)
 
@@ -402,6 +596,19 @@ func (p *Probe) recvPackets(runID uint16, tracker chan bool) {
 
 		result.rcvd++
 		result.latency.AddFloat64(rtt.Seconds() / p.opts.LatencyUnit.Seconds())
+		if p.opts.SLOLatencyObjective > 0 && p.opts.MeetsLatencyObjective(rtt) {
+			result.sloGood++
+		}
+		if p.opts.ExportRawLatency {
+			result.rawLatencies = append(result.rawLatencies, rtt)
+		}
+		if p.c.GetExportJitterMetrics() {
+			result.updateJitter(rtt.Seconds() / p.opts.LatencyUnit.Seconds())
+		}
+
+		if p.timestampMode {
+			ts.addReply(pkt, rtt)
+		}
 	}
 }
 
@@ -425,6 +632,7 @@ func (p *Probe) newRunID() uint16 {
 // runProbe is called by Run for each probe interval. It does the following on
 // each run:
 //   * Resolve targets if target resolve interval has elapsed.
+//   * Pick the payload size, if sweeping through payload sizes.
 //   * Increment run count (runCnt).
 //   * Get a new run ID.
 //   * Starts a goroutine to receive packets.
@@ -434,10 +642,20 @@ func (p *Probe) runProbe() {
 	if (p.runCnt % uint64(p.c.GetResolveTargetsInterval())) == 0 {
 		p.updateTargets()
 	}
+	if p.sizeResults != nil {
+		p.payloadSize = p.payloadSizes[p.runCnt%uint64(len(p.payloadSizes))]
+		p.results = p.sizeResults[p.payloadSize]
+	}
 	p.runCnt++
 	runID := p.newRunID()
 	wg := new(sync.WaitGroup)
-	tracker := make(chan bool, int(p.c.GetPacketsPerProbe())*len(p.targets))
+	numPkts := int(p.c.GetPacketsPerProbe()) * len(p.targets)
+	if p.timestampMode {
+		p.sendTimes = make(map[packetKey]int64, numPkts)
+		// One additional echo request per target.
+		numPkts += len(p.targets)
+	}
+	tracker := make(chan bool, numPkts)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -447,6 +665,17 @@ func (p *Probe) runProbe() {
 	wg.Wait()
 }
 
+// gaugeEM returns a gauge EventMetrics for the metric, with the same labels as
+// the given EventMetrics.
+func gaugeEM(em *metrics.EventMetrics, name string, v metrics.Value) *metrics.EventMetrics {
+	gem := metrics.NewEventMetrics(em.Timestamp).AddMetric(name, v)
+	gem.Kind = metrics.GAUGE
+	for _, k := range em.LabelsKeys() {
+		gem.AddLabel(k, em.Label(k))
+	}
+	return gem
+}
+
 // Start starts the probe and writes back the data on the provided channel.
 // Probe should have been initialized with Init() before calling Start on it.
 func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
@@ -455,7 +684,7 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 	}
 	defer p.conn.close()
 
-	ticker := time.NewTicker(p.opts.Interval)
+	ticker := p.opts.NewTicker()
 	defer ticker.Stop()
 
 	for ts := range ticker.C {
@@ -466,33 +695,108 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 		default:
 		}
 
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
 		p.runProbe()
+		runstats.RecordRun(p.name, start)
 		p.l.Debugf("%s: Probe finished.", p.name)
+		p.exportRawLatencies(dataChan)
 		if (p.runCnt % uint64(p.statsExportFreq)) != 0 {
 			continue
 		}
-		for _, target := range p.targets {
-			result := p.results[target.Name]
-			em := metrics.NewEventMetrics(ts).
-				AddMetric("total", metrics.NewInt(result.sent)).
-				AddMetric("success", metrics.NewInt(result.rcvd)).
-				AddMetric(p.opts.LatencyMetricName, result.latency).
-				AddLabel("ptype", "ping").
-				AddLabel("probe", p.name).
-				AddLabel("dst", target.Name)
-
-			em.LatencyUnit = p.opts.LatencyUnit
-
-			for _, al := range p.opts.AdditionalLabels {
-				em.AddLabel(al.KeyValueForTarget(target.Name))
-			}
+		ts = p.opts.MetricTimestamp(ts, start, time.Now())
+		if p.sizeResults == nil {
+			p.exportResults(ts, p.results, "", dataChan)
+			continue
+		}
+		for _, size := range p.payloadSizes {
+			p.exportResults(ts, p.sizeResults[size], strconv.Itoa(int(size)), dataChan)
+		}
+	}
+}
 
-			if p.opts.Validators != nil {
-				em.AddMetric("validation_failure", result.validationFailure)
-			}
+// exportRawLatencies exports the RTTs of the replies received in the last
+// probe run, as separate GAUGE EventMetrics, if export_raw_latency is enabled.
+func (p *Probe) exportRawLatencies(dataChan chan *metrics.EventMetrics) {
+	// Payload size label, if sweeping through payload sizes.
+	var labels *metrics.EventMetrics
+	if p.sizeResults != nil {
+		labels = metrics.NewEventMetrics(time.Now()).AddLabel("payload_size", strconv.Itoa(int(p.payloadSize)))
+	}
 
+	for _, target := range p.targets {
+		result := p.results[target.Name]
+		for _, rtt := range result.rawLatencies {
+			em := p.opts.RawLatencyEM(time.Now(), "ping", p.name, target.Name, rtt, labels)
 			p.opts.LogMetrics(em)
 			dataChan <- em
 		}
+		result.rawLatencies = result.rawLatencies[:0]
+	}
+}
+
+// exportResults exports the results for all the targets. payloadSize, if not
+// empty, is added as a label.
+func (p *Probe) exportResults(ts time.Time, results map[string]*result, payloadSize string, dataChan chan *metrics.EventMetrics) {
+	for _, target := range p.targets {
+		result := results[target.Name]
+		em := metrics.NewEventMetrics(ts).
+			AddMetric("total", metrics.NewInt(result.sent)).
+			AddMetric("success", metrics.NewInt(result.rcvd)).
+			AddMetric(p.opts.LatencyMetricName, result.latency).
+			AddLabel("ptype", "ping").
+			AddLabel("probe", p.name).
+			AddLabel("dst", target.Name)
+		if payloadSize != "" {
+			em.AddLabel("payload_size", payloadSize)
+		}
+
+		em.LatencyUnit = p.opts.LatencyUnit
+
+		for _, al := range p.opts.AdditionalLabels {
+			em.AddLabel(al.KeyValueForTarget(target.Name))
+		}
+
+		if p.opts.Validators != nil {
+			em.AddMetric("validation_failure", result.validationFailure)
+		}
+
+		if p.c.GetDontFragment() {
+			em.AddMetric("frag_needed", metrics.NewInt(result.fragNeeded))
+		}
+
+		if p.timestampMode {
+			em.AddMetric("unreachable", metrics.NewInt(result.unreachable)).
+				AddMetric("timestamp_failures", metrics.NewInt(result.timestampFailures))
+		}
+
+		if p.c.GetExportJitterMetrics() {
+			em.AddMetric("out_of_order", metrics.NewInt(result.outOfOrder)).
+				AddMetric("duplicates", metrics.NewInt(result.duplicates))
+		}
+
+		if p.opts.SLOLatencyObjective > 0 {
+			em.AddMetric("slo_good_total", metrics.NewInt(result.sloGood)).
+				AddMetric("slo_total", metrics.NewInt(result.sent))
+		}
+
+		p.opts.LogMetrics(em)
+		dataChan <- em
+
+		if p.c.GetExportJitterMetrics() && result.rttSamples > 1 {
+			jem := gaugeEM(em, "jitter", metrics.NewFloat(result.jitter))
+			p.opts.LogMetrics(jem)
+			dataChan <- jem
+		}
+
+		if p.timestampMode && result.hasClockOffset {
+			oem := clockOffsetEM(em, result.clockOffsetMs)
+			p.opts.LogMetrics(oem)
+			dataChan <- oem
+		}
 	}
 }
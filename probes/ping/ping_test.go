@@ -15,18 +15,25 @@
 package ping
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
+	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
+	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/probes/options"
 	configpb "github.com/cloudprober/cloudprober/probes/ping/proto"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
 	"github.com/cloudprober/cloudprober/targets"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"golang.org/x/net/icmp"
@@ -59,6 +66,58 @@ func replyPkt(pkt []byte, ipVersion int) []byte {
 	return b
 }
 
+// fragNeededPkt creates an ICMP "fragmentation needed" (IPv6: "packet too
+// big") error message for the ECHO request packet sent to dst.
+func fragNeededPkt(pkt []byte, dst net.IP, ipVersion, mtu int) []byte {
+	if ipVersion == 6 {
+		hdr := make([]byte, ipv6.HeaderLen)
+		hdr[0], hdr[6], hdr[7] = 0x60, protocolIPv6ICMP, 64
+		copy(hdr[24:40], dst.To16())
+		m := &icmp.Message{
+			Type: ipv6.ICMPTypePacketTooBig,
+			Body: &icmp.PacketTooBig{MTU: mtu, Data: append(hdr, pkt[:icmpHeaderSize]...)},
+		}
+		b, _ := m.Marshal(nil)
+		return b
+	}
+
+	h := &ipv4.Header{
+		Version:  4,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(pkt),
+		TTL:      64,
+		Protocol: protocolICMP,
+		Dst:      dst,
+	}
+	hdr, _ := h.Marshal()
+	m := &icmp.Message{
+		Type: ipv4.ICMPTypeDestinationUnreachable,
+		Code: 4,
+		Body: &icmp.DstUnreach{Data: append(hdr, pkt[:icmpHeaderSize]...)},
+	}
+	b, _ := m.Marshal(nil)
+	return b
+}
+
+// timestampReplyPkt creates a timestamp reply packet from the timestamp
+// request packet, setting the receive and transmit timestamps from the
+// current time and the given clock offset.
+func timestampReplyPkt(pkt []byte, clockOffsetMs int64) []byte {
+	b := make([]byte, len(pkt))
+	copy(b, pkt)
+	b[0] = byte(ipv4.ICMPTypeTimestampReply)
+	b[2], b[3] = 0, 0
+
+	ts := uint32((int64(msSinceMidnight(time.Now().UnixNano())) + clockOffsetMs + msPerDay) % msPerDay)
+	binary.BigEndian.PutUint32(b[12:16], ts)
+	binary.BigEndian.PutUint32(b[16:20], ts)
+
+	csum := checksum(b)
+	b[2] ^= byte(csum)
+	b[3] ^= byte(csum >> 8)
+	return b
+}
+
 // testICMPConn implements the icmpConn interface.
 // It implements the following packets pipeline:
 //      write(packet) --> sentPackets channel -> read() -> packet
@@ -72,6 +131,23 @@ type testICMPConn struct {
 
 	flipLastByte   bool
 	flipLastByteMu sync.Mutex
+
+	// Timestamp mode related.
+	deadline        time.Time
+	clockOffsetMs   int64           // Responder's clock offset.
+	noTimestampResp map[string]bool // Targets that don't answer timestamps.
+	noResp          map[string]bool // Targets that don't answer at all.
+
+	// Path MTU. Packets that don't fit in it are answered with an ICMP
+	// "fragmentation needed" error from a router, or for datagram sockets,
+	// are refused by the "kernel".
+	mtu int
+
+	// Packet delivery issues: every packet is answered twice (duplicate), or
+	// consecutive packets are answered in the reverse order (reorder).
+	duplicate bool
+	reorder   bool
+	held      map[string][]byte
 }
 
 func newTestICMPConn(opts *options.Options, targets []endpoint.Endpoint) *testICMPConn {
@@ -79,9 +155,10 @@ func newTestICMPConn(opts *options.Options, targets []endpoint.Endpoint) *testIC
 		c:           opts.ProbeConf.(*configpb.ProbeConf),
 		ipVersion:   opts.IPVersion,
 		sentPackets: make(map[string](chan []byte)),
+		held:        make(map[string][]byte),
 	}
 	for _, target := range targets {
-		tic.sentPackets[target.Name] = make(chan []byte, tic.c.GetPacketsPerProbe())
+		tic.sentPackets[target.Name] = make(chan []byte, 2*tic.c.GetPacketsPerProbe())
 	}
 	return tic
 }
@@ -102,17 +179,34 @@ func (tic *testICMPConn) read(buf []byte) (int, net.Addr, time.Time, error) {
 		targets = append(targets, t)
 	}
 
+	// If a read deadline is set, add a timeout case.
+	if !tic.deadline.IsZero() {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(time.Until(tic.deadline)))})
+	}
+
 	// Select over the select cases.
 	chosen, value, ok := reflect.Select(cases)
+	if chosen == len(targets) {
+		return 0, nil, time.Now(), &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}
+	}
 	if !ok {
 		return 0, nil, time.Now(), fmt.Errorf("nothing to read")
 	}
 
 	pkt := value.Bytes()
+	peerIP := net.ParseIP(targets[chosen])
 
 	// Since we are echoing the packets, copy the received packet into the
 	// provided buffer.
-	respPkt := replyPkt(pkt, tic.ipVersion)
+	var respPkt []byte
+	if tic.tooBig(pkt) {
+		respPkt = fragNeededPkt(pkt, peerIP, tic.ipVersion, tic.mtu)
+		peerIP = net.ParseIP(map[int]string{4: "10.0.0.1", 6: "fe80::1"}[tic.ipVersion])
+	} else if ipv4.ICMPType(pkt[0]) == ipv4.ICMPTypeTimestamp {
+		respPkt = timestampReplyPkt(pkt, tic.clockOffsetMs)
+	} else {
+		respPkt = replyPkt(pkt, tic.ipVersion)
+	}
 	tic.flipLastByteMu.Lock()
 	if tic.flipLastByte {
 		lastByte := ^respPkt[len(respPkt)-1]
@@ -120,15 +214,19 @@ func (tic *testICMPConn) read(buf []byte) (int, net.Addr, time.Time, error) {
 	}
 	tic.flipLastByteMu.Unlock()
 
-	copy(buf[0:len(pkt)], respPkt)
-	peerIP := net.ParseIP(targets[chosen])
+	copy(buf[0:len(respPkt)], respPkt)
 
 	var peer net.Addr
 	peer = &net.IPAddr{IP: peerIP}
 	if tic.c.GetUseDatagramSocket() {
 		peer = &net.UDPAddr{IP: peerIP}
 	}
-	return len(pkt), peer, time.Now(), nil
+	return len(respPkt), peer, time.Now(), nil
+}
+
+func (tic *testICMPConn) tooBig(pkt []byte) bool {
+	ipHeaderLen := map[int]int{4: ipv4.HeaderLen, 6: ipv6.HeaderLen}[tic.ipVersion]
+	return tic.mtu != 0 && ipHeaderLen+len(pkt) > tic.mtu
 }
 
 // write simply queues packets into the sentPackets channel. These packets are
@@ -136,16 +234,37 @@ func (tic *testICMPConn) read(buf []byte) (int, net.Addr, time.Time, error) {
 func (tic *testICMPConn) write(in []byte, peer net.Addr) (int, error) {
 	target := peerToIP(peer)
 
+	if tic.noResp[target] || (tic.noTimestampResp[target] && ipv4.ICMPType(in[0]) == ipv4.ICMPTypeTimestamp) {
+		return len(in), nil
+	}
+
+	if tic.c.GetUseDatagramSocket() && tic.tooBig(in) {
+		return 0, &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+	}
+
 	// Copy incoming bytes slice and store in the internal channel for use
 	// during the read call.
 	b := make([]byte, len(in))
 	copy(b, in)
+
+	if tic.reorder && tic.held[target] == nil {
+		tic.held[target] = b
+		return len(b), nil
+	}
 	tic.sentPackets[target] <- b
+	if tic.duplicate {
+		tic.sentPackets[target] <- b
+	}
+	if tic.held[target] != nil {
+		tic.sentPackets[target] <- tic.held[target]
+		tic.held[target] = nil
+	}
 
 	return len(b), nil
 }
 
 func (tic *testICMPConn) setReadDeadline(deadline time.Time) {
+	tic.deadline = deadline
 }
 
 func (tic *testICMPConn) close() {
@@ -391,3 +510,345 @@ func TestDataIntegrityValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestRunProbeTimestamp(t *testing.T) {
+	c := &configpb.ProbeConf{
+		Mode:              configpb.ProbeConf_TIMESTAMP.Enum(),
+		UseDatagramSocket: proto.Bool(false),
+	}
+	p, err := newProbe(c, 4, []string{"2.2.2.2", "3.3.3.3", "4.4.4.4"})
+	if err != nil {
+		t.Fatalf("Got error from newProbe: %v", err)
+	}
+	p.opts.Timeout = 200 * time.Millisecond
+
+	tic := newTestICMPConn(p.opts, p.targets)
+	tic.clockOffsetMs = 5000
+	tic.noTimestampResp = map[string]bool{"3.3.3.3": true}
+	tic.noResp = map[string]bool{"4.4.4.4": true}
+	p.conn = tic
+
+	p.runProbe()
+
+	for _, test := range []struct {
+		target                        string
+		rcvd, unreachable, tsFailures int64
+		hasClockOffset                bool
+	}{
+		{target: "2.2.2.2", rcvd: 2, hasClockOffset: true},
+		{target: "3.3.3.3", tsFailures: 1},
+		{target: "4.4.4.4", unreachable: 1},
+	} {
+		res := p.results[test.target]
+		if res.sent != 2 || res.rcvd != test.rcvd {
+			t.Errorf("Target %s: sent=%d, rcvd=%d, want sent=2, rcvd=%d", test.target, res.sent, res.rcvd, test.rcvd)
+		}
+		if res.unreachable != test.unreachable || res.timestampFailures != test.tsFailures {
+			t.Errorf("Target %s: unreachable=%d, timestamp_failures=%d, want unreachable=%d, timestamp_failures=%d", test.target, res.unreachable, res.timestampFailures, test.unreachable, test.tsFailures)
+		}
+		if res.hasClockOffset != test.hasClockOffset {
+			t.Errorf("Target %s: hasClockOffset=%v, want=%v", test.target, res.hasClockOffset, test.hasClockOffset)
+		}
+		// Timestamps have millisecond resolution.
+		if test.hasClockOffset && math.Abs(res.clockOffsetMs-5000) > 5 {
+			t.Errorf("Target %s: clock offset=%f ms, want ~5000 ms", test.target, res.clockOffsetMs)
+		}
+	}
+
+	// Clock offset is not carried over to the runs without timestamp replies.
+	tic.noTimestampResp["2.2.2.2"] = true
+	p.runProbe()
+	if res := p.results["2.2.2.2"]; res.hasClockOffset {
+		t.Errorf("Target 2.2.2.2: hasClockOffset=true after a run without timestamp replies, offset: %f ms", res.clockOffsetMs)
+	}
+}
+
+func TestTimestampModeConfig(t *testing.T) {
+	for _, test := range []struct {
+		desc              string
+		ipVersion         int
+		useDatagramSocket bool
+		wantErr           bool
+	}{
+		{desc: "ipv4_raw", ipVersion: 4},
+		{desc: "ipv6", ipVersion: 6, wantErr: true},
+		{desc: "datagram_socket", ipVersion: 4, useDatagramSocket: true, wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c := &configpb.ProbeConf{
+				Mode:              configpb.ProbeConf_TIMESTAMP.Enum(),
+				UseDatagramSocket: proto.Bool(test.useDatagramSocket),
+			}
+			_, err := newProbe(c, test.ipVersion, []string{"2.2.2.2"})
+			if (err != nil) != test.wantErr {
+				t.Errorf("newProbe() error: %v, wantErr: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestIPv6FlowLabelConfig(t *testing.T) {
+	if !probeutils.IPv6FlowLabelSupported {
+		t.Skip("IPv6 flow label is not supported on this platform")
+	}
+	for _, test := range []struct {
+		desc              string
+		ipVersion         int
+		useDatagramSocket bool
+		wantFlowLabel     uint32
+		wantErr           bool
+	}{
+		{desc: "ipv6_raw", ipVersion: 6, wantFlowLabel: 0x12345},
+		{desc: "ipv6_datagram_socket", ipVersion: 6, useDatagramSocket: true, wantErr: true},
+		{desc: "ipv4_ignored", ipVersion: 4},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := &Probe{
+				name: "ping_test",
+				opts: &options.Options{
+					ProbeConf:     &configpb.ProbeConf{UseDatagramSocket: proto.Bool(test.useDatagramSocket)},
+					Targets:       targets.StaticTargets("::1"),
+					Interval:      2 * time.Second,
+					Timeout:       time.Second,
+					IPVersion:     test.ipVersion,
+					IPv6FlowLabel: 0x12345,
+				},
+			}
+			err := p.initInternal()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("initInternal() error: %v, wantErr: %v", err, test.wantErr)
+			}
+			if p.flowLabel != test.wantFlowLabel {
+				t.Errorf("Got flow label: %#x, want: %#x", p.flowLabel, test.wantFlowLabel)
+			}
+		})
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	body := make([]byte, timestampBodySize)
+	// Local send and receive times, 10ms apart, just before midnight.
+	sendTime := int64(msPerDay-5) * int64(time.Millisecond)
+	recvTime := sendTime + 10*int64(time.Millisecond)
+
+	// Remote clock is 20ms ahead: receive and transmit timestamps are after
+	// midnight.
+	binary.BigEndian.PutUint32(body[4:8], 20)
+	binary.BigEndian.PutUint32(body[8:12], 20)
+	offset, ok := clockOffset(body, sendTime, recvTime)
+	if !ok || offset != 20 {
+		t.Errorf("clockOffset()=%f, %v, want=20, true", offset, ok)
+	}
+
+	// Non-standard timestamps.
+	binary.BigEndian.PutUint32(body[8:12], 20|nonStandardTimestampBit)
+	if _, ok := clockOffset(body, sendTime, recvTime); ok {
+		t.Errorf("clockOffset() returned ok for non-standard timestamps")
+	}
+}
+
+func TestRunProbeDontFragment(t *testing.T) {
+	for _, ipVersion := range []int{4, 6} {
+		for _, datagram := range []bool{false, true} {
+			t.Run(fmt.Sprintf("ipv%d_datagram_%v", ipVersion, datagram), func(t *testing.T) {
+				targets := map[int][]string{4: {"2.2.2.2", "3.3.3.3"}, 6: {"::2", "::3"}}[ipVersion]
+
+				for _, test := range []struct {
+					payloadSize    int
+					wantRcvd       int64
+					wantFragNeeded int64
+				}{
+					{payloadSize: 1000, wantRcvd: 2},
+					{payloadSize: 1500, wantFragNeeded: 2},
+				} {
+					c := &configpb.ProbeConf{
+						UseDatagramSocket: proto.Bool(datagram),
+						PayloadSize:       proto.Int32(int32(test.payloadSize)),
+						DontFragment:      proto.Bool(true),
+					}
+					p, err := newProbe(c, ipVersion, targets)
+					if err != nil {
+						t.Fatalf("Got error from newProbe: %v", err)
+					}
+					tic := newTestICMPConn(p.opts, p.targets)
+					tic.mtu = 1500
+					p.conn = tic
+
+					p.runProbe()
+					for _, target := range targets {
+						res := p.results[target]
+						if res.sent != 2 || res.rcvd != test.wantRcvd || res.fragNeeded != test.wantFragNeeded {
+							t.Errorf("payload_size=%d, target=%s: got sent=%d, rcvd=%d, frag_needed=%d, want sent=2, rcvd=%d, frag_needed=%d", test.payloadSize, target, res.sent, res.rcvd, res.fragNeeded, test.wantRcvd, test.wantFragNeeded)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRunProbeJitterMetrics(t *testing.T) {
+	for _, test := range []struct {
+		desc           string
+		duplicate      bool
+		reorder        bool
+		wantDuplicates int64
+		wantOutOfOrder int64
+	}{
+		{desc: "normal"},
+		// Duplicate reply to the last packet is not read, as the probe run
+		// finishes as soon as all the replies are received.
+		{desc: "duplicate", duplicate: true, wantDuplicates: 1},
+		{desc: "reorder", reorder: true, wantOutOfOrder: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c := &configpb.ProbeConf{
+				UseDatagramSocket:   proto.Bool(false),
+				ExportJitterMetrics: proto.Bool(true),
+			}
+			p, err := newProbe(c, 4, []string{"2.2.2.2"})
+			if err != nil {
+				t.Fatalf("Got error from newProbe: %v", err)
+			}
+			p.opts.LatencyUnit = time.Millisecond
+			tic := newTestICMPConn(p.opts, p.targets)
+			tic.duplicate, tic.reorder = test.duplicate, test.reorder
+			p.conn = tic
+
+			p.runProbe()
+			res := p.results["2.2.2.2"]
+			if res.sent != 2 || res.rcvd != 2 {
+				t.Errorf("Got sent=%d, rcvd=%d, want sent=2, rcvd=2", res.sent, res.rcvd)
+			}
+			if res.duplicates != test.wantDuplicates || res.outOfOrder != test.wantOutOfOrder {
+				t.Errorf("Got duplicates=%d, out_of_order=%d, want duplicates=%d, out_of_order=%d", res.duplicates, res.outOfOrder, test.wantDuplicates, test.wantOutOfOrder)
+			}
+			if res.rttSamples != 2 || res.jitter < 0 {
+				t.Errorf("Got rtt samples=%d, jitter=%f, want 2 samples and jitter >= 0", res.rttSamples, res.jitter)
+			}
+		})
+	}
+}
+
+func TestRunProbeRawLatencyAndSLO(t *testing.T) {
+	c := &configpb.ProbeConf{
+		UseDatagramSocket: proto.Bool(false),
+	}
+	p, err := newProbe(c, 4, []string{"2.2.2.2"})
+	if err != nil {
+		t.Fatalf("Got error from newProbe: %v", err)
+	}
+	p.opts.LatencyUnit = time.Millisecond
+	p.opts.LogMetrics = func(*metrics.EventMetrics) {}
+	p.opts.LatencyMetricName = "latency"
+	p.opts.ExportRawLatency = true
+	// Test ICMP conn replies right away, so all replies are within the SLO.
+	p.opts.SLOLatencyObjective = time.Second
+	p.conn = newTestICMPConn(p.opts, p.targets)
+
+	p.runProbe()
+	dataChan := make(chan *metrics.EventMetrics, 10)
+	p.exportRawLatencies(dataChan)
+
+	// One raw latency EventMetrics per reply.
+	for i := 0; i < 2; i++ {
+		em := <-dataChan
+		if em.Kind != metrics.GAUGE || em.Metric("latency_raw") == nil || em.Label("dst") != "2.2.2.2" {
+			t.Errorf("Got unexpected raw latency EventMetrics: %s", em.String())
+		}
+	}
+	if len(dataChan) != 0 || len(p.results["2.2.2.2"].rawLatencies) != 0 {
+		t.Errorf("Got %d more EventMetrics, %d unexported latencies, want none", len(dataChan), len(p.results["2.2.2.2"].rawLatencies))
+	}
+
+	p.exportResults(time.Now(), p.results, "", dataChan)
+	em := <-dataChan
+	for _, name := range []string{"slo_good_total", "slo_total"} {
+		if got := em.Metric(name).(metrics.NumValue).Int64(); got != 2 {
+			t.Errorf("Got %s=%d, want=2", name, got)
+		}
+	}
+}
+
+func TestUpdateJitter(t *testing.T) {
+	r := &result{}
+	for i, test := range []struct {
+		rtt, wantJitter float64
+	}{
+		{rtt: 10, wantJitter: 0},
+		{rtt: 20, wantJitter: 0.625},
+		{rtt: 15, wantJitter: 0.625 + (5-0.625)/16},
+	} {
+		r.updateJitter(test.rtt)
+		if r.jitter != test.wantJitter {
+			t.Errorf("After RTT #%d (%f): got jitter=%f, want=%f", i, test.rtt, r.jitter, test.wantJitter)
+		}
+	}
+}
+
+func TestRunProbePayloadSizeSweep(t *testing.T) {
+	targets := []string{"2.2.2.2", "3.3.3.3"}
+	c := &configpb.ProbeConf{
+		UseDatagramSocket: proto.Bool(false),
+		PayloadSizeSweep:  []int32{1000, 1500},
+		DontFragment:      proto.Bool(true),
+	}
+	p, err := newProbe(c, 4, targets)
+	if err != nil {
+		t.Fatalf("Got error from newProbe: %v", err)
+	}
+	p.opts.LogMetrics = func(*metrics.EventMetrics) {}
+	tic := newTestICMPConn(p.opts, p.targets)
+	tic.mtu = 1500
+	p.conn = tic
+
+	// Each run uses the next payload size, wrapping around.
+	for i := 0; i < 3; i++ {
+		p.runProbe()
+	}
+
+	for _, test := range []struct {
+		size                            int32
+		wantSent, wantRcvd, wantFragged int64
+	}{
+		{size: 1000, wantSent: 4, wantRcvd: 4},
+		{size: 1500, wantSent: 2, wantFragged: 2},
+	} {
+		for _, target := range targets {
+			res := p.sizeResults[test.size][target]
+			if res.sent != test.wantSent || res.rcvd != test.wantRcvd || res.fragNeeded != test.wantFragged {
+				t.Errorf("size=%d, target=%s: got sent=%d, rcvd=%d, frag_needed=%d, want sent=%d, rcvd=%d, frag_needed=%d", test.size, target, res.sent, res.rcvd, res.fragNeeded, test.wantSent, test.wantRcvd, test.wantFragged)
+			}
+		}
+	}
+
+	dataChan := make(chan *metrics.EventMetrics, 2*len(targets))
+	for _, size := range p.payloadSizes {
+		p.exportResults(time.Now(), p.sizeResults[size], strconv.Itoa(int(size)), dataChan)
+	}
+	close(dataChan)
+	got := make(map[string]int64)
+	for em := range dataChan {
+		got[em.Label("dst")+"/"+em.Label("payload_size")] = em.Metric("success").(*metrics.Int).Int64()
+	}
+	want := map[string]int64{"2.2.2.2/1000": 4, "3.3.3.3/1000": 4, "2.2.2.2/1500": 0, "3.3.3.3/1500": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got exported success per target and payload size: %v, want: %v", got, want)
+	}
+}
+
+func TestPayloadSizeSweepConfig(t *testing.T) {
+	for _, c := range []*configpb.ProbeConf{
+		{PayloadSizeSweep: []int32{1000, 4}},
+		{PayloadSizeSweep: []int32{1000, 1000}},
+		{
+			PayloadSizeSweep:  []int32{1000},
+			Mode:              configpb.ProbeConf_TIMESTAMP.Enum(),
+			UseDatagramSocket: proto.Bool(false),
+		},
+	} {
+		if _, err := newProbe(c, 4, []string{"2.2.2.2"}); err == nil {
+			t.Errorf("newProbe(%v): expected error", c)
+		}
+	}
+}
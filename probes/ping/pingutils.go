@@ -51,6 +51,42 @@ func validEchoReply(ipVer int, typeByte byte) bool {
 	}
 }
 
+// fragNeededInfo parses an ICMP "fragmentation needed" (IPv6: "packet too
+// big") error message, and returns the destination, ICMP id and sequence
+// number of the echo request that triggered it. Error messages carry the
+// original packet's IP header and at least 8 bytes of its payload.
+func fragNeededInfo(ipVer int, pkt []byte) (dst net.IP, id, seq uint16, ok bool) {
+	if len(pkt) < icmpHeaderSize {
+		return nil, 0, 0, false
+	}
+	orig := pkt[icmpHeaderSize:]
+
+	var echo []byte
+	if ipVer == 6 {
+		if ipv6.ICMPType(pkt[0]) != ipv6.ICMPTypePacketTooBig || len(orig) < ipv6.HeaderLen+icmpHeaderSize || orig[6] != protocolIPv6ICMP {
+			return nil, 0, 0, false
+		}
+		dst, echo = net.IP(orig[24:40]), orig[ipv6.HeaderLen:]
+		if ipv6.ICMPType(echo[0]) != ipv6.ICMPTypeEchoRequest {
+			return nil, 0, 0, false
+		}
+	} else {
+		// Code 4 of destination unreachable is "fragmentation needed".
+		if ipv4.ICMPType(pkt[0]) != ipv4.ICMPTypeDestinationUnreachable || pkt[1] != 4 || len(orig) < ipv4.HeaderLen {
+			return nil, 0, 0, false
+		}
+		hdrLen := int(orig[0]&0x0f) << 2
+		if hdrLen < ipv4.HeaderLen || len(orig) < hdrLen+icmpHeaderSize || orig[9] != protocolICMP {
+			return nil, 0, 0, false
+		}
+		dst, echo = net.IP(orig[16:20]), orig[hdrLen:]
+		if ipv4.ICMPType(echo[0]) != ipv4.ICMPTypeEcho {
+			return nil, 0, 0, false
+		}
+	}
+	return dst, binary.BigEndian.Uint16(echo[4:6]), binary.BigEndian.Uint16(echo[6:8]), true
+}
+
 func prepareRequestPayload(payload []byte, unixNano int64) {
 	var timeBytes [timeBytesSize]byte
 	for i := uint8(0); i < timeBytesSize; i++ {
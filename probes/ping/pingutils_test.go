@@ -16,6 +16,7 @@ package ping
 
 import (
 	"bytes"
+	"net"
 	"testing"
 	"time"
 
@@ -120,3 +121,31 @@ func TestPktString(t *testing.T) {
 		t.Errorf("pktString(%q, %s): expected=%s wanted=%s", testPkt, rtt, got, expectedString)
 	}
 }
+
+func TestFragNeededInfo(t *testing.T) {
+	for _, ipVer := range []int{4, 6} {
+		p := &Probe{
+			c:     &configpb.ProbeConf{PayloadSize: proto.Int32(1400)},
+			ipVer: ipVer,
+		}
+		pktbuf := make([]byte, icmpHeaderSize+p.c.GetPayloadSize())
+		p.prepareRequestPacket(pktbuf, 0x1234, 0x1201, time.Now().UnixNano())
+
+		dst := map[int]string{4: "2.2.2.2", 6: "2001:db8::2"}[ipVer]
+		errPkt := fragNeededPkt(pktbuf, net.ParseIP(dst), ipVer, 1280)
+
+		gotDst, id, seq, ok := fragNeededInfo(ipVer, errPkt)
+		if !ok || !gotDst.Equal(net.ParseIP(dst)) || id != 0x1234 || seq != 0x1201 {
+			t.Errorf("IPv%d: fragNeededInfo()=%v, %#x, %#x, %v; want=%s, 0x1234, 0x1201, true", ipVer, gotDst, id, seq, ok, dst)
+		}
+
+		// Not an error message.
+		if _, _, _, ok := fragNeededInfo(ipVer, replyPkt(pktbuf, ipVer)); ok {
+			t.Errorf("IPv%d: fragNeededInfo(echo reply): ok=true, want=false", ipVer)
+		}
+		// Truncated error message.
+		if _, _, _, ok := fragNeededInfo(ipVer, errPkt[:20]); ok {
+			t.Errorf("IPv%d: fragNeededInfo(truncated message): ok=true, want=false", ipVer)
+		}
+	}
+}
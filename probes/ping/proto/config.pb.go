@@ -20,7 +20,72 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// Next tag: 1
+type ProbeConf_Mode int32
+
+const (
+	// ICMP echo requests (regular ping).
+	ProbeConf_ECHO ProbeConf_Mode = 0
+	// ICMP timestamp requests (type 13). Timestamps from the replies are used
+	// to estimate the target's clock offset, exported as the clock_offset_ms
+	// gauge. Along with the timestamp requests, probe sends one echo request
+	// to each target per probe run, to tell apart unreachable targets
+	// ("unreachable" counter) from the targets that are reachable but don't
+	// answer timestamp requests ("timestamp_failures" counter).
+	// Timestamp mode is supported only for IPv4 and requires raw sockets, i.e.
+	// use_datagram_socket should be set to false.
+	ProbeConf_TIMESTAMP ProbeConf_Mode = 1
+)
+
+// Enum value maps for ProbeConf_Mode.
+var (
+	ProbeConf_Mode_name = map[int32]string{
+		0: "ECHO",
+		1: "TIMESTAMP",
+	}
+	ProbeConf_Mode_value = map[string]int32{
+		"ECHO":      0,
+		"TIMESTAMP": 1,
+	}
+)
+
+func (x ProbeConf_Mode) Enum() *ProbeConf_Mode {
+	p := new(ProbeConf_Mode)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_Mode) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Mode) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Mode(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Mode.Descriptor instead.
+func (ProbeConf_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+// Next tag: 16
 type ProbeConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -48,7 +113,34 @@ type ProbeConf struct {
 	// Disable integrity checks. To detect data courruption in the network, we
 	// craft the outgoing ICMP packet payload in a certain format and verify that
 	// the reply payload matches the same format.
-	DisableIntegrityCheck *bool `protobuf:"varint,13,opt,name=disable_integrity_check,json=disableIntegrityCheck,def=0" json:"disable_integrity_check,omitempty"`
+	DisableIntegrityCheck *bool           `protobuf:"varint,13,opt,name=disable_integrity_check,json=disableIntegrityCheck,def=0" json:"disable_integrity_check,omitempty"`
+	Mode                  *ProbeConf_Mode `protobuf:"varint,14,opt,name=mode,enum=cloudprober.probes.ping.ProbeConf_Mode,def=0" json:"mode,omitempty"`
+	// Set the don't-fragment (DF) bit on the outgoing packets, by enabling the
+	// path MTU discovery on the socket (IP_MTU_DISCOVER/IPV6_MTU_DISCOVER set to
+	// "DO"). Along with payload_size, it can be used to test the path MTU.
+	// Packets that don't fit in the path MTU are counted in the "frag_needed"
+	// metric: these are the packets for which we get an ICMP "fragmentation
+	// needed" (IPv6: "packet too big") error back (raw sockets only), and the
+	// packets that kernel refuses to send as they are bigger than the known
+	// path MTU. Supported only on Linux.
+	DontFragment *bool `protobuf:"varint,15,opt,name=dont_fragment,json=dontFragment" json:"dont_fragment,omitempty"`
+	// Export packet delivery quality metrics, in addition to latency and loss:
+	//   - "jitter": inter-packet jitter, estimated as in RFC 3550 (section
+	//     6.4.1) from the differences between the consecutive RTTs, in the
+	//     probe's latency unit. It's exported as a gauge.
+	//   - "out_of_order": replies that arrived after a reply to a later packet
+	//     in the same probe run.
+	//   - "duplicates": duplicate replies.
+	ExportJitterMetrics *bool `protobuf:"varint,16,opt,name=export_jitter_metrics,json=exportJitterMetrics" json:"export_jitter_metrics,omitempty"`
+	// Payload sizes to cycle through, one size per probe run, e.g. to check
+	// the path MTU along with dont_fragment:
+	//   payload_size_sweep: [1272, 1372, 1472, 8972]
+	//   dont_fragment: true
+	// Results are reported per payload size, with an additional label:
+	// payload_size, so that the sizes lost to an MTU black hole show up
+	// directly in the metrics. If set, payload_size is ignored. Not supported in
+	// the timestamp mode.
+	PayloadSizeSweep []int32 `protobuf:"varint,17,rep,name=payload_size_sweep,json=payloadSizeSweep" json:"payload_size_sweep,omitempty"`
 }
 
 // Default values for ProbeConf fields.
@@ -59,6 +151,7 @@ const (
 	Default_ProbeConf_PayloadSize            = int32(56)
 	Default_ProbeConf_UseDatagramSocket      = bool(true)
 	Default_ProbeConf_DisableIntegrityCheck  = bool(false)
+	Default_ProbeConf_Mode                   = ProbeConf_ECHO
 )
 
 func (x *ProbeConf) Reset() {
@@ -135,6 +228,34 @@ func (x *ProbeConf) GetDisableIntegrityCheck() bool {
 	return Default_ProbeConf_DisableIntegrityCheck
 }
 
+func (x *ProbeConf) GetMode() ProbeConf_Mode {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return Default_ProbeConf_Mode
+}
+
+func (x *ProbeConf) GetDontFragment() bool {
+	if x != nil && x.DontFragment != nil {
+		return *x.DontFragment
+	}
+	return false
+}
+
+func (x *ProbeConf) GetExportJitterMetrics() bool {
+	if x != nil && x.ExportJitterMetrics != nil {
+		return *x.ExportJitterMetrics
+	}
+	return false
+}
+
+func (x *ProbeConf) GetPayloadSizeSweep() []int32 {
+	if x != nil {
+		return x.PayloadSizeSweep
+	}
+	return nil
+}
+
 var File_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDesc = []byte{
@@ -143,7 +264,7 @@ var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDe
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x70, 0x69, 0x6e, 0x67,
 	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x12, 0x17, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x70, 0x69, 0x6e, 0x67, 0x22, 0xcb, 0x02, 0x0a,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x70, 0x69, 0x6e, 0x67, 0x22, 0xb6, 0x04, 0x0a,
 	0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x2d, 0x0a, 0x11, 0x70, 0x61,
 	0x63, 0x6b, 0x65, 0x74, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18,
 	0x06, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x32, 0x52, 0x0f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74,
@@ -164,11 +285,25 @@ var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDe
 	0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x69, 0x74, 0x79,
 	0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61,
 	0x6c, 0x73, 0x65, 0x52, 0x15, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x74, 0x65,
-	0x67, 0x72, 0x69, 0x74, 0x79, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x70, 0x69, 0x6e, 0x67, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f,
+	0x67, 0x72, 0x69, 0x74, 0x79, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x41, 0x0a, 0x04, 0x6d, 0x6f,
+	0x64, 0x65, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x70, 0x69,
+	0x6e, 0x67, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x4d, 0x6f, 0x64,
+	0x65, 0x3a, 0x04, 0x45, 0x43, 0x48, 0x4f, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a,
+	0x0d, 0x64, 0x6f, 0x6e, 0x74, 0x5f, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x64, 0x6f, 0x6e, 0x74, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x12, 0x32, 0x0a, 0x15, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6a, 0x69, 0x74,
+	0x74, 0x65, 0x72, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x10, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x13, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x4a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x4d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61,
+	0x64, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x73, 0x77, 0x65, 0x65, 0x70, 0x18, 0x11, 0x20, 0x03,
+	0x28, 0x05, 0x52, 0x10, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x69, 0x7a, 0x65, 0x53,
+	0x77, 0x65, 0x65, 0x70, 0x22, 0x1f, 0x0a, 0x04, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x08, 0x0a, 0x04,
+	0x45, 0x43, 0x48, 0x4f, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x49, 0x4d, 0x45, 0x53, 0x54,
+	0x41, 0x4d, 0x50, 0x10, 0x01, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x73, 0x2f, 0x70, 0x69, 0x6e, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -183,16 +318,19 @@ func file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawD
 	return file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDescData
 }
 
+var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
 var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_goTypes = []interface{}{
-	(*ProbeConf)(nil), // 0: cloudprober.probes.ping.ProbeConf
+	(ProbeConf_Mode)(0), // 0: cloudprober.probes.ping.ProbeConf.Mode
+	(*ProbeConf)(nil),   // 1: cloudprober.probes.ping.ProbeConf
 }
 var file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: cloudprober.probes.ping.ProbeConf.mode:type_name -> cloudprober.probes.ping.ProbeConf.Mode
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_init() }
@@ -219,13 +357,14 @@ func file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_init
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_rawDesc,
-			NumEnums:      0,
+			NumEnums:      1,
 			NumMessages:   1,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_goTypes,
 		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_enumTypes,
 		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto_msgTypes,
 	}.Build()
 	File_github_com_cloudprober_cloudprober_probes_ping_proto_config_proto = out.File
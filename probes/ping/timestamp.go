@@ -0,0 +1,166 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ping
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"golang.org/x/net/ipv4"
+)
+
+// ICMP timestamp messages (RFC 792) carry three 32-bit timestamps after the
+// ICMP header: originate, receive and transmit. Timestamps are in milliseconds
+// since midnight UT.
+const (
+	timestampBodySize   = 12
+	timestampPacketSize = icmpHeaderSize + timestampBodySize
+
+	msPerDay = 24 * 60 * 60 * 1000
+
+	// If a host can't provide a timestamp in milliseconds since midnight UT,
+	// it sets the high-order bit of the timestamp.
+	nonStandardTimestampBit = 1 << 31
+)
+
+func validTimestampReply(typeByte byte) bool {
+	return ipv4.ICMPType(typeByte) == ipv4.ICMPTypeTimestampReply
+}
+
+// msSinceMidnight returns the milliseconds since midnight UT for the given
+// time in nanoseconds since the epoch.
+func msSinceMidnight(unixNano int64) float64 {
+	return float64(unixNano%(msPerDay*int64(time.Millisecond))) / float64(time.Millisecond)
+}
+
+// wrapMs brings a difference of two "milliseconds since midnight" values in
+// the [-12h, 12h) range, to account for the midnight rollover.
+func wrapMs(d float64) float64 {
+	if d >= msPerDay/2 {
+		return d - msPerDay
+	}
+	if d < -msPerDay/2 {
+		return d + msPerDay
+	}
+	return d
+}
+
+// prepareTimestampPacket prepares the timestamp request packet in the given
+// pktbuf bytes buffer.
+func prepareTimestampPacket(pktbuf []byte, runID, seq uint16, unixNano int64) {
+	pktbuf[0] = byte(ipv4.ICMPTypeTimestamp)
+	pktbuf[1] = 0
+	pktbuf[2] = 0
+	pktbuf[3] = 0
+
+	binary.BigEndian.PutUint16(pktbuf[4:6], runID)
+	binary.BigEndian.PutUint16(pktbuf[6:8], seq)
+
+	binary.BigEndian.PutUint32(pktbuf[8:12], uint32(msSinceMidnight(unixNano)))
+	for i := 12; i < timestampPacketSize; i++ {
+		pktbuf[i] = 0
+	}
+
+	csum := checksum(pktbuf)
+	pktbuf[2] ^= byte(csum)
+	pktbuf[3] ^= byte(csum >> 8)
+}
+
+// clockOffset estimates the remote clock's offset in milliseconds, using the
+// timestamp reply's body and the local send and receive times (in nanoseconds
+// since the epoch). Similar to NTP, offset is estimated as
+// ((receive - send) + (transmit - recv)) / 2. It returns false if the reply doesn't carry standard timestamps.
+func clockOffset(body []byte, sendUnixNano, recvUnixNano int64) (float64, bool) {
+	if len(body) < timestampBodySize {
+		return 0, false
+	}
+
+	receive := binary.BigEndian.Uint32(body[4:8])
+	transmit := binary.BigEndian.Uint32(body[8:12])
+	if receive&nonStandardTimestampBit != 0 || transmit&nonStandardTimestampBit != 0 {
+		return 0, false
+	}
+
+	d1 := wrapMs(float64(receive) - msSinceMidnight(sendUnixNano))
+	d2 := wrapMs(float64(transmit) - msSinceMidnight(recvUnixNano))
+	return (d1 + d2) / 2, true
+}
+
+// timestampRun tracks a probe run's results in the timestamp mode.
+type timestampRun struct {
+	reachable map[string]bool    // Targets that replied to the echo request.
+	replied   map[string]bool    // Targets that replied to timestamp requests.
+	offsets   map[string]float64 // Clock offset from the lowest RTT reply.
+	minRTT    map[string]time.Duration
+}
+
+func newTimestampRun() *timestampRun {
+	return &timestampRun{
+		reachable: make(map[string]bool),
+		replied:   make(map[string]bool),
+		offsets:   make(map[string]float64),
+		minRTT:    make(map[string]time.Duration),
+	}
+}
+
+// addReply records a timestamp reply. Similar to NTP, we use the reply with
+// the lowest RTT for the clock offset estimate, as it's the least affected
+// by the network delays' asymmetry.
+func (ts *timestampRun) addReply(pkt rcvdPkt, rtt time.Duration) {
+	ts.replied[pkt.target] = true
+
+	offset, ok := clockOffset(pkt.data, pkt.tsUnix-rtt.Nanoseconds(), pkt.tsUnix)
+	if !ok {
+		return
+	}
+	if minRTT, ok := ts.minRTT[pkt.target]; ok && rtt >= minRTT {
+		return
+	}
+	ts.minRTT[pkt.target] = rtt
+	ts.offsets[pkt.target] = offset
+}
+
+// updateTimestampResults updates targets' results at the end of a probe run.
+// Targets that didn't reply to any timestamp request count as unreachable if
+// they didn't reply to the echo request either, and as timestamp failures
+// otherwise.
+func (p *Probe) updateTimestampResults(ts *timestampRun) {
+	for _, target := range p.targets {
+		if p.target2addr[target.Name] == nil {
+			continue
+		}
+		result := p.results[target.Name]
+
+		// Clock offset is a gauge: it's reset every run, so that we don't keep
+		// exporting a stale offset once the target stops replying.
+		result.clockOffsetMs, result.hasClockOffset = ts.offsets[target.Name]
+
+		if ts.replied[target.Name] {
+			continue
+		}
+		if ts.reachable[target.Name] {
+			result.timestampFailures++
+		} else {
+			result.unreachable++
+		}
+	}
+}
+
+// clockOffsetEM returns a gauge EventMetrics for the clock offset, with the
+// same labels as the given EventMetrics.
+func clockOffsetEM(em *metrics.EventMetrics, offsetMs float64) *metrics.EventMetrics {
+	return gaugeEM(em, "clock_offset_ms", metrics.NewFloat(offsetMs))
+}
@@ -20,20 +20,33 @@ package probes
 import (
 	"context"
 	"fmt"
+	"net"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/probes/dns"
 	"github.com/cloudprober/cloudprober/probes/external"
+	"github.com/cloudprober/cloudprober/probes/ftp"
 	grpcprobe "github.com/cloudprober/cloudprober/probes/grpc"
 	httpprobe "github.com/cloudprober/cloudprober/probes/http"
+	"github.com/cloudprober/cloudprober/probes/ldap"
+	"github.com/cloudprober/cloudprober/probes/mqtt"
+	"github.com/cloudprober/cloudprober/probes/ntp"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/probes/ping"
 	configpb "github.com/cloudprober/cloudprober/probes/proto"
+	"github.com/cloudprober/cloudprober/probes/smtp"
+	"github.com/cloudprober/cloudprober/probes/snmp"
+	"github.com/cloudprober/cloudprober/probes/tcp"
+	tlsprobe "github.com/cloudprober/cloudprober/probes/tls"
+	"github.com/cloudprober/cloudprober/probes/traceroute"
 	"github.com/cloudprober/cloudprober/probes/udp"
 	"github.com/cloudprober/cloudprober/probes/udplistener"
+	"github.com/cloudprober/cloudprober/probes/websocket"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 	"github.com/cloudprober/cloudprober/web/formatutils"
 )
 
@@ -69,6 +82,16 @@ type Probe interface {
 	Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 }
 
+// OnDemandProbe is implemented by the probes that can run once for a target
+// on demand, out of their regular schedule, e.g. for troubleshooting.
+//
+// RunOnDemand runs the probe for the given target and returns the run's
+// latency, or an error describing the failure. On-demand runs are not
+// included in the probe's metrics.
+type OnDemandProbe interface {
+	RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error)
+}
+
 // ProbeInfo encapsulates the probe and associated information.
 type ProbeInfo struct {
 	Probe
@@ -131,7 +154,7 @@ func CreateProbe(p *configpb.ProbeDef, opts *options.Options) (*ProbeInfo, error
 		probeInfo.LatencyDistLB = fmt.Sprintf("%v", opts.LatencyDist.Data().LowerBounds)
 	}
 	if opts.SourceIP != nil {
-		probeInfo.SourceIP = opts.SourceIP.String()
+		probeInfo.SourceIP = (&net.IPAddr{IP: opts.SourceIP, Zone: opts.SourceIPZone}).String()
 	}
 	return probeInfo, nil
 }
@@ -159,6 +182,36 @@ func initProbe(p *configpb.ProbeDef, opts *options.Options) (probe Probe, probeC
 	case configpb.ProbeDef_GRPC:
 		probe = &grpcprobe.Probe{}
 		probeConf = p.GetGrpcProbe()
+	case configpb.ProbeDef_TCP:
+		probe = &tcp.Probe{}
+		probeConf = p.GetTcpProbe()
+	case configpb.ProbeDef_TLS:
+		probe = &tlsprobe.Probe{}
+		probeConf = p.GetTlsProbe()
+	case configpb.ProbeDef_NTP:
+		probe = &ntp.Probe{}
+		probeConf = p.GetNtpProbe()
+	case configpb.ProbeDef_WEBSOCKET:
+		probe = &websocket.Probe{}
+		probeConf = p.GetWebsocketProbe()
+	case configpb.ProbeDef_SMTP:
+		probe = &smtp.Probe{}
+		probeConf = p.GetSmtpProbe()
+	case configpb.ProbeDef_FTP:
+		probe = &ftp.Probe{}
+		probeConf = p.GetFtpProbe()
+	case configpb.ProbeDef_LDAP:
+		probe = &ldap.Probe{}
+		probeConf = p.GetLdapProbe()
+	case configpb.ProbeDef_MQTT:
+		probe = &mqtt.Probe{}
+		probeConf = p.GetMqttProbe()
+	case configpb.ProbeDef_SNMP:
+		probe = &snmp.Probe{}
+		probeConf = p.GetSnmpProbe()
+	case configpb.ProbeDef_TRACEROUTE:
+		probe = &traceroute.Probe{}
+		probeConf = p.GetTracerouteProbe()
 	case configpb.ProbeDef_EXTENSION:
 		probe, probeConf, err = getExtensionProbe(p)
 		if err != nil {
@@ -0,0 +1,201 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probeutils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// IPv6FlowLabelSupported tells if the IPv6 flow label can be configured on
+// this platform.
+const IPv6FlowLabelSupported = true
+
+// Socket options and flow label manager constants from linux/in6.h. These
+// are not defined by the syscall and unix packages.
+const (
+	ipv6FlowInfo     = 11 // IPV6_FLOWINFO
+	ipv6FlowLabelMgr = 32 // IPV6_FLOWLABEL_MGR
+	ipv6FlowInfoSend = 33 // IPV6_FLOWINFO_SEND
+
+	ipv6FlActionGet   = 0 // IPV6_FL_A_GET
+	ipv6FlFlagCreate  = 1 // IPV6_FL_F_CREATE
+	ipv6FlShareProc   = 2 // IPV6_FL_S_PROCESS
+	ipv6FlowLabelMask = 0xfffff
+)
+
+// in6FlowLabelReq mirrors the kernel's struct in6_flowlabel_req.
+type in6FlowLabelReq struct {
+	dst     [16]byte
+	label   uint32 // Network byte order.
+	action  uint8
+	share   uint8
+	flags   uint16
+	expires uint16
+	linger  uint16
+	_       uint32
+}
+
+// htonl converts the flow label to the network byte order, as expected by
+// the kernel in in6_flowlabel_req and sin6_flowinfo.
+func htonl(v uint32) uint32 {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return *(*uint32)(unsafe.Pointer(&b[0]))
+}
+
+// SetIPv6FlowLabel registers the flow label with the IPv6 socket fd. Kernel
+// accepts only the registered flow labels on the outgoing packets. Flow label
+// is shared by all the sockets of the process.
+//
+// Kernel requires a destination address (dst) to register a flow label with,
+// but it doesn't restrict the flow label's use to that destination. If dst is
+// nil, e.g. for the sockets used for multiple targets, IPv6 loopback address
+// is used.
+func SetIPv6FlowLabel(fd int, label uint32, dst net.IP) error {
+	if label&^ipv6FlowLabelMask != 0 {
+		return fmt.Errorf("invalid IPv6 flow label: %d, must fit in 20 bits", label)
+	}
+	if dst == nil {
+		dst = net.IPv6loopback
+	}
+	req := &in6FlowLabelReq{
+		label:  htonl(label),
+		action: ipv6FlActionGet,
+		share:  ipv6FlShareProc,
+		flags:  ipv6FlFlagCreate,
+	}
+	copy(req.dst[:], dst.To16())
+	_, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), unix.IPPROTO_IPV6, ipv6FlowLabelMgr, uintptr(unsafe.Pointer(req)), unsafe.Sizeof(*req), 0)
+	if errno != 0 {
+		return fmt.Errorf("error registering IPv6 flow label %d: %v", label, os.NewSyscallError("setsockopt", errno))
+	}
+	return nil
+}
+
+// IPv6FlowLabelOOB returns the control message that sets the flow label on
+// the datagrams sent through a socket, e.g. using net.UDPConn's WriteMsgUDP.
+// Flow label should be registered with the socket using SetIPv6FlowLabel.
+func IPv6FlowLabelOOB(label uint32) []byte {
+	b := make([]byte, unix.CmsgSpace(4))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = unix.IPPROTO_IPV6
+	h.Type = ipv6FlowInfo
+	h.SetLen(unix.CmsgLen(4))
+	binary.BigEndian.PutUint32(b[unix.CmsgLen(0):], label)
+	return b
+}
+
+// IPv6FlowLabelControl returns a net.Dialer Control function that sets the
+// flow label on the outgoing packets of IPv6 TCP connections. IPv4
+// connections are not affected.
+//
+// TCP sockets pick their flow label at connect time from the destination
+// address, which net.Dialer doesn't let us set. So we connect the socket
+// ourselves, with the flow label in the destination address, and let the
+// dialer finish the connection setup. As sockets must be bound before
+// connecting, local address (laddr), if any, should be provided here instead
+// of in the net.Dialer.
+func IPv6FlowLabelControl(label uint32, laddr *net.TCPAddr) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		if network != "tcp6" {
+			if laddr == nil {
+				return nil
+			}
+			return bindControl(laddr, c)
+		}
+
+		raddr, err := net.ResolveTCPAddr(network, address)
+		if err != nil {
+			return err
+		}
+		rsa, err := rawSockaddr(raddr)
+		if err != nil {
+			return err
+		}
+		rsa.Flowinfo = htonl(label)
+
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			if sockErr = SetIPv6FlowLabel(int(fd), label, raddr.IP); sockErr != nil {
+				return
+			}
+			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FlowInfoSend, 1); err != nil {
+				sockErr = os.NewSyscallError("setsockopt", err)
+				return
+			}
+			if laddr != nil {
+				if sockErr = bind(int(fd), laddr); sockErr != nil {
+					return
+				}
+			}
+			_, _, errno := unix.Syscall(unix.SYS_CONNECT, fd, uintptr(unsafe.Pointer(rsa)), unsafe.Sizeof(*rsa))
+			// Socket is non-blocking, dialer waits for the connection to
+			// complete.
+			if errno != 0 && errno != unix.EINPROGRESS {
+				sockErr = os.NewSyscallError("connect", errno)
+			}
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
+
+func rawSockaddr(addr *net.TCPAddr) (*unix.RawSockaddrInet6, error) {
+	rsa := &unix.RawSockaddrInet6{Family: unix.AF_INET6}
+	binary.BigEndian.PutUint16((*[2]byte)(unsafe.Pointer(&rsa.Port))[:], uint16(addr.Port))
+	copy(rsa.Addr[:], addr.IP.To16())
+	if addr.Zone != "" {
+		ifi, err := net.InterfaceByName(addr.Zone)
+		if err != nil {
+			return nil, err
+		}
+		rsa.Scope_id = uint32(ifi.Index)
+	}
+	return rsa, nil
+}
+
+func bind(fd int, laddr *net.TCPAddr) error {
+	var sa unix.Sockaddr
+	if ip4 := laddr.IP.To4(); ip4 != nil {
+		sa4 := &unix.SockaddrInet4{Port: laddr.Port}
+		copy(sa4.Addr[:], ip4)
+		sa = sa4
+	} else {
+		rsa, err := rawSockaddr(laddr)
+		if err != nil {
+			return err
+		}
+		sa = &unix.SockaddrInet6{Port: laddr.Port, ZoneId: rsa.Scope_id, Addr: rsa.Addr}
+	}
+	return os.NewSyscallError("bind", unix.Bind(fd, sa))
+}
+
+func bindControl(laddr *net.TCPAddr, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = bind(int(fd), laddr)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probeutils
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const testFlowLabel = 0x12345
+
+// socketFlowLabel reads back the flow label of the connected socket fd.
+func socketFlowLabel(t *testing.T, fd int) uint32 {
+	t.Helper()
+	req := &in6FlowLabelReq{action: ipv6FlActionGet}
+	size := unsafe.Sizeof(*req)
+	_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), unix.IPPROTO_IPV6, ipv6FlowLabelMgr, uintptr(unsafe.Pointer(req)), uintptr(unsafe.Pointer(&size)), 0)
+	if errno != 0 {
+		t.Fatalf("Error reading IPV6_FLOWLABEL_MGR: %v", errno)
+	}
+	return htonl(req.label)
+}
+
+func TestIPv6FlowLabelControl(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	defer ln.Close()
+
+	dialer := &net.Dialer{Control: IPv6FlowLabelControl(testFlowLabel, nil)}
+	conn, err := dialer.Dial("tcp6", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting with flow label: %v", err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var label uint32
+	rawConn.Control(func(fd uintptr) {
+		label = socketFlowLabel(t, int(fd))
+	})
+	if label != testFlowLabel {
+		t.Errorf("Got flow label: %#x, want: %#x", label, testFlowLabel)
+	}
+
+	// IPv4 connections are not affected.
+	ln4, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting IPv4 listener: %v", err)
+	}
+	defer ln4.Close()
+	conn4, err := dialer.Dial("tcp4", ln4.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting over IPv4: %v", err)
+	}
+	conn4.Close()
+}
+
+func TestIPv6FlowLabelOOB(t *testing.T) {
+	rcvr, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	defer rcvr.Close()
+
+	// Ask the kernel to report the received packets' flow info.
+	rc, err := rcvr.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FlowInfo, 1)
+	})
+	if err != nil {
+		t.Fatalf("Error enabling IPV6_FLOWINFO: %v", err)
+	}
+
+	sender, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	sc, err := sender.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Control(func(fd uintptr) {
+		err = SetIPv6FlowLabel(int(fd), testFlowLabel, net.ParseIP("2001:db8::1"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := sender.WriteMsgUDP([]byte("test"), IPv6FlowLabelOOB(testFlowLabel), rcvr.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatalf("Error sending packet with flow label: %v", err)
+	}
+
+	buf, oob := make([]byte, 16), make([]byte, 64)
+	_, oobn, _, _, err := rcvr.ReadMsgUDP(buf, oob)
+	if err != nil {
+		t.Fatalf("Error receiving packet: %v", err)
+	}
+	cmsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatalf("Error parsing control messages: %v", err)
+	}
+	for _, cmsg := range cmsgs {
+		if cmsg.Header.Level == unix.IPPROTO_IPV6 && cmsg.Header.Type == ipv6FlowInfo {
+			if got := binary.BigEndian.Uint32(cmsg.Data) & ipv6FlowLabelMask; got != testFlowLabel {
+				t.Errorf("Got flow label: %#x, want: %#x", got, testFlowLabel)
+			}
+			return
+		}
+	}
+	t.Errorf("Didn't get flow info in the received packet's control messages: %v", cmsgs)
+}
+
+func TestSetIPv6FlowLabelInvalid(t *testing.T) {
+	if err := SetIPv6FlowLabel(-1, 1<<20, nil); err == nil {
+		t.Errorf("Expected error for the flow label outside 20 bits")
+	}
+}
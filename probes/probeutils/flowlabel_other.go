@@ -0,0 +1,46 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package probeutils
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// IPv6FlowLabelSupported tells if the IPv6 flow label can be configured on
+// this platform.
+const IPv6FlowLabelSupported = false
+
+// SetIPv6FlowLabel is not supported on this platform.
+func SetIPv6FlowLabel(fd int, label uint32, dst net.IP) error {
+	return errors.New("setting IPv6 flow label is supported only on Linux")
+}
+
+// IPv6FlowLabelOOB returns nil on this platform.
+func IPv6FlowLabelOOB(label uint32) []byte {
+	return nil
+}
+
+// IPv6FlowLabelControl returns a net.Dialer Control function that doesn't do
+// anything, as IPv6 flow label can't be configured on this platform.
+func IPv6FlowLabelControl(label uint32, laddr *net.TCPAddr) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return nil
+	}
+}
@@ -20,7 +20,13 @@ package probeutils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 )
 
 // PatternPayload builds a payload that can be verified using VerifyPayloadPattern.
@@ -54,3 +60,60 @@ func VerifyPayloadPattern(payload, pattern []byte) error {
 
 	return nil
 }
+
+// RunForTargets runs probeF for each of the given targets in a separate
+// goroutine and waits for all of them to finish. If maxConcurrent is greater
+// than 0, at most maxConcurrent probeF calls are in flight at any time, and
+// remaining targets wait for a free slot. Targets that don't get a slot before
+// ctx is done are not probed; skippedF is called for them instead.
+func RunForTargets(ctx context.Context, targets []endpoint.Endpoint, maxConcurrent int, probeF, skippedF func(endpoint.Endpoint)) {
+	var sem chan struct{}
+	if maxConcurrent > 0 {
+		sem = make(chan struct{}, maxConcurrent)
+	}
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		if sem != nil {
+			if ctx.Err() != nil {
+				skippedF(target)
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				skippedF(target)
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(target endpoint.Endpoint) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			probeF(target)
+		}(target)
+	}
+	wg.Wait()
+}
+
+// TargetPort returns the port to use for the given target. If portLabel is
+// not empty and target has a valid port in that label, that port is used.
+// Otherwise, port, if it's not 0, is used, and finally the target's own port.
+// An invalid port in the label is logged and ignored.
+func TargetPort(target endpoint.Endpoint, portLabel string, port int, l *logger.Logger) int {
+	if portLabel != "" && target.Labels[portLabel] != "" {
+		labelPort, err := strconv.Atoi(target.Labels[portLabel])
+		if err == nil && labelPort > 0 && labelPort <= 65535 {
+			return labelPort
+		}
+		l.Warningf("Target %s has invalid port in label %s: %s, ignoring it", target.Name, portLabel, target.Labels[portLabel])
+	}
+
+	if port != 0 {
+		return port
+	}
+	return target.Port
+}
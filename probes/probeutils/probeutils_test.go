@@ -15,8 +15,14 @@
 package probeutils
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
 )
 
 func TestPayloadVerification(t *testing.T) {
@@ -61,3 +67,106 @@ func BenchmarkVerifyPayloadPattern56(b *testing.B)   { benchmarkVerifyPayloadPat
 func BenchmarkVerifyPayloadPattern256(b *testing.B)  { benchmarkVerifyPayloadPattern(256, b) }
 func BenchmarkVerifyPayloadPattern1999(b *testing.B) { benchmarkVerifyPayloadPattern(1999, b) }
 func BenchmarkVerifyPayloadPattern9999(b *testing.B) { benchmarkVerifyPayloadPattern(9999, b) }
+
+func TestRunForTargets(t *testing.T) {
+	var eps []endpoint.Endpoint
+	for i := 0; i < 6; i++ {
+		eps = append(eps, endpoint.Endpoint{Name: fmt.Sprintf("target%d", i)})
+	}
+
+	for _, test := range []struct {
+		desc          string
+		maxConcurrent int
+		deadline      time.Duration
+		wantMaxInFly  int
+		wantSkipped   int
+	}{
+		{
+			desc:         "no_limit",
+			deadline:     time.Second,
+			wantMaxInFly: 6,
+		},
+		{
+			desc:          "limit_no_skips",
+			maxConcurrent: 2,
+			deadline:      time.Second,
+			wantMaxInFly:  2,
+		},
+		{
+			// Each probe takes 100ms, with a limit of 2 and a deadline of 150ms,
+			// only 4 targets get a slot.
+			desc:          "limit_with_skips",
+			maxConcurrent: 2,
+			deadline:      150 * time.Millisecond,
+			wantMaxInFly:  2,
+			wantSkipped:   2,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), test.deadline)
+			defer cancel()
+
+			var mu sync.Mutex
+			var inFlight, maxInFlight, probed, skipped int
+
+			probeF := func(ep endpoint.Endpoint) {
+				mu.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				mu.Unlock()
+
+				time.Sleep(100 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				probed++
+				mu.Unlock()
+			}
+			skippedF := func(ep endpoint.Endpoint) {
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+			}
+
+			RunForTargets(ctx, eps, test.maxConcurrent, probeF, skippedF)
+
+			if maxInFlight != test.wantMaxInFly {
+				t.Errorf("Max in-flight probes=%d, want=%d", maxInFlight, test.wantMaxInFly)
+			}
+			if skipped != test.wantSkipped {
+				t.Errorf("Skipped targets=%d, want=%d", skipped, test.wantSkipped)
+			}
+			if probed+skipped != len(eps) {
+				t.Errorf("Probed (%d) + skipped (%d) targets != %d", probed, skipped, len(eps))
+			}
+		})
+	}
+}
+
+func TestTargetPort(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		portLabel  string
+		port       int
+		targetPort int
+		labels     map[string]string
+		want       int
+	}{
+		{desc: "configured_port", port: 80, targetPort: 8080, want: 80},
+		{desc: "target_port", targetPort: 8080, want: 8080},
+		{desc: "label_not_configured", port: 80, labels: map[string]string{"port": "9313"}, want: 80},
+		{desc: "port_from_label", portLabel: "port", port: 80, targetPort: 8080, labels: map[string]string{"port": "9313"}, want: 9313},
+		{desc: "missing_label", portLabel: "port", port: 80, want: 80},
+		{desc: "invalid_label", portLabel: "port", targetPort: 8080, labels: map[string]string{"port": "http"}, want: 8080},
+		{desc: "out_of_range_label", portLabel: "port", port: 80, labels: map[string]string{"port": "70000"}, want: 80},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			target := endpoint.Endpoint{Name: "test-target", Port: test.targetPort, Labels: test.labels}
+			if got := TargetPort(target, test.portLabel, test.port, nil); got != test.want {
+				t.Errorf("TargetPort()=%d, want=%d", got, test.want)
+			}
+		})
+	}
+}
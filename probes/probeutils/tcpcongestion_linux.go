@@ -0,0 +1,45 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probeutils
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// TCPCongestionSupported tells if the TCP congestion control algorithm can be
+// configured on this platform.
+const TCPCongestionSupported = true
+
+// TCPCongestionControl returns a net.Dialer Control function that sets the
+// TCP congestion control algorithm (TCP_CONGESTION socket option) for the
+// connections, e.g. "bbr" or "cubic". Connections fail if the algorithm is
+// not available, or not allowed, on the host.
+func TCPCongestionControl(algo string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, algo)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("error setting TCP congestion control algorithm to %s: %v", algo, sockErr)
+		}
+		return nil
+	}
+}
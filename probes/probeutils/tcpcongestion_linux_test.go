@@ -0,0 +1,61 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probeutils
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestTCPCongestionControl(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	defer ln.Close()
+
+	// reno is always built into the kernel.
+	dialer := &net.Dialer{Control: TCPCongestionControl("reno")}
+	conn, err := dialer.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting with reno congestion control: %v", err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var algo string
+	var sockErr error
+	rawConn.Control(func(fd uintptr) {
+		algo, sockErr = unix.GetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION)
+		algo = strings.TrimRight(algo, "\x00 ")
+	})
+	if sockErr != nil {
+		t.Fatalf("Error reading TCP_CONGESTION: %v", sockErr)
+	}
+	if algo != "reno" {
+		t.Errorf("Got congestion control algorithm: %s, want: reno", algo)
+	}
+
+	dialer = &net.Dialer{Control: TCPCongestionControl("no-such-algo")}
+	if _, err := dialer.Dial("tcp", ln.Addr().String()); err == nil || !strings.Contains(err.Error(), "no-such-algo") {
+		t.Errorf("Got error: %v, want error for unsupported algorithm", err)
+	}
+}
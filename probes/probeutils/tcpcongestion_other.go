@@ -0,0 +1,33 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package probeutils
+
+import "syscall"
+
+// TCPCongestionSupported tells if the TCP congestion control algorithm can be
+// configured on this platform.
+const TCPCongestionSupported = false
+
+// TCPCongestionControl returns a net.Dialer Control function that doesn't do
+// anything, as TCP congestion control algorithm cannot be configured on this
+// platform.
+func TCPCongestionControl(algo string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return nil
+	}
+}
@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.26.0
-// 	protoc        v3.19.1
+// 	protoc        v3.3.0
 // source: github.com/cloudprober/cloudprober/probes/proto/config.proto
 
 package proto
@@ -10,11 +10,21 @@ import (
 	proto1 "github.com/cloudprober/cloudprober/metrics/proto"
 	proto5 "github.com/cloudprober/cloudprober/probes/dns/proto"
 	proto6 "github.com/cloudprober/cloudprober/probes/external/proto"
+	proto15 "github.com/cloudprober/cloudprober/probes/ftp/proto"
 	proto9 "github.com/cloudprober/cloudprober/probes/grpc/proto"
 	proto4 "github.com/cloudprober/cloudprober/probes/http/proto"
+	proto16 "github.com/cloudprober/cloudprober/probes/ldap/proto"
+	proto17 "github.com/cloudprober/cloudprober/probes/mqtt/proto"
+	proto12 "github.com/cloudprober/cloudprober/probes/ntp/proto"
 	proto3 "github.com/cloudprober/cloudprober/probes/ping/proto"
+	proto14 "github.com/cloudprober/cloudprober/probes/smtp/proto"
+	proto18 "github.com/cloudprober/cloudprober/probes/snmp/proto"
+	proto10 "github.com/cloudprober/cloudprober/probes/tcp/proto"
+	proto11 "github.com/cloudprober/cloudprober/probes/tls/proto"
+	proto19 "github.com/cloudprober/cloudprober/probes/traceroute/proto"
 	proto7 "github.com/cloudprober/cloudprober/probes/udp/proto"
 	proto8 "github.com/cloudprober/cloudprober/probes/udplistener/proto"
+	proto13 "github.com/cloudprober/cloudprober/probes/websocket/proto"
 	proto "github.com/cloudprober/cloudprober/targets/proto"
 	proto2 "github.com/cloudprober/cloudprober/validators/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
@@ -41,6 +51,16 @@ const (
 	ProbeDef_UDP          ProbeDef_Type = 4
 	ProbeDef_UDP_LISTENER ProbeDef_Type = 5
 	ProbeDef_GRPC         ProbeDef_Type = 6
+	ProbeDef_TCP          ProbeDef_Type = 7
+	ProbeDef_TLS          ProbeDef_Type = 8
+	ProbeDef_NTP          ProbeDef_Type = 9
+	ProbeDef_WEBSOCKET    ProbeDef_Type = 10
+	ProbeDef_SMTP         ProbeDef_Type = 11
+	ProbeDef_FTP          ProbeDef_Type = 12
+	ProbeDef_LDAP         ProbeDef_Type = 13
+	ProbeDef_MQTT         ProbeDef_Type = 14
+	ProbeDef_SNMP         ProbeDef_Type = 15
+	ProbeDef_TRACEROUTE   ProbeDef_Type = 16
 	// One of the extension probe types. See "extensions" below for more
 	// details.
 	ProbeDef_EXTENSION ProbeDef_Type = 98
@@ -61,6 +81,16 @@ var (
 		4:  "UDP",
 		5:  "UDP_LISTENER",
 		6:  "GRPC",
+		7:  "TCP",
+		8:  "TLS",
+		9:  "NTP",
+		10: "WEBSOCKET",
+		11: "SMTP",
+		12: "FTP",
+		13: "LDAP",
+		14: "MQTT",
+		15: "SNMP",
+		16: "TRACEROUTE",
 		98: "EXTENSION",
 		99: "USER_DEFINED",
 	}
@@ -72,6 +102,16 @@ var (
 		"UDP":          4,
 		"UDP_LISTENER": 5,
 		"GRPC":         6,
+		"TCP":          7,
+		"TLS":          8,
+		"NTP":          9,
+		"WEBSOCKET":    10,
+		"SMTP":         11,
+		"FTP":          12,
+		"LDAP":         13,
+		"MQTT":         14,
+		"SNMP":         15,
+		"TRACEROUTE":   16,
 		"EXTENSION":    98,
 		"USER_DEFINED": 99,
 	}
@@ -114,6 +154,74 @@ func (ProbeDef_Type) EnumDescriptor() ([]byte, []int) {
 	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 0}
 }
 
+// Timestamp to export the probe's metrics with. By default, metrics are
+// timestamped at the time of their export. To correlate metrics with the
+// probe runs more accurately, they can be timestamped with the start or the
+// end of the last probe cycle instead. Surfacers that export timestamps,
+// e.g. Stackdriver, or Prometheus with include_timestamp, use this
+// timestamp.
+//
+// NOTE: Only DNS, FTP, gRPC, HTTP, LDAP, MQTT, NTP, ping, SMTP, SNMP, TCP,
+// TLS, traceroute and WebSocket probes support this option currently.
+type ProbeDef_TimestampSource int32
+
+const (
+	ProbeDef_EMIT        ProbeDef_TimestampSource = 0
+	ProbeDef_CYCLE_START ProbeDef_TimestampSource = 1
+	ProbeDef_CYCLE_END   ProbeDef_TimestampSource = 2
+)
+
+// Enum value maps for ProbeDef_TimestampSource.
+var (
+	ProbeDef_TimestampSource_name = map[int32]string{
+		0: "EMIT",
+		1: "CYCLE_START",
+		2: "CYCLE_END",
+	}
+	ProbeDef_TimestampSource_value = map[string]int32{
+		"EMIT":        0,
+		"CYCLE_START": 1,
+		"CYCLE_END":   2,
+	}
+)
+
+func (x ProbeDef_TimestampSource) Enum() *ProbeDef_TimestampSource {
+	p := new(ProbeDef_TimestampSource)
+	*p = x
+	return p
+}
+
+func (x ProbeDef_TimestampSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeDef_TimestampSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeDef_TimestampSource) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeDef_TimestampSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeDef_TimestampSource) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeDef_TimestampSource(num)
+	return nil
+}
+
+// Deprecated: Use ProbeDef_TimestampSource.Descriptor instead.
+func (ProbeDef_TimestampSource) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
 // IP version to use for networking probes. If specified, this is used at the
 // time of resolving a target, picking the correct IP for the source IP if
 // source_interface option is provided, and to craft the packet correctly
@@ -124,8 +232,8 @@ func (ProbeDef_Type) EnumDescriptor() ([]byte, []int) {
 // is a conflict between the two.
 //
 // If left unspecified and both addresses are available in resolve call or on
-// source interface, IPv4 is preferred.
-// Future work: provide an option to prefer IPv4 and IPv6 explicitly.
+// source interface, IPv4 is preferred. To prefer one IP version but fall back
+// to the other, use ip_version_mode instead.
 type ProbeDef_IPVersion int32
 
 const (
@@ -159,11 +267,11 @@ func (x ProbeDef_IPVersion) String() string {
 }
 
 func (ProbeDef_IPVersion) Descriptor() protoreflect.EnumDescriptor {
-	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[1].Descriptor()
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[2].Descriptor()
 }
 
 func (ProbeDef_IPVersion) Type() protoreflect.EnumType {
-	return &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[1]
+	return &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[2]
 }
 
 func (x ProbeDef_IPVersion) Number() protoreflect.EnumNumber {
@@ -182,7 +290,144 @@ func (x *ProbeDef_IPVersion) UnmarshalJSON(b []byte) error {
 
 // Deprecated: Use ProbeDef_IPVersion.Descriptor instead.
 func (ProbeDef_IPVersion) EnumDescriptor() ([]byte, []int) {
-	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 1}
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 2}
+}
+
+// IP version mode. IPV4_ONLY and IPV6_ONLY behave exactly like ip_version.
+// PREFER_IPV6 and PREFER_IPV4 try the preferred IP version first and fall
+// back to the other one if target resolution or connection fails. Probes
+// that resolve targets or connect per request (HTTP, DNS, EXTERNAL, and the
+// connection based probes: TCP, TLS, NTP, SMTP, FTP, LDAP, MQTT and
+// WEBSOCKET) honor the fallback; PING and UDP probes, which use a single
+// socket for all targets, use the preferred IP version only. HTTP and the
+// connection based probes also export the IP version used for connections,
+// as the "ip_version" key of the "ip_version_used" map metric.
+//
+// Only one of ip_version and ip_version_mode can be configured.
+type ProbeDef_IPVersionMode int32
+
+const (
+	ProbeDef_IP_VERSION_MODE_UNSPECIFIED ProbeDef_IPVersionMode = 0
+	ProbeDef_IPV4_ONLY                   ProbeDef_IPVersionMode = 1
+	ProbeDef_IPV6_ONLY                   ProbeDef_IPVersionMode = 2
+	ProbeDef_PREFER_IPV6                 ProbeDef_IPVersionMode = 3
+	ProbeDef_PREFER_IPV4                 ProbeDef_IPVersionMode = 4
+)
+
+// Enum value maps for ProbeDef_IPVersionMode.
+var (
+	ProbeDef_IPVersionMode_name = map[int32]string{
+		0: "IP_VERSION_MODE_UNSPECIFIED",
+		1: "IPV4_ONLY",
+		2: "IPV6_ONLY",
+		3: "PREFER_IPV6",
+		4: "PREFER_IPV4",
+	}
+	ProbeDef_IPVersionMode_value = map[string]int32{
+		"IP_VERSION_MODE_UNSPECIFIED": 0,
+		"IPV4_ONLY":                   1,
+		"IPV6_ONLY":                   2,
+		"PREFER_IPV6":                 3,
+		"PREFER_IPV4":                 4,
+	}
+)
+
+func (x ProbeDef_IPVersionMode) Enum() *ProbeDef_IPVersionMode {
+	p := new(ProbeDef_IPVersionMode)
+	*p = x
+	return p
+}
+
+func (x ProbeDef_IPVersionMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeDef_IPVersionMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[3].Descriptor()
+}
+
+func (ProbeDef_IPVersionMode) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[3]
+}
+
+func (x ProbeDef_IPVersionMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeDef_IPVersionMode) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeDef_IPVersionMode(num)
+	return nil
+}
+
+// Deprecated: Use ProbeDef_IPVersionMode.Descriptor instead.
+func (ProbeDef_IPVersionMode) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 3}
+}
+
+type ProbeDef_Schedule_Mode int32
+
+const (
+	// Probe cycles run at the configured interval, when the current time
+	// matches the cron expression.
+	ProbeDef_Schedule_GATE ProbeDef_Schedule_Mode = 0
+	// Probe cycles run once at the start of every minute matching the cron
+	// expression, instead of at the configured interval. Interval still
+	// bounds the timeout and the duration of each cycle.
+	ProbeDef_Schedule_TRIGGER ProbeDef_Schedule_Mode = 1
+)
+
+// Enum value maps for ProbeDef_Schedule_Mode.
+var (
+	ProbeDef_Schedule_Mode_name = map[int32]string{
+		0: "GATE",
+		1: "TRIGGER",
+	}
+	ProbeDef_Schedule_Mode_value = map[string]int32{
+		"GATE":    0,
+		"TRIGGER": 1,
+	}
+)
+
+func (x ProbeDef_Schedule_Mode) Enum() *ProbeDef_Schedule_Mode {
+	p := new(ProbeDef_Schedule_Mode)
+	*p = x
+	return p
+}
+
+func (x ProbeDef_Schedule_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeDef_Schedule_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[4].Descriptor()
+}
+
+func (ProbeDef_Schedule_Mode) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes[4]
+}
+
+func (x ProbeDef_Schedule_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeDef_Schedule_Mode) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeDef_Schedule_Mode(num)
+	return nil
+}
+
+// Deprecated: Use ProbeDef_Schedule_Mode.Descriptor instead.
+func (ProbeDef_Schedule_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 0, 0}
 }
 
 type ProbeDef struct {
@@ -198,20 +443,41 @@ type ProbeDef struct {
 	RunOn *string `protobuf:"bytes,3,opt,name=run_on,json=runOn" json:"run_on,omitempty"`
 	// Interval between two probe runs in milliseconds.
 	// Only one of "interval" and "inteval_msec" should be defined.
-	// Default interval is 2s
+	// Default interval is 2s.
 	IntervalMsec *int32 `protobuf:"varint,4,opt,name=interval_msec,json=intervalMsec" json:"interval_msec,omitempty"`
 	// Interval between two probe runs in string format, e.g. 10s.
 	// Only one of "interval" and "inteval_msec" should be defined.
-	// Default interval is 2s
+	// Default interval is 2s.
 	Interval *string `protobuf:"bytes,16,opt,name=interval" json:"interval,omitempty"`
 	// Timeout for each probe in milliseconds
 	// Only one of "timeout" and "timeout_msec" should be defined.
-	// Default timeout is 1s
+	// Default timeout is 1s.
 	TimeoutMsec *int32 `protobuf:"varint,5,opt,name=timeout_msec,json=timeoutMsec" json:"timeout_msec,omitempty"`
 	// Timeout for each probe in string format, e.g. 10s.
 	// Only one of "timeout" and "timeout_msec" should be defined.
-	// Default timeout is 1s
+	// Default timeout is 1s.
+	//
+	// HTTP and TCP probes let targets override the timeout through the
+	// "timeout_ms" label, e.g. for known-slow targets. Target timeout is capped
+	// at the probe interval. Invalid values are ignored.
 	Timeout *string `protobuf:"bytes,17,opt,name=timeout" json:"timeout,omitempty"`
+	// Connection setup timeout in milliseconds, for the HTTP and TCP probes.
+	// If set, connection establishment (TCP handshake) is bounded by this
+	// timeout, while the overall probe timeout (see above) still governs the
+	// entire request. Connections timing out are counted in the
+	// "connect_timeouts" metric, instead of the "timeouts" metric, making it
+	// possible to alert on slow handshakes separately from slow responses.
+	// It should not be larger than the probe timeout.
+	ConnectTimeoutMsec *int32 `protobuf:"varint,34,opt,name=connect_timeout_msec,json=connectTimeoutMsec" json:"connect_timeout_msec,omitempty"`
+	// Target name resolution timeout in milliseconds, for the probes that
+	// resolve targets before connecting to them (e.g. with resolve_first). If
+	// set, resolution taking longer than this fails right away, before any
+	// connection attempt, instead of eating into the connection and request
+	// budget. TCP probes count these failures in the "dns_timeouts" metric, and
+	// TLS and NTP probes in the "failures" metric with reason "dns_timeout". It
+	// should not be larger than the probe timeout.
+	DnsResolveTimeoutMsec *int32             `protobuf:"varint,40,opt,name=dns_resolve_timeout_msec,json=dnsResolveTimeoutMsec" json:"dns_resolve_timeout_msec,omitempty"`
+	Schedule              *ProbeDef_Schedule `protobuf:"bytes,35,opt,name=schedule" json:"schedule,omitempty"`
 	// Targets for the probe
 	Targets *proto.TargetsDef `protobuf:"bytes,6,req,name=targets" json:"targets,omitempty"`
 	// Latency distribution. If specified, latency is stored as a distribution.
@@ -234,17 +500,48 @@ type ProbeDef struct {
 	//     ...
 	//   }
 	LatencyMetricName *string `protobuf:"bytes,15,opt,name=latency_metric_name,json=latencyMetricName,def=latency" json:"latency_metric_name,omitempty"`
+	// Export the raw latency of each probe result, in addition to the aggregated
+	// latency metric. If enabled, every successful probe result is exported as a
+	// GAUGE EventMetrics carrying the result's latency (metric name:
+	// <latency_metric_name>_raw) and the target labels, which is useful for
+	// offline percentile analysis.
+	//
+	// WARNING: This exports one EventMetrics per target per probe run,
+	// regardless of the stats export interval, and can increase metrics volume
+	// substantially. Use it with care, e.g. with a reasonably large probe
+	// interval.
+	//
+	// For HTTP probes, every request (or synthetic transaction) is a result, and
+	// for ping probes, every echo reply is.
+	//
+	// NOTE: Only DNS, FTP, HTTP, LDAP, MQTT, NTP, ping, SMTP, SNMP, TCP, TLS,
+	// traceroute and WebSocket probes support this option currently.
+	ExportRawLatency *bool                     `protobuf:"varint,36,opt,name=export_raw_latency,json=exportRawLatency" json:"export_raw_latency,omitempty"`
+	Slo              *ProbeDef_SLO             `protobuf:"bytes,39,opt,name=slo" json:"slo,omitempty"`
+	TimestampSource  *ProbeDef_TimestampSource `protobuf:"varint,41,opt,name=timestamp_source,json=timestampSource,enum=cloudprober.probes.ProbeDef_TimestampSource,def=0" json:"timestamp_source,omitempty"`
 	// Validators are in experimental phase right now and can change at any time.
-	// NOTE: Only PING, HTTP and DNS probes support validators.
+	// NOTE: Only PING, HTTP, DNS, WEBSOCKET and GRPC (GENERIC mode) probes
+	// support validators.
 	Validator []*proto2.Validator `protobuf:"bytes,9,rep,name=validator" json:"validator,omitempty"`
 	// Set the source IP to send packets from, either by providing an IP address
 	// directly, or a network interface.
+	// For IPv6 link-local addresses, zone is retained (derived from the network
+	// interface or specified explicitly, e.g. "fe80::1%eth0").
 	//
 	// Types that are assignable to SourceIpConfig:
 	//	*ProbeDef_SourceIp
 	//	*ProbeDef_SourceInterface
 	SourceIpConfig isProbeDef_SourceIpConfig `protobuf_oneof:"source_ip_config"`
 	IpVersion      *ProbeDef_IPVersion       `protobuf:"varint,12,opt,name=ip_version,json=ipVersion,enum=cloudprober.probes.ProbeDef_IPVersion" json:"ip_version,omitempty"`
+	IpVersionMode  *ProbeDef_IPVersionMode   `protobuf:"varint,33,opt,name=ip_version_mode,json=ipVersionMode,enum=cloudprober.probes.ProbeDef_IPVersionMode" json:"ip_version_mode,omitempty"`
+	// IPv6 flow label (20 bits) to set on the outgoing IPv6 packets, e.g. for
+	// testing IPv6 traffic engineering. It's ignored for IPv4 targets. Flow
+	// label is registered with the kernel using the IPV6_FLOWLABEL_MGR socket
+	// option, and it's shared by all the probes using the same flow label.
+	//
+	// NOTE: Only PING (raw sockets, i.e. use_datagram_socket: false), UDP and
+	// TCP probes support this option currently, and only on Linux.
+	Ipv6FlowLabel *int32 `protobuf:"varint,37,opt,name=ipv6_flow_label,json=ipv6FlowLabel" json:"ipv6_flow_label,omitempty"`
 	// How often to export stats. Probes usually run at a higher frequency (e.g.
 	// every second); stats from individual probes are aggregated within
 	// cloudprober until exported. In most cases, users don't need to change the
@@ -269,6 +566,38 @@ type ProbeDef struct {
 	//     value: "@target.label.app@"
 	//   }
 	AdditionalLabel []*AdditionalLabel `protobuf:"bytes,14,rep,name=additional_label,json=additionalLabel" json:"additional_label,omitempty"`
+	// Maximum number of per-target probes that can be in flight at the same
+	// time. This is useful for probes with large target sets, where probing all
+	// targets in parallel may exhaust local resources. Targets beyond the limit
+	// wait for a free slot; if a target doesn't get a slot before the probe
+	// cycle's deadline, it's skipped for that cycle and counted in the
+	// "skipped_targets" counter. Default is 0, i.e. no limit.
+	//
+	// NOTE: PING, UDP and UDP_LISTENER probes, and EXTERNAL probes in the SERVER
+	// mode don't support this option, and fail to initialize if it's set. For
+	// the EXTERNAL SERVER mode, use max_inflight_requests instead.
+	MaxConcurrentProbes *int32 `protobuf:"varint,18,opt,name=max_concurrent_probes,json=maxConcurrentProbes" json:"max_concurrent_probes,omitempty"`
+	// Delay before the first probe cycle, e.g. to give targets time to become
+	// ready after cloudprober starts. Note that this is in addition to the
+	// probe start jitter (see disable_jitter in the cloudprober config).
+	InitialDelayMsec *int32 `protobuf:"varint,19,opt,name=initial_delay_msec,json=initialDelayMsec" json:"initial_delay_msec,omitempty"`
+	// Warm-up window, starting after the initial delay. Results from the probe
+	// cycles that run during the warm-up window are exported with an additional
+	// label: warmup="true", and are not counted in the regular (non-warm-up)
+	// metrics, i.e. regular counters start from zero after the warm-up.
+	WarmupMsec      *int32                    `protobuf:"varint,30,opt,name=warmup_msec,json=warmupMsec" json:"warmup_msec,omitempty"`
+	FailureDebounce *ProbeDef_FailureDebounce `protobuf:"bytes,31,opt,name=failure_debounce,json=failureDebounce" json:"failure_debounce,omitempty"`
+	// Alerting through a webhook. See AlertingConf for details.
+	Alerting *AlertingConf `protobuf:"bytes,32,opt,name=alerting" json:"alerting,omitempty"`
+	// Fraction of the probe's results to export, for the high-frequency probes
+	// (e.g. 0.1 to export 1 in 10 results, on average). Results to export are
+	// picked at random. Probe still runs every cycle, and failure debounce and
+	// alerting still see all the results. Since probes export cumulative
+	// counters, sampling reduces their resolution but not their accuracy, so
+	// they are not scaled. GAUGE metrics can't be corrected this way, so they
+	// are marked as sampled with a "sample_rate" label.
+	// Valid values: (0, 1]. Default is 1, i.e. no sampling.
+	ResultSampleRate *float32 `protobuf:"fixed32,38,opt,name=result_sample_rate,json=resultSampleRate,def=1" json:"result_sample_rate,omitempty"`
 	// Types that are assignable to Probe:
 	//	*ProbeDef_PingProbe
 	//	*ProbeDef_HttpProbe
@@ -277,6 +606,16 @@ type ProbeDef struct {
 	//	*ProbeDef_UdpProbe
 	//	*ProbeDef_UdpListenerProbe
 	//	*ProbeDef_GrpcProbe
+	//	*ProbeDef_TcpProbe
+	//	*ProbeDef_TlsProbe
+	//	*ProbeDef_NtpProbe
+	//	*ProbeDef_WebsocketProbe
+	//	*ProbeDef_SmtpProbe
+	//	*ProbeDef_FtpProbe
+	//	*ProbeDef_LdapProbe
+	//	*ProbeDef_MqttProbe
+	//	*ProbeDef_SnmpProbe
+	//	*ProbeDef_TracerouteProbe
 	//	*ProbeDef_UserDefinedProbe
 	Probe        isProbeDef_Probe `protobuf_oneof:"probe"`
 	DebugOptions *DebugOptions    `protobuf:"bytes,100,opt,name=debug_options,json=debugOptions" json:"debug_options,omitempty"`
@@ -286,6 +625,8 @@ type ProbeDef struct {
 const (
 	Default_ProbeDef_LatencyUnit       = string("us")
 	Default_ProbeDef_LatencyMetricName = string("latency")
+	Default_ProbeDef_TimestampSource   = ProbeDef_EMIT
+	Default_ProbeDef_ResultSampleRate  = float32(1)
 )
 
 func (x *ProbeDef) Reset() {
@@ -378,6 +719,27 @@ func (x *ProbeDef) GetTimeout() string {
 	return ""
 }
 
+func (x *ProbeDef) GetConnectTimeoutMsec() int32 {
+	if x != nil && x.ConnectTimeoutMsec != nil {
+		return *x.ConnectTimeoutMsec
+	}
+	return 0
+}
+
+func (x *ProbeDef) GetDnsResolveTimeoutMsec() int32 {
+	if x != nil && x.DnsResolveTimeoutMsec != nil {
+		return *x.DnsResolveTimeoutMsec
+	}
+	return 0
+}
+
+func (x *ProbeDef) GetSchedule() *ProbeDef_Schedule {
+	if x != nil {
+		return x.Schedule
+	}
+	return nil
+}
+
 func (x *ProbeDef) GetTargets() *proto.TargetsDef {
 	if x != nil {
 		return x.Targets
@@ -406,6 +768,27 @@ func (x *ProbeDef) GetLatencyMetricName() string {
 	return Default_ProbeDef_LatencyMetricName
 }
 
+func (x *ProbeDef) GetExportRawLatency() bool {
+	if x != nil && x.ExportRawLatency != nil {
+		return *x.ExportRawLatency
+	}
+	return false
+}
+
+func (x *ProbeDef) GetSlo() *ProbeDef_SLO {
+	if x != nil {
+		return x.Slo
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetTimestampSource() ProbeDef_TimestampSource {
+	if x != nil && x.TimestampSource != nil {
+		return *x.TimestampSource
+	}
+	return Default_ProbeDef_TimestampSource
+}
+
 func (x *ProbeDef) GetValidator() []*proto2.Validator {
 	if x != nil {
 		return x.Validator
@@ -441,6 +824,20 @@ func (x *ProbeDef) GetIpVersion() ProbeDef_IPVersion {
 	return ProbeDef_IP_VERSION_UNSPECIFIED
 }
 
+func (x *ProbeDef) GetIpVersionMode() ProbeDef_IPVersionMode {
+	if x != nil && x.IpVersionMode != nil {
+		return *x.IpVersionMode
+	}
+	return ProbeDef_IP_VERSION_MODE_UNSPECIFIED
+}
+
+func (x *ProbeDef) GetIpv6FlowLabel() int32 {
+	if x != nil && x.Ipv6FlowLabel != nil {
+		return *x.Ipv6FlowLabel
+	}
+	return 0
+}
+
 func (x *ProbeDef) GetStatsExportIntervalMsec() int32 {
 	if x != nil && x.StatsExportIntervalMsec != nil {
 		return *x.StatsExportIntervalMsec
@@ -455,6 +852,48 @@ func (x *ProbeDef) GetAdditionalLabel() []*AdditionalLabel {
 	return nil
 }
 
+func (x *ProbeDef) GetMaxConcurrentProbes() int32 {
+	if x != nil && x.MaxConcurrentProbes != nil {
+		return *x.MaxConcurrentProbes
+	}
+	return 0
+}
+
+func (x *ProbeDef) GetInitialDelayMsec() int32 {
+	if x != nil && x.InitialDelayMsec != nil {
+		return *x.InitialDelayMsec
+	}
+	return 0
+}
+
+func (x *ProbeDef) GetWarmupMsec() int32 {
+	if x != nil && x.WarmupMsec != nil {
+		return *x.WarmupMsec
+	}
+	return 0
+}
+
+func (x *ProbeDef) GetFailureDebounce() *ProbeDef_FailureDebounce {
+	if x != nil {
+		return x.FailureDebounce
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetAlerting() *AlertingConf {
+	if x != nil {
+		return x.Alerting
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetResultSampleRate() float32 {
+	if x != nil && x.ResultSampleRate != nil {
+		return *x.ResultSampleRate
+	}
+	return Default_ProbeDef_ResultSampleRate
+}
+
 func (m *ProbeDef) GetProbe() isProbeDef_Probe {
 	if m != nil {
 		return m.Probe
@@ -511,6 +950,76 @@ func (x *ProbeDef) GetGrpcProbe() *proto9.ProbeConf {
 	return nil
 }
 
+func (x *ProbeDef) GetTcpProbe() *proto10.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_TcpProbe); ok {
+		return x.TcpProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetTlsProbe() *proto11.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_TlsProbe); ok {
+		return x.TlsProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetNtpProbe() *proto12.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_NtpProbe); ok {
+		return x.NtpProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetWebsocketProbe() *proto13.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_WebsocketProbe); ok {
+		return x.WebsocketProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetSmtpProbe() *proto14.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_SmtpProbe); ok {
+		return x.SmtpProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetFtpProbe() *proto15.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_FtpProbe); ok {
+		return x.FtpProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetLdapProbe() *proto16.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_LdapProbe); ok {
+		return x.LdapProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetMqttProbe() *proto17.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_MqttProbe); ok {
+		return x.MqttProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetSnmpProbe() *proto18.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_SnmpProbe); ok {
+		return x.SnmpProbe
+	}
+	return nil
+}
+
+func (x *ProbeDef) GetTracerouteProbe() *proto19.ProbeConf {
+	if x, ok := x.GetProbe().(*ProbeDef_TracerouteProbe); ok {
+		return x.TracerouteProbe
+	}
+	return nil
+}
+
 func (x *ProbeDef) GetUserDefinedProbe() string {
 	if x, ok := x.GetProbe().(*ProbeDef_UserDefinedProbe); ok {
 		return x.UserDefinedProbe
@@ -573,6 +1082,46 @@ type ProbeDef_GrpcProbe struct {
 	GrpcProbe *proto9.ProbeConf `protobuf:"bytes,26,opt,name=grpc_probe,json=grpcProbe,oneof"`
 }
 
+type ProbeDef_TcpProbe struct {
+	TcpProbe *proto10.ProbeConf `protobuf:"bytes,27,opt,name=tcp_probe,json=tcpProbe,oneof"`
+}
+
+type ProbeDef_TlsProbe struct {
+	TlsProbe *proto11.ProbeConf `protobuf:"bytes,28,opt,name=tls_probe,json=tlsProbe,oneof"`
+}
+
+type ProbeDef_NtpProbe struct {
+	NtpProbe *proto12.ProbeConf `protobuf:"bytes,29,opt,name=ntp_probe,json=ntpProbe,oneof"`
+}
+
+type ProbeDef_WebsocketProbe struct {
+	WebsocketProbe *proto13.ProbeConf `protobuf:"bytes,42,opt,name=websocket_probe,json=websocketProbe,oneof"`
+}
+
+type ProbeDef_SmtpProbe struct {
+	SmtpProbe *proto14.ProbeConf `protobuf:"bytes,43,opt,name=smtp_probe,json=smtpProbe,oneof"`
+}
+
+type ProbeDef_FtpProbe struct {
+	FtpProbe *proto15.ProbeConf `protobuf:"bytes,44,opt,name=ftp_probe,json=ftpProbe,oneof"`
+}
+
+type ProbeDef_LdapProbe struct {
+	LdapProbe *proto16.ProbeConf `protobuf:"bytes,45,opt,name=ldap_probe,json=ldapProbe,oneof"`
+}
+
+type ProbeDef_MqttProbe struct {
+	MqttProbe *proto17.ProbeConf `protobuf:"bytes,46,opt,name=mqtt_probe,json=mqttProbe,oneof"`
+}
+
+type ProbeDef_SnmpProbe struct {
+	SnmpProbe *proto18.ProbeConf `protobuf:"bytes,47,opt,name=snmp_probe,json=snmpProbe,oneof"`
+}
+
+type ProbeDef_TracerouteProbe struct {
+	TracerouteProbe *proto19.ProbeConf `protobuf:"bytes,48,opt,name=traceroute_probe,json=tracerouteProbe,oneof"`
+}
+
 type ProbeDef_UserDefinedProbe struct {
 	// This field's contents are passed on to the user defined probe, registered
 	// for this probe's name through probes.RegisterUserDefined().
@@ -593,6 +1142,26 @@ func (*ProbeDef_UdpListenerProbe) isProbeDef_Probe() {}
 
 func (*ProbeDef_GrpcProbe) isProbeDef_Probe() {}
 
+func (*ProbeDef_TcpProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_TlsProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_NtpProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_WebsocketProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_SmtpProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_FtpProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_LdapProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_MqttProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_SnmpProbe) isProbeDef_Probe() {}
+
+func (*ProbeDef_TracerouteProbe) isProbeDef_Probe() {}
+
 func (*ProbeDef_UserDefinedProbe) isProbeDef_Probe() {}
 
 type AdditionalLabel struct {
@@ -653,6 +1222,94 @@ func (x *AdditionalLabel) GetValue() string {
 	return ""
 }
 
+// Alerting sends a JSON payload to a webhook URL, using an HTTP POST request,
+// when a target's state changes between success and failure, i.e. alerts are
+// edge-triggered. An alert fires when a target fails for min_failures
+// consecutive runs, and resolves when the target succeeds again. Payload looks
+// like the following:
+//
+//	{
+//	  "status": "firing",  // or "resolved"
+//	  "probe": "homepage",
+//	  "target": "www.example.com",
+//	  "failure_count": 3,
+//	  "failing_since": "2021-06-01T10:00:00Z",
+//	  "timestamp": "2021-06-01T10:00:20Z"
+//	}
+//
+// For a resolve notification, failure_count is the number of failed runs
+// before the recovery.
+type AlertingConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	WebhookUrl *string `protobuf:"bytes,1,req,name=webhook_url,json=webhookUrl" json:"webhook_url,omitempty"`
+	// Number of consecutive failed runs before an alert fires.
+	MinFailures *int32 `protobuf:"varint,2,opt,name=min_failures,json=minFailures,def=1" json:"min_failures,omitempty"`
+	// If set, firing notification is resent at this interval while the target
+	// continues to fail.
+	ResendIntervalSec *int32 `protobuf:"varint,3,opt,name=resend_interval_sec,json=resendIntervalSec" json:"resend_interval_sec,omitempty"`
+}
+
+// Default values for AlertingConf fields.
+const (
+	Default_AlertingConf_MinFailures = int32(1)
+)
+
+func (x *AlertingConf) Reset() {
+	*x = AlertingConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AlertingConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertingConf) ProtoMessage() {}
+
+func (x *AlertingConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertingConf.ProtoReflect.Descriptor instead.
+func (*AlertingConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AlertingConf) GetWebhookUrl() string {
+	if x != nil && x.WebhookUrl != nil {
+		return *x.WebhookUrl
+	}
+	return ""
+}
+
+func (x *AlertingConf) GetMinFailures() int32 {
+	if x != nil && x.MinFailures != nil {
+		return *x.MinFailures
+	}
+	return Default_AlertingConf_MinFailures
+}
+
+func (x *AlertingConf) GetResendIntervalSec() int32 {
+	if x != nil && x.ResendIntervalSec != nil {
+		return *x.ResendIntervalSec
+	}
+	return 0
+}
+
 type DebugOptions struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -665,7 +1322,7 @@ type DebugOptions struct {
 func (x *DebugOptions) Reset() {
 	*x = DebugOptions{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[2]
+		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -678,7 +1335,7 @@ func (x *DebugOptions) String() string {
 func (*DebugOptions) ProtoMessage() {}
 
 func (x *DebugOptions) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[2]
+	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -691,7 +1348,7 @@ func (x *DebugOptions) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DebugOptions.ProtoReflect.Descriptor instead.
 func (*DebugOptions) Descriptor() ([]byte, []int) {
-	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{2}
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *DebugOptions) GetLogMetrics() bool {
@@ -701,6 +1358,214 @@ func (x *DebugOptions) GetLogMetrics() bool {
 	return false
 }
 
+// Schedule to gate probe cycles with, e.g. to run some checks only during
+// the business hours. Probe cycles still run at the configured interval,
+// but only when the current time matches the cron expression (with a
+// minute granularity); outside the schedule, no cycles run and no new
+// results are reported. To run probes only at specific times instead, use
+// the TRIGGER mode. Examples:
+//
+//	schedule {
+//	  cron: "* 9-17 * * mon-fri"
+//	  timezone: "America/New_York"
+//	}
+//	schedule {
+//	  cron: "30 2 * * *"  # Run a traceroute every night at 2:30.
+//	  mode: TRIGGER
+//	}
+type ProbeDef_Schedule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Standard 5-field cron expression: minute, hour, day of month, month and
+	// day of week. Fields support lists, ranges, steps, and month and day of
+	// week names, e.g. "*/15 9-17 * * 1-5".
+	Cron *string `protobuf:"bytes,1,req,name=cron" json:"cron,omitempty"`
+	// Time zone to evaluate the cron expression in, in the IANA format.
+	// Default is the local time zone.
+	Timezone *string                 `protobuf:"bytes,2,opt,name=timezone" json:"timezone,omitempty"`
+	Mode     *ProbeDef_Schedule_Mode `protobuf:"varint,3,opt,name=mode,enum=cloudprober.probes.ProbeDef_Schedule_Mode,def=0" json:"mode,omitempty"`
+}
+
+// Default values for ProbeDef_Schedule fields.
+const (
+	Default_ProbeDef_Schedule_Mode = ProbeDef_Schedule_GATE
+)
+
+func (x *ProbeDef_Schedule) Reset() {
+	*x = ProbeDef_Schedule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeDef_Schedule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeDef_Schedule) ProtoMessage() {}
+
+func (x *ProbeDef_Schedule) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeDef_Schedule.ProtoReflect.Descriptor instead.
+func (*ProbeDef_Schedule) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *ProbeDef_Schedule) GetCron() string {
+	if x != nil && x.Cron != nil {
+		return *x.Cron
+	}
+	return ""
+}
+
+func (x *ProbeDef_Schedule) GetTimezone() string {
+	if x != nil && x.Timezone != nil {
+		return *x.Timezone
+	}
+	return ""
+}
+
+func (x *ProbeDef_Schedule) GetMode() ProbeDef_Schedule_Mode {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return Default_ProbeDef_Schedule_Mode
+}
+
+// SLO (service level objective) metrics. If configured, a probe result
+// counts as good only if it succeeded and its latency is under the latency
+// objective. Good and total results are exported as the "slo_good_total"
+// and "slo_total" counters, along with the probe's regular metrics, e.g.
+// to compute the SLO burn rate. Example:
+//
+//	slo { latency_objective: "200ms" }
+//
+// For HTTP probes, every request (or synthetic transaction) is a result, and
+// for ping probes, every echo request is.
+//
+// NOTE: Only DNS, FTP, HTTP, LDAP, MQTT, NTP, ping, SMTP, SNMP, TCP, TLS,
+// traceroute and WebSocket probes support this option currently.
+type ProbeDef_SLO struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Latency objective, as a duration string, e.g. "200ms".
+	LatencyObjective *string `protobuf:"bytes,1,req,name=latency_objective,json=latencyObjective" json:"latency_objective,omitempty"`
+}
+
+func (x *ProbeDef_SLO) Reset() {
+	*x = ProbeDef_SLO{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeDef_SLO) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeDef_SLO) ProtoMessage() {}
+
+func (x *ProbeDef_SLO) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeDef_SLO.ProtoReflect.Descriptor instead.
+func (*ProbeDef_SLO) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+func (x *ProbeDef_SLO) GetLatencyObjective() string {
+	if x != nil && x.LatencyObjective != nil {
+		return *x.LatencyObjective
+	}
+	return ""
+}
+
+// Failure debounce, to avoid alerting on transient failures. If configured,
+// a target's failed runs are reported as successful until the target fails
+// for the configured number of consecutive runs. Raw (not debounced)
+// success counter is exported as "raw_success". Example:
+//
+//	failure_debounce { consecutive: 3 }
+type ProbeDef_FailureDebounce struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Number of consecutive failed runs before failures are reported.
+	Consecutive *int32 `protobuf:"varint,1,opt,name=consecutive,def=3" json:"consecutive,omitempty"`
+}
+
+// Default values for ProbeDef_FailureDebounce fields.
+const (
+	Default_ProbeDef_FailureDebounce_Consecutive = int32(3)
+)
+
+func (x *ProbeDef_FailureDebounce) Reset() {
+	*x = ProbeDef_FailureDebounce{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeDef_FailureDebounce) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeDef_FailureDebounce) ProtoMessage() {}
+
+func (x *ProbeDef_FailureDebounce) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeDef_FailureDebounce.ProtoReflect.Descriptor instead.
+func (*ProbeDef_FailureDebounce) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZIP(), []int{0, 2}
+}
+
+func (x *ProbeDef_FailureDebounce) GetConsecutive() int32 {
+	if x != nil && x.Consecutive != nil {
+		return *x.Consecutive
+	}
+	return Default_ProbeDef_FailureDebounce_Consecutive
+}
+
 var File_github_com_cloudprober_cloudprober_probes_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDesc = []byte{
@@ -721,79 +1586,152 @@ var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDesc =
 	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
 	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x65, 0x78, 0x74,
 	0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2f, 0x66, 0x74, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x41, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x41, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x68, 0x74, 0x74, 0x70, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6c, 0x64, 0x61, 0x70,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x1a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x6d,
+	0x71, 0x74, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x73, 0x2f, 0x6e, 0x74, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
 	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
 	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
 	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x73, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63,
+	0x62, 0x65, 0x73, 0x2f, 0x70, 0x69, 0x6e, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63,
 	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x41, 0x67, 0x69, 0x74,
 	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
 	0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x68, 0x74, 0x74, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73, 0x6d, 0x74, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74,
 	0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x41,
 	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
 	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x70, 0x69, 0x6e, 0x67, 0x2f, 0x70,
+	0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73, 0x6e, 0x6d, 0x70, 0x2f, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74,
 	0x6f, 0x1a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
 	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x74, 0x63, 0x70,
 	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x1a, 0x48, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x6f, 0x74, 0x6f, 0x1a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
 	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
-	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75,
-	0x64, 0x70, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x3e, 0x67,
-	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
-	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x40, 0x67,
-	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0x88, 0x0d, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x12, 0x35, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0e, 0x32, 0x21,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x74,
+	0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x47, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73,
+	0x2f, 0x74, 0x72, 0x61, 0x63, 0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x40,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64, 0x70, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x48, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64, 0x70, 0x6c,
+	0x69, 0x73, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x77, 0x65, 0x62, 0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x3e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72,
+	0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0xae, 0x1d, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65,
+	0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x35, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20,
+	0x02, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65,
+	0x66, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x15, 0x0a, 0x06,
+	0x72, 0x75, 0x6e, 0x5f, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x75,
+	0x6e, 0x4f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f,
+	0x6d, 0x73, 0x65, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f,
+	0x6d, 0x73, 0x65, 0x63, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x74, 0x69, 0x6d, 0x65,
+	0x6f, 0x75, 0x74, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75,
+	0x74, 0x12, 0x30, 0x0a, 0x14, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x22, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x12, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d,
+	0x73, 0x65, 0x63, 0x12, 0x37, 0x0a, 0x18, 0x64, 0x6e, 0x73, 0x5f, 0x72, 0x65, 0x73, 0x6f, 0x6c,
+	0x76, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18,
+	0x28, 0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x64, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76,
+	0x65, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x41, 0x0a, 0x08,
+	0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25,
 	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x54, 0x79, 0x70,
-	0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x72, 0x75, 0x6e, 0x5f, 0x6f,
-	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x75, 0x6e, 0x4f, 0x6e, 0x12, 0x23,
-	0x0a, 0x0d, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x4d,
-	0x73, 0x65, 0x63, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18,
-	0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12,
-	0x21, 0x0a, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x73,
-	0x65, 0x63, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x11, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x39, 0x0a, 0x07,
-	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18, 0x06, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x1f, 0x2e,
-	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74, 0x61, 0x72, 0x67,
-	0x65, 0x74, 0x73, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x44, 0x65, 0x66, 0x52, 0x07,
-	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x12, 0x4c, 0x0a, 0x14, 0x6c, 0x61, 0x74, 0x65, 0x6e,
-	0x63, 0x79, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x18,
-	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x72, 0x2e, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2e, 0x44, 0x69, 0x73, 0x74,
-	0x52, 0x13, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x44, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62,
-	0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x0c, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79,
-	0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x02, 0x75, 0x73, 0x52,
-	0x0b, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x37, 0x0a, 0x13,
-	0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x5f, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x07, 0x6c, 0x61, 0x74, 0x65, 0x6e,
-	0x63, 0x79, 0x52, 0x11, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
-	0x6f, 0x72, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72,
-	0x73, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x09, 0x76, 0x61, 0x6c,
-	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x1d, 0x0a, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
-	0x5f, 0x69, 0x70, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x08, 0x73, 0x6f, 0x75,
-	0x72, 0x63, 0x65, 0x49, 0x70, 0x12, 0x2b, 0x0a, 0x10, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f,
-	0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x48,
-	0x00, 0x52, 0x0f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61,
-	0x63, 0x65, 0x12, 0x45, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x18, 0x0c, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x26, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x53, 0x63, 0x68,
+	0x65, 0x64, 0x75, 0x6c, 0x65, 0x52, 0x08, 0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x12,
+	0x39, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18, 0x06, 0x20, 0x02, 0x28, 0x0b,
+	0x32, 0x1f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x74,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x44, 0x65,
+	0x66, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x12, 0x4c, 0x0a, 0x14, 0x6c, 0x61,
+	0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2e, 0x44,
+	0x69, 0x73, 0x74, 0x52, 0x13, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x44, 0x69, 0x73, 0x74,
+	0x72, 0x69, 0x62, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x0c, 0x6c, 0x61, 0x74, 0x65,
+	0x6e, 0x63, 0x79, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x02,
+	0x75, 0x73, 0x52, 0x0b, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x55, 0x6e, 0x69, 0x74, 0x12,
+	0x37, 0x0a, 0x13, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x07, 0x6c, 0x61,
+	0x74, 0x65, 0x6e, 0x63, 0x79, 0x52, 0x11, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4d, 0x65,
+	0x74, 0x72, 0x69, 0x63, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x65, 0x78, 0x70, 0x6f,
+	0x72, 0x74, 0x5f, 0x72, 0x61, 0x77, 0x5f, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x24,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x61, 0x77, 0x4c,
+	0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x32, 0x0a, 0x03, 0x73, 0x6c, 0x6f, 0x18, 0x27, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65,
+	0x66, 0x2e, 0x53, 0x4c, 0x4f, 0x52, 0x03, 0x73, 0x6c, 0x6f, 0x12, 0x5d, 0x0a, 0x10, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x29,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x2c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44,
+	0x65, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x53, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x3a, 0x04, 0x45, 0x4d, 0x49, 0x54, 0x52, 0x0f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x76, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x6f, 0x72, 0x73, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x52,
+	0x09, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x1d, 0x0a, 0x09, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x70, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x08, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x70, 0x12, 0x2b, 0x0a, 0x10, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x18, 0x0b, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x12, 0x45, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x26, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x49, 0x50, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x52, 0x09, 0x69, 0x70, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x52, 0x0a,
+	0x0f, 0x69, 0x70, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x6f, 0x64, 0x65,
+	0x18, 0x21, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
 	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62,
-	0x65, 0x44, 0x65, 0x66, 0x2e, 0x49, 0x50, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x09,
-	0x69, 0x70, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x3b, 0x0a, 0x1a, 0x73, 0x74, 0x61,
+	0x65, 0x44, 0x65, 0x66, 0x2e, 0x49, 0x50, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x6f,
+	0x64, 0x65, 0x52, 0x0d, 0x69, 0x70, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64,
+	0x65, 0x12, 0x26, 0x0a, 0x0f, 0x69, 0x70, 0x76, 0x36, 0x5f, 0x66, 0x6c, 0x6f, 0x77, 0x5f, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x18, 0x25, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x69, 0x70, 0x76, 0x36,
+	0x46, 0x6c, 0x6f, 0x77, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x3b, 0x0a, 0x1a, 0x73, 0x74, 0x61,
 	0x74, 0x73, 0x5f, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76,
 	0x61, 0x6c, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x17, 0x73,
 	0x74, 0x61, 0x74, 0x73, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76,
@@ -802,70 +1740,178 @@ var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDesc =
 	0x32, 0x23, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70,
 	0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x41, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c,
 	0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x0f, 0x61, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61,
-	0x6c, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x43, 0x0a, 0x0a, 0x70, 0x69, 0x6e, 0x67, 0x5f, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f,
+	0x6c, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x32, 0x0a, 0x15, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6f,
+	0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x18,
+	0x12, 0x20, 0x01, 0x28, 0x05, 0x52, 0x13, 0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e, 0x63, 0x75, 0x72,
+	0x72, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x69, 0x6e,
+	0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x5f, 0x6d, 0x73, 0x65, 0x63,
+	0x18, 0x13, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x44,
+	0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x1f, 0x0a, 0x0b, 0x77, 0x61, 0x72, 0x6d,
+	0x75, 0x70, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x77,
+	0x61, 0x72, 0x6d, 0x75, 0x70, 0x4d, 0x73, 0x65, 0x63, 0x12, 0x57, 0x0a, 0x10, 0x66, 0x61, 0x69,
+	0x6c, 0x75, 0x72, 0x65, 0x5f, 0x64, 0x65, 0x62, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x18, 0x1f, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x44, 0x65,
+	0x66, 0x2e, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x44, 0x65, 0x62, 0x6f, 0x75, 0x6e, 0x63,
+	0x65, 0x52, 0x0f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x44, 0x65, 0x62, 0x6f, 0x75, 0x6e,
+	0x63, 0x65, 0x12, 0x3c, 0x0a, 0x08, 0x61, 0x6c, 0x65, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x20,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x41, 0x6c, 0x65, 0x72, 0x74, 0x69,
+	0x6e, 0x67, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x08, 0x61, 0x6c, 0x65, 0x72, 0x74, 0x69, 0x6e, 0x67,
+	0x12, 0x2f, 0x0a, 0x12, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x5f, 0x73, 0x61, 0x6d, 0x70, 0x6c,
+	0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x26, 0x20, 0x01, 0x28, 0x02, 0x3a, 0x01, 0x31, 0x52,
+	0x10, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x53, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52, 0x61, 0x74,
+	0x65, 0x12, 0x43, 0x0a, 0x0a, 0x70, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18,
+	0x14, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x70, 0x69, 0x6e, 0x67, 0x2e,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x70, 0x69, 0x6e,
+	0x67, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f,
 	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e,
-	0x70, 0x69, 0x6e, 0x67, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01,
-	0x52, 0x09, 0x70, 0x69, 0x6e, 0x67, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x68,
-	0x74, 0x74, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x68, 0x74, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01,
+	0x52, 0x09, 0x68, 0x74, 0x74, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x64,
+	0x6e, 0x73, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e,
+	0x66, 0x48, 0x01, 0x52, 0x08, 0x64, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x4f, 0x0a,
+	0x0e, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18,
+	0x17, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x65, 0x78, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52,
+	0x0d, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x40,
+	0x0a, 0x09, 0x75, 0x64, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x18, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x75, 0x64, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x75, 0x64, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x12, 0x59, 0x0a, 0x12, 0x75, 0x64, 0x70, 0x5f, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x65, 0x72,
+	0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x19, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x73, 0x2e, 0x75, 0x64, 0x70, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x50, 0x72,
+	0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x10, 0x75, 0x64, 0x70, 0x4c, 0x69,
+	0x73, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x67,
+	0x72, 0x70, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x0b, 0x32,
 	0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x68, 0x74, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43,
-	0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x68, 0x74, 0x74, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x65,
-	0x12, 0x40, 0x0a, 0x09, 0x64, 0x6e, 0x73, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x16, 0x20,
+	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x67, 0x72, 0x70, 0x63, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x12, 0x40, 0x0a, 0x09, 0x74, 0x63, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x1b, 0x20,
 	0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x64, 0x6e, 0x73, 0x2e, 0x50, 0x72, 0x6f,
-	0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x64, 0x6e, 0x73, 0x50, 0x72, 0x6f,
-	0x62, 0x65, 0x12, 0x4f, 0x0a, 0x0e, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x18, 0x17, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x63, 0x6c, 0x6f,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x74, 0x63, 0x70, 0x2e, 0x50, 0x72, 0x6f,
+	0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x74, 0x63, 0x70, 0x50, 0x72, 0x6f,
+	0x62, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x74, 0x6c, 0x73, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18,
+	0x1c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x74, 0x6c, 0x73, 0x2e, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x74, 0x6c, 0x73, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x6e, 0x74, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6e, 0x74, 0x70,
+	0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x6e, 0x74,
+	0x70, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x52, 0x0a, 0x0f, 0x77, 0x65, 0x62, 0x73, 0x6f, 0x63,
+	0x6b, 0x65, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x77, 0x65, 0x62, 0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x0e, 0x77, 0x65, 0x62, 0x73,
+	0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x73, 0x6d,
+	0x74, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x73, 0x6d, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
+	0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x73, 0x6d, 0x74, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12,
+	0x40, 0x0a, 0x09, 0x66, 0x74, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x2c, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x66, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62,
+	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x66, 0x74, 0x70, 0x50, 0x72, 0x6f, 0x62,
+	0x65, 0x12, 0x43, 0x0a, 0x0a, 0x6c, 0x64, 0x61, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18,
+	0x2d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x6c, 0x64, 0x61, 0x70, 0x2e,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x6c, 0x64, 0x61,
+	0x70, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x6d, 0x71, 0x74, 0x74, 0x5f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x18, 0x2e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f,
 	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e,
-	0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
-	0x6e, 0x66, 0x48, 0x01, 0x52, 0x0d, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x50, 0x72,
-	0x6f, 0x62, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x75, 0x64, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x18, 0x18, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x75, 0x64, 0x70, 0x2e,
-	0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x08, 0x75, 0x64, 0x70,
-	0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x59, 0x0a, 0x12, 0x75, 0x64, 0x70, 0x5f, 0x6c, 0x69, 0x73,
-	0x74, 0x65, 0x6e, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x19, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x29, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x75, 0x64, 0x70, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e,
-	0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x10,
-	0x75, 0x64, 0x70, 0x4c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65,
-	0x12, 0x43, 0x0a, 0x0a, 0x67, 0x72, 0x70, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x1a,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x50,
-	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x67, 0x72, 0x70, 0x63,
-	0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x2e, 0x0a, 0x12, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x64, 0x65,
-	0x66, 0x69, 0x6e, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x63, 0x20, 0x01, 0x28,
-	0x09, 0x48, 0x01, 0x52, 0x10, 0x75, 0x73, 0x65, 0x72, 0x44, 0x65, 0x66, 0x69, 0x6e, 0x65, 0x64,
-	0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x45, 0x0a, 0x0d, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x6f,
-	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x64, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63,
-	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65,
-	0x73, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x0c,
-	0x64, 0x65, 0x62, 0x75, 0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x77, 0x0a, 0x04,
-	0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x50, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x08,
-	0x0a, 0x04, 0x48, 0x54, 0x54, 0x50, 0x10, 0x01, 0x12, 0x07, 0x0a, 0x03, 0x44, 0x4e, 0x53, 0x10,
-	0x02, 0x12, 0x0c, 0x0a, 0x08, 0x45, 0x58, 0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x10, 0x03, 0x12,
-	0x07, 0x0a, 0x03, 0x55, 0x44, 0x50, 0x10, 0x04, 0x12, 0x10, 0x0a, 0x0c, 0x55, 0x44, 0x50, 0x5f,
-	0x4c, 0x49, 0x53, 0x54, 0x45, 0x4e, 0x45, 0x52, 0x10, 0x05, 0x12, 0x08, 0x0a, 0x04, 0x47, 0x52,
-	0x50, 0x43, 0x10, 0x06, 0x12, 0x0d, 0x0a, 0x09, 0x45, 0x58, 0x54, 0x45, 0x4e, 0x53, 0x49, 0x4f,
-	0x4e, 0x10, 0x62, 0x12, 0x10, 0x0a, 0x0c, 0x55, 0x53, 0x45, 0x52, 0x5f, 0x44, 0x45, 0x46, 0x49,
-	0x4e, 0x45, 0x44, 0x10, 0x63, 0x22, 0x3b, 0x0a, 0x09, 0x49, 0x50, 0x56, 0x65, 0x72, 0x73, 0x69,
-	0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x16, 0x49, 0x50, 0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e,
-	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x08,
-	0x0a, 0x04, 0x49, 0x50, 0x56, 0x34, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x50, 0x56, 0x36,
-	0x10, 0x02, 0x2a, 0x09, 0x08, 0xc8, 0x01, 0x10, 0x80, 0x80, 0x80, 0x80, 0x02, 0x42, 0x12, 0x0a,
-	0x10, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69,
-	0x67, 0x42, 0x07, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x22, 0x39, 0x0a, 0x0f, 0x41, 0x64,
-	0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x10, 0x0a,
-	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
-	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x09, 0x52, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x2f, 0x0a, 0x0c, 0x44, 0x65, 0x62, 0x75, 0x67, 0x4f, 0x70,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6c, 0x6f, 0x67, 0x5f, 0x6d, 0x65, 0x74,
-	0x72, 0x69, 0x63, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x6c, 0x6f, 0x67, 0x4d,
-	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x6d, 0x71, 0x74, 0x74, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01,
+	0x52, 0x09, 0x6d, 0x71, 0x74, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x73,
+	0x6e, 0x6d, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x2f, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x22, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x09, 0x73, 0x6e, 0x6d, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x12, 0x55, 0x0a, 0x10, 0x74, 0x72, 0x61, 0x63, 0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x5f, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x18, 0x30, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e,
+	0x74, 0x72, 0x61, 0x63, 0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65,
+	0x43, 0x6f, 0x6e, 0x66, 0x48, 0x01, 0x52, 0x0f, 0x74, 0x72, 0x61, 0x63, 0x65, 0x72, 0x6f, 0x75,
+	0x74, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x2e, 0x0a, 0x12, 0x75, 0x73, 0x65, 0x72, 0x5f,
+	0x64, 0x65, 0x66, 0x69, 0x6e, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x63, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x10, 0x75, 0x73, 0x65, 0x72, 0x44, 0x65, 0x66, 0x69, 0x6e,
+	0x65, 0x64, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x45, 0x0a, 0x0d, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x5f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x64, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x52, 0x0c, 0x64, 0x65, 0x62, 0x75, 0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x1a, 0x9f,
+	0x01, 0x0a, 0x08, 0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63,
+	0x72, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x04, 0x63, 0x72, 0x6f, 0x6e, 0x12,
+	0x1a, 0x0a, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f, 0x6e, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f, 0x6e, 0x65, 0x12, 0x44, 0x0a, 0x04, 0x6d,
+	0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65,
+	0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x3a, 0x04, 0x47, 0x41, 0x54, 0x45, 0x52, 0x04, 0x6d, 0x6f, 0x64,
+	0x65, 0x22, 0x1d, 0x0a, 0x04, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x47, 0x41, 0x54,
+	0x45, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x54, 0x52, 0x49, 0x47, 0x47, 0x45, 0x52, 0x10, 0x01,
+	0x1a, 0x32, 0x0a, 0x03, 0x53, 0x4c, 0x4f, 0x12, 0x2b, 0x0a, 0x11, 0x6c, 0x61, 0x74, 0x65, 0x6e,
+	0x63, 0x79, 0x5f, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x01, 0x20, 0x02,
+	0x28, 0x09, 0x52, 0x10, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4f, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x69, 0x76, 0x65, 0x1a, 0x36, 0x0a, 0x0f, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x44,
+	0x65, 0x62, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x73, 0x65,
+	0x63, 0x75, 0x74, 0x69, 0x76, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x33, 0x52,
+	0x0b, 0x63, 0x6f, 0x6e, 0x73, 0x65, 0x63, 0x75, 0x74, 0x69, 0x76, 0x65, 0x22, 0xe2, 0x01, 0x0a,
+	0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x50, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12,
+	0x08, 0x0a, 0x04, 0x48, 0x54, 0x54, 0x50, 0x10, 0x01, 0x12, 0x07, 0x0a, 0x03, 0x44, 0x4e, 0x53,
+	0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x45, 0x58, 0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x10, 0x03,
+	0x12, 0x07, 0x0a, 0x03, 0x55, 0x44, 0x50, 0x10, 0x04, 0x12, 0x10, 0x0a, 0x0c, 0x55, 0x44, 0x50,
+	0x5f, 0x4c, 0x49, 0x53, 0x54, 0x45, 0x4e, 0x45, 0x52, 0x10, 0x05, 0x12, 0x08, 0x0a, 0x04, 0x47,
+	0x52, 0x50, 0x43, 0x10, 0x06, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x43, 0x50, 0x10, 0x07, 0x12, 0x07,
+	0x0a, 0x03, 0x54, 0x4c, 0x53, 0x10, 0x08, 0x12, 0x07, 0x0a, 0x03, 0x4e, 0x54, 0x50, 0x10, 0x09,
+	0x12, 0x0d, 0x0a, 0x09, 0x57, 0x45, 0x42, 0x53, 0x4f, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x0a, 0x12,
+	0x08, 0x0a, 0x04, 0x53, 0x4d, 0x54, 0x50, 0x10, 0x0b, 0x12, 0x07, 0x0a, 0x03, 0x46, 0x54, 0x50,
+	0x10, 0x0c, 0x12, 0x08, 0x0a, 0x04, 0x4c, 0x44, 0x41, 0x50, 0x10, 0x0d, 0x12, 0x08, 0x0a, 0x04,
+	0x4d, 0x51, 0x54, 0x54, 0x10, 0x0e, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x4e, 0x4d, 0x50, 0x10, 0x0f,
+	0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x52, 0x41, 0x43, 0x45, 0x52, 0x4f, 0x55, 0x54, 0x45, 0x10, 0x10,
+	0x12, 0x0d, 0x0a, 0x09, 0x45, 0x58, 0x54, 0x45, 0x4e, 0x53, 0x49, 0x4f, 0x4e, 0x10, 0x62, 0x12,
+	0x10, 0x0a, 0x0c, 0x55, 0x53, 0x45, 0x52, 0x5f, 0x44, 0x45, 0x46, 0x49, 0x4e, 0x45, 0x44, 0x10,
+	0x63, 0x22, 0x3b, 0x0a, 0x0f, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x53, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x45, 0x4d, 0x49, 0x54, 0x10, 0x00, 0x12, 0x0f,
+	0x0a, 0x0b, 0x43, 0x59, 0x43, 0x4c, 0x45, 0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x10, 0x01, 0x12,
+	0x0d, 0x0a, 0x09, 0x43, 0x59, 0x43, 0x4c, 0x45, 0x5f, 0x45, 0x4e, 0x44, 0x10, 0x02, 0x22, 0x3b,
+	0x0a, 0x09, 0x49, 0x50, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x16, 0x49,
+	0x50, 0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43,
+	0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x50, 0x56, 0x34, 0x10,
+	0x01, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x50, 0x56, 0x36, 0x10, 0x02, 0x22, 0x70, 0x0a, 0x0d, 0x49,
+	0x50, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x1f, 0x0a, 0x1b,
+	0x49, 0x50, 0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x4d, 0x4f, 0x44, 0x45, 0x5f,
+	0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0d, 0x0a,
+	0x09, 0x49, 0x50, 0x56, 0x34, 0x5f, 0x4f, 0x4e, 0x4c, 0x59, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09,
+	0x49, 0x50, 0x56, 0x36, 0x5f, 0x4f, 0x4e, 0x4c, 0x59, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x50,
+	0x52, 0x45, 0x46, 0x45, 0x52, 0x5f, 0x49, 0x50, 0x56, 0x36, 0x10, 0x03, 0x12, 0x0f, 0x0a, 0x0b,
+	0x50, 0x52, 0x45, 0x46, 0x45, 0x52, 0x5f, 0x49, 0x50, 0x56, 0x34, 0x10, 0x04, 0x2a, 0x09, 0x08,
+	0xc8, 0x01, 0x10, 0x80, 0x80, 0x80, 0x80, 0x02, 0x42, 0x12, 0x0a, 0x10, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x5f, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x42, 0x07, 0x0a, 0x05,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x22, 0x39, 0x0a, 0x0f, 0x41, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x61, 0x6c, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x22, 0x85, 0x01, 0x0a, 0x0c, 0x41, 0x6c, 0x65, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6f, 0x6e,
+	0x66, 0x12, 0x1f, 0x0a, 0x0b, 0x77, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x0a, 0x77, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x55,
+	0x72, 0x6c, 0x12, 0x24, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72,
+	0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x31, 0x52, 0x0b, 0x6d, 0x69, 0x6e,
+	0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x72, 0x65, 0x73, 0x65,
+	0x6e, 0x64, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x11, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x64, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x22, 0x2f, 0x0a, 0x0c, 0x44, 0x65, 0x62, 0x75,
+	0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6c, 0x6f, 0x67, 0x5f,
+	0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x6c,
+	0x6f, 0x67, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -880,45 +1926,79 @@ func file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescGZ
 	return file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_goTypes = []interface{}{
-	(ProbeDef_Type)(0),       // 0: cloudprober.probes.ProbeDef.Type
-	(ProbeDef_IPVersion)(0),  // 1: cloudprober.probes.ProbeDef.IPVersion
-	(*ProbeDef)(nil),         // 2: cloudprober.probes.ProbeDef
-	(*AdditionalLabel)(nil),  // 3: cloudprober.probes.AdditionalLabel
-	(*DebugOptions)(nil),     // 4: cloudprober.probes.DebugOptions
-	(*proto.TargetsDef)(nil), // 5: cloudprober.targets.TargetsDef
-	(*proto1.Dist)(nil),      // 6: cloudprober.metrics.Dist
-	(*proto2.Validator)(nil), // 7: cloudprober.validators.Validator
-	(*proto3.ProbeConf)(nil), // 8: cloudprober.probes.ping.ProbeConf
-	(*proto4.ProbeConf)(nil), // 9: cloudprober.probes.http.ProbeConf
-	(*proto5.ProbeConf)(nil), // 10: cloudprober.probes.dns.ProbeConf
-	(*proto6.ProbeConf)(nil), // 11: cloudprober.probes.external.ProbeConf
-	(*proto7.ProbeConf)(nil), // 12: cloudprober.probes.udp.ProbeConf
-	(*proto8.ProbeConf)(nil), // 13: cloudprober.probes.udplistener.ProbeConf
-	(*proto9.ProbeConf)(nil), // 14: cloudprober.probes.grpc.ProbeConf
+	(ProbeDef_Type)(0),               // 0: cloudprober.probes.ProbeDef.Type
+	(ProbeDef_TimestampSource)(0),    // 1: cloudprober.probes.ProbeDef.TimestampSource
+	(ProbeDef_IPVersion)(0),          // 2: cloudprober.probes.ProbeDef.IPVersion
+	(ProbeDef_IPVersionMode)(0),      // 3: cloudprober.probes.ProbeDef.IPVersionMode
+	(ProbeDef_Schedule_Mode)(0),      // 4: cloudprober.probes.ProbeDef.Schedule.Mode
+	(*ProbeDef)(nil),                 // 5: cloudprober.probes.ProbeDef
+	(*AdditionalLabel)(nil),          // 6: cloudprober.probes.AdditionalLabel
+	(*AlertingConf)(nil),             // 7: cloudprober.probes.AlertingConf
+	(*DebugOptions)(nil),             // 8: cloudprober.probes.DebugOptions
+	(*ProbeDef_Schedule)(nil),        // 9: cloudprober.probes.ProbeDef.Schedule
+	(*ProbeDef_SLO)(nil),             // 10: cloudprober.probes.ProbeDef.SLO
+	(*ProbeDef_FailureDebounce)(nil), // 11: cloudprober.probes.ProbeDef.FailureDebounce
+	(*proto.TargetsDef)(nil),         // 12: cloudprober.targets.TargetsDef
+	(*proto1.Dist)(nil),              // 13: cloudprober.metrics.Dist
+	(*proto2.Validator)(nil),         // 14: cloudprober.validators.Validator
+	(*proto3.ProbeConf)(nil),         // 15: cloudprober.probes.ping.ProbeConf
+	(*proto4.ProbeConf)(nil),         // 16: cloudprober.probes.http.ProbeConf
+	(*proto5.ProbeConf)(nil),         // 17: cloudprober.probes.dns.ProbeConf
+	(*proto6.ProbeConf)(nil),         // 18: cloudprober.probes.external.ProbeConf
+	(*proto7.ProbeConf)(nil),         // 19: cloudprober.probes.udp.ProbeConf
+	(*proto8.ProbeConf)(nil),         // 20: cloudprober.probes.udplistener.ProbeConf
+	(*proto9.ProbeConf)(nil),         // 21: cloudprober.probes.grpc.ProbeConf
+	(*proto10.ProbeConf)(nil),        // 22: cloudprober.probes.tcp.ProbeConf
+	(*proto11.ProbeConf)(nil),        // 23: cloudprober.probes.tls.ProbeConf
+	(*proto12.ProbeConf)(nil),        // 24: cloudprober.probes.ntp.ProbeConf
+	(*proto13.ProbeConf)(nil),        // 25: cloudprober.probes.websocket.ProbeConf
+	(*proto14.ProbeConf)(nil),        // 26: cloudprober.probes.smtp.ProbeConf
+	(*proto15.ProbeConf)(nil),        // 27: cloudprober.probes.ftp.ProbeConf
+	(*proto16.ProbeConf)(nil),        // 28: cloudprober.probes.ldap.ProbeConf
+	(*proto17.ProbeConf)(nil),        // 29: cloudprober.probes.mqtt.ProbeConf
+	(*proto18.ProbeConf)(nil),        // 30: cloudprober.probes.snmp.ProbeConf
+	(*proto19.ProbeConf)(nil),        // 31: cloudprober.probes.traceroute.ProbeConf
 }
 var file_github_com_cloudprober_cloudprober_probes_proto_config_proto_depIdxs = []int32{
 	0,  // 0: cloudprober.probes.ProbeDef.type:type_name -> cloudprober.probes.ProbeDef.Type
-	5,  // 1: cloudprober.probes.ProbeDef.targets:type_name -> cloudprober.targets.TargetsDef
-	6,  // 2: cloudprober.probes.ProbeDef.latency_distribution:type_name -> cloudprober.metrics.Dist
-	7,  // 3: cloudprober.probes.ProbeDef.validator:type_name -> cloudprober.validators.Validator
-	1,  // 4: cloudprober.probes.ProbeDef.ip_version:type_name -> cloudprober.probes.ProbeDef.IPVersion
-	3,  // 5: cloudprober.probes.ProbeDef.additional_label:type_name -> cloudprober.probes.AdditionalLabel
-	8,  // 6: cloudprober.probes.ProbeDef.ping_probe:type_name -> cloudprober.probes.ping.ProbeConf
-	9,  // 7: cloudprober.probes.ProbeDef.http_probe:type_name -> cloudprober.probes.http.ProbeConf
-	10, // 8: cloudprober.probes.ProbeDef.dns_probe:type_name -> cloudprober.probes.dns.ProbeConf
-	11, // 9: cloudprober.probes.ProbeDef.external_probe:type_name -> cloudprober.probes.external.ProbeConf
-	12, // 10: cloudprober.probes.ProbeDef.udp_probe:type_name -> cloudprober.probes.udp.ProbeConf
-	13, // 11: cloudprober.probes.ProbeDef.udp_listener_probe:type_name -> cloudprober.probes.udplistener.ProbeConf
-	14, // 12: cloudprober.probes.ProbeDef.grpc_probe:type_name -> cloudprober.probes.grpc.ProbeConf
-	4,  // 13: cloudprober.probes.ProbeDef.debug_options:type_name -> cloudprober.probes.DebugOptions
-	14, // [14:14] is the sub-list for method output_type
-	14, // [14:14] is the sub-list for method input_type
-	14, // [14:14] is the sub-list for extension type_name
-	14, // [14:14] is the sub-list for extension extendee
-	0,  // [0:14] is the sub-list for field type_name
+	9,  // 1: cloudprober.probes.ProbeDef.schedule:type_name -> cloudprober.probes.ProbeDef.Schedule
+	12, // 2: cloudprober.probes.ProbeDef.targets:type_name -> cloudprober.targets.TargetsDef
+	13, // 3: cloudprober.probes.ProbeDef.latency_distribution:type_name -> cloudprober.metrics.Dist
+	10, // 4: cloudprober.probes.ProbeDef.slo:type_name -> cloudprober.probes.ProbeDef.SLO
+	1,  // 5: cloudprober.probes.ProbeDef.timestamp_source:type_name -> cloudprober.probes.ProbeDef.TimestampSource
+	14, // 6: cloudprober.probes.ProbeDef.validator:type_name -> cloudprober.validators.Validator
+	2,  // 7: cloudprober.probes.ProbeDef.ip_version:type_name -> cloudprober.probes.ProbeDef.IPVersion
+	3,  // 8: cloudprober.probes.ProbeDef.ip_version_mode:type_name -> cloudprober.probes.ProbeDef.IPVersionMode
+	6,  // 9: cloudprober.probes.ProbeDef.additional_label:type_name -> cloudprober.probes.AdditionalLabel
+	11, // 10: cloudprober.probes.ProbeDef.failure_debounce:type_name -> cloudprober.probes.ProbeDef.FailureDebounce
+	7,  // 11: cloudprober.probes.ProbeDef.alerting:type_name -> cloudprober.probes.AlertingConf
+	15, // 12: cloudprober.probes.ProbeDef.ping_probe:type_name -> cloudprober.probes.ping.ProbeConf
+	16, // 13: cloudprober.probes.ProbeDef.http_probe:type_name -> cloudprober.probes.http.ProbeConf
+	17, // 14: cloudprober.probes.ProbeDef.dns_probe:type_name -> cloudprober.probes.dns.ProbeConf
+	18, // 15: cloudprober.probes.ProbeDef.external_probe:type_name -> cloudprober.probes.external.ProbeConf
+	19, // 16: cloudprober.probes.ProbeDef.udp_probe:type_name -> cloudprober.probes.udp.ProbeConf
+	20, // 17: cloudprober.probes.ProbeDef.udp_listener_probe:type_name -> cloudprober.probes.udplistener.ProbeConf
+	21, // 18: cloudprober.probes.ProbeDef.grpc_probe:type_name -> cloudprober.probes.grpc.ProbeConf
+	22, // 19: cloudprober.probes.ProbeDef.tcp_probe:type_name -> cloudprober.probes.tcp.ProbeConf
+	23, // 20: cloudprober.probes.ProbeDef.tls_probe:type_name -> cloudprober.probes.tls.ProbeConf
+	24, // 21: cloudprober.probes.ProbeDef.ntp_probe:type_name -> cloudprober.probes.ntp.ProbeConf
+	25, // 22: cloudprober.probes.ProbeDef.websocket_probe:type_name -> cloudprober.probes.websocket.ProbeConf
+	26, // 23: cloudprober.probes.ProbeDef.smtp_probe:type_name -> cloudprober.probes.smtp.ProbeConf
+	27, // 24: cloudprober.probes.ProbeDef.ftp_probe:type_name -> cloudprober.probes.ftp.ProbeConf
+	28, // 25: cloudprober.probes.ProbeDef.ldap_probe:type_name -> cloudprober.probes.ldap.ProbeConf
+	29, // 26: cloudprober.probes.ProbeDef.mqtt_probe:type_name -> cloudprober.probes.mqtt.ProbeConf
+	30, // 27: cloudprober.probes.ProbeDef.snmp_probe:type_name -> cloudprober.probes.snmp.ProbeConf
+	31, // 28: cloudprober.probes.ProbeDef.traceroute_probe:type_name -> cloudprober.probes.traceroute.ProbeConf
+	8,  // 29: cloudprober.probes.ProbeDef.debug_options:type_name -> cloudprober.probes.DebugOptions
+	4,  // 30: cloudprober.probes.ProbeDef.Schedule.mode:type_name -> cloudprober.probes.ProbeDef.Schedule.Mode
+	31, // [31:31] is the sub-list for method output_type
+	31, // [31:31] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_probes_proto_config_proto_init() }
@@ -954,6 +2034,18 @@ func file_github_com_cloudprober_cloudprober_probes_proto_config_proto_init() {
 			}
 		}
 		file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AlertingConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DebugOptions); i {
 			case 0:
 				return &v.state
@@ -965,6 +2057,42 @@ func file_github_com_cloudprober_cloudprober_probes_proto_config_proto_init() {
 				return nil
 			}
 		}
+		file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeDef_Schedule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeDef_SLO); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeDef_FailureDebounce); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_github_com_cloudprober_cloudprober_probes_proto_config_proto_msgTypes[0].OneofWrappers = []interface{}{
 		(*ProbeDef_SourceIp)(nil),
@@ -976,6 +2104,16 @@ func file_github_com_cloudprober_cloudprober_probes_proto_config_proto_init() {
 		(*ProbeDef_UdpProbe)(nil),
 		(*ProbeDef_UdpListenerProbe)(nil),
 		(*ProbeDef_GrpcProbe)(nil),
+		(*ProbeDef_TcpProbe)(nil),
+		(*ProbeDef_TlsProbe)(nil),
+		(*ProbeDef_NtpProbe)(nil),
+		(*ProbeDef_WebsocketProbe)(nil),
+		(*ProbeDef_SmtpProbe)(nil),
+		(*ProbeDef_FtpProbe)(nil),
+		(*ProbeDef_LdapProbe)(nil),
+		(*ProbeDef_MqttProbe)(nil),
+		(*ProbeDef_SnmpProbe)(nil),
+		(*ProbeDef_TracerouteProbe)(nil),
 		(*ProbeDef_UserDefinedProbe)(nil),
 	}
 	type x struct{}
@@ -983,8 +2121,8 @@ func file_github_com_cloudprober_cloudprober_probes_proto_config_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_proto_config_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   3,
+			NumEnums:      5,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,288 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/smtp/proto/config.proto
+
+package proto
+
+import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Port to connect to. If not specified, target's port is used, and if the
+	// target doesn't have a port either, 25 is used.
+	Port *int32 `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	// Hostname to announce in the EHLO command.
+	EhloName *string `protobuf:"bytes,2,opt,name=ehlo_name,json=ehloName,def=localhost" json:"ehlo_name,omitempty"`
+	// Upgrade the connection using STARTTLS after EHLO. If enabled, probe runs
+	// fail if the server doesn't advertise STARTTLS.
+	Starttls *bool `protobuf:"varint,3,opt,name=starttls,def=1" json:"starttls,omitempty"`
+	// TLS config for STARTTLS. If server_name is not set, target's name is
+	// used.
+	TlsConfig *proto.TLSConfig `protobuf:"bytes,4,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	Auth      *ProbeConf_Auth  `protobuf:"bytes,5,opt,name=auth" json:"auth,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_EhloName = string("localhost")
+	Default_ProbeConf_Starttls = bool(true)
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetEhloName() string {
+	if x != nil && x.EhloName != nil {
+		return *x.EhloName
+	}
+	return Default_ProbeConf_EhloName
+}
+
+func (x *ProbeConf) GetStarttls() bool {
+	if x != nil && x.Starttls != nil {
+		return *x.Starttls
+	}
+	return Default_ProbeConf_Starttls
+}
+
+func (x *ProbeConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetAuth() *ProbeConf_Auth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
+// Credentials to authenticate with (AUTH PLAIN), after STARTTLS. Requires
+// starttls, as credentials are not sent over unencrypted connections.
+type ProbeConf_Auth struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Username *string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	Password *string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
+}
+
+func (x *ProbeConf_Auth) Reset() {
+	*x = ProbeConf_Auth{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf_Auth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf_Auth) ProtoMessage() {}
+
+func (x *ProbeConf_Auth) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf_Auth.ProtoReflect.Descriptor instead.
+func (*ProbeConf_Auth) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *ProbeConf_Auth) GetUsername() string {
+	if x != nil && x.Username != nil {
+		return *x.Username
+	}
+	return ""
+}
+
+func (x *ProbeConf_Auth) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+var File_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73, 0x6d, 0x74, 0x70,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x17, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6d, 0x74, 0x70, 0x1a, 0x46, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0xa7, 0x02, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
+	0x6e, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x26, 0x0a, 0x09, 0x65, 0x68, 0x6c, 0x6f, 0x5f, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x09, 0x6c, 0x6f, 0x63, 0x61, 0x6c,
+	0x68, 0x6f, 0x73, 0x74, 0x52, 0x08, 0x65, 0x68, 0x6c, 0x6f, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x20,
+	0x0a, 0x08, 0x73, 0x74, 0x61, 0x72, 0x74, 0x74, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08,
+	0x3a, 0x04, 0x74, 0x72, 0x75, 0x65, 0x52, 0x08, 0x73, 0x74, 0x61, 0x72, 0x74, 0x74, 0x6c, 0x73,
+	0x12, 0x3f, 0x0a, 0x0a, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c, 0x53,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x12, 0x3b, 0x0a, 0x04, 0x61, 0x75, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6d, 0x74, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x52, 0x04, 0x61, 0x75, 0x74, 0x68, 0x1a, 0x3e,
+	0x0a, 0x04, 0x41, 0x75, 0x74, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x42, 0x36,
+	0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73, 0x6d, 0x74, 0x70,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_goTypes = []interface{}{
+	(*ProbeConf)(nil),       // 0: cloudprober.probes.smtp.ProbeConf
+	(*ProbeConf_Auth)(nil),  // 1: cloudprober.probes.smtp.ProbeConf.Auth
+	(*proto.TLSConfig)(nil), // 2: cloudprober.tlsconfig.TLSConfig
+}
+var file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_depIdxs = []int32{
+	2, // 0: cloudprober.probes.smtp.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	1, // 1: cloudprober.probes.smtp.ProbeConf.auth:type_name -> cloudprober.probes.smtp.ProbeConf.Auth
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf_Auth); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_depIdxs,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_smtp_proto_config_proto_depIdxs = nil
+}
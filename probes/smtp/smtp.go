@@ -0,0 +1,383 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package smtp implements an SMTP prober. It connects to the targets, waits for
+the server greeting, sends EHLO and, if configured, upgrades the connection
+using STARTTLS and authenticates, before quitting the session. No mail is
+sent. It reports statistics on probe runs, successful runs and latency, along
+with the latency of each phase of the session, and the reason of the failures.
+
+Probes for all targets run in parallel.
+*/
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/smtp/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+const defaultPort = 25
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonConnect    = "connect"
+	reasonGreeting   = "greeting"
+	reasonEHLO       = "ehlo"
+	reasonNoSTARTTLS = "starttls_unsupported"
+	reasonSTARTTLS   = "starttls"
+	reasonAuth       = "auth"
+)
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets   []endpoint.Endpoint
+	dialF     func(context.Context, string, string) (net.Conn, error)
+	tlsConfig *tls.Config // nil if STARTTLS is disabled.
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	latencyMetricName string
+
+	// Per-phase latencies. starttlsLatency and authLatency are exported only
+	// if the respective phase is configured.
+	connectLatency  metrics.Value
+	ehloLatency     metrics.Value
+	starttlsLatency metrics.Value
+	authLatency     metrics.Value
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures).
+		AddMetric("connect_latency", prr.connectLatency).
+		AddMetric("ehlo_latency", prr.ehloLatency)
+	if prr.starttlsLatency != nil {
+		em.AddMetric("starttls_latency", prr.starttlsLatency)
+	}
+	if prr.authLatency != nil {
+		em.AddMetric("auth_latency", prr.authLatency)
+	}
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no smtp config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("smtp_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if p.c.GetAuth() != nil && !p.c.GetStarttls() {
+		return fmt.Errorf("smtp_probe(%s): auth requires starttls", name)
+	}
+
+	if p.c.GetStarttls() {
+		p.tlsConfig = &tls.Config{}
+		if p.c.GetTlsConfig() != nil {
+			if err := tlsconfig.UpdateTLSConfig(p.tlsConfig, p.c.GetTlsConfig(), false); err != nil {
+				return fmt.Errorf("smtp_probe(%s): error configuring TLS: %v", name, err)
+			}
+		}
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+		connectLatency:    p.newLatencyValue(),
+		ehloLatency:       p.newLatencyValue(),
+	}
+
+	if p.c.GetStarttls() {
+		result.starttlsLatency = p.newLatencyValue()
+	}
+	if p.c.GetAuth() != nil {
+		result.authLatency = p.newLatencyValue()
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// session runs the SMTP session with the target, recording the latency of
+// each phase. It returns the failure reason, along with the error, if it
+// fails.
+func (p *Probe) session(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) (string, error) {
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(target.Name, strconv.Itoa(port))
+
+	record := func(v metrics.Value, start time.Time) {
+		v.AddFloat64(time.Since(start).Seconds() / p.opts.LatencyUnit.Seconds())
+	}
+
+	// Connect latency covers the TCP connection and the server greeting.
+	start := time.Now()
+	conn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		return reasonConnect, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	options.RecordIPVersionUsed(result.ipVersionUsed, conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, target.Name)
+	if err != nil {
+		return reasonGreeting, fmt.Errorf("error reading greeting from %s: %w", addr, err)
+	}
+	record(result.connectLatency, start)
+
+	start = time.Now()
+	if err := client.Hello(p.c.GetEhloName()); err != nil {
+		return reasonEHLO, fmt.Errorf("EHLO failed: %w", err)
+	}
+	record(result.ehloLatency, start)
+
+	if p.tlsConfig != nil {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return reasonNoSTARTTLS, errors.New("server doesn't support STARTTLS")
+		}
+
+		tlsConfig := p.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = target.Name
+		}
+		start = time.Now()
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return reasonSTARTTLS, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+		record(result.starttlsLatency, start)
+	}
+
+	if auth := p.c.GetAuth(); auth != nil {
+		start = time.Now()
+		if err := client.Auth(smtp.PlainAuth("", auth.GetUsername(), auth.GetPassword(), target.Name)); err != nil {
+			return reasonAuth, fmt.Errorf("AUTH failed: %w", err)
+		}
+		record(result.authLatency, start)
+	}
+
+	// Session has completed at this point, we don't fail the probe run if
+	// the server doesn't acknowledge QUIT.
+	if err := client.Quit(); err != nil {
+		p.l.Debugf("Target(%s): QUIT failed: %v", target.Name, err)
+	}
+	return "", nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	start := time.Now()
+	if reason, err := p.session(ctx, target, result); err != nil {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reason)
+		return
+	}
+
+	latency := time.Since(start)
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "smtp", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	configpb "github.com/cloudprober/cloudprober/probes/smtp/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+// testServer is a minimal SMTP server, supporting EHLO, STARTTLS, AUTH PLAIN
+// (for user:pass) and QUIT.
+type testServer struct {
+	ln         net.Listener
+	tlsConfig  *tls.Config
+	noSTARTTLS bool // Don't advertise STARTTLS.
+	greeting   string
+}
+
+// newTestServer starts a test server. Default greeting is used if greeting is
+// empty.
+func newTestServer(t *testing.T, greeting string, noSTARTTLS bool) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	// Borrow httptest's certificate (valid for 127.0.0.1) for STARTTLS.
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsConfig := &tls.Config{Certificates: hs.TLS.Certificates}
+	hs.Close()
+
+	if greeting == "" {
+		greeting = "220 test ESMTP"
+	}
+	ts := &testServer{ln: ln, tlsConfig: tlsConfig, greeting: greeting, noSTARTTLS: noSTARTTLS}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go ts.serve(conn)
+		}
+	}()
+	return ts
+}
+
+func (ts *testServer) target() endpoint.Endpoint {
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: ts.ln.Addr().(*net.TCPAddr).Port}
+}
+
+func (ts *testServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("%s", ts.greeting)
+	if !strings.HasPrefix(ts.greeting, "220") {
+		return
+	}
+
+	isTLS := false
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+
+		switch cmd {
+		case "EHLO":
+			tc.PrintfLine("250-test")
+			if !ts.noSTARTTLS && !isTLS {
+				tc.PrintfLine("250-STARTTLS")
+			}
+			tc.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tc.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, ts.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, isTLS = tlsConn, true
+			tc = textproto.NewConn(conn)
+		case "AUTH":
+			want := base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass"))
+			if line == "AUTH PLAIN "+want {
+				tc.PrintfLine("235 Authentication successful")
+			} else {
+				tc.PrintfLine("535 Authentication failed")
+			}
+		case "QUIT":
+			tc.PrintfLine("221 Bye")
+			return
+		default:
+			tc.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "smtp_test", testutils.Options("127.0.0.1", time.Second, c))
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	insecureTLS := &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)}
+	auth := func(password string) *configpb.ProbeConf_Auth {
+		return &configpb.ProbeConf_Auth{Username: proto.String("user"), Password: proto.String(password)}
+	}
+
+	for _, test := range []struct {
+		desc        string
+		conf        *configpb.ProbeConf
+		noSTARTTLS  bool
+		greeting    string
+		wantReason  string
+		wantMetrics []string
+	}{
+		{
+			desc:        "no_starttls",
+			conf:        &configpb.ProbeConf{Starttls: proto.Bool(false)},
+			wantMetrics: []string{"connect_latency", "ehlo_latency"},
+		},
+		{
+			desc:        "starttls",
+			conf:        &configpb.ProbeConf{TlsConfig: insecureTLS},
+			wantMetrics: []string{"connect_latency", "ehlo_latency", "starttls_latency"},
+		},
+		{
+			desc:        "starttls_auth",
+			conf:        &configpb.ProbeConf{TlsConfig: insecureTLS, Auth: auth("pass")},
+			wantMetrics: []string{"connect_latency", "ehlo_latency", "starttls_latency", "auth_latency"},
+		},
+		{
+			desc:       "auth_failure",
+			conf:       &configpb.ProbeConf{TlsConfig: insecureTLS, Auth: auth("wrong")},
+			wantReason: reasonAuth,
+		},
+		{
+			desc:       "starttls_cert_failure",
+			conf:       &configpb.ProbeConf{},
+			wantReason: reasonSTARTTLS,
+		},
+		{
+			desc:       "starttls_unsupported",
+			conf:       &configpb.ProbeConf{TlsConfig: insecureTLS},
+			noSTARTTLS: true,
+			wantReason: reasonNoSTARTTLS,
+		},
+		{
+			desc:       "greeting_rejected",
+			conf:       &configpb.ProbeConf{Starttls: proto.Bool(false)},
+			greeting:   "554 No SMTP service here",
+			wantReason: reasonGreeting,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ts := newTestServer(t, test.greeting, test.noSTARTTLS)
+			target := ts.target()
+
+			p := testProbe(t, test.conf)
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantReason != "" {
+				return
+			}
+
+			em := result.Metrics()
+			for _, name := range []string{"connect_latency", "ehlo_latency", "starttls_latency", "auth_latency"} {
+				want := false
+				for _, n := range test.wantMetrics {
+					want = want || n == name
+				}
+				if (em.Metric(name) != nil) != want {
+					t.Errorf("Metric %s exported=%v, want=%v: %s", name, em.Metric(name) != nil, want, em.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConnectFailure(t *testing.T) {
+	ts := newTestServer(t, "", false)
+	target := ts.target()
+	ts.ln.Close()
+
+	p := testProbe(t, &configpb.ProbeConf{Starttls: proto.Bool(false)})
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(context.Background(), target, &result)
+
+	if got := result.failures.GetKey(reasonConnect).Int64(); got != 1 {
+		t.Errorf("Got failures=%s, want 1 failure with reason: %s", result.failures.String(), reasonConnect)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	// Server that accepts connections but never sends the greeting.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go bufio.NewReader(conn).ReadString('\n')
+		}
+	}()
+	target := endpoint.Endpoint{Name: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port}
+
+	p := testProbe(t, &configpb.ProbeConf{Starttls: proto.Bool(false)})
+	p.opts.Timeout = 100 * time.Millisecond
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(context.Background(), target, &result)
+
+	if result.timeouts.Int64() != 1 || result.failures.GetKey(reasonGreeting).Int64() != 1 {
+		t.Errorf("Got timeouts=%d, failures=%s, want 1 timeout with reason: %s", result.timeouts.Int64(), result.failures.String(), reasonGreeting)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{
+			Desc: "auth_without_starttls",
+			Conf: &configpb.ProbeConf{
+				Starttls: proto.Bool(false),
+				Auth:     &configpb.ProbeConf_Auth{Username: proto.String("user"), Password: proto.String("pass")},
+			},
+		},
+	})
+}
@@ -0,0 +1,495 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of the SNMPv2c (RFC 1901) and SNMPv3
+// (RFC 3412, RFC 3414) messages, and their BER encoding, used by the probe.
+
+// BER tags.
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+)
+
+// SNMP application types (RFC 2578) and exceptions (RFC 3416).
+const (
+	tagIPAddress = 0x40
+	tagCounter32 = 0x41
+	tagGauge32   = 0x42
+	tagTimeTicks = 0x43
+	tagOpaque    = 0x44
+	tagCounter64 = 0x46
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+)
+
+// PDU tags.
+const (
+	tagGetRequest  = 0xa0
+	tagGetResponse = 0xa2
+	tagReport      = 0xa8
+)
+
+// SNMP message versions.
+const (
+	versionV2c = 1
+	versionV3  = 3
+)
+
+// SNMPv3 message flags and security model.
+const (
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+
+	securityModelUSM = 3
+)
+
+// maxMessageSize is the maximum message size we advertise, and accept.
+const maxMessageSize = 65507
+
+// berTLV encodes a BER element with the given tag and content.
+func berTLV(tag byte, content []byte) []byte {
+	b := []byte{tag}
+	n := len(content)
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	default:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	}
+	return append(b, content...)
+}
+
+func berConstructed(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, c := range children {
+		content = append(content, c...)
+	}
+	return berTLV(tag, content)
+}
+
+func berInt(tag byte, v int64) []byte {
+	// Minimal two's complement encoding.
+	b := []byte{byte(v)}
+	for (v >= 0x80 || v < -0x80) && len(b) < 8 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(tag, b)
+}
+
+// berUint encodes unsigned integers, e.g. Counter64 values.
+func berUint(tag byte, v uint64) []byte {
+	b := []byte{byte(v)}
+	for v >= 0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(tag, b)
+}
+
+func berBytes(tag byte, b []byte) []byte {
+	return berTLV(tag, b)
+}
+
+// parseTLV parses a BER element from b, and returns its tag and content, along
+// with the rest of b.
+func parseTLV(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	tag, n, b := b[0], int(b[1]), b[2:]
+	if n&0x80 != 0 {
+		numBytes := n & 0x7f
+		if numBytes == 0 || numBytes > 4 || len(b) < numBytes {
+			return 0, nil, nil, errors.New("invalid BER length")
+		}
+		n = 0
+		for _, c := range b[:numBytes] {
+			n = n<<8 | int(c)
+		}
+		b = b[numBytes:]
+	}
+	if n < 0 || len(b) < n {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	return tag, b[:n], b[n:], nil
+}
+
+// parseExpected parses a BER element from b, and verifies its tag.
+func parseExpected(b []byte, tag byte, what string) ([]byte, []byte, error) {
+	t, content, rest, err := parseTLV(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s: %v", what, err)
+	}
+	if t != tag {
+		return nil, nil, fmt.Errorf("unexpected tag for %s: 0x%x, want: 0x%x", what, t, tag)
+	}
+	return content, rest, nil
+}
+
+func parseInt(content []byte) int64 {
+	var v int64
+	for i, c := range content {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func parseUint(content []byte) uint64 {
+	var v uint64
+	for _, c := range content {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// encodeOID encodes an OID in the dotted notation, e.g. "1.3.6.1.2.1.1.3.0",
+// to the content of a BER OID element.
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID: %s", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID: %s", oid)
+		}
+		arcs[i] = v
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID: %s", oid)
+	}
+
+	var b []byte
+	appendArc := func(v uint64) {
+		enc := []byte{byte(v & 0x7f)}
+		for v >>= 7; v > 0; v >>= 7 {
+			enc = append([]byte{byte(v&0x7f) | 0x80}, enc...)
+		}
+		b = append(b, enc...)
+	}
+	appendArc(arcs[0]*40 + arcs[1])
+	for _, v := range arcs[2:] {
+		appendArc(v)
+	}
+	return b, nil
+}
+
+// oidString returns the dotted notation of an encoded OID.
+func oidString(content []byte) string {
+	var arcs []string
+	var v uint64
+	for _, c := range content {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if len(arcs) == 0 {
+			first := v / 40
+			if first > 2 {
+				first = 2
+			}
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(v-first*40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
+
+// varBind is a variable binding in a PDU.
+type varBind struct {
+	oid   string
+	tag   byte
+	value []byte
+}
+
+// pdu is an SNMP protocol data unit.
+type pdu struct {
+	tag         byte
+	requestID   int64
+	errorStatus int64
+	errorIndex  int64
+	varBinds    []varBind
+}
+
+// getRequest returns a GetRequest PDU for the given encoded OIDs.
+func getRequest(requestID int64, oids [][]byte) []byte {
+	var varBinds [][]byte
+	for _, oid := range oids {
+		varBinds = append(varBinds, berConstructed(tagSequence, berBytes(tagOID, oid), berTLV(tagNull, nil)))
+	}
+	return berConstructed(tagGetRequest,
+		berInt(tagInteger, requestID),
+		berInt(tagInteger, 0),
+		berInt(tagInteger, 0),
+		berConstructed(tagSequence, varBinds...))
+}
+
+func parsePDU(b []byte) (*pdu, error) {
+	tag, content, _, err := parseTLV(b)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PDU: %v", err)
+	}
+	p := &pdu{tag: tag}
+
+	var fields [3]int64
+	for i := range fields {
+		var v []byte
+		if v, content, err = parseExpected(content, tagInteger, "PDU header"); err != nil {
+			return nil, err
+		}
+		fields[i] = parseInt(v)
+	}
+	p.requestID, p.errorStatus, p.errorIndex = fields[0], fields[1], fields[2]
+
+	varBinds, _, err := parseExpected(content, tagSequence, "variable bindings")
+	if err != nil {
+		return nil, err
+	}
+	for len(varBinds) > 0 {
+		var vb, oid []byte
+		if vb, varBinds, err = parseExpected(varBinds, tagSequence, "variable binding"); err != nil {
+			return nil, err
+		}
+		if oid, vb, err = parseExpected(vb, tagOID, "variable binding"); err != nil {
+			return nil, err
+		}
+		tag, value, _, err := parseTLV(vb)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing variable binding value: %v", err)
+		}
+		p.varBinds = append(p.varBinds, varBind{oid: oidString(oid), tag: tag, value: value})
+	}
+	return p, nil
+}
+
+// v2cMessage returns an SNMPv2c message.
+func v2cMessage(community string, pdu []byte) []byte {
+	return berConstructed(tagSequence,
+		berInt(tagInteger, versionV2c),
+		berBytes(tagOctetString, []byte(community)),
+		pdu)
+}
+
+// parseVersion returns the version of an SNMP message, and the rest of the
+// message's content.
+func parseVersion(msg []byte) (int64, []byte, error) {
+	content, _, err := parseExpected(msg, tagSequence, "message")
+	if err != nil {
+		return 0, nil, err
+	}
+	v, rest, err := parseExpected(content, tagInteger, "message version")
+	if err != nil {
+		return 0, nil, err
+	}
+	return parseInt(v), rest, nil
+}
+
+// parseV2cMessage returns the community and the PDU of an SNMPv2c message.
+func parseV2cMessage(msg []byte) (string, *pdu, error) {
+	version, rest, err := parseVersion(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	if version != versionV2c {
+		return "", nil, fmt.Errorf("unexpected message version: %d", version)
+	}
+	community, rest, err := parseExpected(rest, tagOctetString, "community")
+	if err != nil {
+		return "", nil, err
+	}
+	p, err := parsePDU(rest)
+	return string(community), p, err
+}
+
+// usmParams are the user-based security model's parameters of an SNMPv3
+// message (RFC 3414).
+type usmParams struct {
+	engineID   []byte
+	engineBoot int64
+	engineTime int64
+	username   string
+	authParams []byte
+	privParams []byte
+}
+
+// v3Message is an SNMPv3 message. data is the encoded scoped PDU, or the
+// encrypted scoped PDU if privacy is used.
+type v3Message struct {
+	msgID int64
+	flags byte
+	usm   usmParams
+	data  []byte
+
+	// authOffset is the offset of the authentication parameters in the
+	// encoded message.
+	authOffset int
+}
+
+// scopedPDU returns an SNMPv3 scoped PDU.
+func scopedPDU(contextEngineID []byte, contextName string, pdu []byte) []byte {
+	return berConstructed(tagSequence,
+		berBytes(tagOctetString, contextEngineID),
+		berBytes(tagOctetString, []byte(contextName)),
+		pdu)
+}
+
+// parseScopedPDU returns the PDU of a scoped PDU. Encrypted scoped PDUs may
+// have trailing padding, which is ignored.
+func parseScopedPDU(b []byte) (*pdu, error) {
+	content, _, err := parseExpected(b, tagSequence, "scoped PDU")
+	if err != nil {
+		return nil, err
+	}
+	if _, content, err = parseExpected(content, tagOctetString, "context engine ID"); err != nil {
+		return nil, err
+	}
+	if _, content, err = parseExpected(content, tagOctetString, "context name"); err != nil {
+		return nil, err
+	}
+	return parsePDU(content)
+}
+
+// encode encodes the message, with the authentication parameters set to
+// msg.usm.authParams, and sets msg.authOffset.
+func (msg *v3Message) encode() []byte {
+	usm := berConstructed(tagSequence,
+		berBytes(tagOctetString, msg.usm.engineID),
+		berInt(tagInteger, msg.usm.engineBoot),
+		berInt(tagInteger, msg.usm.engineTime),
+		berBytes(tagOctetString, []byte(msg.usm.username)),
+		berBytes(tagOctetString, msg.usm.authParams),
+		berBytes(tagOctetString, msg.usm.privParams))
+	securityParams := berBytes(tagOctetString, usm)
+
+	data := msg.data
+	if msg.flags&flagPriv != 0 {
+		data = berBytes(tagOctetString, msg.data)
+	}
+
+	b := berConstructed(tagSequence,
+		berInt(tagInteger, versionV3),
+		berConstructed(tagSequence,
+			berInt(tagInteger, msg.msgID),
+			berInt(tagInteger, maxMessageSize),
+			berBytes(tagOctetString, []byte{msg.flags}),
+			berInt(tagInteger, securityModelUSM)),
+		securityParams,
+		data)
+
+	// Authentication parameters are followed by the privacy parameters in
+	// the security parameters, which are followed by the data.
+	privParamsLen := len(berBytes(tagOctetString, msg.usm.privParams))
+	msg.authOffset = len(b) - len(data) - privParamsLen - len(msg.usm.authParams)
+	return b
+}
+
+// parseV3Message parses an SNMPv3 message. For encrypted messages, data is the
+// encrypted scoped PDU.
+func parseV3Message(b []byte) (*v3Message, error) {
+	version, rest, err := parseVersion(b)
+	if err != nil {
+		return nil, err
+	}
+	if version != versionV3 {
+		return nil, fmt.Errorf("unexpected message version: %d", version)
+	}
+
+	header, rest, err := parseExpected(rest, tagSequence, "message header")
+	if err != nil {
+		return nil, err
+	}
+	msg := &v3Message{}
+	var v []byte
+	if v, header, err = parseExpected(header, tagInteger, "message ID"); err != nil {
+		return nil, err
+	}
+	msg.msgID = parseInt(v)
+	if _, header, err = parseExpected(header, tagInteger, "message max size"); err != nil {
+		return nil, err
+	}
+	if v, _, err = parseExpected(header, tagOctetString, "message flags"); err != nil {
+		return nil, err
+	}
+	if len(v) != 1 {
+		return nil, fmt.Errorf("invalid message flags: %x", v)
+	}
+	msg.flags = v[0]
+
+	securityParams, data, err := parseExpected(rest, tagOctetString, "security parameters")
+	if err != nil {
+		return nil, err
+	}
+	usm, _, err := parseExpected(securityParams, tagSequence, "security parameters")
+	if err != nil {
+		return nil, err
+	}
+	if msg.usm.engineID, usm, err = parseExpected(usm, tagOctetString, "engine ID"); err != nil {
+		return nil, err
+	}
+	if v, usm, err = parseExpected(usm, tagInteger, "engine boots"); err != nil {
+		return nil, err
+	}
+	msg.usm.engineBoot = parseInt(v)
+	if v, usm, err = parseExpected(usm, tagInteger, "engine time"); err != nil {
+		return nil, err
+	}
+	msg.usm.engineTime = parseInt(v)
+	if v, usm, err = parseExpected(usm, tagOctetString, "user name"); err != nil {
+		return nil, err
+	}
+	msg.usm.username = string(v)
+	if msg.usm.authParams, usm, err = parseExpected(usm, tagOctetString, "authentication parameters"); err != nil {
+		return nil, err
+	}
+	// All slices share the message's backing array, starting at different
+	// offsets.
+	msg.authOffset = cap(b) - cap(msg.usm.authParams)
+	if msg.usm.privParams, _, err = parseExpected(usm, tagOctetString, "privacy parameters"); err != nil {
+		return nil, err
+	}
+
+	if msg.flags&flagPriv != 0 {
+		if msg.data, _, err = parseExpected(data, tagOctetString, "encrypted scoped PDU"); err != nil {
+			return nil, err
+		}
+	} else {
+		msg.data = data
+	}
+	return msg, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBERInt(t *testing.T) {
+	for _, test := range []struct {
+		v    int64
+		want string
+	}{
+		{v: 0, want: "020100"},
+		{v: 127, want: "02017f"},
+		{v: 128, want: "02020080"},
+		{v: 256, want: "02020100"},
+		{v: -1, want: "0201ff"},
+		{v: -129, want: "0202ff7f"},
+		{v: 2147483647, want: "02047fffffff"},
+	} {
+		b := berInt(tagInteger, test.v)
+		if got := hex.EncodeToString(b); got != test.want {
+			t.Errorf("berInt(%d)=%s, want=%s", test.v, got, test.want)
+		}
+		if _, content, _, _ := parseTLV(b); parseInt(content) != test.v {
+			t.Errorf("parseInt(%x)=%d, want=%d", content, parseInt(content), test.v)
+		}
+	}
+
+	b := berUint(tagCounter64, 1<<63)
+	if got, want := hex.EncodeToString(b), "4609008000000000000000"; got != want {
+		t.Errorf("berUint(1<<63)=%s, want=%s", got, want)
+	}
+	if _, content, _, _ := parseTLV(b); parseUint(content) != 1<<63 {
+		t.Errorf("parseUint(%x)=%d, want=%d", content, parseUint(content), uint64(1<<63))
+	}
+}
+
+func TestOID(t *testing.T) {
+	for _, test := range []struct {
+		oid     string
+		want    string
+		wantErr bool
+	}{
+		{oid: "1.3.6.1.2.1.1.3.0", want: "2b06010201010300"},
+		{oid: ".1.3.6.1.4.1.2021.10.1.3.1", want: "2b060104018f650a010301"},
+		{oid: "2.999.3", want: "883703"},
+		{oid: "1", wantErr: true},
+		{oid: "1.40", wantErr: true},
+		{oid: "3.1", wantErr: true},
+		{oid: "1.3.a", wantErr: true},
+		{oid: "1.3..6", wantErr: true},
+	} {
+		t.Run(test.oid, func(t *testing.T) {
+			b, err := encodeOID(test.oid)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("encodeOID(%s) error: %v, want error: %v", test.oid, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := hex.EncodeToString(b); got != test.want {
+				t.Errorf("encodeOID(%s)=%s, want=%s", test.oid, got, test.want)
+			}
+			if got, want := oidString(b), test.oid[len(test.oid)-len(oidString(b)):]; got != want {
+				t.Errorf("oidString(%x)=%s, want=%s", b, got, want)
+			}
+		})
+	}
+}
+
+func TestV3MessageAuthOffset(t *testing.T) {
+	for _, priv := range []bool{false, true} {
+		msg := &v3Message{
+			msgID: 42,
+			flags: flagAuth | flagReportable,
+			usm: usmParams{
+				engineID:   []byte("engine"),
+				engineBoot: 1,
+				engineTime: 300,
+				username:   "alice",
+				authParams: bytes.Repeat([]byte{0xaa}, authParamsLen),
+			},
+			data: bytes.Repeat([]byte{0x30}, 200),
+		}
+		if priv {
+			msg.flags |= flagPriv
+			msg.usm.privParams = bytes.Repeat([]byte{0xbb}, 8)
+		}
+
+		b := msg.encode()
+		if got := b[msg.authOffset : msg.authOffset+authParamsLen]; !bytes.Equal(got, msg.usm.authParams) {
+			t.Errorf("priv=%v: got %x at the auth offset, want: %x", priv, got, msg.usm.authParams)
+		}
+
+		got, err := parseV3Message(b)
+		if err != nil {
+			t.Fatalf("priv=%v: error parsing the message: %v", priv, err)
+		}
+		if got.authOffset != msg.authOffset || got.msgID != msg.msgID || got.usm.username != "alice" || got.usm.engineTime != 300 {
+			t.Errorf("priv=%v: got message: %+v, want: %+v", priv, got, msg)
+		}
+		if !bytes.Equal(got.data, msg.data) {
+			t.Errorf("priv=%v: got data: %x, want: %x", priv, got.data, msg.data)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/snmp/proto/config.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf_Version int32
+
+const (
+	ProbeConf_V2C ProbeConf_Version = 0
+	ProbeConf_V3  ProbeConf_Version = 1
+)
+
+// Enum value maps for ProbeConf_Version.
+var (
+	ProbeConf_Version_name = map[int32]string{
+		0: "V2C",
+		1: "V3",
+	}
+	ProbeConf_Version_value = map[string]int32{
+		"V2C": 0,
+		"V3":  1,
+	}
+)
+
+func (x ProbeConf_Version) Enum() *ProbeConf_Version {
+	p := new(ProbeConf_Version)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Version) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Version) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_Version) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_Version) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Version) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Version(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Version.Descriptor instead.
+func (ProbeConf_Version) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf_USM_AuthProtocol int32
+
+const (
+	ProbeConf_USM_NO_AUTH ProbeConf_USM_AuthProtocol = 0
+	ProbeConf_USM_MD5     ProbeConf_USM_AuthProtocol = 1
+	ProbeConf_USM_SHA     ProbeConf_USM_AuthProtocol = 2
+)
+
+// Enum value maps for ProbeConf_USM_AuthProtocol.
+var (
+	ProbeConf_USM_AuthProtocol_name = map[int32]string{
+		0: "NO_AUTH",
+		1: "MD5",
+		2: "SHA",
+	}
+	ProbeConf_USM_AuthProtocol_value = map[string]int32{
+		"NO_AUTH": 0,
+		"MD5":     1,
+		"SHA":     2,
+	}
+)
+
+func (x ProbeConf_USM_AuthProtocol) Enum() *ProbeConf_USM_AuthProtocol {
+	p := new(ProbeConf_USM_AuthProtocol)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_USM_AuthProtocol) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_USM_AuthProtocol) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeConf_USM_AuthProtocol) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[1]
+}
+
+func (x ProbeConf_USM_AuthProtocol) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_USM_AuthProtocol) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_USM_AuthProtocol(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_USM_AuthProtocol.Descriptor instead.
+func (ProbeConf_USM_AuthProtocol) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0, 0, 0}
+}
+
+// Privacy requires authentication.
+type ProbeConf_USM_PrivProtocol int32
+
+const (
+	ProbeConf_USM_NO_PRIV ProbeConf_USM_PrivProtocol = 0
+	ProbeConf_USM_DES     ProbeConf_USM_PrivProtocol = 1
+	ProbeConf_USM_AES     ProbeConf_USM_PrivProtocol = 2 // AES-128.
+)
+
+// Enum value maps for ProbeConf_USM_PrivProtocol.
+var (
+	ProbeConf_USM_PrivProtocol_name = map[int32]string{
+		0: "NO_PRIV",
+		1: "DES",
+		2: "AES",
+	}
+	ProbeConf_USM_PrivProtocol_value = map[string]int32{
+		"NO_PRIV": 0,
+		"DES":     1,
+		"AES":     2,
+	}
+)
+
+func (x ProbeConf_USM_PrivProtocol) Enum() *ProbeConf_USM_PrivProtocol {
+	p := new(ProbeConf_USM_PrivProtocol)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_USM_PrivProtocol) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_USM_PrivProtocol) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[2].Descriptor()
+}
+
+func (ProbeConf_USM_PrivProtocol) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes[2]
+}
+
+func (x ProbeConf_USM_PrivProtocol) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_USM_PrivProtocol) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_USM_PrivProtocol(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_USM_PrivProtocol.Descriptor instead.
+func (ProbeConf_USM_PrivProtocol) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0, 0, 1}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version *ProbeConf_Version `protobuf:"varint,1,opt,name=version,enum=cloudprober.probes.snmp.ProbeConf_Version,def=0" json:"version,omitempty"`
+	// Port to send the requests to. If not specified, target's port is used,
+	// and if the target doesn't have a port either, 161 is used.
+	Port *int32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	// Community for SNMPv2c.
+	Community *string          `protobuf:"bytes,3,opt,name=community,def=public" json:"community,omitempty"`
+	Usm       *ProbeConf_USM   `protobuf:"bytes,4,opt,name=usm" json:"usm,omitempty"`
+	Oid       []*ProbeConf_OID `protobuf:"bytes,5,rep,name=oid" json:"oid,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Version   = ProbeConf_V2C
+	Default_ProbeConf_Community = string("public")
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetVersion() ProbeConf_Version {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return Default_ProbeConf_Version
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetCommunity() string {
+	if x != nil && x.Community != nil {
+		return *x.Community
+	}
+	return Default_ProbeConf_Community
+}
+
+func (x *ProbeConf) GetUsm() *ProbeConf_USM {
+	if x != nil {
+		return x.Usm
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetOid() []*ProbeConf_OID {
+	if x != nil {
+		return x.Oid
+	}
+	return nil
+}
+
+// User-based security model (USM) parameters for SNMPv3. Required for V3.
+type ProbeConf_USM struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Username     *string                     `protobuf:"bytes,1,req,name=username" json:"username,omitempty"`
+	AuthProtocol *ProbeConf_USM_AuthProtocol `protobuf:"varint,2,opt,name=auth_protocol,json=authProtocol,enum=cloudprober.probes.snmp.ProbeConf_USM_AuthProtocol,def=0" json:"auth_protocol,omitempty"`
+	AuthPassword *string                     `protobuf:"bytes,3,opt,name=auth_password,json=authPassword" json:"auth_password,omitempty"`
+	PrivProtocol *ProbeConf_USM_PrivProtocol `protobuf:"varint,4,opt,name=priv_protocol,json=privProtocol,enum=cloudprober.probes.snmp.ProbeConf_USM_PrivProtocol,def=0" json:"priv_protocol,omitempty"`
+	PrivPassword *string                     `protobuf:"bytes,5,opt,name=priv_password,json=privPassword" json:"priv_password,omitempty"`
+	ContextName  *string                     `protobuf:"bytes,6,opt,name=context_name,json=contextName" json:"context_name,omitempty"`
+}
+
+// Default values for ProbeConf_USM fields.
+const (
+	Default_ProbeConf_USM_AuthProtocol = ProbeConf_USM_NO_AUTH
+	Default_ProbeConf_USM_PrivProtocol = ProbeConf_USM_NO_PRIV
+)
+
+func (x *ProbeConf_USM) Reset() {
+	*x = ProbeConf_USM{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf_USM) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf_USM) ProtoMessage() {}
+
+func (x *ProbeConf_USM) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf_USM.ProtoReflect.Descriptor instead.
+func (*ProbeConf_USM) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *ProbeConf_USM) GetUsername() string {
+	if x != nil && x.Username != nil {
+		return *x.Username
+	}
+	return ""
+}
+
+func (x *ProbeConf_USM) GetAuthProtocol() ProbeConf_USM_AuthProtocol {
+	if x != nil && x.AuthProtocol != nil {
+		return *x.AuthProtocol
+	}
+	return Default_ProbeConf_USM_AuthProtocol
+}
+
+func (x *ProbeConf_USM) GetAuthPassword() string {
+	if x != nil && x.AuthPassword != nil {
+		return *x.AuthPassword
+	}
+	return ""
+}
+
+func (x *ProbeConf_USM) GetPrivProtocol() ProbeConf_USM_PrivProtocol {
+	if x != nil && x.PrivProtocol != nil {
+		return *x.PrivProtocol
+	}
+	return Default_ProbeConf_USM_PrivProtocol
+}
+
+func (x *ProbeConf_USM) GetPrivPassword() string {
+	if x != nil && x.PrivPassword != nil {
+		return *x.PrivPassword
+	}
+	return ""
+}
+
+func (x *ProbeConf_USM) GetContextName() string {
+	if x != nil && x.ContextName != nil {
+		return *x.ContextName
+	}
+	return ""
+}
+
+// OIDs to poll, in a single GET request. Probe runs succeed if the agent
+// returns values for all of them. Values are exported as gauges, named
+// after the OIDs' names. Numeric values (Integer, Counter32, Gauge32,
+// TimeTicks and Counter64) are exported as numbers, and other values as
+// strings. Example:
+//
+//	oid {
+//	  oid: "1.3.6.1.2.1.1.3.0"
+//	  name: "sys_uptime"
+//	}
+type ProbeConf_OID struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Oid  *string `protobuf:"bytes,1,req,name=oid" json:"oid,omitempty"`
+	Name *string `protobuf:"bytes,2,req,name=name" json:"name,omitempty"`
+}
+
+func (x *ProbeConf_OID) Reset() {
+	*x = ProbeConf_OID{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf_OID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf_OID) ProtoMessage() {}
+
+func (x *ProbeConf_OID) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf_OID.ProtoReflect.Descriptor instead.
+func (*ProbeConf_OID) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+func (x *ProbeConf_OID) GetOid() string {
+	if x != nil && x.Oid != nil {
+		return *x.Oid
+	}
+	return ""
+}
+
+func (x *ProbeConf_OID) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+var File_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73, 0x6e, 0x6d, 0x70,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x17, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70, 0x22, 0x82, 0x06, 0x0a,
+	0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x49, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73,
+	0x2e, 0x73, 0x6e, 0x6d, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x3a, 0x03, 0x56, 0x32, 0x43, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x24, 0x0a, 0x09, 0x63, 0x6f, 0x6d,
+	0x6d, 0x75, 0x6e, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x06, 0x70, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x74, 0x79, 0x12,
+	0x38, 0x0a, 0x03, 0x75, 0x73, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66,
+	0x2e, 0x55, 0x53, 0x4d, 0x52, 0x03, 0x75, 0x73, 0x6d, 0x12, 0x38, 0x0a, 0x03, 0x6f, 0x69, 0x64,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70,
+	0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x4f, 0x49, 0x44, 0x52, 0x03,
+	0x6f, 0x69, 0x64, 0x1a, 0xb2, 0x03, 0x0a, 0x03, 0x55, 0x53, 0x4d, 0x12, 0x1a, 0x0a, 0x08, 0x75,
+	0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x08, 0x75,
+	0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x61, 0x0a, 0x0d, 0x61, 0x75, 0x74, 0x68, 0x5f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x33,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f,
+	0x6e, 0x66, 0x2e, 0x55, 0x53, 0x4d, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x50, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x3a, 0x07, 0x4e, 0x4f, 0x5f, 0x41, 0x55, 0x54, 0x48, 0x52, 0x0c, 0x61, 0x75,
+	0x74, 0x68, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x75,
+	0x74, 0x68, 0x5f, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0c, 0x61, 0x75, 0x74, 0x68, 0x50, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x12,
+	0x61, 0x0a, 0x0d, 0x70, 0x72, 0x69, 0x76, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x33, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x73, 0x6e, 0x6d, 0x70,
+	0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x55, 0x53, 0x4d, 0x2e, 0x50,
+	0x72, 0x69, 0x76, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x3a, 0x07, 0x4e, 0x4f, 0x5f,
+	0x50, 0x52, 0x49, 0x56, 0x52, 0x0c, 0x70, 0x72, 0x69, 0x76, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63,
+	0x6f, 0x6c, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x69, 0x76, 0x5f, 0x70, 0x61, 0x73, 0x73, 0x77,
+	0x6f, 0x72, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x70, 0x72, 0x69, 0x76, 0x50,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65,
+	0x78, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63,
+	0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x2d, 0x0a, 0x0c, 0x41, 0x75,
+	0x74, 0x68, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x0b, 0x0a, 0x07, 0x4e, 0x4f,
+	0x5f, 0x41, 0x55, 0x54, 0x48, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x4d, 0x44, 0x35, 0x10, 0x01,
+	0x12, 0x07, 0x0a, 0x03, 0x53, 0x48, 0x41, 0x10, 0x02, 0x22, 0x2d, 0x0a, 0x0c, 0x50, 0x72, 0x69,
+	0x76, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x0b, 0x0a, 0x07, 0x4e, 0x4f, 0x5f,
+	0x50, 0x52, 0x49, 0x56, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x44, 0x45, 0x53, 0x10, 0x01, 0x12,
+	0x07, 0x0a, 0x03, 0x41, 0x45, 0x53, 0x10, 0x02, 0x1a, 0x2b, 0x0a, 0x03, 0x4f, 0x49, 0x44, 0x12,
+	0x10, 0x0a, 0x03, 0x6f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x03, 0x6f, 0x69,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x1a, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x07, 0x0a, 0x03, 0x56, 0x32, 0x43, 0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x33, 0x10,
+	0x01, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x73,
+	0x6e, 0x6d, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_Version)(0),          // 0: cloudprober.probes.snmp.ProbeConf.Version
+	(ProbeConf_USM_AuthProtocol)(0), // 1: cloudprober.probes.snmp.ProbeConf.USM.AuthProtocol
+	(ProbeConf_USM_PrivProtocol)(0), // 2: cloudprober.probes.snmp.ProbeConf.USM.PrivProtocol
+	(*ProbeConf)(nil),               // 3: cloudprober.probes.snmp.ProbeConf
+	(*ProbeConf_USM)(nil),           // 4: cloudprober.probes.snmp.ProbeConf.USM
+	(*ProbeConf_OID)(nil),           // 5: cloudprober.probes.snmp.ProbeConf.OID
+}
+var file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.snmp.ProbeConf.version:type_name -> cloudprober.probes.snmp.ProbeConf.Version
+	4, // 1: cloudprober.probes.snmp.ProbeConf.usm:type_name -> cloudprober.probes.snmp.ProbeConf.USM
+	5, // 2: cloudprober.probes.snmp.ProbeConf.oid:type_name -> cloudprober.probes.snmp.ProbeConf.OID
+	1, // 3: cloudprober.probes.snmp.ProbeConf.USM.auth_protocol:type_name -> cloudprober.probes.snmp.ProbeConf.USM.AuthProtocol
+	2, // 4: cloudprober.probes.snmp.ProbeConf.USM.priv_protocol:type_name -> cloudprober.probes.snmp.ProbeConf.USM.PrivProtocol
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf_USM); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf_OID); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDesc,
+			NumEnums:      3,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_snmp_proto_config_proto_depIdxs = nil
+}
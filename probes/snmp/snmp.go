@@ -0,0 +1,619 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package snmp implements an SNMP prober. It polls the configured OIDs from the
+targets with an SNMP GET request, using SNMPv2c or SNMPv3 with the user-based
+security model. It reports statistics on probe runs, successful runs and
+latency, and the reason of the failures. For every successful run, it also
+reports the polled values, as gauges.
+
+Probes for all targets run in parallel.
+*/
+package snmp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/snmp/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonRequest      = "request"
+	reasonAuth         = "auth"
+	reasonErrorStatus  = "error_status"
+	reasonNoSuchObject = "no_such_object"
+)
+
+const defaultPort = 161
+
+// minPasswordLen is the minimum length of the USM passwords (RFC 3414, 11.2).
+const minPasswordLen = 8
+
+// usmStats are the USM error counters that agents report failures with.
+var usmStats = map[string]string{
+	"1.3.6.1.6.3.15.1.1.1.0": "usmStatsUnsupportedSecLevels",
+	"1.3.6.1.6.3.15.1.1.2.0": "usmStatsNotInTimeWindows",
+	"1.3.6.1.6.3.15.1.1.3.0": "usmStatsUnknownUserNames",
+	"1.3.6.1.6.3.15.1.1.4.0": "usmStatsUnknownEngineIDs",
+	"1.3.6.1.6.3.15.1.1.5.0": "usmStatsWrongDigests",
+	"1.3.6.1.6.3.15.1.1.6.0": "usmStatsDecryptionErrors",
+}
+
+// authError is returned for the SNMPv3 security failures.
+type authError struct {
+	msg string
+}
+
+func (e *authError) Error() string {
+	return e.msg
+}
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets []endpoint.Endpoint
+	dialF   func(context.Context, string, string) (net.Conn, error)
+	oids    [][]byte // Encoded OIDs.
+	nextID  int32    // Last request and message ID.
+
+	mu   sync.Mutex
+	salt uint64 // Last privacy salt.
+	// USM keys are localized per engine ID, and key localization is
+	// expensive, so we cache them.
+	keys map[string]*usmKeys
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	latencyMetricName string
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// Polled values, reported as a separate result. nil if the run didn't
+	// succeed.
+	values *valuesResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures)
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// valuesResult is a gauge update for the polled values.
+type valuesResult struct {
+	target string
+	names  []string
+	values []metrics.Value
+}
+
+// Metrics converts valuesResult into metrics.EventMetrics object
+func (vr valuesResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now())
+	for i, name := range vr.names {
+		em.AddMetric(name, vr.values[i])
+	}
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the vr.target.
+func (vr valuesResult) Target() string {
+	return vr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no snmp config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("snmp_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if len(p.c.GetOid()) == 0 {
+		return fmt.Errorf("snmp_probe(%s): no OIDs configured", name)
+	}
+	names := make(map[string]bool)
+	for _, oid := range p.c.GetOid() {
+		b, err := encodeOID(oid.GetOid())
+		if err != nil {
+			return fmt.Errorf("snmp_probe(%s): %v", name, err)
+		}
+		if oid.GetName() == "" || names[oid.GetName()] {
+			return fmt.Errorf("snmp_probe(%s): OID names should be non-empty and unique, got: %q", name, oid.GetName())
+		}
+		names[oid.GetName()] = true
+		p.oids = append(p.oids, b)
+	}
+
+	if p.c.GetVersion() == configpb.ProbeConf_V3 {
+		if err := validateUSM(p.c.GetUsm()); err != nil {
+			return fmt.Errorf("snmp_probe(%s): %v", name, err)
+		}
+		p.keys = make(map[string]*usmKeys)
+	}
+
+	p.nextID = rand.Int31()
+	p.salt = rand.Uint64()
+
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.UDPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = dialer.DialContext
+
+	p.updateTargets()
+	return nil
+}
+
+func validateUSM(usm *configpb.ProbeConf_USM) error {
+	if usm == nil {
+		return errors.New("usm is required for SNMPv3")
+	}
+	if usm.GetAuthProtocol() == configpb.ProbeConf_USM_NO_AUTH {
+		if usm.GetPrivProtocol() != configpb.ProbeConf_USM_NO_PRIV {
+			return errors.New("privacy requires authentication")
+		}
+		return nil
+	}
+	if len(usm.GetAuthPassword()) < minPasswordLen {
+		return fmt.Errorf("auth_password should be at least %d characters long", minPasswordLen)
+	}
+	if usm.GetPrivProtocol() != configpb.ProbeConf_USM_NO_PRIV && len(usm.GetPrivPassword()) < minPasswordLen {
+		return fmt.Errorf("priv_password should be at least %d characters long", minPasswordLen)
+	}
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	return probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+	}
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p *Probe) newID() int64 {
+	return int64(atomic.AddInt32(&p.nextID, 1) & 0x7fffffff)
+}
+
+func (p *Probe) newSalt() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.salt++
+	return p.salt
+}
+
+// usmKeys returns the USM keys localized for the given engine ID.
+func (p *Probe) usmKeys(engineID []byte) *usmKeys {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := p.keys[string(engineID)]
+	if keys == nil {
+		keys = newUSMKeys(p.c.GetUsm(), engineID)
+		p.keys[string(engineID)] = keys
+	}
+	return keys
+}
+
+// roundTrip sends the request and returns the response.
+func roundTrip(conn net.Conn, req []byte) ([]byte, error) {
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// getV2c polls the OIDs with an SNMPv2c GET request.
+func (p *Probe) getV2c(conn net.Conn) (*pdu, error) {
+	id := p.newID()
+	resp, err := roundTrip(conn, v2cMessage(p.c.GetCommunity(), getRequest(id, p.oids)))
+	if err != nil {
+		return nil, err
+	}
+	_, respPDU, err := parseV2cMessage(resp)
+	if err != nil {
+		return nil, err
+	}
+	if respPDU.requestID != id {
+		return nil, fmt.Errorf("unexpected request ID: %d, want: %d", respPDU.requestID, id)
+	}
+	return respPDU, nil
+}
+
+// reportError returns an error for a report PDU.
+func reportError(report *pdu) error {
+	var names []string
+	for _, vb := range report.varBinds {
+		if name, ok := usmStats[vb.oid]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, vb.oid)
+		}
+	}
+	return &authError{msg: "agent reported: " + strings.Join(names, ",")}
+}
+
+// getV3 discovers the agent's engine ID, boots and time, and polls the OIDs
+// with an SNMPv3 GET request.
+func (p *Probe) getV3(conn net.Conn) (*pdu, error) {
+	usm := p.c.GetUsm()
+
+	// Discovery (RFC 3414, 4).
+	id := p.newID()
+	discovery := &v3Message{msgID: id, flags: flagReportable, data: scopedPDU(nil, "", getRequest(id, nil))}
+	resp, err := roundTrip(conn, discovery.encode())
+	if err != nil {
+		return nil, fmt.Errorf("engine discovery failed: %w", err)
+	}
+	respMsg, err := parseV3Message(resp)
+	if err != nil {
+		return nil, fmt.Errorf("engine discovery failed: %v", err)
+	}
+	if len(respMsg.usm.engineID) == 0 {
+		return nil, errors.New("engine discovery failed: agent didn't report engine ID")
+	}
+	engineID := respMsg.usm.engineID
+	keys := p.usmKeys(engineID)
+
+	id = p.newID()
+	msg := &v3Message{
+		msgID: id,
+		flags: flagReportable,
+		usm: usmParams{
+			engineID:   engineID,
+			engineBoot: respMsg.usm.engineBoot,
+			engineTime: respMsg.usm.engineTime,
+			username:   usm.GetUsername(),
+		},
+		data: scopedPDU(engineID, usm.GetContextName(), getRequest(id, p.oids)),
+	}
+	if keys.authHash != nil {
+		msg.flags |= flagAuth
+		msg.usm.authParams = make([]byte, authParamsLen)
+	}
+	if keys.privKey != nil {
+		msg.flags |= flagPriv
+		if msg.data, msg.usm.privParams, err = keys.encrypt(msg.data, msg.usm.engineBoot, msg.usm.engineTime, p.newSalt()); err != nil {
+			return nil, err
+		}
+	}
+	req := msg.encode()
+	if keys.authHash != nil {
+		keys.sign(req, msg.authOffset)
+	}
+
+	if resp, err = roundTrip(conn, req); err != nil {
+		return nil, err
+	}
+	if respMsg, err = parseV3Message(resp); err != nil {
+		return nil, err
+	}
+	if respMsg.msgID != id {
+		return nil, fmt.Errorf("unexpected message ID: %d, want: %d", respMsg.msgID, id)
+	}
+
+	// Agents report some errors, e.g. unknown user names, without
+	// authentication.
+	data := respMsg.data
+	if respMsg.flags&flagAuth != 0 {
+		if keys.authHash == nil {
+			return nil, &authError{msg: "unexpected authenticated response"}
+		}
+		if err := keys.verify(resp, respMsg.authOffset); err != nil {
+			return nil, &authError{msg: err.Error()}
+		}
+	}
+	if respMsg.flags&flagPriv != 0 {
+		if keys.privKey == nil {
+			return nil, &authError{msg: "unexpected encrypted response"}
+		}
+		if data, err = keys.decrypt(data, respMsg.usm.engineBoot, respMsg.usm.engineTime, respMsg.usm.privParams); err != nil {
+			return nil, &authError{msg: fmt.Sprintf("error decrypting the response: %v", err)}
+		}
+	}
+	respPDU, err := parseScopedPDU(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if respPDU.tag == tagReport {
+		return nil, reportError(respPDU)
+	}
+	if respMsg.flags&flagAuth == 0 && keys.authHash != nil {
+		return nil, &authError{msg: "unauthenticated response"}
+	}
+	if respPDU.requestID != id {
+		return nil, fmt.Errorf("unexpected request ID: %d, want: %d", respPDU.requestID, id)
+	}
+	return respPDU, nil
+}
+
+// stringValue returns a printable representation of an octet string.
+func stringValue(b []byte) string {
+	s := string(b)
+	if !utf8.ValidString(s) {
+		return hex.EncodeToString(b)
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return hex.EncodeToString(b)
+		}
+	}
+	return s
+}
+
+// metricValue converts a variable binding's value to a metric value. It
+// returns nil for the exceptions, e.g. noSuchObject.
+func metricValue(vb varBind) metrics.Value {
+	switch vb.tag {
+	case tagInteger:
+		return metrics.NewInt(parseInt(vb.value))
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		return metrics.NewInt(int64(parseUint(vb.value)))
+	case tagOctetString:
+		return metrics.NewString(stringValue(vb.value))
+	case tagIPAddress:
+		return metrics.NewString(net.IP(vb.value).String())
+	case tagOID:
+		return metrics.NewString(oidString(vb.value))
+	case tagOpaque:
+		return metrics.NewString(hex.EncodeToString(vb.value))
+	}
+	return nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	failed := func(reason string, err error) {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reason)
+	}
+
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(target.Name, strconv.Itoa(port))
+
+	start := time.Now()
+	conn, err := p.dialF(ctx, "udp", addr)
+	if err != nil {
+		failed(reasonRequest, fmt.Errorf("error connecting to %s: %w", addr, err))
+		return
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var resp *pdu
+	if p.c.GetVersion() == configpb.ProbeConf_V3 {
+		resp, err = p.getV3(conn)
+	} else {
+		resp, err = p.getV2c(conn)
+	}
+	if err != nil {
+		var authErr *authError
+		if errors.As(err, &authErr) {
+			failed(reasonAuth, err)
+		} else {
+			failed(reasonRequest, fmt.Errorf("GET request to %s failed: %w", addr, err))
+		}
+		return
+	}
+	latency := time.Since(start)
+
+	if resp.errorStatus != 0 {
+		failed(reasonErrorStatus, fmt.Errorf("agent returned error status: %d, index: %d", resp.errorStatus, resp.errorIndex))
+		return
+	}
+	if len(resp.varBinds) != len(p.oids) {
+		failed(reasonRequest, fmt.Errorf("agent returned %d values, want: %d", len(resp.varBinds), len(p.oids)))
+		return
+	}
+
+	values := &valuesResult{target: target.Name}
+	for i, vb := range resp.varBinds {
+		oid := p.c.GetOid()[i]
+		v := metricValue(vb)
+		if v == nil {
+			failed(reasonNoSuchObject, fmt.Errorf("no value for %s (%s), type: 0x%x", oid.GetName(), oid.GetOid(), vb.tag))
+			return
+		}
+		values.names = append(values.names, oid.GetName())
+		values.values = append(values.values, v)
+	}
+	result.values = values
+
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		if result.values != nil {
+			resultsChan <- *result.values
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "snmp", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/probes/snmp/proto"
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testCommunity    = "testcommunity"
+	testUsername     = "alice"
+	testAuthPassword = "authpassword"
+	testPrivPassword = "privpassword"
+	testEngineBoot   = 3
+	testEngineTime   = 100
+
+	oidUptime     = "1.3.6.1.2.1.1.3.0"
+	oidName       = "1.3.6.1.2.1.1.5.0"
+	oidInOctets   = "1.3.6.1.2.1.2.2.1.10.1"
+	oidHCInOctets = "1.3.6.1.2.1.31.1.1.1.6.1"
+	oidGenErr     = "1.3.6.1.2.1.99.0"
+
+	errorStatusGenErr = 5
+)
+
+var testEngineID = []byte("\x80\x00\x1f\x88\x04test")
+
+// testValues are the values returned by the test agent.
+var testValues = map[string][]byte{
+	oidUptime:     berUint(tagTimeTicks, 12345),
+	oidName:       berBytes(tagOctetString, []byte("router1")),
+	oidInOctets:   berUint(tagCounter32, 4000000000),
+	oidHCInOctets: berUint(tagCounter64, 1<<40),
+}
+
+// testAgent is a minimal SNMP agent. It accepts SNMPv2c requests with
+// testCommunity, and SNMPv3 requests from testUsername, with the security
+// level and the protocols of the usm config.
+type testAgent struct {
+	usm  *configpb.ProbeConf_USM
+	keys *usmKeys
+}
+
+func (ta *testAgent) start(t *testing.T) endpoint.Endpoint {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if ta.usm != nil {
+		ta.keys = newUSMKeys(ta.usm, testEngineID)
+	}
+
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := ta.handle(append([]byte{}, buf[:n]...)); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return endpoint.Endpoint{Name: "127.0.0.1", Port: conn.LocalAddr().(*net.UDPAddr).Port}
+}
+
+// response returns the response PDU for a request PDU.
+func response(req *pdu) []byte {
+	var varBinds [][]byte
+	var errorStatus, errorIndex int64
+	for i, vb := range req.varBinds {
+		oid, _ := encodeOID(vb.oid)
+		value, ok := testValues[vb.oid]
+		if !ok {
+			value = berTLV(tagNoSuchObject, nil)
+		}
+		if vb.oid == oidGenErr {
+			errorStatus, errorIndex = errorStatusGenErr, int64(i+1)
+		}
+		varBinds = append(varBinds, berConstructed(tagSequence, berBytes(tagOID, oid), value))
+	}
+	return berConstructed(tagGetResponse,
+		berInt(tagInteger, req.requestID),
+		berInt(tagInteger, errorStatus),
+		berInt(tagInteger, errorIndex),
+		berConstructed(tagSequence, varBinds...))
+}
+
+func (ta *testAgent) handle(b []byte) []byte {
+	version, _, err := parseVersion(b)
+	if err != nil {
+		return nil
+	}
+
+	if version == versionV2c {
+		community, req, err := parseV2cMessage(b)
+		// Agents ignore the requests with wrong community.
+		if err != nil || community != testCommunity {
+			return nil
+		}
+		return v2cMessage(community, response(req))
+	}
+
+	msg, err := parseV3Message(b)
+	if err != nil {
+		return nil
+	}
+	report := func(oid string, flags byte) []byte {
+		oidBytes, _ := encodeOID(oid)
+		reportPDU := berConstructed(tagReport,
+			berInt(tagInteger, msg.msgID),
+			berInt(tagInteger, 0),
+			berInt(tagInteger, 0),
+			berConstructed(tagSequence, berConstructed(tagSequence, berBytes(tagOID, oidBytes), berUint(tagCounter32, 1))))
+		resp := &v3Message{
+			msgID: msg.msgID,
+			flags: flags,
+			usm:   usmParams{engineID: testEngineID, engineBoot: testEngineBoot, engineTime: testEngineTime, username: msg.usm.username},
+			data:  scopedPDU(testEngineID, "", reportPDU),
+		}
+		if flags&flagAuth != 0 {
+			resp.usm.authParams = make([]byte, authParamsLen)
+			out := resp.encode()
+			ta.keys.sign(out, resp.authOffset)
+			return out
+		}
+		return resp.encode()
+	}
+
+	switch {
+	case len(msg.usm.engineID) == 0:
+		return report("1.3.6.1.6.3.15.1.1.4.0", 0)
+	case ta.usm == nil || msg.usm.username != testUsername:
+		return report("1.3.6.1.6.3.15.1.1.3.0", 0)
+	case msg.flags&(flagAuth|flagPriv) != securityFlags(ta.keys):
+		return report("1.3.6.1.6.3.15.1.1.1.0", 0)
+	}
+	if msg.flags&flagAuth != 0 {
+		if err := ta.keys.verify(b, msg.authOffset); err != nil {
+			return report("1.3.6.1.6.3.15.1.1.5.0", 0)
+		}
+	}
+	data := msg.data
+	if msg.flags&flagPriv != 0 {
+		if data, err = ta.keys.decrypt(data, msg.usm.engineBoot, msg.usm.engineTime, msg.usm.privParams); err != nil {
+			return report("1.3.6.1.6.3.15.1.1.6.0", flagAuth)
+		}
+	}
+	req, err := parseScopedPDU(data)
+	if err != nil {
+		return report("1.3.6.1.6.3.15.1.1.6.0", msg.flags&flagAuth)
+	}
+
+	resp := &v3Message{
+		msgID: msg.msgID,
+		flags: msg.flags &^ flagReportable,
+		usm:   usmParams{engineID: testEngineID, engineBoot: testEngineBoot, engineTime: testEngineTime, username: testUsername},
+		data:  scopedPDU(testEngineID, "", response(req)),
+	}
+	if resp.flags&flagAuth != 0 {
+		resp.usm.authParams = make([]byte, authParamsLen)
+	}
+	if resp.flags&flagPriv != 0 {
+		resp.data, resp.usm.privParams, _ = ta.keys.encrypt(resp.data, testEngineBoot, testEngineTime, 99)
+	}
+	out := resp.encode()
+	if resp.flags&flagAuth != 0 {
+		ta.keys.sign(out, resp.authOffset)
+	}
+	return out
+}
+
+// securityFlags returns the security flags expected for the keys.
+func securityFlags(keys *usmKeys) byte {
+	var flags byte
+	if keys.authHash != nil {
+		flags |= flagAuth
+	}
+	if keys.privKey != nil {
+		flags |= flagPriv
+	}
+	return flags
+}
+
+func testOIDs(oids ...string) []*configpb.ProbeConf_OID {
+	names := map[string]string{
+		oidUptime:     "sys_uptime",
+		oidName:       "sys_name",
+		oidInOctets:   "if_in_octets",
+		oidHCInOctets: "if_hc_in_octets",
+		oidGenErr:     "gen_err",
+		"1.3.6.1.9.9": "missing",
+	}
+	var out []*configpb.ProbeConf_OID
+	for _, oid := range oids {
+		out = append(out, &configpb.ProbeConf_OID{Oid: proto.String(oid), Name: proto.String(names[oid])})
+	}
+	return out
+}
+
+func testUSM(auth configpb.ProbeConf_USM_AuthProtocol, priv configpb.ProbeConf_USM_PrivProtocol) *configpb.ProbeConf_USM {
+	return &configpb.ProbeConf_USM{
+		Username:     proto.String(testUsername),
+		AuthProtocol: auth.Enum(),
+		AuthPassword: proto.String(testAuthPassword),
+		PrivProtocol: priv.Enum(),
+		PrivPassword: proto.String(testPrivPassword),
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	allOIDs := testOIDs(oidUptime, oidName, oidInOctets, oidHCInOctets)
+	v3 := configpb.ProbeConf_V3.Enum()
+
+	for _, test := range []struct {
+		desc        string
+		conf        *configpb.ProbeConf
+		agentUSM    *configpb.ProbeConf_USM
+		wantReason  string
+		wantTimeout bool
+	}{
+		{
+			desc: "v2c",
+			conf: &configpb.ProbeConf{Community: proto.String(testCommunity), Oid: allOIDs},
+		},
+		{
+			desc:        "v2c_wrong_community",
+			conf:        &configpb.ProbeConf{Oid: allOIDs},
+			wantReason:  reasonRequest,
+			wantTimeout: true,
+		},
+		{
+			desc:       "v2c_no_such_object",
+			conf:       &configpb.ProbeConf{Community: proto.String(testCommunity), Oid: testOIDs(oidUptime, "1.3.6.1.9.9")},
+			wantReason: reasonNoSuchObject,
+		},
+		{
+			desc:       "v2c_error_status",
+			conf:       &configpb.ProbeConf{Community: proto.String(testCommunity), Oid: testOIDs(oidUptime, oidGenErr)},
+			wantReason: reasonErrorStatus,
+		},
+		{
+			desc:     "v3_no_auth",
+			conf:     &configpb.ProbeConf{Version: v3, Usm: &configpb.ProbeConf_USM{Username: proto.String(testUsername)}, Oid: allOIDs},
+			agentUSM: &configpb.ProbeConf_USM{Username: proto.String(testUsername)},
+		},
+		{
+			desc:     "v3_md5",
+			conf:     &configpb.ProbeConf{Version: v3, Usm: testUSM(configpb.ProbeConf_USM_MD5, configpb.ProbeConf_USM_NO_PRIV), Oid: allOIDs},
+			agentUSM: testUSM(configpb.ProbeConf_USM_MD5, configpb.ProbeConf_USM_NO_PRIV),
+		},
+		{
+			desc:     "v3_sha_des",
+			conf:     &configpb.ProbeConf{Version: v3, Usm: testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_DES), Oid: allOIDs},
+			agentUSM: testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_DES),
+		},
+		{
+			desc:     "v3_sha_aes",
+			conf:     &configpb.ProbeConf{Version: v3, Usm: testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_AES), Oid: allOIDs},
+			agentUSM: testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_AES),
+		},
+		{
+			desc: "v3_wrong_password",
+			conf: &configpb.ProbeConf{
+				Version: v3,
+				Usm: &configpb.ProbeConf_USM{
+					Username:     proto.String(testUsername),
+					AuthProtocol: configpb.ProbeConf_USM_SHA.Enum(),
+					AuthPassword: proto.String("wrongpassword"),
+				},
+				Oid: allOIDs,
+			},
+			agentUSM:   testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_NO_PRIV),
+			wantReason: reasonAuth,
+		},
+		{
+			desc:       "v3_unknown_user",
+			conf:       &configpb.ProbeConf{Version: v3, Usm: &configpb.ProbeConf_USM{Username: proto.String("bob")}, Oid: allOIDs},
+			agentUSM:   &configpb.ProbeConf_USM{Username: proto.String(testUsername)},
+			wantReason: reasonAuth,
+		},
+		{
+			desc:       "v3_security_level_mismatch",
+			conf:       &configpb.ProbeConf{Version: v3, Usm: testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_NO_PRIV), Oid: allOIDs},
+			agentUSM:   testUSM(configpb.ProbeConf_USM_SHA, configpb.ProbeConf_USM_AES),
+			wantReason: reasonAuth,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ta := &testAgent{usm: test.agentUSM}
+			target := ta.start(t)
+
+			p := &Probe{}
+			testutils.InitProbe(t, p, "snmp_test", testutils.Options("127.0.0.1", 500*time.Millisecond, test.conf))
+
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+			wantTimeouts := int64(0)
+			if test.wantTimeout {
+				wantTimeouts = 1
+			}
+			if result.timeouts.Int64() != wantTimeouts {
+				t.Errorf("Got timeouts=%d, want=%d", result.timeouts.Int64(), wantTimeouts)
+			}
+
+			if test.wantReason != "" {
+				if result.values != nil {
+					t.Errorf("Got values for a failed run: %v", result.values)
+				}
+				return
+			}
+
+			if result.values == nil {
+				t.Fatal("Got no values")
+			}
+			em := result.values.Metrics()
+			if em.Kind != metrics.GAUGE {
+				t.Errorf("Got values of kind: %v, want GAUGE", em.Kind)
+			}
+			wantValues := map[string]string{
+				"sys_uptime":      "12345",
+				"sys_name":        "\"router1\"",
+				"if_in_octets":    "4000000000",
+				"if_hc_in_octets": "1099511627776",
+			}
+			for name, want := range wantValues {
+				if m := em.Metric(name); m == nil || m.String() != want {
+					t.Errorf("Got %s=%v, want=%s", name, m, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	oids := testOIDs(oidUptime)
+	v3 := configpb.ProbeConf_V3.Enum()
+
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "no_oids", Conf: &configpb.ProbeConf{}},
+		{Desc: "invalid_oid", Conf: &configpb.ProbeConf{Oid: []*configpb.ProbeConf_OID{{Oid: proto.String("1.3.x"), Name: proto.String("x")}}}},
+		{Desc: "duplicate_names", Conf: &configpb.ProbeConf{Oid: append(testOIDs(oidUptime), testOIDs(oidUptime)...)}},
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000), Oid: oids}},
+		{Desc: "v3_without_usm", Conf: &configpb.ProbeConf{Version: v3, Oid: oids}},
+		{
+			Desc: "v3_priv_without_auth",
+			Conf: &configpb.ProbeConf{Version: v3, Usm: testUSM(configpb.ProbeConf_USM_NO_AUTH, configpb.ProbeConf_USM_AES), Oid: oids},
+		},
+		{
+			Desc: "v3_short_password",
+			Conf: &configpb.ProbeConf{
+				Version: v3,
+				Usm: &configpb.ProbeConf_USM{
+					Username:     proto.String(testUsername),
+					AuthProtocol: configpb.ProbeConf_USM_MD5.Enum(),
+					AuthPassword: proto.String("short"),
+				},
+				Oid: oids,
+			},
+		},
+	})
+}
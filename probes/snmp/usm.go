@@ -0,0 +1,205 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	configpb "github.com/cloudprober/cloudprober/probes/snmp/proto"
+)
+
+// This file implements the SNMPv3 user-based security model (RFC 3414), with
+// HMAC-MD5-96 and HMAC-SHA-96 authentication, and CBC-DES (RFC 3414) and
+// CFB128-AES-128 (RFC 3826) privacy.
+
+// authParamsLen is the length of the HMAC-MD5-96 and HMAC-SHA-96 MACs.
+const authParamsLen = 12
+
+// usmKeys are the user's keys, localized for an SNMP engine.
+type usmKeys struct {
+	authHash func() hash.Hash // nil for noAuth.
+	authKey  []byte
+	privAlgo configpb.ProbeConf_USM_PrivProtocol
+	privKey  []byte
+}
+
+func authHashFunc(proto configpb.ProbeConf_USM_AuthProtocol) func() hash.Hash {
+	switch proto {
+	case configpb.ProbeConf_USM_MD5:
+		return md5.New
+	case configpb.ProbeConf_USM_SHA:
+		return sha1.New
+	}
+	return nil
+}
+
+// localizedKey converts a password to a key, localized for the given engine
+// ID (RFC 3414, A.2).
+func localizedKey(h func() hash.Hash, password string, engineID []byte) []byte {
+	hh := h()
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	for i, count := 0, 0; count < 1024*1024; count += len(buf) {
+		for j := range buf {
+			buf[j] = pw[i%len(pw)]
+			i++
+		}
+		hh.Write(buf)
+	}
+	key := hh.Sum(nil)
+
+	hh.Reset()
+	hh.Write(key)
+	hh.Write(engineID)
+	hh.Write(key)
+	return hh.Sum(nil)
+}
+
+// newUSMKeys returns the keys for the given USM config, localized for the
+// given engine ID.
+func newUSMKeys(c *configpb.ProbeConf_USM, engineID []byte) *usmKeys {
+	keys := &usmKeys{authHash: authHashFunc(c.GetAuthProtocol())}
+	if keys.authHash == nil {
+		return keys
+	}
+	keys.authKey = localizedKey(keys.authHash, c.GetAuthPassword(), engineID)
+
+	if c.GetPrivProtocol() != configpb.ProbeConf_USM_NO_PRIV {
+		keys.privAlgo = c.GetPrivProtocol()
+		keys.privKey = localizedKey(keys.authHash, c.GetPrivPassword(), engineID)
+	}
+	return keys
+}
+
+// mac returns the truncated HMAC of the message, computed with the
+// authentication parameters zeroed.
+func (k *usmKeys) mac(msg []byte, authOffset int) []byte {
+	zeroed := append([]byte{}, msg...)
+	copy(zeroed[authOffset:authOffset+authParamsLen], make([]byte, authParamsLen))
+	h := hmac.New(k.authHash, k.authKey)
+	h.Write(zeroed)
+	return h.Sum(nil)[:authParamsLen]
+}
+
+// sign sets the authentication parameters of the encoded message, which are
+// expected to be zeroed already.
+func (k *usmKeys) sign(msg []byte, authOffset int) {
+	copy(msg[authOffset:], k.mac(msg, authOffset))
+}
+
+// verify verifies the authentication parameters of the encoded message.
+func (k *usmKeys) verify(msg []byte, authOffset int) error {
+	if authOffset < 0 || authOffset+authParamsLen > len(msg) {
+		return errors.New("invalid authentication parameters")
+	}
+	if !hmac.Equal(msg[authOffset:authOffset+authParamsLen], k.mac(msg, authOffset)) {
+		return errors.New("authentication failure, wrong digest")
+	}
+	return nil
+}
+
+// encrypt encrypts the scoped PDU, and returns it along with the privacy
+// parameters (salt).
+func (k *usmKeys) encrypt(scopedPDU []byte, engineBoot, engineTime int64, salt uint64) ([]byte, []byte, error) {
+	switch k.privAlgo {
+	case configpb.ProbeConf_USM_DES:
+		privParams := make([]byte, 8)
+		binary.BigEndian.PutUint32(privParams, uint32(engineBoot))
+		binary.BigEndian.PutUint32(privParams[4:], uint32(salt))
+
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = k.privKey[8+i] ^ privParams[i]
+		}
+		// Padding is ignored by the receivers, as the scoped PDU carries
+		// its length.
+		data := append([]byte{}, scopedPDU...)
+		if pad := len(data) % des.BlockSize; pad != 0 {
+			data = append(data, make([]byte, des.BlockSize-pad)...)
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+		return data, privParams, nil
+
+	case configpb.ProbeConf_USM_AES:
+		privParams := make([]byte, 8)
+		binary.BigEndian.PutUint64(privParams, salt)
+
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		data := make([]byte, len(scopedPDU))
+		cipher.NewCFBEncrypter(block, aesIV(engineBoot, engineTime, privParams)).XORKeyStream(data, scopedPDU)
+		return data, privParams, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported privacy protocol: %v", k.privAlgo)
+}
+
+// decrypt decrypts an encrypted scoped PDU.
+func (k *usmKeys) decrypt(data []byte, engineBoot, engineTime int64, privParams []byte) ([]byte, error) {
+	if len(privParams) != 8 {
+		return nil, fmt.Errorf("invalid privacy parameters: %x", privParams)
+	}
+
+	switch k.privAlgo {
+	case configpb.ProbeConf_USM_DES:
+		if len(data)%des.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid encrypted data length: %d", len(data))
+		}
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = k.privKey[8+i] ^ privParams[i]
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+
+	case configpb.ProbeConf_USM_AES:
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(engineBoot, engineTime, privParams)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported privacy protocol: %v", k.privAlgo)
+}
+
+// aesIV returns the AES initialization vector (RFC 3826, 3.1.2.1).
+func aesIV(engineBoot, engineTime int64, privParams []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv, uint32(engineBoot))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], privParams)
+	return iv
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+
+	configpb "github.com/cloudprober/cloudprober/probes/snmp/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLocalizedKey(t *testing.T) {
+	// Test vectors from RFC 3414, A.3.
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+
+	if got, want := hex.EncodeToString(localizedKey(md5.New, "maplesyrup", engineID)), "526f5eed9fcce26f8964c2930787d82b"; got != want {
+		t.Errorf("MD5 localized key=%s, want=%s", got, want)
+	}
+	if got, want := hex.EncodeToString(localizedKey(sha1.New, "maplesyrup", engineID)), "6695febc9288e36282235fc7151f128497b38f3f"; got != want {
+		t.Errorf("SHA localized key=%s, want=%s", got, want)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	scopedPDU := bytes.Repeat([]byte("scoped pdu "), 10)
+
+	for _, privProto := range []configpb.ProbeConf_USM_PrivProtocol{configpb.ProbeConf_USM_DES, configpb.ProbeConf_USM_AES} {
+		keys := newUSMKeys(&configpb.ProbeConf_USM{
+			AuthProtocol: configpb.ProbeConf_USM_SHA.Enum(),
+			AuthPassword: proto.String("authpassword"),
+			PrivProtocol: privProto.Enum(),
+			PrivPassword: proto.String("privpassword"),
+		}, []byte("engine"))
+
+		data, privParams, err := keys.encrypt(scopedPDU, 3, 100, 7)
+		if err != nil {
+			t.Fatalf("%v: error encrypting: %v", privProto, err)
+		}
+		if bytes.Contains(data, []byte("scoped pdu")) {
+			t.Errorf("%v: encrypted data contains the plain text", privProto)
+		}
+
+		got, err := keys.decrypt(data, 3, 100, privParams)
+		if err != nil {
+			t.Fatalf("%v: error decrypting: %v", privProto, err)
+		}
+		// DES adds padding.
+		if !bytes.HasPrefix(got, scopedPDU) {
+			t.Errorf("%v: decrypted data: %q, want: %q", privProto, got, scopedPDU)
+		}
+	}
+}
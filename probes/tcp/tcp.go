@@ -0,0 +1,732 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package tcp implements a TCP prober. It establishes TCP connections to the
+targets and reports statistics on connection attempts, successful connections
+and connect latency. Optionally, it also reports TCP handshake RTT, as seen by
+the kernel, and completes a TLS handshake after connecting, reporting the
+handshake latency and the server certificate's metadata.
+
+Connections to all targets, and to all ports if probing multiple ports, are
+established in parallel.
+*/
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/tcp/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Default handshake RTT distribution's lower bounds start at 100us and go up
+// to ~3.2s (100us * 2^15).
+const (
+	defaultRTTDistBase       = 2
+	defaultRTTDistScale      = 100 * time.Microsecond
+	defaultRTTDistNumBuckets = 16
+)
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets    []endpoint.Endpoint
+	dialer     *net.Dialer
+	dialF      func(context.Context, string, string) (net.Conn, error)
+	rttDist    *metrics.Distribution // Used only if handshake RTT is exported.
+	exportRTT  bool
+	handshakeF func(net.Conn) (time.Duration, error)
+	tlsConfig  *tls.Config // Set only if TLS is configured.
+
+	// Last resolved IP for each target, used only if export_ip_changes is
+	// enabled.
+	resolvedIPsMu sync.Mutex
+	resolvedIPs   map[string]string
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	port              int // Set only if probing multiple ports.
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	latencyMetricName string
+
+	// connectTimeouts is exported only if connect timeout is configured.
+	connectTimeouts       metrics.Int
+	exportConnectTimeouts bool
+
+	// dnsTimeouts is exported only if DNS resolve timeout is configured.
+	dnsTimeouts       metrics.Int
+	exportDNSTimeouts bool
+
+	// handshakeRTT is exported only if export_handshake_rtt is enabled.
+	handshakeRTT *metrics.Distribution
+
+	// TLS handshake metrics are exported only if TLS is configured.
+	tlsHandshakeLatency  metrics.Value
+	tlsHandshakeFailures metrics.Int
+	// Server certificate update, reported as a separate result.
+	cert *certResult
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawOutcome is exported only if expect_failure is enabled.
+	rawOutcome *metrics.Map
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipChanges is exported only if export_ip_changes is enabled.
+	ipChanges       metrics.Int
+	exportIPChanges bool
+	// Updates to the resolved IP gauge, reported as separate results.
+	ipUpdates []ipResult
+
+	// onDemand is set for the on-demand runs, which don't track the resolved
+	// IPs.
+	onDemand bool
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts)
+	if prr.exportConnectTimeouts {
+		em.AddMetric("connect_timeouts", &prr.connectTimeouts)
+	}
+	if prr.exportDNSTimeouts {
+		em.AddMetric("dns_timeouts", &prr.dnsTimeouts)
+	}
+	if prr.handshakeRTT != nil {
+		em.AddMetric("handshake_rtt", prr.handshakeRTT)
+	}
+	if prr.tlsHandshakeLatency != nil {
+		em.AddMetric("tls_handshake_latency", prr.tlsHandshakeLatency)
+		em.AddMetric("tls_handshake_failures", &prr.tlsHandshakeFailures)
+	}
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	if prr.exportIPChanges {
+		em.AddMetric("ip_change_total", &prr.ipChanges)
+	}
+	if prr.rawOutcome != nil {
+		em.AddMetric("raw_outcome", prr.rawOutcome)
+	}
+	if prr.port != 0 {
+		em.AddLabel("port", strconv.Itoa(prr.port))
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// ipResult is a resolved_ip gauge update for a target. As results are
+// aggregated per target and labels, each IP gets its own gauge: 1 for the
+// current IP and 0 for the previous ones.
+type ipResult struct {
+	target  string
+	ip      string
+	current bool
+}
+
+// Metrics converts ipResult into metrics.EventMetrics object
+func (ir ipResult) Metrics() *metrics.EventMetrics {
+	var v int64
+	if ir.current {
+		v = 1
+	}
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("resolved_ip", metrics.NewInt(v)).
+		AddLabel("ip", ir.ip)
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the ir.target.
+func (ir ipResult) Target() string {
+	return ir.target
+}
+
+// certResult is a gauge update for the server certificate's expiry and chain
+// depth, labeled with the negotiated TLS version, cipher suite and the
+// certificate's issuer.
+type certResult struct {
+	target     string
+	port       int // Set only if probing multiple ports.
+	version    string
+	cipher     string
+	issuer     string
+	expirySec  int64 // Negative if the certificate has expired.
+	chainDepth int64
+}
+
+// Metrics converts certResult into metrics.EventMetrics object
+func (cr certResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("cert_expiry_seconds", metrics.NewInt(cr.expirySec)).
+		AddMetric("cert_chain_depth", metrics.NewInt(cr.chainDepth)).
+		AddLabel("tls_version", cr.version).
+		AddLabel("cipher", cr.cipher).
+		AddLabel("issuer", cr.issuer)
+	if cr.port != 0 {
+		em.AddLabel("port", strconv.Itoa(cr.port))
+	}
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the cr.target.
+func (cr certResult) Target() string {
+	return cr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+
+	if p.resolvedIPs != nil {
+		p.resolvedIPsMu.Lock()
+		defer p.resolvedIPsMu.Unlock()
+		active := make(map[string]bool, len(p.targets))
+		for _, target := range p.targets {
+			active[target.Name] = true
+		}
+		for name := range p.resolvedIPs {
+			if !active[name] {
+				delete(p.resolvedIPs, name)
+			}
+		}
+	}
+}
+
+// updateResolvedIP records the target's resolved IP and, if it has changed
+// since the last probe cycle, records the change in the result.
+func (p *Probe) updateResolvedIP(target string, ip net.IP, result *probeRunResult) {
+	p.resolvedIPsMu.Lock()
+	defer p.resolvedIPsMu.Unlock()
+
+	lastIP, ok := p.resolvedIPs[target]
+	if ok && lastIP == ip.String() {
+		return
+	}
+	p.resolvedIPs[target] = ip.String()
+
+	if ok {
+		p.l.Infof("Target(%s): resolved IP changed from %s to %s", target, lastIP, ip.String())
+		result.ipChanges.Inc()
+		result.ipUpdates = append(result.ipUpdates, ipResult{target: target, ip: lastIP})
+	}
+	result.ipUpdates = append(result.ipUpdates, ipResult{target: target, ip: ip.String(), current: true})
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no tcp config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("tcp_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+	if len(p.c.GetPorts()) > 0 {
+		if p.c.GetPort() != 0 || p.c.GetPortLabel() != "" {
+			return fmt.Errorf("tcp_probe(%s): ports can't be used with port or port_label", name)
+		}
+		for _, port := range p.c.GetPorts() {
+			if port <= 0 || port > 65535 {
+				return fmt.Errorf("tcp_probe(%s): invalid port in ports: %d", name, port)
+			}
+		}
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	p.dialer = &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		p.dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	var controls []func(network, address string, c syscall.RawConn) error
+	if algo := p.c.GetTcpCongestion(); algo != "" {
+		if probeutils.TCPCongestionSupported {
+			controls = append(controls, probeutils.TCPCongestionControl(algo))
+		} else {
+			p.l.Warningf("tcp_probe(%s): tcp_congestion is not supported on this platform, ignoring it", name)
+		}
+	}
+	if fl := p.opts.IPv6FlowLabel; fl != 0 {
+		if probeutils.IPv6FlowLabelSupported {
+			// Flow label control connects the socket itself, so it needs to
+			// bind it to the source address as well.
+			laddr, _ := p.dialer.LocalAddr.(*net.TCPAddr)
+			p.dialer.LocalAddr = nil
+			controls = append(controls, probeutils.IPv6FlowLabelControl(fl, laddr))
+		} else {
+			p.l.Warningf("tcp_probe(%s): ipv6_flow_label is not supported on this platform, ignoring it", name)
+		}
+	}
+	if len(controls) > 0 {
+		p.dialer.Control = func(network, address string, c syscall.RawConn) error {
+			for _, control := range controls {
+				if err := control(network, address, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	p.dialF = p.opts.DialContextFunc(p.dialer)
+
+	if p.c.GetExportHandshakeRtt() {
+		if !handshakeRTTSupported {
+			p.l.Warningf("tcp_probe(%s): export_handshake_rtt is not supported on this platform, not exporting handshake RTT", name)
+		} else {
+			p.exportRTT = true
+			p.handshakeF = handshakeRTT
+			if p.opts.LatencyDist != nil {
+				p.rttDist = p.opts.LatencyDist.Clone().(*metrics.Distribution)
+			} else {
+				scale := defaultRTTDistScale.Seconds() / p.opts.LatencyUnit.Seconds()
+				d, err := metrics.NewExponentialDistribution(defaultRTTDistBase, scale, defaultRTTDistNumBuckets)
+				if err != nil {
+					return err
+				}
+				p.rttDist = d
+			}
+		}
+	}
+
+	if p.c.GetExportIpChanges() {
+		if !p.c.GetResolveFirst() {
+			return fmt.Errorf("tcp_probe(%s): export_ip_changes requires resolve_first", name)
+		}
+		p.resolvedIPs = make(map[string]string)
+	}
+
+	if p.c.GetTls() != nil {
+		if p.c.GetExpectFailure() {
+			return fmt.Errorf("tcp_probe(%s): tls can't be used with expect_failure", name)
+		}
+		p.tlsConfig = &tls.Config{}
+		if err := tlsconfig.UpdateTLSConfig(p.tlsConfig, p.c.GetTls(), false); err != nil {
+			return fmt.Errorf("tcp_probe(%s): %v", name, err)
+		}
+	}
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+
+		exportConnectTimeouts: p.opts.ConnectTimeout > 0,
+		exportDNSTimeouts:     p.opts.DNSResolveTimeout > 0,
+		exportIPChanges:       p.resolvedIPs != nil,
+	}
+
+	if p.opts.LatencyDist != nil {
+		result.latency = p.opts.LatencyDist.Clone()
+	} else {
+		result.latency = metrics.NewFloat(0)
+	}
+
+	if p.exportRTT {
+		result.handshakeRTT = p.rttDist.Clone().(*metrics.Distribution)
+	}
+
+	if p.tlsConfig != nil {
+		if p.opts.LatencyDist != nil {
+			result.tlsHandshakeLatency = p.opts.LatencyDist.Clone()
+		} else {
+			result.tlsHandshakeLatency = metrics.NewFloat(0)
+		}
+	}
+
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+
+	if p.c.GetExpectFailure() {
+		result.rawOutcome = metrics.NewMap("outcome", metrics.NewInt(0))
+	}
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// targetAddr returns the address to connect to for the given target and
+// port. If port is 0, it's determined from the config and the target. Target
+// is resolved for the given IP version if resolve_first is set or if IP
+// version fallback is configured, in which case the resolved IP is returned
+// as well.
+func (p *Probe) targetAddr(target endpoint.Endpoint, port, ipVer int) (string, net.IP, error) {
+	if port == 0 {
+		port = probeutils.TargetPort(target, p.c.GetPortLabel(), int(p.c.GetPort()), p.l)
+	}
+	if port == 0 {
+		return "", nil, errors.New("no port configured for the target")
+	}
+
+	host := target.Name
+	var ip net.IP
+	if p.c.GetResolveFirst() || p.opts.FallbackIPVersion != 0 {
+		var err error
+		if ip, err = p.opts.Resolve(target.Name, ipVer); err != nil {
+			return "", nil, fmt.Errorf("resolve error: %w", err)
+		}
+		host = ip.String()
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), ip, nil
+}
+
+// tlsHandshake completes a TLS handshake over the connection, within the
+// context's deadline, and returns the handshake latency and the server
+// certificate's result.
+func (p *Probe) tlsHandshake(ctx context.Context, conn net.Conn, target endpoint.Endpoint, port int) (time.Duration, *certResult, error) {
+	tlsConfig := p.tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = target.Name
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	start := time.Now()
+	if err := tlsConn.Handshake(); err != nil {
+		return 0, nil, err
+	}
+	latency := time.Since(start)
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return latency, nil, nil
+	}
+	leaf := state.PeerCertificates[0]
+	issuer := leaf.Issuer.CommonName
+	if issuer == "" {
+		issuer = leaf.Issuer.String()
+	}
+	return latency, &certResult{
+		target:     target.Name,
+		port:       port,
+		version:    tlsconfig.VersionName(state.Version),
+		cipher:     tls.CipherSuiteName(state.CipherSuite),
+		issuer:     issuer,
+		expirySec:  int64(time.Until(leaf.NotAfter) / time.Second),
+		chainDepth: int64(len(state.PeerCertificates)),
+	}, nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	// If IP version fallback is configured, we try the fallback IP version if
+	// we fail to resolve or connect using the preferred IP version.
+	ipVers := []int{p.opts.IPVersion}
+	if p.opts.FallbackIPVersion != 0 {
+		ipVers = append(ipVers, p.opts.FallbackIPVersion)
+	}
+
+	var conn net.Conn
+	var latency time.Duration
+	var timedOut, connectTimedOut, dnsTimedOut, ipTracked bool
+	for _, ipVer := range ipVers {
+		addr, ip, err := p.targetAddr(target, result.port, ipVer)
+		if err != nil {
+			p.l.Warningf("Target(%s): %v", target.Name, err)
+			timedOut, connectTimedOut = false, false
+			dnsTimedOut = options.IsDNSTimeout(err)
+			continue
+		}
+		dnsTimedOut = false
+
+		// Track the first address that we resolve to in this cycle.
+		if p.resolvedIPs != nil && !ipTracked && !result.onDemand {
+			p.updateResolvedIP(target.Name, ip, result)
+			ipTracked = true
+		}
+
+		start := time.Now()
+		conn, err = p.dialF(ctx, "tcp", addr)
+		latency = time.Since(start)
+
+		if err == nil {
+			if result.ipVersionUsed != nil {
+				result.ipVersionUsed.IncKey(strconv.Itoa(ipVer))
+			}
+			break
+		}
+
+		connectTimedOut = options.IsConnectTimeout(err)
+		if timedOut = isTimeout(err); timedOut {
+			p.l.Warningf("Target(%s): timeout connecting to %s: %v", target.Name, addr, err)
+		} else {
+			p.l.Warningf("Target(%s): error connecting to %s: %v", target.Name, addr, err)
+		}
+	}
+
+	if conn == nil {
+		if dnsTimedOut {
+			result.dnsTimeouts.Inc()
+		} else if connectTimedOut {
+			result.connectTimeouts.Inc()
+		} else if timedOut {
+			result.timeouts.Inc()
+		}
+		if result.rawOutcome != nil {
+			result.rawOutcome.IncKey("failure")
+			result.success.Inc()
+		}
+		return
+	}
+	defer conn.Close()
+
+	if result.rawOutcome != nil {
+		p.l.Warningf("Target(%s): connected while expecting failure", target.Name)
+		result.rawOutcome.IncKey("success")
+		return
+	}
+
+	// Connect latency remains the probe's latency, TLS handshake latency is
+	// exported separately.
+	if p.tlsConfig != nil {
+		tlsLatency, cert, err := p.tlsHandshake(ctx, conn, target, result.port)
+		if err != nil {
+			p.l.Warningf("Target(%s): TLS handshake failed: %v", target.Name, err)
+			if isTimeout(err) {
+				result.timeouts.Inc()
+			}
+			result.tlsHandshakeFailures.Inc()
+			return
+		}
+		result.tlsHandshakeLatency.AddFloat64(tlsLatency.Seconds() / p.opts.LatencyUnit.Seconds())
+		result.cert = cert
+	}
+
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+
+	if result.handshakeRTT != nil {
+		rtt, err := p.handshakeF(conn)
+		if err != nil {
+			p.l.Warningf("Target(%s): error getting handshake RTT: %v", target.Name, err)
+			return
+		}
+		result.handshakeRTT.AddFloat64(rtt.Seconds() / p.opts.LatencyUnit.Seconds())
+	}
+}
+
+// probePorts returns the ports to probe for each target: the configured ports,
+// or a single 0 to use the port from the target or the port option.
+func (p *Probe) probePorts() []int {
+	if len(p.c.GetPorts()) == 0 {
+		return []int{0}
+	}
+	var ports []int
+	for _, port := range p.c.GetPorts() {
+		ports = append(ports, int(port))
+	}
+	return ports
+}
+
+// failureReason describes the failure of a probe run.
+func (prr *probeRunResult) failureReason() string {
+	switch {
+	case prr.tlsHandshakeFailures.Int64() > 0:
+		return "TLS handshake failed"
+	case prr.dnsTimeouts.Int64() > 0:
+		return "target resolution timed out"
+	case prr.connectTimeouts.Int64() > 0:
+		return "connection setup timed out"
+	case prr.timeouts.Int64() > 0:
+		return "connection timed out"
+	case prr.rawOutcome != nil:
+		return "connected while expecting failure"
+	default:
+		return "connection failed"
+	}
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics. With multiple
+// ports, the run succeeds only if all the ports succeed, and its latency is
+// the highest of the ports' latencies.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	var latency time.Duration
+	for _, port := range p.probePorts() {
+		result := p.newResult(target.Name)
+		result.port = port
+		result.onDemand = true
+		p.runProbeForTarget(ctx, target, &result)
+
+		if result.success.Int64() == 0 {
+			if port != 0 {
+				return 0, fmt.Errorf("port %d: %s", port, result.failureReason())
+			}
+			return 0, errors.New(result.failureReason())
+		}
+		if result.rawLatency > latency {
+			latency = result.rawLatency
+		}
+	}
+	return latency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	// With multiple ports, each port is probed independently, in parallel.
+	ports := p.probePorts()
+
+	probeF := func(target endpoint.Endpoint) {
+		var wg sync.WaitGroup
+		for _, port := range ports {
+			wg.Add(1)
+			go func(port int) {
+				defer wg.Done()
+				result := p.newResult(target.Name)
+				result.port = port
+				p.runProbeForTarget(ctx, target, &result)
+				resultsChan <- result
+				for _, ir := range result.ipUpdates {
+					resultsChan <- ir
+				}
+				if result.cert != nil {
+					resultsChan <- *result.cert
+				}
+			}(port)
+		}
+		wg.Wait()
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		for _, port := range ports {
+			result := p.newResult(target.Name)
+			result.port = port
+			result.skipped.Inc()
+			resultsChan <- result
+		}
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets)*(len(p.c.GetPorts())+1))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "tcp", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
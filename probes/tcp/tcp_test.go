@@ -0,0 +1,779 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	tlsconfigpb "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/tcp/proto"
+	"github.com/cloudprober/cloudprober/targets"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+// testListener starts a TCP listener on localhost that accepts and closes
+// connections. It returns the listener's port.
+func testListener(t *testing.T) (net.Listener, int) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln, ln.Addr().(*net.TCPAddr).Port
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	opts := options.DefaultOptions()
+	opts.Targets = targets.StaticTargets("127.0.0.1")
+	opts.Timeout = time.Second
+	opts.ProbeConf = c
+
+	p := &Probe{}
+	if err := p.Init("tcp_test", opts); err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	// Get a port that's not listening anymore.
+	closedLn, closedPort := testListener(t)
+	closedLn.Close()
+
+	for _, test := range []struct {
+		desc        string
+		confPort    int32
+		targetPort  int
+		wantSuccess int64
+	}{
+		{
+			desc:        "port_from_config",
+			confPort:    int32(port),
+			wantSuccess: 1,
+		},
+		{
+			desc:        "port_from_target",
+			targetPort:  port,
+			wantSuccess: 1,
+		},
+		{
+			desc:     "closed_port",
+			confPort: int32(closedPort),
+		},
+		{
+			desc: "no_port",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{Port: proto.Int32(test.confPort)})
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1", Port: test.targetPort}, &result)
+
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+			if result.handshakeRTT != nil {
+				t.Errorf("Got handshake RTT metric without export_handshake_rtt: %v", result.handshakeRTT)
+			}
+		})
+	}
+}
+
+func TestHandshakeRTT(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Handshake RTT is supported only on Linux")
+	}
+
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	p := testProbe(t, &configpb.ProbeConf{
+		Port:               proto.Int32(int32(port)),
+		ExportHandshakeRtt: proto.Bool(true),
+	})
+
+	result := p.newResult("127.0.0.1")
+	for i := 0; i < 3; i++ {
+		p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+	}
+
+	if result.success.Int64() != 3 {
+		t.Fatalf("Got success=%d, want=3", result.success.Int64())
+	}
+
+	em := result.Metrics()
+	d, ok := em.Metric("handshake_rtt").(*metrics.Distribution)
+	if !ok {
+		t.Fatalf("handshake_rtt metric is not a distribution: %v", em.Metric("handshake_rtt"))
+	}
+
+	dpb := d.Data()
+	if dpb.Count != 3 {
+		t.Errorf("Got handshake RTT count=%d, want=3", dpb.Count)
+	}
+	// Loopback RTT (in microseconds, default latency unit) should be
+	// positive, and shouldn't be more than the probe timeout.
+	if dpb.Sum <= 0 || dpb.Sum > 3*time.Second.Seconds()*1e6 {
+		t.Errorf("Implausible handshake RTT sum: %v us", dpb.Sum)
+	}
+}
+
+// dualStackTargets mocks targets that resolve to different addresses for
+// IPv4 and IPv6.
+type dualStackTargets struct {
+	targets.Targets
+	addrs map[string][2]string // IPv4 and IPv6 address for each target.
+}
+
+func (dt *dualStackTargets) Resolve(name string, ipVer int) (net.IP, error) {
+	addr := dt.addrs[name][0]
+	if ipVer == 6 {
+		addr = dt.addrs[name][1]
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("no IPv%d address for %s", ipVer, name)
+	}
+	return net.ParseIP(addr), nil
+}
+
+func TestIPVersionFallback(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	// Nothing listens on the IPv6 loopback address at this port, so
+	// connections over IPv6 fail.
+	addrs := map[string][2]string{
+		"dual":   {"127.0.0.1", "::1"},
+		"v6only": {"", "::1"},
+	}
+
+	for _, test := range []struct {
+		target            string
+		ipVer, fallback   int
+		wantSuccess       int64
+		wantIPVersionUsed string
+	}{
+		{target: "dual", ipVer: 6, fallback: 4, wantSuccess: 1, wantIPVersionUsed: "4"},
+		{target: "dual", ipVer: 4, fallback: 6, wantSuccess: 1, wantIPVersionUsed: "4"},
+		{target: "v6only", ipVer: 6, fallback: 4},
+		// No fallback.
+		{target: "dual", ipVer: 6},
+	} {
+		t.Run(fmt.Sprintf("%s,%d,%d", test.target, test.ipVer, test.fallback), func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{Port: proto.Int32(int32(port))})
+			p.opts.Targets = &dualStackTargets{Targets: p.opts.Targets, addrs: addrs}
+			p.opts.IPVersion, p.opts.FallbackIPVersion = test.ipVer, test.fallback
+
+			result := p.newResult(test.target)
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: test.target}, &result)
+
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+
+			if test.fallback == 0 {
+				if result.ipVersionUsed != nil {
+					t.Errorf("Got ip_version_used metric without fallback: %s", result.ipVersionUsed.String())
+				}
+				return
+			}
+
+			var gotIPVersionUsed string
+			for _, k := range result.ipVersionUsed.Keys() {
+				gotIPVersionUsed += k
+			}
+			if gotIPVersionUsed != test.wantIPVersionUsed {
+				t.Errorf("Got ip_version_used=%s, want=%s", result.ipVersionUsed.String(), test.wantIPVersionUsed)
+			}
+			if test.wantSuccess == 1 && result.Metrics().Metric("ip_version_used") == nil {
+				t.Errorf("ip_version_used metric missing in EventMetrics: %s", result.Metrics().String())
+			}
+		})
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	for _, test := range []struct {
+		desc                      string
+		connectTimeout, dialDelay time.Duration
+		wantSuccess, wantTimeouts int64
+		wantConnectTimeouts       int64
+	}{
+		{desc: "fast_connect", connectTimeout: 500 * time.Millisecond, wantSuccess: 1},
+		{desc: "slow_connect", connectTimeout: 50 * time.Millisecond, dialDelay: 100 * time.Millisecond, wantConnectTimeouts: 1},
+		// Overall timeout (200ms) expires before the connect timeout.
+		{desc: "slow_connect_overall_timeout", connectTimeout: 200 * time.Millisecond, dialDelay: 300 * time.Millisecond, wantTimeouts: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			opts := options.DefaultOptions()
+			opts.Targets = targets.StaticTargets("127.0.0.1")
+			opts.Timeout = 200 * time.Millisecond
+			opts.ConnectTimeout = test.connectTimeout
+			opts.ProbeConf = &configpb.ProbeConf{Port: proto.Int32(int32(port))}
+
+			p := &Probe{}
+			if err := p.Init("tcp_test", opts); err != nil {
+				t.Fatalf("Error initializing probe: %v", err)
+			}
+			// Simulate a slow handshake by delaying the connection setup.
+			p.dialer.Control = func(_, _ string, _ syscall.RawConn) error {
+				time.Sleep(test.dialDelay)
+				return nil
+			}
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+			if result.success.Int64() != test.wantSuccess || result.timeouts.Int64() != test.wantTimeouts || result.connectTimeouts.Int64() != test.wantConnectTimeouts {
+				t.Errorf("Got success=%d, timeouts=%d, connect_timeouts=%d, want: %d, %d, %d", result.success.Int64(), result.timeouts.Int64(), result.connectTimeouts.Int64(), test.wantSuccess, test.wantTimeouts, test.wantConnectTimeouts)
+			}
+			if result.Metrics().Metric("connect_timeouts") == nil {
+				t.Errorf("connect_timeouts metric missing in EventMetrics: %s", result.Metrics().String())
+			}
+		})
+	}
+}
+
+func TestStartSchedule(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	for _, test := range []struct {
+		cron      string
+		wantCycle bool
+	}{
+		{cron: "* * * * *", wantCycle: true},
+		{cron: "0 0 30 2 *"}, // Never matches: February 30.
+	} {
+		t.Run(test.cron, func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{Port: proto.Int32(int32(port))})
+			p.opts.Interval, p.opts.StatsExportInterval = 10*time.Millisecond, 20*time.Millisecond
+			schedule, err := options.NewSchedule(test.cron, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.opts.Schedule = schedule
+
+			ctx, cancel := context.WithCancel(context.Background())
+			dataChan := make(chan *metrics.EventMetrics, 100)
+			done := make(chan struct{})
+			go func() {
+				p.Start(ctx, dataChan)
+				close(done)
+			}()
+			time.Sleep(200 * time.Millisecond)
+			cancel()
+			<-done
+
+			// Outside the schedule, no cycles run and no metrics are exported.
+			if gotCycle := len(dataChan) > 0; gotCycle != test.wantCycle {
+				t.Errorf("Got %d EventMetrics, want cycles: %v", len(dataChan), test.wantCycle)
+			}
+		})
+	}
+}
+
+// changingTargets mocks targets whose resolved IP changes across probe
+// cycles: nth resolution returns ips[n].
+type changingTargets struct {
+	targets.Targets
+	ips []string
+	n   int
+}
+
+func (ct *changingTargets) Resolve(name string, ipVer int) (net.IP, error) {
+	ip := ct.ips[ct.n]
+	ct.n++
+	return net.ParseIP(ip), nil
+}
+
+func TestIPChanges(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	p := testProbe(t, &configpb.ProbeConf{
+		Port:            proto.Int32(int32(port)),
+		ExportIpChanges: proto.Bool(true),
+	})
+	p.opts.Targets = &changingTargets{
+		Targets: p.opts.Targets,
+		ips:     []string{"127.0.0.1", "127.0.0.1", "127.0.0.2", "127.0.0.2", "127.0.0.1"},
+	}
+
+	var ipChanges []int64
+	resolvedIP := make(map[string]int64)
+	for i := 0; i < 5; i++ {
+		resultsChan := make(chan statskeeper.ProbeResult, 10)
+		p.runProbe(context.Background(), resultsChan)
+		close(resultsChan)
+
+		var cycleChanges int64
+		for r := range resultsChan {
+			em := r.Metrics()
+			if m := em.Metric("ip_change_total"); m != nil {
+				cycleChanges += m.(metrics.NumValue).Int64()
+				continue
+			}
+			if em.Kind != metrics.GAUGE {
+				t.Errorf("Got resolved_ip EventMetrics of kind %v, want GAUGE", em.Kind)
+			}
+			resolvedIP[em.Label("ip")] = em.Metric("resolved_ip").(metrics.NumValue).Int64()
+		}
+		ipChanges = append(ipChanges, cycleChanges)
+	}
+
+	// First resolution doesn't count as a change.
+	wantIPChanges := []int64{0, 0, 1, 0, 1}
+	if !reflect.DeepEqual(ipChanges, wantIPChanges) {
+		t.Errorf("Got ip_change_total per cycle: %v, want: %v", ipChanges, wantIPChanges)
+	}
+	wantResolvedIP := map[string]int64{"127.0.0.1": 1, "127.0.0.2": 0}
+	if !reflect.DeepEqual(resolvedIP, wantResolvedIP) {
+		t.Errorf("Got resolved_ip gauges: %v, want: %v", resolvedIP, wantResolvedIP)
+	}
+
+	// Without resolve_first, there is nothing to track.
+	if err := (&Probe{}).Init("tcp_test", &options.Options{
+		Targets: targets.StaticTargets("127.0.0.1"),
+		ProbeConf: &configpb.ProbeConf{
+			ResolveFirst:    proto.Bool(false),
+			ExportIpChanges: proto.Bool(true),
+		},
+	}); err == nil {
+		t.Error("Init(): expected error for export_ip_changes without resolve_first")
+	}
+}
+
+func TestTCPCongestion(t *testing.T) {
+	if !probeutils.TCPCongestionSupported {
+		t.Skip("tcp_congestion is not supported on this platform")
+	}
+
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	for algo, wantSuccess := range map[string]int64{"reno": 1, "no-such-algo": 0} {
+		p := testProbe(t, &configpb.ProbeConf{
+			Port:          proto.Int32(int32(port)),
+			TcpCongestion: proto.String(algo),
+		})
+
+		result := p.newResult("127.0.0.1")
+		p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+		if result.total.Int64() != 1 || result.success.Int64() != wantSuccess {
+			t.Errorf("tcp_congestion=%s: got total=%d, success=%d, want total=1, success=%d", algo, result.total.Int64(), result.success.Int64(), wantSuccess)
+		}
+	}
+}
+
+func TestPortLabel(t *testing.T) {
+	ln1, port1 := testListener(t)
+	defer ln1.Close()
+	ln2, port2 := testListener(t)
+	defer ln2.Close()
+
+	closedLn, closedPort := testListener(t)
+	closedLn.Close()
+
+	for _, test := range []struct {
+		desc        string
+		labels      map[string]string
+		wantSuccess int64
+	}{
+		{
+			desc:        "port_from_label_1",
+			labels:      map[string]string{"port": fmt.Sprint(port1)},
+			wantSuccess: 1,
+		},
+		{
+			desc:        "port_from_label_2",
+			labels:      map[string]string{"port": fmt.Sprint(port2)},
+			wantSuccess: 1,
+		},
+		{
+			desc:   "closed_port_from_label",
+			labels: map[string]string{"port": fmt.Sprint(closedPort)},
+		},
+		{
+			// Falls back to the configured port.
+			desc:        "invalid_port_label",
+			labels:      map[string]string{"port": "http"},
+			wantSuccess: 1,
+		},
+		{
+			desc:        "no_port_label",
+			wantSuccess: 1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{
+				Port:      proto.Int32(int32(port1)),
+				PortLabel: proto.String("port"),
+			})
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1", Labels: test.labels}, &result)
+
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestMultiplePorts(t *testing.T) {
+	ln1, port1 := testListener(t)
+	defer ln1.Close()
+	ln2, port2 := testListener(t)
+	defer ln2.Close()
+
+	closedLn, closedPort := testListener(t)
+	closedLn.Close()
+
+	p := testProbe(t, &configpb.ProbeConf{
+		Ports: []int32{int32(port1), int32(closedPort), int32(port2)},
+	})
+
+	resultsChan := make(chan statskeeper.ProbeResult, 10)
+	p.runProbe(context.Background(), resultsChan)
+	close(resultsChan)
+
+	gotSuccess := make(map[string]int64)
+	for r := range resultsChan {
+		em := r.Metrics()
+		if got := em.Metric("total").(metrics.NumValue).Int64(); got != 1 {
+			t.Errorf("Port %s: got total=%d, want=1", em.Label("port"), got)
+		}
+		gotSuccess[em.Label("port")] = em.Metric("success").(metrics.NumValue).Int64()
+	}
+
+	wantSuccess := map[string]int64{
+		fmt.Sprint(port1):      1,
+		fmt.Sprint(port2):      1,
+		fmt.Sprint(closedPort): 0,
+	}
+	if !reflect.DeepEqual(gotSuccess, wantSuccess) {
+		t.Errorf("Got success per port: %v, want: %v", gotSuccess, wantSuccess)
+	}
+
+	// Invalid configs.
+	for _, c := range []*configpb.ProbeConf{
+		{Ports: []int32{int32(port1)}, Port: proto.Int32(int32(port1))},
+		{Ports: []int32{int32(port1)}, PortLabel: proto.String("port")},
+		{Ports: []int32{0}},
+		{Ports: []int32{70000}},
+	} {
+		if err := (&Probe{}).Init("tcp_test", &options.Options{
+			Targets:   targets.StaticTargets("127.0.0.1"),
+			ProbeConf: c,
+		}); err == nil {
+			t.Errorf("Init(): expected error for the config: %v", c)
+		}
+	}
+}
+
+// slowTargets mocks targets that take the given delay to resolve.
+type slowTargets struct {
+	targets.Targets
+	delay time.Duration
+}
+
+func (st *slowTargets) Resolve(name string, ipVer int) (net.IP, error) {
+	time.Sleep(st.delay)
+	return net.ParseIP("127.0.0.1"), nil
+}
+
+func TestDNSResolveTimeout(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	for _, test := range []struct {
+		desc            string
+		delay           time.Duration
+		wantSuccess     int64
+		wantDNSTimeouts int64
+	}{
+		{desc: "fast_resolution", delay: 10 * time.Millisecond, wantSuccess: 1},
+		{desc: "slow_resolution", delay: 500 * time.Millisecond, wantDNSTimeouts: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			opts := options.DefaultOptions()
+			opts.Targets = &slowTargets{Targets: targets.StaticTargets("localhost"), delay: test.delay}
+			opts.Timeout = time.Second
+			opts.DNSResolveTimeout = 100 * time.Millisecond
+			opts.ProbeConf = &configpb.ProbeConf{Port: proto.Int32(int32(port))}
+
+			p := &Probe{}
+			if err := p.Init("tcp_test", opts); err != nil {
+				t.Fatalf("Error initializing probe: %v", err)
+			}
+
+			result := p.newResult("localhost")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "localhost"}, &result)
+
+			if result.success.Int64() != test.wantSuccess || result.dnsTimeouts.Int64() != test.wantDNSTimeouts {
+				t.Errorf("Got success=%d, dns_timeouts=%d, want success=%d, dns_timeouts=%d", result.success.Int64(), result.dnsTimeouts.Int64(), test.wantSuccess, test.wantDNSTimeouts)
+			}
+			if result.timeouts.Int64() != 0 {
+				t.Errorf("Got timeouts=%d, want DNS timeouts to be counted separately", result.timeouts.Int64())
+			}
+			if result.Metrics().Metric("dns_timeouts") == nil {
+				t.Error("dns_timeouts metric is not exported")
+			}
+		})
+	}
+}
+
+func TestExpectFailure(t *testing.T) {
+	ln, port := testListener(t)
+	defer ln.Close()
+
+	closedLn, closedPort := testListener(t)
+	closedLn.Close()
+
+	for _, test := range []struct {
+		desc           string
+		port           int
+		wantSuccess    int64
+		wantRawOutcome string
+	}{
+		{
+			desc:           "closed_port",
+			port:           closedPort,
+			wantSuccess:    1,
+			wantRawOutcome: "map:outcome,failure:1",
+		},
+		{
+			desc:           "open_port",
+			port:           port,
+			wantRawOutcome: "map:outcome,success:1",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{
+				Port:          proto.Int32(int32(test.port)),
+				ExpectFailure: proto.Bool(true),
+			})
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+			if got := result.Metrics().Metric("raw_outcome").String(); got != test.wantRawOutcome {
+				t.Errorf("Got raw_outcome=%s, want=%s", got, test.wantRawOutcome)
+			}
+		})
+	}
+}
+
+func TestRunOnDemand(t *testing.T) {
+	ln1, port1 := testListener(t)
+	defer ln1.Close()
+	ln2, port2 := testListener(t)
+	defer ln2.Close()
+
+	closedLn, closedPort := testListener(t)
+	closedLn.Close()
+
+	target := endpoint.Endpoint{Name: "127.0.0.1"}
+
+	for _, test := range []struct {
+		desc    string
+		conf    *configpb.ProbeConf
+		wantErr string
+	}{
+		{
+			desc: "success",
+			conf: &configpb.ProbeConf{Port: proto.Int32(int32(port1))},
+		},
+		{
+			desc:    "failure",
+			conf:    &configpb.ProbeConf{Port: proto.Int32(int32(closedPort))},
+			wantErr: "connection failed",
+		},
+		{
+			desc: "multiple_ports",
+			conf: &configpb.ProbeConf{Ports: []int32{int32(port1), int32(port2)}},
+		},
+		{
+			desc:    "multiple_ports_failure",
+			conf:    &configpb.ProbeConf{Ports: []int32{int32(port1), int32(closedPort)}},
+			wantErr: fmt.Sprintf("port %d: connection failed", closedPort),
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, test.conf)
+
+			latency, err := p.RunOnDemand(context.Background(), target)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("RunOnDemand(): got error %v, want: %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunOnDemand(): unexpected error: %v", err)
+			}
+			if latency <= 0 {
+				t.Errorf("RunOnDemand(): got latency %v, want > 0", latency)
+			}
+		})
+	}
+
+	// On-demand runs don't track the resolved IPs.
+	p := testProbe(t, &configpb.ProbeConf{
+		Port:            proto.Int32(int32(port1)),
+		ExportIpChanges: proto.Bool(true),
+	})
+	if _, err := p.RunOnDemand(context.Background(), target); err != nil {
+		t.Fatalf("RunOnDemand(): unexpected error: %v", err)
+	}
+	if len(p.resolvedIPs) != 0 {
+		t.Errorf("Got resolved IPs tracked after an on-demand run: %v", p.resolvedIPs)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	tlsPort := ts.Listener.Addr().(*net.TCPAddr).Port
+
+	// Plain TCP listener, which closes the connections without a handshake.
+	ln, plainPort := testListener(t)
+	defer ln.Close()
+
+	for _, test := range []struct {
+		desc        string
+		port        int
+		tlsConf     *tlsconfigpb.TLSConfig
+		wantSuccess int64
+	}{
+		{
+			desc:        "no_cert_validation",
+			port:        tlsPort,
+			tlsConf:     &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+			wantSuccess: 1,
+		},
+		{
+			desc:    "untrusted_cert",
+			port:    tlsPort,
+			tlsConf: &tlsconfigpb.TLSConfig{},
+		},
+		{
+			desc:    "no_tls",
+			port:    plainPort,
+			tlsConf: &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{
+				Port: proto.Int32(int32(test.port)),
+				Tls:  test.tlsConf,
+			})
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+			if result.total.Int64() != 1 || result.success.Int64() != test.wantSuccess {
+				t.Errorf("Got total=%d, success=%d, want total=1, success=%d", result.total.Int64(), result.success.Int64(), test.wantSuccess)
+			}
+			if got, want := result.tlsHandshakeFailures.Int64(), 1-test.wantSuccess; got != want {
+				t.Errorf("Got tls_handshake_failures=%d, want=%d", got, want)
+			}
+			em := result.Metrics()
+			if em.Metric("tls_handshake_latency") == nil || em.Metric("tls_handshake_failures") == nil {
+				t.Errorf("TLS handshake metrics missing from: %s", em.String())
+			}
+
+			if test.wantSuccess == 0 {
+				if result.cert != nil {
+					t.Errorf("Got cert result for a failed handshake: %v", result.cert.Metrics())
+				}
+				return
+			}
+			if got := result.tlsHandshakeLatency.(*metrics.Float).Float64(); got <= 0 {
+				t.Errorf("Got tls_handshake_latency=%v, want > 0", got)
+			}
+			if result.cert == nil {
+				t.Fatal("No cert result for a successful handshake")
+			}
+			certEM := result.cert.Metrics()
+			if certEM.Label("tls_version") != "TLS 1.3" || certEM.Label("issuer") == "" || certEM.Label("cipher") == "" {
+				t.Errorf("Unexpected cert labels: %s", certEM.String())
+			}
+			if got := certEM.Metric("cert_expiry_seconds").(*metrics.Int).Int64(); got <= 0 {
+				t.Errorf("Got cert_expiry_seconds=%d, want > 0", got)
+			}
+			if got := certEM.Metric("cert_chain_depth").(*metrics.Int).Int64(); got != 1 {
+				t.Errorf("Got cert_chain_depth=%d, want=1", got)
+			}
+		})
+	}
+}
+
+func TestTLSInitErrors(t *testing.T) {
+	for _, c := range []*configpb.ProbeConf{
+		{
+			Tls:           &tlsconfigpb.TLSConfig{DisableCertValidation: proto.Bool(true)},
+			ExpectFailure: proto.Bool(true),
+		},
+		{
+			Tls: &tlsconfigpb.TLSConfig{CaCertFile: proto.String("/non-existent/ca.pem")},
+		},
+	} {
+		opts := options.DefaultOptions()
+		opts.Targets = targets.StaticTargets("127.0.0.1")
+		opts.ProbeConf = c
+		if err := (&Probe{}).Init("tcp_test", opts); err == nil {
+			t.Errorf("Init(%v): expected error", c)
+		}
+	}
+}
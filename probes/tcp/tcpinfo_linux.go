@@ -0,0 +1,52 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const handshakeRTTSupported = true
+
+// handshakeRTT returns the connection's RTT as recorded by the kernel
+// (tcpi_rtt). Right after connect, it's the SYN -> SYN-ACK round trip time.
+func handshakeRTT(conn net.Conn) (time.Duration, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return 0, fmt.Errorf("not a TCP connection: %T", conn)
+	}
+
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var info *unix.TCPInfo
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		info, sockErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil {
+		return 0, err
+	}
+	if sockErr != nil {
+		return 0, fmt.Errorf("error getting TCP_INFO: %v", sockErr)
+	}
+
+	return time.Duration(info.Rtt) * time.Microsecond, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package testutils provides utilities for the probes' tests.
+*/
+package testutils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/targets"
+)
+
+// Prober is implemented by the probes. It's a subset of the probes.Probe
+// interface, which can't be used here without an import cycle.
+type Prober interface {
+	Init(name string, opts *options.Options) error
+}
+
+// Options returns the options for a test probe: default options with the
+// given static targets, timeout and probe config.
+func Options(targetsStr string, timeout time.Duration, c interface{}) *options.Options {
+	opts := options.DefaultOptions()
+	opts.Targets = targets.StaticTargets(targetsStr)
+	opts.Timeout = timeout
+	opts.ProbeConf = c
+	return opts
+}
+
+// InitProbe initializes the probe with the given options, failing the test if
+// initialization fails.
+func InitProbe(t *testing.T, p Prober, name string, opts *options.Options) {
+	t.Helper()
+
+	if err := p.Init(name, opts); err != nil {
+		t.Fatalf("Error initializing probe: %v", err)
+	}
+}
+
+// InitErrorTest is a test case for VerifyInitErrors.
+type InitErrorTest struct {
+	Desc string
+	Conf interface{}
+}
+
+// VerifyInitErrors verifies that the probes returned by newProbe fail to
+// initialize with each of the test cases' config.
+func VerifyInitErrors(t *testing.T, newProbe func() Prober, tests []InitErrorTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			if err := newProbe().Init("test_probe", Options("localhost", time.Second, test.Conf)); err == nil {
+				t.Errorf("Expected error for the config: %v", test.Conf)
+			}
+		})
+	}
+}
+
+// CheckRunResult checks the metrics of a single probe run: "total" should be
+// 1, and the run should have succeeded if wantReason is empty, or failed with
+// wantReason as the "failures" key otherwise.
+func CheckRunResult(em *metrics.EventMetrics, wantReason string) error {
+	total := em.Metric("total").(metrics.NumValue).Int64()
+	success := em.Metric("success").(metrics.NumValue).Int64()
+	failures := em.Metric("failures").(*metrics.Map)
+
+	if total != 1 {
+		return fmt.Errorf("got total=%d, want=1", total)
+	}
+
+	if wantReason == "" {
+		if success != 1 {
+			return fmt.Errorf("got success=%d, failures=%s, want success=1", success, failures.String())
+		}
+		return nil
+	}
+
+	if success != 0 {
+		return fmt.Errorf("got success=%d, want=0", success)
+	}
+	if v := failures.GetKey(wantReason); v == nil || v.Int64() != 1 {
+		return fmt.Errorf("got failures=%s, want 1 failure with reason: %s", failures.String(), wantReason)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+func runMetrics(total, success int64, failureReason string) *metrics.EventMetrics {
+	failures := metrics.NewMap("reason", metrics.NewInt(0))
+	if failureReason != "" {
+		failures.IncKey(failureReason)
+	}
+	return metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(total)).
+		AddMetric("success", metrics.NewInt(success)).
+		AddMetric("failures", failures)
+}
+
+func TestCheckRunResult(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		em         *metrics.EventMetrics
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			desc: "success",
+			em:   runMetrics(1, 1, ""),
+		},
+		{
+			desc:    "unexpected_failure",
+			em:      runMetrics(1, 0, "connect"),
+			wantErr: true,
+		},
+		{
+			desc:    "unexpected_total",
+			em:      runMetrics(2, 2, ""),
+			wantErr: true,
+		},
+		{
+			desc:       "failure",
+			em:         runMetrics(1, 0, "connect"),
+			wantReason: "connect",
+		},
+		{
+			desc:       "failure_other_reason",
+			em:         runMetrics(1, 0, "timeout"),
+			wantReason: "connect",
+			wantErr:    true,
+		},
+		{
+			desc:       "unexpected_success",
+			em:         runMetrics(1, 1, ""),
+			wantReason: "connect",
+			wantErr:    true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			err := CheckRunResult(test.em, test.wantReason)
+			if (err != nil) != test.wantErr {
+				t.Errorf("CheckRunResult(%s, %q): got err=%v, want error=%v", test.em.String(), test.wantReason, err, test.wantErr)
+			}
+		})
+	}
+}
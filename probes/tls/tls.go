@@ -0,0 +1,486 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package tls implements a TLS prober. It connects to the targets, completes the
+TLS handshake and verifies the server's certificate, without sending any
+application-layer traffic. It reports statistics on handshake attempts,
+successful handshakes and handshake latency, along with the reason of the
+failures. For every completed handshake, it also reports the server
+certificate's expiry and the depth of the certificate chain presented by the
+server, labeled with the negotiated TLS version, cipher suite and the
+certificate's issuer.
+
+Handshakes with all targets are done in parallel.
+*/
+package tls
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/file"
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/tls/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+const defaultPort = 443
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonDNSTimeout       = "dns_timeout"
+	reasonConnect          = "connect"
+	reasonHandshake        = "handshake"
+	reasonExpired          = "expired"
+	reasonUntrusted        = "untrusted"
+	reasonHostnameMismatch = "hostname_mismatch"
+	reasonInvalidCert      = "invalid_cert"
+	reasonPinMismatch      = "pin_mismatch"
+)
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets []endpoint.Endpoint
+	dialF   func(context.Context, string, string) (net.Conn, error)
+	roots   *x509.CertPool // nil for system's root CAs.
+	pins    [][]byte       // Pinned certificates' SHA-256 fingerprints.
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	latencyMetricName string
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+
+	// Server certificate's details, reported as a separate result. nil if the
+	// handshake didn't complete.
+	cert *certResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures)
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// certResult is a gauge update for the server certificate's expiry and chain
+// depth, labeled with the negotiated TLS version, cipher suite and the
+// certificate's issuer.
+type certResult struct {
+	target     string
+	version    string
+	cipher     string
+	issuer     string
+	expirySec  int64 // Negative if the certificate has expired.
+	chainDepth int64
+}
+
+// Metrics converts certResult into metrics.EventMetrics object
+func (cr certResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("cert_expiry_seconds", metrics.NewInt(cr.expirySec)).
+		AddMetric("cert_chain_depth", metrics.NewInt(cr.chainDepth)).
+		AddLabel("tls_version", cr.version).
+		AddLabel("cipher", cr.cipher).
+		AddLabel("issuer", cr.issuer)
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the cr.target.
+func (cr certResult) Target() string {
+	return cr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// parseFingerprint parses a hex encoded SHA-256 fingerprint, optionally
+// separated by colons.
+func parseFingerprint(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.Replace(s, ":", "", -1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != sha256.Size {
+		return nil, fmt.Errorf("got %d bytes, want %d", len(b), sha256.Size)
+	}
+	return b, nil
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no tls config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("tls_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if p.c.GetCaCertFile() != "" {
+		caCert, err := file.ReadFile(p.c.GetCaCertFile())
+		if err != nil {
+			return fmt.Errorf("tls_probe(%s): error reading CA cert file (%s): %v", name, p.c.GetCaCertFile(), err)
+		}
+		p.roots = x509.NewCertPool()
+		if !p.roots.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("tls_probe(%s): no CA certs found in: %s", name, p.c.GetCaCertFile())
+		}
+	}
+
+	for _, s := range p.c.GetPinnedCertSha256() {
+		pin, err := parseFingerprint(s)
+		if err != nil {
+			return fmt.Errorf("tls_probe(%s): invalid pinned_cert_sha256 (%s): %v", name, s, err)
+		}
+		p.pins = append(p.pins, pin)
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+	}
+
+	if p.opts.LatencyDist != nil {
+		result.latency = p.opts.LatencyDist.Clone()
+	} else {
+		result.latency = metrics.NewFloat(0)
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// targetAddr returns the address to connect to for the given target.
+func (p *Probe) targetAddr(target endpoint.Endpoint) (string, error) {
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+
+	host := target.Name
+	if p.c.GetResolveFirst() {
+		ip, err := p.opts.ResolveTarget(target.Name, nil)
+		if err != nil {
+			return "", fmt.Errorf("resolve error: %w", err)
+		}
+		host = ip.String()
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// verifyFailureReason maps a certificate verification error to the failure
+// reason.
+func verifyFailureReason(err error) string {
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired {
+		return reasonExpired
+	}
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &authorityErr) {
+		return reasonUntrusted
+	}
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return reasonHostnameMismatch
+	}
+	return reasonInvalidCert
+}
+
+// verifyCert verifies the server's certificate chain, unless cert validation
+// is disabled, and pinned fingerprints, if any. It returns the failure
+// reason, along with the error, if verification fails.
+func (p *Probe) verifyCert(certs []*x509.Certificate, serverName string) (string, error) {
+	if len(certs) == 0 {
+		return reasonInvalidCert, errors.New("no certificate from the server")
+	}
+	leaf := certs[0]
+
+	if !p.c.GetDisableCertValidation() {
+		opts := x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         p.roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(opts); err != nil {
+			return verifyFailureReason(err), err
+		}
+	}
+
+	if len(p.pins) == 0 {
+		return "", nil
+	}
+	fp := sha256.Sum256(leaf.Raw)
+	for _, pin := range p.pins {
+		if bytes.Equal(fp[:], pin) {
+			return "", nil
+		}
+	}
+	return reasonPinMismatch, fmt.Errorf("certificate fingerprint %x doesn't match any pinned fingerprint", fp)
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	addr, err := p.targetAddr(target)
+	if err != nil {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if options.IsDNSTimeout(err) {
+			result.failures.IncKey(reasonDNSTimeout)
+		} else {
+			result.failures.IncKey(reasonConnect)
+		}
+		return
+	}
+
+	conn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		p.l.Warningf("Target(%s): error connecting to %s: %v", target.Name, addr, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reasonConnect)
+		return
+	}
+	defer conn.Close()
+	options.RecordIPVersionUsed(result.ipVersionUsed, conn)
+
+	serverName := p.c.GetServerName()
+	if serverName == "" {
+		serverName = target.Name
+	}
+
+	// We verify the certificate ourselves, to report the reason of the
+	// verification failures, and the certificate expiry regardless of them.
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	err = tlsConn.Handshake()
+	latency := time.Since(start)
+	if err != nil {
+		p.l.Warningf("Target(%s): TLS handshake with %s failed: %v", target.Name, addr, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		result.failures.IncKey(reasonHandshake)
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		issuer := leaf.Issuer.CommonName
+		if issuer == "" {
+			issuer = leaf.Issuer.String()
+		}
+		result.cert = &certResult{
+			target:     target.Name,
+			version:    tlsconfig.VersionName(state.Version),
+			cipher:     tls.CipherSuiteName(state.CipherSuite),
+			issuer:     issuer,
+			expirySec:  int64(time.Until(leaf.NotAfter) / time.Second),
+			chainDepth: int64(len(state.PeerCertificates)),
+		}
+	}
+
+	if reason, err := p.verifyCert(state.PeerCertificates, serverName); err != nil {
+		p.l.Warningf("Target(%s): certificate verification failed (%s): %v", target.Name, reason, err)
+		result.failures.IncKey(reason)
+		return
+	}
+
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		if result.cert != nil {
+			resultsChan <- *result.cert
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "tls", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
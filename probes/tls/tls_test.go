@@ -0,0 +1,251 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	configpb "github.com/cloudprober/cloudprober/probes/tls/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+// testServer starts a TLS server with the given certificate, or with the
+// httptest's default certificate (valid for 127.0.0.1 and example.com) if
+// cert is nil. It returns the server and its port.
+func testServer(t *testing.T, cert *tls.Certificate) (*httptest.Server, int32) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	if cert != nil {
+		srv.TLS = &tls.Config{Certificates: []tls.Certificate{*cert}}
+	}
+	srv.StartTLS()
+	return srv, int32(srv.Listener.Addr().(*net.TCPAddr).Port)
+}
+
+// expiredCert returns a self-signed certificate for 127.0.0.1 that expired a
+// day ago.
+func expiredCert(t *testing.T) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "expired"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCAFile writes the certificate to a PEM file and returns its path.
+func writeCAFile(t *testing.T, cert *x509.Certificate) string {
+	t.Helper()
+
+	fname := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(fname, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "tls_test", testutils.Options("127.0.0.1", time.Second, c))
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	srv, port := testServer(t, nil)
+	defer srv.Close()
+	caFile := writeCAFile(t, srv.Certificate())
+	fp := sha256.Sum256(srv.Certificate().Raw)
+
+	expired := expiredCert(t)
+	expiredSrv, expiredPort := testServer(t, expired)
+	defer expiredSrv.Close()
+	expiredCAFile := writeCAFile(t, expired.Leaf)
+
+	// Get a port that's not listening anymore.
+	closedSrv, closedPort := testServer(t, nil)
+	closedSrv.Close()
+
+	for _, test := range []struct {
+		desc        string
+		conf        *configpb.ProbeConf
+		wantReason  string
+		wantExpired bool
+	}{
+		{
+			desc: "trusted_ca",
+			conf: &configpb.ProbeConf{Port: proto.Int32(port), CaCertFile: proto.String(caFile)},
+		},
+		{
+			desc: "trusted_ca_with_sni",
+			conf: &configpb.ProbeConf{
+				Port:       proto.Int32(port),
+				CaCertFile: proto.String(caFile),
+				ServerName: proto.String("example.com"),
+			},
+		},
+		{
+			desc:       "untrusted",
+			conf:       &configpb.ProbeConf{Port: proto.Int32(port)},
+			wantReason: reasonUntrusted,
+		},
+		{
+			desc: "hostname_mismatch",
+			conf: &configpb.ProbeConf{
+				Port:       proto.Int32(port),
+				CaCertFile: proto.String(caFile),
+				ServerName: proto.String("cloudprober.org"),
+			},
+			wantReason: reasonHostnameMismatch,
+		},
+		{
+			desc:        "expired",
+			conf:        &configpb.ProbeConf{Port: proto.Int32(expiredPort), CaCertFile: proto.String(expiredCAFile)},
+			wantReason:  reasonExpired,
+			wantExpired: true,
+		},
+		{
+			desc: "pinned",
+			conf: &configpb.ProbeConf{
+				Port:                  proto.Int32(port),
+				PinnedCertSha256:      []string{fmt.Sprintf("%X", fp)},
+				DisableCertValidation: proto.Bool(true),
+			},
+		},
+		{
+			desc: "pin_mismatch",
+			conf: &configpb.ProbeConf{
+				Port:             proto.Int32(port),
+				CaCertFile:       proto.String(caFile),
+				PinnedCertSha256: []string{fmt.Sprintf("%x", sha256.Sum256([]byte("other")))},
+			},
+			wantReason: reasonPinMismatch,
+		},
+		{
+			desc:        "expired_validation_disabled",
+			conf:        &configpb.ProbeConf{Port: proto.Int32(expiredPort), DisableCertValidation: proto.Bool(true)},
+			wantExpired: true,
+		},
+		{
+			desc:       "closed_port",
+			conf:       &configpb.ProbeConf{Port: proto.Int32(closedPort)},
+			wantReason: reasonConnect,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, test.conf)
+
+			result := p.newResult("127.0.0.1")
+			p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "127.0.0.1"}, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantReason == reasonConnect {
+				if result.cert != nil {
+					t.Errorf("Got certificate result without a handshake: %+v", result.cert)
+				}
+				return
+			}
+			if result.cert == nil {
+				t.Fatal("Got no certificate result")
+			}
+			if result.cert.version == "" || result.cert.cipher == "" {
+				t.Errorf("Missing TLS version or cipher in the certificate result: %+v", result.cert)
+			}
+			if (result.cert.expirySec < 0) != test.wantExpired {
+				t.Errorf("Got cert_expiry_seconds=%d, want expired=%v", result.cert.expirySec, test.wantExpired)
+			}
+			if result.cert.issuer == "" || result.cert.chainDepth != 1 {
+				t.Errorf("Got issuer=%q, cert_chain_depth=%d, want non-empty issuer and chain depth 1", result.cert.issuer, result.cert.chainDepth)
+			}
+		})
+	}
+}
+
+func TestRunProbeIPVersionFallback(t *testing.T) {
+	srv, port := testServer(t, nil)
+	defer srv.Close()
+
+	opts := testutils.Options("localhost", time.Second, &configpb.ProbeConf{
+		Port:                  proto.Int32(port),
+		DisableCertValidation: proto.Bool(true),
+	})
+	// Test server listens on IPv4 only, so connecting over IPv6 fails.
+	opts.IPVersion, opts.FallbackIPVersion = 6, 4
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "tls_test", opts)
+
+	result := p.newResult("localhost")
+	p.runProbeForTarget(context.Background(), endpoint.Endpoint{Name: "localhost"}, &result)
+
+	if err := testutils.CheckRunResult(result.Metrics(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Metrics().Metric("ip_version_used"); got == nil || got.String() != "map:ip_version,4:1" {
+		t.Errorf("Got ip_version_used=%v, want=map:ip_version,4:1", got)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "missing_ca_file", Conf: &configpb.ProbeConf{CaCertFile: proto.String("/nonexistent/ca.pem")}},
+		{Desc: "invalid_pin", Conf: &configpb.ProbeConf{PinnedCertSha256: []string{"ab:cd"}}},
+	})
+}
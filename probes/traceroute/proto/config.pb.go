@@ -0,0 +1,263 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/traceroute/proto/config.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Probe packets to send: ICMP echo requests, or UDP datagrams to high
+// ports. Replies are received over a raw ICMP socket in both cases, which
+// requires root privileges or CAP_NET_RAW.
+type ProbeConf_Method int32
+
+const (
+	ProbeConf_ICMP ProbeConf_Method = 0
+	ProbeConf_UDP  ProbeConf_Method = 1
+)
+
+// Enum value maps for ProbeConf_Method.
+var (
+	ProbeConf_Method_name = map[int32]string{
+		0: "ICMP",
+		1: "UDP",
+	}
+	ProbeConf_Method_value = map[string]int32{
+		"ICMP": 0,
+		"UDP":  1,
+	}
+)
+
+func (x ProbeConf_Method) Enum() *ProbeConf_Method {
+	p := new(ProbeConf_Method)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_Method) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_Method) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_Method) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_Method) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_Method) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_Method(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_Method.Descriptor instead.
+func (ProbeConf_Method) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Method *ProbeConf_Method `protobuf:"varint,1,opt,name=method,enum=cloudprober.probes.traceroute.ProbeConf_Method,def=0" json:"method,omitempty"`
+	// Maximum number of hops (TTL) to probe, up to 255.
+	MaxHops *int32 `protobuf:"varint,2,opt,name=max_hops,json=maxHops,def=30" json:"max_hops,omitempty"`
+	// Number of probe packets per hop, in every probe run, up to 10. Packets
+	// for all hops are sent at once, and the replies are collected until the
+	// probe timeout.
+	ProbesPerHop *int32 `protobuf:"varint,3,opt,name=probes_per_hop,json=probesPerHop,def=3" json:"probes_per_hop,omitempty"`
+	// Destination port of the first UDP probe packet. Every UDP probe packet
+	// in a probe run uses a different port, incrementing from this one.
+	UdpBasePort *int32 `protobuf:"varint,4,opt,name=udp_base_port,json=udpBasePort,def=33434" json:"udp_base_port,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Method       = ProbeConf_ICMP
+	Default_ProbeConf_MaxHops      = int32(30)
+	Default_ProbeConf_ProbesPerHop = int32(3)
+	Default_ProbeConf_UdpBasePort  = int32(33434)
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetMethod() ProbeConf_Method {
+	if x != nil && x.Method != nil {
+		return *x.Method
+	}
+	return Default_ProbeConf_Method
+}
+
+func (x *ProbeConf) GetMaxHops() int32 {
+	if x != nil && x.MaxHops != nil {
+		return *x.MaxHops
+	}
+	return Default_ProbeConf_MaxHops
+}
+
+func (x *ProbeConf) GetProbesPerHop() int32 {
+	if x != nil && x.ProbesPerHop != nil {
+		return *x.ProbesPerHop
+	}
+	return Default_ProbeConf_ProbesPerHop
+}
+
+func (x *ProbeConf) GetUdpBasePort() int32 {
+	if x != nil && x.UdpBasePort != nil {
+		return *x.UdpBasePort
+	}
+	return Default_ProbeConf_UdpBasePort
+}
+
+var File_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x47, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x74, 0x72, 0x61, 0x63,
+	0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1d, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x74, 0x72,
+	0x61, 0x63, 0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x22, 0xea, 0x01, 0x0a, 0x09, 0x50, 0x72, 0x6f,
+	0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x4d, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2f, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x74, 0x72, 0x61, 0x63,
+	0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66,
+	0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x3a, 0x04, 0x49, 0x43, 0x4d, 0x50, 0x52, 0x06, 0x6d,
+	0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x1d, 0x0a, 0x08, 0x6d, 0x61, 0x78, 0x5f, 0x68, 0x6f, 0x70,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x02, 0x33, 0x30, 0x52, 0x07, 0x6d, 0x61, 0x78,
+	0x48, 0x6f, 0x70, 0x73, 0x12, 0x27, 0x0a, 0x0e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x5f, 0x70,
+	0x65, 0x72, 0x5f, 0x68, 0x6f, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x33, 0x52,
+	0x0c, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x50, 0x65, 0x72, 0x48, 0x6f, 0x70, 0x12, 0x29, 0x0a,
+	0x0d, 0x75, 0x64, 0x70, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x3a, 0x05, 0x33, 0x33, 0x34, 0x33, 0x34, 0x52, 0x0b, 0x75, 0x64, 0x70,
+	0x42, 0x61, 0x73, 0x65, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x1b, 0x0a, 0x06, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x43, 0x4d, 0x50, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03,
+	0x55, 0x44, 0x50, 0x10, 0x01, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x73, 0x2f, 0x74, 0x72, 0x61, 0x63, 0x65, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_Method)(0), // 0: cloudprober.probes.traceroute.ProbeConf.Method
+	(*ProbeConf)(nil),     // 1: cloudprober.probes.traceroute.ProbeConf
+}
+var file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.traceroute.ProbeConf.method:type_name -> cloudprober.probes.traceroute.ProbeConf.Method
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_traceroute_proto_config_proto_depIdxs = nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceroute
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	configpb "github.com/cloudprober/cloudprober/probes/traceroute/proto"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	protocolICMP     = 1
+	protocolUDP      = 17
+	protocolIPv6ICMP = 58
+
+	icmpHeaderSize = 8
+	udpHeaderSize  = 8
+)
+
+// Destination unreachable codes for "port unreachable".
+const (
+	codePortUnreachableV4 = 3
+	codePortUnreachableV6 = 4
+)
+
+// reply is a reply to a probe packet.
+type reply struct {
+	from    net.IP
+	rtt     time.Duration
+	reached bool // Reply came from the destination.
+}
+
+// tracer sends the probe packets of a single trace, and matches the ICMP
+// replies to them. Probe packets are identified by their keys, 0 to
+// numPackets-1: the ICMP echo sequence number, or the UDP destination port
+// offset from the base port.
+type tracer struct {
+	ipVer      int
+	method     configpb.ProbeConf_Method
+	dst        net.IP
+	numPackets int
+
+	icmpConn *icmp.PacketConn
+	echoID   int // ICMP method only.
+
+	udpConn   *net.UDPConn // UDP method only.
+	localPort int
+	basePort  int
+}
+
+func newTracer(ipVer int, method configpb.ProbeConf_Method, sourceIP net.IP, zone string, dst net.IP, numPackets, basePort, echoID int) (*tracer, error) {
+	t := &tracer{
+		ipVer:      ipVer,
+		method:     method,
+		dst:        dst,
+		numPackets: numPackets,
+		echoID:     echoID,
+		basePort:   basePort,
+	}
+
+	network, laddr := "ip4:icmp", "0.0.0.0"
+	if ipVer == 6 {
+		network, laddr = "ip6:ipv6-icmp", "::"
+	}
+	if sourceIP != nil {
+		laddr = sourceIP.String()
+		if zone != "" {
+			laddr += "%" + zone
+		}
+	}
+	var err error
+	if t.icmpConn, err = icmp.ListenPacket(network, laddr); err != nil {
+		return nil, fmt.Errorf("error opening ICMP socket (requires root or CAP_NET_RAW): %v", err)
+	}
+
+	if method == configpb.ProbeConf_UDP {
+		udpAddr := &net.UDPAddr{IP: sourceIP, Zone: zone}
+		if t.udpConn, err = net.ListenUDP(fmt.Sprintf("udp%d", ipVer), udpAddr); err != nil {
+			t.close()
+			return nil, fmt.Errorf("error opening UDP socket: %v", err)
+		}
+		t.localPort = t.udpConn.LocalAddr().(*net.UDPAddr).Port
+	}
+	return t, nil
+}
+
+func (t *tracer) close() {
+	t.icmpConn.Close()
+	if t.udpConn != nil {
+		t.udpConn.Close()
+	}
+}
+
+// send sends the probe packet with the given key and TTL.
+func (t *tracer) send(key, ttl int) error {
+	if t.method == configpb.ProbeConf_UDP {
+		var err error
+		if t.ipVer == 6 {
+			err = ipv6.NewPacketConn(t.udpConn).SetHopLimit(ttl)
+		} else {
+			err = ipv4.NewPacketConn(t.udpConn).SetTTL(ttl)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = t.udpConn.WriteTo([]byte("cloudprober"), &net.UDPAddr{IP: t.dst, Port: t.basePort + key})
+		return err
+	}
+
+	var msgType icmp.Type = ipv4.ICMPTypeEcho
+	var err error
+	if t.ipVer == 6 {
+		msgType = ipv6.ICMPTypeEchoRequest
+		err = t.icmpConn.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		err = t.icmpConn.IPv4PacketConn().SetTTL(ttl)
+	}
+	if err != nil {
+		return err
+	}
+	msg := icmp.Message{
+		Type: msgType,
+		Body: &icmp.Echo{ID: t.echoID, Seq: key, Data: []byte("cloudprober")},
+	}
+	// Checksum is computed by the kernel for ICMPv6.
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	_, err = t.icmpConn.WriteTo(b, &net.IPAddr{IP: t.dst})
+	return err
+}
+
+// read reads the next ICMP message.
+func (t *tracer) read(buf []byte) ([]byte, net.IP, error) {
+	n, addr, err := t.icmpConn.ReadFrom(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf[:n], addr.(*net.IPAddr).IP, nil
+}
+
+// parseReply matches an ICMP message from the given address to a probe
+// packet, and returns the packet's key, and if the message came from the
+// destination. ICMP error messages, e.g. "time exceeded", carry the original
+// packet's IP header and at least 8 bytes of its payload.
+func (t *tracer) parseReply(pkt []byte, from net.IP) (key int, reached, ok bool) {
+	if len(pkt) < icmpHeaderSize {
+		return 0, false, false
+	}
+
+	var timeExceeded, dstUnreach, portUnreach bool
+	if t.ipVer == 6 {
+		if t.method == configpb.ProbeConf_ICMP && ipv6.ICMPType(pkt[0]) == ipv6.ICMPTypeEchoReply {
+			return t.matchEcho(pkt, from.Equal(t.dst))
+		}
+		timeExceeded = ipv6.ICMPType(pkt[0]) == ipv6.ICMPTypeTimeExceeded
+		dstUnreach = ipv6.ICMPType(pkt[0]) == ipv6.ICMPTypeDestinationUnreachable
+		portUnreach = dstUnreach && pkt[1] == codePortUnreachableV6
+	} else {
+		if t.method == configpb.ProbeConf_ICMP && ipv4.ICMPType(pkt[0]) == ipv4.ICMPTypeEchoReply {
+			return t.matchEcho(pkt, from.Equal(t.dst))
+		}
+		timeExceeded = ipv4.ICMPType(pkt[0]) == ipv4.ICMPTypeTimeExceeded
+		dstUnreach = ipv4.ICMPType(pkt[0]) == ipv4.ICMPTypeDestinationUnreachable
+		portUnreach = dstUnreach && pkt[1] == codePortUnreachableV4
+	}
+	if !timeExceeded && !dstUnreach {
+		return 0, false, false
+	}
+
+	orig := pkt[icmpHeaderSize:]
+	var dst net.IP
+	var proto byte
+	if t.ipVer == 6 {
+		if len(orig) < ipv6.HeaderLen+8 {
+			return 0, false, false
+		}
+		dst, proto, orig = net.IP(orig[24:40]), orig[6], orig[ipv6.HeaderLen:]
+	} else {
+		if len(orig) < ipv4.HeaderLen {
+			return 0, false, false
+		}
+		hdrLen := int(orig[0]&0x0f) << 2
+		if hdrLen < ipv4.HeaderLen || len(orig) < hdrLen+8 {
+			return 0, false, false
+		}
+		dst, proto, orig = net.IP(orig[16:20]), orig[9], orig[hdrLen:]
+	}
+	if !dst.Equal(t.dst) {
+		return 0, false, false
+	}
+
+	// For the UDP method, destination replies with "port unreachable". Other
+	// "destination unreachable" messages, e.g. from a firewall, are hops.
+	if t.method == configpb.ProbeConf_UDP {
+		if proto != protocolUDP || int(binary.BigEndian.Uint16(orig[0:2])) != t.localPort {
+			return 0, false, false
+		}
+		key = int(binary.BigEndian.Uint16(orig[2:4])) - t.basePort
+		if key < 0 || key >= t.numPackets {
+			return 0, false, false
+		}
+		return key, portUnreach && from.Equal(t.dst), true
+	}
+
+	if (t.ipVer == 6 && proto != protocolIPv6ICMP) || (t.ipVer == 4 && proto != protocolICMP) {
+		return 0, false, false
+	}
+	key, _, ok = t.matchEcho(orig, false)
+	return key, false, ok
+}
+
+// matchEcho matches an ICMP echo message (request or reply) to a probe
+// packet.
+func (t *tracer) matchEcho(echo []byte, reached bool) (int, bool, bool) {
+	id, seq := int(binary.BigEndian.Uint16(echo[4:6])), int(binary.BigEndian.Uint16(echo[6:8]))
+	if id != t.echoID || seq >= t.numPackets {
+		return 0, false, false
+	}
+	return seq, reached, true
+}
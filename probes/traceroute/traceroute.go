@@ -0,0 +1,498 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package traceroute implements a traceroute prober. It traces the path to the
+targets with ICMP echo requests or UDP datagrams, sending the probe packets for
+all hops at once, and collecting the ICMP replies until the probe timeout. It
+reports statistics on probe runs, runs that reached the destination and the
+round-trip time to the destination, along with the number of path changes.
+For every hop, it reports the number of probe packets sent and replied to, and
+a distribution of the round-trip times, labeled with the hop number. For every
+probe run, it also reports the number of hops to the destination and the path,
+as gauges.
+
+Receiving the ICMP replies requires raw sockets, i.e. root privileges or
+CAP_NET_RAW.
+
+Probes for all targets run in parallel.
+*/
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/traceroute/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonResolve    = "resolve"
+	reasonDNSTimeout = "dns_timeout"
+	reasonSocket     = "socket"
+	reasonSend       = "send"
+	reasonNotReached = "not_reached"
+)
+
+// Default distribution for the per-hop round-trip times, if latency
+// distribution is not configured: exponential buckets, from 100us to ~3.3s.
+const (
+	defaultHopRTTBase       = 2
+	defaultHopRTTScale      = 100 * time.Microsecond
+	defaultHopRTTNumBuckets = 16
+)
+
+// noReply is the path element for the hops that didn't reply.
+const noReply = "*"
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name  string
+	opts  *options.Options
+	c     *configpb.ProbeConf
+	l     *logger.Logger
+	ipVer int
+
+	// book-keeping params
+	targets    []endpoint.Endpoint
+	numPackets int
+
+	mu        sync.Mutex
+	lastPaths map[string][]string // Last path per target.
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	failures          *metrics.Map
+	pathChanges       metrics.Int
+	latencyMetricName string
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// Per-hop results, and the path, reported as separate results.
+	hops []*hopResult
+	path *pathResult
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("failures", prr.failures).
+		AddMetric("path_changes", &prr.pathChanges)
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+// hopResult captures the results of a single probe run for a hop.
+type hopResult struct {
+	target string
+	hop    int
+	sent   metrics.Int
+	rcvd   metrics.Int
+	rtt    metrics.Value
+}
+
+// Metrics converts hopResult into metrics.EventMetrics object
+func (hr hopResult) Metrics() *metrics.EventMetrics {
+	return metrics.NewEventMetrics(time.Now()).
+		AddMetric("hop_sent", &hr.sent).
+		AddMetric("hop_rcvd", &hr.rcvd).
+		AddMetric("hop_rtt", hr.rtt).
+		AddLabel("hop", strconv.Itoa(hr.hop))
+}
+
+// Target returns the hr.target.
+func (hr hopResult) Target() string {
+	return hr.target
+}
+
+// pathResult is a gauge update for the number of hops to the destination, 0
+// if the destination was not reached, and the path.
+type pathResult struct {
+	target   string
+	hopCount int64
+	path     []string
+}
+
+// Metrics converts pathResult into metrics.EventMetrics object
+func (pr pathResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("hop_count", metrics.NewInt(pr.hopCount)).
+		AddMetric("path", metrics.NewString(strings.Join(pr.path, ",")))
+	em.Kind = metrics.GAUGE
+	return em
+}
+
+// Target returns the pr.target.
+func (pr pathResult) Target() string {
+	return pr.target
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no traceroute config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetMaxHops() < 1 || p.c.GetMaxHops() > 255 {
+		return fmt.Errorf("traceroute_probe(%s): invalid max_hops: %d, should be between 1 and 255", name, p.c.GetMaxHops())
+	}
+	if p.c.GetProbesPerHop() < 1 || p.c.GetProbesPerHop() > 10 {
+		return fmt.Errorf("traceroute_probe(%s): invalid probes_per_hop: %d, should be between 1 and 10", name, p.c.GetProbesPerHop())
+	}
+	p.numPackets = int(p.c.GetMaxHops() * p.c.GetProbesPerHop())
+
+	if p.c.GetMethod() == configpb.ProbeConf_UDP {
+		if port := int(p.c.GetUdpBasePort()); port < 1 || port+p.numPackets-1 > 65535 {
+			return fmt.Errorf("traceroute_probe(%s): invalid udp_base_port: %d, ports up to %d are required", name, port, port+p.numPackets-1)
+		}
+	}
+
+	// We need to know the IP version to open the ICMP socket. We default to
+	// IPv4, like the ping probe.
+	p.ipVer = 4
+	if p.opts.IPVersion != 0 {
+		p.ipVer = p.opts.IPVersion
+	}
+
+	p.lastPaths = make(map[string][]string)
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newHopRTTValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	d, err := metrics.NewExponentialDistribution(defaultHopRTTBase, defaultHopRTTScale.Seconds()/p.opts.LatencyUnit.Seconds(), defaultHopRTTNumBuckets)
+	if err != nil {
+		// Can't happen with the constant parameters.
+		panic(err)
+	}
+	return d
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	return probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+	}
+}
+
+// pathChanged returns true if the new path is different from the old one.
+// Hops that didn't reply in either path are not compared.
+func pathChanged(oldPath, newPath []string) bool {
+	if len(oldPath) != len(newPath) {
+		return true
+	}
+	for i := range oldPath {
+		if oldPath[i] != noReply && newPath[i] != noReply && oldPath[i] != newPath[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// trace runs the trace to the target, and returns the replies per packet key.
+func (p *Probe) trace(ctx context.Context, t *tracer) (map[int]*reply, error) {
+	sent := make([]time.Time, p.numPackets)
+	perHop := int(p.c.GetProbesPerHop())
+	for key := 0; key < p.numPackets; key++ {
+		sent[key] = time.Now()
+		if err := t.send(key, key/perHop+1); err != nil {
+			return nil, err
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		t.icmpConn.SetReadDeadline(deadline)
+	}
+
+	// Replies are collected until the destination is reached and all the
+	// probe packets up to its hop have been replied to, or until the timeout.
+	replies := make(map[int]*reply)
+	destTTL := 0
+	done := func() bool {
+		if destTTL == 0 {
+			return false
+		}
+		for key := 0; key < destTTL*perHop; key++ {
+			if replies[key] == nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	buf := make([]byte, 1500)
+	for !done() {
+		pkt, from, err := t.read(buf)
+		if err != nil {
+			// Timeout ends the trace.
+			break
+		}
+		recvTime := time.Now()
+		key, reached, ok := t.parseReply(pkt, from)
+		if !ok || replies[key] != nil {
+			continue
+		}
+		replies[key] = &reply{from: from, rtt: recvTime.Sub(sent[key]), reached: reached}
+		if ttl := key/perHop + 1; reached && (destTTL == 0 || ttl < destTTL) {
+			destTTL = ttl
+		}
+	}
+	return replies, nil
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	ip, err := p.opts.Resolve(target.Name, p.ipVer)
+	if err != nil {
+		p.l.Warningf("Target(%s): resolve error: %v", target.Name, err)
+		if options.IsDNSTimeout(err) {
+			result.failures.IncKey(reasonDNSTimeout)
+		} else {
+			result.failures.IncKey(reasonResolve)
+		}
+		return
+	}
+
+	t, err := newTracer(p.ipVer, p.c.GetMethod(), p.opts.SourceIP, p.opts.SourceIPZone, ip, p.numPackets, int(p.c.GetUdpBasePort()), rand.Intn(0x10000))
+	if err != nil {
+		p.l.Errorf("Target(%s): %v", target.Name, err)
+		result.failures.IncKey(reasonSocket)
+		return
+	}
+	defer t.close()
+
+	replies, err := p.trace(ctx, t)
+	if err != nil {
+		p.l.Warningf("Target(%s): error sending probe packets: %v", target.Name, err)
+		result.failures.IncKey(reasonSend)
+		return
+	}
+	p.processReplies(target.Name, replies, result)
+}
+
+// processReplies updates the result with the trace's replies.
+func (p *Probe) processReplies(target string, replies map[int]*reply, result *probeRunResult) {
+	perHop := int(p.c.GetProbesPerHop())
+
+	// Destination's hop, or the last hop that replied, if the destination
+	// was not reached.
+	destTTL, lastTTL := 0, 0
+	var destRTT time.Duration
+	for key, r := range replies {
+		ttl := key/perHop + 1
+		if ttl > lastTTL {
+			lastTTL = ttl
+		}
+		if r.reached && (destTTL == 0 || ttl < destTTL || (ttl == destTTL && r.rtt < destRTT)) {
+			destTTL, destRTT = ttl, r.rtt
+		}
+	}
+	numHops := lastTTL
+	if destTTL != 0 {
+		numHops = destTTL
+	}
+
+	path := make([]string, numHops)
+	for hop := 1; hop <= numHops; hop++ {
+		hr := &hopResult{target: target, hop: hop, rtt: p.newHopRTTValue()}
+		path[hop-1] = noReply
+		for key := (hop - 1) * perHop; key < hop*perHop; key++ {
+			hr.sent.Inc()
+			r := replies[key]
+			if r == nil {
+				continue
+			}
+			hr.rcvd.Inc()
+			hr.rtt.AddFloat64(r.rtt.Seconds() / p.opts.LatencyUnit.Seconds())
+			if path[hop-1] == noReply {
+				path[hop-1] = r.from.String()
+			}
+		}
+		result.hops = append(result.hops, hr)
+	}
+	result.path = &pathResult{target: target, hopCount: int64(destTTL), path: path}
+
+	if destTTL == 0 {
+		p.l.Warningf("Target(%s): destination not reached in %d hops, path: %s", target, p.c.GetMaxHops(), strings.Join(path, ","))
+		result.failures.IncKey(reasonNotReached)
+	} else {
+		result.success.Inc()
+		result.latency.AddFloat64(destRTT.Seconds() / p.opts.LatencyUnit.Seconds())
+		result.rawLatency = destRTT
+	}
+
+	// Path changes are tracked only for the traces that reached the
+	// destination.
+	if destTTL == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if lastPath := p.lastPaths[target]; lastPath != nil && pathChanged(lastPath, path) {
+		p.l.Infof("Target(%s): path changed from %s to %s", target, strings.Join(lastPath, ","), strings.Join(path, ","))
+		result.pathChanges.Inc()
+	}
+	p.lastPaths[target] = path
+}
+
+// RunOnDemand runs the probe once for the given target, out of the regular
+// schedule. Its result is not included in the probe's metrics.
+func (p *Probe) RunOnDemand(ctx context.Context, target endpoint.Endpoint) (time.Duration, error) {
+	result := p.newResult(target.Name)
+	p.runProbeForTarget(ctx, target, &result)
+	if result.success.Int64() == 0 {
+		return 0, fmt.Errorf("probe failed, reason: %s", strings.Join(result.failures.Keys(), ","))
+	}
+	return result.rawLatency, nil
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+		for _, hr := range result.hops {
+			resultsChan <- *hr
+		}
+		if result.path != nil {
+			resultsChan <- *result.path
+		}
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	// Every target's result is followed by up to max_hops hop results.
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets)*(int(p.c.GetMaxHops())+2))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "traceroute", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
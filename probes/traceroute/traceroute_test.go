@@ -0,0 +1,328 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	configpb "github.com/cloudprober/cloudprober/probes/traceroute/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testEchoID    = 0x1234
+	testLocalPort = 50000
+	testBasePort  = 33434
+)
+
+var (
+	testDst    = net.ParseIP("192.0.2.1").To4()
+	testRouter = net.ParseIP("198.51.100.1").To4()
+)
+
+// icmpError returns an ICMPv4 error message, carrying the IPv4 header and the
+// first 8 bytes of the original packet.
+func icmpError(typ, code byte, proto byte, dst net.IP, payload []byte) []byte {
+	ipHdr := make([]byte, 20)
+	ipHdr[0] = 0x45
+	ipHdr[9] = proto
+	copy(ipHdr[16:20], dst)
+	return append(append([]byte{typ, code, 0, 0, 0, 0, 0, 0}, ipHdr...), payload...)
+}
+
+func echo(typ byte, id, seq int) []byte {
+	b := []byte{typ, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(b[4:], uint16(id))
+	binary.BigEndian.PutUint16(b[6:], uint16(seq))
+	return b
+}
+
+func udpHeader(srcPort, dstPort int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b, uint16(srcPort))
+	binary.BigEndian.PutUint16(b[2:], uint16(dstPort))
+	return b
+}
+
+func TestParseReply(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		method      configpb.ProbeConf_Method
+		pkt         []byte
+		from        net.IP
+		wantKey     int
+		wantReached bool
+		wantOK      bool
+	}{
+		{
+			desc:    "icmp_time_exceeded",
+			method:  configpb.ProbeConf_ICMP,
+			pkt:     icmpError(11, 0, protocolICMP, testDst, echo(8, testEchoID, 2)),
+			from:    testRouter,
+			wantKey: 2,
+			wantOK:  true,
+		},
+		{
+			desc:        "icmp_echo_reply",
+			method:      configpb.ProbeConf_ICMP,
+			pkt:         echo(0, testEchoID, 5),
+			from:        testDst,
+			wantKey:     5,
+			wantReached: true,
+			wantOK:      true,
+		},
+		{
+			desc:   "icmp_echo_reply_other_id",
+			method: configpb.ProbeConf_ICMP,
+			pkt:    echo(0, testEchoID+1, 5),
+			from:   testDst,
+		},
+		{
+			desc:   "icmp_seq_out_of_range",
+			method: configpb.ProbeConf_ICMP,
+			pkt:    echo(0, testEchoID, 10),
+			from:   testDst,
+		},
+		{
+			desc:   "icmp_time_exceeded_other_dst",
+			method: configpb.ProbeConf_ICMP,
+			pkt:    icmpError(11, 0, protocolICMP, testRouter, echo(8, testEchoID, 2)),
+			from:   testRouter,
+		},
+		{
+			desc:   "icmp_echo_request",
+			method: configpb.ProbeConf_ICMP,
+			pkt:    echo(8, testEchoID, 2),
+			from:   testDst,
+		},
+		{
+			desc:    "udp_time_exceeded",
+			method:  configpb.ProbeConf_UDP,
+			pkt:     icmpError(11, 0, protocolUDP, testDst, udpHeader(testLocalPort, testBasePort+3)),
+			from:    testRouter,
+			wantKey: 3,
+			wantOK:  true,
+		},
+		{
+			desc:        "udp_port_unreachable",
+			method:      configpb.ProbeConf_UDP,
+			pkt:         icmpError(3, codePortUnreachableV4, protocolUDP, testDst, udpHeader(testLocalPort, testBasePort+4)),
+			from:        testDst,
+			wantKey:     4,
+			wantReached: true,
+			wantOK:      true,
+		},
+		{
+			desc:    "udp_host_unreachable_from_router",
+			method:  configpb.ProbeConf_UDP,
+			pkt:     icmpError(3, 1, protocolUDP, testDst, udpHeader(testLocalPort, testBasePort+4)),
+			from:    testRouter,
+			wantKey: 4,
+			wantOK:  true,
+		},
+		{
+			desc:   "udp_other_local_port",
+			method: configpb.ProbeConf_UDP,
+			pkt:    icmpError(11, 0, protocolUDP, testDst, udpHeader(testLocalPort+1, testBasePort+3)),
+			from:   testRouter,
+		},
+		{
+			desc:   "udp_echo_reply",
+			method: configpb.ProbeConf_UDP,
+			pkt:    echo(0, testEchoID, 5),
+			from:   testDst,
+		},
+		{
+			desc:   "truncated",
+			method: configpb.ProbeConf_UDP,
+			pkt:    icmpError(11, 0, protocolUDP, testDst, nil),
+			from:   testRouter,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tr := &tracer{
+				ipVer:      4,
+				method:     test.method,
+				dst:        testDst,
+				numPackets: 6,
+				echoID:     testEchoID,
+				localPort:  testLocalPort,
+				basePort:   testBasePort,
+			}
+			key, reached, ok := tr.parseReply(test.pkt, test.from)
+			if ok != test.wantOK {
+				t.Fatalf("parseReply(): ok=%v, want=%v", ok, test.wantOK)
+			}
+			if key != test.wantKey || reached != test.wantReached {
+				t.Errorf("parseReply(): key=%d, reached=%v, want key=%d, reached=%v", key, reached, test.wantKey, test.wantReached)
+			}
+		})
+	}
+}
+
+func testProbe(t *testing.T, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "traceroute_test", testutils.Options("127.0.0.1", 500*time.Millisecond, c))
+	return p
+}
+
+func TestProcessReplies(t *testing.T) {
+	p := testProbe(t, &configpb.ProbeConf{
+		MaxHops:      proto.Int32(4),
+		ProbesPerHop: proto.Int32(2),
+	})
+	r1, r2, r3 := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")
+
+	for _, test := range []struct {
+		desc            string
+		replies         map[int]*reply
+		wantSuccess     int64
+		wantHopCount    int64
+		wantPath        string
+		wantRcvd        []int64
+		wantPathChanges int64
+	}{
+		{
+			desc: "reached",
+			replies: map[int]*reply{
+				0: {from: r1, rtt: time.Millisecond},
+				1: {from: r1, rtt: time.Millisecond},
+				3: {from: r2, rtt: 2 * time.Millisecond},
+				4: {from: testDst, rtt: 3 * time.Millisecond, reached: true},
+				6: {from: testDst, rtt: 3 * time.Millisecond, reached: true},
+			},
+			wantSuccess:  1,
+			wantHopCount: 3,
+			wantPath:     "10.0.0.1,10.0.0.2,192.0.2.1",
+			wantRcvd:     []int64{2, 1, 1},
+		},
+		{
+			desc: "path_changed",
+			replies: map[int]*reply{
+				0: {from: r1, rtt: time.Millisecond},
+				2: {from: r3, rtt: 2 * time.Millisecond},
+				4: {from: testDst, rtt: 3 * time.Millisecond, reached: true},
+			},
+			wantSuccess:     1,
+			wantHopCount:    3,
+			wantPath:        "10.0.0.1,10.0.0.3,192.0.2.1",
+			wantRcvd:        []int64{1, 1, 1},
+			wantPathChanges: 1,
+		},
+		{
+			desc: "reached_hop_not_replying",
+			replies: map[int]*reply{
+				0: {from: r1, rtt: time.Millisecond},
+				4: {from: testDst, rtt: 3 * time.Millisecond, reached: true},
+			},
+			wantSuccess:  1,
+			wantHopCount: 3,
+			wantPath:     "10.0.0.1,*,192.0.2.1",
+			wantRcvd:     []int64{1, 0, 1},
+		},
+		{
+			desc: "not_reached",
+			replies: map[int]*reply{
+				0: {from: r1, rtt: time.Millisecond},
+				2: {from: r3, rtt: 2 * time.Millisecond},
+			},
+			wantPath: "10.0.0.1,10.0.0.3",
+			wantRcvd: []int64{1, 1},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			result := p.newResult("test-target")
+			p.processReplies("test-target", test.replies, &result)
+
+			if result.success.Int64() != test.wantSuccess {
+				t.Errorf("success=%d, want=%d", result.success.Int64(), test.wantSuccess)
+			}
+			if result.pathChanges.Int64() != test.wantPathChanges {
+				t.Errorf("path_changes=%d, want=%d", result.pathChanges.Int64(), test.wantPathChanges)
+			}
+			if result.path.hopCount != test.wantHopCount {
+				t.Errorf("hop_count=%d, want=%d", result.path.hopCount, test.wantHopCount)
+			}
+			if path := strings.Join(result.path.path, ","); path != test.wantPath {
+				t.Errorf("path=%s, want=%s", path, test.wantPath)
+			}
+			if len(result.hops) != len(test.wantRcvd) {
+				t.Fatalf("Got %d hop results, want=%d", len(result.hops), len(test.wantRcvd))
+			}
+			for i, hr := range result.hops {
+				if hr.hop != i+1 || hr.sent.Int64() != 2 || hr.rcvd.Int64() != test.wantRcvd[i] {
+					t.Errorf("hop %d: hop=%d, sent=%d, rcvd=%d, want hop=%d, sent=2, rcvd=%d", i+1, hr.hop, hr.sent.Int64(), hr.rcvd.Int64(), i+1, test.wantRcvd[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	for _, method := range []configpb.ProbeConf_Method{configpb.ProbeConf_ICMP, configpb.ProbeConf_UDP} {
+		t.Run(method.String(), func(t *testing.T) {
+			p := testProbe(t, &configpb.ProbeConf{
+				Method:  method.Enum(),
+				MaxHops: proto.Int32(3),
+			})
+
+			// Tracing needs raw sockets.
+			tr, err := newTracer(4, method, nil, "", net.ParseIP("127.0.0.1"), 1, testBasePort, testEchoID)
+			if err != nil {
+				t.Skipf("Can't open sockets: %v", err)
+			}
+			tr.close()
+
+			target := endpoint.Endpoint{Name: "127.0.0.1"}
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), ""); err != nil {
+				t.Fatal(err)
+			}
+			if result.path.hopCount != 1 || len(result.hops) != 1 {
+				t.Errorf("hop_count=%d, hop results=%d, want both 1", result.path.hopCount, len(result.hops))
+			}
+			if result.hops[0].rcvd.Int64() != 3 {
+				t.Errorf("hop_rcvd=%d, want=3", result.hops[0].rcvd.Int64())
+			}
+			if path := strings.Join(result.path.path, ","); path != "127.0.0.1" {
+				t.Errorf("path=%s, want=127.0.0.1", path)
+			}
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "max_hops_zero", Conf: &configpb.ProbeConf{MaxHops: proto.Int32(0)}},
+		{Desc: "max_hops_too_large", Conf: &configpb.ProbeConf{MaxHops: proto.Int32(256)}},
+		{Desc: "probes_per_hop_zero", Conf: &configpb.ProbeConf{ProbesPerHop: proto.Int32(0)}},
+		{Desc: "probes_per_hop_too_large", Conf: &configpb.ProbeConf{ProbesPerHop: proto.Int32(11)}},
+		{
+			Desc: "udp_base_port_too_large",
+			Conf: &configpb.ProbeConf{Method: configpb.ProbeConf_UDP.Enum(), UdpBasePort: proto.Int32(65500)},
+		},
+	})
+}
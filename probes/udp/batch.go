@@ -0,0 +1,134 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv6"
+)
+
+// Maximum number of packets sent or received in a single syscall.
+const batchSize = 64
+
+// Batching reduces the number of syscalls only on Linux. On other platforms,
+// x/net batch functions send and receive one packet at a time.
+var batchSupported = runtime.GOOS == "linux"
+
+// runBatchedProbe is the batched version of runProbe's send path. It groups
+// the flows by their connection, and sends each connection's packets in
+// batches, concurrently across the connections.
+func (p *Probe) runBatchedProbe(packetsPerTarget, initialConn, maxLen, dstPort int) {
+	connFlows := make([][]flow, len(p.connList))
+	for _, target := range p.targets {
+		for i := 0; i < packetsPerTarget; i++ {
+			connID := (initialConn + i) % len(p.connList)
+			connFlows[connID] = append(connFlows[connID], flow{p.srcPortList[connID], target.Name})
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(connFlows))
+	for connID, flows := range connFlows {
+		go func(pc *ipv6.PacketConn, flows []flow) {
+			defer wg.Done()
+			p.sendBatch(pc, flows, maxLen, dstPort)
+		}(p.batchConns[connID], flows)
+	}
+	wg.Wait()
+}
+
+// sendBatch sends a packet for each of the flows over the connection, up to
+// batchSize packets per syscall. A packet that fails to send is skipped, and
+// sending continues with the next one.
+func (p *Probe) sendBatch(pc *ipv6.PacketConn, flows []flow, maxLen, dstPort int) {
+	ms := make([]ipv6.Message, 0, len(flows))
+	pkts := make([]packetID, 0, len(flows))
+	for _, f := range flows {
+		msg, raddr, pkt, err := p.newProbeMessage(f, maxLen, dstPort)
+		if err != nil {
+			p.l.Errorf("Probing %+v failed: %v", f, err)
+			continue
+		}
+		ms = append(ms, ipv6.Message{Buffers: [][]byte{msg}, OOB: p.oob(raddr), Addr: raddr})
+		pkts = append(pkts, pkt)
+	}
+
+	for len(ms) > 0 {
+		end := len(ms)
+		if end > batchSize {
+			end = batchSize
+		}
+		n, err := pc.WriteBatch(ms[:end], 0)
+		if n < 0 {
+			n = 0
+		}
+		for _, pkt := range pkts[:n] {
+			if err := p.queueSentPacket(pkt); err != nil {
+				p.l.Errorf("Probing %+v failed: %v", pkt.f, err)
+			}
+		}
+		if err == nil && n == 0 {
+			err = errors.New("no packets sent")
+		}
+		// Packet at index n couldn't be sent, skip it.
+		if err != nil {
+			p.withdrawMessage(pkts[n])
+			p.l.Errorf("Probing %+v failed: unable to send to %v: %v", pkts[n].f, ms[n].Addr, err)
+			n++
+		}
+		ms, pkts = ms[n:], pkts[n:]
+	}
+}
+
+// recvBatchLoop is the batched version of recvLoop. It receives up to
+// batchSize packets per syscall.
+func (p *Probe) recvBatchLoop(ctx context.Context, conn *net.UDPConn, pc *ipv6.PacketConn) {
+	// We send messages of up to max_length bytes, so we don't expect larger
+	// replies.
+	ms := make([]ipv6.Message, batchSize)
+	for i := range ms {
+		ms[i].Buffers = [][]byte{make([]byte, p.c.GetMaxLength())}
+	}
+
+	// All packets received over a connection belong to the flows with the
+	// same source port, so we can track their sequence numbers locally.
+	lastSeq := make(map[flow]uint64)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		conn.SetReadDeadline(time.Now().Add(p.opts.Timeout))
+		n, err := pc.ReadBatch(ms, 0)
+		if err != nil {
+			if !isClientTimeout(err) {
+				p.l.Errorf("Receive error on %s: %v", conn.LocalAddr(), err)
+			}
+			continue
+		}
+
+		rxTS := time.Now()
+		for _, m := range ms[:n] {
+			p.queueRcvdPacket(m.Buffers[0][:m.N], rxTS, m.Addr, lastSeq)
+		}
+	}
+}
@@ -49,6 +49,32 @@ type ProbeConf struct {
 	// 16 packets to every target (1 per tx port).
 	// Note that setting this field to true will increase the probe traffic.
 	UseAllTxPortsPerProbe *bool `protobuf:"varint,8,opt,name=use_all_tx_ports_per_probe,json=useAllTxPortsPerProbe,def=0" json:"use_all_tx_ports_per_probe,omitempty"`
+	// Batch the packets sent to, and received from, the targets, using a single
+	// sendmmsg (recvmmsg) syscall for multiple packets. This reduces the syscall
+	// overhead for the probes with a large number of targets. Results are not
+	// affected. Note that received packets larger than max_length are truncated
+	// in this mode.
+	//
+	// NOTE: This is supported only on Linux currently. On other platforms,
+	// packets are sent and received one at a time.
+	BatchPackets *bool `protobuf:"varint,9,opt,name=batch_packets,json=batchPackets,def=0" json:"batch_packets,omitempty"`
+	// Expect packets to not get a response, e.g. to verify that a firewall
+	// blocks the port. If set, success counts the packets that didn't get a
+	// (valid) response within the timeout, and latency is not recorded. Raw
+	// outcome of the packets is exported as the "raw_outcome" map metric, keyed
+	// by the "outcome" label ("success" or "failure").
+	ExpectFailure *bool `protobuf:"varint,10,opt,name=expect_failure,json=expectFailure,def=0" json:"expect_failure,omitempty"`
+	// Export the loss pattern metrics, as loss percentage alone hides bursty
+	// loss. Packets of a target (of a source port, with export_metrics_by_port)
+	// are ordered by their send time, and a packet is lost if it doesn't get a
+	// valid response within the timeout. Following metrics are exported:
+	//   max_consecutive_loss: longest run of consecutively lost packets since
+	//                         the probe started.
+	//   loss_bursts: number of runs of two or more consecutively lost packets.
+	//   loss_gap: distribution of the number of packets received between two
+	//             lost packets (0 for consecutively lost packets).
+	// Can't be used with expect_failure.
+	ExportLossMetrics *bool `protobuf:"varint,11,opt,name=export_loss_metrics,json=exportLossMetrics,def=0" json:"export_loss_metrics,omitempty"`
 }
 
 // Default values for ProbeConf fields.
@@ -58,6 +84,9 @@ const (
 	Default_ProbeConf_MaxLength             = int32(1300)
 	Default_ProbeConf_ExportMetricsByPort   = bool(false)
 	Default_ProbeConf_UseAllTxPortsPerProbe = bool(false)
+	Default_ProbeConf_BatchPackets          = bool(false)
+	Default_ProbeConf_ExpectFailure         = bool(false)
+	Default_ProbeConf_ExportLossMetrics     = bool(false)
 )
 
 func (x *ProbeConf) Reset() {
@@ -134,6 +163,27 @@ func (x *ProbeConf) GetUseAllTxPortsPerProbe() bool {
 	return Default_ProbeConf_UseAllTxPortsPerProbe
 }
 
+func (x *ProbeConf) GetBatchPackets() bool {
+	if x != nil && x.BatchPackets != nil {
+		return *x.BatchPackets
+	}
+	return Default_ProbeConf_BatchPackets
+}
+
+func (x *ProbeConf) GetExpectFailure() bool {
+	if x != nil && x.ExpectFailure != nil {
+		return *x.ExpectFailure
+	}
+	return Default_ProbeConf_ExpectFailure
+}
+
+func (x *ProbeConf) GetExportLossMetrics() bool {
+	if x != nil && x.ExportLossMetrics != nil {
+		return *x.ExportLossMetrics
+	}
+	return Default_ProbeConf_ExportLossMetrics
+}
+
 var File_github_com_cloudprober_cloudprober_probes_udp_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_probes_udp_proto_config_proto_rawDesc = []byte{
@@ -142,7 +192,7 @@ var file_github_com_cloudprober_cloudprober_probes_udp_proto_config_proto_rawDes
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64, 0x70, 0x2f,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x16, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x75, 0x64, 0x70, 0x22, 0x92, 0x02, 0x0a, 0x09, 0x50,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x75, 0x64, 0x70, 0x22, 0xa3, 0x03, 0x0a, 0x09, 0x50,
 	0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x19, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74,
 	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x05, 0x33, 0x31, 0x31, 0x32, 0x32, 0x52, 0x04, 0x70,
 	0x6f, 0x72, 0x74, 0x12, 0x24, 0x0a, 0x0c, 0x6e, 0x75, 0x6d, 0x5f, 0x74, 0x78, 0x5f, 0x70, 0x6f,
@@ -159,11 +209,20 @@ var file_github_com_cloudprober_cloudprober_probes_udp_proto_config_proto_rawDes
 	0x1a, 0x75, 0x73, 0x65, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x74, 0x78, 0x5f, 0x70, 0x6f, 0x72, 0x74,
 	0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28,
 	0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x15, 0x75, 0x73, 0x65, 0x41, 0x6c, 0x6c,
-	0x54, 0x78, 0x50, 0x6f, 0x72, 0x74, 0x73, 0x50, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x42,
-	0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
-	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
-	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64, 0x70,
-	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x54, 0x78, 0x50, 0x6f, 0x72, 0x74, 0x73, 0x50, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12,
+	0x2a, 0x0a, 0x0d, 0x62, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x0c, 0x62,
+	0x61, 0x74, 0x63, 0x68, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x2c, 0x0a, 0x0e, 0x65,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x65,
+	0x63, 0x74, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x12, 0x35, 0x0a, 0x13, 0x65, 0x78, 0x70,
+	0x6f, 0x72, 0x74, 0x5f, 0x6c, 0x6f, 0x73, 0x73, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x11, 0x65,
+	0x78, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x6f, 0x73, 0x73, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
+	0x42, 0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x75, 0x64,
+	0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -18,27 +18,38 @@ targets and reports statistics on queries sent, queries received, and latency
 experienced.
 
 Queries to each target are sent in parallel.
+
+Each query carries a sequence number and a nonce, which are verified on the
+echoed replies to detect corrupted and out-of-order replies. Optionally, it
+also reports the loss patterns: consecutively lost packets, loss bursts and
+the gaps between the lost packets.
 */
 package udp
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"net"
+	"runtime"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/cloudprober/cloudprober/common/message"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/probes/options"
 	"github.com/cloudprober/cloudprober/probes/probeutils"
 	configpb "github.com/cloudprober/cloudprober/probes/udp/proto"
 	udpsrv "github.com/cloudprober/cloudprober/servers/udp"
 	"github.com/cloudprober/cloudprober/sysvars"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"golang.org/x/net/ipv6"
 )
 
 const (
@@ -50,6 +61,11 @@ const (
 	// on resource consumption.
 	maxTargets     = 500
 	payloadPattern = "cloudprober"
+
+	// Loss gap distribution's lower bounds start at 0 (consecutive losses)
+	// and go up to 512 packets.
+	lossGapDistBase       = 2
+	lossGapDistNumBuckets = 10
 )
 
 // flow represents a UDP flow.
@@ -69,17 +85,22 @@ type Probe struct {
 	l    *logger.Logger
 
 	// List of UDP connections to use.
-	connList    []*net.UDPConn
-	srcPortList []string
-	numConn     int32
-	runID       uint64
-	ipVer       int
+	connList     []*net.UDPConn
+	srcPortList  []string
+	batchConns   []*ipv6.PacketConn // Set only if packets are batched.
+	flowLabelOOB []byte             // Set only if IPv6 flow label is configured.
+	numConn      int32
+	runID        uint64
+	ipVer        int
 
 	targets []endpoint.Endpoint   // List of targets for a probe iteration.
 	res     map[flow]*probeResult // Results by flow.
 	fsm     *message.FlowStateMap // Map flow parameters to flow state.
 	payload []byte
 
+	// nonceKey is used to generate and verify messages' nonce.
+	nonceKey uint64
+
 	// Intermediate buffers of sent and received packets
 	sentPackets, rcvdPackets chan packetID
 	sPackets, rPackets       []packetID
@@ -92,7 +113,38 @@ type Probe struct {
 // That's the reason we use metrics.Int types instead of metrics.AtomicInt.
 type probeResult struct {
 	total, success, delayed int64
+	outOfOrder, corrupted   int64
 	latency                 metrics.Value
+
+	// Loss pattern metrics, exported only if export_loss_metrics is enabled.
+	maxConsecutiveLoss, lossBursts int64
+	lossGap                        *metrics.Distribution
+	// Current run of lost packets, and the number of packets received since
+	// the last lost packet (-1 before the first loss).
+	lossRun, sinceLoss int64
+}
+
+// updateLoss updates the loss pattern metrics with the next packet's outcome.
+func (prr *probeResult) updateLoss(lost bool) {
+	if !lost {
+		prr.lossRun = 0
+		if prr.sinceLoss >= 0 {
+			prr.sinceLoss++
+		}
+		return
+	}
+
+	if prr.sinceLoss >= 0 {
+		prr.lossGap.AddInt64(prr.sinceLoss)
+	}
+	prr.sinceLoss = 0
+	prr.lossRun++
+	if prr.lossRun == 2 {
+		prr.lossBursts++
+	}
+	if prr.lossRun > prr.maxConsecutiveLoss {
+		prr.maxConsecutiveLoss = prr.lossRun
+	}
 }
 
 // Metrics converts probeResult into metrics.EventMetrics object
@@ -101,15 +153,36 @@ func (prr probeResult) eventMetrics(probeName string, opts *options.Options, f f
 	if c.GetExportMetricsByPort() {
 		suffix = "-per-port"
 	}
+	success := prr.success
+	if c.GetExpectFailure() {
+		success = prr.total - prr.success
+	}
 	m := metrics.NewEventMetrics(time.Now()).
 		AddMetric("total"+suffix, metrics.NewInt(prr.total)).
-		AddMetric("success"+suffix, metrics.NewInt(prr.success)).
+		AddMetric("success"+suffix, metrics.NewInt(success)).
 		AddMetric(opts.LatencyMetricName+suffix, prr.latency.Clone()).
 		AddMetric("delayed"+suffix, metrics.NewInt(prr.delayed)).
+		AddMetric("out_of_order"+suffix, metrics.NewInt(prr.outOfOrder)).
+		AddMetric("corrupted"+suffix, metrics.NewInt(prr.corrupted)).
 		AddLabel("ptype", "udp").
 		AddLabel("probe", probeName).
 		AddLabel("dst", f.target)
 
+	// With expect_failure, success above is inverted, and we export the raw
+	// outcome as well.
+	if c.GetExpectFailure() {
+		rawOutcome := metrics.NewMap("outcome", metrics.NewInt(0))
+		rawOutcome.IncKeyBy("success", metrics.NewInt(prr.success))
+		rawOutcome.IncKeyBy("failure", metrics.NewInt(prr.total-prr.success))
+		m.AddMetric("raw_outcome"+suffix, rawOutcome)
+	}
+
+	if prr.lossGap != nil {
+		m.AddMetric("max_consecutive_loss"+suffix, metrics.NewInt(prr.maxConsecutiveLoss)).
+			AddMetric("loss_bursts"+suffix, metrics.NewInt(prr.lossBursts)).
+			AddMetric("loss_gap"+suffix, prr.lossGap.Clone())
+	}
+
 	for _, al := range opts.AdditionalLabels {
 		m.AddLabel(al.KeyValueForTarget(f.target))
 	}
@@ -129,9 +202,14 @@ func (p *Probe) newProbeResult() *probeResult {
 	} else {
 		latVal = metrics.NewFloat(0)
 	}
-	return &probeResult{
-		latency: latVal,
+	res := &probeResult{
+		latency:   latVal,
+		sinceLoss: -1,
 	}
+	if p.c.GetExportLossMetrics() {
+		res.lossGap, _ = metrics.NewExponentialDistribution(lossGapDistBase, 1, lossGapDistNumBuckets)
+	}
+	return res
 }
 
 // Init initializes the probe with the given params.
@@ -149,6 +227,11 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 	p.c = c
 	p.fsm = message.NewFlowStateMap()
 	p.res = make(map[flow]*probeResult)
+	p.nonceKey = rand.Uint64()
+
+	if p.c.GetExportLossMetrics() && p.c.GetExpectFailure() {
+		return errors.New("UDP probe: export_loss_metrics can't be used with expect_failure")
+	}
 
 	if p.c.GetPayloadSize() != 0 {
 		p.payload = make([]byte, p.c.GetPayloadSize())
@@ -181,7 +264,7 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 
 	udpAddr := &net.UDPAddr{Port: 0}
 	if p.opts.SourceIP != nil {
-		udpAddr.IP = p.opts.SourceIP
+		udpAddr.IP, udpAddr.Zone = p.opts.SourceIP, p.opts.SourceIPZone
 	}
 	p.ipVer = p.opts.IPVersion
 
@@ -206,9 +289,68 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 		}
 		return fmt.Errorf("UDP socket creation failed: got %d connections, want %d", p.numConn, wantConn)
 	}
+
+	if err := p.initFlowLabel(); err != nil {
+		for _, c := range p.connList {
+			c.Close()
+		}
+		return err
+	}
+
+	if p.c.GetBatchPackets() {
+		if !batchSupported {
+			p.l.Warningf("batch_packets is not supported on %s, sending and receiving packets one at a time", runtime.GOOS)
+			return nil
+		}
+		// We use an IPv6 connection wrapper to handle both IPv4 and IPv6
+		// packets.
+		for _, conn := range p.connList {
+			p.batchConns = append(p.batchConns, ipv6.NewPacketConn(conn))
+		}
+	}
 	return nil
 }
 
+// initFlowLabel registers the IPv6 flow label, if configured, with the UDP
+// sockets. Flow label is set on the packets sent to the IPv6 targets through
+// a control message.
+func (p *Probe) initFlowLabel() error {
+	fl := p.opts.IPv6FlowLabel
+	if fl == 0 || p.ipVer == 4 {
+		return nil
+	}
+	if !probeutils.IPv6FlowLabelSupported {
+		p.l.Warningf("ipv6_flow_label is not supported on %s, ignoring it", runtime.GOOS)
+		return nil
+	}
+	for _, conn := range p.connList {
+		rawConn, err := conn.SyscallConn()
+		if err != nil {
+			return err
+		}
+		var sockErr error
+		if err := rawConn.Control(func(fd uintptr) {
+			sockErr = probeutils.SetIPv6FlowLabel(int(fd), fl, nil)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("UDP probe: %v", sockErr)
+		}
+	}
+	p.flowLabelOOB = probeutils.IPv6FlowLabelOOB(fl)
+	return nil
+}
+
+// oob returns the out-of-band data (control message) to send the packet to
+// the given address with.
+func (p *Probe) oob(raddr *net.UDPAddr) []byte {
+	if raddr.IP.To4() != nil {
+		return nil
+	}
+	return p.flowLabelOOB
+}
+
 // initProbeRunResults initializes missing probe results objects.
 func (p *Probe) initProbeRunResults() error {
 	for _, target := range p.targets {
@@ -238,6 +380,10 @@ type packetID struct {
 	seq  uint64
 	txTS time.Time
 	rxTS time.Time
+
+	// Set only for the received packets.
+	outOfOrder bool // Received after a packet with higher seq.
+	corrupted  bool // Nonce or payload doesn't match.
 }
 
 func (p *Probe) resultsKey(f flow) flow {
@@ -247,24 +393,44 @@ func (p *Probe) resultsKey(f flow) flow {
 	return flow{"", f.target}
 }
 
-func (p *Probe) processRcvdPacket(rpkt packetID) {
+// packetKey identifies a packet across the sent and received packets.
+type packetKey struct {
+	f   flow
+	seq uint64
+}
+
+// processRcvdPacket updates the results for the received packet, and returns
+// true if the packet counts as a success.
+func (p *Probe) processRcvdPacket(rpkt packetID) bool {
 	p.l.Debugf("rpkt seq: %d, target: %s", rpkt.seq, rpkt.f)
 	res, ok := p.res[p.resultsKey(rpkt.f)]
 	if !ok {
-		return
+		return false
 	}
 	latency := rpkt.rxTS.Sub(rpkt.txTS)
 	if latency < 0 {
 		p.l.Errorf("Got negative time delta %v for flow %v seq %d", latency, rpkt.f, rpkt.seq)
-		return
+		return false
 	}
+	// Late packets are counted as lost, irrespective of their content.
 	if latency > p.opts.Timeout {
 		p.l.Debugf("Packet delayed. Seq: %d, flow: %v, delay: %v", rpkt.seq, rpkt.f, latency)
 		res.delayed++
-		return
+		return false
+	}
+	if rpkt.corrupted {
+		p.l.Debugf("Packet corrupted. Seq: %d, flow: %v", rpkt.seq, rpkt.f)
+		res.corrupted++
+		return false
+	}
+	if rpkt.outOfOrder {
+		res.outOfOrder++
 	}
 	res.success++
-	res.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	if !p.c.GetExpectFailure() {
+		res.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	}
+	return true
 }
 
 func (p *Probe) processSentPacket(spkt packetID) {
@@ -276,18 +442,54 @@ func (p *Probe) processSentPacket(spkt packetID) {
 	res.total++
 }
 
+// updateLossMetrics updates the loss pattern metrics for the sent packets,
+// in the order they were sent. rcvd contains the packets that were received
+// successfully.
+func (p *Probe) updateLossMetrics(sent []packetID, rcvd map[packetKey]bool) {
+	sort.SliceStable(sent, func(i, j int) bool { return sent[i].txTS.Before(sent[j].txTS) })
+	for _, spkt := range sent {
+		res, ok := p.res[p.resultsKey(spkt.f)]
+		if !ok {
+			continue
+		}
+		res.updateLoss(!rcvd[packetKey{spkt.f, spkt.seq}])
+	}
+}
+
 // processPackets processes packets on the sentPackets and rcvdPackets
 // channels. Packets are inserted into a lookup map as soon as they are
 // received. At every "statsExportInterval" interval, we go through the maps
 // and update the probe results.
 func (p *Probe) processPackets() {
+	// Sent and successfully received packets processed in this run, used for
+	// the loss pattern metrics. A packet and its reply are processed in the
+	// same run, as both are processed only after the timeout from the send
+	// time.
+	var sent []packetID
+	var rcvd map[packetKey]bool
+	exportLoss := p.c.GetExportLossMetrics()
+	if exportLoss {
+		rcvd = make(map[packetKey]bool)
+	}
+	processRcvd := func(rpkt packetID) {
+		if p.processRcvdPacket(rpkt) && exportLoss {
+			rcvd[packetKey{rpkt.f, rpkt.seq}] = true
+		}
+	}
+	processSent := func(spkt packetID) {
+		p.processSentPacket(spkt)
+		if exportLoss {
+			sent = append(sent, spkt)
+		}
+	}
+
 	// Process packets that we queued earlier (mostly from the last timeout
 	// interval)
 	for _, rpkt := range p.rPackets {
-		p.processRcvdPacket(rpkt)
+		processRcvd(rpkt)
 	}
 	for _, spkt := range p.sPackets {
-		p.processSentPacket(spkt)
+		processSent(spkt)
 	}
 	p.rPackets = p.rPackets[0:0]
 	p.sPackets = p.sPackets[0:0]
@@ -305,7 +507,7 @@ func (p *Probe) processPackets() {
 			p.sPackets = append(p.sPackets, pkt)
 			continue
 		}
-		p.processSentPacket(pkt)
+		processSent(pkt)
 		if pkt.seq > p.highestSeq[pkt.f] {
 			p.highestSeq[pkt.f] = pkt.seq
 		}
@@ -323,7 +525,11 @@ func (p *Probe) processPackets() {
 			p.rPackets = append(p.rPackets, pkt)
 			continue
 		}
-		p.processRcvdPacket(pkt)
+		processRcvd(pkt)
+	}
+
+	if exportLoss {
+		p.updateLossMetrics(sent, rcvd)
 	}
 }
 
@@ -334,10 +540,43 @@ func isClientTimeout(err error) bool {
 	return ok && e != nil && e.Timeout()
 }
 
+// rcvdPacketID parses the received message and returns its packetID.
+// lastSeq tracks the highest sequence number received so far for each flow,
+// and is used to detect out-of-order packets.
+func (p *Probe) rcvdPacketID(b []byte, rxTS time.Time, lastSeq map[flow]uint64) (packetID, error) {
+	msg, err := message.NewMessage(b)
+	if err != nil {
+		return packetID{}, err
+	}
+
+	pkt := packetID{
+		f:    flow{msg.SrcPort(), msg.Dst()},
+		seq:  msg.Seq(),
+		txTS: msg.SrcTS(),
+		rxTS: rxTS,
+	}
+
+	// If message is corrupted, we cannot trust its sequence number either.
+	if !msg.VerifyNonce(p.nonceKey) || !bytes.Equal(msg.Payload(), p.payload) {
+		pkt.corrupted = true
+		return pkt, nil
+	}
+
+	if pkt.seq < lastSeq[pkt.f] {
+		pkt.outOfOrder = true
+	} else {
+		lastSeq[pkt.f] = pkt.seq
+	}
+	return pkt, nil
+}
+
 // recvLoop receives all packets over a UDP socket and updates
 // flowStates accordingly.
 func (p *Probe) recvLoop(ctx context.Context, conn *net.UDPConn) {
 	b := make([]byte, maxMsgSize)
+	// All packets received over a connection belong to the flows with the
+	// same source port, so we can track their sequence numbers locally.
+	lastSeq := make(map[flow]uint64)
 	for {
 		select {
 		case <-ctx.Done():
@@ -353,51 +592,74 @@ func (p *Probe) recvLoop(ctx context.Context, conn *net.UDPConn) {
 			continue
 		}
 
-		rxTS := time.Now()
-		msg, err := message.NewMessage(b[:msgLen])
-		if err != nil {
-			p.l.Errorf("Incoming message error from %s: %v", raddr, err)
-			continue
-		}
-		select {
-		case p.rcvdPackets <- packetID{flow{msg.SrcPort(), msg.Dst()}, msg.Seq(), msg.SrcTS(), rxTS}:
-		default:
-			p.l.Errorf("rcvdPackets channel full")
-		}
+		p.queueRcvdPacket(b[:msgLen], time.Now(), raddr, lastSeq)
 	}
 }
 
-func (p *Probe) runSingleProbe(f flow, conn *net.UDPConn, maxLen, dstPort int) error {
+// queueRcvdPacket parses the received message and queues it for processing.
+func (p *Probe) queueRcvdPacket(b []byte, rxTS time.Time, raddr net.Addr, lastSeq map[flow]uint64) {
+	pkt, err := p.rcvdPacketID(b, rxTS, lastSeq)
+	if err != nil {
+		p.l.Errorf("Incoming message error from %s: %v", raddr, err)
+		return
+	}
+	select {
+	case p.rcvdPackets <- pkt:
+	default:
+		p.l.Errorf("rcvdPackets channel full")
+	}
+}
+
+// newProbeMessage resolves the flow's target and creates the next message for
+// the flow. Caller should withdraw the message if it's not sent.
+func (p *Probe) newProbeMessage(f flow, maxLen, dstPort int) ([]byte, *net.UDPAddr, packetID, error) {
 	ip, err := p.opts.Targets.Resolve(f.target, p.ipVer)
 	if err != nil {
-		return fmt.Errorf("unable to resolve %s: %v", f.target, err)
+		return nil, nil, packetID{}, fmt.Errorf("unable to resolve %s: %v", f.target, err)
 	}
 	raddr := &net.UDPAddr{
 		IP:   ip,
 		Port: dstPort,
 	}
 
-	flowState := p.fsm.FlowState(p.src, f.srcPort, f.target)
 	now := time.Now()
-	msg, seq, err := flowState.CreateMessage(now, p.payload, maxLen)
+	msg, seq, err := p.fsm.FlowState(p.src, f.srcPort, f.target).CreateMessageWithNonce(now, p.payload, maxLen, p.nonceKey)
 	if err != nil {
-		return fmt.Errorf("error creating new message to probe target(%s): %v", f.target, err)
+		return nil, nil, packetID{}, fmt.Errorf("error creating new message to probe target(%s): %v", f.target, err)
 	}
+	return msg, raddr, packetID{f: f, seq: seq, txTS: now}, nil
+}
 
-	if _, err := conn.WriteToUDP(msg, raddr); err != nil {
-		flowState.WithdrawMessage(seq)
-		return fmt.Errorf("unable to send to %s(%v): %v", f.target, raddr, err)
-	}
+// withdrawMessage updates the flow state for a message that was not sent.
+func (p *Probe) withdrawMessage(pkt packetID) {
+	p.fsm.FlowState(p.src, pkt.f.srcPort, pkt.f.target).WithdrawMessage(pkt.seq)
+}
+
+// queueSentPacket queues the sent packet for processing.
+func (p *Probe) queueSentPacket(pkt packetID) error {
 	// Send packet over sentPackets channel
 	// May need to make a longer buffer for the channel.
 	select {
-	case p.sentPackets <- packetID{f, seq, now, time.Time{}}:
+	case p.sentPackets <- pkt:
 		return nil
 	default:
 		return fmt.Errorf("sentPackets channel full")
 	}
 }
 
+func (p *Probe) runSingleProbe(f flow, conn *net.UDPConn, maxLen, dstPort int) error {
+	msg, raddr, pkt, err := p.newProbeMessage(f, maxLen, dstPort)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := conn.WriteMsgUDP(msg, p.oob(raddr), raddr); err != nil {
+		p.withdrawMessage(pkt)
+		return fmt.Errorf("unable to send to %s(%v): %v", f.target, raddr, err)
+	}
+	return p.queueSentPacket(pkt)
+}
+
 // runProbe performs a single probe run. The main thread launches one goroutine
 // per target to probe. It manages a sync.WaitGroup and Wait's until all probes
 // have finished, then exits the runProbe method.
@@ -422,12 +684,19 @@ func (p *Probe) runProbe() {
 		initialConn = int(p.runID % uint64(len(p.connList)))
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(len(p.targets) * packetsPerTarget)
-
 	for _, conn := range p.connList {
 		conn.SetWriteDeadline(time.Now().Add(p.opts.Interval / 2))
 	}
+
+	if p.batchConns != nil {
+		p.runBatchedProbe(packetsPerTarget, initialConn, maxLen, dstPort)
+		p.runID++
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(p.targets) * packetsPerTarget)
+
 	for _, target := range p.targets {
 		for i := 0; i < packetsPerTarget; i++ {
 			connID := (initialConn + i) % len(p.connList)
@@ -462,11 +731,15 @@ func (p *Probe) updateTargets() {
 func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
 	p.updateTargets()
 
-	for _, conn := range p.connList {
+	for i, conn := range p.connList {
+		if p.batchConns != nil {
+			go p.recvBatchLoop(ctx, conn, p.batchConns[i])
+			continue
+		}
 		go p.recvLoop(ctx, conn)
 	}
 
-	probeTicker := time.NewTicker(p.opts.Interval)
+	probeTicker := p.opts.NewTicker()
 	statsExportTicker := time.NewTicker(p.opts.StatsExportInterval)
 	flushTicker := time.NewTicker(p.flushIntv)
 
@@ -478,7 +751,12 @@ func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics)
 			statsExportTicker.Stop()
 			return
 		case <-probeTicker.C:
+			if !p.opts.IsScheduled() {
+				continue
+			}
+			start := time.Now()
 			p.runProbe()
+			runstats.RecordRun(p.name, start)
 		case <-flushTicker.C:
 			p.processPackets()
 		case <-statsExportTicker.C:
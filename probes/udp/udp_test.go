@@ -321,6 +321,29 @@ func TestExport(t *testing.T) {
 	}
 }
 
+func TestExportExpectFailure(t *testing.T) {
+	res := probeResult{
+		total:   5,
+		success: 1,
+		delayed: 1,
+		latency: metrics.NewFloat(0),
+	}
+	conf := configpb.ProbeConf{
+		ExpectFailure: proto.Bool(true),
+	}
+	m := res.eventMetrics("probe", &options.Options{}, flow{"port", "target"}, &conf)
+	if r := extractMetric(m, "total"); r != 5 {
+		t.Errorf("extractMetric(m,\"total\")=%d, want 5", r)
+	}
+	// 4 packets didn't get a valid response.
+	if r := extractMetric(m, "success"); r != 4 {
+		t.Errorf("extractMetric(m,\"success\")=%d, want 4", r)
+	}
+	if got, want := m.Metric("raw_outcome").String(), "map:outcome,failure:4,success:1"; got != want {
+		t.Errorf("m.Metric(\"raw_outcome\")=%s, want %s", got, want)
+	}
+}
+
 func TestLossAndDelayed(t *testing.T) {
 	var pktCount int64 = 10
 	cases := []struct {
@@ -372,3 +395,163 @@ func TestLossAndDelayed(t *testing.T) {
 		}
 	}
 }
+
+// startReorderingServer starts a mock UDP echo server on the IPv4 loopback
+// address. It waits for len(order) packets and echoes them back in the given
+// order, flipping the last byte of the packets at the "corrupt" indices.
+func startReorderingServer(t *testing.T, order []int, corrupt map[int]bool) int {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Starting UDP server failed: %v", err)
+	}
+
+	go func() {
+		defer conn.Close()
+		var pkts [][]byte
+		var addr *net.UDPAddr
+		b := make([]byte, maxMsgSize)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for len(pkts) < len(order) {
+			msgLen, raddr, err := conn.ReadFromUDP(b)
+			if err != nil {
+				t.Logf("Error receiving message: %v", err)
+				return
+			}
+			pkts, addr = append(pkts, append([]byte{}, b[:msgLen]...)), raddr
+		}
+		for _, i := range order {
+			pkt := pkts[i]
+			if corrupt[i] {
+				pkt[len(pkt)-1] ^= 0xff
+			}
+			if _, err := conn.WriteToUDP(pkt, addr); err != nil {
+				t.Logf("Error sending message: %v", err)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestOutOfOrderAndCorrupted(t *testing.T) {
+	sysvars.Init(&logger.Logger{}, nil)
+
+	// Packets 1 and 2 are swapped, and packet 3 is corrupted.
+	order := []int{0, 2, 1, 3, 4}
+	port := startReorderingServer(t, order, map[int]bool{3: true})
+
+	p := &Probe{}
+	opts := &options.Options{
+		IPVersion: 4,
+		Targets:   targets.StaticTargets("127.0.0.1"),
+		Interval:  time.Second,
+		Timeout:   2 * time.Second,
+		ProbeConf: &configpb.ProbeConf{
+			Port:        proto.Int32(int32(port)),
+			NumTxPorts:  proto.Int32(1),
+			PayloadSize: proto.Int32(32),
+		},
+		StatsExportInterval: 10 * time.Second,
+	}
+	if err := p.Init("udp", opts); err != nil {
+		t.Fatalf("Error initializing UDP probe: %v", err)
+	}
+	p.targets = p.opts.Targets.ListEndpoints()
+	p.initProbeRunResults()
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	go p.recvLoop(ctx, p.connList[0])
+
+	f := flow{p.srcPortList[0], "127.0.0.1"}
+	for range order {
+		if err := p.runSingleProbe(f, p.connList[0], int(p.c.GetMaxLength()), port); err != nil {
+			t.Fatalf("Error sending packet: %v", err)
+		}
+	}
+
+	for range order {
+		select {
+		case pkt := <-p.rcvdPackets:
+			p.processRcvdPacket(pkt)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for echoed packets")
+		}
+	}
+
+	res := p.res[flow{"", "127.0.0.1"}]
+	if res.success != 4 || res.outOfOrder != 1 || res.corrupted != 1 || res.delayed != 0 {
+		t.Errorf("Got success=%d, out_of_order=%d, corrupted=%d, delayed=%d, want 4, 1, 1, 0", res.success, res.outOfOrder, res.corrupted, res.delayed)
+	}
+}
+
+func TestLateCorruptedPacket(t *testing.T) {
+	p := &Probe{
+		opts: &options.Options{Timeout: time.Second, LatencyUnit: time.Microsecond},
+		c:    &configpb.ProbeConf{},
+		l:    &logger.Logger{},
+	}
+	p.opts.Targets = targets.StaticTargets("t1")
+	p.res = map[flow]*probeResult{{"", "t1"}: p.newProbeResult()}
+
+	txTS := time.Now()
+	// A late reply is counted as lost, even if it's corrupted or out-of-order.
+	p.processRcvdPacket(packetID{f: flow{"1234", "t1"}, seq: 1, txTS: txTS, rxTS: txTS.Add(2 * time.Second), corrupted: true})
+	p.processRcvdPacket(packetID{f: flow{"1234", "t1"}, seq: 2, txTS: txTS, rxTS: txTS.Add(2 * time.Second), outOfOrder: true})
+
+	res := p.res[flow{"", "t1"}]
+	if res.delayed != 2 || res.corrupted != 0 || res.outOfOrder != 0 || res.success != 0 {
+		t.Errorf("Got delayed=%d, corrupted=%d, out_of_order=%d, success=%d, want 2, 0, 0, 0", res.delayed, res.corrupted, res.outOfOrder, res.success)
+	}
+}
+
+func TestLossMetrics(t *testing.T) {
+	p := &Probe{
+		opts: &options.Options{Timeout: time.Second, LatencyUnit: time.Microsecond},
+		c:    &configpb.ProbeConf{ExportLossMetrics: proto.Bool(true)},
+		l:    &logger.Logger{},
+
+		sentPackets: make(chan packetID, 10),
+		rcvdPackets: make(chan packetID, 10),
+		highestSeq:  make(map[flow]uint64),
+	}
+	p.opts.Targets = targets.StaticTargets("t1")
+	p.res = map[flow]*probeResult{{"", "t1"}: p.newProbeResult()}
+
+	// Packets 3, 4, 5 and 8 are lost. Packets are processed in two runs, and
+	// sent packets are queued out of order.
+	lost := map[uint64]bool{3: true, 4: true, 5: true, 8: true}
+	txTS := time.Now().Add(-10 * time.Second)
+	for _, seqs := range [][]uint64{{2, 1, 4, 3}, {10, 5, 6, 7, 9, 8}} {
+		for _, seq := range seqs {
+			pkt := packetID{f: flow{"1234", "t1"}, seq: seq, txTS: txTS.Add(time.Duration(seq) * time.Millisecond)}
+			p.sentPackets <- pkt
+			if !lost[seq] {
+				pkt.rxTS = pkt.txTS.Add(time.Millisecond)
+				p.rcvdPackets <- pkt
+			}
+		}
+		p.processPackets()
+	}
+
+	res := p.res[flow{"", "t1"}]
+	if res.total != 10 || res.success != 6 {
+		t.Errorf("Got total=%d, success=%d, want 10, 6", res.total, res.success)
+	}
+	if res.maxConsecutiveLoss != 3 || res.lossBursts != 1 {
+		t.Errorf("Got max_consecutive_loss=%d, loss_bursts=%d, want 3, 1", res.maxConsecutiveLoss, res.lossBursts)
+	}
+	// Gaps: 0 (3->4), 0 (4->5), 2 (5->8).
+	if got := res.lossGap.Data(); got.Count != 3 || got.Sum != 2 {
+		t.Errorf("Got loss_gap count=%d, sum=%v, want 3, 2", got.Count, got.Sum)
+	}
+
+	em := res.eventMetrics("udp", p.opts, flow{"", "t1"}, p.c)
+	for _, name := range []string{"max_consecutive_loss", "loss_bursts", "loss_gap"} {
+		if em.Metric(name) == nil {
+			t.Errorf("Metric %s missing from: %s", name, em.String())
+		}
+	}
+}
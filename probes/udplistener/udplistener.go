@@ -177,7 +177,7 @@ func (p *Probe) Init(name string, opts *options.Options) error {
 
 	udpAddr := &net.UDPAddr{Port: int(p.c.GetPort())}
 	if p.opts.SourceIP != nil {
-		udpAddr.IP = p.opts.SourceIP
+		udpAddr.IP, udpAddr.Zone = p.opts.SourceIP, p.opts.SourceIPZone
 	}
 
 	conn, err := udpsrv.Listen(udpAddr, p.l)
@@ -0,0 +1,402 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/probes/websocket/proto/config.proto
+
+package proto
+
+import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProbeConf_ProtocolType int32
+
+const (
+	ProbeConf_WS  ProbeConf_ProtocolType = 0
+	ProbeConf_WSS ProbeConf_ProtocolType = 1
+)
+
+// Enum value maps for ProbeConf_ProtocolType.
+var (
+	ProbeConf_ProtocolType_name = map[int32]string{
+		0: "WS",
+		1: "WSS",
+	}
+	ProbeConf_ProtocolType_value = map[string]int32{
+		"WS":  0,
+		"WSS": 1,
+	}
+)
+
+func (x ProbeConf_ProtocolType) Enum() *ProbeConf_ProtocolType {
+	p := new(ProbeConf_ProtocolType)
+	*p = x
+	return p
+}
+
+func (x ProbeConf_ProtocolType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeConf_ProtocolType) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ProbeConf_ProtocolType) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_enumTypes[0]
+}
+
+func (x ProbeConf_ProtocolType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *ProbeConf_ProtocolType) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = ProbeConf_ProtocolType(num)
+	return nil
+}
+
+// Deprecated: Use ProbeConf_ProtocolType.Descriptor instead.
+func (ProbeConf_ProtocolType) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type ProbeConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Protocol *ProbeConf_ProtocolType `protobuf:"varint,1,opt,name=protocol,enum=cloudprober.probes.websocket.ProbeConf_ProtocolType,def=0" json:"protocol,omitempty"`
+	// Port to connect to. If not specified, target's port is used, and if the
+	// target doesn't have a port either, 80 (WS) or 443 (WSS) is used.
+	Port *int32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	// Relative URL (to append to all targets). Must begin with '/'.
+	RelativeUrl *string `protobuf:"bytes,3,opt,name=relative_url,json=relativeUrl,def=/" json:"relative_url,omitempty"`
+	// Origin to send in the upgrade request. Default is http(s)://<target>.
+	Origin  *string             `protobuf:"bytes,4,opt,name=origin" json:"origin,omitempty"`
+	Headers []*ProbeConf_Header `protobuf:"bytes,5,rep,name=headers" json:"headers,omitempty"`
+	// TLS config for the WSS connections.
+	TlsConfig *proto.TLSConfig `protobuf:"bytes,6,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	// Message (text) to send after the upgrade. If set, probe waits for a
+	// response message and reports the message round-trip time. Response is
+	// checked against the probe's validators, if any. If not set, probe runs
+	// succeed once the connection is upgraded.
+	Message *string `protobuf:"bytes,7,opt,name=message" json:"message,omitempty"`
+	// Require the response to be the same as the message sent, e.g. for the
+	// echo servers.
+	ExpectEcho *bool `protobuf:"varint,8,opt,name=expect_echo,json=expectEcho,def=0" json:"expect_echo,omitempty"`
+	// Keep the connection open across probe runs, and send the message on the
+	// same connection every time. Connection is re-established (and upgrade
+	// latency reported again) only if it drops. Requires message.
+	PersistentConnection *bool `protobuf:"varint,9,opt,name=persistent_connection,json=persistentConnection,def=0" json:"persistent_connection,omitempty"`
+}
+
+// Default values for ProbeConf fields.
+const (
+	Default_ProbeConf_Protocol             = ProbeConf_WS
+	Default_ProbeConf_RelativeUrl          = string("/")
+	Default_ProbeConf_ExpectEcho           = bool(false)
+	Default_ProbeConf_PersistentConnection = bool(false)
+)
+
+func (x *ProbeConf) Reset() {
+	*x = ProbeConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf) ProtoMessage() {}
+
+func (x *ProbeConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf.ProtoReflect.Descriptor instead.
+func (*ProbeConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProbeConf) GetProtocol() ProbeConf_ProtocolType {
+	if x != nil && x.Protocol != nil {
+		return *x.Protocol
+	}
+	return Default_ProbeConf_Protocol
+}
+
+func (x *ProbeConf) GetPort() int32 {
+	if x != nil && x.Port != nil {
+		return *x.Port
+	}
+	return 0
+}
+
+func (x *ProbeConf) GetRelativeUrl() string {
+	if x != nil && x.RelativeUrl != nil {
+		return *x.RelativeUrl
+	}
+	return Default_ProbeConf_RelativeUrl
+}
+
+func (x *ProbeConf) GetOrigin() string {
+	if x != nil && x.Origin != nil {
+		return *x.Origin
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetHeaders() []*ProbeConf_Header {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *ProbeConf) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
+func (x *ProbeConf) GetExpectEcho() bool {
+	if x != nil && x.ExpectEcho != nil {
+		return *x.ExpectEcho
+	}
+	return Default_ProbeConf_ExpectEcho
+}
+
+func (x *ProbeConf) GetPersistentConnection() bool {
+	if x != nil && x.PersistentConnection != nil {
+		return *x.PersistentConnection
+	}
+	return Default_ProbeConf_PersistentConnection
+}
+
+type ProbeConf_Header struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Value *string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (x *ProbeConf_Header) Reset() {
+	*x = ProbeConf_Header{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProbeConf_Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeConf_Header) ProtoMessage() {}
+
+func (x *ProbeConf_Header) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeConf_Header.ProtoReflect.Descriptor instead.
+func (*ProbeConf_Header) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *ProbeConf_Header) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *ProbeConf_Header) GetValue() string {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return ""
+}
+
+var File_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x46, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f, 0x77, 0x65, 0x62, 0x73,
+	0x6f, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1c, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x77, 0x65, 0x62,
+	0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f,
+	0x6e, 0x2f, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x91,
+	0x04, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x54, 0x0a, 0x08,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x73, 0x2e, 0x77, 0x65, 0x62, 0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x50, 0x72,
+	0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x54, 0x79, 0x70, 0x65, 0x3a, 0x02, 0x57, 0x53, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63,
+	0x6f, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x24, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
+	0x76, 0x65, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x01, 0x2f, 0x52,
+	0x0b, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x76, 0x65, 0x55, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06,
+	0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6f, 0x72,
+	0x69, 0x67, 0x69, 0x6e, 0x12, 0x48, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2e, 0x77, 0x65, 0x62, 0x73, 0x6f,
+	0x63, 0x6b, 0x65, 0x74, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x48,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x3f,
+	0x0a, 0x0a, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x26, 0x0a, 0x0b, 0x65, 0x78, 0x70,
+	0x65, 0x63, 0x74, 0x5f, 0x65, 0x63, 0x68, 0x6f, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x3a, 0x05,
+	0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x45, 0x63, 0x68,
+	0x6f, 0x12, 0x3a, 0x0a, 0x15, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x74, 0x5f,
+	0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08,
+	0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x14, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74,
+	0x65, 0x6e, 0x74, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x32, 0x0a,
+	0x06, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x22, 0x1f, 0x0a, 0x0c, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x06, 0x0a, 0x02, 0x57, 0x53, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x57, 0x53, 0x53,
+	0x10, 0x01, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x2f,
+	0x77, 0x65, 0x62, 0x73, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_goTypes = []interface{}{
+	(ProbeConf_ProtocolType)(0), // 0: cloudprober.probes.websocket.ProbeConf.ProtocolType
+	(*ProbeConf)(nil),           // 1: cloudprober.probes.websocket.ProbeConf
+	(*ProbeConf_Header)(nil),    // 2: cloudprober.probes.websocket.ProbeConf.Header
+	(*proto.TLSConfig)(nil),     // 3: cloudprober.tlsconfig.TLSConfig
+}
+var file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.probes.websocket.ProbeConf.protocol:type_name -> cloudprober.probes.websocket.ProbeConf.ProtocolType
+	2, // 1: cloudprober.probes.websocket.ProbeConf.headers:type_name -> cloudprober.probes.websocket.ProbeConf.Header
+	3, // 2: cloudprober.probes.websocket.ProbeConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProbeConf_Header); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_probes_websocket_proto_config_proto_depIdxs = nil
+}
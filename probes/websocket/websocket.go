@@ -0,0 +1,496 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package websocket implements a WebSocket prober. It connects to the targets,
+upgrades the connection to WebSocket and, if configured, sends a message and
+waits for the response. It reports statistics on probe runs, successful runs
+and latency, along with the upgrade latency, message round-trip time and
+connection drops, and the reason of the failures.
+
+Probes for all targets run in parallel.
+*/
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
+	"github.com/cloudprober/cloudprober/probes/common/statskeeper"
+	"github.com/cloudprober/cloudprober/probes/options"
+	"github.com/cloudprober/cloudprober/probes/probeutils"
+	configpb "github.com/cloudprober/cloudprober/probes/websocket/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"github.com/cloudprober/cloudprober/validators"
+	"golang.org/x/net/websocket"
+)
+
+// Failure reasons, exported as the "reason" label of the "failures" metric.
+const (
+	reasonConnect        = "connect"
+	reasonUpgrade        = "upgrade"
+	reasonSend           = "send"
+	reasonReceive        = "receive"
+	reasonConnectionDrop = "connection_drop"
+	reasonEchoMismatch   = "echo_mismatch"
+	reasonValidation     = "validation"
+)
+
+// Probe holds aggregate information about all probe runs, per-target.
+type Probe struct {
+	name string
+	opts *options.Options
+	c    *configpb.ProbeConf
+	l    *logger.Logger
+
+	// book-keeping params
+	targets   []endpoint.Endpoint
+	dialF     func(context.Context, string, string) (net.Conn, error)
+	tlsConfig *tls.Config
+
+	// Open connections, keyed by the target name. Used only with
+	// persistent_connection.
+	connsMu sync.Mutex
+	conns   map[string]*websocket.Conn
+}
+
+// probeRunResult captures the results of a single probe run. The way we work
+// with stats makes sure that probeRunResult and its fields are not accessed
+// concurrently. That's the reason we use metrics.Int types instead of
+// metrics.AtomicInt.
+type probeRunResult struct {
+	target            string
+	total             metrics.Int
+	success           metrics.Int
+	latency           metrics.Value
+	timeouts          metrics.Int
+	failures          *metrics.Map
+	upgradeLatency    metrics.Value
+	connDrops         metrics.Int
+	latencyMetricName string
+
+	// messageRTT is exported only if message is configured.
+	messageRTT metrics.Value
+
+	// validationFailure is exported only if validators are configured.
+	validationFailure *metrics.Map
+
+	// skipped is exported only if probe's concurrency is limited.
+	skipped       metrics.Int
+	exportSkipped bool
+
+	// rawLatency is the latency of the probe run, 0 if the run didn't succeed.
+	// It's used only if export_raw_latency is enabled.
+	rawLatency time.Duration
+
+	// ipVersionUsed is exported only if IP version fallback is configured.
+	ipVersionUsed *metrics.Map
+}
+
+// Metrics converts probeRunResult into metrics.EventMetrics object
+func (prr probeRunResult) Metrics() *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", &prr.total).
+		AddMetric("success", &prr.success).
+		AddMetric(prr.latencyMetricName, prr.latency).
+		AddMetric("timeouts", &prr.timeouts).
+		AddMetric("failures", prr.failures).
+		AddMetric("upgrade_latency", prr.upgradeLatency).
+		AddMetric("connection_drops", &prr.connDrops)
+	if prr.messageRTT != nil {
+		em.AddMetric("message_rtt", prr.messageRTT)
+	}
+	if prr.validationFailure != nil {
+		em.AddMetric("validation_failure", prr.validationFailure)
+	}
+	if prr.ipVersionUsed != nil {
+		em.AddMetric("ip_version_used", prr.ipVersionUsed)
+	}
+	if prr.exportSkipped {
+		em.AddMetric("skipped_targets", &prr.skipped)
+	}
+	return em
+}
+
+// Target returns the p.target.
+func (prr probeRunResult) Target() string {
+	return prr.target
+}
+
+// RawLatency returns the latency of the probe run, if it succeeded.
+func (prr probeRunResult) RawLatency() (time.Duration, bool) {
+	return prr.rawLatency, prr.rawLatency != 0
+}
+
+func (p *Probe) updateTargets() {
+	p.targets = p.opts.Targets.ListEndpoints()
+
+	for _, target := range p.targets {
+		for _, al := range p.opts.AdditionalLabels {
+			al.UpdateForTarget(target)
+		}
+	}
+}
+
+// Init initializes the probe with the given params.
+func (p *Probe) Init(name string, opts *options.Options) error {
+	c, ok := opts.ProbeConf.(*configpb.ProbeConf)
+	if !ok {
+		return errors.New("no websocket config")
+	}
+	p.c = c
+	p.name = name
+	p.opts = opts
+	if p.l = opts.Logger; p.l == nil {
+		p.l = &logger.Logger{}
+	}
+
+	if p.c.GetPort() < 0 || p.c.GetPort() > 65535 {
+		return fmt.Errorf("websocket_probe(%s): invalid port: %d", name, p.c.GetPort())
+	}
+
+	if !strings.HasPrefix(p.c.GetRelativeUrl(), "/") {
+		return fmt.Errorf("websocket_probe(%s): relative_url should start with '/': %s", name, p.c.GetRelativeUrl())
+	}
+
+	if p.c.GetMessage() == "" && (p.c.GetExpectEcho() || p.c.GetPersistentConnection()) {
+		return fmt.Errorf("websocket_probe(%s): expect_echo and persistent_connection require message", name)
+	}
+
+	if p.c.GetProtocol() == configpb.ProbeConf_WSS {
+		p.tlsConfig = &tls.Config{}
+		if p.c.GetTlsConfig() != nil {
+			if err := tlsconfig.UpdateTLSConfig(p.tlsConfig, p.c.GetTlsConfig(), false); err != nil {
+				return fmt.Errorf("websocket_probe(%s): error configuring TLS: %v", name, err)
+			}
+		}
+	}
+
+	// Connections are bounded by the target's timeout through the context,
+	// and by the connect timeout, if configured.
+	dialer := &net.Dialer{}
+	if p.opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.opts.SourceIP, Zone: p.opts.SourceIPZone}
+	}
+	p.dialF = p.opts.DialContextFunc(dialer)
+
+	p.conns = make(map[string]*websocket.Conn)
+
+	p.updateTargets()
+	return nil
+}
+
+func (p *Probe) newLatencyValue() metrics.Value {
+	if p.opts.LatencyDist != nil {
+		return p.opts.LatencyDist.Clone()
+	}
+	return metrics.NewFloat(0)
+}
+
+func (p *Probe) newResult(target string) probeRunResult {
+	result := probeRunResult{
+		target:            target,
+		latencyMetricName: p.opts.LatencyMetricName,
+		exportSkipped:     p.opts.MaxConcurrentProbes > 0,
+		failures:          metrics.NewMap("reason", metrics.NewInt(0)),
+		latency:           p.newLatencyValue(),
+		upgradeLatency:    p.newLatencyValue(),
+	}
+
+	if p.c.GetMessage() != "" {
+		result.messageRTT = p.newLatencyValue()
+	}
+	if p.opts.Validators != nil {
+		result.validationFailure = validators.ValidationFailureMap(p.opts.Validators)
+	}
+	result.ipVersionUsed = p.opts.IPVersionUsedMap()
+	return result
+}
+
+// isTimeout returns true if the given error is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isConnectionDrop returns true if the given error indicates that the
+// connection was closed by the other end.
+func isConnectionDrop(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// urls returns the address to connect to, and the WebSocket URL and origin
+// for the given target.
+func (p *Probe) urls(target endpoint.Endpoint) (addr, wsURL, origin string) {
+	scheme, originScheme, defaultPort := "ws", "http", 80
+	if p.c.GetProtocol() == configpb.ProbeConf_WSS {
+		scheme, originScheme, defaultPort = "wss", "https", 443
+	}
+
+	port := probeutils.TargetPort(target, "", int(p.c.GetPort()), p.l)
+	if port == 0 {
+		port = defaultPort
+	}
+
+	addr = net.JoinHostPort(target.Name, strconv.Itoa(port))
+	wsURL = fmt.Sprintf("%s://%s%s", scheme, addr, p.c.GetRelativeUrl())
+
+	origin = p.c.GetOrigin()
+	if origin == "" {
+		origin = originScheme + "://" + target.Name
+	}
+	return
+}
+
+// connect connects to the target and upgrades the connection. It returns the
+// failure reason, along with the error, if it fails.
+func (p *Probe) connect(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) (*websocket.Conn, string, error) {
+	addr, wsURL, origin := p.urls(target)
+
+	config, err := websocket.NewConfig(wsURL, origin)
+	if err != nil {
+		return nil, reasonConnect, err
+	}
+	for _, h := range p.c.GetHeaders() {
+		config.Header.Set(h.GetName(), h.GetValue())
+	}
+
+	conn, err := p.dialF(ctx, "tcp", addr)
+	if err != nil {
+		return nil, reasonConnect, fmt.Errorf("error connecting to %s: %v", addr, err)
+	}
+	options.RecordIPVersionUsed(result.ipVersionUsed, conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if p.tlsConfig != nil {
+		tlsConfig := p.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = target.Name
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, reasonConnect, fmt.Errorf("TLS handshake with %s failed: %v", addr, err)
+		}
+		conn = tlsConn
+	}
+
+	start := time.Now()
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, reasonUpgrade, fmt.Errorf("upgrade failed: %v", err)
+	}
+	result.upgradeLatency.AddFloat64(time.Since(start).Seconds() / p.opts.LatencyUnit.Seconds())
+
+	return ws, "", nil
+}
+
+// exchange sends the configured message and receives the response. It returns
+// the failure reason, along with the error, if it fails.
+func (p *Probe) exchange(ctx context.Context, ws *websocket.Conn, result *probeRunResult) (string, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		ws.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	if err := websocket.Message.Send(ws, p.c.GetMessage()); err != nil {
+		if isConnectionDrop(err) {
+			return reasonConnectionDrop, err
+		}
+		return reasonSend, err
+	}
+
+	var resp []byte
+	if err := websocket.Message.Receive(ws, &resp); err != nil {
+		if isConnectionDrop(err) {
+			return reasonConnectionDrop, err
+		}
+		return reasonReceive, err
+	}
+	result.messageRTT.AddFloat64(time.Since(start).Seconds() / p.opts.LatencyUnit.Seconds())
+
+	if p.c.GetExpectEcho() && !bytes.Equal(resp, []byte(p.c.GetMessage())) {
+		return reasonEchoMismatch, fmt.Errorf("response (%d bytes) doesn't match the message sent", len(resp))
+	}
+
+	if p.opts.Validators != nil {
+		failedValidations := validators.RunValidators(p.opts.Validators, &validators.Input{ResponseBody: resp}, result.validationFailure, p.l)
+		if len(failedValidations) > 0 {
+			return reasonValidation, fmt.Errorf("failed validations: %s", strings.Join(failedValidations, ","))
+		}
+	}
+
+	return "", nil
+}
+
+// persistentConn returns the open connection for the target, if any.
+func (p *Probe) persistentConn(target string) *websocket.Conn {
+	p.connsMu.Lock()
+	defer p.connsMu.Unlock()
+	return p.conns[target]
+}
+
+func (p *Probe) setPersistentConn(target string, ws *websocket.Conn) {
+	p.connsMu.Lock()
+	defer p.connsMu.Unlock()
+	if ws == nil {
+		delete(p.conns, target)
+		return
+	}
+	p.conns[target] = ws
+}
+
+func (p *Probe) runProbeForTarget(ctx context.Context, target endpoint.Endpoint, result *probeRunResult) {
+	result.total.Inc()
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.TargetTimeout(target))
+	defer cancel()
+
+	failed := func(reason string, err error) {
+		p.l.Warningf("Target(%s): %v", target.Name, err)
+		if isTimeout(err) {
+			result.timeouts.Inc()
+		}
+		if reason == reasonConnectionDrop {
+			result.connDrops.Inc()
+		}
+		result.failures.IncKey(reason)
+	}
+
+	start := time.Now()
+
+	persistent := p.c.GetPersistentConnection()
+	var ws *websocket.Conn
+	if persistent {
+		ws = p.persistentConn(target.Name)
+	}
+
+	if ws == nil {
+		var reason string
+		var err error
+		if ws, reason, err = p.connect(ctx, target, result); err != nil {
+			failed(reason, err)
+			return
+		}
+		if persistent {
+			p.setPersistentConn(target.Name, ws)
+		}
+	}
+	if !persistent {
+		defer ws.Close()
+	}
+
+	if p.c.GetMessage() != "" {
+		if reason, err := p.exchange(ctx, ws, result); err != nil {
+			failed(reason, err)
+			// Response failures leave the connection in a good state, but
+			// with everything else, we can't reuse the connection.
+			if persistent && reason != reasonEchoMismatch && reason != reasonValidation {
+				ws.Close()
+				p.setPersistentConn(target.Name, nil)
+			}
+			return
+		}
+	}
+
+	latency := time.Since(start)
+	result.success.Inc()
+	result.latency.AddFloat64(latency.Seconds() / p.opts.LatencyUnit.Seconds())
+	result.rawLatency = latency
+}
+
+func (p *Probe) runProbe(ctx context.Context, resultsChan chan<- statskeeper.ProbeResult) {
+	// Refresh the list of targets to probe.
+	p.updateTargets()
+
+	// Targets waiting for a concurrency slot should get it within the probe
+	// interval, otherwise they are skipped for this cycle.
+	ctx, cancelFunc := context.WithTimeout(ctx, p.opts.Interval)
+	defer cancelFunc()
+
+	probeF := func(target endpoint.Endpoint) {
+		result := p.newResult(target.Name)
+		p.runProbeForTarget(ctx, target, &result)
+		resultsChan <- result
+	}
+
+	skippedF := func(target endpoint.Endpoint) {
+		p.l.Warningf("Target(%s): no free concurrency slot within the probe interval, skipping", target.Name)
+		result := p.newResult(target.Name)
+		result.skipped.Inc()
+		resultsChan <- result
+	}
+
+	probeutils.RunForTargets(ctx, p.targets, p.opts.MaxConcurrentProbes, probeF, skippedF)
+}
+
+// closeConns closes the open persistent connections.
+func (p *Probe) closeConns() {
+	p.connsMu.Lock()
+	defer p.connsMu.Unlock()
+	for target, ws := range p.conns {
+		ws.Close()
+		delete(p.conns, target)
+	}
+}
+
+// Start starts and runs the probe indefinitely.
+func (p *Probe) Start(ctx context.Context, dataChan chan *metrics.EventMetrics) {
+	resultsChan := make(chan statskeeper.ProbeResult, len(p.targets))
+
+	targetsFunc := func() []endpoint.Endpoint {
+		return p.targets
+	}
+
+	go statskeeper.StatsKeeper(ctx, "websocket", p.name, p.opts, targetsFunc, resultsChan, dataChan)
+
+	defer p.closeConns()
+
+	ticker := p.opts.NewTicker()
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Don't run another probe if context is canceled already.
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		// Skip the cycle if we are outside the probe's schedule.
+		if !p.opts.IsScheduled() {
+			continue
+		}
+
+		start := time.Now()
+		p.runProbe(ctx, resultsChan)
+		runstats.RecordRun(p.name, start)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/probes/testutils"
+	configpb "github.com/cloudprober/cloudprober/probes/websocket/proto"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	"golang.org/x/net/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// testServer starts a WebSocket server with the following handlers:
+//   /echo: echoes the messages back.
+//   /reverse: replies with the reversed message.
+//   /drop: closes the connection after the upgrade.
+//   /drop_second: echoes the first message, and closes the connection after
+//                 receiving the second message.
+// It returns the target for the server, and a counter of the upgraded
+// connections.
+func testServer(t *testing.T) (endpoint.Endpoint, *int32) {
+	t.Helper()
+
+	var upgrades int32
+	handler := func(f func(*websocket.Conn)) websocket.Handler {
+		return func(ws *websocket.Conn) {
+			atomic.AddInt32(&upgrades, 1)
+			f(ws)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/echo", handler(func(ws *websocket.Conn) {
+		for {
+			var msg []byte
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			websocket.Message.Send(ws, string(msg))
+		}
+	}))
+	mux.Handle("/reverse", handler(func(ws *websocket.Conn) {
+		var msg string
+		websocket.Message.Receive(ws, &msg)
+		b := []byte(msg)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		websocket.Message.Send(ws, string(b))
+	}))
+	mux.Handle("/drop", handler(func(ws *websocket.Conn) {}))
+	mux.Handle("/drop_second", handler(func(ws *websocket.Conn) {
+		var msg string
+		websocket.Message.Receive(ws, &msg)
+		websocket.Message.Send(ws, msg)
+		websocket.Message.Receive(ws, &msg)
+	}))
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return endpoint.Endpoint{Name: host, Port: port}, &upgrades
+}
+
+func testProbe(t *testing.T, target endpoint.Endpoint, c *configpb.ProbeConf) *Probe {
+	t.Helper()
+
+	p := &Probe{}
+	testutils.InitProbe(t, p, "websocket_test", testutils.Options(target.Name, time.Second, c))
+	return p
+}
+
+func TestRunProbe(t *testing.T) {
+	target, _ := testServer(t)
+
+	for _, test := range []struct {
+		desc       string
+		conf       *configpb.ProbeConf
+		wantReason string
+	}{
+		{
+			desc: "upgrade_only",
+			conf: &configpb.ProbeConf{RelativeUrl: proto.String("/echo")},
+		},
+		{
+			desc: "echo",
+			conf: &configpb.ProbeConf{RelativeUrl: proto.String("/echo"), Message: proto.String("hello"), ExpectEcho: proto.Bool(true)},
+		},
+		{
+			desc: "no_echo_expected",
+			conf: &configpb.ProbeConf{RelativeUrl: proto.String("/reverse"), Message: proto.String("hello")},
+		},
+		{
+			desc:       "echo_mismatch",
+			conf:       &configpb.ProbeConf{RelativeUrl: proto.String("/reverse"), Message: proto.String("hello"), ExpectEcho: proto.Bool(true)},
+			wantReason: reasonEchoMismatch,
+		},
+		{
+			desc:       "connection_drop",
+			conf:       &configpb.ProbeConf{RelativeUrl: proto.String("/drop"), Message: proto.String("hello")},
+			wantReason: reasonConnectionDrop,
+		},
+		{
+			desc:       "upgrade_failure",
+			conf:       &configpb.ProbeConf{RelativeUrl: proto.String("/not_found")},
+			wantReason: reasonUpgrade,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			p := testProbe(t, target, test.conf)
+
+			result := p.newResult(target.Name)
+			p.runProbeForTarget(context.Background(), target, &result)
+
+			if err := testutils.CheckRunResult(result.Metrics(), test.wantReason); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantReason != "" {
+				wantDrops := int64(0)
+				if test.wantReason == reasonConnectionDrop {
+					wantDrops = 1
+				}
+				if result.connDrops.Int64() != wantDrops {
+					t.Errorf("Got connection_drops=%d, want=%d", result.connDrops.Int64(), wantDrops)
+				}
+				return
+			}
+
+			em := result.Metrics()
+			for _, name := range []string{"latency", "upgrade_latency", "connection_drops"} {
+				if em.Metric(name) == nil {
+					t.Errorf("Metric %s missing: %s", name, em.String())
+				}
+			}
+			if (em.Metric("message_rtt") != nil) != (test.conf.Message != nil) {
+				t.Errorf("Got message_rtt=%v, want it exported only if message is configured", em.Metric("message_rtt"))
+			}
+		})
+	}
+}
+
+func TestPersistentConnection(t *testing.T) {
+	target, upgrades := testServer(t)
+
+	p := testProbe(t, target, &configpb.ProbeConf{
+		RelativeUrl:          proto.String("/echo"),
+		Message:              proto.String("hello"),
+		ExpectEcho:           proto.Bool(true),
+		PersistentConnection: proto.Bool(true),
+	})
+	defer p.closeConns()
+
+	result := p.newResult(target.Name)
+	for i := 0; i < 3; i++ {
+		p.runProbeForTarget(context.Background(), target, &result)
+	}
+	if result.success.Int64() != 3 {
+		t.Errorf("Got success=%d, failures=%s, want success=3", result.success.Int64(), result.failures.String())
+	}
+	if got := atomic.LoadInt32(upgrades); got != 1 {
+		t.Errorf("Got %d upgraded connections, want 1", got)
+	}
+
+	// Server drops the connection after the first message, probe should
+	// report the drop, and reconnect in the next run.
+	p.closeConns()
+	p.c.RelativeUrl = proto.String("/drop_second")
+	result = p.newResult(target.Name)
+	for i := 0; i < 3; i++ {
+		p.runProbeForTarget(context.Background(), target, &result)
+	}
+	if result.success.Int64() != 2 || result.connDrops.Int64() != 1 {
+		t.Errorf("Got success=%d, connection_drops=%d, want success=2, connection_drops=1", result.success.Int64(), result.connDrops.Int64())
+	}
+	if got := atomic.LoadInt32(upgrades); got != 3 {
+		t.Errorf("Got %d upgraded connections, want 3", got)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testutils.VerifyInitErrors(t, func() testutils.Prober { return &Probe{} }, []testutils.InitErrorTest{
+		{Desc: "invalid_port", Conf: &configpb.ProbeConf{Port: proto.Int32(70000)}},
+		{Desc: "invalid_relative_url", Conf: &configpb.ProbeConf{RelativeUrl: proto.String("echo")}},
+		{Desc: "echo_without_message", Conf: &configpb.ProbeConf{ExpectEcho: proto.Bool(true)}},
+		{Desc: "persistent_without_message", Conf: &configpb.ProbeConf{PersistentConnection: proto.Bool(true)}},
+	})
+}
@@ -18,9 +18,14 @@ Package file implements a file-based targets provider for cloudprober.
 package file
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"math/rand"
+	"net"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -137,11 +142,66 @@ func (ls *lister) parseFileContent(b []byte) ([]*pb.Resource, error) {
 			return nil, fmt.Errorf("file_provider(%s): error unmarshaling as JSON: %v", ls.filePath, err)
 		}
 		return resources.GetResource(), nil
+	case configpb.ProviderConfig_PLAINTEXT:
+		res, err := parsePlaintext(b)
+		if err != nil {
+			return nil, fmt.Errorf("file_provider(%s): error parsing plaintext: %v", ls.filePath, err)
+		}
+		return res, nil
 	}
 
 	return nil, fmt.Errorf("file_provider(%s): unknown format - %v", ls.filePath, ls.format)
 }
 
+// parsePlaintext parses resources from the plaintext format: one resource per
+// line, in the form "<host>[:<port>] [<label_key>=<label_value> ...]". Blank
+// lines and lines starting with '#' are ignored.
+func parsePlaintext(b []byte) ([]*pb.Resource, error) {
+	var resources []*pb.Resource
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		host, port := fields[0], ""
+		if h, p, err := net.SplitHostPort(fields[0]); err == nil {
+			host, port = h, p
+		}
+
+		res := &pb.Resource{
+			Name: proto.String(host),
+			Ip:   proto.String(host),
+		}
+
+		if port != "" {
+			portNum, err := strconv.ParseInt(port, 10, 32)
+			if err != nil || portNum <= 0 || portNum > 65535 {
+				return nil, fmt.Errorf("line %d: invalid port: %s", lineNum, port)
+			}
+			res.Port = proto.Int32(int32(portNum))
+		}
+
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("line %d: invalid label: %s, expected key=value", lineNum, f)
+			}
+			if res.Labels == nil {
+				res.Labels = make(map[string]string)
+			}
+			res.Labels[kv[0]] = kv[1]
+		}
+
+		resources = append(resources, res)
+	}
+
+	return resources, scanner.Err()
+}
+
 func (ls *lister) shouldReloadFile() bool {
 	if !ls.checkModTime {
 		return true
@@ -190,6 +250,8 @@ func formatFromPath(path string) configpb.ProviderConfig_Format {
 		return configpb.ProviderConfig_TEXTPB
 	case ".json":
 		return configpb.ProviderConfig_JSON
+	case ".txt":
+		return configpb.ProviderConfig_PLAINTEXT
 	}
 	return configpb.ProviderConfig_TEXTPB
 }
@@ -214,11 +276,15 @@ func newLister(filePath string, c *configpb.ProviderConfig, l *logger.Logger) (*
 		return ls, ls.refresh()
 	}
 
+	// Load the file once before returning, so that resources are available
+	// right away. Since we'll keep trying to reload the file, we don't fail
+	// on error here.
+	if err := ls.refresh(); err != nil {
+		l.Error(err.Error())
+	}
+
 	reEvalInterval := time.Duration(reEvalSec) * time.Second
 	go func() {
-		if err := ls.refresh(); err != nil {
-			l.Error(err.Error())
-		}
 		// Introduce a random delay between 0-reEvalInterval before
 		// starting the refresh loop. If there are multiple cloudprober
 		// instances, this will make sure that each instance refreshes
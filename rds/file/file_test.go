@@ -374,3 +374,53 @@ func TestListResourcesWithCache(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePlaintext(t *testing.T) {
+	content := `
+# Comment line, followed by a blank line.
+
+switch-xx-1:8080 device_type=switch cluster=xx
+  10.1.1.2:8081  cluster=xx
+[::aaa:1]:8080
+switch-yy-1
+`
+	wantResources := []*rdspb.Resource{
+		{
+			Name: proto.String("switch-xx-1"),
+			Ip:   proto.String("switch-xx-1"),
+			Port: proto.Int32(8080),
+			Labels: map[string]string{
+				"device_type": "switch",
+				"cluster":     "xx",
+			},
+		},
+		{
+			Name:   proto.String("10.1.1.2"),
+			Ip:     proto.String("10.1.1.2"),
+			Port:   proto.Int32(8081),
+			Labels: map[string]string{"cluster": "xx"},
+		},
+		{
+			Name: proto.String("::aaa:1"),
+			Ip:   proto.String("::aaa:1"),
+			Port: proto.Int32(8080),
+		},
+		{
+			Name: proto.String("switch-yy-1"),
+			Ip:   proto.String("switch-yy-1"),
+		},
+	}
+
+	ls := &lister{format: configpb.ProviderConfig_PLAINTEXT}
+	got, err := ls.parseFileContent([]byte(content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	compareResourceList(t, got, wantResources)
+
+	for _, bad := range []string{"host:badport", "host:0", "host:8080 badlabel", "host =value"} {
+		if _, err := ls.parseFileContent([]byte(bad)); err == nil {
+			t.Errorf("Expected error for line: %q", bad)
+		}
+	}
+}
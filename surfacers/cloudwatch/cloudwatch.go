@@ -19,17 +19,23 @@ package cloudwatch
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/sysvars"
 
 	configpb "github.com/cloudprober/cloudprober/surfacers/cloudwatch/proto"
+	"github.com/cloudprober/cloudprober/surfacers/common/backoff"
 	"github.com/cloudprober/cloudprober/surfacers/common/options"
 )
 
@@ -44,21 +50,39 @@ type CWSurfacer struct {
 	c         *configpb.SurfacerConf
 	opts      *options.Options
 	writeChan chan *metrics.EventMetrics
-	session   *cloudwatch.CloudWatch
+	writeDone chan struct{} // Closed when the write loop exits.
+	session   cloudwatchiface.CloudWatchAPI
 	l         *logger.Logger
 
+	// Sender for metric datum batches, backs off while cloudwatch is
+	// unavailable.
+	sender *backoff.Sender
+
+	// Labels to export as dimensions. If nil, all labels are exported.
+	dimensionLabels map[string]bool
+
+	// Latest values of cumulative metrics, used for aggregating metrics across
+	// dropped labels. It's keyed by the metric name and dimensions first, and
+	// then by the full label set of the incoming EventMetrics.
+	aggregates map[string]map[string]float64
+
 	// A cache of []*cloudwatch.MetricDatum's, used for batch writing to the
 	// cloudwatch api.
 	cwMetricDatumCache []*cloudwatch.MetricDatum
 }
 
 func (cw *CWSurfacer) processIncomingMetrics(ctx context.Context) {
+	defer close(cw.writeDone)
+
 	for {
 		select {
 		case <-ctx.Done():
 			cw.l.Infof("Context canceled, stopping the surfacer write loop")
 			return
-		case em := <-cw.writeChan:
+		case em, ok := <-cw.writeChan:
+			if !ok {
+				return
+			}
 			cw.recordEventMetrics(em)
 		}
 	}
@@ -75,48 +99,127 @@ func (cw *CWSurfacer) recordEventMetrics(em *metrics.EventMetrics) {
 
 		switch value := em.Metric(metricKey).(type) {
 		case metrics.NumValue:
-			cw.publishMetrics(cw.newCWMetricDatum(metricKey, value.Float64(), emLabelsToDimensions(em), em.Timestamp, em.LatencyUnit))
+			dimensions := cw.dimensions(em)
+			cw.publishMetrics(cw.newCWMetricDatum(metricKey, cw.aggregate(metricKey, dimensions, em, value.Float64()), dimensions, em.Timestamp, em.LatencyUnit))
 
 		case *metrics.Map:
 			for _, mapKey := range value.Keys() {
-				dimensions := emLabelsToDimensions(em)
+				dimensions := cw.dimensions(em)
 				dimensions = append(dimensions, &cloudwatch.Dimension{
 					Name:  aws.String(value.MapName),
 					Value: aws.String(mapKey),
 				})
-				cw.publishMetrics(cw.newCWMetricDatum(metricKey, value.GetKey(mapKey).Float64(), dimensions, em.Timestamp, em.LatencyUnit))
+				cw.publishMetrics(cw.newCWMetricDatum(metricKey, cw.aggregate(metricKey, dimensions, em, value.GetKey(mapKey).Float64()), dimensions, em.Timestamp, em.LatencyUnit))
 			}
 
 		case *metrics.Distribution:
 			for i, distributionBound := range value.Data().LowerBounds {
-				dimensions := append(emLabelsToDimensions(em), &cloudwatch.Dimension{
+				dimensions := append(cw.dimensions(em), &cloudwatch.Dimension{
 					Name:  aws.String(distributionDimensionName),
 					Value: aws.String(strconv.FormatFloat(distributionBound, 'f', -1, 64)),
 				})
 
-				cw.publishMetrics(cw.newCWMetricDatum(metricKey, float64(value.Data().BucketCounts[i]), dimensions, em.Timestamp, em.LatencyUnit))
+				cw.publishMetrics(cw.newCWMetricDatum(metricKey, cw.aggregate(metricKey, dimensions, em, float64(value.Data().BucketCounts[i])), dimensions, em.Timestamp, em.LatencyUnit))
 			}
 		}
 	}
 }
 
+// dimensions returns the dimensions for the given EventMetrics, keeping only
+// the labels selected through the dimension_labels config.
+func (cw *CWSurfacer) dimensions(em *metrics.EventMetrics) []*cloudwatch.Dimension {
+	dimensions := emLabelsToDimensions(em)
+	if cw.dimensionLabels == nil {
+		return dimensions
+	}
+
+	selected := dimensions[:0]
+	for _, d := range dimensions {
+		if cw.dimensionLabels[aws.StringValue(d.Name)] {
+			selected = append(selected, d)
+		}
+	}
+	return selected
+}
+
+func dimensionsKey(dimensions []*cloudwatch.Dimension) string {
+	var b strings.Builder
+	for _, d := range dimensions {
+		b.WriteString(aws.StringValue(d.Name) + "=" + aws.StringValue(d.Value) + ",")
+	}
+	return b.String()
+}
+
+// aggregate returns the value to publish for the given metric. If
+// aggregation across dropped labels is enabled, it returns the sum of the
+// latest values of the metric across all label sets that map to the same
+// dimensions. Only cumulative metrics are aggregated, gauges are returned
+// as it is.
+func (cw *CWSurfacer) aggregate(metricName string, dimensions []*cloudwatch.Dimension, em *metrics.EventMetrics, value float64) float64 {
+	if !cw.c.GetAggregateDroppedLabels() || cw.dimensionLabels == nil || em.Kind != metrics.CUMULATIVE {
+		return value
+	}
+
+	if cw.aggregates == nil {
+		cw.aggregates = make(map[string]map[string]float64)
+	}
+	key := metricName + "," + dimensionsKey(dimensions)
+	if cw.aggregates[key] == nil {
+		cw.aggregates[key] = make(map[string]float64)
+	}
+	cw.aggregates[key][dimensionsKey(emLabelsToDimensions(em))] = value
+
+	var sum float64
+	for _, v := range cw.aggregates[key] {
+		sum += v
+	}
+	return sum
+}
+
 // Publish the metrics to cloudwatch, using the namespace provided from
 // configuration.
 func (cw *CWSurfacer) publishMetrics(md *cloudwatch.MetricDatum) {
 	if len(cw.cwMetricDatumCache) >= maxMetricDatums {
-		_, err := cw.session.PutMetricData(&cloudwatch.PutMetricDataInput{
-			Namespace:  aws.String(cw.c.GetNamespace()),
-			MetricData: cw.cwMetricDatumCache,
-		})
+		cw.flush()
+	}
 
-		if err != nil {
-			cw.l.Errorf("Failed to publish metrics to cloudwatch: %s", err)
-		}
+	cw.cwMetricDatumCache = append(cw.cwMetricDatumCache, md)
+}
 
-		cw.cwMetricDatumCache = cw.cwMetricDatumCache[:0]
+// flush writes the cached metric datums to cloudwatch.
+func (cw *CWSurfacer) flush() {
+	if len(cw.cwMetricDatumCache) == 0 {
+		return
 	}
 
-	cw.cwMetricDatumCache = append(cw.cwMetricDatumCache, md)
+	// Copy the datums as the cache is reused, while the sender may buffer the
+	// batch if cloudwatch is unavailable.
+	cw.sender.Send(append([]*cloudwatch.MetricDatum{}, cw.cwMetricDatumCache...))
+
+	cw.cwMetricDatumCache = cw.cwMetricDatumCache[:0]
+}
+
+// putMetricData writes a batch of metric datums to cloudwatch.
+func (cw *CWSurfacer) putMetricData(item interface{}) error {
+	_, err := cw.session.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(cw.c.GetNamespace()),
+		MetricData: item.([]*cloudwatch.MetricDatum),
+	})
+	if err != nil {
+		// Requests rejected by cloudwatch (other than for throttling) are
+		// rejected again if retried.
+		if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode()/100 == 4 && rerr.StatusCode() != http.StatusTooManyRequests {
+			return backoff.Permanent(fmt.Errorf("cloudwatch rejected the metrics: %v", err))
+		}
+		return fmt.Errorf("failed to publish metrics to cloudwatch: %v", err)
+	}
+	return nil
+}
+
+// Dropped returns the number of metric datum batches dropped, either because
+// cloudwatch rejected them, or because the reconnect buffer was full.
+func (cw *CWSurfacer) Dropped() int64 {
+	return cw.sender.Dropped()
 }
 
 // Create a new cloudwatch metriddatum using the values passed in.
@@ -191,10 +294,20 @@ func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Optio
 		c:         config,
 		opts:      opts,
 		writeChan: make(chan *metrics.EventMetrics, opts.MetricsBufferSize),
+		writeDone: make(chan struct{}),
 		session:   cloudwatch.New(sess),
 		l:         l,
 	}
 
+	cw.sender = backoff.NewSender(opts.Config, cw.putMetricData, l)
+
+	if len(config.GetDimensionLabels()) > 0 {
+		cw.dimensionLabels = make(map[string]bool)
+		for _, label := range config.GetDimensionLabels() {
+			cw.dimensionLabels[label] = true
+		}
+	}
+
 	// Set the capacity of this slice to the max metric value, to avoid having to
 	// grow the slice.
 	cw.cwMetricDatumCache = make([]*cloudwatch.MetricDatum, 0, maxMetricDatums)
@@ -214,3 +327,11 @@ func (cw *CWSurfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
 		cw.l.Error("Surfacer's write channel is full, dropping new data.")
 	}
 }
+
+// Close stops the write loop after processing the already queued metrics, and
+// publishes the cached metric datums.
+func (cw *CWSurfacer) Close() {
+	close(cw.writeChan)
+	<-cw.writeDone
+	cw.flush()
+}
@@ -16,6 +16,7 @@ package cloudwatch
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -23,9 +24,11 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
 	configpb "github.com/cloudprober/cloudprober/surfacers/cloudwatch/proto"
+	"github.com/cloudprober/cloudprober/surfacers/common/backoff"
 )
 
 func newTestCWSurfacer() CWSurfacer {
@@ -302,6 +305,93 @@ func TestNewCWMetricDatum(t *testing.T) {
 	}
 }
 
+type mockCWClient struct {
+	cloudwatchiface.CloudWatchAPI
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (m *mockCWClient) PutMetricData(in *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	// Copy the datums as the surfacer reuses the underlying cache.
+	m.inputs = append(m.inputs, &cloudwatch.PutMetricDataInput{
+		Namespace:  in.Namespace,
+		MetricData: append([]*cloudwatch.MetricDatum{}, in.MetricData...),
+	})
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestDimensionLabels(t *testing.T) {
+	for _, aggregate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("aggregate=%v", aggregate), func(t *testing.T) {
+			s := newTestCWSurfacer()
+			mc := &mockCWClient{}
+			s.session = mc
+			s.sender = backoff.NewSender(nil, s.putMetricData, s.l)
+			s.dimensionLabels = map[string]bool{"probe": true}
+			s.c.AggregateDroppedLabels = aws.Bool(aggregate)
+
+			// 11 targets x 2 metrics result in one PutMetricData call with
+			// maxMetricDatums (20) datums.
+			for i := 0; i < 11; i++ {
+				em := metrics.NewEventMetrics(time.Now()).
+					AddMetric("total", metrics.NewInt(10)).
+					AddMetric("success", metrics.NewInt(5)).
+					AddLabel("ptype", "http").
+					AddLabel("probe", "probe1").
+					AddLabel("dst", fmt.Sprintf("target%d", i))
+				s.recordEventMetrics(em)
+			}
+
+			if len(mc.inputs) != 1 {
+				t.Fatalf("Got %d PutMetricData calls, want 1", len(mc.inputs))
+			}
+
+			wantDimensions := []*cloudwatch.Dimension{
+				{Name: aws.String("probe"), Value: aws.String("probe1")},
+			}
+			for i, md := range mc.inputs[0].MetricData {
+				if !reflect.DeepEqual(md.Dimensions, wantDimensions) {
+					t.Errorf("Datum %d dimensions: %v, want: %v", i, md.Dimensions, wantDimensions)
+				}
+
+				// Without aggregation, we get the same value for each target. With
+				// aggregation, values are summed across targets.
+				target := i / 2
+				want := map[string]float64{"total": 10, "success": 5}[aws.StringValue(md.MetricName)]
+				if aggregate {
+					want *= float64(target + 1)
+				}
+				if got := aws.Float64Value(md.Value); got != want {
+					t.Errorf("Datum %d (%s) value: %v, want: %v", i, aws.StringValue(md.MetricName), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDimensionsMapMetric(t *testing.T) {
+	s := newTestCWSurfacer()
+	s.dimensionLabels = map[string]bool{"dst": true}
+
+	m := metrics.NewMap("code", metrics.NewInt(0))
+	m.IncKey("200")
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("resp-code", m).
+		AddLabel("probe", "probe1").
+		AddLabel("dst", "target1")
+	s.recordEventMetrics(em)
+
+	want := []*cloudwatch.Dimension{
+		{Name: aws.String("dst"), Value: aws.String("target1")},
+		{Name: aws.String("code"), Value: aws.String("200")},
+	}
+	if len(s.cwMetricDatumCache) != 1 {
+		t.Fatalf("Got %d datums, want 1", len(s.cwMetricDatumCache))
+	}
+	if got := s.cwMetricDatumCache[0].Dimensions; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dimensions: %v, want: %v", got, want)
+	}
+}
+
 func ErrorContains(out error, want string) bool {
 	if out == nil {
 		return want == ""
@@ -39,12 +39,28 @@ type SurfacerConf struct {
 	// 4. AWS_DEFAULT_REGION environment value, if AWS_SDK_LOAD_CONFIG is set.
 	// https://docs.aws.amazon.com/sdk-for-go/api/aws/session/
 	Region *string `protobuf:"bytes,3,opt,name=region" json:"region,omitempty"`
+	// Labels to export as CloudWatch dimensions. CloudWatch allows at most 10
+	// dimensions per metric and charges for every unique combination of
+	// dimensions, so it's often useful to export only a subset of labels, e.g.
+	//   dimension_labels: "probe"
+	//   dimension_labels: "dst"
+	// If not specified, all labels are exported as dimensions.
+	DimensionLabels []string `protobuf:"bytes,4,rep,name=dimension_labels,json=dimensionLabels" json:"dimension_labels,omitempty"`
+	// Whether to aggregate metrics across the labels dropped by the
+	// dimension_labels setting. If enabled, cumulative metrics from all
+	// EventMetrics that map to the same set of dimensions are summed together
+	// before being published, e.g. with dimension_labels set to "probe", the
+	// "total" metric is the sum of "total" across all targets of the probe.
+	// If disabled, metrics are published as they come and metrics that differ
+	// only in dropped labels will overwrite each other in CloudWatch.
+	AggregateDroppedLabels *bool `protobuf:"varint,5,opt,name=aggregate_dropped_labels,json=aggregateDroppedLabels,def=0" json:"aggregate_dropped_labels,omitempty"`
 }
 
 // Default values for SurfacerConf fields.
 const (
-	Default_SurfacerConf_Namespace  = string("cloudprober")
-	Default_SurfacerConf_Resolution = int64(60)
+	Default_SurfacerConf_Namespace              = string("cloudprober")
+	Default_SurfacerConf_Resolution             = int64(60)
+	Default_SurfacerConf_AggregateDroppedLabels = bool(false)
 )
 
 func (x *SurfacerConf) Reset() {
@@ -100,6 +116,20 @@ func (x *SurfacerConf) GetRegion() string {
 	return ""
 }
 
+func (x *SurfacerConf) GetDimensionLabels() []string {
+	if x != nil {
+		return x.DimensionLabels
+	}
+	return nil
+}
+
+func (x *SurfacerConf) GetAggregateDroppedLabels() bool {
+	if x != nil && x.AggregateDroppedLabels != nil {
+		return *x.AggregateDroppedLabels
+	}
+	return Default_SurfacerConf_AggregateDroppedLabels
+}
+
 var File_github_com_cloudprober_cloudprober_surfacers_cloudwatch_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_surfacers_cloudwatch_proto_config_proto_rawDesc = []byte{
@@ -109,19 +139,26 @@ var file_github_com_cloudprober_cloudprober_surfacers_cloudwatch_proto_config_pr
 	0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
 	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1f, 0x63, 0x6c,
 	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63,
-	0x65, 0x72, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x22, 0x75, 0x0a,
-	0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x29, 0x0a,
-	0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x3a, 0x0b, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x52, 0x09, 0x6e,
-	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x22, 0x0a, 0x0a, 0x72, 0x65, 0x73, 0x6f,
-	0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x3a, 0x02, 0x36, 0x30,
-	0x52, 0x0a, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06,
-	0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65,
-	0x67, 0x69, 0x6f, 0x6e, 0x42, 0x3f, 0x5a, 0x3d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63,
-	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61,
-	0x63, 0x65, 0x72, 0x73, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2f,
-	0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x65, 0x72, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x22, 0xe1, 0x01,
+	0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x29,
+	0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x3a, 0x0b, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x52, 0x09,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x22, 0x0a, 0x0a, 0x72, 0x65, 0x73,
+	0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x3a, 0x02, 0x36,
+	0x30, 0x52, 0x0a, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x10, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69,
+	0x6f, 0x6e, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x0f, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x12, 0x3f, 0x0a, 0x18, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x5f, 0x64, 0x72,
+	0x6f, 0x70, 0x70, 0x65, 0x64, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x16, 0x61, 0x67, 0x67, 0x72, 0x65,
+	0x67, 0x61, 0x74, 0x65, 0x44, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x4c, 0x61, 0x62, 0x65, 0x6c,
+	0x73, 0x42, 0x3f, 0x5a, 0x3d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72,
+	0x73, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f,
 }
 
 var (
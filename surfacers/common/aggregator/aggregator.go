@@ -0,0 +1,202 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package aggregator implements a pseudo-surfacer that rolls up EventMetrics
+over a time window before forwarding them to a downstream surfacer.
+
+For CUMULATIVE EventMetrics, aggregator sums the per-cycle increments of the
+counters, and merges the per-cycle increments of the distributions, and at the
+end of every window, forwards the running totals as a CUMULATIVE EventMetrics.
+Since per-cycle increments are computed from successive EventMetrics, counter
+resets (e.g. on probe restarts) don't show up as drops in the rolled-up
+metrics. String metrics carry the last value.
+
+For GAUGE EventMetrics, aggregator forwards the last EventMetrics received
+within the window.
+*/
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// staleWindows is the number of windows after which we drop the state of
+// EventMetrics that are not being updated anymore.
+const staleWindows = 10
+
+// Surfacer is the interface of the downstream surfacer. It's the same as the
+// surfacers.Surfacer interface, redefined here to avoid a dependency cycle.
+type Surfacer interface {
+	Write(ctx context.Context, em *metrics.EventMetrics)
+}
+
+// rollup is the aggregation state of a single EventMetrics (identified by its
+// metrics and labels).
+type rollup struct {
+	last    *metrics.EventMetrics // Last EventMetrics received.
+	acc     *metrics.EventMetrics // Rolled up EventMetrics.
+	updated bool                  // Updated within the current window.
+	idle    int                   // Windows since the last update.
+}
+
+// Aggregator rolls up EventMetrics over a window, and forwards the rolled up
+// EventMetrics to the downstream surfacer at the end of every window.
+type Aggregator struct {
+	window     time.Duration
+	downstream Surfacer
+	l          *logger.Logger
+
+	mu      sync.Mutex
+	rollups map[string]*rollup
+	keys    []string // To keep the output order stable.
+}
+
+// New returns a new aggregator that rolls up metrics over the given window and
+// writes them to the downstream surfacer. Rolled up metrics are flushed at the
+// end of every window, until the context is canceled.
+func New(ctx context.Context, window time.Duration, downstream Surfacer, l *logger.Logger) (*Aggregator, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("aggregator: invalid window: %v", window)
+	}
+
+	a := &Aggregator{
+		window:     window,
+		downstream: downstream,
+		l:          l,
+		rollups:    make(map[string]*rollup),
+	}
+
+	go func() {
+		ticker := time.NewTicker(a.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.flush(ctx)
+			}
+		}
+	}()
+
+	return a, nil
+}
+
+// accumulate adds em's increments over last to acc, and returns the new
+// rolled up EventMetrics.
+func accumulate(acc, last, em *metrics.EventMetrics) (*metrics.EventMetrics, error) {
+	delta := em.Clone()
+	for _, name := range em.MetricsKeys() {
+		val, lastVal := delta.Metric(name), last.Metric(name)
+		if _, ok := val.(metrics.String); ok || lastVal == nil {
+			continue
+		}
+		wasReset, err := val.SubtractCounter(lastVal)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %v", name, err)
+		}
+		// If any metric is reset, consider it a full reset of EventMetrics,
+		// i.e. all current values are increments.
+		if wasReset {
+			delta = em.Clone()
+			break
+		}
+	}
+
+	newAcc := metrics.NewEventMetrics(em.Timestamp)
+	newAcc.Kind, newAcc.LatencyUnit = em.Kind, em.LatencyUnit
+	for _, k := range em.LabelsKeys() {
+		newAcc.AddLabel(k, em.Label(k))
+	}
+	for _, name := range em.MetricsKeys() {
+		val, accVal := delta.Metric(name), acc.Metric(name)
+		if _, ok := val.(metrics.String); ok || accVal == nil {
+			newAcc.AddMetric(name, val)
+			continue
+		}
+		newVal := accVal.Clone()
+		if err := newVal.Add(val); err != nil {
+			return nil, fmt.Errorf("metric %s: %v", name, err)
+		}
+		newAcc.AddMetric(name, newVal)
+	}
+	return newAcc, nil
+}
+
+// Write adds the EventMetrics to the current window.
+func (a *Aggregator) Write(ctx context.Context, em *metrics.EventMetrics) {
+	key := em.Key()
+	// Cache a copy of "em" as some fields like maps and dist can be shared
+	// across successive "em" writes.
+	emCopy := em.Clone()
+	emCopy.LatencyUnit = em.LatencyUnit
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := a.rollups[key]
+	if r == nil {
+		r = &rollup{}
+		a.rollups[key] = r
+		a.keys = append(a.keys, key)
+	}
+	r.updated, r.idle = true, 0
+
+	if r.last == nil || em.Kind != metrics.CUMULATIVE || r.last.Kind != metrics.CUMULATIVE {
+		r.last, r.acc = emCopy, emCopy
+		return
+	}
+
+	acc, err := accumulate(r.acc, r.last, emCopy)
+	if err != nil {
+		a.l.Warningf("Error rolling up metrics (%s), starting over: %v", em.String(), err)
+		acc = emCopy
+	}
+	r.last, r.acc = emCopy, acc
+}
+
+// flush writes the rolled up EventMetrics, updated in the current window, to
+// the downstream surfacer.
+func (a *Aggregator) flush(ctx context.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var keys []string
+	for _, key := range a.keys {
+		r := a.rollups[key]
+		if r.updated {
+			r.updated = false
+			em := r.acc.Clone()
+			em.LatencyUnit = r.acc.LatencyUnit
+			a.downstream.Write(ctx, em)
+		} else if r.idle++; r.idle >= staleWindows {
+			delete(a.rollups, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	a.keys = keys
+}
+
+// Close flushes the metrics rolled up so far.
+func (a *Aggregator) Close() {
+	a.flush(context.Background())
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+type testSurfacer struct {
+	ems []*metrics.EventMetrics
+}
+
+func (ts *testSurfacer) Write(_ context.Context, em *metrics.EventMetrics) {
+	ts.ems = append(ts.ems, em)
+}
+
+func testAggregator(t *testing.T) (*Aggregator, *testSurfacer) {
+	t.Helper()
+
+	// Cancel the context right away, we flush explicitly in tests.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ts := &testSurfacer{}
+	a, err := New(ctx, time.Minute, ts, nil)
+	if err != nil {
+		t.Fatalf("Error creating aggregator: %v", err)
+	}
+	return a, ts
+}
+
+func testDist(t *testing.T, samples ...float64) *metrics.Distribution {
+	t.Helper()
+	d := metrics.NewDistribution([]float64{1, 2, 4})
+	for _, s := range samples {
+		d.AddFloat64(s)
+	}
+	return d
+}
+
+func cumulativeEM(ts time.Time, total, success int64, latency *metrics.Distribution, version string) *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(ts).
+		AddMetric("total", metrics.NewInt(total)).
+		AddMetric("success", metrics.NewInt(success)).
+		AddMetric("latency", latency).
+		AddMetric("version", metrics.NewString(version)).
+		AddLabel("probe", "p1").
+		AddLabel("dst", "t1")
+	em.LatencyUnit = time.Millisecond
+	return em
+}
+
+func TestAggregatorCumulative(t *testing.T) {
+	a, ts := testAggregator(t)
+	start := time.Now()
+
+	// Window 1: 3 cycles.
+	a.Write(context.Background(), cumulativeEM(start, 1, 1, testDist(t, 0.5), "v1"))
+	a.Write(context.Background(), cumulativeEM(start.Add(10*time.Second), 2, 1, testDist(t, 0.5, 3), "v1"))
+	a.Write(context.Background(), cumulativeEM(start.Add(20*time.Second), 3, 2, testDist(t, 0.5, 3, 1.5), "v2"))
+	a.flush(context.Background())
+
+	// Window 2: probe restarted, counters reset.
+	a.Write(context.Background(), cumulativeEM(start.Add(30*time.Second), 1, 1, testDist(t, 5), "v2"))
+	a.Write(context.Background(), cumulativeEM(start.Add(40*time.Second), 2, 2, testDist(t, 5, 0.5), "v2"))
+	a.flush(context.Background())
+
+	// Window 3: no new metrics.
+	a.flush(context.Background())
+
+	wantTS := []time.Time{start.Add(20 * time.Second), start.Add(40 * time.Second)}
+	wantTotal, wantSuccess := []int64{3, 5}, []int64{2, 4}
+	wantLatency := []*metrics.Distribution{testDist(t, 0.5, 3, 1.5), testDist(t, 0.5, 3, 1.5, 5, 0.5)}
+
+	if len(ts.ems) != len(wantTS) {
+		t.Fatalf("Got %d EventMetrics, want %d: %v", len(ts.ems), len(wantTS), ts.ems)
+	}
+	for i, em := range ts.ems {
+		if !em.Timestamp.Equal(wantTS[i]) || em.Kind != metrics.CUMULATIVE || em.LatencyUnit != time.Millisecond {
+			t.Errorf("Window %d: got timestamp=%v, kind=%v, latency unit=%v", i, em.Timestamp, em.Kind, em.LatencyUnit)
+		}
+		if em.Label("probe") != "p1" || em.Label("dst") != "t1" {
+			t.Errorf("Window %d: unexpected labels: %s", i, em.String())
+		}
+		total, success := em.Metric("total").(metrics.NumValue).Int64(), em.Metric("success").(metrics.NumValue).Int64()
+		if total != wantTotal[i] || success != wantSuccess[i] {
+			t.Errorf("Window %d: got total=%d, success=%d, want %d, %d", i, total, success, wantTotal[i], wantSuccess[i])
+		}
+		if got, want := em.Metric("latency").String(), wantLatency[i].String(); got != want {
+			t.Errorf("Window %d: got latency=%s, want=%s", i, got, want)
+		}
+		if got := em.Metric("version").String(); got != "\"v2\"" {
+			t.Errorf("Window %d: got version=%s, want=\"v2\"", i, got)
+		}
+	}
+}
+
+func TestAggregatorGauge(t *testing.T) {
+	a, ts := testAggregator(t)
+
+	for _, v := range []int64{5, 2, 7} {
+		em := metrics.NewEventMetrics(time.Now()).
+			AddMetric("goroutines", metrics.NewInt(v)).
+			AddLabel("ptype", "sysvars")
+		em.Kind = metrics.GAUGE
+		a.Write(context.Background(), em)
+	}
+	a.flush(context.Background())
+
+	if len(ts.ems) != 1 {
+		t.Fatalf("Got %d EventMetrics, want 1: %v", len(ts.ems), ts.ems)
+	}
+	if got := ts.ems[0].Metric("goroutines").(metrics.NumValue).Int64(); got != 7 || ts.ems[0].Kind != metrics.GAUGE {
+		t.Errorf("Got goroutines=%d (kind: %v), want=7 (GAUGE)", got, ts.ems[0].Kind)
+	}
+}
+
+func TestAggregatorBucketsMismatch(t *testing.T) {
+	a, ts := testAggregator(t)
+
+	d := metrics.NewDistribution([]float64{1, 10})
+	d.AddFloat64(20)
+
+	a.Write(context.Background(), cumulativeEM(time.Now(), 1, 1, testDist(t, 0.5), "v1"))
+	a.Write(context.Background(), cumulativeEM(time.Now(), 2, 2, d, "v1"))
+	a.flush(context.Background())
+
+	// Metrics with different buckets cannot be merged, aggregator starts over.
+	if len(ts.ems) != 1 || ts.ems[0].Metric("latency").String() != d.String() {
+		t.Errorf("Got EventMetrics: %v, want latency=%s", ts.ems, d.String())
+	}
+}
+
+func TestAggregatorStaleState(t *testing.T) {
+	a, _ := testAggregator(t)
+
+	a.Write(context.Background(), cumulativeEM(time.Now(), 1, 1, testDist(t, 0.5), "v1"))
+	// First window flushes the metrics, following windows are idle.
+	for i := 0; i <= staleWindows; i++ {
+		if len(a.rollups) != 1 {
+			t.Fatalf("Window %d: state dropped too early", i)
+		}
+		a.flush(context.Background())
+	}
+	if len(a.rollups) != 0 || len(a.keys) != 0 {
+		t.Errorf("Stale state not dropped after %d idle windows: %v", staleWindows, a.keys)
+	}
+}
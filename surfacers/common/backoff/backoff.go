@@ -0,0 +1,193 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package backoff implements a sender for the surfacers that push metrics to
+remote backends.
+
+When a send fails, Sender assumes that the backend is unavailable and backs
+off exponentially (with jitter) before trying again. While backing off,
+incoming items (e.g. batches of metrics) are buffered, up to a limit beyond
+which new items are dropped. Once the backend is reachable again, buffered
+items are flushed, in order, before the new ones.
+
+Send functions should wrap the errors that retrying won't fix (e.g. data
+rejected by the backend as invalid) using Permanent(). Items failing with such
+errors are dropped right away, instead of blocking the items behind them.
+*/
+package backoff
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	surfacerpb "github.com/cloudprober/cloudprober/surfacers/proto"
+)
+
+// permanentError is an error that retrying the send won't fix.
+type permanentError struct {
+	err error
+}
+
+func (pe *permanentError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe *permanentError) Unwrap() error {
+	return pe.err
+}
+
+// Permanent wraps the given error to indicate to the Sender that retrying the
+// send won't help, e.g. because the backend rejected the data. Such items are
+// dropped, and the backend is not considered unavailable.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Sender sends items using the provided send function, backing off on
+// failures.
+type Sender struct {
+	send           func(item interface{}) error
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	bufferSize     int
+	l              *logger.Logger
+
+	mu          sync.Mutex
+	backoff     time.Duration // Current backoff, 0 if backend is healthy.
+	nextAttempt time.Time
+	buffer      []interface{}
+	dropped     int64
+
+	// Functions to get current time and to add jitter to the backoff,
+	// overridden in tests.
+	nowF    func() time.Time
+	jitterF func(time.Duration) time.Duration
+}
+
+// NewSender returns a new sender that sends items using the send function.
+// Backoff and buffering parameters are taken from the surfacer config.
+func NewSender(sdef *surfacerpb.SurfacerDef, send func(item interface{}) error, l *logger.Logger) *Sender {
+	return &Sender{
+		send:           send,
+		initialBackoff: time.Duration(sdef.GetReconnectInitialBackoffMsec()) * time.Millisecond,
+		maxBackoff:     time.Duration(sdef.GetReconnectMaxBackoffMsec()) * time.Millisecond,
+		bufferSize:     int(sdef.GetReconnectBufferSize()),
+		l:              l,
+		nowF:           time.Now,
+		jitterF:        equalJitter,
+	}
+}
+
+// equalJitter returns a random duration in [d/2, d).
+func equalJitter(d time.Duration) time.Duration {
+	if d < 2 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// bufferItem adds the item to the buffer, dropping it if the buffer is full.
+// Must be called with s.mu held.
+func (s *Sender) bufferItem(item interface{}) {
+	if len(s.buffer) >= s.bufferSize {
+		s.dropped++
+		s.l.Warningf("Backend unavailable and reconnect buffer is full, dropping data. Total dropped: %d", s.dropped)
+		return
+	}
+	s.buffer = append(s.buffer, item)
+}
+
+// failed records a send failure and computes the next backoff. Must be called
+// with s.mu held.
+func (s *Sender) failed(err error) {
+	if s.backoff == 0 {
+		s.backoff = s.initialBackoff
+	} else {
+		s.backoff *= 2
+	}
+	if s.backoff > s.maxBackoff {
+		s.backoff = s.maxBackoff
+	}
+	wait := s.jitterF(s.backoff)
+	s.nextAttempt = s.nowF().Add(wait)
+	s.l.Warningf("Error sending data: %v, backend assumed unavailable, retrying in %v", err, wait)
+}
+
+// trySend sends the item, dropping it if the send fails with a permanent
+// error. Must be called with s.mu held.
+func (s *Sender) trySend(item interface{}) error {
+	err := s.send(item)
+
+	var pe *permanentError
+	if errors.As(err, &pe) {
+		s.dropped++
+		s.l.Warningf("Error sending data: %v, retrying won't help, dropping it. Total dropped: %d", pe.err, s.dropped)
+		return nil
+	}
+	return err
+}
+
+// Send sends the item. If we are backing off, the item is buffered to be sent
+// later. Otherwise, buffered items, if any, are sent first.
+func (s *Sender) Send(item interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nowF().Before(s.nextAttempt) {
+		s.bufferItem(item)
+		return
+	}
+
+	for len(s.buffer) > 0 {
+		if err := s.trySend(s.buffer[0]); err != nil {
+			s.failed(err)
+			s.bufferItem(item)
+			return
+		}
+		s.buffer = s.buffer[1:]
+	}
+
+	if err := s.trySend(item); err != nil {
+		s.failed(err)
+		s.bufferItem(item)
+		return
+	}
+
+	if s.backoff != 0 {
+		s.l.Infof("Backend available again, resuming sending data.")
+		s.backoff = 0
+	}
+}
+
+// Buffered returns the number of items currently buffered.
+func (s *Sender) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buffer)
+}
+
+// Dropped returns the number of items dropped, either because the buffer was
+// full, or because they failed with a permanent error.
+func (s *Sender) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
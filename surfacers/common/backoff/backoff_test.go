@@ -0,0 +1,153 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	surfacerpb "github.com/cloudprober/cloudprober/surfacers/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// testBackend simulates a backend that can go up and down.
+type testBackend struct {
+	up       bool
+	rejected map[interface{}]bool
+	received []interface{}
+}
+
+func (tb *testBackend) send(item interface{}) error {
+	if !tb.up {
+		return errors.New("backend down")
+	}
+	if tb.rejected[item] {
+		return Permanent(errors.New("bad item"))
+	}
+	tb.received = append(tb.received, item)
+	return nil
+}
+
+func testSender(tb *testBackend, now *time.Time) *Sender {
+	s := NewSender(&surfacerpb.SurfacerDef{
+		ReconnectInitialBackoffMsec: proto.Int32(1000),
+		ReconnectMaxBackoffMsec:     proto.Int32(5000),
+		ReconnectBufferSize:         proto.Int32(3),
+	}, tb.send, nil)
+	s.nowF = func() time.Time { return *now }
+	s.jitterF = func(d time.Duration) time.Duration { return d }
+	return s
+}
+
+func TestBackoffGrowth(t *testing.T) {
+	tb := &testBackend{}
+	now := time.Now()
+	s := testSender(tb, &now)
+
+	var backoffs []time.Duration
+	for i := 0; i < 5; i++ {
+		s.Send(i)
+		backoffs = append(backoffs, s.backoff)
+		// Move to the next attempt.
+		now = s.nextAttempt
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(backoffs, want) {
+		t.Errorf("Got backoffs: %v, want: %v", backoffs, want)
+	}
+
+	// Buffer size is 3, rest are dropped.
+	if s.Buffered() != 3 || s.Dropped() != 2 {
+		t.Errorf("Got buffered=%d, dropped=%d, want buffered=3, dropped=2", s.Buffered(), s.Dropped())
+	}
+}
+
+func TestBackendFlaps(t *testing.T) {
+	tb := &testBackend{up: true}
+	now := time.Now()
+	s := testSender(tb, &now)
+
+	s.Send(0)
+
+	// Backend goes down, first send fails and we start backing off.
+	tb.up = false
+	s.Send(1)
+	if s.backoff != time.Second {
+		t.Errorf("Got backoff=%v, want=%v", s.backoff, time.Second)
+	}
+
+	// While backing off, items are buffered without hitting the backend, even
+	// if it comes back up in the meantime.
+	tb.up = true
+	now = now.Add(500 * time.Millisecond)
+	s.Send(2)
+	if len(tb.received) != 1 || s.Buffered() != 2 {
+		t.Errorf("Got received=%v, buffered=%d, want received=[0], buffered=2", tb.received, s.Buffered())
+	}
+
+	// After the backoff, buffered items are flushed, in order, before the new
+	// item, and backoff is reset.
+	now = now.Add(time.Second)
+	s.Send(3)
+	if want := []interface{}{0, 1, 2, 3}; !reflect.DeepEqual(tb.received, want) {
+		t.Errorf("Got received=%v, want=%v", tb.received, want)
+	}
+	if s.Buffered() != 0 || s.backoff != 0 {
+		t.Errorf("Got buffered=%d, backoff=%v, want buffered=0, backoff=0", s.Buffered(), s.backoff)
+	}
+
+	// Backend goes down again, backoff starts from the initial backoff.
+	tb.up = false
+	s.Send(4)
+	if s.backoff != time.Second {
+		t.Errorf("Got backoff=%v, want=%v", s.backoff, time.Second)
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	tb := &testBackend{rejected: map[interface{}]bool{1: true}}
+	now := time.Now()
+	s := testSender(tb, &now)
+
+	// Backend is down, items are buffered.
+	s.Send(0)
+	now = s.nextAttempt
+	s.Send(1)
+	now = s.nextAttempt
+
+	// Once backend is up, rejected item is dropped, without blocking the items
+	// behind it or triggering a backoff.
+	tb.up = true
+	s.Send(2)
+	s.Send(3)
+	if want := []interface{}{0, 2, 3}; !reflect.DeepEqual(tb.received, want) {
+		t.Errorf("Got received=%v, want=%v", tb.received, want)
+	}
+	if s.Buffered() != 0 || s.Dropped() != 1 || s.backoff != 0 {
+		t.Errorf("Got buffered=%d, dropped=%d, backoff=%v, want buffered=0, dropped=1, backoff=0", s.Buffered(), s.Dropped(), s.backoff)
+	}
+}
+
+func TestEqualJitter(t *testing.T) {
+	d := 10 * time.Second
+	for i := 0; i < 100; i++ {
+		if got := equalJitter(d); got < d/2 || got >= d {
+			t.Errorf("equalJitter(%v)=%v, want in [%v, %v)", d, got, d/2, d)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package cardinality implements a guard against runaway label cardinality.
+
+Guard tracks the unique label sets (series) seen for each metric. Once a
+metric reaches the configured max cardinality, new series for that metric are
+dropped, while the already tracked series continue to go through. Each key of
+a map metric (e.g. HTTP response codes) is a separate series.
+*/
+package cardinality
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// logInterval is the minimum interval between the logs about dropped series.
+var logInterval = time.Minute
+
+// Guard limits the number of unique label sets per metric.
+type Guard struct {
+	maxCardinality int
+	l              *logger.Logger
+
+	mu      sync.Mutex
+	series  map[string]map[string]bool // metric name -> label sets
+	dropped int64
+	lastLog time.Time
+}
+
+// New returns a new guard that allows up to maxCardinality unique label sets
+// per metric.
+func New(maxCardinality int, l *logger.Logger) *Guard {
+	return &Guard{
+		maxCardinality: maxCardinality,
+		l:              l,
+		series:         make(map[string]map[string]bool),
+	}
+}
+
+// allow returns whether the given series should be allowed, tracking it if
+// it's new and there is room for it. Must be called with g.mu held.
+func (g *Guard) allow(name, key string) bool {
+	if g.series[name] == nil {
+		g.series[name] = make(map[string]bool)
+	}
+	if g.series[name][key] {
+		return true
+	}
+	if len(g.series[name]) >= g.maxCardinality {
+		return false
+	}
+	g.series[name][key] = true
+	return true
+}
+
+// filterMap returns the map value with the keys that would exceed the max
+// cardinality removed, and the number of removed keys. If all keys are
+// removed, it returns nil. Must be called with g.mu held.
+func (g *Guard) filterMap(name, key string, m *metrics.Map) (*metrics.Map, int) {
+	keys := m.Keys()
+
+	var allowedKeys []string
+	for _, k := range keys {
+		if g.allow(name, key+","+m.MapName+"="+k) {
+			allowedKeys = append(allowedKeys, k)
+		}
+	}
+
+	numDropped := len(keys) - len(allowedKeys)
+	if numDropped == 0 {
+		return m, 0
+	}
+	if len(allowedKeys) == 0 {
+		return nil, numDropped
+	}
+
+	var defaultValue metrics.NumValue = metrics.NewInt(0)
+	if _, ok := m.GetKey(allowedKeys[0]).(*metrics.Float); ok {
+		defaultValue = metrics.NewFloat(0)
+	}
+	newMap := metrics.NewMap(m.MapName, defaultValue)
+	for _, k := range allowedKeys {
+		newMap.IncKeyBy(k, m.GetKey(k))
+	}
+	return newMap, numDropped
+}
+
+// Filter returns the EventMetrics with the metrics (or map metrics' keys)
+// that would exceed the max cardinality removed. If nothing is removed, it
+// returns the original EventMetrics, and if all metrics are removed, it
+// returns nil. Removed metrics and map keys are counted, see Dropped().
+func (g *Guard) Filter(em *metrics.EventMetrics) *metrics.EventMetrics {
+	key := em.LabelsKey()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var allowed, dropped []string
+	var numDropped int
+	values := make(map[string]metrics.Value)
+	for _, name := range em.MetricsKeys() {
+		val := em.Metric(name)
+
+		if m, ok := val.(*metrics.Map); ok {
+			newMap, n := g.filterMap(name, key, m)
+			if n > 0 {
+				dropped = append(dropped, name)
+				numDropped += n
+			}
+			if newMap != nil {
+				allowed = append(allowed, name)
+				values[name] = newMap
+			}
+			continue
+		}
+
+		if g.allow(name, key) {
+			allowed = append(allowed, name)
+			values[name] = val
+		} else {
+			dropped = append(dropped, name)
+			numDropped++
+		}
+	}
+
+	if numDropped == 0 {
+		return em
+	}
+
+	g.dropped += int64(numDropped)
+	if now := time.Now(); now.Sub(g.lastLog) >= logInterval {
+		g.lastLog = now
+		g.l.Warningf("Max cardinality (%d) reached for metrics %v, dropping new series (labels: %s). Total dropped so far: %d", g.maxCardinality, dropped, key, g.dropped)
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	newEM := metrics.NewEventMetrics(em.Timestamp)
+	newEM.Kind = em.Kind
+	newEM.LatencyUnit = em.LatencyUnit
+	for _, name := range allowed {
+		newEM.AddMetric(name, values[name])
+	}
+	for _, k := range em.LabelsKeys() {
+		newEM.AddLabel(k, em.Label(k))
+	}
+	return newEM
+}
+
+// Dropped returns the number of metrics (or map metrics' keys) dropped so far
+// because of the max cardinality.
+func (g *Guard) Dropped() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.dropped
+}
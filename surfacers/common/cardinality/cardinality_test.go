@@ -0,0 +1,102 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+func testEM(target string, metricNames ...string) *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(time.Now()).
+		AddLabel("ptype", "http").
+		AddLabel("dst", target)
+	for _, name := range metricNames {
+		em.AddMetric(name, metrics.NewInt(1))
+	}
+	return em
+}
+
+func TestFilter(t *testing.T) {
+	g := New(3, nil)
+
+	// Feed an increasing number of unique label sets: only the first 3 get
+	// through.
+	var allowed []string
+	for i := 0; i < 10; i++ {
+		target := fmt.Sprintf("target%d", i)
+		if em := g.Filter(testEM(target, "total", "success")); em != nil {
+			allowed = append(allowed, em.Label("dst"))
+		}
+	}
+	if want := []string{"target0", "target1", "target2"}; !reflect.DeepEqual(allowed, want) {
+		t.Errorf("Allowed targets: %v, want: %v", allowed, want)
+	}
+	if g.Dropped() != 14 {
+		t.Errorf("Dropped metrics: %d, want: 14", g.Dropped())
+	}
+
+	// Already tracked series are not dropped.
+	for i := 0; i < 3; i++ {
+		em := testEM(fmt.Sprintf("target%d", i), "total", "success")
+		if got := g.Filter(em); got != em {
+			t.Errorf("Tracked series (target%d) modified or dropped: %v", i, got)
+		}
+	}
+
+	// New metric for a new series: tracked separately per metric, so only the
+	// capped metrics are removed.
+	em := g.Filter(testEM("target9", "total", "latency"))
+	if em == nil || !reflect.DeepEqual(em.MetricsKeys(), []string{"latency"}) {
+		t.Errorf("Got EventMetrics: %v, want only latency metric", em)
+	}
+}
+
+func TestFilterMap(t *testing.T) {
+	g := New(3, nil)
+
+	codes := metrics.NewMap("code", metrics.NewInt(0))
+	for _, code := range []string{"200", "301", "404", "500", "503"} {
+		codes.IncKey(code)
+	}
+
+	// Each map key is a separate series, only the first 3 get through.
+	em := g.Filter(testEM("target0", "total").AddMetric("resp_code", codes))
+	if em == nil {
+		t.Fatalf("Got nil EventMetrics, want total and resp_code metrics")
+	}
+	if got, want := em.Metric("resp_code").String(), "map:code,200:1,301:1,404:1"; got != want {
+		t.Errorf("resp_code=%s, want=%s", got, want)
+	}
+	if em.Metric("total") == nil {
+		t.Errorf("total metric dropped: %v", em)
+	}
+	if g.Dropped() != 2 {
+		t.Errorf("Dropped metrics: %d, want: 2", g.Dropped())
+	}
+
+	// A new series (target) for resp_code is dropped completely.
+	em = g.Filter(testEM("target1").AddMetric("resp_code", codes))
+	if em != nil {
+		t.Errorf("Got EventMetrics: %v, want nil", em)
+	}
+	if g.Dropped() != 7 {
+		t.Errorf("Dropped metrics: %d, want: 7", g.Dropped())
+	}
+}
@@ -18,6 +18,7 @@ package options
 import (
 	"fmt"
 	"regexp"
+	"sync/atomic"
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
@@ -73,6 +74,11 @@ type Options struct {
 	ignoreLabelFilters []*labelFilter
 	allowMetricName    *regexp.Regexp
 	ignoreMetricName   *regexp.Regexp
+	allowMetrics       []*regexp.Regexp
+	denyMetrics        []*regexp.Regexp
+
+	// Number of metrics filtered out by FilterMetrics. Accessed atomically.
+	filteredMetrics int64
 
 	AddFailureMetric bool
 }
@@ -124,6 +130,81 @@ func (opts *Options) AllowMetric(metricName string) bool {
 	return opts.allowMetricName.MatchString(metricName)
 }
 
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileRegexes(regexes []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, s := range regexes {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// allowMetricByName returns whether a metric should be exported based on the
+// allow_metrics and deny_metrics filters.
+func (opts *Options) allowMetricByName(metricName string) bool {
+	if matchAny(opts.denyMetrics, metricName) {
+		return false
+	}
+	return len(opts.allowMetrics) == 0 || matchAny(opts.allowMetrics, metricName)
+}
+
+// FilterMetrics applies the allow_metrics and deny_metrics filters to the
+// given EventMetrics. If no metric is filtered out, it returns the original
+// EventMetrics, otherwise it returns a new EventMetrics with only the allowed
+// metrics, or nil if none of the metrics are allowed. Filtered out metrics
+// are counted, see FilteredMetrics().
+func (opts *Options) FilterMetrics(em *metrics.EventMetrics) *metrics.EventMetrics {
+	if opts == nil || (len(opts.allowMetrics) == 0 && len(opts.denyMetrics) == 0) {
+		return em
+	}
+
+	var allowed []string
+	for _, name := range em.MetricsKeys() {
+		if opts.allowMetricByName(name) {
+			allowed = append(allowed, name)
+		}
+	}
+
+	filtered := len(em.MetricsKeys()) - len(allowed)
+	if filtered == 0 {
+		return em
+	}
+	atomic.AddInt64(&opts.filteredMetrics, int64(filtered))
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	newEM := metrics.NewEventMetrics(em.Timestamp)
+	newEM.Kind = em.Kind
+	newEM.LatencyUnit = em.LatencyUnit
+	for _, name := range allowed {
+		newEM.AddMetric(name, em.Metric(name))
+	}
+	for _, k := range em.LabelsKeys() {
+		newEM.AddLabel(k, em.Label(k))
+	}
+	return newEM
+}
+
+// FilteredMetrics returns the number of metrics filtered out by the
+// allow_metrics and deny_metrics filters so far.
+func (opts *Options) FilteredMetrics() int64 {
+	return atomic.LoadInt64(&opts.filteredMetrics)
+}
+
 // BuildOptionsFromConfig builds surfacer options using config.
 func BuildOptionsFromConfig(sdef *surfacerpb.SurfacerDef, l *logger.Logger) (*Options, error) {
 	opts := &Options{
@@ -157,6 +238,19 @@ func BuildOptionsFromConfig(sdef *surfacerpb.SurfacerDef, l *logger.Logger) (*Op
 		}
 	}
 
+	if opts.allowMetrics, err = compileRegexes(sdef.GetAllowMetrics()); err != nil {
+		return nil, fmt.Errorf("invalid allow_metrics regex: %v", err)
+	}
+	if opts.denyMetrics, err = compileRegexes(sdef.GetDenyMetrics()); err != nil {
+		return nil, fmt.Errorf("invalid deny_metrics regex: %v", err)
+	}
+
+	for _, p := range sdef.GetExportPercentiles() {
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid export_percentiles value: %v, should be in (0, 100]", p)
+		}
+	}
+
 	opts.AddFailureMetric = opts.Config.GetAddFailureMetric()
 	defaultFailureMetric := map[surfacerpb.Type]bool{
 		surfacerpb.Type_STACKDRIVER: true,
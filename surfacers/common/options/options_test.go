@@ -15,6 +15,7 @@
 package options
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -195,3 +196,110 @@ func TestAllowMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestExportPercentiles(t *testing.T) {
+	for _, test := range []struct {
+		percentiles []float64
+		wantErr     bool
+	}{
+		{percentiles: []float64{50, 99.9, 100}},
+		{percentiles: []float64{0}, wantErr: true},
+		{percentiles: []float64{50, 101}, wantErr: true},
+	} {
+		t.Run(fmt.Sprintf("%v", test.percentiles), func(t *testing.T) {
+			_, err := BuildOptionsFromConfig(&configpb.SurfacerDef{ExportPercentiles: test.percentiles}, nil)
+			if (err != nil) != test.wantErr {
+				t.Errorf("BuildOptionsFromConfig(): err=%v, wantErr=%v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterMetrics(t *testing.T) {
+	tests := []struct {
+		desc         string
+		allow, deny  []string
+		wantMetrics  []string
+		wantFiltered int64
+		wantErr      bool
+	}{
+		{
+			desc:        "no-filters",
+			wantMetrics: []string{"total", "success", "latency", "validation_failure"},
+		},
+		{
+			desc:         "allow-only",
+			allow:        []string{"^(total|success)$", "^lat"},
+			wantMetrics:  []string{"total", "success", "latency"},
+			wantFiltered: 1,
+		},
+		{
+			desc:         "deny-only",
+			deny:         []string{"validation", "latency"},
+			wantMetrics:  []string{"total", "success"},
+			wantFiltered: 2,
+		},
+		{
+			desc:         "deny-precedence",
+			allow:        []string{"^(total|success|latency)$"},
+			deny:         []string{"^success$"},
+			wantMetrics:  []string{"total", "latency"},
+			wantFiltered: 2,
+		},
+		{
+			desc:         "all-filtered",
+			deny:         []string{".*"},
+			wantFiltered: 4,
+		},
+		{
+			desc:    "bad-allow-regex",
+			allow:   []string{"(?badRe)"},
+			wantErr: true,
+		},
+		{
+			desc:    "bad-deny-regex",
+			deny:    []string{"(?badRe)"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			opts, err := BuildOptionsFromConfig(&configpb.SurfacerDef{
+				AllowMetrics: test.allow,
+				DenyMetrics:  test.deny,
+			}, nil)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("BuildOptionsFromConfig(): err=%v, wantErr=%v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			em := metrics.NewEventMetrics(time.Now()).
+				AddMetric("total", metrics.NewInt(10)).
+				AddMetric("success", metrics.NewInt(9)).
+				AddMetric("latency", metrics.NewFloat(100)).
+				AddMetric("validation_failure", metrics.NewInt(1)).
+				AddLabel("probe", "p1")
+
+			gotEM := opts.FilterMetrics(em)
+			if test.wantMetrics == nil {
+				if gotEM != nil {
+					t.Errorf("Expected nil EventMetrics, got: %s", gotEM.String())
+				}
+			} else {
+				if !reflect.DeepEqual(gotEM.MetricsKeys(), test.wantMetrics) {
+					t.Errorf("Got metrics: %v, wanted: %v", gotEM.MetricsKeys(), test.wantMetrics)
+				}
+				if gotEM.Label("probe") != "p1" {
+					t.Errorf("Label probe=%s, want=p1", gotEM.Label("probe"))
+				}
+			}
+
+			if opts.FilteredMetrics() != test.wantFiltered {
+				t.Errorf("FilteredMetrics()=%d, want=%d", opts.FilteredMetrics(), test.wantFiltered)
+			}
+		})
+	}
+}
@@ -18,6 +18,9 @@ package transform
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
@@ -67,3 +70,48 @@ func CumulativeToGauge(em *metrics.EventMetrics, lvCache map[string]*metrics.Eve
 
 	return gaugeEM, nil
 }
+
+// PercentileMetricName returns the name of the metric that carries the given
+// percentile for a distribution metric, e.g. latency_p99, latency_p99_9.
+func PercentileMetricName(name string, p float64) string {
+	return name + "_p" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+}
+
+// PercentileMetrics creates a "gauge" EventMetrics containing the given
+// percentiles for all the distribution metrics in the EventMetrics. Labels
+// are copied from the original EventMetrics. It returns nil if EventMetrics
+// has no distribution metrics, or if no percentiles could be estimated (e.g.
+// all distributions are empty).
+func PercentileMetrics(em *metrics.EventMetrics, percentiles []float64) *metrics.EventMetrics {
+	if len(percentiles) == 0 {
+		return nil
+	}
+
+	pem := metrics.NewEventMetrics(em.Timestamp)
+	pem.Kind = metrics.GAUGE
+	pem.LatencyUnit = em.LatencyUnit
+
+	found := false
+	for _, name := range em.MetricsKeys() {
+		d, ok := em.Metric(name).(*metrics.Distribution)
+		if !ok {
+			continue
+		}
+		for _, p := range percentiles {
+			v := d.Percentile(p)
+			if math.IsNaN(v) {
+				continue
+			}
+			pem.AddMetric(PercentileMetricName(name, p), metrics.NewFloat(v))
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	for _, k := range em.LabelsKeys() {
+		pem.AddLabel(k, em.Label(k))
+	}
+	return pem
+}
@@ -16,6 +16,7 @@ package transform
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -100,3 +101,68 @@ func TestFailureCountForDefaultMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestPercentileMetricName(t *testing.T) {
+	for _, test := range []struct {
+		p    float64
+		want string
+	}{
+		{50, "latency_p50"},
+		{99, "latency_p99"},
+		{99.9, "latency_p99_9"},
+		{100, "latency_p100"},
+	} {
+		if got := PercentileMetricName("latency", test.p); got != test.want {
+			t.Errorf("PercentileMetricName(latency, %v)=%s, want=%s", test.p, got, test.want)
+		}
+	}
+}
+
+func TestPercentileMetrics(t *testing.T) {
+	d := metrics.NewDistribution([]float64{0, 10, 20, 30})
+	for i := 0; i < 100; i++ {
+		d.AddFloat64(float64(i%30) + 0.5)
+	}
+
+	ts := time.Now()
+	em := metrics.NewEventMetrics(ts).
+		AddMetric("total", metrics.NewInt(100)).
+		AddMetric("latency", d).
+		AddMetric("empty_dist", metrics.NewDistribution([]float64{0, 10})).
+		AddLabel("probe", "p1").
+		AddLabel("dst", "t1")
+
+	pem := PercentileMetrics(em, []float64{50, 99.9})
+	if pem == nil {
+		t.Fatalf("PercentileMetrics() returned nil")
+	}
+
+	if pem.Kind != metrics.GAUGE || pem.Timestamp != ts {
+		t.Errorf("Got kind=%v, timestamp=%v, want kind=GAUGE, timestamp=%v", pem.Kind, pem.Timestamp, ts)
+	}
+
+	wantMetrics := []string{"latency_p50", "latency_p99_9"}
+	if !reflect.DeepEqual(pem.MetricsKeys(), wantMetrics) {
+		t.Errorf("Got metrics: %v, want: %v", pem.MetricsKeys(), wantMetrics)
+	}
+	for _, k := range []string{"probe", "dst"} {
+		if pem.Label(k) != em.Label(k) {
+			t.Errorf("Label %s=%s, want=%s", k, pem.Label(k), em.Label(k))
+		}
+	}
+
+	for _, name := range wantMetrics {
+		v := pem.Metric(name).(*metrics.Float).Float64()
+		if v < 0 || v > 30 {
+			t.Errorf("%s=%v, not within the distribution's range", name, v)
+		}
+	}
+
+	// No distributions, no percentiles.
+	if pem := PercentileMetrics(metrics.NewEventMetrics(ts).AddMetric("total", metrics.NewInt(1)), []float64{50}); pem != nil {
+		t.Errorf("Got non-nil EventMetrics for EventMetrics without distributions: %v", pem)
+	}
+	if pem := PercentileMetrics(em, nil); pem != nil {
+		t.Errorf("Got non-nil EventMetrics with no percentiles: %v", pem)
+	}
+}
@@ -57,6 +57,22 @@ type Surfacer struct {
 	compressionBuffer *compress.CompressionBuffer
 }
 
+// lineForEM serializes the EventMetrics into a line, according to the
+// configured format.
+func (s *Surfacer) lineForEM(em *metrics.EventMetrics) (string, error) {
+	if s.c.GetFormat() == configpb.SurfacerConf_JSONL {
+		return jsonLineForEM(s.c.GetPrefix(), s.id, em)
+	}
+
+	var emStr strings.Builder
+	emStr.WriteString(s.c.GetPrefix())
+	emStr.WriteByte(' ')
+	emStr.WriteString(strconv.FormatInt(s.id, 10))
+	emStr.WriteByte(' ')
+	emStr.WriteString(em.String())
+	return emStr.String(), nil
+}
+
 func (s *Surfacer) processInput(ctx context.Context) {
 	defer s.processInputWg.Done()
 
@@ -69,21 +85,20 @@ func (s *Surfacer) processInput(ctx context.Context) {
 			if !ok {
 				return
 			}
-			var emStr strings.Builder
-			emStr.WriteString(s.c.GetPrefix())
-			emStr.WriteByte(' ')
-			emStr.WriteString(strconv.FormatInt(s.id, 10))
-			emStr.WriteByte(' ')
-			emStr.WriteString(em.String())
+			line, err := s.lineForEM(em)
 			s.id++
+			if err != nil {
+				s.l.Errorf("Error serializing EventMetrics: %v", err)
+				continue
+			}
 
 			// If compression is not enabled, write line to file and continue.
 			if !s.c.GetCompressionEnabled() {
-				if _, err := s.outf.WriteString(emStr.String() + "\n"); err != nil {
+				if _, err := s.outf.WriteString(line + "\n"); err != nil {
 					s.l.Errorf("Unable to write data to %s. Err: %v", s.c.GetFilePath(), err)
 				}
 			} else {
-				s.compressionBuffer.WriteLineToBuffer(emStr.String())
+				s.compressionBuffer.WriteLineToBuffer(line)
 			}
 
 		case <-ctx.Done():
@@ -123,9 +138,9 @@ func (s *Surfacer) init(ctx context.Context, id int64) error {
 	return nil
 }
 
-// close closes the input channel, waits for input processing to finish,
+// Close closes the input channel, waits for input processing to finish,
 // and closes the compression buffer if open.
-func (s *Surfacer) close() {
+func (s *Surfacer) Close() {
 	close(s.inChan)
 	s.processInputWg.Wait()
 
@@ -133,7 +148,9 @@ func (s *Surfacer) close() {
 		s.compressionBuffer.Close()
 	}
 
-	s.outf.Close()
+	if s.outf != os.Stdout {
+		s.outf.Close()
+	}
 }
 
 // Write queues the incoming data into a channel. This channel is watched by a
@@ -22,9 +22,12 @@ lead to flakiness in the future.
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -88,7 +91,7 @@ func testWrite(t *testing.T, compressionEnabled bool) {
 		}
 
 		s.Write(context.Background(), tt.em)
-		s.close()
+		s.Close()
 
 		dat, err := ioutil.ReadFile(f.Name())
 		if err != nil {
@@ -109,3 +112,78 @@ func testWrite(t *testing.T, compressionEnabled bool) {
 		}
 	}
 }
+
+func TestWriteJSONL(t *testing.T) {
+	f, err := ioutil.TempFile("", "file_test")
+	if err != nil {
+		t.Fatalf("Unable to create a new file for testing: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	d := metrics.NewDistribution([]float64{1, 5, 10})
+	for _, sample := range []float64{0.5, 2, 3, 7, 20} {
+		d.AddSample(sample)
+	}
+	em := metrics.NewEventMetrics(time.Unix(1519084040, 0)).
+		AddMetric("total", metrics.NewInt(5)).
+		AddMetric("latency", d).
+		AddLabel("ptype", "http").
+		AddLabel("dst", "test-target")
+
+	s := &Surfacer{
+		c: &configpb.SurfacerConf{
+			FilePath: proto.String(f.Name()),
+			Format:   configpb.SurfacerConf_JSONL.Enum(),
+		},
+		opts: &options.Options{
+			MetricsBufferSize: 1000,
+		},
+	}
+	if err := s.init(context.Background(), 1); err != nil {
+		t.Fatalf("Unable to create a new file surfacer: %v", err)
+	}
+	s.Write(context.Background(), em)
+	s.Close()
+
+	dat, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("Unable to open test output file for reading: %v", err)
+	}
+
+	var got struct {
+		Prefix    string
+		ID        int64
+		Timestamp int64
+		Kind      string
+		Labels    map[string]string
+		Metrics   struct {
+			Total   int64
+			Latency jsonDist
+		}
+	}
+	if err := json.Unmarshal(dat, &got); err != nil {
+		t.Fatalf("Error parsing JSONL output (%s): %v", string(dat), err)
+	}
+
+	if got.Prefix != "cloudprober" || got.ID != 1 || got.Timestamp != 1519084040 || got.Kind != "CUMULATIVE" {
+		t.Errorf("Got prefix=%s, id=%d, timestamp=%d, kind=%s; want prefix=cloudprober, id=1, timestamp=1519084040, kind=CUMULATIVE", got.Prefix, got.ID, got.Timestamp, got.Kind)
+	}
+	if want := map[string]string{"ptype": "http", "dst": "test-target"}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("Got labels=%v, want=%v", got.Labels, want)
+	}
+	if got.Metrics.Total != 5 {
+		t.Errorf("Got total=%d, want=5", got.Metrics.Total)
+	}
+
+	// Reconstruct the distribution from its JSONL representation.
+	gotDist := got.Metrics.Latency
+	rd := &metrics.DistributionData{
+		LowerBounds:  append([]float64{math.Inf(-1)}, gotDist.LowerBounds...),
+		BucketCounts: gotDist.BucketCounts,
+		Count:        gotDist.Count,
+		Sum:          gotDist.Sum,
+	}
+	if !reflect.DeepEqual(rd, d.Data()) {
+		t.Errorf("Reconstructed distribution=%+v, want=%+v", rd, d.Data())
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+
+	"github.com/cloudprober/cloudprober/metrics"
+)
+
+// jsonDist is the JSONL representation of a distribution. LowerBounds don't
+// include the first bucket's lower bound (-Inf), as JSON can't represent it.
+type jsonDist struct {
+	LowerBounds  []float64 `json:"lower_bounds"`
+	BucketCounts []int64   `json:"bucket_counts"`
+	Count        int64     `json:"count"`
+	Sum          float64   `json:"sum"`
+}
+
+type jsonLine struct {
+	Prefix    string                 `json:"prefix"`
+	ID        int64                  `json:"id"`
+	Timestamp int64                  `json:"timestamp"`
+	Kind      string                 `json:"kind"`
+	Labels    map[string]string      `json:"labels"`
+	Metrics   map[string]interface{} `json:"metrics"`
+}
+
+func jsonValue(v metrics.Value) interface{} {
+	switch v := v.(type) {
+	case *metrics.Int:
+		return v.Int64()
+	case *metrics.Float:
+		return v.Float64()
+	case *metrics.Map:
+		m := make(map[string]interface{})
+		for _, k := range v.Keys() {
+			m[k] = jsonValue(v.GetKey(k))
+		}
+		return m
+	case *metrics.Distribution:
+		d := v.Data()
+		return &jsonDist{
+			LowerBounds:  d.LowerBounds[1:],
+			BucketCounts: d.BucketCounts,
+			Count:        d.Count,
+			Sum:          d.Sum,
+		}
+	default:
+		return v.String()
+	}
+}
+
+// jsonLineForEM returns the JSONL representation of the given EventMetrics.
+func jsonLineForEM(prefix string, id int64, em *metrics.EventMetrics) (string, error) {
+	jl := &jsonLine{
+		Prefix:    prefix,
+		ID:        id,
+		Timestamp: em.Timestamp.Unix(),
+		Kind:      "CUMULATIVE",
+		Labels:    make(map[string]string),
+		Metrics:   make(map[string]interface{}),
+	}
+	if em.Kind == metrics.GAUGE {
+		jl.Kind = "GAUGE"
+	}
+	for _, k := range em.LabelsKeys() {
+		jl.Labels[k] = em.Label(k)
+	}
+	for _, k := range em.MetricsKeys() {
+		jl.Metrics[k] = jsonValue(em.Metric(k))
+	}
+
+	b, err := json.Marshal(jl)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
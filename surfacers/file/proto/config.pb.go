@@ -20,6 +20,72 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SurfacerConf_Format int32
+
+const (
+	// Default text format:
+	//   <prefix> <id> <timestamp> labels=<k>=<v>,... <metric>=<value> ...
+	SurfacerConf_TEXT SurfacerConf_Format = 0
+	// One JSON object per line, with distributions rendered as explicit
+	// bucket arrays, e.g.:
+	//   {"prefix":"cloudprober","id":1,"timestamp":1519084040,
+	//    "kind":"CUMULATIVE","labels":{"ptype":"http"},
+	//    "metrics":{"total":10,"latency":{"lower_bounds":[0,10],
+	//    "bucket_counts":[0,8,2],"count":10,"sum":73.5}}}
+	// Note that lower_bounds don't include the first bucket's lower bound,
+	// -Inf, so there is one more bucket count than lower bounds.
+	SurfacerConf_JSONL SurfacerConf_Format = 1
+)
+
+// Enum value maps for SurfacerConf_Format.
+var (
+	SurfacerConf_Format_name = map[int32]string{
+		0: "TEXT",
+		1: "JSONL",
+	}
+	SurfacerConf_Format_value = map[string]int32{
+		"TEXT":  0,
+		"JSONL": 1,
+	}
+)
+
+func (x SurfacerConf_Format) Enum() *SurfacerConf_Format {
+	p := new(SurfacerConf_Format)
+	*p = x
+	return p
+}
+
+func (x SurfacerConf_Format) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SurfacerConf_Format) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (SurfacerConf_Format) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_enumTypes[0]
+}
+
+func (x SurfacerConf_Format) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *SurfacerConf_Format) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = SurfacerConf_Format(num)
+	return nil
+}
+
+// Deprecated: Use SurfacerConf_Format.Descriptor instead.
+func (SurfacerConf_Format) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
 type SurfacerConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -30,13 +96,15 @@ type SurfacerConf struct {
 	FilePath *string `protobuf:"bytes,1,opt,name=file_path,json=filePath" json:"file_path,omitempty"`
 	Prefix   *string `protobuf:"bytes,2,opt,name=prefix,def=cloudprober" json:"prefix,omitempty"`
 	// Compress data before writing to the file.
-	CompressionEnabled *bool `protobuf:"varint,3,opt,name=compression_enabled,json=compressionEnabled,def=0" json:"compression_enabled,omitempty"`
+	CompressionEnabled *bool                `protobuf:"varint,3,opt,name=compression_enabled,json=compressionEnabled,def=0" json:"compression_enabled,omitempty"`
+	Format             *SurfacerConf_Format `protobuf:"varint,4,opt,name=format,enum=cloudprober.surfacer.file.SurfacerConf_Format,def=0" json:"format,omitempty"`
 }
 
 // Default values for SurfacerConf fields.
 const (
 	Default_SurfacerConf_Prefix             = string("cloudprober")
 	Default_SurfacerConf_CompressionEnabled = bool(false)
+	Default_SurfacerConf_Format             = SurfacerConf_TEXT
 )
 
 func (x *SurfacerConf) Reset() {
@@ -92,6 +160,13 @@ func (x *SurfacerConf) GetCompressionEnabled() bool {
 	return Default_SurfacerConf_CompressionEnabled
 }
 
+func (x *SurfacerConf) GetFormat() SurfacerConf_Format {
+	if x != nil && x.Format != nil {
+		return *x.Format
+	}
+	return Default_SurfacerConf_Format
+}
+
 var File_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_rawDesc = []byte{
@@ -101,7 +176,7 @@ var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_ra
 	0x69, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
 	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x19, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
 	0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x66, 0x69, 0x6c,
-	0x65, 0x22, 0x88, 0x01, 0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f,
+	0x65, 0x22, 0xf5, 0x01, 0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f,
 	0x6e, 0x66, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x74, 0x68, 0x12,
 	0x23, 0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x3a,
@@ -109,11 +184,18 @@ var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_ra
 	0x65, 0x66, 0x69, 0x78, 0x12, 0x36, 0x0a, 0x13, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73,
 	0x69, 0x6f, 0x6e, 0x5f, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
 	0x08, 0x3a, 0x05, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x52, 0x12, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65,
-	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x42, 0x39, 0x5a, 0x37,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x66, 0x69, 0x6c,
-	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x4c, 0x0a, 0x06,
+	0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2e, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x72, 0x2e, 0x66, 0x69, 0x6c, 0x65, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x3a, 0x04, 0x54, 0x45,
+	0x58, 0x54, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x22, 0x1d, 0x0a, 0x06, 0x46, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x12, 0x08, 0x0a, 0x04, 0x54, 0x45, 0x58, 0x54, 0x10, 0x00, 0x12, 0x09,
+	0x0a, 0x05, 0x4a, 0x53, 0x4f, 0x4e, 0x4c, 0x10, 0x01, 0x42, 0x39, 0x5a, 0x37, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f,
+	0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x66, 0x69, 0x6c, 0x65, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f,
 }
 
 var (
@@ -128,16 +210,19 @@ func file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_r
 	return file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_rawDescData
 }
 
+var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
 var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_goTypes = []interface{}{
-	(*SurfacerConf)(nil), // 0: cloudprober.surfacer.file.SurfacerConf
+	(SurfacerConf_Format)(0), // 0: cloudprober.surfacer.file.SurfacerConf.Format
+	(*SurfacerConf)(nil),     // 1: cloudprober.surfacer.file.SurfacerConf
 }
 var file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: cloudprober.surfacer.file.SurfacerConf.format:type_name -> cloudprober.surfacer.file.SurfacerConf.Format
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_init() }
@@ -164,13 +249,14 @@ func file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_i
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_rawDesc,
-			NumEnums:      0,
+			NumEnums:      1,
 			NumMessages:   1,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_goTypes,
 		DependencyIndexes: file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_enumTypes,
 		MessageInfos:      file_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto_msgTypes,
 	}.Build()
 	File_github_com_cloudprober_cloudprober_surfacers_file_proto_config_proto = out.File
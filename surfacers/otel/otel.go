@@ -0,0 +1,476 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package otel implements a surfacer to export metrics to OpenTelemetry
+collectors, using the OTLP/HTTP protocol (JSON encoding) or the OTLP/gRPC
+protocol.
+*/
+package otel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	configpb "github.com/cloudprober/cloudprober/surfacers/otel/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Delay before the first retry. It's doubled for every subsequent retry.
+var retryDelay = time.Second
+
+// retryableStatus are the HTTP status codes for which export is retried, as
+// per the OTLP/HTTP specification.
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// retryableCodes are the gRPC status codes for which export is retried.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.ResourceExhausted: true,
+	codes.DeadlineExceeded:  true,
+	codes.Aborted:           true,
+}
+
+// OTLP/gRPC export method.
+const exportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// rawCodec is a gRPC codec for already encoded protobuf messages. It works
+// with *[]byte messages.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte{}, data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// OtelSurfacer implements an OpenTelemetry (OTLP) surfacer.
+type OtelSurfacer struct {
+	c         *configpb.SurfacerConf
+	writeChan chan *metrics.EventMetrics
+	writeDone chan struct{} // Closed when the write loop exits.
+	endpoint  string
+	client    *http.Client     // For the OTLP/HTTP protocol.
+	conn      *grpc.ClientConn // For the OTLP/gRPC protocol.
+	latencyRe *regexp.Regexp
+	l         *logger.Logger
+
+	resourceAttrs []keyValue
+	startTime     string
+
+	// Metrics waiting to be exported, and number of data points in them.
+	pending    []*metric
+	pendingDPs int
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func attributes(em *metrics.EventMetrics, extra ...string) []keyValue {
+	var attrs []keyValue
+	for _, k := range em.LabelsKeys() {
+		attrs = append(attrs, keyValue{Key: k, Value: anyValue{StringValue: em.Label(k)}})
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		attrs = append(attrs, keyValue{Key: extra[i], Value: anyValue{StringValue: extra[i+1]}})
+	}
+	return attrs
+}
+
+func (s *OtelSurfacer) numberDataPoint(em *metrics.EventMetrics, v metrics.NumValue, attrs []keyValue) *numberDataPoint {
+	dp := &numberDataPoint{
+		Attributes:   attrs,
+		TimeUnixNano: unixNano(em.Timestamp),
+	}
+	if em.Kind == metrics.CUMULATIVE {
+		dp.StartTimeUnixNano = s.startTime
+	}
+	if _, ok := v.(*metrics.Float); ok {
+		f := v.Float64()
+		dp.AsDouble = &f
+	} else {
+		i := strconv.FormatInt(v.Int64(), 10)
+		dp.AsInt = &i
+	}
+	return dp
+}
+
+// numberMetric creates an OTLP Sum (for cumulative metrics) or Gauge metric.
+func (s *OtelSurfacer) numberMetric(name, unit string, em *metrics.EventMetrics, dps []*numberDataPoint) *metric {
+	m := &metric{Name: name, Unit: unit}
+	if em.Kind == metrics.CUMULATIVE {
+		m.Sum = &sum{
+			DataPoints:             dps,
+			AggregationTemporality: temporalityCumulative,
+			IsMonotonic:            true,
+		}
+	} else {
+		m.Gauge = &gauge{DataPoints: dps}
+	}
+	return m
+}
+
+func (s *OtelSurfacer) histogramMetric(name, unit string, em *metrics.EventMetrics, d *metrics.DistributionData) *metric {
+	dp := &histogramDataPoint{
+		Attributes:   attributes(em),
+		TimeUnixNano: unixNano(em.Timestamp),
+		Count:        strconv.FormatInt(d.Count, 10),
+		Sum:          d.Sum,
+		// Cloudprober distributions' first lower bound is always -Inf. Rest
+		// of the lower bounds work as OTLP's explicit bounds.
+		ExplicitBounds: append([]float64{}, d.LowerBounds[1:]...),
+	}
+	for _, c := range d.BucketCounts {
+		dp.BucketCounts = append(dp.BucketCounts, strconv.FormatInt(c, 10))
+	}
+
+	temporality := temporalityDelta
+	if em.Kind == metrics.CUMULATIVE {
+		temporality = temporalityCumulative
+		dp.StartTimeUnixNano = s.startTime
+	}
+
+	return &metric{
+		Name: name,
+		Unit: unit,
+		Histogram: &histogram{
+			DataPoints:             []*histogramDataPoint{dp},
+			AggregationTemporality: temporality,
+		},
+	}
+}
+
+// otelMetrics converts an EventMetrics into OTLP metrics.
+func (s *OtelSurfacer) otelMetrics(em *metrics.EventMetrics) []*metric {
+	var result []*metric
+
+	for _, k := range em.MetricsKeys() {
+		name := s.c.GetMetricsPrefix() + k
+
+		unit := "1"
+		if s.latencyRe.MatchString(k) {
+			unit = map[time.Duration]string{
+				time.Second:      "s",
+				time.Millisecond: "ms",
+				time.Microsecond: "us",
+				time.Nanosecond:  "ns",
+			}[em.LatencyUnit]
+		}
+
+		switch v := em.Metric(k).(type) {
+		case metrics.NumValue:
+			result = append(result, s.numberMetric(name, unit, em, []*numberDataPoint{s.numberDataPoint(em, v, attributes(em))}))
+		case *metrics.Map:
+			var dps []*numberDataPoint
+			for _, mapKey := range v.Keys() {
+				dps = append(dps, s.numberDataPoint(em, v.GetKey(mapKey), attributes(em, v.MapName, mapKey)))
+			}
+			result = append(result, s.numberMetric(name, unit, em, dps))
+		case *metrics.Distribution:
+			result = append(result, s.histogramMetric(name, unit, em, v.Data()))
+		default:
+			s.l.Debugf("Unsupported metric type for the metric %s: %T, skipping it", k, v)
+		}
+	}
+
+	return result
+}
+
+func dataPoints(m *metric) int {
+	switch {
+	case m.Sum != nil:
+		return len(m.Sum.DataPoints)
+	case m.Gauge != nil:
+		return len(m.Gauge.DataPoints)
+	case m.Histogram != nil:
+		return len(m.Histogram.DataPoints)
+	}
+	return 0
+}
+
+func (s *OtelSurfacer) recordEventMetrics(ctx context.Context, em *metrics.EventMetrics) {
+	for _, m := range s.otelMetrics(em) {
+		s.pending = append(s.pending, m)
+		s.pendingDPs += dataPoints(m)
+	}
+
+	if s.pendingDPs >= int(s.c.GetBatchSize()) {
+		s.flush(ctx)
+	}
+}
+
+// flush exports the pending metrics.
+func (s *OtelSurfacer) flush(ctx context.Context) {
+	if len(s.pending) == 0 {
+		return
+	}
+
+	req := &exportRequest{
+		ResourceMetrics: []*resourceMetrics{
+			{
+				Resource: resource{Attributes: s.resourceAttrs},
+				ScopeMetrics: []*scopeMetrics{
+					{
+						Scope:   scope{Name: "cloudprober"},
+						Metrics: s.pending,
+					},
+				},
+			},
+		},
+	}
+
+	if err := s.export(ctx, req); err != nil {
+		s.l.Errorf("Failed to export %d data points: %v", s.pendingDPs, err)
+	}
+
+	s.pending, s.pendingDPs = nil, 0
+}
+
+// export sends the export request to the OTLP endpoint, retrying the
+// retryable failures.
+func (s *OtelSurfacer) export(ctx context.Context, req *exportRequest) error {
+	var body []byte
+	if s.conn != nil {
+		body = req.marshalProto()
+	} else {
+		var err error
+		if body, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.send(ctx, body)
+		if err == nil || !retryable || attempt >= int(s.c.GetMaxRetries()) {
+			return err
+		}
+
+		s.l.Warningf("Export attempt %d failed: %v, retrying in %v", attempt+1, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// send sends the request body once. It returns an error, and whether the
+// error is retryable.
+func (s *OtelSurfacer) send(ctx context.Context, body []byte) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, time.Duration(s.c.GetTimeoutSec())*time.Second)
+	defer cancelFunc()
+
+	if s.conn != nil {
+		return s.sendGRPC(ctx, body)
+	}
+	return s.sendHTTP(ctx, body)
+}
+
+// sendGRPC sends the request body using the OTLP/gRPC protocol.
+func (s *OtelSurfacer) sendGRPC(ctx context.Context, body []byte) (bool, error) {
+	for _, h := range s.c.GetHttpHeader() {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(h.GetName()), h.GetValue())
+	}
+
+	var resp []byte
+	if err := s.conn.Invoke(ctx, exportMethod, &body, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return retryableCodes[status.Code(err)], err
+	}
+	return false, nil
+}
+
+// sendHTTP sends the request body using the OTLP/HTTP protocol.
+func (s *OtelSurfacer) sendHTTP(ctx context.Context, body []byte) (bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for _, h := range s.c.GetHttpHeader() {
+		httpReq.Header.Set(h.GetName(), h.GetValue())
+	}
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return retryableStatus[resp.StatusCode], fmt.Errorf("got HTTP status: %s", resp.Status)
+	}
+	return false, nil
+}
+
+func (s *OtelSurfacer) processIncomingMetrics(ctx context.Context) {
+	defer close(s.writeDone)
+
+	ticker := time.NewTicker(time.Duration(s.c.GetExportIntervalSec()) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.l.Infof("Context canceled, stopping the surfacer write loop")
+			return
+		case em, ok := <-s.writeChan:
+			if !ok {
+				return
+			}
+			s.recordEventMetrics(ctx, em)
+		case <-ticker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+func resourceAttributes(c *configpb.SurfacerConf) []keyValue {
+	attrs := map[string]string{
+		"service.name": "cloudprober",
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs["service.instance.id"] = hostname
+	}
+	for _, attr := range c.GetResourceAttribute() {
+		attrs[attr.GetKey()] = attr.GetValue()
+	}
+
+	var result []keyValue
+	for _, k := range []string{"service.name", "service.instance.id"} {
+		if v, ok := attrs[k]; ok {
+			result = append(result, keyValue{Key: k, Value: anyValue{StringValue: v}})
+			delete(attrs, k)
+		}
+	}
+	for _, attr := range c.GetResourceAttribute() {
+		if v, ok := attrs[attr.GetKey()]; ok {
+			result = append(result, keyValue{Key: attr.GetKey(), Value: anyValue{StringValue: v}})
+			delete(attrs, attr.GetKey())
+		}
+	}
+	return result
+}
+
+// New creates a new instance of an OTLP surfacer, based on the config passed
+// in. It then hands off to a goroutine to export metrics.
+func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Options, l *logger.Logger) (*OtelSurfacer, error) {
+	if config.GetBatchSize() <= 0 || config.GetExportIntervalSec() <= 0 {
+		return nil, fmt.Errorf("otel surfacer: batch_size (%d) and export_interval_sec (%d) should be positive", config.GetBatchSize(), config.GetExportIntervalSec())
+	}
+
+	latencyRe, err := regexp.Compile(config.GetLatencyMetricRegex())
+	if err != nil {
+		return nil, fmt.Errorf("otel surfacer: invalid latency_metric_regex: %v", err)
+	}
+
+	var tlsConfig *tls.Config
+	if config.GetTlsConfig() != nil {
+		tlsConfig = &tls.Config{}
+		if err := tlsconfig.UpdateTLSConfig(tlsConfig, config.GetTlsConfig(), false); err != nil {
+			return nil, fmt.Errorf("otel surfacer: error configuring TLS: %v", err)
+		}
+	}
+
+	s := &OtelSurfacer{
+		c:             config,
+		writeChan:     make(chan *metrics.EventMetrics, opts.MetricsBufferSize),
+		writeDone:     make(chan struct{}),
+		endpoint:      config.GetEndpoint(),
+		latencyRe:     latencyRe,
+		l:             l,
+		resourceAttrs: resourceAttributes(config),
+		startTime:     unixNano(time.Now()),
+	}
+
+	if config.GetProtocol() == configpb.SurfacerConf_GRPC {
+		if s.endpoint == "" {
+			s.endpoint = "localhost:4317"
+		}
+		creds := grpc.WithInsecure()
+		if tlsConfig != nil {
+			creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+		}
+		// Dial doesn't block, connection is established in the background.
+		if s.conn, err = grpc.Dial(s.endpoint, creds); err != nil {
+			return nil, fmt.Errorf("otel surfacer: error creating gRPC connection to %s: %v", s.endpoint, err)
+		}
+	} else {
+		if s.endpoint == "" {
+			s.endpoint = "http://localhost:4318/v1/metrics"
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		s.client = &http.Client{Transport: transport}
+	}
+
+	go s.processIncomingMetrics(ctx)
+
+	s.l.Infof("Initialized OTLP surfacer, protocol: %s, endpoint: %s", config.GetProtocol(), s.endpoint)
+	return s, nil
+}
+
+// Write queues the incoming data into a channel. This channel is watched by a
+// goroutine that actually exports the metrics.
+func (s *OtelSurfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
+	select {
+	case s.writeChan <- em:
+	default:
+		s.l.Error("Surfacer's write channel is full, dropping new data.")
+	}
+}
+
+// Close stops the write loop and exports the pending metrics.
+func (s *OtelSurfacer) Close() {
+	close(s.writeChan)
+	<-s.writeDone
+	s.flush(context.Background())
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	configpb "github.com/cloudprober/cloudprober/surfacers/otel/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// testReceiver is a mock OTLP/HTTP receiver.
+type testReceiver struct {
+	mu          sync.Mutex
+	requests    []*exportRequest
+	headers     []http.Header
+	failures    int // Number of requests to fail with 503.
+	numRequests int
+}
+
+func (tr *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.numRequests++
+	if tr.failures > 0 {
+		tr.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	req := &exportRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	tr.requests = append(tr.requests, req)
+	tr.headers = append(tr.headers, r.Header)
+}
+
+func testSurfacer(t *testing.T, tr *testReceiver) *OtelSurfacer {
+	t.Helper()
+
+	ts := httptest.NewServer(tr)
+	t.Cleanup(ts.Close)
+
+	s, err := New(context.Background(), &configpb.SurfacerConf{
+		Endpoint: proto.String(ts.URL + "/v1/metrics"),
+		HttpHeader: []*configpb.SurfacerConf_Header{
+			{Name: proto.String("Authorization"), Value: proto.String("Bearer token")},
+		},
+		ResourceAttribute: []*configpb.SurfacerConf_Attribute{
+			{Key: proto.String("service.instance.id"), Value: proto.String("instance-1")},
+			{Key: proto.String("env"), Value: proto.String("test")},
+		},
+		MetricsPrefix: proto.String("cp_"),
+	}, &options.Options{MetricsBufferSize: 10}, nil)
+	if err != nil {
+		t.Fatalf("Error creating surfacer: %v", err)
+	}
+	return s
+}
+
+func TestOtelMetricTypeMapping(t *testing.T) {
+	tr := &testReceiver{}
+	s := testSurfacer(t, tr)
+
+	d := metrics.NewDistribution([]float64{1, 10})
+	d.AddSample(5)
+	d.AddSample(20)
+
+	respCodes := metrics.NewMap("code", metrics.NewInt(0))
+	respCodes.IncKey("200")
+
+	ts := time.Now()
+	em := metrics.NewEventMetrics(ts).
+		AddMetric("total", metrics.NewInt(10)).
+		AddMetric("latency", d).
+		AddMetric("resp-code", respCodes).
+		AddMetric("version", metrics.NewString("v1")).
+		AddLabel("probe", "p1")
+	em.LatencyUnit = time.Millisecond
+
+	gaugeEM := metrics.NewEventMetrics(ts).
+		AddMetric("temp", metrics.NewFloat(1.5)).
+		AddLabel("probe", "p1")
+	gaugeEM.Kind = metrics.GAUGE
+
+	s.Write(context.Background(), em)
+	s.Write(context.Background(), gaugeEM)
+	s.Close()
+
+	if len(tr.requests) != 1 {
+		t.Fatalf("Got %d export requests, want 1", len(tr.requests))
+	}
+	if got := tr.headers[0].Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization header=%q, want=%q", got, "Bearer token")
+	}
+
+	rm := tr.requests[0].ResourceMetrics[0]
+	wantAttrs := []keyValue{
+		{Key: "service.name", Value: anyValue{StringValue: "cloudprober"}},
+		{Key: "service.instance.id", Value: anyValue{StringValue: "instance-1"}},
+		{Key: "env", Value: anyValue{StringValue: "test"}},
+	}
+	if !reflect.DeepEqual(rm.Resource.Attributes, wantAttrs) {
+		t.Errorf("Resource attributes: %v, want: %v", rm.Resource.Attributes, wantAttrs)
+	}
+
+	gotMetrics := make(map[string]*metric)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		gotMetrics[m.Name] = m
+	}
+	if len(gotMetrics) != 4 {
+		t.Errorf("Got metrics: %v, want: cp_total, cp_latency, cp_resp-code and cp_temp", gotMetrics)
+	}
+
+	probeAttr := keyValue{Key: "probe", Value: anyValue{StringValue: "p1"}}
+
+	// Cumulative Int -> monotonic cumulative Sum.
+	total := gotMetrics["cp_total"]
+	if total == nil || total.Sum == nil || !total.Sum.IsMonotonic || total.Sum.AggregationTemporality != temporalityCumulative {
+		t.Fatalf("cp_total is not a monotonic cumulative sum: %+v", total)
+	}
+	dp := total.Sum.DataPoints[0]
+	if dp.AsInt == nil || *dp.AsInt != "10" || dp.StartTimeUnixNano == "" || !reflect.DeepEqual(dp.Attributes, []keyValue{probeAttr}) {
+		t.Errorf("cp_total data point: %+v", dp)
+	}
+
+	// Map -> Sum with map key as an attribute.
+	respCode := gotMetrics["cp_resp-code"]
+	if respCode == nil || respCode.Sum == nil {
+		t.Fatalf("cp_resp-code is not a sum: %+v", respCode)
+	}
+	wantAttrs = []keyValue{probeAttr, {Key: "code", Value: anyValue{StringValue: "200"}}}
+	if dp := respCode.Sum.DataPoints[0]; *dp.AsInt != "1" || !reflect.DeepEqual(dp.Attributes, wantAttrs) {
+		t.Errorf("cp_resp-code data point: %+v", dp)
+	}
+
+	// Distribution -> Histogram.
+	latency := gotMetrics["cp_latency"]
+	if latency == nil || latency.Histogram == nil || latency.Unit != "ms" {
+		t.Fatalf("cp_latency is not a histogram with unit ms: %+v", latency)
+	}
+	hdp := latency.Histogram.DataPoints[0]
+	if hdp.Count != "2" || hdp.Sum != 25 || !reflect.DeepEqual(hdp.ExplicitBounds, []float64{1, 10}) || !reflect.DeepEqual(hdp.BucketCounts, []string{"0", "1", "1"}) {
+		t.Errorf("cp_latency data point: %+v", hdp)
+	}
+
+	// Gauge Float -> Gauge.
+	temp := gotMetrics["cp_temp"]
+	if temp == nil || temp.Gauge == nil {
+		t.Fatalf("cp_temp is not a gauge: %+v", temp)
+	}
+	if dp := temp.Gauge.DataPoints[0]; dp.AsDouble == nil || *dp.AsDouble != 1.5 || dp.StartTimeUnixNano != "" {
+		t.Errorf("cp_temp data point: %+v", dp)
+	}
+}
+
+func TestOtelLatencyUnit(t *testing.T) {
+	s, err := New(context.Background(), &configpb.SurfacerConf{
+		LatencyMetricRegex: proto.String("^latency_dist$"),
+	}, &options.Options{MetricsBufferSize: 10}, nil)
+	if err != nil {
+		t.Fatalf("Error creating surfacer: %v", err)
+	}
+	defer s.Close()
+
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("latency", metrics.NewFloat(10)).
+		AddMetric("latency_dist", metrics.NewFloat(10))
+	em.LatencyUnit = time.Microsecond
+
+	units := make(map[string]string)
+	for _, m := range s.otelMetrics(em) {
+		units[m.Name] = m.Unit
+	}
+	if want := map[string]string{"latency": "1", "latency_dist": "us"}; !reflect.DeepEqual(units, want) {
+		t.Errorf("Got units: %v, want: %v", units, want)
+	}
+}
+
+func TestOtelRetry(t *testing.T) {
+	oldRetryDelay := retryDelay
+	retryDelay = 10 * time.Millisecond
+	defer func() { retryDelay = oldRetryDelay }()
+
+	for _, test := range []struct {
+		failures     int
+		wantRequests int
+		wantExported bool
+	}{
+		{failures: 2, wantRequests: 3, wantExported: true},
+		{failures: 5, wantRequests: 4}, // 1 + 3 retries
+	} {
+		tr := &testReceiver{failures: test.failures}
+		s := testSurfacer(t, tr)
+
+		s.Write(context.Background(), metrics.NewEventMetrics(time.Now()).AddMetric("total", metrics.NewInt(1)))
+		s.Close()
+
+		if tr.numRequests != test.wantRequests {
+			t.Errorf("failures=%d: got %d requests, want %d", test.failures, tr.numRequests, test.wantRequests)
+		}
+		if (len(tr.requests) == 1) != test.wantExported {
+			t.Errorf("failures=%d: exported=%v, want=%v", test.failures, len(tr.requests) == 1, test.wantExported)
+		}
+	}
+}
+
+// testGRPCCodec is the codec for the mock OTLP/gRPC receiver.
+type testGRPCCodec struct {
+	rawCodec
+}
+
+func (testGRPCCodec) String() string {
+	return "proto"
+}
+
+// testGRPCReceiver is a mock OTLP/gRPC receiver. It records the raw export
+// requests.
+type testGRPCReceiver struct {
+	mu       sync.Mutex
+	methods  []string
+	requests [][]byte
+	md       []metadata.MD
+}
+
+func (tr *testGRPCReceiver) handler(srv interface{}, stream grpc.ServerStream) error {
+	var req []byte
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	method, _ := grpc.MethodFromServerStream(stream)
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	tr.mu.Lock()
+	tr.methods = append(tr.methods, method)
+	tr.requests = append(tr.requests, req)
+	tr.md = append(tr.md, md)
+	tr.mu.Unlock()
+
+	resp := []byte{}
+	return stream.SendMsg(&resp)
+}
+
+// protoMessages returns the values of the given length-delimited field.
+func protoMessages(t *testing.T, b []byte, field protowire.Number) [][]byte {
+	t.Helper()
+
+	var result [][]byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Error parsing protobuf message: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if num == field && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			result = append(result, v)
+			b = b[n:]
+			continue
+		}
+		b = b[protowire.ConsumeFieldValue(num, typ, b):]
+	}
+	return result
+}
+
+// protoFixed64 returns the value of the given fixed64 field.
+func protoFixed64(t *testing.T, b []byte, field protowire.Number) uint64 {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Error parsing protobuf message: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if num == field && typ == protowire.Fixed64Type {
+			v, _ := protowire.ConsumeFixed64(b)
+			return v
+		}
+		b = b[protowire.ConsumeFieldValue(num, typ, b):]
+	}
+	return 0
+}
+
+func TestOtelGRPC(t *testing.T) {
+	tr := &testGRPCReceiver{}
+	srv := grpc.NewServer(grpc.CustomCodec(testGRPCCodec{}), grpc.UnknownServiceHandler(tr.handler))
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	s, err := New(context.Background(), &configpb.SurfacerConf{
+		Protocol: configpb.SurfacerConf_GRPC.Enum(),
+		Endpoint: proto.String(ln.Addr().String()),
+		HttpHeader: []*configpb.SurfacerConf_Header{
+			{Name: proto.String("Authorization"), Value: proto.String("Bearer token")},
+		},
+		MetricsPrefix: proto.String("cp_"),
+	}, &options.Options{MetricsBufferSize: 10}, nil)
+	if err != nil {
+		t.Fatalf("Error creating surfacer: %v", err)
+	}
+
+	d := metrics.NewDistribution([]float64{1, 10})
+	d.AddSample(5)
+
+	em := metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(10)).
+		AddMetric("latency", d).
+		AddLabel("probe", "p1")
+	em.LatencyUnit = time.Millisecond
+
+	gaugeEM := metrics.NewEventMetrics(time.Now()).
+		AddMetric("temp", metrics.NewFloat(1.5)).
+		AddLabel("probe", "p1")
+	gaugeEM.Kind = metrics.GAUGE
+
+	s.Write(context.Background(), em)
+	s.Write(context.Background(), gaugeEM)
+	s.Close()
+
+	if len(tr.requests) != 1 {
+		t.Fatalf("Got %d export requests, want 1", len(tr.requests))
+	}
+	if tr.methods[0] != exportMethod {
+		t.Errorf("Got method: %s, want: %s", tr.methods[0], exportMethod)
+	}
+	if got := tr.md[0].Get("authorization"); !reflect.DeepEqual(got, []string{"Bearer token"}) {
+		t.Errorf("authorization metadata=%v, want=[Bearer token]", got)
+	}
+
+	// ExportMetricsServiceRequest -> ResourceMetrics -> ScopeMetrics -> Metric
+	rm := protoMessages(t, tr.requests[0], 1)
+	if len(rm) != 1 {
+		t.Fatalf("Got %d resource metrics, want 1", len(rm))
+	}
+	sm := protoMessages(t, rm[0], 2)
+	if len(sm) != 1 {
+		t.Fatalf("Got %d scope metrics, want 1", len(sm))
+	}
+
+	gotMetrics := make(map[string][]byte)
+	for _, m := range protoMessages(t, sm[0], 2) {
+		name := string(protoMessages(t, m, 1)[0])
+		gotMetrics[name] = m
+	}
+	if len(gotMetrics) != 3 {
+		t.Fatalf("Got metrics: %v, want: cp_total, cp_latency and cp_temp", gotMetrics)
+	}
+
+	// Sum's data point value: as_int.
+	sum := protoMessages(t, gotMetrics["cp_total"], 7)
+	if len(sum) != 1 {
+		t.Fatalf("cp_total is not a sum: %v", gotMetrics["cp_total"])
+	}
+	if got := protoFixed64(t, protoMessages(t, sum[0], 1)[0], 6); got != 10 {
+		t.Errorf("cp_total value: %d, want: 10", got)
+	}
+
+	// Histogram's unit and data point count.
+	latency := gotMetrics["cp_latency"]
+	if unit := protoMessages(t, latency, 3); len(unit) != 1 || string(unit[0]) != "ms" {
+		t.Errorf("cp_latency unit: %q, want: ms", unit)
+	}
+	hist := protoMessages(t, latency, 9)
+	if len(hist) != 1 {
+		t.Fatalf("cp_latency is not a histogram: %v", latency)
+	}
+	if got := protoFixed64(t, protoMessages(t, hist[0], 1)[0], 4); got != 1 {
+		t.Errorf("cp_latency count: %d, want: 1", got)
+	}
+
+	// Gauge's data point value: as_double.
+	gauge := protoMessages(t, gotMetrics["cp_temp"], 5)
+	if len(gauge) != 1 {
+		t.Fatalf("cp_temp is not a gauge: %v", gotMetrics["cp_temp"])
+	}
+	if got := math.Float64frombits(protoFixed64(t, protoMessages(t, gauge[0], 1)[0], 4)); got != 1.5 {
+		t.Errorf("cp_temp value: %f, want: 1.5", got)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+// This file defines a subset of the OTLP metrics data model, as required by
+// this surfacer, for the JSON encoding of the OTLP/HTTP protocol. See
+// https://github.com/open-telemetry/opentelemetry-proto for the protobuf
+// definitions. Note that in the JSON encoding, 64-bit integers are encoded as
+// strings, and enums as integers.
+
+// Aggregation temporality values.
+const (
+	temporalityDelta      = 1
+	temporalityCumulative = 2
+)
+
+type exportRequest struct {
+	ResourceMetrics []*resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource        `json:"resource"`
+	ScopeMetrics []*scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeMetrics struct {
+	Scope   scope     `json:"scope"`
+	Metrics []*metric `json:"metrics"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type metric struct {
+	Name      string     `json:"name"`
+	Unit      string     `json:"unit,omitempty"`
+	Sum       *sum       `json:"sum,omitempty"`
+	Gauge     *gauge     `json:"gauge,omitempty"`
+	Histogram *histogram `json:"histogram,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []*numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                `json:"aggregationTemporality"`
+	IsMonotonic            bool               `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []*numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	DataPoints             []*histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
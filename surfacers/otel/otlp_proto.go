@@ -0,0 +1,178 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+// This file implements the protobuf encoding of the OTLP metrics data model
+// defined in otlp.go, for the OTLP/gRPC protocol. Field numbers are from the
+// opentelemetry-proto definitions:
+//   opentelemetry/proto/collector/metrics/v1/metrics_service.proto
+//   opentelemetry/proto/metrics/v1/metrics.proto
+//   opentelemetry/proto/common/v1/common.proto
+
+import (
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+// appendTimestamp appends a timestamp, encoded as a string of nanoseconds
+// since epoch in the JSON data model.
+func appendTimestamp(b []byte, num protowire.Number, ts string) []byte {
+	if ts == "" {
+		return b
+	}
+	v, _ := strconv.ParseUint(ts, 10, 64)
+	return appendFixed64(b, num, v)
+}
+
+func marshalAttributes(b []byte, num protowire.Number, attrs []keyValue) []byte {
+	for _, attr := range attrs {
+		var value []byte
+		value = appendString(value, 1, attr.Value.StringValue) // string_value
+
+		var kv []byte
+		kv = appendString(kv, 1, attr.Key) // key
+		kv = appendMessage(kv, 2, value)   // value
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+func marshalNumberDataPoint(dp *numberDataPoint) []byte {
+	var b []byte
+	b = appendTimestamp(b, 2, dp.StartTimeUnixNano) // start_time_unix_nano
+	b = appendTimestamp(b, 3, dp.TimeUnixNano)      // time_unix_nano
+	if dp.AsDouble != nil {
+		b = appendFixed64(b, 4, math.Float64bits(*dp.AsDouble)) // as_double
+	}
+	if dp.AsInt != nil {
+		i, _ := strconv.ParseInt(*dp.AsInt, 10, 64)
+		b = appendFixed64(b, 6, uint64(i)) // as_int (sfixed64)
+	}
+	return marshalAttributes(b, 7, dp.Attributes) // attributes
+}
+
+func marshalHistogramDataPoint(dp *histogramDataPoint) []byte {
+	var b []byte
+	b = appendTimestamp(b, 2, dp.StartTimeUnixNano) // start_time_unix_nano
+	b = appendTimestamp(b, 3, dp.TimeUnixNano)      // time_unix_nano
+	count, _ := strconv.ParseUint(dp.Count, 10, 64)
+	b = appendFixed64(b, 4, count)                    // count
+	b = appendFixed64(b, 5, math.Float64bits(dp.Sum)) // sum
+
+	var counts []byte
+	for _, c := range dp.BucketCounts {
+		v, _ := strconv.ParseUint(c, 10, 64)
+		counts = protowire.AppendFixed64(counts, v)
+	}
+	b = appendMessage(b, 6, counts) // bucket_counts (packed)
+
+	var bounds []byte
+	for _, f := range dp.ExplicitBounds {
+		bounds = protowire.AppendFixed64(bounds, math.Float64bits(f))
+	}
+	b = appendMessage(b, 7, bounds) // explicit_bounds (packed)
+
+	return marshalAttributes(b, 9, dp.Attributes) // attributes
+}
+
+func marshalMetric(m *metric) []byte {
+	var b []byte
+	b = appendString(b, 1, m.Name) // name
+	b = appendString(b, 3, m.Unit) // unit
+
+	switch {
+	case m.Gauge != nil:
+		var g []byte
+		for _, dp := range m.Gauge.DataPoints {
+			g = appendMessage(g, 1, marshalNumberDataPoint(dp)) // data_points
+		}
+
+		b = appendMessage(b, 5, g) // gauge
+	case m.Sum != nil:
+		var s []byte
+		for _, dp := range m.Sum.DataPoints {
+			s = appendMessage(s, 1, marshalNumberDataPoint(dp)) // data_points
+		}
+		s = appendVarint(s, 2, uint64(m.Sum.AggregationTemporality)) // aggregation_temporality
+		if m.Sum.IsMonotonic {
+			s = appendVarint(s, 3, 1) // is_monotonic
+		}
+
+		b = appendMessage(b, 7, s) // sum
+	case m.Histogram != nil:
+		var h []byte
+		for _, dp := range m.Histogram.DataPoints {
+			h = appendMessage(h, 1, marshalHistogramDataPoint(dp)) // data_points
+		}
+		h = appendVarint(h, 2, uint64(m.Histogram.AggregationTemporality)) // aggregation_temporality
+
+		b = appendMessage(b, 9, h) // histogram
+	}
+	return b
+}
+
+// marshalProto returns the protobuf encoding of the export request, i.e. of
+// the ExportMetricsServiceRequest message.
+func (req *exportRequest) marshalProto() []byte {
+	var b []byte
+	for _, rm := range req.ResourceMetrics {
+		var r []byte
+		r = marshalAttributes(r, 1, rm.Resource.Attributes) // attributes
+
+		var rmb []byte
+		rmb = appendMessage(rmb, 1, r) // resource
+		for _, sm := range rm.ScopeMetrics {
+			var scope []byte
+			scope = appendString(scope, 1, sm.Scope.Name) // name
+
+			var smb []byte
+			smb = appendMessage(smb, 1, scope) // scope
+			for _, m := range sm.Metrics {
+				smb = appendMessage(smb, 2, marshalMetric(m)) // metrics
+			}
+			rmb = appendMessage(rmb, 2, smb) // scope_metrics
+		}
+		b = appendMessage(b, 1, rmb) // resource_metrics
+	}
+	return b
+}
@@ -0,0 +1,522 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/surfacers/otel/proto/config.proto
+
+package proto
+
+import (
+	proto "github.com/cloudprober/cloudprober/common/tlsconfig/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SurfacerConf_Protocol int32
+
+const (
+	SurfacerConf_HTTP_JSON SurfacerConf_Protocol = 0
+	SurfacerConf_GRPC      SurfacerConf_Protocol = 1
+)
+
+// Enum value maps for SurfacerConf_Protocol.
+var (
+	SurfacerConf_Protocol_name = map[int32]string{
+		0: "HTTP_JSON",
+		1: "GRPC",
+	}
+	SurfacerConf_Protocol_value = map[string]int32{
+		"HTTP_JSON": 0,
+		"GRPC":      1,
+	}
+)
+
+func (x SurfacerConf_Protocol) Enum() *SurfacerConf_Protocol {
+	p := new(SurfacerConf_Protocol)
+	*p = x
+	return p
+}
+
+func (x SurfacerConf_Protocol) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SurfacerConf_Protocol) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (SurfacerConf_Protocol) Type() protoreflect.EnumType {
+	return &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_enumTypes[0]
+}
+
+func (x SurfacerConf_Protocol) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *SurfacerConf_Protocol) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = SurfacerConf_Protocol(num)
+	return nil
+}
+
+// Deprecated: Use SurfacerConf_Protocol.Descriptor instead.
+func (SurfacerConf_Protocol) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+// OpenTelemetry (OTLP) surfacer config. Metrics are exported using either the
+// OTLP/HTTP protocol, with JSON encoding, or the OTLP/gRPC protocol.
+//
+// Metrics are mapped to OTLP metrics in the following manner:
+//   - Numerical CUMULATIVE metrics -> monotonic cumulative Sum
+//   - Numerical GAUGE metrics -> Gauge
+//   - Distributions -> Histogram (cumulative or delta, based on metrics kind)
+//   - Map metrics -> Sum or Gauge, with map's keys as an attribute
+//
+// EventMetrics labels are exported as data point attributes. String metrics
+// are not exported.
+type SurfacerConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Protocol *SurfacerConf_Protocol `protobuf:"varint,10,opt,name=protocol,enum=cloudprober.surfacer.otel.SurfacerConf_Protocol,def=0" json:"protocol,omitempty"`
+	// OTLP metrics endpoint. For the OTLP/HTTP protocol, it's the metrics URL,
+	// e.g. "http://localhost:4318/v1/metrics" (default). For the OTLP/gRPC
+	// protocol, it's the collector's address, e.g. "localhost:4317" (default).
+	Endpoint *string `protobuf:"bytes,1,opt,name=endpoint" json:"endpoint,omitempty"`
+	// TLS config for the HTTPS endpoints, or for the gRPC connection. If not
+	// specified, gRPC connection is not encrypted.
+	TlsConfig         *proto.TLSConfig          `protobuf:"bytes,2,opt,name=tls_config,json=tlsConfig" json:"tls_config,omitempty"`
+	HttpHeader        []*SurfacerConf_Header    `protobuf:"bytes,3,rep,name=http_header,json=httpHeader" json:"http_header,omitempty"`
+	ResourceAttribute []*SurfacerConf_Attribute `protobuf:"bytes,4,rep,name=resource_attribute,json=resourceAttribute" json:"resource_attribute,omitempty"`
+	// Prefix to add to all metric names.
+	MetricsPrefix *string `protobuf:"bytes,5,opt,name=metrics_prefix,json=metricsPrefix" json:"metrics_prefix,omitempty"`
+	// Maximum number of metrics (data points) to send in one export request.
+	BatchSize *int32 `protobuf:"varint,6,opt,name=batch_size,json=batchSize,def=1000" json:"batch_size,omitempty"`
+	// How often to export metrics, irrespective of the batch size.
+	ExportIntervalSec *int32 `protobuf:"varint,7,opt,name=export_interval_sec,json=exportIntervalSec,def=10" json:"export_interval_sec,omitempty"`
+	// Number of times to retry a failed export request. Requests failing
+	// with HTTP status codes other than 429, 502, 503 and 504 (gRPC status codes
+	// other than UNAVAILABLE, RESOURCE_EXHAUSTED, DEADLINE_EXCEEDED and ABORTED)
+	// are not retried.
+	MaxRetries *int32 `protobuf:"varint,8,opt,name=max_retries,json=maxRetries,def=3" json:"max_retries,omitempty"`
+	// Timeout for each export request.
+	TimeoutSec *int32 `protobuf:"varint,9,opt,name=timeout_sec,json=timeoutSec,def=10" json:"timeout_sec,omitempty"`
+	// Metrics with names matching this regex are considered latency metrics,
+	// and their unit is set based on the probe's latency unit. Update it if you
+	// use a non-default latency_metric_name for your probes.
+	LatencyMetricRegex *string `protobuf:"bytes,11,opt,name=latency_metric_regex,json=latencyMetricRegex,def=^latency(_raw)?$" json:"latency_metric_regex,omitempty"`
+}
+
+// Default values for SurfacerConf fields.
+const (
+	Default_SurfacerConf_Protocol           = SurfacerConf_HTTP_JSON
+	Default_SurfacerConf_BatchSize          = int32(1000)
+	Default_SurfacerConf_ExportIntervalSec  = int32(10)
+	Default_SurfacerConf_MaxRetries         = int32(3)
+	Default_SurfacerConf_TimeoutSec         = int32(10)
+	Default_SurfacerConf_LatencyMetricRegex = string("^latency(_raw)?$")
+)
+
+func (x *SurfacerConf) Reset() {
+	*x = SurfacerConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SurfacerConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurfacerConf) ProtoMessage() {}
+
+func (x *SurfacerConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurfacerConf.ProtoReflect.Descriptor instead.
+func (*SurfacerConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SurfacerConf) GetProtocol() SurfacerConf_Protocol {
+	if x != nil && x.Protocol != nil {
+		return *x.Protocol
+	}
+	return Default_SurfacerConf_Protocol
+}
+
+func (x *SurfacerConf) GetEndpoint() string {
+	if x != nil && x.Endpoint != nil {
+		return *x.Endpoint
+	}
+	return ""
+}
+
+func (x *SurfacerConf) GetTlsConfig() *proto.TLSConfig {
+	if x != nil {
+		return x.TlsConfig
+	}
+	return nil
+}
+
+func (x *SurfacerConf) GetHttpHeader() []*SurfacerConf_Header {
+	if x != nil {
+		return x.HttpHeader
+	}
+	return nil
+}
+
+func (x *SurfacerConf) GetResourceAttribute() []*SurfacerConf_Attribute {
+	if x != nil {
+		return x.ResourceAttribute
+	}
+	return nil
+}
+
+func (x *SurfacerConf) GetMetricsPrefix() string {
+	if x != nil && x.MetricsPrefix != nil {
+		return *x.MetricsPrefix
+	}
+	return ""
+}
+
+func (x *SurfacerConf) GetBatchSize() int32 {
+	if x != nil && x.BatchSize != nil {
+		return *x.BatchSize
+	}
+	return Default_SurfacerConf_BatchSize
+}
+
+func (x *SurfacerConf) GetExportIntervalSec() int32 {
+	if x != nil && x.ExportIntervalSec != nil {
+		return *x.ExportIntervalSec
+	}
+	return Default_SurfacerConf_ExportIntervalSec
+}
+
+func (x *SurfacerConf) GetMaxRetries() int32 {
+	if x != nil && x.MaxRetries != nil {
+		return *x.MaxRetries
+	}
+	return Default_SurfacerConf_MaxRetries
+}
+
+func (x *SurfacerConf) GetTimeoutSec() int32 {
+	if x != nil && x.TimeoutSec != nil {
+		return *x.TimeoutSec
+	}
+	return Default_SurfacerConf_TimeoutSec
+}
+
+func (x *SurfacerConf) GetLatencyMetricRegex() string {
+	if x != nil && x.LatencyMetricRegex != nil {
+		return *x.LatencyMetricRegex
+	}
+	return Default_SurfacerConf_LatencyMetricRegex
+}
+
+// HTTP headers (gRPC metadata for the OTLP/gRPC protocol) to add to the
+// export requests, e.g. for authentication.
+type SurfacerConf_Header struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Value *string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (x *SurfacerConf_Header) Reset() {
+	*x = SurfacerConf_Header{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SurfacerConf_Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurfacerConf_Header) ProtoMessage() {}
+
+func (x *SurfacerConf_Header) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurfacerConf_Header.ProtoReflect.Descriptor instead.
+func (*SurfacerConf_Header) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *SurfacerConf_Header) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *SurfacerConf_Header) GetValue() string {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return ""
+}
+
+// Resource attributes. If not specified, "service.name" is set to
+// "cloudprober", and "service.instance.id" is set to the hostname.
+type SurfacerConf_Attribute struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key   *string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value *string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (x *SurfacerConf_Attribute) Reset() {
+	*x = SurfacerConf_Attribute{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SurfacerConf_Attribute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurfacerConf_Attribute) ProtoMessage() {}
+
+func (x *SurfacerConf_Attribute) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurfacerConf_Attribute.ProtoReflect.Descriptor instead.
+func (*SurfacerConf_Attribute) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescGZIP(), []int{0, 1}
+}
+
+func (x *SurfacerConf_Attribute) GetKey() string {
+	if x != nil && x.Key != nil {
+		return *x.Key
+	}
+	return ""
+}
+
+func (x *SurfacerConf_Attribute) GetValue() string {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return ""
+}
+
+var File_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x44, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x6f,
+	0x74, 0x65, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x19, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x6f, 0x74, 0x65,
+	0x6c, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x74, 0x6c, 0x73,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x92, 0x06, 0x0a, 0x0c, 0x53, 0x75,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x57, 0x0a, 0x08, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x30, 0x2e, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x72, 0x2e, 0x6f, 0x74, 0x65, 0x6c, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x3a, 0x09,
+	0x48, 0x54, 0x54, 0x50, 0x5f, 0x4a, 0x53, 0x4f, 0x4e, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12,
+	0x3f, 0x0a, 0x0a, 0x74, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x74, 0x6c, 0x73, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x54, 0x4c, 0x53, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x09, 0x74, 0x6c, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x4f, 0x0a, 0x0b, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18,
+	0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x6f, 0x74, 0x65,
+	0x6c, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x48,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x0a, 0x68, 0x74, 0x74, 0x70, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x12, 0x60, 0x0a, 0x12, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x61, 0x74,
+	0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x31, 0x2e,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66,
+	0x61, 0x63, 0x65, 0x72, 0x2e, 0x6f, 0x74, 0x65, 0x6c, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
+	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65,
+	0x52, 0x11, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62,
+	0x75, 0x74, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x70,
+	0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x73, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x23, 0x0a, 0x0a, 0x62, 0x61,
+	0x74, 0x63, 0x68, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x04,
+	0x31, 0x30, 0x30, 0x30, 0x52, 0x09, 0x62, 0x61, 0x74, 0x63, 0x68, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x32, 0x0a, 0x13, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x02, 0x31, 0x30,
+	0x52, 0x11, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x53, 0x65, 0x63, 0x12, 0x22, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x72, 0x65, 0x74, 0x72, 0x69,
+	0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x33, 0x52, 0x0a, 0x6d, 0x61, 0x78,
+	0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x02, 0x31, 0x30,
+	0x52, 0x0a, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x12, 0x42, 0x0a, 0x14,
+	0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x5f, 0x72,
+	0x65, 0x67, 0x65, 0x78, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x10, 0x5e, 0x6c, 0x61, 0x74,
+	0x65, 0x6e, 0x63, 0x79, 0x28, 0x5f, 0x72, 0x61, 0x77, 0x29, 0x3f, 0x24, 0x52, 0x12, 0x6c, 0x61,
+	0x74, 0x65, 0x6e, 0x63, 0x79, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x52, 0x65, 0x67, 0x65, 0x78,
+	0x1a, 0x32, 0x0a, 0x06, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x1a, 0x33, 0x0a, 0x09, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x23, 0x0a, 0x08, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x0d, 0x0a, 0x09, 0x48, 0x54, 0x54, 0x50, 0x5f, 0x4a, 0x53,
+	0x4f, 0x4e, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x47, 0x52, 0x50, 0x43, 0x10, 0x01, 0x42, 0x39,
+	0x5a, 0x37, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x6f,
+	0x74, 0x65, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_goTypes = []interface{}{
+	(SurfacerConf_Protocol)(0),     // 0: cloudprober.surfacer.otel.SurfacerConf.Protocol
+	(*SurfacerConf)(nil),           // 1: cloudprober.surfacer.otel.SurfacerConf
+	(*SurfacerConf_Header)(nil),    // 2: cloudprober.surfacer.otel.SurfacerConf.Header
+	(*SurfacerConf_Attribute)(nil), // 3: cloudprober.surfacer.otel.SurfacerConf.Attribute
+	(*proto.TLSConfig)(nil),        // 4: cloudprober.tlsconfig.TLSConfig
+}
+var file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_depIdxs = []int32{
+	0, // 0: cloudprober.surfacer.otel.SurfacerConf.protocol:type_name -> cloudprober.surfacer.otel.SurfacerConf.Protocol
+	4, // 1: cloudprober.surfacer.otel.SurfacerConf.tls_config:type_name -> cloudprober.tlsconfig.TLSConfig
+	2, // 2: cloudprober.surfacer.otel.SurfacerConf.http_header:type_name -> cloudprober.surfacer.otel.SurfacerConf.Header
+	3, // 3: cloudprober.surfacer.otel.SurfacerConf.resource_attribute:type_name -> cloudprober.surfacer.otel.SurfacerConf.Attribute
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SurfacerConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SurfacerConf_Header); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SurfacerConf_Attribute); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_enumTypes,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_surfacers_otel_proto_config_proto_depIdxs = nil
+}
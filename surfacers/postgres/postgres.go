@@ -42,6 +42,8 @@ import (
 
 	"github.com/lib/pq"
 
+	"github.com/cloudprober/cloudprober/surfacers/common/backoff"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
 	configpb "github.com/cloudprober/cloudprober/surfacers/postgres/proto"
 )
 
@@ -166,11 +168,14 @@ type Surfacer struct {
 
 	openDB func(connectionString string) (*sql.DB, error)
 	db     *sql.DB
+
+	// Sender for writing metrics, backs off while database is unavailable.
+	sender *backoff.Sender
 }
 
 // New initializes a Postgres surfacer. Postgres surfacer inserts probe results
 // into a postgres database.
-func New(ctx context.Context, config *configpb.SurfacerConf, l *logger.Logger) (*Surfacer, error) {
+func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Options, l *logger.Logger) (*Surfacer, error) {
 	s := &Surfacer{
 		c: config,
 		l: l,
@@ -178,6 +183,9 @@ func New(ctx context.Context, config *configpb.SurfacerConf, l *logger.Logger) (
 			return sql.Open("postgres", cs)
 		},
 	}
+	s.sender = backoff.NewSender(opts.Config, func(item interface{}) error {
+		return s.writeMetrics(item.(*metrics.EventMetrics))
+	}, l)
 	return s, s.init(ctx)
 }
 
@@ -199,7 +207,8 @@ func (s *Surfacer) writeMetrics(em *metrics.EventMetrics) error {
 	for _, pgMetric := range emToPGMetrics(em) {
 		var s string
 		if s, err = labelsJSON(pgMetric.labels); err != nil {
-			return err
+			// Retrying won't fix bad labels.
+			return backoff.Permanent(err)
 		}
 		if _, err = stmt.Exec(pgMetric.time, pgMetric.metricName, pgMetric.value, s); err != nil {
 			return err
@@ -216,6 +225,12 @@ func (s *Surfacer) writeMetrics(em *metrics.EventMetrics) error {
 	return txn.Commit()
 }
 
+// Dropped returns the number of EventMetrics dropped, either because they
+// couldn't be converted to rows, or because the reconnect buffer was full.
+func (s *Surfacer) Dropped() int64 {
+	return s.sender.Dropped()
+}
+
 // init connects to postgres
 func (s *Surfacer) init(ctx context.Context) error {
 	var err error
@@ -244,9 +259,7 @@ func (s *Surfacer) init(ctx context.Context) error {
 				}
 				// Note: we may want to batch calls to writeMetrics, as each call results in
 				// a database transaction.
-				if err := s.writeMetrics(em); err != nil {
-					s.l.Warningf("Error while writing metrics: %v", err)
-				}
+				s.sender.Send(em)
 			}
 		}
 	}()
@@ -0,0 +1,317 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package promremote implements a surfacer that pushes metrics to a Prometheus
+remote-write endpoint.
+
+Write requests rejected by the endpoint, or dropped because the endpoint was
+unavailable for too long, are counted and exported as the surfacer's "dropped"
+metric.
+*/
+package promremote
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudprober/cloudprober/common/oauth"
+	"github.com/cloudprober/cloudprober/common/tlsconfig"
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/backoff"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	"github.com/cloudprober/cloudprober/surfacers/prometheus"
+	configpb "github.com/cloudprober/cloudprober/surfacers/promremote/proto"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	metricNameRe = regexp.MustCompile(prometheus.ValidMetricNameRegex)
+	labelNameRe  = regexp.MustCompile(prometheus.ValidLabelNameRegex)
+)
+
+// Surfacer implements a Prometheus remote-write surfacer.
+type Surfacer struct {
+	c         *configpb.SurfacerConf
+	opts      *options.Options
+	writeChan chan *metrics.EventMetrics
+	writeDone chan struct{} // Closed when the write loop exits.
+	client    *http.Client
+	oauthTS   oauth2.TokenSource
+	l         *logger.Logger
+
+	// Sender for the write requests, backs off while the endpoint is
+	// unavailable.
+	sender *backoff.Sender
+
+	// Series waiting to be pushed, and number of samples in them.
+	pending        []*configpb.TimeSeries
+	pendingSamples int
+}
+
+// promName converts a name to a valid Prometheus metric or label name. If name
+// is invalid even after the conversion, an empty string is returned.
+func promName(name string, re *regexp.Regexp) string {
+	// Prometheus doesn't support "-" in metric and label names.
+	name = strings.Replace(name, "-", "_", -1)
+	if !re.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// newSeries creates a series with the given name and labels, and a single
+// sample. Extra labels are given as name-value pairs.
+func newSeries(name string, baseLabels []*configpb.Label, val float64, ts int64, extra ...string) *configpb.TimeSeries {
+	labels := append([]*configpb.Label{{Name: "__name__", Value: name}}, baseLabels...)
+	for i := 0; i+1 < len(extra); i += 2 {
+		labels = append(labels, &configpb.Label{Name: extra[i], Value: extra[i+1]})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	return &configpb.TimeSeries{
+		Labels:  labels,
+		Samples: []*configpb.Sample{{Value: val, Timestamp: ts}},
+	}
+}
+
+// timeSeries converts an EventMetrics into the remote-write time series.
+func (s *Surfacer) timeSeries(em *metrics.EventMetrics) []*configpb.TimeSeries {
+	var labels []*configpb.Label
+	for _, k := range em.LabelsKeys() {
+		if name := promName(k, labelNameRe); name != "" {
+			labels = append(labels, &configpb.Label{Name: name, Value: em.Label(k)})
+		}
+	}
+	ts := em.Timestamp.UnixNano() / int64(time.Millisecond)
+
+	var result []*configpb.TimeSeries
+	for _, k := range em.MetricsKeys() {
+		if !s.opts.AllowMetric(k) {
+			continue
+		}
+		name := promName(s.c.GetMetricsPrefix()+k, metricNameRe)
+		if name == "" {
+			s.l.Warningf("Ignoring invalid prometheus metric name: %s", s.c.GetMetricsPrefix()+k)
+			continue
+		}
+
+		switch v := em.Metric(k).(type) {
+		case metrics.NumValue:
+			result = append(result, newSeries(name, labels, v.Float64(), ts))
+
+		case *metrics.Map:
+			mapLabel := promName(v.MapName, labelNameRe)
+			if mapLabel == "" {
+				continue
+			}
+			for _, mapKey := range v.Keys() {
+				result = append(result, newSeries(name, labels, v.GetKey(mapKey).Float64(), ts, mapLabel, mapKey))
+			}
+
+		case *metrics.Distribution:
+			d := v.Data()
+			result = append(result, newSeries(name+"_sum", labels, d.Sum, ts))
+			result = append(result, newSeries(name+"_count", labels, float64(d.Count), ts))
+			var cumCount int64
+			for i := range d.LowerBounds {
+				cumCount += d.BucketCounts[i]
+				le := "+Inf"
+				if i < len(d.LowerBounds)-1 {
+					le = strconv.FormatFloat(d.LowerBounds[i+1], 'f', -1, 64)
+				}
+				result = append(result, newSeries(name+"_bucket", labels, float64(cumCount), ts, "le", le))
+			}
+
+		case metrics.String:
+			// String() returns the quoted string.
+			val := strings.TrimSuffix(strings.TrimPrefix(v.String(), "\""), "\"")
+			result = append(result, newSeries(name, labels, 1, ts, "val", val))
+
+		default:
+			s.l.Debugf("Unsupported metric type for the metric %s: %T, skipping it", k, v)
+		}
+	}
+
+	return result
+}
+
+func (s *Surfacer) recordEventMetrics(em *metrics.EventMetrics) {
+	for _, series := range s.timeSeries(em) {
+		s.pending = append(s.pending, series)
+		s.pendingSamples += len(series.Samples)
+	}
+
+	if s.pendingSamples >= int(s.c.GetBatchSize()) {
+		s.flush()
+	}
+}
+
+// flush sends the pending series as a write request.
+func (s *Surfacer) flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+
+	b, err := proto.Marshal(&configpb.WriteRequest{Timeseries: s.pending})
+	s.pending, s.pendingSamples = nil, 0
+	if err != nil {
+		s.l.Errorf("Error marshaling the write request: %v", err)
+		return
+	}
+
+	s.sender.Send(snappyEncode(b))
+}
+
+// write sends a compressed write request to the remote-write endpoint.
+// Requests rejected by the endpoint are dropped, by returning a permanent
+// error, as retrying them won't help.
+func (s *Surfacer) write(item interface{}) error {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Duration(s.c.GetTimeoutSec())*time.Second)
+	defer cancelFunc()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.c.GetUrl(), bytes.NewReader(item.([]byte)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "cloudprober")
+	for _, h := range s.c.GetHttpHeader() {
+		req.Header.Set(h.GetName(), h.GetValue())
+	}
+	if s.c.GetBasicAuth() != nil {
+		req.SetBasicAuth(s.c.GetBasicAuth().GetUsername(), s.c.GetBasicAuth().GetPassword())
+	}
+	if s.oauthTS != nil {
+		tok, err := s.oauthTS.Token()
+		if err != nil {
+			return fmt.Errorf("error getting oauth token: %v", err)
+		}
+		tok.SetAuthHeader(req)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return backoff.Permanent(fmt.Errorf("write request rejected, status: %s, response: %s", resp.Status, string(respBody)))
+	}
+	return fmt.Errorf("got HTTP status: %s, response: %s", resp.Status, string(respBody))
+}
+
+// Dropped returns the number of write requests dropped, either because they
+// were rejected by the endpoint, or because the reconnect buffer was full.
+func (s *Surfacer) Dropped() int64 {
+	return s.sender.Dropped()
+}
+
+func (s *Surfacer) processIncomingMetrics(ctx context.Context) {
+	defer close(s.writeDone)
+
+	ticker := time.NewTicker(time.Duration(s.c.GetFlushIntervalSec()) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.l.Infof("Context canceled, stopping the surfacer write loop")
+			return
+		case em, ok := <-s.writeChan:
+			if !ok {
+				return
+			}
+			s.recordEventMetrics(em)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// New creates a new instance of a Prometheus remote-write surfacer, based on
+// the config passed in. It then hands off to a goroutine to push metrics.
+func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Options, l *logger.Logger) (*Surfacer, error) {
+	if config.GetBatchSize() <= 0 || config.GetFlushIntervalSec() <= 0 {
+		return nil, fmt.Errorf("promremote surfacer: batch_size (%d) and flush_interval_sec (%d) should be positive", config.GetBatchSize(), config.GetFlushIntervalSec())
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.GetTlsConfig() != nil {
+		transport.TLSClientConfig = &tls.Config{}
+		if err := tlsconfig.UpdateTLSConfig(transport.TLSClientConfig, config.GetTlsConfig(), false); err != nil {
+			return nil, fmt.Errorf("promremote surfacer: error configuring TLS: %v", err)
+		}
+	}
+
+	s := &Surfacer{
+		c:         config,
+		opts:      opts,
+		writeChan: make(chan *metrics.EventMetrics, opts.MetricsBufferSize),
+		writeDone: make(chan struct{}),
+		client:    &http.Client{Transport: transport},
+		l:         l,
+	}
+
+	if config.GetOauthConfig() != nil {
+		oauthTS, err := oauth.TokenSourceFromConfig(config.GetOauthConfig(), l)
+		if err != nil {
+			return nil, fmt.Errorf("promremote surfacer: error configuring oauth: %v", err)
+		}
+		s.oauthTS = oauthTS
+	}
+
+	s.sender = backoff.NewSender(opts.Config, s.write, l)
+
+	go s.processIncomingMetrics(ctx)
+
+	s.l.Infof("Initialized Prometheus remote-write surfacer, URL: %s", config.GetUrl())
+	return s, nil
+}
+
+// Write queues the incoming data into a channel. This channel is watched by a
+// goroutine that actually pushes the metrics.
+func (s *Surfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
+	select {
+	case s.writeChan <- em:
+	default:
+		s.l.Error("Surfacer's write channel is full, dropping new data.")
+	}
+}
+
+// Close stops the write loop and pushes the pending metrics.
+func (s *Surfacer) Close() {
+	close(s.writeChan)
+	<-s.writeDone
+	s.flush()
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promremote
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	configpb "github.com/cloudprober/cloudprober/surfacers/promremote/proto"
+	surfacerpb "github.com/cloudprober/cloudprober/surfacers/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// testReceiver is a mock remote-write receiver.
+type testReceiver struct {
+	mu          sync.Mutex
+	requests    []*configpb.WriteRequest
+	headers     []http.Header
+	status      []int // Statuses to reply with, before replying with 200.
+	numRequests int
+}
+
+func (tr *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.numRequests++
+	if len(tr.status) > 0 {
+		w.WriteHeader(tr.status[0])
+		tr.status = tr.status[1:]
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	b, err := snappyDecode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &configpb.WriteRequest{}
+	if err := proto.Unmarshal(b, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tr.requests = append(tr.requests, req)
+	tr.headers = append(tr.headers, r.Header)
+}
+
+func testSurfacer(t *testing.T, tr *testReceiver) *Surfacer {
+	t.Helper()
+
+	ts := httptest.NewServer(tr)
+	t.Cleanup(ts.Close)
+
+	s, err := New(context.Background(), &configpb.SurfacerConf{
+		Url: proto.String(ts.URL + "/api/v1/write"),
+		BasicAuth: &configpb.SurfacerConf_BasicAuth{
+			Username: proto.String("user"),
+			Password: proto.String("pass"),
+		},
+		HttpHeader: []*configpb.SurfacerConf_Header{
+			{Name: proto.String("X-Scope-OrgID"), Value: proto.String("tenant-1")},
+		},
+		MetricsPrefix: proto.String("cp_"),
+	}, &options.Options{
+		MetricsBufferSize: 10,
+		Config:            &surfacerpb.SurfacerDef{ReconnectInitialBackoffMsec: proto.Int32(1), ReconnectMaxBackoffMsec: proto.Int32(1)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating surfacer: %v", err)
+	}
+	return s
+}
+
+// seriesString returns a series' labels and samples in the text format, e.g.
+// cp_total{dst="d1",probe="p1"} 10@1600000000000.
+func seriesString(ts *configpb.TimeSeries) string {
+	var name string
+	var labels []string
+	for _, l := range ts.GetLabels() {
+		if l.GetName() == "__name__" {
+			name = l.GetValue()
+			continue
+		}
+		labels = append(labels, l.GetName()+"=\""+l.GetValue()+"\"")
+	}
+	var samples []string
+	for _, s := range ts.GetSamples() {
+		samples = append(samples, fmt.Sprintf("%v@%d", s.GetValue(), s.GetTimestamp()))
+	}
+	return name + "{" + strings.Join(labels, ",") + "} " + strings.Join(samples, ";")
+}
+
+func TestWrite(t *testing.T) {
+	tr := &testReceiver{}
+	s := testSurfacer(t, tr)
+
+	d := metrics.NewDistribution([]float64{1, 10})
+	d.AddSample(5)
+	d.AddSample(20)
+
+	respCodes := metrics.NewMap("code", metrics.NewInt(0))
+	respCodes.IncKey("200")
+
+	ts := time.Unix(1600000000, 0)
+	em := metrics.NewEventMetrics(ts).
+		AddMetric("total", metrics.NewInt(10)).
+		AddMetric("latency", d).
+		AddMetric("resp-code", respCodes).
+		AddMetric("version", metrics.NewString("v1")).
+		AddLabel("probe", "p1").
+		AddLabel("dst", "d1")
+
+	gaugeEM := metrics.NewEventMetrics(ts).
+		AddMetric("temp", metrics.NewFloat(1.5)).
+		AddLabel("probe", "p1")
+	gaugeEM.Kind = metrics.GAUGE
+
+	s.Write(context.Background(), em)
+	s.Write(context.Background(), gaugeEM)
+	s.Close()
+
+	if len(tr.requests) != 1 {
+		t.Fatalf("Got %d write requests, want 1", len(tr.requests))
+	}
+
+	h := tr.headers[0]
+	for k, v := range map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+		"X-Scope-OrgID":                     "tenant-1",
+		"Authorization":                     "Basic dXNlcjpwYXNz",
+	} {
+		if h.Get(k) != v {
+			t.Errorf("Header %s=%q, want=%q", k, h.Get(k), v)
+		}
+	}
+
+	var got []string
+	for _, ts := range tr.requests[0].GetTimeseries() {
+		got = append(got, seriesString(ts))
+	}
+	want := []string{
+		`cp_total{dst="d1",probe="p1"} 10@1600000000000`,
+		`cp_latency_sum{dst="d1",probe="p1"} 25@1600000000000`,
+		`cp_latency_count{dst="d1",probe="p1"} 2@1600000000000`,
+		`cp_latency_bucket{dst="d1",le="1",probe="p1"} 0@1600000000000`,
+		`cp_latency_bucket{dst="d1",le="10",probe="p1"} 1@1600000000000`,
+		`cp_latency_bucket{dst="d1",le="+Inf",probe="p1"} 2@1600000000000`,
+		`cp_resp_code{code="200",dst="d1",probe="p1"} 1@1600000000000`,
+		`cp_version{dst="d1",probe="p1",val="v1"} 1@1600000000000`,
+		`cp_temp{probe="p1"} 1.5@1600000000000`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got series:\n%s\nWant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	tr := &testReceiver{}
+	s := testSurfacer(t, tr)
+	s.c.BatchSize = proto.Int32(2)
+
+	for i := 0; i < 5; i++ {
+		s.recordEventMetrics(metrics.NewEventMetrics(time.Now()).AddMetric("total", metrics.NewInt(int64(i))))
+	}
+	s.Close()
+
+	if len(tr.requests) != 3 {
+		t.Errorf("Got %d write requests, want 3", len(tr.requests))
+	}
+}
+
+func TestWriteFailures(t *testing.T) {
+	for _, test := range []struct {
+		desc         string
+		status       []int
+		wantRequests int // Number of write requests accepted by the receiver.
+		wantDropped  int64
+	}{
+		{
+			desc:         "retry_unavailable",
+			status:       []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantRequests: 3,
+		},
+		{
+			desc:         "drop_rejected",
+			status:       []int{http.StatusBadRequest},
+			wantRequests: 2,
+			wantDropped:  1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tr := &testReceiver{status: test.status}
+			s := testSurfacer(t, tr)
+
+			// Failed write requests are retried with the next write request,
+			// once the backoff is over.
+			for i := 0; i < 3; i++ {
+				s.recordEventMetrics(metrics.NewEventMetrics(time.Now()).AddMetric("total", metrics.NewInt(int64(i))))
+				s.flush()
+				time.Sleep(10 * time.Millisecond)
+			}
+			s.Close()
+
+			if len(tr.requests) != test.wantRequests {
+				t.Errorf("Got %d write requests, want %d", len(tr.requests), test.wantRequests)
+			}
+			if s.Dropped() != test.wantDropped {
+				t.Errorf("Got dropped=%d, want=%d", s.Dropped(), test.wantDropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promremote
+
+// This file implements an encoder for the snappy block format, as required by
+// the remote-write protocol (note: it's not the snappy framing format). See
+// https://github.com/google/snappy/blob/main/format_description.txt for the
+// format description.
+
+import (
+	"encoding/binary"
+)
+
+const (
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+
+	// Input is encoded in blocks of this size, so that copy offsets always
+	// fit in 2 bytes.
+	snappyBlockSize = 1 << 16
+
+	// Inputs smaller than this are emitted as literals.
+	minMatchBlockSize = 17
+
+	snappyHashBits = 14
+)
+
+func emitLiteral(dst, lit []byte) []byte {
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|tagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|tagLiteral, byte(n))
+	default:
+		// Blocks are at most 64KB, so length always fits in 2 bytes.
+		dst = append(dst, 61<<2|tagLiteral, byte(n), byte(n>>8))
+	}
+	return append(dst, lit...)
+}
+
+func emitCopy(dst []byte, offset, length int) []byte {
+	// Copies with 2-byte offsets can be up to 64 bytes long. We emit 60 bytes
+	// long copies for lengths between 65 and 67 so that the remaining length
+	// is at least 4.
+	for length >= 68 {
+		dst = append(dst, 63<<2|tagCopy2, byte(offset), byte(offset>>8))
+		length -= 64
+	}
+	if length > 64 {
+		dst = append(dst, 59<<2|tagCopy2, byte(offset), byte(offset>>8))
+		length -= 60
+	}
+	if length >= 12 || offset >= 2048 {
+		return append(dst, byte(length-1)<<2|tagCopy2, byte(offset), byte(offset>>8))
+	}
+	return append(dst, byte(offset>>8)<<5|byte(length-4)<<2|tagCopy1, byte(offset))
+}
+
+func snappyHash(u uint32) uint32 {
+	return (u * 0x1e35a7bd) >> (32 - snappyHashBits)
+}
+
+// encodeBlock encodes a block of at most snappyBlockSize bytes, looking for
+// the matches using a hash table of the 4-byte sequences seen so far.
+func encodeBlock(dst, src []byte) []byte {
+	if len(src) < minMatchBlockSize {
+		return emitLiteral(dst, src)
+	}
+
+	var table [1 << snappyHashBits]int32 // Position+1 of the 4-byte sequences.
+	litStart := 0
+	for s := 0; s+4 <= len(src); {
+		cur := binary.LittleEndian.Uint32(src[s:])
+		h := snappyHash(cur)
+		candidate := int(table[h]) - 1
+		table[h] = int32(s + 1)
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != cur {
+			s++
+			continue
+		}
+
+		if litStart < s {
+			dst = emitLiteral(dst, src[litStart:s])
+		}
+		offset, matchStart := s-candidate, s
+		for s += 4; s < len(src) && src[s] == src[s-offset]; s++ {
+		}
+		dst = emitCopy(dst, offset, s-matchStart)
+		litStart = s
+	}
+
+	if litStart < len(src) {
+		dst = emitLiteral(dst, src[litStart:])
+	}
+	return dst
+}
+
+// snappyEncode returns the snappy block format encoding of src.
+func snappyEncode(src []byte) []byte {
+	var lenBuf [binary.MaxVarintLen64]byte
+	dst := append([]byte{}, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(src)))]...)
+
+	for len(src) > 0 {
+		block := src
+		if len(block) > snappyBlockSize {
+			block = block[:snappyBlockSize]
+		}
+		dst = encodeBlock(dst, block)
+		src = src[len(block):]
+	}
+	return dst
+}
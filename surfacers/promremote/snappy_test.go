@@ -0,0 +1,121 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promremote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// snappyDecode decodes the snappy block format, to verify the encoder's
+// output.
+func snappyDecode(src []byte) ([]byte, error) {
+	decodedLen, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("invalid length preamble")
+	}
+	src = src[n:]
+
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+
+		switch tag & 0x03 {
+		case tagLiteral:
+			length, src = int(tag>>2), src[1:]
+			switch length {
+			case 60:
+				length, src = int(src[0]), src[1:]
+			case 61:
+				length, src = int(src[0])|int(src[1])<<8, src[2:]
+			}
+			length++
+			if length > len(src) {
+				return nil, errors.New("literal beyond input")
+			}
+			dst, src = append(dst, src[:length]...), src[length:]
+			continue
+
+		case tagCopy1:
+			if len(src) < 2 {
+				return nil, errors.New("truncated copy")
+			}
+			length, offset = 4+int(tag>>2)&0x07, int(tag>>5)<<8|int(src[1])
+			src = src[2:]
+
+		case tagCopy2:
+			if len(src) < 3 {
+				return nil, errors.New("truncated copy")
+			}
+			length, offset = 1+int(tag>>2), int(src[1])|int(src[2])<<8
+			src = src[3:]
+
+		default:
+			return nil, errors.New("unexpected copy with 4-byte offset")
+		}
+
+		if offset == 0 || offset > len(dst) {
+			return nil, errors.New("invalid copy offset")
+		}
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+
+	if uint64(len(dst)) != decodedLen {
+		return nil, errors.New("decoded length mismatch")
+	}
+	return dst, nil
+}
+
+func TestSnappyEncode(t *testing.T) {
+	random := make([]byte, 200000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	repetitive := []byte(strings.Repeat(`cloudprober_latency_bucket{probe="homepage",dst="www.example.com",le="0.5"}`, 3000))
+
+	for _, test := range []struct {
+		desc          string
+		input         []byte
+		wantSmallerBy int
+	}{
+		{desc: "empty", input: []byte{}},
+		{desc: "short", input: []byte("cloudprober")},
+		{desc: "long_literal", input: random[:300]},
+		{desc: "random", input: random},
+		{desc: "repetitive", input: repetitive, wantSmallerBy: 10},
+		{desc: "runs", input: bytes.Repeat([]byte{'a'}, 70000), wantSmallerBy: 10},
+		{desc: "mixed", input: append(append([]byte{}, random[:50000]...), repetitive...)},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			encoded := snappyEncode(test.input)
+			decoded, err := snappyDecode(encoded)
+			if err != nil {
+				t.Fatalf("Error decoding: %v", err)
+			}
+			if !bytes.Equal(decoded, test.input) {
+				t.Errorf("Decoded data (length: %d) doesn't match the input (length: %d)", len(decoded), len(test.input))
+			}
+			if test.wantSmallerBy != 0 && len(encoded)*test.wantSmallerBy > len(test.input) {
+				t.Errorf("Encoded length: %d, want less than 1/%d of the input length: %d", len(encoded), test.wantSmallerBy, len(test.input))
+			}
+		})
+	}
+}
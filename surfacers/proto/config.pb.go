@@ -10,9 +10,12 @@ import (
 	proto5 "github.com/cloudprober/cloudprober/surfacers/cloudwatch/proto"
 	proto6 "github.com/cloudprober/cloudprober/surfacers/datadog/proto"
 	proto2 "github.com/cloudprober/cloudprober/surfacers/file/proto"
+	proto7 "github.com/cloudprober/cloudprober/surfacers/otel/proto"
 	proto3 "github.com/cloudprober/cloudprober/surfacers/postgres/proto"
 	proto "github.com/cloudprober/cloudprober/surfacers/prometheus/proto"
+	proto9 "github.com/cloudprober/cloudprober/surfacers/promremote/proto"
 	proto4 "github.com/cloudprober/cloudprober/surfacers/pubsub/proto"
+	proto8 "github.com/cloudprober/cloudprober/surfacers/redis/proto"
 	proto1 "github.com/cloudprober/cloudprober/surfacers/stackdriver/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
@@ -31,15 +34,18 @@ const (
 type Type int32
 
 const (
-	Type_NONE         Type = 0
-	Type_PROMETHEUS   Type = 1
-	Type_STACKDRIVER  Type = 2
-	Type_FILE         Type = 3
-	Type_POSTGRES     Type = 4
-	Type_PUBSUB       Type = 5
-	Type_CLOUDWATCH   Type = 6 // Experimental mode.
-	Type_DATADOG      Type = 7 // Experimental mode.
-	Type_USER_DEFINED Type = 99
+	Type_NONE                    Type = 0
+	Type_PROMETHEUS              Type = 1
+	Type_STACKDRIVER             Type = 2
+	Type_FILE                    Type = 3
+	Type_POSTGRES                Type = 4
+	Type_PUBSUB                  Type = 5
+	Type_CLOUDWATCH              Type = 6  // Experimental mode.
+	Type_DATADOG                 Type = 7  // Experimental mode.
+	Type_OTEL                    Type = 8  // Experimental mode.
+	Type_REDIS                   Type = 9  // Experimental mode.
+	Type_PROMETHEUS_REMOTE_WRITE Type = 10 // Experimental mode.
+	Type_USER_DEFINED            Type = 99
 )
 
 // Enum value maps for Type.
@@ -53,18 +59,24 @@ var (
 		5:  "PUBSUB",
 		6:  "CLOUDWATCH",
 		7:  "DATADOG",
+		8:  "OTEL",
+		9:  "REDIS",
+		10: "PROMETHEUS_REMOTE_WRITE",
 		99: "USER_DEFINED",
 	}
 	Type_value = map[string]int32{
-		"NONE":         0,
-		"PROMETHEUS":   1,
-		"STACKDRIVER":  2,
-		"FILE":         3,
-		"POSTGRES":     4,
-		"PUBSUB":       5,
-		"CLOUDWATCH":   6,
-		"DATADOG":      7,
-		"USER_DEFINED": 99,
+		"NONE":                    0,
+		"PROMETHEUS":              1,
+		"STACKDRIVER":             2,
+		"FILE":                    3,
+		"POSTGRES":                4,
+		"PUBSUB":                  5,
+		"CLOUDWATCH":              6,
+		"DATADOG":                 7,
+		"OTEL":                    8,
+		"REDIS":                   9,
+		"PROMETHEUS_REMOTE_WRITE": 10,
+		"USER_DEFINED":            99,
 	}
 )
 
@@ -216,6 +228,70 @@ type SurfacerDef struct {
 	// However, it should not be noticeable unless you're producing large number
 	// of metrics (say > 10000 metrics per second).
 	ExportAsGauge *bool `protobuf:"varint,9,opt,name=export_as_gauge,json=exportAsGauge" json:"export_as_gauge,omitempty"`
+	// Percentiles to export for distribution metrics, e.g. 50, 90, 99. If
+	// specified, percentiles are estimated from the distribution buckets and
+	// are exported as a separate GAUGE EventMetrics, with the same labels as
+	// the original EventMetrics and metric names like "latency_p50",
+	// "latency_p99_9". Note that these are approximations: percentiles are
+	// computed by linear interpolation within the bucket that contains them,
+	// so the error is bounded by that bucket's width.
+	// If export_as_gauge is also set, percentiles are computed over the
+	// distribution's delta since the last export.
+	ExportPercentiles []float64 `protobuf:"fixed64,17,rep,name=export_percentiles,json=exportPercentiles" json:"export_percentiles,omitempty"`
+	// Allow and deny metrics based on their names, using lists of regexes.
+	// Unlike allow_metrics_with_name and ignore_metrics_with_name above, these
+	// filters are applied to all surfacer types, before metrics are written to
+	// the surfacer. A metric is exported if it doesn't match any of the
+	// deny_metrics regexes, and matches at least one of the allow_metrics
+	// regexes (if specified). Deny has precedence over allow.
+	// Examples:
+	//  allow_metrics: "^(total|success)$"
+	//  allow_metrics: "^latency"
+	//  deny_metrics: "validation_failure"
+	//
+	// EventMetrics with all their metrics filtered out are not exported at all.
+	// Number of metrics filtered out so far is exported by the surfacer itself,
+	// as the "filtered_metrics" metric with the labels probe="sysvars" and
+	// surfacer=<surfacer name>.
+	AllowMetrics []string `protobuf:"bytes,18,rep,name=allow_metrics,json=allowMetrics" json:"allow_metrics,omitempty"`
+	DenyMetrics  []string `protobuf:"bytes,19,rep,name=deny_metrics,json=denyMetrics" json:"deny_metrics,omitempty"`
+	// If set, metrics are rolled up over windows of this duration, and the
+	// surfacer receives the rolled up metrics once at the end of every window.
+	// This is useful for the monitoring systems that can't handle metrics at
+	// the probe's stats export interval resolution.
+	// For cumulative metrics, per-cycle increments of counters are summed and
+	// per-cycle increments of distributions are merged (distributions must keep
+	// the same buckets), and their running totals are exported. For gauge
+	// metrics, the last value within the window is exported.
+	// Filters, failure metric, export_as_gauge and export_percentiles work as
+	// usual; the latter two are applied on the rolled up metrics.
+	AggregationWindowSec *int32 `protobuf:"varint,21,opt,name=aggregation_window_sec,json=aggregationWindowSec" json:"aggregation_window_sec,omitempty"`
+	// Reconnect behavior for the surfacers that push metrics to remote
+	// backends: STACKDRIVER, CLOUDWATCH, POSTGRES and PROMETHEUS_REMOTE_WRITE.
+	// If a write to the backend fails, backend is considered unavailable and
+	// writes are retried after an exponentially growing backoff (with jitter),
+	// starting with the initial backoff and capped at the max backoff. While
+	// backing off, up to reconnect_buffer_size writes (batches of metrics for
+	// STACKDRIVER, CLOUDWATCH and PROMETHEUS_REMOTE_WRITE, EventMetrics for
+	// POSTGRES) are buffered, and newer ones are dropped. Buffered writes are flushed once the backend is available again.
+	// Writes rejected by the backend as invalid are dropped right away, as
+	// retrying them won't help. Number of dropped writes is exported as the
+	// "dropped" metric, with the labels probe="sysvars" and
+	// surfacer=<surfacer name>.
+	ReconnectInitialBackoffMsec *int32 `protobuf:"varint,23,opt,name=reconnect_initial_backoff_msec,json=reconnectInitialBackoffMsec,def=1000" json:"reconnect_initial_backoff_msec,omitempty"`
+	ReconnectMaxBackoffMsec     *int32 `protobuf:"varint,24,opt,name=reconnect_max_backoff_msec,json=reconnectMaxBackoffMsec,def=60000" json:"reconnect_max_backoff_msec,omitempty"`
+	ReconnectBufferSize         *int32 `protobuf:"varint,25,opt,name=reconnect_buffer_size,json=reconnectBufferSize,def=100" json:"reconnect_buffer_size,omitempty"`
+	// Max number of unique label sets (series) per metric. If set, once a
+	// metric reaches this many series, metrics for the new label sets are
+	// dropped (and logged, at most once a minute), while the already seen
+	// series continue to be exported. This protects the monitoring systems
+	// from a runaway cardinality, e.g. because of a misconfigured probe.
+	// Note that series are tracked for the lifetime of the process.
+	// Each key of a map metric (e.g. resp_code) counts as a separate series.
+	// Number of dropped metrics is exported as the
+	// "cardinality_dropped_metrics" metric, with the labels probe="sysvars" and
+	// surfacer=<surfacer name>.
+	MaxCardinality *int32 `protobuf:"varint,26,opt,name=max_cardinality,json=maxCardinality" json:"max_cardinality,omitempty"`
 	// Matching surfacer specific configuration (one for each type in the above
 	// enum)
 	//
@@ -227,12 +303,18 @@ type SurfacerDef struct {
 	//	*SurfacerDef_PubsubSurfacer
 	//	*SurfacerDef_CloudwatchSurfacer
 	//	*SurfacerDef_DatadogSurfacer
+	//	*SurfacerDef_OtelSurfacer
+	//	*SurfacerDef_RedisSurfacer
+	//	*SurfacerDef_PrometheusRemoteWriteSurfacer
 	Surfacer isSurfacerDef_Surfacer `protobuf_oneof:"surfacer"`
 }
 
 // Default values for SurfacerDef fields.
 const (
-	Default_SurfacerDef_MetricsBufferSize = int64(10000)
+	Default_SurfacerDef_MetricsBufferSize           = int64(10000)
+	Default_SurfacerDef_ReconnectInitialBackoffMsec = int32(1000)
+	Default_SurfacerDef_ReconnectMaxBackoffMsec     = int32(60000)
+	Default_SurfacerDef_ReconnectBufferSize         = int32(100)
 )
 
 func (x *SurfacerDef) Reset() {
@@ -330,6 +412,62 @@ func (x *SurfacerDef) GetExportAsGauge() bool {
 	return false
 }
 
+func (x *SurfacerDef) GetExportPercentiles() []float64 {
+	if x != nil {
+		return x.ExportPercentiles
+	}
+	return nil
+}
+
+func (x *SurfacerDef) GetAllowMetrics() []string {
+	if x != nil {
+		return x.AllowMetrics
+	}
+	return nil
+}
+
+func (x *SurfacerDef) GetDenyMetrics() []string {
+	if x != nil {
+		return x.DenyMetrics
+	}
+	return nil
+}
+
+func (x *SurfacerDef) GetAggregationWindowSec() int32 {
+	if x != nil && x.AggregationWindowSec != nil {
+		return *x.AggregationWindowSec
+	}
+	return 0
+}
+
+func (x *SurfacerDef) GetReconnectInitialBackoffMsec() int32 {
+	if x != nil && x.ReconnectInitialBackoffMsec != nil {
+		return *x.ReconnectInitialBackoffMsec
+	}
+	return Default_SurfacerDef_ReconnectInitialBackoffMsec
+}
+
+func (x *SurfacerDef) GetReconnectMaxBackoffMsec() int32 {
+	if x != nil && x.ReconnectMaxBackoffMsec != nil {
+		return *x.ReconnectMaxBackoffMsec
+	}
+	return Default_SurfacerDef_ReconnectMaxBackoffMsec
+}
+
+func (x *SurfacerDef) GetReconnectBufferSize() int32 {
+	if x != nil && x.ReconnectBufferSize != nil {
+		return *x.ReconnectBufferSize
+	}
+	return Default_SurfacerDef_ReconnectBufferSize
+}
+
+func (x *SurfacerDef) GetMaxCardinality() int32 {
+	if x != nil && x.MaxCardinality != nil {
+		return *x.MaxCardinality
+	}
+	return 0
+}
+
 func (m *SurfacerDef) GetSurfacer() isSurfacerDef_Surfacer {
 	if m != nil {
 		return m.Surfacer
@@ -386,6 +524,27 @@ func (x *SurfacerDef) GetDatadogSurfacer() *proto6.SurfacerConf {
 	return nil
 }
 
+func (x *SurfacerDef) GetOtelSurfacer() *proto7.SurfacerConf {
+	if x, ok := x.GetSurfacer().(*SurfacerDef_OtelSurfacer); ok {
+		return x.OtelSurfacer
+	}
+	return nil
+}
+
+func (x *SurfacerDef) GetRedisSurfacer() *proto8.SurfacerConf {
+	if x, ok := x.GetSurfacer().(*SurfacerDef_RedisSurfacer); ok {
+		return x.RedisSurfacer
+	}
+	return nil
+}
+
+func (x *SurfacerDef) GetPrometheusRemoteWriteSurfacer() *proto9.SurfacerConf {
+	if x, ok := x.GetSurfacer().(*SurfacerDef_PrometheusRemoteWriteSurfacer); ok {
+		return x.PrometheusRemoteWriteSurfacer
+	}
+	return nil
+}
+
 type isSurfacerDef_Surfacer interface {
 	isSurfacerDef_Surfacer()
 }
@@ -418,6 +577,18 @@ type SurfacerDef_DatadogSurfacer struct {
 	DatadogSurfacer *proto6.SurfacerConf `protobuf:"bytes,16,opt,name=datadog_surfacer,json=datadogSurfacer,oneof"`
 }
 
+type SurfacerDef_OtelSurfacer struct {
+	OtelSurfacer *proto7.SurfacerConf `protobuf:"bytes,20,opt,name=otel_surfacer,json=otelSurfacer,oneof"`
+}
+
+type SurfacerDef_RedisSurfacer struct {
+	RedisSurfacer *proto8.SurfacerConf `protobuf:"bytes,22,opt,name=redis_surfacer,json=redisSurfacer,oneof"`
+}
+
+type SurfacerDef_PrometheusRemoteWriteSurfacer struct {
+	PrometheusRemoteWriteSurfacer *proto9.SurfacerConf `protobuf:"bytes,27,opt,name=prometheus_remote_write_surfacer,json=prometheusRemoteWriteSurfacer,oneof"`
+}
+
 func (*SurfacerDef_PrometheusSurfacer) isSurfacerDef_Surfacer() {}
 
 func (*SurfacerDef_StackdriverSurfacer) isSurfacerDef_Surfacer() {}
@@ -432,6 +603,12 @@ func (*SurfacerDef_CloudwatchSurfacer) isSurfacerDef_Surfacer() {}
 
 func (*SurfacerDef_DatadogSurfacer) isSurfacerDef_Surfacer() {}
 
+func (*SurfacerDef_OtelSurfacer) isSurfacerDef_Surfacer() {}
+
+func (*SurfacerDef_RedisSurfacer) isSurfacerDef_Surfacer() {}
+
+func (*SurfacerDef_PrometheusRemoteWriteSurfacer) isSurfacerDef_Surfacer() {}
+
 var File_github_com_cloudprober_cloudprober_surfacers_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_rawDesc = []byte{
@@ -454,114 +631,175 @@ var file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_rawDesc
 	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
 	0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x66, 0x69, 0x6c, 0x65, 0x2f,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x1a, 0x48, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x74, 0x6f, 0x1a, 0x44, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
 	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
 	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73,
-	0x2f, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
-	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x4a, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x6d, 0x65,
-	0x74, 0x68, 0x65, 0x75, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
-	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2f, 0x6f, 0x74, 0x65, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x48, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
 	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
 	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72,
-	0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2f, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x1a, 0x4b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
-	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x73,
-	0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x35, 0x0a,
-	0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03,
-	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
-	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x22, 0x98, 0x09, 0x0a, 0x0b, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
-	0x72, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
-	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x54, 0x79,
-	0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x35, 0x0a, 0x13, 0x6d, 0x65, 0x74, 0x72,
-	0x69, 0x63, 0x73, 0x5f, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x03, 0x3a, 0x05, 0x31, 0x30, 0x30, 0x30, 0x30, 0x52, 0x11, 0x6d, 0x65,
-	0x74, 0x72, 0x69, 0x63, 0x73, 0x42, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12,
-	0x5a, 0x0a, 0x18, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
-	0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x46, 0x69,
-	0x6c, 0x74, 0x65, 0x72, 0x52, 0x15, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x5c, 0x0a, 0x19, 0x69,
-	0x67, 0x6e, 0x6f, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x77, 0x69,
-	0x74, 0x68, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21,
-	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72,
-	0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x46, 0x69, 0x6c, 0x74, 0x65,
-	0x72, 0x52, 0x16, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
-	0x57, 0x69, 0x74, 0x68, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x35, 0x0a, 0x17, 0x61, 0x6c, 0x6c,
-	0x6f, 0x77, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x61, 0x6c, 0x6c, 0x6f,
-	0x77, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4e, 0x61, 0x6d, 0x65,
-	0x12, 0x37, 0x0a, 0x18, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x15, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63,
-	0x73, 0x57, 0x69, 0x74, 0x68, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x61, 0x64, 0x64,
-	0x5f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x18,
-	0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x61, 0x64, 0x64, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72,
-	0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x26, 0x0a, 0x0f, 0x65, 0x78, 0x70, 0x6f, 0x72,
-	0x74, 0x5f, 0x61, 0x73, 0x5f, 0x67, 0x61, 0x75, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x0d, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x41, 0x73, 0x47, 0x61, 0x75, 0x67, 0x65, 0x12,
-	0x60, 0x0a, 0x13, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x5f, 0x73, 0x75,
-	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63,
+	0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65, 0x73, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x4a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73,
+	0x2f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x4a,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x72, 0x6f,
+	0x6d, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x46, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73,
+	0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73,
+	0x2f, 0x72, 0x65, 0x64, 0x69, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x4b, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69,
+	0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x35, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x46,
+	0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xd3, 0x0e,
+	0x0a, 0x0b, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x1a, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x35, 0x0a, 0x13, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x62, 0x75, 0x66,
+	0x66, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x3a, 0x05,
+	0x31, 0x30, 0x30, 0x30, 0x30, 0x52, 0x11, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x42, 0x75,
+	0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x5a, 0x0a, 0x18, 0x61, 0x6c, 0x6c, 0x6f,
+	0x77, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x15, 0x61,
+	0x6c, 0x6c, 0x6f, 0x77, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4c,
+	0x61, 0x62, 0x65, 0x6c, 0x12, 0x5c, 0x0a, 0x19, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x5f, 0x6d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6c, 0x61, 0x62, 0x65,
+	0x6c, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x4c,
+	0x61, 0x62, 0x65, 0x6c, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x16, 0x69, 0x67, 0x6e, 0x6f,
+	0x72, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4c, 0x61, 0x62,
+	0x65, 0x6c, 0x12, 0x35, 0x0a, 0x17, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x6d, 0x65, 0x74, 0x72,
+	0x69, 0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x14, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63,
+	0x73, 0x57, 0x69, 0x74, 0x68, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x37, 0x0a, 0x18, 0x69, 0x67, 0x6e,
+	0x6f, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x77, 0x69, 0x74, 0x68,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x69, 0x67, 0x6e,
+	0x6f, 0x72, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x57, 0x69, 0x74, 0x68, 0x4e, 0x61,
+	0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x61, 0x64, 0x64, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72,
+	0x65, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10,
+	0x61, 0x64, 0x64, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63,
+	0x12, 0x26, 0x0a, 0x0f, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x61, 0x73, 0x5f, 0x67, 0x61,
+	0x75, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x6f, 0x72,
+	0x74, 0x41, 0x73, 0x47, 0x61, 0x75, 0x67, 0x65, 0x12, 0x2d, 0x0a, 0x12, 0x65, 0x78, 0x70, 0x6f,
+	0x72, 0x74, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x11,
+	0x20, 0x03, 0x28, 0x01, 0x52, 0x11, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x50, 0x65, 0x72, 0x63,
+	0x65, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x6c, 0x6c, 0x6f, 0x77,
+	0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x12, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c,
+	0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x21, 0x0a, 0x0c,
+	0x64, 0x65, 0x6e, 0x79, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x13, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x6e, 0x79, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12,
+	0x34, 0x0a, 0x16, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x77,
+	0x69, 0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x15, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x14, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x69, 0x6e, 0x64,
+	0x6f, 0x77, 0x53, 0x65, 0x63, 0x12, 0x49, 0x0a, 0x1e, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x5f, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x62, 0x61, 0x63, 0x6b, 0x6f,
+	0x66, 0x66, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x17, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x04, 0x31,
+	0x30, 0x30, 0x30, 0x52, 0x1b, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x49, 0x6e,
+	0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66, 0x4d, 0x73, 0x65, 0x63,
+	0x12, 0x42, 0x0a, 0x1a, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x5f, 0x6d, 0x61,
+	0x78, 0x5f, 0x62, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x18,
+	0x20, 0x01, 0x28, 0x05, 0x3a, 0x05, 0x36, 0x30, 0x30, 0x30, 0x30, 0x52, 0x17, 0x72, 0x65, 0x63,
+	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x4d, 0x61, 0x78, 0x42, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66,
+	0x4d, 0x73, 0x65, 0x63, 0x12, 0x37, 0x0a, 0x15, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
+	0x74, 0x5f, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x19, 0x20,
+	0x01, 0x28, 0x05, 0x3a, 0x03, 0x31, 0x30, 0x30, 0x52, 0x13, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x42, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x27, 0x0a,
+	0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x18, 0x1a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x6d, 0x61, 0x78, 0x43, 0x61, 0x72, 0x64, 0x69,
+	0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x60, 0x0a, 0x13, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74,
+	0x68, 0x65, 0x75, 0x73, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65,
+	0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x6d, 0x65,
+	0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f,
+	0x6e, 0x66, 0x48, 0x00, 0x52, 0x12, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73,
+	0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x63, 0x0a, 0x14, 0x73, 0x74, 0x61, 0x63,
+	0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x74,
+	0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
+	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x13, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64,
+	0x72, 0x69, 0x76, 0x65, 0x72, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x4e, 0x0a,
+	0x0d, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x66, 0x69, 0x6c, 0x65,
+	0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52,
+	0x0c, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x5a, 0x0a,
+	0x11, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65, 0x73, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63,
+	0x65, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2b, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e,
+	0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65, 0x73, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x10, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65,
+	0x73, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x54, 0x0a, 0x0f, 0x70, 0x75, 0x62,
+	0x73, 0x75, 0x62, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x29, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62,
+	0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52,
+	0x0e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12,
+	0x60, 0x0a, 0x13, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x73, 0x75,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63,
 	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61,
-	0x63, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53,
-	0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x12, 0x70,
-	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
-	0x72, 0x12, 0x63, 0x0a, 0x14, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72,
-	0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x2e, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75,
-	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76,
-	0x65, 0x72, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48,
-	0x00, 0x52, 0x13, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x53, 0x75,
-	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x4e, 0x0a, 0x0d, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x73,
-	0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e,
-	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66,
-	0x61, 0x63, 0x65, 0x72, 0x2e, 0x66, 0x69, 0x6c, 0x65, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
-	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x0c, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x75,
-	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x5a, 0x0a, 0x11, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72,
-	0x65, 0x73, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x2b, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
-	0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65,
-	0x73, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00,
-	0x52, 0x10, 0x70, 0x6f, 0x73, 0x74, 0x67, 0x72, 0x65, 0x73, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
-	0x65, 0x72, 0x12, 0x54, 0x0a, 0x0f, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x5f, 0x73, 0x75, 0x72,
-	0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x63, 0x6c,
+	0x63, 0x65, 0x72, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x53,
+	0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x12, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x12, 0x57, 0x0a, 0x10, 0x64, 0x61, 0x74, 0x61, 0x64, 0x6f, 0x67, 0x5f, 0x73, 0x75, 0x72,
+	0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x63, 0x6c,
 	0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63,
-	0x65, 0x72, 0x2e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
-	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x0e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62,
-	0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x60, 0x0a, 0x13, 0x63, 0x6c, 0x6f, 0x75,
-	0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18,
-	0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f,
-	0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x63, 0x6c, 0x6f,
-	0x75, 0x64, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72,
-	0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x12, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x77, 0x61, 0x74,
-	0x63, 0x68, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x57, 0x0a, 0x10, 0x64, 0x61,
-	0x74, 0x61, 0x64, 0x6f, 0x67, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x10,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x64, 0x61, 0x74, 0x61,
-	0x64, 0x6f, 0x67, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66,
-	0x48, 0x00, 0x52, 0x0f, 0x64, 0x61, 0x74, 0x61, 0x64, 0x6f, 0x67, 0x53, 0x75, 0x72, 0x66, 0x61,
-	0x63, 0x65, 0x72, 0x42, 0x0a, 0x0a, 0x08, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2a,
-	0x84, 0x01, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45,
-	0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x52, 0x4f, 0x4d, 0x45, 0x54, 0x48, 0x45, 0x55, 0x53,
-	0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x41, 0x43, 0x4b, 0x44, 0x52, 0x49, 0x56, 0x45,
-	0x52, 0x10, 0x02, 0x12, 0x08, 0x0a, 0x04, 0x46, 0x49, 0x4c, 0x45, 0x10, 0x03, 0x12, 0x0c, 0x0a,
-	0x08, 0x50, 0x4f, 0x53, 0x54, 0x47, 0x52, 0x45, 0x53, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06, 0x50,
-	0x55, 0x42, 0x53, 0x55, 0x42, 0x10, 0x05, 0x12, 0x0e, 0x0a, 0x0a, 0x43, 0x4c, 0x4f, 0x55, 0x44,
-	0x57, 0x41, 0x54, 0x43, 0x48, 0x10, 0x06, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x41, 0x54, 0x41, 0x44,
-	0x4f, 0x47, 0x10, 0x07, 0x12, 0x10, 0x0a, 0x0c, 0x55, 0x53, 0x45, 0x52, 0x5f, 0x44, 0x45, 0x46,
-	0x49, 0x4e, 0x45, 0x44, 0x10, 0x63, 0x42, 0x34, 0x5a, 0x32, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
-	0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72,
-	0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x65, 0x72, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x64, 0x6f, 0x67, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x0f, 0x64, 0x61, 0x74, 0x61, 0x64,
+	0x6f, 0x67, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x4e, 0x0a, 0x0d, 0x6f, 0x74,
+	0x65, 0x6c, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x14, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x27, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e,
+	0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x6f, 0x74, 0x65, 0x6c, 0x2e, 0x53, 0x75,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x0c, 0x6f, 0x74,
+	0x65, 0x6c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x51, 0x0a, 0x0e, 0x72, 0x65,
+	0x64, 0x69, 0x73, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x18, 0x16, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x28, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72,
+	0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x72, 0x65, 0x64, 0x69, 0x73, 0x2e,
+	0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x0d,
+	0x72, 0x65, 0x64, 0x69, 0x73, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x12, 0x78, 0x0a,
+	0x20, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x5f, 0x72, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x5f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x72, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70,
+	0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x6d, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x2e, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63,
+	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x48, 0x00, 0x52, 0x1d, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74,
+	0x68, 0x65, 0x75, 0x73, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x57, 0x72, 0x69, 0x74, 0x65, 0x53,
+	0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x42, 0x0a, 0x0a, 0x08, 0x73, 0x75, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x72, 0x2a, 0xb6, 0x01, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x08, 0x0a, 0x04,
+	0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x52, 0x4f, 0x4d, 0x45, 0x54,
+	0x48, 0x45, 0x55, 0x53, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x41, 0x43, 0x4b, 0x44,
+	0x52, 0x49, 0x56, 0x45, 0x52, 0x10, 0x02, 0x12, 0x08, 0x0a, 0x04, 0x46, 0x49, 0x4c, 0x45, 0x10,
+	0x03, 0x12, 0x0c, 0x0a, 0x08, 0x50, 0x4f, 0x53, 0x54, 0x47, 0x52, 0x45, 0x53, 0x10, 0x04, 0x12,
+	0x0a, 0x0a, 0x06, 0x50, 0x55, 0x42, 0x53, 0x55, 0x42, 0x10, 0x05, 0x12, 0x0e, 0x0a, 0x0a, 0x43,
+	0x4c, 0x4f, 0x55, 0x44, 0x57, 0x41, 0x54, 0x43, 0x48, 0x10, 0x06, 0x12, 0x0b, 0x0a, 0x07, 0x44,
+	0x41, 0x54, 0x41, 0x44, 0x4f, 0x47, 0x10, 0x07, 0x12, 0x08, 0x0a, 0x04, 0x4f, 0x54, 0x45, 0x4c,
+	0x10, 0x08, 0x12, 0x09, 0x0a, 0x05, 0x52, 0x45, 0x44, 0x49, 0x53, 0x10, 0x09, 0x12, 0x1b, 0x0a,
+	0x17, 0x50, 0x52, 0x4f, 0x4d, 0x45, 0x54, 0x48, 0x45, 0x55, 0x53, 0x5f, 0x52, 0x45, 0x4d, 0x4f,
+	0x54, 0x45, 0x5f, 0x57, 0x52, 0x49, 0x54, 0x45, 0x10, 0x0a, 0x12, 0x10, 0x0a, 0x0c, 0x55, 0x53,
+	0x45, 0x52, 0x5f, 0x44, 0x45, 0x46, 0x49, 0x4e, 0x45, 0x44, 0x10, 0x63, 0x42, 0x34, 0x5a, 0x32,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f,
 }
 
 var (
@@ -589,6 +827,9 @@ var file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_goTypes
 	(*proto4.SurfacerConf)(nil), // 7: cloudprober.surfacer.pubsub.SurfacerConf
 	(*proto5.SurfacerConf)(nil), // 8: cloudprober.surfacer.cloudwatch.SurfacerConf
 	(*proto6.SurfacerConf)(nil), // 9: cloudprober.surfacer.datadog.SurfacerConf
+	(*proto7.SurfacerConf)(nil), // 10: cloudprober.surfacer.otel.SurfacerConf
+	(*proto8.SurfacerConf)(nil), // 11: cloudprober.surfacer.redis.SurfacerConf
+	(*proto9.SurfacerConf)(nil), // 12: cloudprober.surfacer.promremote.SurfacerConf
 }
 var file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_depIdxs = []int32{
 	0,  // 0: cloudprober.surfacer.SurfacerDef.type:type_name -> cloudprober.surfacer.Type
@@ -601,11 +842,14 @@ var file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_depIdxs
 	7,  // 7: cloudprober.surfacer.SurfacerDef.pubsub_surfacer:type_name -> cloudprober.surfacer.pubsub.SurfacerConf
 	8,  // 8: cloudprober.surfacer.SurfacerDef.cloudwatch_surfacer:type_name -> cloudprober.surfacer.cloudwatch.SurfacerConf
 	9,  // 9: cloudprober.surfacer.SurfacerDef.datadog_surfacer:type_name -> cloudprober.surfacer.datadog.SurfacerConf
-	10, // [10:10] is the sub-list for method output_type
-	10, // [10:10] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	10, // 10: cloudprober.surfacer.SurfacerDef.otel_surfacer:type_name -> cloudprober.surfacer.otel.SurfacerConf
+	11, // 11: cloudprober.surfacer.SurfacerDef.redis_surfacer:type_name -> cloudprober.surfacer.redis.SurfacerConf
+	12, // 12: cloudprober.surfacer.SurfacerDef.prometheus_remote_write_surfacer:type_name -> cloudprober.surfacer.promremote.SurfacerConf
+	13, // [13:13] is the sub-list for method output_type
+	13, // [13:13] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_init() }
@@ -647,6 +891,9 @@ func file_github_com_cloudprober_cloudprober_surfacers_proto_config_proto_init()
 		(*SurfacerDef_PubsubSurfacer)(nil),
 		(*SurfacerDef_CloudwatchSurfacer)(nil),
 		(*SurfacerDef_DatadogSurfacer)(nil),
+		(*SurfacerDef_OtelSurfacer)(nil),
+		(*SurfacerDef_RedisSurfacer)(nil),
+		(*SurfacerDef_PrometheusRemoteWriteSurfacer)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -184,9 +184,9 @@ func (s *Surfacer) init(ctx context.Context) error {
 	return nil
 }
 
-// close closes the input channel, waits for input processing to finish,
+// Close closes the input channel, waits for input processing to finish,
 // and closes the compression buffer if open.
-func (s *Surfacer) close() {
+func (s *Surfacer) Close() {
 	close(s.inChan)
 	s.processInputWg.Wait()
 
@@ -152,7 +152,7 @@ func createSurfacerAndVerify(t *testing.T, srv *testServer, compression bool) {
 	}
 
 	// Closing the surfacer waits for inputs to be processed.
-	s.close()
+	s.Close()
 
 	srv.wg.Wait()
 
@@ -0,0 +1,230 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.3.0
+// source: github.com/cloudprober/cloudprober/surfacers/redis/proto/config.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Redis surfacer config. Redis surfacer writes the latest value of each
+// metric to a Redis key, overwriting the previous value, e.g. for status
+// dashboards. Keys are of the form:
+//
+//	<key_prefix>:<probe>:<dst>:<metric>
+//
+// EventMetrics labels other than "ptype", "probe" and "dst" are appended to
+// the key as <label>=<value>, e.g.
+//
+//	cloudprober:http_google:www.google.com:latency:region=us
+//
+// Values are metrics' string representation, e.g. "1532" for numerical
+// metrics, and "map:code,200:15,500:2" for map metrics.
+type SurfacerConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Redis server address.
+	Address *string `protobuf:"bytes,1,opt,name=address,def=localhost:6379" json:"address,omitempty"`
+	// Redis password, if authentication is enabled.
+	Password *string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
+	// Redis database number.
+	Db *int32 `protobuf:"varint,3,opt,name=db,def=0" json:"db,omitempty"`
+	// Prefix for the keys.
+	KeyPrefix *string `protobuf:"bytes,4,opt,name=key_prefix,json=keyPrefix,def=cloudprober" json:"key_prefix,omitempty"`
+	// Keys' TTL, so that metrics that are not updated anymore (e.g. removed
+	// targets) disappear. Set it to 0 to disable expiration.
+	TtlSec *int32 `protobuf:"varint,5,opt,name=ttl_sec,json=ttlSec,def=300" json:"ttl_sec,omitempty"`
+	// Timeout for connecting to Redis and for each write. Metrics are dropped,
+	// not retried, if Redis is unreachable.
+	TimeoutMsec *int32 `protobuf:"varint,6,opt,name=timeout_msec,json=timeoutMsec,def=1000" json:"timeout_msec,omitempty"`
+}
+
+// Default values for SurfacerConf fields.
+const (
+	Default_SurfacerConf_Address     = string("localhost:6379")
+	Default_SurfacerConf_Db          = int32(0)
+	Default_SurfacerConf_KeyPrefix   = string("cloudprober")
+	Default_SurfacerConf_TtlSec      = int32(300)
+	Default_SurfacerConf_TimeoutMsec = int32(1000)
+)
+
+func (x *SurfacerConf) Reset() {
+	*x = SurfacerConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SurfacerConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurfacerConf) ProtoMessage() {}
+
+func (x *SurfacerConf) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurfacerConf.ProtoReflect.Descriptor instead.
+func (*SurfacerConf) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SurfacerConf) GetAddress() string {
+	if x != nil && x.Address != nil {
+		return *x.Address
+	}
+	return Default_SurfacerConf_Address
+}
+
+func (x *SurfacerConf) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *SurfacerConf) GetDb() int32 {
+	if x != nil && x.Db != nil {
+		return *x.Db
+	}
+	return Default_SurfacerConf_Db
+}
+
+func (x *SurfacerConf) GetKeyPrefix() string {
+	if x != nil && x.KeyPrefix != nil {
+		return *x.KeyPrefix
+	}
+	return Default_SurfacerConf_KeyPrefix
+}
+
+func (x *SurfacerConf) GetTtlSec() int32 {
+	if x != nil && x.TtlSec != nil {
+		return *x.TtlSec
+	}
+	return Default_SurfacerConf_TtlSec
+}
+
+func (x *SurfacerConf) GetTimeoutMsec() int32 {
+	if x != nil && x.TimeoutMsec != nil {
+		return *x.TimeoutMsec
+	}
+	return Default_SurfacerConf_TimeoutMsec
+}
+
+var File_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto protoreflect.FileDescriptor
+
+var file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDesc = []byte{
+	0x0a, 0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x72,
+	0x65, 0x64, 0x69, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1a, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x2e, 0x72, 0x65,
+	0x64, 0x69, 0x73, 0x22, 0xda, 0x01, 0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72,
+	0x43, 0x6f, 0x6e, 0x66, 0x12, 0x28, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x3a, 0x0e, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x68, 0x6f, 0x73, 0x74,
+	0x3a, 0x36, 0x33, 0x37, 0x39, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1a,
+	0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x11, 0x0a, 0x02, 0x64, 0x62,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x01, 0x30, 0x52, 0x02, 0x64, 0x62, 0x12, 0x2a, 0x0a,
+	0x0a, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x3a, 0x0b, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x52, 0x09,
+	0x6b, 0x65, 0x79, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x1c, 0x0a, 0x07, 0x74, 0x74, 0x6c,
+	0x5f, 0x73, 0x65, 0x63, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x03, 0x33, 0x30, 0x30, 0x52,
+	0x06, 0x74, 0x74, 0x6c, 0x53, 0x65, 0x63, 0x12, 0x27, 0x0a, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x5f, 0x6d, 0x73, 0x65, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x3a, 0x04, 0x31,
+	0x30, 0x30, 0x30, 0x52, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x65, 0x63,
+	0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73,
+	0x2f, 0x72, 0x65, 0x64, 0x69, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+}
+
+var (
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescOnce sync.Once
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescData = file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDesc
+)
+
+func file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescGZIP() []byte {
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescOnce.Do(func() {
+		file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescData)
+	})
+	return file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDescData
+}
+
+var file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_goTypes = []interface{}{
+	(*SurfacerConf)(nil), // 0: cloudprober.surfacer.redis.SurfacerConf
+}
+var file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_init() }
+func file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_init() {
+	if File_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SurfacerConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_goTypes,
+		DependencyIndexes: file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_depIdxs,
+		MessageInfos:      file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_msgTypes,
+	}.Build()
+	File_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto = out.File
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_rawDesc = nil
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_goTypes = nil
+	file_github_com_cloudprober_cloudprober_surfacers_redis_proto_config_proto_depIdxs = nil
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package redis implements a surfacer that writes the latest value of each
+metric to Redis keys, with a TTL. It's meant for lightweight status
+dashboards, not for storing time series.
+
+To use this surfacer, add a stanza similar to the following to your
+cloudprober config:
+
+	surfacer {
+	  type: REDIS
+	  redis_surfacer {
+	    address: "localhost:6379"
+	    ttl_sec: 300
+	  }
+	}
+
+Each EventMetrics is written using a single pipelined request. If Redis is
+unreachable, metrics are dropped, instead of being queued up. Number of dropped
+EventMetrics is exported as the surfacer's "dropped" metric.
+*/
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	configpb "github.com/cloudprober/cloudprober/surfacers/redis/proto"
+)
+
+// reconnectInterval is the minimum interval between connection attempts, so
+// that an unreachable Redis server doesn't hold up the write loop.
+var reconnectInterval = 5 * time.Second
+
+// Labels that are part of every key.
+var keyLabels = map[string]bool{"ptype": true, "probe": true, "dst": true}
+
+// RedisSurfacer implements a Redis surfacer.
+type RedisSurfacer struct {
+	c         *configpb.SurfacerConf
+	opts      *options.Options
+	timeout   time.Duration
+	writeChan chan *metrics.EventMetrics
+	writeDone chan struct{} // Closed when the write loop exits.
+	l         *logger.Logger
+
+	// Connection state, accessed only from the write loop.
+	conn     net.Conn
+	reader   *bufio.Reader
+	lastDial time.Time
+
+	droppedEMs int64 // Accessed atomically.
+}
+
+// key returns the Redis key for the given metric.
+func (s *RedisSurfacer) key(em *metrics.EventMetrics, metricName string) string {
+	var parts []string
+	if s.c.GetKeyPrefix() != "" {
+		parts = append(parts, s.c.GetKeyPrefix())
+	}
+	parts = append(parts, em.Label("probe"), em.Label("dst"), metricName)
+	for _, k := range em.LabelsKeys() {
+		if !keyLabels[k] {
+			parts = append(parts, k+"="+em.Label(k))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// commands returns the commands to write the EventMetrics to Redis.
+func (s *RedisSurfacer) commands(em *metrics.EventMetrics) [][]string {
+	var cmds [][]string
+	for _, name := range em.MetricsKeys() {
+		if !s.opts.AllowMetric(name) {
+			continue
+		}
+		cmd := []string{"SET", s.key(em, name), em.Metric(name).String()}
+		if s.c.GetTtlSec() > 0 {
+			cmd = append(cmd, "EX", strconv.Itoa(int(s.c.GetTtlSec())))
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// do sends the commands in a single pipelined request, and reads their
+// replies. It returns the first error encountered.
+func (s *RedisSurfacer) do(cmds [][]string) error {
+	var buf bytes.Buffer
+	for _, cmd := range cmds {
+		writeCommand(&buf, cmd)
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	// Read all replies, even if some commands failed, to keep the connection
+	// in sync.
+	var firstErr error
+	for range cmds {
+		err := readReply(s.reader)
+		if _, ok := err.(redisError); err != nil && !ok {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *RedisSurfacer) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// connect connects to the Redis server, if not connected already.
+func (s *RedisSurfacer) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	if time.Since(s.lastDial) < reconnectInterval {
+		return errors.New("not connected, waiting to reconnect")
+	}
+	s.lastDial = time.Now()
+
+	conn, err := net.DialTimeout("tcp", s.c.GetAddress(), s.timeout)
+	if err != nil {
+		return err
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if s.c.GetPassword() != "" {
+		setup = append(setup, []string{"AUTH", s.c.GetPassword()})
+	}
+	if s.c.GetDb() != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(int(s.c.GetDb()))})
+	}
+	if len(setup) > 0 {
+		if err := s.do(setup); err != nil {
+			s.closeConn()
+			return fmt.Errorf("error setting up the connection: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisSurfacer) dropped(err error) {
+	n := atomic.AddInt64(&s.droppedEMs, 1)
+	s.l.Warningf("Error writing metrics to Redis (%s), dropped EventMetrics so far: %d, err: %v", s.c.GetAddress(), n, err)
+}
+
+// writeEM writes the EventMetrics to Redis.
+func (s *RedisSurfacer) writeEM(em *metrics.EventMetrics) {
+	if !s.opts.AllowEventMetrics(em) {
+		return
+	}
+	cmds := s.commands(em)
+	if len(cmds) == 0 {
+		return
+	}
+
+	if err := s.connect(); err != nil {
+		s.dropped(err)
+		return
+	}
+	if err := s.do(cmds); err != nil {
+		if _, ok := err.(redisError); !ok {
+			s.closeConn()
+		}
+		s.dropped(err)
+	}
+}
+
+func (s *RedisSurfacer) processIncomingMetrics(ctx context.Context) {
+	defer close(s.writeDone)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.l.Infof("Context canceled, stopping the surfacer write loop")
+			return
+		case em, ok := <-s.writeChan:
+			if !ok {
+				return
+			}
+			s.writeEM(em)
+		}
+	}
+}
+
+// New creates a new instance of a Redis surfacer, based on the config passed
+// in. It then hands off to a goroutine to write metrics.
+func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Options, l *logger.Logger) (*RedisSurfacer, error) {
+	if config.GetTtlSec() < 0 || config.GetTimeoutMsec() <= 0 {
+		return nil, fmt.Errorf("redis surfacer: ttl_sec (%d) cannot be negative and timeout_msec (%d) should be positive", config.GetTtlSec(), config.GetTimeoutMsec())
+	}
+
+	s := &RedisSurfacer{
+		c:         config,
+		opts:      opts,
+		timeout:   time.Duration(config.GetTimeoutMsec()) * time.Millisecond,
+		writeChan: make(chan *metrics.EventMetrics, opts.MetricsBufferSize),
+		writeDone: make(chan struct{}),
+		l:         l,
+	}
+
+	go s.processIncomingMetrics(ctx)
+
+	s.l.Infof("Initialized Redis surfacer, address: %s", config.GetAddress())
+	return s, nil
+}
+
+// Write queues the incoming data into a channel. This channel is watched by a
+// goroutine that actually writes the metrics to Redis.
+func (s *RedisSurfacer) Write(ctx context.Context, em *metrics.EventMetrics) {
+	select {
+	case s.writeChan <- em:
+	default:
+		s.dropped(errors.New("surfacer's write channel is full"))
+	}
+}
+
+// Close stops the write loop and closes the Redis connection.
+func (s *RedisSurfacer) Close() {
+	close(s.writeChan)
+	<-s.writeDone
+	s.closeConn()
+}
+
+// Dropped returns the number of EventMetrics dropped so far,
+// because of the Redis errors or a full write channel.
+func (s *RedisSurfacer) Dropped() int64 {
+	return atomic.LoadInt64(&s.droppedEMs)
+}
@@ -0,0 +1,362 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/options"
+	configpb "github.com/cloudprober/cloudprober/surfacers/redis/proto"
+	"github.com/golang/protobuf/proto"
+)
+
+type entry struct {
+	value string
+	ttl   int
+}
+
+// fakeRedis is an in-memory Redis server, supporting the AUTH, SELECT and SET
+// commands.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu    sync.Mutex
+	data  map[string]entry
+	cmds  []string // Names of the received commands.
+	conns []net.Conn
+}
+
+func startFakeRedis(t *testing.T, addr, password string) *fakeRedis {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Error starting fake Redis server: %v", err)
+	}
+	fr := &fakeRedis{
+		ln:       ln,
+		password: password,
+		data:     make(map[string]entry),
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fr.mu.Lock()
+			fr.conns = append(fr.conns, conn)
+			fr.mu.Unlock()
+			go fr.serve(conn)
+		}
+	}()
+	return fr
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var l int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &l); err != nil {
+			return nil, err
+		}
+		b := make([]byte, l+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:l])
+	}
+	return args, nil
+}
+
+func (fr *fakeRedis) handle(args []string) string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	fr.cmds = append(fr.cmds, args[0])
+	switch {
+	case args[0] == "AUTH" && len(args) == 2:
+		if args[1] != fr.password {
+			return "-ERR invalid password"
+		}
+		return "+OK"
+	case args[0] == "SELECT" && len(args) == 2:
+		return "+OK"
+	case args[0] == "SET" && len(args) == 3:
+		fr.data[args[1]] = entry{value: args[2]}
+		return "+OK"
+	case args[0] == "SET" && len(args) == 5 && args[3] == "EX":
+		ttl, _ := strconv.Atoi(args[4])
+		fr.data[args[1]] = entry{value: args[2], ttl: ttl}
+		return "+OK"
+	}
+	return "-ERR unknown command"
+}
+
+func (fr *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte(fr.handle(args) + "\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+func (fr *fakeRedis) close() {
+	fr.ln.Close()
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for _, conn := range fr.conns {
+		conn.Close()
+	}
+}
+
+func (fr *fakeRedis) get(key string) (entry, bool) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	e, ok := fr.data[key]
+	return e, ok
+}
+
+// testSurfacer returns a surfacer without the write loop, tests write the
+// metrics directly.
+func testSurfacer(c *configpb.SurfacerConf, opts *options.Options) *RedisSurfacer {
+	return &RedisSurfacer{
+		c:         c,
+		opts:      opts,
+		timeout:   time.Duration(c.GetTimeoutMsec()) * time.Millisecond,
+		writeChan: make(chan *metrics.EventMetrics, 1),
+	}
+}
+
+func testEM(total, success int64) *metrics.EventMetrics {
+	respCodes := metrics.NewMap("code", metrics.NewInt(0))
+	respCodes.IncKey("200")
+	return metrics.NewEventMetrics(time.Now()).
+		AddMetric("total", metrics.NewInt(total)).
+		AddMetric("success", metrics.NewInt(success)).
+		AddMetric("resp-code", respCodes).
+		AddLabel("ptype", "http").
+		AddLabel("probe", "p1").
+		AddLabel("dst", "t1")
+}
+
+func TestCommands(t *testing.T) {
+	em := testEM(5, 4).AddLabel("region", "us")
+
+	for _, test := range []struct {
+		desc string
+		c    *configpb.SurfacerConf
+		want [][]string
+	}{
+		{
+			desc: "default",
+			c:    &configpb.SurfacerConf{},
+			want: [][]string{
+				{"SET", "cloudprober:p1:t1:total:region=us", "5", "EX", "300"},
+				{"SET", "cloudprober:p1:t1:success:region=us", "4", "EX", "300"},
+				{"SET", "cloudprober:p1:t1:resp-code:region=us", "map:code,200:1", "EX", "300"},
+			},
+		},
+		{
+			desc: "no_prefix_no_ttl",
+			c: &configpb.SurfacerConf{
+				KeyPrefix: proto.String(""),
+				TtlSec:    proto.Int32(0),
+			},
+			want: [][]string{
+				{"SET", "p1:t1:total:region=us", "5"},
+				{"SET", "p1:t1:success:region=us", "4"},
+				{"SET", "p1:t1:resp-code:region=us", "map:code,200:1"},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := testSurfacer(test.c, nil)
+			if got := s.commands(em); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Got commands: %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteEM(t *testing.T) {
+	fr := startFakeRedis(t, "127.0.0.1:0", "secret")
+	defer fr.close()
+
+	s := testSurfacer(&configpb.SurfacerConf{
+		Address:  proto.String(fr.ln.Addr().String()),
+		Password: proto.String("secret"),
+		Db:       proto.Int32(2),
+		TtlSec:   proto.Int32(60),
+	}, nil)
+	defer s.closeConn()
+
+	s.writeEM(testEM(1, 1))
+	s.writeEM(testEM(2, 1))
+
+	// Latest values overwrite the previous ones.
+	for key, want := range map[string]entry{
+		"cloudprober:p1:t1:total":     {"2", 60},
+		"cloudprober:p1:t1:success":   {"1", 60},
+		"cloudprober:p1:t1:resp-code": {"map:code,200:1", 60},
+	} {
+		if got, ok := fr.get(key); !ok || got != want {
+			t.Errorf("Key %s: got %+v (exists: %v), want %+v", key, got, ok, want)
+		}
+	}
+
+	// Connection is set up once, and reused.
+	wantCmds := []string{"AUTH", "SELECT", "SET", "SET", "SET", "SET", "SET", "SET"}
+	if !reflect.DeepEqual(fr.cmds, wantCmds) {
+		t.Errorf("Got commands: %v, want: %v", fr.cmds, wantCmds)
+	}
+	if s.Dropped() != 0 {
+		t.Errorf("Got dropped EventMetrics: %d, want: 0", s.Dropped())
+	}
+}
+
+func TestWriteEMAuthError(t *testing.T) {
+	fr := startFakeRedis(t, "127.0.0.1:0", "secret")
+	defer fr.close()
+
+	s := testSurfacer(&configpb.SurfacerConf{
+		Address:  proto.String(fr.ln.Addr().String()),
+		Password: proto.String("wrong"),
+	}, nil)
+
+	s.writeEM(testEM(1, 1))
+	if s.Dropped() != 1 || s.conn != nil {
+		t.Errorf("Got dropped EventMetrics: %d, connected: %v, want: 1, false", s.Dropped(), s.conn != nil)
+	}
+	if len(fr.data) != 0 {
+		t.Errorf("Unexpected keys written: %v", fr.data)
+	}
+}
+
+func TestConnectionLoss(t *testing.T) {
+	defer func(d time.Duration) { reconnectInterval = d }(reconnectInterval)
+	reconnectInterval = 200 * time.Millisecond
+
+	fr := startFakeRedis(t, "127.0.0.1:0", "")
+	addr := fr.ln.Addr().String()
+
+	s := testSurfacer(&configpb.SurfacerConf{Address: proto.String(addr)}, nil)
+	defer s.closeConn()
+
+	s.writeEM(testEM(1, 1))
+	fr.close()
+
+	// First write fails on the broken connection, subsequent writes are
+	// dropped right away until it's time to reconnect.
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		s.writeEM(testEM(2, 2))
+	}
+	if s.Dropped() != 3 {
+		t.Errorf("Got dropped EventMetrics: %d, want: 3", s.Dropped())
+	}
+	if elapsed := time.Since(start); elapsed > reconnectInterval {
+		t.Errorf("Writes blocked for %v with Redis down", elapsed)
+	}
+
+	fr = startFakeRedis(t, addr, "")
+	defer fr.close()
+	time.Sleep(reconnectInterval)
+
+	s.writeEM(testEM(3, 3))
+	if got, _ := fr.get("cloudprober:p1:t1:total"); got.value != "3" {
+		t.Errorf("After reconnect, got total=%q, want=3", got.value)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	fr := startFakeRedis(t, "127.0.0.1:0", "")
+	defer fr.close()
+
+	s, err := New(context.Background(), &configpb.SurfacerConf{
+		Address: proto.String(fr.ln.Addr().String()),
+	}, &options.Options{MetricsBufferSize: 10}, nil)
+	if err != nil {
+		t.Fatalf("Error creating surfacer: %v", err)
+	}
+
+	s.Write(context.Background(), testEM(1, 1).AddLabel("region", "us"))
+	s.Close()
+
+	if got, ok := fr.get("cloudprober:p1:t1:total:region=us"); !ok || got.value != "1" || got.ttl != 300 {
+		t.Errorf("Got %+v (exists: %v), want value=1, ttl=300", got, ok)
+	}
+}
+
+func TestWriteChannelFull(t *testing.T) {
+	s := testSurfacer(&configpb.SurfacerConf{}, nil)
+
+	// There is no write loop, second write finds the channel full.
+	for i := 0; i < 2; i++ {
+		s.Write(context.Background(), testEM(1, 1))
+	}
+	if s.Dropped() != 1 {
+		t.Errorf("Got dropped EventMetrics: %d, want: 1", s.Dropped())
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	for _, test := range []struct {
+		reply   string
+		wantErr string
+	}{
+		{reply: "+OK\r\n"},
+		{reply: ":1\r\n"},
+		{reply: "$3\r\nfoo\r\n"},
+		{reply: "$-1\r\n"},
+		{reply: "-ERR wrong type\r\n", wantErr: "redis error: ERR wrong type"},
+		{reply: "*1\r\n", wantErr: "unexpected reply"},
+	} {
+		r := bufio.NewReader(strings.NewReader(test.reply + "+NEXT\r\n"))
+		err := readReply(r)
+		if (err == nil) != (test.wantErr == "") || (err != nil && !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("readReply(%q): got error: %v, want error: %q", test.reply, err, test.wantErr)
+		}
+		// Make sure that entire reply was consumed.
+		if test.wantErr == "" {
+			if line, _ := r.ReadString('\n'); line != "+NEXT\r\n" {
+				t.Errorf("readReply(%q): reply not consumed fully, next line: %q", test.reply, line)
+			}
+		}
+	}
+}
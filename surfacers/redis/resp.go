@@ -0,0 +1,77 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+// This file implements the small subset of the Redis protocol (RESP) that we
+// need to pipeline simple commands: encoding commands, and reading the status,
+// integer and bulk string replies.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// redisError is an error reply from the Redis server. Unlike other errors, it
+// doesn't affect the connection.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis error: " + string(e)
+}
+
+// writeCommand appends the RESP encoding of the command to the buffer.
+func writeCommand(buf *bytes.Buffer, args []string) {
+	fmt.Fprintf(buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readReply reads a single reply, returning a redisError for the error
+// replies. Bulk string replies are discarded.
+func readReply(r *bufio.Reader) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return fmt.Errorf("invalid reply: %q", line)
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return fmt.Errorf("invalid bulk string length: %q", line)
+		}
+		if n < 0 { // Null bulk string.
+			return nil
+		}
+		_, err = io.CopyN(ioutil.Discard, r, int64(n)+2)
+		return err
+	default:
+		return fmt.Errorf("unexpected reply: %q", line)
+	}
+}
@@ -46,6 +46,21 @@ type SurfacerConf struct {
 	// processing is paused while serving data to Stackdriver. This buffer is to
 	// make writes to Stackdriver surfacer non-blocking.
 	MetricsBufferSize *int64 `protobuf:"varint,5,opt,name=metrics_buffer_size,json=metricsBufferSize,def=10000" json:"metrics_buffer_size,omitempty"`
+	// Monitored resource type to export metrics to, e.g. k8s_container or
+	// generic_task. If not specified, metrics are exported to the automatically
+	// detected resource when running on GCE (gce_instance) or GKE
+	// (k8s_container). Resource's labels are set using resource_label below.
+	// See https://cloud.google.com/monitoring/api/resources for the resource
+	// types and their labels.
+	ResourceType *string `protobuf:"bytes,6,opt,name=resource_type,json=resourceType" json:"resource_type,omitempty"`
+	// Labels for the resource_type above. If project_id label is not configured,
+	// it's set to the surfacer's project. Example:
+	//   resource_type: "generic_task"
+	//   resource_label { name: "location" value: "us-east1" }
+	//   resource_label { name: "namespace" value: "probes" }
+	//   resource_label { name: "job" em_label: "probe" }
+	//   resource_label { name: "task_id" em_label: "dst" }
+	ResourceLabel []*SurfacerConf_ResourceLabel `protobuf:"bytes,7,rep,name=resource_label,json=resourceLabel" json:"resource_label,omitempty"`
 }
 
 // Default values for SurfacerConf fields.
@@ -122,6 +137,113 @@ func (x *SurfacerConf) GetMetricsBufferSize() int64 {
 	return Default_SurfacerConf_MetricsBufferSize
 }
 
+func (x *SurfacerConf) GetResourceType() string {
+	if x != nil && x.ResourceType != nil {
+		return *x.ResourceType
+	}
+	return ""
+}
+
+func (x *SurfacerConf) GetResourceLabel() []*SurfacerConf_ResourceLabel {
+	if x != nil {
+		return x.ResourceLabel
+	}
+	return nil
+}
+
+type SurfacerConf_ResourceLabel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Monitored resource label, e.g. namespace_name.
+	Name *string `protobuf:"bytes,1,req,name=name" json:"name,omitempty"`
+	// Types that are assignable to ValueSource:
+	//	*SurfacerConf_ResourceLabel_EmLabel
+	//	*SurfacerConf_ResourceLabel_Value
+	ValueSource isSurfacerConf_ResourceLabel_ValueSource `protobuf_oneof:"value_source"`
+}
+
+func (x *SurfacerConf_ResourceLabel) Reset() {
+	*x = SurfacerConf_ResourceLabel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SurfacerConf_ResourceLabel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurfacerConf_ResourceLabel) ProtoMessage() {}
+
+func (x *SurfacerConf_ResourceLabel) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurfacerConf_ResourceLabel.ProtoReflect.Descriptor instead.
+func (*SurfacerConf_ResourceLabel) Descriptor() ([]byte, []int) {
+	return file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *SurfacerConf_ResourceLabel) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (m *SurfacerConf_ResourceLabel) GetValueSource() isSurfacerConf_ResourceLabel_ValueSource {
+	if m != nil {
+		return m.ValueSource
+	}
+	return nil
+}
+
+func (x *SurfacerConf_ResourceLabel) GetEmLabel() string {
+	if x, ok := x.GetValueSource().(*SurfacerConf_ResourceLabel_EmLabel); ok {
+		return x.EmLabel
+	}
+	return ""
+}
+
+func (x *SurfacerConf_ResourceLabel) GetValue() string {
+	if x, ok := x.GetValueSource().(*SurfacerConf_ResourceLabel_Value); ok {
+		return x.Value
+	}
+	return ""
+}
+
+type isSurfacerConf_ResourceLabel_ValueSource interface {
+	isSurfacerConf_ResourceLabel_ValueSource()
+}
+
+type SurfacerConf_ResourceLabel_EmLabel struct {
+	// EventMetrics label to take the resource label's value from. Mapped
+	// EventMetrics labels are not exported as metric labels; all other
+	// labels continue to be exported as metric labels.
+	EmLabel string `protobuf:"bytes,2,opt,name=em_label,json=emLabel,oneof"`
+}
+
+type SurfacerConf_ResourceLabel_Value struct {
+	// Static value for the resource label.
+	Value string `protobuf:"bytes,3,opt,name=value,oneof"`
+}
+
+func (*SurfacerConf_ResourceLabel_EmLabel) isSurfacerConf_ResourceLabel_ValueSource() {}
+
+func (*SurfacerConf_ResourceLabel_Value) isSurfacerConf_ResourceLabel_ValueSource() {}
+
 var File_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto protoreflect.FileDescriptor
 
 var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_rawDesc = []byte{
@@ -132,7 +254,7 @@ var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_p
 	0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x20, 0x63,
 	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61,
 	0x63, 0x65, 0x72, 0x2e, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x22,
-	0x8a, 0x02, 0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66,
+	0xfe, 0x03, 0x0a, 0x0c, 0x53, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66,
 	0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x2a, 0x0a, 0x0f, 0x62, 0x61,
 	0x74, 0x63, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x02, 0x20,
@@ -148,11 +270,27 @@ var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_p
 	0x6e, 0x67, 0x55, 0x72, 0x6c, 0x12, 0x35, 0x0a, 0x13, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73,
 	0x5f, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01,
 	0x28, 0x03, 0x3a, 0x05, 0x31, 0x30, 0x30, 0x30, 0x30, 0x52, 0x11, 0x6d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x73, 0x42, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x42, 0x40, 0x5a, 0x3e,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
-	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62,
-	0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73, 0x2f, 0x73, 0x74, 0x61,
-	0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x73, 0x42, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x23, 0x0a, 0x0d,
+	0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x63, 0x0a, 0x0e, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x6c, 0x61,
+	0x62, 0x65, 0x6c, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x3c, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72,
+	0x2e, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x75, 0x72,
+	0x66, 0x61, 0x63, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x0d, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x1a, 0x68, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x02, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x08, 0x65,
+	0x6d, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x07, 0x65, 0x6d, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x16, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x42, 0x0e, 0x0a, 0x0c, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x42, 0x40, 0x5a, 0x3e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63,
+	0x6c, 0x6f, 0x75, 0x64, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x72, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x61, 0x63, 0x65, 0x72, 0x73,
+	0x2f, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f,
 }
 
 var (
@@ -167,16 +305,18 @@ func file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_
 	return file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_rawDescData
 }
 
-var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_goTypes = []interface{}{
-	(*SurfacerConf)(nil), // 0: cloudprober.surfacer.stackdriver.SurfacerConf
+	(*SurfacerConf)(nil),               // 0: cloudprober.surfacer.stackdriver.SurfacerConf
+	(*SurfacerConf_ResourceLabel)(nil), // 1: cloudprober.surfacer.stackdriver.SurfacerConf.ResourceLabel
 }
 var file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: cloudprober.surfacer.stackdriver.SurfacerConf.resource_label:type_name -> cloudprober.surfacer.stackdriver.SurfacerConf.ResourceLabel
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_init() }
@@ -197,6 +337,22 @@ func file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_
 				return nil
 			}
 		}
+		file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SurfacerConf_ResourceLabel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*SurfacerConf_ResourceLabel_EmLabel)(nil),
+		(*SurfacerConf_ResourceLabel_Value)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -204,7 +360,7 @@ func file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_github_com_cloudprober_cloudprober_surfacers_stackdriver_proto_config_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
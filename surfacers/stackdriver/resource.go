@@ -15,10 +15,13 @@
 package stackdriver
 
 import (
+	"fmt"
 	"os"
 
 	"cloud.google.com/go/compute/metadata"
 	md "github.com/cloudprober/cloudprober/common/metadata"
+	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/surfacers/stackdriver/proto"
 	monitoring "google.golang.org/api/monitoring/v3"
 )
 
@@ -77,3 +80,41 @@ func monitoredResourceOnGCE(projectID string) (*monitoring.MonitoredResource, er
 	}
 	return gceResource(projectID)
 }
+
+// validateResourceConfig verifies the configured resource mapping.
+func validateResourceConfig(c *configpb.SurfacerConf) error {
+	if len(c.GetResourceLabel()) != 0 && c.GetResourceType() == "" {
+		return fmt.Errorf("resource_label is configured without resource_type")
+	}
+	for _, rl := range c.GetResourceLabel() {
+		if rl.ValueSource == nil {
+			return fmt.Errorf("resource_label %s: one of em_label or value is required", rl.GetName())
+		}
+	}
+	return nil
+}
+
+// mappedResource returns the configured monitored resource for the given
+// EventMetrics, along with the EventMetrics labels used for it. It returns a
+// nil resource if resource_type is not configured.
+func (s *SDSurfacer) mappedResource(em *metrics.EventMetrics) (*monitoring.MonitoredResource, map[string]bool) {
+	if s.c.GetResourceType() == "" {
+		return nil, nil
+	}
+
+	mr := &monitoring.MonitoredResource{
+		Type:   s.c.GetResourceType(),
+		Labels: map[string]string{"project_id": s.projectName},
+	}
+	mappedLabels := make(map[string]bool)
+
+	for _, rl := range s.c.GetResourceLabel() {
+		if _, ok := rl.ValueSource.(*configpb.SurfacerConf_ResourceLabel_EmLabel); ok {
+			mr.Labels[rl.GetName()] = em.Label(rl.GetEmLabel())
+			mappedLabels[rl.GetEmLabel()] = true
+			continue
+		}
+		mr.Labels[rl.GetName()] = rl.GetValue()
+	}
+	return mr, mappedLabels
+}
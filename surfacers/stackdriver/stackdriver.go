@@ -23,6 +23,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -30,9 +31,11 @@ import (
 	"cloud.google.com/go/compute/metadata"
 	"github.com/cloudprober/cloudprober/logger"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	monitoring "google.golang.org/api/monitoring/v3"
 
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/surfacers/common/backoff"
 	"github.com/cloudprober/cloudprober/surfacers/common/options"
 	configpb "github.com/cloudprober/cloudprober/surfacers/stackdriver/proto"
 )
@@ -75,6 +78,10 @@ type SDSurfacer struct {
 
 	// Monitoring client
 	client *monitoring.Service
+
+	// Sender for timeseries batches, backs off while stackdriver is
+	// unavailable.
+	sender *backoff.Sender
 }
 
 // New initializes a SDSurfacer for Stack Driver with all its necessary internal
@@ -95,6 +102,12 @@ func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Optio
 		l:            l,
 	}
 
+	s.sender = backoff.NewSender(opts.Config, s.createTimeSeries, l)
+
+	if err := validateResourceConfig(s.c); err != nil {
+		return nil, err
+	}
+
 	if s.c.GetAllowedMetricsRegex() != "" {
 		l.Warning("allowed_metrics_regex is now deprecated. Please use the common surfacer options: allow_metrics, ignore_metrics.")
 		r, err := regexp.Compile(s.c.GetAllowedMetricsRegex())
@@ -117,13 +130,16 @@ func New(ctx context.Context, config *configpb.SurfacerConf, opts *options.Optio
 			}
 		}
 
-		mr, err := monitoredResourceOnGCE(s.projectName)
-		if err != nil {
-			return nil, fmt.Errorf("error initializing monitored resource for stackdriver on GCE: %v", err)
-		}
-
-		s.resource = mr
+		// Configured resource type, if any, takes precedence over the
+		// automatically detected resource.
+		if s.c.GetResourceType() == "" {
+			mr, err := monitoredResourceOnGCE(s.projectName)
+			if err != nil {
+				return nil, fmt.Errorf("error initializing monitored resource for stackdriver on GCE: %v", err)
+			}
 
+			s.resource = mr
+		}
 	}
 
 	// Create monitoring client
@@ -239,13 +255,7 @@ func (s *SDSurfacer) writeBatch(ctx context.Context) {
 				// a time series create call will automatically register a new metric
 				// with the correct information if it does not already exist.
 				// Ref: https://cloud.google.com/monitoring/custom-metrics/creating-metrics#auto-creation
-				requestBody := monitoring.CreateTimeSeriesRequest{
-					TimeSeries: ts[i:endIndex],
-				}
-				if _, err := s.client.Projects.TimeSeries.Create("projects/"+s.projectName, &requestBody).Do(); err != nil {
-					s.failCnt++
-					s.l.Warningf("Unable to fulfill TimeSeries Create call. Err: %v", err)
-				}
+				s.sender.Send(ts[i:endIndex])
 			}
 
 			// Flush the cache after we've finished writing so we don't accidentally
@@ -259,6 +269,28 @@ func (s *SDSurfacer) writeBatch(ctx context.Context) {
 
 }
 
+// createTimeSeries writes a batch of timeseries to stackdriver.
+func (s *SDSurfacer) createTimeSeries(item interface{}) error {
+	requestBody := monitoring.CreateTimeSeriesRequest{
+		TimeSeries: item.([]*monitoring.TimeSeries),
+	}
+	if _, err := s.client.Projects.TimeSeries.Create("projects/"+s.projectName, &requestBody).Do(); err != nil {
+		s.failCnt++
+		// Invalid requests are rejected again if retried.
+		if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusBadRequest {
+			return backoff.Permanent(fmt.Errorf("TimeSeries Create call rejected: %v", err))
+		}
+		return fmt.Errorf("unable to fulfill TimeSeries Create call: %v", err)
+	}
+	return nil
+}
+
+// Dropped returns the number of TimeSeries write requests dropped, either
+// because they were invalid, or because the reconnect buffer was full.
+func (s *SDSurfacer) Dropped() int64 {
+	return s.sender.Dropped()
+}
+
 //-----------------------------------------------------------------------------
 // StackDriver Object Creation and Helper Functions
 //-----------------------------------------------------------------------------
@@ -268,7 +300,7 @@ func (s *SDSurfacer) writeBatch(ctx context.Context) {
 //
 // More information on the object and specific fields can be found here:
 //	https://cloud.google.com/monitoring/api/ref_v3/rest/v3/TimeSeries
-func (s *SDSurfacer) recordTimeSeries(metricKind, metricName, msgType string, labels map[string]string, timestamp time.Time, tv *monitoring.TypedValue, unit, cacheKey string) *monitoring.TimeSeries {
+func (s *SDSurfacer) recordTimeSeries(resource *monitoring.MonitoredResource, metricKind, metricName, msgType string, labels map[string]string, timestamp time.Time, tv *monitoring.TypedValue, unit, cacheKey string) *monitoring.TimeSeries {
 	startTime := s.startTime.Format(time.RFC3339Nano)
 	if metricKind == "GAUGE" {
 		startTime = timestamp.Format(time.RFC3339Nano)
@@ -301,8 +333,8 @@ func (s *SDSurfacer) recordTimeSeries(metricKind, metricName, msgType string, la
 		},
 	}
 
-	if s.resource != nil {
-		ts.Resource = s.resource
+	if resource != nil {
+		ts.Resource = resource
 	}
 
 	// We create a key that is a composite of both the name and the
@@ -388,6 +420,16 @@ func (s *SDSurfacer) recordEventMetrics(em *metrics.EventMetrics) (ts []*monitor
 
 	emLabels, cacheKey, metricPrefix := processLabels(em)
 
+	// If resource mapping is configured, EventMetrics labels used for the
+	// resource labels are not exported as metric labels.
+	resource := s.resource
+	if mr, mappedLabels := s.mappedResource(em); mr != nil {
+		resource = mr
+		for k := range mappedLabels {
+			delete(emLabels, k)
+		}
+	}
+
 	for _, k := range em.MetricsKeys() {
 		if !s.opts.AllowMetric(k) {
 			continue
@@ -420,7 +462,7 @@ func (s *SDSurfacer) recordEventMetrics(em *metrics.EventMetrics) (ts []*monitor
 		// If metric value is of type numerical value.
 		if v, ok := val.(metrics.NumValue); ok {
 			f := float64(v.Int64())
-			ts = append(ts, s.recordTimeSeries(metricKind, name, "DOUBLE", mLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
+			ts = append(ts, s.recordTimeSeries(resource, metricKind, name, "DOUBLE", mLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
 			continue
 		}
 
@@ -434,7 +476,7 @@ func (s *SDSurfacer) recordEventMetrics(em *metrics.EventMetrics) (ts []*monitor
 			// for stackdriver.
 			mLabels["val"] = strings.Trim(v.String(), "\"")
 			f := float64(1)
-			ts = append(ts, s.recordTimeSeries(metricKind, name, "DOUBLE", mLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
+			ts = append(ts, s.recordTimeSeries(resource, metricKind, name, "DOUBLE", mLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
 			continue
 		}
 
@@ -449,14 +491,14 @@ func (s *SDSurfacer) recordEventMetrics(em *metrics.EventMetrics) (ts []*monitor
 				}
 				mmLabels[mapValue.MapName] = mapKey
 				f := float64(mapValue.GetKey(mapKey).Int64())
-				ts = append(ts, s.recordTimeSeries(metricKind, name, "DOUBLE", mmLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
+				ts = append(ts, s.recordTimeSeries(resource, metricKind, name, "DOUBLE", mmLabels, em.Timestamp, &monitoring.TypedValue{DoubleValue: &f}, unit, cacheKey))
 			}
 			continue
 		}
 
 		// If metric value is of type Distribution.
 		if distValue, ok := val.(*metrics.Distribution); ok {
-			ts = append(ts, s.recordTimeSeries(metricKind, name, "DISTRIBUTION", mLabels, em.Timestamp, distValue.StackdriverTypedValue(), unit, cacheKey))
+			ts = append(ts, s.recordTimeSeries(resource, metricKind, name, "DISTRIBUTION", mLabels, em.Timestamp, distValue.StackdriverTypedValue(), unit, cacheKey))
 			continue
 		}
 
@@ -21,6 +21,8 @@ import (
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	configpb "github.com/cloudprober/cloudprober/surfacers/stackdriver/proto"
+	"github.com/golang/protobuf/proto"
 	"github.com/kylelemons/godebug/pretty"
 	monitoring "google.golang.org/api/monitoring/v3"
 )
@@ -222,3 +224,121 @@ func TestTimeSeries(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceMapping(t *testing.T) {
+	testTimestamp := time.Now()
+	em := metrics.NewEventMetrics(testTimestamp).
+		AddMetric("total", metrics.NewInt(10)).
+		AddLabel("ptype", "http").
+		AddLabel("probe", "p1").
+		AddLabel("dst", "t1").
+		AddLabel("namespace", "ns1").
+		AddLabel("pod", "pod1")
+
+	for _, test := range []struct {
+		desc             string
+		conf             *configpb.SurfacerConf
+		wantResource     *monitoring.MonitoredResource
+		wantMetricLabels map[string]string
+	}{
+		{
+			desc: "k8s_container",
+			conf: &configpb.SurfacerConf{
+				ResourceType: proto.String("k8s_container"),
+				ResourceLabel: []*configpb.SurfacerConf_ResourceLabel{
+					{Name: proto.String("location"), ValueSource: &configpb.SurfacerConf_ResourceLabel_Value{Value: "us-central1"}},
+					{Name: proto.String("cluster_name"), ValueSource: &configpb.SurfacerConf_ResourceLabel_Value{Value: "c1"}},
+					{Name: proto.String("namespace_name"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "namespace"}},
+					{Name: proto.String("pod_name"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "pod"}},
+					{Name: proto.String("container_name"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "container"}},
+				},
+			},
+			wantResource: &monitoring.MonitoredResource{
+				Type: "k8s_container",
+				Labels: map[string]string{
+					"project_id":     "test-project",
+					"location":       "us-central1",
+					"cluster_name":   "c1",
+					"namespace_name": "ns1",
+					"pod_name":       "pod1",
+					"container_name": "",
+				},
+			},
+			wantMetricLabels: map[string]string{"dst": "t1"},
+		},
+		{
+			desc: "generic_task",
+			conf: &configpb.SurfacerConf{
+				ResourceType: proto.String("generic_task"),
+				ResourceLabel: []*configpb.SurfacerConf_ResourceLabel{
+					{Name: proto.String("project_id"), ValueSource: &configpb.SurfacerConf_ResourceLabel_Value{Value: "other-project"}},
+					{Name: proto.String("namespace"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "namespace"}},
+					{Name: proto.String("job"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "probe"}},
+					{Name: proto.String("task_id"), ValueSource: &configpb.SurfacerConf_ResourceLabel_EmLabel{EmLabel: "dst"}},
+				},
+			},
+			wantResource: &monitoring.MonitoredResource{
+				Type: "generic_task",
+				Labels: map[string]string{
+					"project_id": "other-project",
+					"namespace":  "ns1",
+					"job":        "p1",
+					"task_id":    "t1",
+				},
+			},
+			wantMetricLabels: map[string]string{"pod": "pod1"},
+		},
+		{
+			desc: "no_mapping",
+			conf: &configpb.SurfacerConf{},
+			wantResource: &monitoring.MonitoredResource{
+				Type: "gce_instance",
+				Labels: map[string]string{
+					"instance_id": "test-instance",
+					"zone":        "us-central1-a",
+				},
+			},
+			wantMetricLabels: map[string]string{"dst": "t1", "namespace": "ns1", "pod": "pod1"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := validateResourceConfig(test.conf); err != nil {
+				t.Fatalf("validateResourceConfig: %v", err)
+			}
+			s := newTestSurfacer()
+			s.c = test.conf
+
+			ts := s.recordEventMetrics(em)
+			if len(ts) != 1 {
+				t.Fatalf("Got %d timeseries, want 1", len(ts))
+			}
+			if diff := pretty.Compare(test.wantResource, ts[0].Resource); diff != "" {
+				t.Errorf("Unexpected resource (-want +got):\n%s", diff)
+			}
+			if diff := pretty.Compare(test.wantMetricLabels, ts[0].Metric.Labels); diff != "" {
+				t.Errorf("Unexpected metric labels (-want +got):\n%s", diff)
+			}
+			if ts[0].Metric.Type != "custom.googleapis.com/cloudprober/http/p1/total" {
+				t.Errorf("Metric type=%s", ts[0].Metric.Type)
+			}
+		})
+	}
+}
+
+func TestValidateResourceConfig(t *testing.T) {
+	for _, conf := range []*configpb.SurfacerConf{
+		{
+			ResourceLabel: []*configpb.SurfacerConf_ResourceLabel{
+				{Name: proto.String("job"), ValueSource: &configpb.SurfacerConf_ResourceLabel_Value{Value: "j"}},
+			},
+		},
+		{
+			ResourceType:  proto.String("generic_task"),
+			ResourceLabel: []*configpb.SurfacerConf_ResourceLabel{{Name: proto.String("job")}},
+		},
+	} {
+		if err := validateResourceConfig(conf); err == nil {
+			t.Errorf("Expected error for config: %v", conf)
+		}
+	}
+}
@@ -29,17 +29,23 @@ import (
 	"html/template"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
 	"github.com/cloudprober/cloudprober/surfacers/cloudwatch"
+	"github.com/cloudprober/cloudprober/surfacers/common/aggregator"
+	"github.com/cloudprober/cloudprober/surfacers/common/cardinality"
 	"github.com/cloudprober/cloudprober/surfacers/common/options"
 	"github.com/cloudprober/cloudprober/surfacers/common/transform"
 	"github.com/cloudprober/cloudprober/surfacers/datadog"
 	"github.com/cloudprober/cloudprober/surfacers/file"
+	"github.com/cloudprober/cloudprober/surfacers/otel"
 	"github.com/cloudprober/cloudprober/surfacers/postgres"
 	"github.com/cloudprober/cloudprober/surfacers/prometheus"
+	"github.com/cloudprober/cloudprober/surfacers/promremote"
 	"github.com/cloudprober/cloudprober/surfacers/pubsub"
+	"github.com/cloudprober/cloudprober/surfacers/redis"
 	"github.com/cloudprober/cloudprober/surfacers/stackdriver"
 	"github.com/cloudprober/cloudprober/web/formatutils"
 
@@ -95,10 +101,42 @@ type Surfacer interface {
 	Write(ctx context.Context, em *metrics.EventMetrics)
 }
 
+// Closer is an optional interface implemented by the surfacers that buffer
+// data internally. Close flushes all the buffered data, and releases the
+// resources held by the surfacer. Write must not be called after Close.
+type Closer interface {
+	Close()
+}
+
+// dropCounter is implemented by the surfacers that may drop data, e.g. if
+// the backend is unavailable. Number of dropped items (EventMetrics or write
+// requests, depending on the surfacer) is exported as the "dropped" metric.
+type dropCounter interface {
+	Dropped() int64
+}
+
+// Make sure that the surfacers that drop data keep exporting the dropped
+// count, e.g. if Dropped() is renamed.
+var (
+	_ dropCounter = &cloudwatch.CWSurfacer{}
+	_ dropCounter = &postgres.Surfacer{}
+	_ dropCounter = &promremote.Surfacer{}
+	_ dropCounter = &redis.RedisSurfacer{}
+	_ dropCounter = &stackdriver.SDSurfacer{}
+)
+
 type surfacerWrapper struct {
 	Surfacer
+	name    string
 	opts    *options.Options
 	lvCache map[string]*metrics.EventMetrics
+
+	// If aggregation is enabled, metrics are rolled up by the aggregator
+	// before being written to the surfacer.
+	agg *aggregator.Aggregator
+
+	// If max cardinality is configured, new series beyond it are dropped.
+	cg *cardinality.Guard
 }
 
 func (sw *surfacerWrapper) Write(ctx context.Context, em *metrics.EventMetrics) {
@@ -112,6 +150,26 @@ func (sw *surfacerWrapper) Write(ctx context.Context, em *metrics.EventMetrics)
 		}
 	}
 
+	if em = sw.opts.FilterMetrics(em); em == nil {
+		return
+	}
+
+	if sw.cg != nil {
+		if em = sw.cg.Filter(em); em == nil {
+			return
+		}
+	}
+
+	if sw.agg != nil {
+		sw.agg.Write(ctx, em)
+		return
+	}
+	sw.write(ctx, em)
+}
+
+// write writes the EventMetrics to the underlying surfacer, converting it to
+// gauge and adding percentiles, if configured.
+func (sw *surfacerWrapper) write(ctx context.Context, em *metrics.EventMetrics) {
 	if sw.opts.Config.GetExportAsGauge() && em.Kind == metrics.CUMULATIVE {
 		newEM, err := transform.CumulativeToGauge(em, sw.lvCache, sw.opts.Logger)
 		if err != nil {
@@ -122,6 +180,82 @@ func (sw *surfacerWrapper) Write(ctx context.Context, em *metrics.EventMetrics)
 	}
 
 	sw.Surfacer.Write(ctx, em)
+
+	if pem := transform.PercentileMetrics(em, sw.opts.Config.GetExportPercentiles()); pem != nil {
+		sw.Surfacer.Write(ctx, pem)
+	}
+}
+
+// Close closes the underlying surfacer if it implements the Closer interface.
+// If aggregation is enabled, metrics rolled up so far are flushed first.
+func (sw *surfacerWrapper) Close() {
+	if sw.agg != nil {
+		sw.agg.Close()
+	}
+	if c, ok := sw.Surfacer.(Closer); ok {
+		c.Close()
+	}
+}
+
+// stats returns an EventMetrics with the surfacer's own stats, e.g. the number
+// of metrics filtered out by it. It returns nil if there is nothing to report.
+func (sw *surfacerWrapper) stats(ts time.Time) *metrics.EventMetrics {
+	em := metrics.NewEventMetrics(ts).
+		AddLabel("ptype", "sysvars").
+		AddLabel("probe", "sysvars").
+		AddLabel("surfacer", sw.name)
+	em.Kind = metrics.CUMULATIVE
+
+	if len(sw.opts.Config.GetAllowMetrics())+len(sw.opts.Config.GetDenyMetrics()) > 0 {
+		em.AddMetric("filtered_metrics", metrics.NewInt(sw.opts.FilteredMetrics()))
+	}
+
+	if sw.cg != nil {
+		em.AddMetric("cardinality_dropped_metrics", metrics.NewInt(sw.cg.Dropped()))
+	}
+
+	if dc, ok := sw.Surfacer.(dropCounter); ok {
+		em.AddMetric("dropped", metrics.NewInt(dc.Dropped()))
+	}
+
+	if len(em.MetricsKeys()) == 0 {
+		return nil
+	}
+	return em
+}
+
+// StatsEventMetrics returns the surfacers' own stats, as EventMetrics labeled
+// with the surfacer name. Surfacers that have nothing to report are skipped.
+func StatsEventMetrics(ts time.Time, sis []*SurfacerInfo) []*metrics.EventMetrics {
+	var result []*metrics.EventMetrics
+	for _, si := range sis {
+		sw, ok := si.Surfacer.(*surfacerWrapper)
+		if !ok {
+			continue
+		}
+		if em := sw.stats(ts); em != nil {
+			result = append(result, em)
+		}
+	}
+	return result
+}
+
+// ExportStats exports the surfacers' own stats at the given interval, until
+// the context is canceled.
+func ExportStats(ctx context.Context, sis []*SurfacerInfo, dataChan chan *metrics.EventMetrics, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ts := <-ticker.C:
+			for _, em := range StatsEventMetrics(ts, sis) {
+				dataChan <- em
+			}
+		}
+	}
 }
 
 // SurfacerInfo encapsulates a Surfacer and related info.
@@ -148,6 +282,12 @@ func inferType(s *surfacerpb.SurfacerDef) surfacerspb.Type {
 		return surfacerspb.Type_CLOUDWATCH
 	case *surfacerpb.SurfacerDef_DatadogSurfacer:
 		return surfacerspb.Type_DATADOG
+	case *surfacerpb.SurfacerDef_OtelSurfacer:
+		return surfacerspb.Type_OTEL
+	case *surfacerpb.SurfacerDef_RedisSurfacer:
+		return surfacerspb.Type_REDIS
+	case *surfacerpb.SurfacerDef_PrometheusRemoteWriteSurfacer:
+		return surfacerspb.Type_PROMETHEUS_REMOTE_WRITE
 	}
 
 	return surfacerspb.Type_NONE
@@ -185,7 +325,7 @@ func initSurfacer(ctx context.Context, s *surfacerpb.SurfacerDef, sType surfacer
 		surfacer, err = file.New(ctx, s.GetFileSurfacer(), opts, l)
 		conf = s.GetFileSurfacer()
 	case surfacerpb.Type_POSTGRES:
-		surfacer, err = postgres.New(ctx, s.GetPostgresSurfacer(), l)
+		surfacer, err = postgres.New(ctx, s.GetPostgresSurfacer(), opts, l)
 		conf = s.GetPostgresSurfacer()
 	case surfacerpb.Type_PUBSUB:
 		surfacer, err = pubsub.New(ctx, s.GetPubsubSurfacer(), opts, l)
@@ -196,6 +336,15 @@ func initSurfacer(ctx context.Context, s *surfacerpb.SurfacerDef, sType surfacer
 	case surfacerpb.Type_DATADOG:
 		surfacer, err = datadog.New(ctx, s.GetDatadogSurfacer(), opts, l)
 		conf = s.GetDatadogSurfacer()
+	case surfacerpb.Type_OTEL:
+		surfacer, err = otel.New(ctx, s.GetOtelSurfacer(), opts, l)
+		conf = s.GetOtelSurfacer()
+	case surfacerpb.Type_REDIS:
+		surfacer, err = redis.New(ctx, s.GetRedisSurfacer(), opts, l)
+		conf = s.GetRedisSurfacer()
+	case surfacerpb.Type_PROMETHEUS_REMOTE_WRITE:
+		surfacer, err = promremote.New(ctx, s.GetPrometheusRemoteWriteSurfacer(), opts, l)
+		conf = s.GetPrometheusRemoteWriteSurfacer()
 	case surfacerpb.Type_USER_DEFINED:
 		userDefinedSurfacersMu.Lock()
 		defer userDefinedSurfacersMu.Unlock()
@@ -207,11 +356,36 @@ func initSurfacer(ctx context.Context, s *surfacerpb.SurfacerDef, sType surfacer
 		return nil, nil, fmt.Errorf("unknown surfacer type: %s", s.GetType())
 	}
 
-	return &surfacerWrapper{
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sw := &surfacerWrapper{
 		Surfacer: surfacer,
+		name:     logName,
 		opts:     opts,
 		lvCache:  make(map[string]*metrics.EventMetrics),
-	}, conf, err
+	}
+
+	if s.GetMaxCardinality() > 0 {
+		sw.cg = cardinality.New(int(s.GetMaxCardinality()), l)
+	}
+
+	if s.GetAggregationWindowSec() != 0 {
+		window := time.Duration(s.GetAggregationWindowSec()) * time.Second
+		if sw.agg, err = aggregator.New(ctx, window, writerFunc(sw.write), l); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return sw, conf, nil
+}
+
+// writerFunc adapts a function to the Surfacer interface.
+type writerFunc func(context.Context, *metrics.EventMetrics)
+
+func (f writerFunc) Write(ctx context.Context, em *metrics.EventMetrics) {
+	f(ctx, em)
 }
 
 // Init initializes the surfacers from the config protobufs and returns them as
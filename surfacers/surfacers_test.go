@@ -16,9 +16,11 @@ package surfacers
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -205,3 +207,173 @@ func TestFailureMetric(t *testing.T) {
 		}
 	}
 }
+
+func TestMetricNameFiltering(t *testing.T) {
+	ts1 := &testSurfacer{}
+	Register("s1", ts1)
+
+	si, err := Init(context.Background(), []*surfacerpb.SurfacerDef{
+		{
+			Name:        proto.String("s1"),
+			Type:        surfacerpb.Type_USER_DEFINED.Enum(),
+			DenyMetrics: []string{"^timeout$", "^memory$", "^num_goroutines$"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected initialization error: %v", err)
+	}
+
+	for _, em := range testEventMetrics {
+		si[0].Surfacer.Write(context.Background(), em)
+	}
+
+	// First EM loses the "timeout" metric, while the second one is dropped
+	// completely.
+	if len(ts1.received) != 1 {
+		t.Fatalf("Received %d EventMetrics, want 1: %v", len(ts1.received), ts1.received)
+	}
+	wantEM := "labels=ptype=http,probe=google_homepage total=20"
+	if got := ts1.received[0].String(); !strings.HasSuffix(got, wantEM) {
+		t.Errorf("Received EventMetrics: %s, want (suffix): %s", got, wantEM)
+	}
+}
+
+// droppingSurfacer is a test surfacer that reports dropped data.
+type droppingSurfacer struct {
+	testSurfacer
+	dropped int64
+}
+
+func (ds *droppingSurfacer) Dropped() int64 {
+	return ds.dropped
+}
+
+func TestStatsEventMetrics(t *testing.T) {
+	ts1, ts2 := &testSurfacer{}, &testSurfacer{}
+	Register("s1", ts1)
+	Register("s2", ts2)
+	Register("s3", &droppingSurfacer{dropped: 5})
+
+	si, err := Init(context.Background(), []*surfacerpb.SurfacerDef{
+		{
+			Name:        proto.String("s1"),
+			Type:        surfacerpb.Type_USER_DEFINED.Enum(),
+			DenyMetrics: []string{"^timeout$", "^memory$"},
+		},
+		{
+			Name: proto.String("s2"),
+			Type: surfacerpb.Type_USER_DEFINED.Enum(),
+		},
+		{
+			Name: proto.String("s3"),
+			Type: surfacerpb.Type_USER_DEFINED.Enum(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected initialization error: %v", err)
+	}
+
+	for _, em := range testEventMetrics {
+		for _, s := range si {
+			s.Surfacer.Write(context.Background(), em)
+		}
+	}
+
+	// s2 has nothing to report.
+	wantEMs := []string{
+		"labels=ptype=sysvars,probe=sysvars,surfacer=s1 filtered_metrics=2",
+		"labels=ptype=sysvars,probe=sysvars,surfacer=s3 dropped=5",
+	}
+	ems := StatsEventMetrics(time.Now(), si)
+	if len(ems) != len(wantEMs) {
+		t.Fatalf("Got %d stats EventMetrics, want %d: %v", len(ems), len(wantEMs), ems)
+	}
+	for i, em := range ems {
+		if got := em.String(); !strings.HasSuffix(got, wantEMs[i]) {
+			t.Errorf("Stats EventMetrics: %s, want (suffix): %s", got, wantEMs[i])
+		}
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	ts := &testSurfacer{}
+	Register("s1", ts)
+
+	si, err := Init(context.Background(), []*surfacerpb.SurfacerDef{
+		{
+			Name:                 proto.String("s1"),
+			Type:                 surfacerpb.Type_USER_DEFINED.Enum(),
+			AggregationWindowSec: proto.Int32(3600),
+			ExportAsGauge:        proto.Bool(true),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected initialization error: %v", err)
+	}
+	s := si[0].Surfacer
+
+	window := func(totals ...int64) {
+		numReceived := len(ts.received)
+		for _, total := range totals {
+			s.Write(context.Background(), metrics.NewEventMetrics(time.Now()).
+				AddMetric("total", metrics.NewInt(total)).
+				AddLabel("ptype", "http"))
+		}
+		if len(ts.received) != numReceived {
+			t.Errorf("Metrics written to the surfacer before the end of the window: %v", ts.received[numReceived:])
+		}
+		// Closing the surfacer flushes the current window.
+		s.(Closer).Close()
+	}
+
+	window(1, 2, 3)
+	window(5, 8)
+
+	// Rolled up metrics are converted to gauge per window.
+	var got []int64
+	for _, em := range ts.received {
+		got = append(got, em.Metric("total").(metrics.NumValue).Int64())
+	}
+	if !reflect.DeepEqual(got, []int64{3, 5}) {
+		t.Errorf("Got totals: %v, want: [3 5]", got)
+	}
+}
+
+func TestMaxCardinality(t *testing.T) {
+	ts := &testSurfacer{}
+	Register("s1", ts)
+
+	si, err := Init(context.Background(), []*surfacerpb.SurfacerDef{
+		{
+			Name:           proto.String("s1"),
+			Type:           surfacerpb.Type_USER_DEFINED.Enum(),
+			MaxCardinality: proto.Int32(5),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected initialization error: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		si[0].Surfacer.Write(context.Background(), metrics.NewEventMetrics(time.Now()).
+			AddMetric("total", metrics.NewInt(int64(i))).
+			AddLabel("ptype", "http").
+			AddLabel("dst", fmt.Sprintf("target%d", i%10)))
+	}
+
+	// Only the first 5 targets get through, twice each.
+	if len(ts.received) != 10 {
+		t.Fatalf("Received %d EventMetrics, want 10: %v", len(ts.received), ts.received)
+	}
+	for i, em := range ts.received {
+		if want := fmt.Sprintf("target%d", i%5); em.Label("dst") != want {
+			t.Errorf("EventMetrics %d: dst=%s, want=%s", i, em.Label("dst"), want)
+		}
+	}
+
+	// Dropped metrics are exported as the surfacer's stats.
+	wantStats := "labels=ptype=sysvars,probe=sysvars,surfacer=s1 cardinality_dropped_metrics=10"
+	if ems := StatsEventMetrics(time.Now(), si); len(ems) != 1 || !strings.HasSuffix(ems[0].String(), wantStats) {
+		t.Errorf("Stats EventMetrics: %v, want (suffix): %s", ems, wantStats)
+	}
+}
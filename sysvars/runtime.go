@@ -20,6 +20,7 @@ import (
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 )
 
 func runtimeVars(dataChan chan *metrics.EventMetrics, l *logger.Logger) {
@@ -29,6 +30,7 @@ func runtimeVars(dataChan chan *metrics.EventMetrics, l *logger.Logger) {
 	osRuntimeVars(dataChan, l)
 	counterRuntimeVars(dataChan, ts, m, l)
 	gaugeRuntimeVars(dataChan, ts, m, l)
+	probeRuntimeVars(dataChan, ts, l)
 }
 
 // counterRuntimeVars exports counter runtime stats, stats that grow through
@@ -69,3 +71,12 @@ func gaugeRuntimeVars(dataChan chan *metrics.EventMetrics, ts time.Time, m *runt
 	dataChan <- em
 	l.Debug(em.String())
 }
+
+// probeRuntimeVars exports probes' runtime stats, e.g. number of goroutines
+// and consecutive failures, as GAUGE EventMetrics.
+func probeRuntimeVars(dataChan chan *metrics.EventMetrics, ts time.Time, l *logger.Logger) {
+	for _, em := range runstats.Default().EventMetrics(ts) {
+		dataChan <- em
+		l.Debug(em.String())
+	}
+}
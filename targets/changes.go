@@ -0,0 +1,142 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package targets
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+)
+
+// Target change types.
+const (
+	TargetAdded   = "added"
+	TargetRemoved = "removed"
+)
+
+// Reasons for the target changes, other than the targets source updates.
+const (
+	// Targets listed for the first time.
+	ReasonInitial = "initial"
+	// Target was removed because it was lameducked, or added back after the
+	// lameduck expired.
+	ReasonLameduck = "lameduck"
+)
+
+// ChangeEvent describes the addition or removal of a target from a probe's
+// active targets set.
+type ChangeEvent struct {
+	Probe  string `json:"probe"`
+	Target string `json:"target"`
+	Change string `json:"change"` // TargetAdded or TargetRemoved.
+
+	// Reason is ReasonInitial, ReasonLameduck, or the targets source update,
+	// e.g. "rds_targets update".
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ChangeListener is called for each target change event.
+type ChangeListener func(*ChangeEvent)
+
+// changeTracker diffs the successive targets lists to generate change events.
+type changeTracker struct {
+	probe    string
+	reason   string // Reason for the targets source updates.
+	listener ChangeListener
+
+	mu         sync.Mutex
+	current    map[string]bool // Nil until the first update.
+	lameducked map[string]bool
+	now        func() time.Time
+}
+
+// update diffs the given targets list with the previous one, and notifies the
+// listener of the changes. lameducked are the targets excluded from the list
+// because of lameduck.
+func (ct *changeTracker) update(list []endpoint.Endpoint, lameducked map[string]bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	reason := ct.reason
+	if ct.current == nil {
+		reason = ReasonInitial
+	}
+
+	next := make(map[string]bool, len(list))
+	var added, removed []string
+	for _, ep := range list {
+		next[ep.Name] = true
+		if !ct.current[ep.Name] {
+			added = append(added, ep.Name)
+		}
+	}
+	for name := range ct.current {
+		if !next[name] {
+			removed = append(removed, name)
+		}
+	}
+	prevLameducked := ct.lameducked
+	ct.current, ct.lameducked = next, lameducked
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	ts := ct.now()
+	notify := func(target, change, reason string) {
+		ct.listener(&ChangeEvent{
+			Probe:     ct.probe,
+			Target:    target,
+			Change:    change,
+			Reason:    reason,
+			Timestamp: ts,
+		})
+	}
+	for _, name := range added {
+		r := reason
+		if prevLameducked[name] {
+			r = ReasonLameduck
+		}
+		notify(name, TargetAdded, r)
+	}
+	for _, name := range removed {
+		r := reason
+		if lameducked[name] {
+			r = ReasonLameduck
+		}
+		notify(name, TargetRemoved, r)
+	}
+}
+
+// WatchChanges makes the targets notify the listener whenever a target is
+// added to, or removed from, the list returned by ListEndpoints. Changes are
+// detected by diffing the successive ListEndpoints results, so they are
+// noticed only as often as the probe lists its targets. It returns an error
+// if targets were not created using New.
+func WatchChanges(t Targets, probe string, listener ChangeListener) error {
+	tgts, ok := t.(*targets)
+	if !ok {
+		return errors.New("targets change notifications are supported only for the targets created using targets.New")
+	}
+	tgts.changes = &changeTracker{
+		probe:    probe,
+		reason:   tgts.typ + " update",
+		listener: listener,
+		now:      time.Now,
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package targets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	targetspb "github.com/cloudprober/cloudprober/targets/proto"
+)
+
+func endpoints(names ...string) []endpoint.Endpoint {
+	var eps []endpoint.Endpoint
+	for _, name := range names {
+		eps = append(eps, endpoint.Endpoint{Name: name, LastUpdated: time.Unix(1000, 0)})
+	}
+	return eps
+}
+
+func TestWatchChanges(t *testing.T) {
+	lister, ldLister := &mockLister{}, &mockLister{}
+	tgts, err := baseTargets(&targetspb.TargetsDef{
+		Type: &targetspb.TargetsDef_FileTargets{},
+	}, ldLister, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error building targets: %v", err)
+	}
+	tgts.lister = lister
+
+	var events []ChangeEvent
+	if err := WatchChanges(tgts, "p1", func(ev *ChangeEvent) { events = append(events, *ev) }); err != nil {
+		t.Fatalf("WatchChanges(): %v", err)
+	}
+	ts := time.Unix(2000, 0)
+	tgts.changes.now = func() time.Time { return ts }
+
+	ev := func(target, change, reason string) ChangeEvent {
+		return ChangeEvent{Probe: "p1", Target: target, Change: change, Reason: reason, Timestamp: ts}
+	}
+
+	for _, test := range []struct {
+		desc       string
+		targets    []endpoint.Endpoint
+		lameducks  []endpoint.Endpoint
+		wantEvents []ChangeEvent
+	}{
+		{
+			desc:    "initial",
+			targets: endpoints("t1", "t2"),
+			wantEvents: []ChangeEvent{
+				ev("t1", TargetAdded, ReasonInitial),
+				ev("t2", TargetAdded, ReasonInitial),
+			},
+		},
+		{
+			desc:    "no_change",
+			targets: endpoints("t2", "t1"),
+		},
+		{
+			desc:    "add_and_remove",
+			targets: endpoints("t2", "t3"),
+			wantEvents: []ChangeEvent{
+				ev("t3", TargetAdded, "file_targets update"),
+				ev("t1", TargetRemoved, "file_targets update"),
+			},
+		},
+		{
+			desc:       "lameduck",
+			targets:    endpoints("t2", "t3"),
+			lameducks:  endpoints("t3"),
+			wantEvents: []ChangeEvent{ev("t3", TargetRemoved, ReasonLameduck)},
+		},
+		{
+			desc:       "lameduck_removed",
+			targets:    endpoints("t2", "t3"),
+			wantEvents: []ChangeEvent{ev("t3", TargetAdded, ReasonLameduck)},
+		},
+		{
+			desc:    "all_removed",
+			targets: nil,
+			wantEvents: []ChangeEvent{
+				ev("t2", TargetRemoved, "file_targets update"),
+				ev("t3", TargetRemoved, "file_targets update"),
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			events = nil
+			lister.list, ldLister.list = test.targets, test.lameducks
+			tgts.ListEndpoints()
+			if !reflect.DeepEqual(events, test.wantEvents) {
+				t.Errorf("Got events:\n%+v\nwant:\n%+v", events, test.wantEvents)
+			}
+		})
+	}
+}
+
+func TestWatchChangesUnsupported(t *testing.T) {
+	if err := WatchChanges(&dummy{}, "p1", func(*ChangeEvent) {}); err == nil {
+		t.Error("WatchChanges(): expected error for targets not created using New")
+	}
+}
@@ -33,7 +33,9 @@ import (
 // New returns new file targets.
 func New(opts *configpb.TargetsConf, res *dnsRes.Resolver, l *logger.Logger) (*client.Client, error) {
 	lister, err := file.New(&file_configpb.ProviderConfig{
-		FilePath: []string{opts.GetFilePath()},
+		FilePath:  []string{opts.GetFilePath()},
+		Format:    opts.Format,
+		ReEvalSec: opts.ReEvalSec,
 	}, l)
 	if err != nil {
 		return nil, err
@@ -15,8 +15,12 @@
 package file
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	rdspb "github.com/cloudprober/cloudprober/rds/proto"
 	"github.com/cloudprober/cloudprober/targets/endpoint"
@@ -113,3 +117,68 @@ func TestListEndpointsWithFilter(t *testing.T) {
 	}
 
 }
+
+func TestFileReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_targets_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fPath := filepath.Join(dir, "targets.txt")
+
+	// writeFile writes the given content to the targets file, and moves its
+	// modified time forward to make sure that the change gets noticed.
+	mtime := time.Now()
+	writeFile := func(content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(fPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime = mtime.Add(time.Minute)
+		if err := os.Chtimes(fPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(eps []endpoint.Endpoint) []string {
+		var result []string
+		for _, ep := range eps {
+			result = append(result, ep.Name)
+		}
+		return result
+	}
+
+	writeFile("# Test targets\nhost-1:8080\nhost-2:8080\n")
+
+	ft, err := New(&configpb.TargetsConf{
+		FilePath:  proto.String(fPath),
+		ReEvalSec: proto.Int32(1),
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantNames := []string{"host-1", "host-2"}
+	if got := names(ft.ListEndpoints()); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("Initial targets: got=%v, want=%v", got, wantNames)
+	}
+
+	// Modify the file and wait for the targets to get updated.
+	writeFile("host-1:8080\n\nhost-3:8080 app=web\n")
+	wantNames = []string{"host-1", "host-3"}
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if reflect.DeepEqual(names(ft.ListEndpoints()), wantNames) {
+			break
+		}
+	}
+	if got := names(ft.ListEndpoints()); !reflect.DeepEqual(got, wantNames) {
+		t.Fatalf("Targets after reload: got=%v, want=%v", got, wantNames)
+	}
+
+	// A bad file should be ignored, and last good targets should be retained.
+	writeFile("host-4:badport\n")
+	time.Sleep(2500 * time.Millisecond)
+	if got := names(ft.ListEndpoints()); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("Targets after bad reload: got=%v, want=%v", got, wantNames)
+	}
+}
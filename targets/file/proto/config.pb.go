@@ -29,7 +29,9 @@ type TargetsConf struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// File that contains resources in either textproto or json format.
+	// File that contains resources in either textproto, json or plaintext
+	// format. See cloudprober.rds.file.ProviderConfig.Format for details of the
+	// plaintext format.
 	// Example in textproto format:
 	//
 	// resource {
@@ -49,7 +51,9 @@ type TargetsConf struct {
 	FilePath *string                       `protobuf:"bytes,1,opt,name=file_path,json=filePath" json:"file_path,omitempty"`
 	Filter   []*proto.Filter               `protobuf:"bytes,2,rep,name=filter" json:"filter,omitempty"`
 	Format   *proto1.ProviderConfig_Format `protobuf:"varint,3,opt,name=format,enum=cloudprober.rds.file.ProviderConfig_Format" json:"format,omitempty"`
-	// If specified, file will be re-read at the given interval.
+	// If specified, file will be re-read at the given interval, if it has been
+	// modified since the last read. If there is an error in parsing the file,
+	// last successfully parsed targets are retained.
 	ReEvalSec *int32 `protobuf:"varint,4,opt,name=re_eval_sec,json=reEvalSec" json:"re_eval_sec,omitempty"`
 }
 
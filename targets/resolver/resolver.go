@@ -28,6 +28,10 @@ import (
 // The max age and the timeout for resolving a target.
 const defaultMaxAge = 5 * time.Minute
 
+// The max age for the failed resolutions. We keep it short so that we don't
+// keep serving the errors for long after a name starts resolving.
+const defaultNegativeMaxAge = 30 * time.Second
+
 type cacheRecord struct {
 	ip4              net.IP
 	ip6              net.IP
@@ -38,12 +42,18 @@ type cacheRecord struct {
 	callInit         sync.Once
 }
 
-// Resolver provides an asynchronous caching DNS resolver.
+// Resolver provides an asynchronous caching DNS resolver. Cache records are
+// keyed by name, and each record holds both, IPv4 and IPv6, addresses for the
+// name, i.e. a single lookup serves all IP versions.
 type Resolver struct {
 	cache         map[string]*cacheRecord
 	mu            sync.Mutex
 	DefaultMaxAge time.Duration
-	resolve       func(string) ([]net.IP, error) // used for testing
+	// negativeMaxAge is the max age for failed resolutions. If it's larger
+	// than the max age, max age is used instead. If it's 0, failed
+	// resolutions are not cached, i.e. they are retried on the next request.
+	negativeMaxAge time.Duration
+	resolve        func(string) ([]net.IP, error) // used for testing
 }
 
 // ipVersion tells if an IP address is IPv4 or IPv6.
@@ -79,19 +89,33 @@ func (r *Resolver) resolveOrTimeout(name string) ([]net.IP, error) {
 	}
 }
 
+// SetMaxAge sets the max age for the cache records, and for the failed
+// resolutions. If negativeMaxAge is 0, failed resolutions are not cached. It's
+// safe to call it while the resolver is in use.
+func (r *Resolver) SetMaxAge(maxAge, negativeMaxAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.DefaultMaxAge = maxAge
+	r.negativeMaxAge = negativeMaxAge
+}
+
 // Resolve returns IP address for a name.
 // Issues an update call for the cache record if it's older than defaultMaxAge.
 func (r *Resolver) Resolve(name string, ipVer int) (net.IP, error) {
+	r.mu.Lock()
 	maxAge := r.DefaultMaxAge
+	r.mu.Unlock()
+
 	if maxAge == 0 {
 		maxAge = defaultMaxAge
 	}
 	return r.resolveWithMaxAge(name, ipVer, maxAge, nil)
 }
 
-// getCacheRecord returns the cache record for the target.
-// It must be kept light, as it blocks the main mutex of the map.
-func (r *Resolver) getCacheRecord(name string) *cacheRecord {
+// getCacheRecord returns the cache record for the target, along with the
+// current negative max age. It must be kept light, as it blocks the main
+// mutex of the map.
+func (r *Resolver) getCacheRecord(name string) (*cacheRecord, time.Duration) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	cr := r.cache[name]
@@ -101,7 +125,7 @@ func (r *Resolver) getCacheRecord(name string) *cacheRecord {
 		}
 		r.cache[name] = cr
 	}
-	return cr
+	return cr, r.negativeMaxAge
 }
 
 // resolveWithMaxAge returns IP address for a name, issuing an update call for
@@ -110,8 +134,8 @@ func (r *Resolver) getCacheRecord(name string) *cacheRecord {
 // refreshed channel once and if the value is refreshed, or false, if it
 // doesn't need refreshing.
 func (r *Resolver) resolveWithMaxAge(name string, ipVer int, maxAge time.Duration, refreshed chan<- bool) (net.IP, error) {
-	cr := r.getCacheRecord(name)
-	cr.refreshIfRequired(name, r.resolveOrTimeout, maxAge, refreshed)
+	cr, negativeMaxAge := r.getCacheRecord(name)
+	cr.refreshIfRequired(name, r.resolveOrTimeout, maxAge, negativeMaxAge, refreshed)
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
 
@@ -174,11 +198,16 @@ func (cr *cacheRecord) refresh(name string, resolve func(string) ([]net.IP, erro
 // If cache record is new, blocks until it's resolved for the first time.
 // If cache record needs updating, kicks off refresh asynchronously.
 // If cache record is already being updated or fresh enough, returns immediately.
-func (cr *cacheRecord) refreshIfRequired(name string, resolve func(string) ([]net.IP, error), maxAge time.Duration, refreshed chan<- bool) {
+// Failed resolutions are considered fresh only for negativeMaxAge.
+func (cr *cacheRecord) refreshIfRequired(name string, resolve func(string) ([]net.IP, error), maxAge, negativeMaxAge time.Duration, refreshed chan<- bool) {
 	cr.callInit.Do(func() { cr.refresh(name, resolve, refreshed) })
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
 
+	if cr.err != nil && negativeMaxAge < maxAge {
+		maxAge = negativeMaxAge
+	}
+
 	// Cache record is old and no update in progress, issue a request to update.
 	if !cr.updateInProgress && time.Since(cr.lastUpdatedAt) >= maxAge {
 		cr.updateInProgress = true
@@ -192,9 +221,10 @@ func (cr *cacheRecord) refreshIfRequired(name string, resolve func(string) ([]ne
 // This is useful for testing.
 func NewWithResolve(resolveFunc func(string) ([]net.IP, error)) *Resolver {
 	return &Resolver{
-		cache:         make(map[string]*cacheRecord),
-		resolve:       resolveFunc,
-		DefaultMaxAge: defaultMaxAge,
+		cache:          make(map[string]*cacheRecord),
+		resolve:        resolveFunc,
+		DefaultMaxAge:  defaultMaxAge,
+		negativeMaxAge: defaultNegativeMaxAge,
 	}
 }
 
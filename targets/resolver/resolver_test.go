@@ -279,6 +279,119 @@ func TestConcurrentInit(t *testing.T) {
 	wg.Wait()
 }
 
+// TestConcurrentResolves verifies that concurrent resolves of the same name,
+// e.g. from different probes, result in a single backend lookup.
+func TestConcurrentResolves(t *testing.T) {
+	b := &resolveBackendWithTracking{
+		nameToIP: map[string][]net.IP{"hostA": {net.ParseIP("1.2.3.4"), net.ParseIP("::1")}},
+	}
+	r := NewWithResolve(func(name string) ([]net.IP, error) {
+		time.Sleep(10 * time.Millisecond)
+		return b.resolve(name)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(ipVer int) {
+			defer wg.Done()
+			if _, err := r.Resolve("hostA", ipVer); err != nil {
+				t.Errorf("Error resolving hostA for IP version %d: %v", ipVer, err)
+			}
+		}([]int{0, 4, 6}[i%3])
+	}
+	wg.Wait()
+
+	if b.calls() != 1 {
+		t.Errorf("Backend calls: %d, want: 1", b.calls())
+	}
+}
+
+func TestNegativeCaching(t *testing.T) {
+	var mu sync.Mutex
+	calls, fail := 0, true
+	r := NewWithResolve(func(name string) ([]net.IP, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if fail {
+			return nil, fmt.Errorf("lookup failed for %s", name)
+		}
+		return []net.IP{net.ParseIP("1.2.3.4")}, nil
+	})
+	r.SetMaxAge(defaultMaxAge, 50*time.Millisecond)
+
+	refreshed := make(chan bool, 2)
+	if _, err := r.resolveWithMaxAge("hostA", 4, time.Minute, refreshed); err == nil {
+		t.Errorf("Expected error, got no error")
+	}
+	// Initial refresh, followed by no refresh as the error is still fresh.
+	if !waitForChannelOrFail(t, refreshed, time.Second) {
+		t.Errorf("refreshed returned false, want true")
+	}
+	if waitForChannelOrFail(t, refreshed, time.Second) {
+		t.Errorf("refreshed returned true, want false")
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	// Negative result expires after NegativeMaxAge, even though max age is
+	// much longer.
+	time.Sleep(100 * time.Millisecond)
+	if _, err := r.resolveWithMaxAge("hostA", 4, time.Minute, refreshed); err == nil {
+		t.Errorf("Expected stale error, got no error")
+	}
+	if !waitForChannelOrFail(t, refreshed, time.Second) {
+		t.Errorf("refreshed returned false, want true")
+	}
+
+	ip, err := r.resolveWithMaxAge("hostA", 4, time.Minute, refreshed)
+	verify("after-negative-cache-expiry", t, ip, net.ParseIP("1.2.3.4"), calls, 2, err)
+	if waitForChannelOrFail(t, refreshed, time.Second) {
+		t.Errorf("refreshed returned true, want false")
+	}
+}
+
+func TestNegativeCachingDisabled(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	r := NewWithResolve(func(name string) ([]net.IP, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil, fmt.Errorf("lookup failed for %s", name)
+	})
+	r.SetMaxAge(time.Minute, 0)
+
+	refreshed := make(chan bool, 2)
+	if _, err := r.resolveWithMaxAge("hostA", 4, time.Minute, refreshed); err == nil {
+		t.Errorf("Expected error, got no error")
+	}
+	// Failed resolutions are not cached: initial lookup is immediately
+	// followed by a refresh.
+	for i := 0; i < 2; i++ {
+		if !waitForChannelOrFail(t, refreshed, time.Second) {
+			t.Errorf("refreshed returned false, want true")
+		}
+	}
+
+	// Next request triggers a refresh again.
+	if _, err := r.resolveWithMaxAge("hostA", 4, time.Minute, refreshed); err == nil {
+		t.Errorf("Expected error, got no error")
+	}
+	if !waitForChannelOrFail(t, refreshed, time.Second) {
+		t.Errorf("refreshed returned false, want true")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 3 {
+		t.Errorf("Backend calls: %d, want: 3", calls)
+	}
+}
+
 // Set up benchmarks. Apart from performance stats it verifies the library's behavior during concurrent
 // runs. It's kind of important as we use mutexes a lot, even though never in long running path, e.g.
 // actual backend resolver is called outside mutexes.
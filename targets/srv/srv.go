@@ -0,0 +1,251 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package srv implements DNS SRV record based targets for cloudprober.
+
+SRV targets resolve the configured SRV record periodically and expand it into
+endpoints, one per record in the answer, carrying the record's target host and
+port. Example config:
+
+	targets {
+	  srv_targets {
+	    name: "_grpc._tcp.service.example.com"
+	  }
+	}
+*/
+package srv
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	dnsRes "github.com/cloudprober/cloudprober/targets/resolver"
+	configpb "github.com/cloudprober/cloudprober/targets/srv/proto"
+	"github.com/miekg/dns"
+)
+
+const resolvConfFile = "/etc/resolv.conf"
+
+// lookupFunc looks up the SRV records for the given name. It's mainly
+// overridden in tests.
+type lookupFunc func(name string) ([]*dns.SRV, error)
+
+// Targets implements SRV record based targets.
+type Targets struct {
+	c      *configpb.TargetsConf
+	r      *dnsRes.Resolver
+	l      *logger.Logger
+	lookup lookupFunc
+
+	minInterval, maxInterval time.Duration
+
+	mu        sync.RWMutex
+	endpoints []endpoint.Endpoint
+
+	// stop is closed by Close to stop the refresh loop.
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// ListEndpoints returns the endpoints from the last successful SRV lookup.
+func (t *Targets) ListEndpoints() []endpoint.Endpoint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]endpoint.Endpoint{}, t.endpoints...)
+}
+
+// Resolve resolves the target host (from SRV records) to an IP address.
+func (t *Targets) Resolve(name string, ipVer int) (net.IP, error) {
+	return t.r.Resolve(name, ipVer)
+}
+
+// endpointsFromRecords converts SRV records into endpoints, ordered by
+// priority (ascending) and weight (descending), as per RFC 2782 preference.
+func endpointsFromRecords(records []*dns.SRV) []endpoint.Endpoint {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Priority != records[j].Priority {
+			return records[i].Priority < records[j].Priority
+		}
+		return records[i].Weight > records[j].Weight
+	})
+
+	eps := make([]endpoint.Endpoint, len(records))
+	for i, rec := range records {
+		eps[i] = endpoint.Endpoint{
+			Name: strings.TrimSuffix(rec.Target, "."),
+			Port: int(rec.Port),
+			Labels: map[string]string{
+				"priority": strconv.Itoa(int(rec.Priority)),
+				"weight":   strconv.Itoa(int(rec.Weight)),
+			},
+		}
+	}
+	return eps
+}
+
+// refresh looks up the SRV record and updates the endpoints. It returns the
+// duration after which the record should be looked up again. On lookup
+// failures, previous endpoints are retained.
+func (t *Targets) refresh() time.Duration {
+	records, err := t.lookup(t.c.GetName())
+	if err != nil {
+		t.l.Errorf("srv: error resolving SRV record %s, keeping the previous targets. Err: %v", t.c.GetName(), err)
+		return t.minInterval
+	}
+	if len(records) == 0 {
+		t.l.Warningf("srv: no SRV records found for %s, keeping the previous targets", t.c.GetName())
+		return t.minInterval
+	}
+
+	// Next refresh is determined by the lowest TTL in the answer.
+	ttl := time.Duration(records[0].Hdr.Ttl) * time.Second
+	for _, rec := range records[1:] {
+		if d := time.Duration(rec.Hdr.Ttl) * time.Second; d < ttl {
+			ttl = d
+		}
+	}
+
+	eps := endpointsFromRecords(records)
+	t.l.Debugf("srv: SRV record %s resolved to %d targets, ttl: %v", t.c.GetName(), len(eps), ttl)
+
+	t.mu.Lock()
+	t.endpoints = eps
+	t.mu.Unlock()
+
+	if ttl < t.minInterval {
+		return t.minInterval
+	}
+	if ttl > t.maxInterval {
+		return t.maxInterval
+	}
+	return ttl
+}
+
+// refreshLoop refreshes the SRV record after the given interval, and then
+// keeps refreshing it as per the intervals returned by refresh, until Close is
+// called.
+func (t *Targets) refreshLoop(interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-timer.C:
+			timer.Reset(t.refresh())
+		}
+	}
+}
+
+// Close stops refreshing the SRV record. Endpoints from the last successful
+// lookup are retained.
+func (t *Targets) Close() {
+	t.closeOnce.Do(func() { close(t.stop) })
+}
+
+func dnsServer(c *configpb.TargetsConf) (string, error) {
+	if c.GetDnsServer() != "" {
+		if _, _, err := net.SplitHostPort(c.GetDnsServer()); err != nil {
+			return net.JoinHostPort(c.GetDnsServer(), "53"), nil
+		}
+		return c.GetDnsServer(), nil
+	}
+
+	cc, err := dns.ClientConfigFromFile(resolvConfFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", resolvConfFile, err)
+	}
+	if len(cc.Servers) == 0 {
+		return "", fmt.Errorf("no nameservers found in %s", resolvConfFile)
+	}
+	return net.JoinHostPort(cc.Servers[0], cc.Port), nil
+}
+
+func dnsLookupFunc(server string) lookupFunc {
+	client := new(dns.Client)
+	return func(name string) ([]*dns.SRV, error) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(name), dns.TypeSRV)
+
+		resp, _, err := client.Exchange(msg, server)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			return nil, fmt.Errorf("bad response code: %s", dns.RcodeToString[resp.Rcode])
+		}
+
+		var records []*dns.SRV
+		for _, rr := range resp.Answer {
+			if srv, ok := rr.(*dns.SRV); ok {
+				records = append(records, srv)
+			}
+		}
+		return records, nil
+	}
+}
+
+func newTargets(c *configpb.TargetsConf, lookup lookupFunc, res *dnsRes.Resolver, l *logger.Logger) (*Targets, error) {
+	if c.GetName() == "" {
+		return nil, errors.New("srv: SRV record name cannot be empty")
+	}
+	if c.GetMinReEvalSec() <= 0 || c.GetMaxReEvalSec() < c.GetMinReEvalSec() {
+		return nil, fmt.Errorf("srv: invalid refresh interval bounds: min_re_eval_sec=%d, max_re_eval_sec=%d", c.GetMinReEvalSec(), c.GetMaxReEvalSec())
+	}
+
+	if res == nil {
+		res = dnsRes.New()
+	}
+	if l == nil {
+		l = &logger.Logger{}
+	}
+
+	return &Targets{
+		c:           c,
+		r:           res,
+		l:           l,
+		lookup:      lookup,
+		minInterval: time.Duration(c.GetMinReEvalSec()) * time.Second,
+		maxInterval: time.Duration(c.GetMaxReEvalSec()) * time.Second,
+		stop:        make(chan struct{}),
+	}, nil
+}
+
+// New returns new SRV targets. It resolves the SRV record once before
+// returning and then keeps refreshing it in the background, until Close is
+// called.
+func New(c *configpb.TargetsConf, res *dnsRes.Resolver, l *logger.Logger) (*Targets, error) {
+	server, err := dnsServer(c)
+	if err != nil {
+		return nil, fmt.Errorf("srv: %v", err)
+	}
+
+	t, err := newTargets(c, dnsLookupFunc(server), res, l)
+	if err != nil {
+		return nil, err
+	}
+
+	go t.refreshLoop(t.refresh())
+	return t, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 The Cloudprober Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package srv
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudprober/cloudprober/targets/endpoint"
+	configpb "github.com/cloudprober/cloudprober/targets/srv/proto"
+	"github.com/miekg/dns"
+	"google.golang.org/protobuf/proto"
+)
+
+type mockResolver struct {
+	records []*dns.SRV
+	err     error
+}
+
+func (mr *mockResolver) lookup(name string) ([]*dns.SRV, error) {
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	// Return a copy as the caller sorts the slice.
+	return append([]*dns.SRV{}, mr.records...), nil
+}
+
+func srvRecord(target string, port, priority, weight uint16, ttl uint32) *dns.SRV {
+	return &dns.SRV{
+		Hdr:      dns.RR_Header{Name: "_grpc._tcp.svc.example.com.", Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: ttl},
+		Target:   target,
+		Port:     port,
+		Priority: priority,
+		Weight:   weight,
+	}
+}
+
+func testEndpoint(name string, port int, priority, weight string) endpoint.Endpoint {
+	return endpoint.Endpoint{
+		Name:   name,
+		Port:   port,
+		Labels: map[string]string{"priority": priority, "weight": weight},
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	mr := &mockResolver{
+		records: []*dns.SRV{
+			srvRecord("backend-3.example.com.", 9003, 20, 10, 60),
+			srvRecord("backend-1.example.com.", 9001, 10, 5, 120),
+			srvRecord("backend-2.example.com.", 9002, 10, 50, 30),
+		},
+	}
+
+	tgts, err := newTargets(&configpb.TargetsConf{
+		Name:         proto.String("_grpc._tcp.svc.example.com"),
+		MinReEvalSec: proto.Int32(10),
+		MaxReEvalSec: proto.Int32(300),
+	}, mr.lookup, nil, nil)
+	if err != nil {
+		t.Fatalf("Error creating SRV targets: %v", err)
+	}
+
+	// Next refresh should happen after the lowest TTL in the answer.
+	if got := tgts.refresh(); got != 30*time.Second {
+		t.Errorf("refresh interval=%v, want=%v", got, 30*time.Second)
+	}
+
+	wantEndpoints := []endpoint.Endpoint{
+		testEndpoint("backend-2.example.com", 9002, "10", "50"),
+		testEndpoint("backend-1.example.com", 9001, "10", "5"),
+		testEndpoint("backend-3.example.com", 9003, "20", "10"),
+	}
+	if got := tgts.ListEndpoints(); !reflect.DeepEqual(got, wantEndpoints) {
+		t.Errorf("ListEndpoints()=%v, want=%v", got, wantEndpoints)
+	}
+
+	// Resolution failure should keep the previous targets.
+	mr.err = errors.New("SERVFAIL")
+	if got := tgts.refresh(); got != 10*time.Second {
+		t.Errorf("refresh interval after error=%v, want=%v", got, 10*time.Second)
+	}
+	if got := tgts.ListEndpoints(); !reflect.DeepEqual(got, wantEndpoints) {
+		t.Errorf("ListEndpoints() after error=%v, want=%v", got, wantEndpoints)
+	}
+
+	// Records changed.
+	mr.err = nil
+	mr.records = []*dns.SRV{srvRecord("backend-4.example.com.", 9004, 0, 0, 3600)}
+	if got := tgts.refresh(); got != 300*time.Second {
+		t.Errorf("refresh interval for high TTL=%v, want=%v", got, 300*time.Second)
+	}
+	wantEndpoints = []endpoint.Endpoint{testEndpoint("backend-4.example.com", 9004, "0", "0")}
+	if got := tgts.ListEndpoints(); !reflect.DeepEqual(got, wantEndpoints) {
+		t.Errorf("ListEndpoints()=%v, want=%v", got, wantEndpoints)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var mu sync.Mutex
+	lookups := 0
+	lookup := func(name string) ([]*dns.SRV, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		lookups++
+		return []*dns.SRV{srvRecord("backend-1.example.com.", 9001, 0, 0, 0)}, nil
+	}
+
+	tgts, err := newTargets(&configpb.TargetsConf{
+		Name:         proto.String("_grpc._tcp.svc.example.com"),
+		MinReEvalSec: proto.Int32(1),
+		MaxReEvalSec: proto.Int32(1),
+	}, lookup, nil, nil)
+	if err != nil {
+		t.Fatalf("Error creating SRV targets: %v", err)
+	}
+	tgts.minInterval, tgts.maxInterval = time.Millisecond, time.Millisecond
+
+	stopped := make(chan struct{})
+	go func() {
+		tgts.refreshLoop(time.Millisecond)
+		close(stopped)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	tgts.Close()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Refresh loop didn't stop after Close")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if lookups == 0 {
+		t.Error("SRV record was not refreshed before Close")
+	}
+
+	// Close should be safe to call more than once.
+	tgts.Close()
+}
+
+func TestNewTargetsErrors(t *testing.T) {
+	for _, c := range []*configpb.TargetsConf{
+		{},
+		{Name: proto.String("_http._tcp.example.com"), MinReEvalSec: proto.Int32(0)},
+		{Name: proto.String("_http._tcp.example.com"), MinReEvalSec: proto.Int32(60), MaxReEvalSec: proto.Int32(30)},
+	} {
+		if _, err := newTargets(c, (&mockResolver{}).lookup, nil, nil); err == nil {
+			t.Errorf("Expected error for config: %v", c)
+		}
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	for _, test := range []struct {
+		server, want string
+	}{
+		{"10.0.0.53", "10.0.0.53:53"},
+		{"10.0.0.53:5353", "10.0.0.53:5353"},
+		{"2001:db8::53", "[2001:db8::53]:53"},
+	} {
+		got, err := dnsServer(&configpb.TargetsConf{DnsServer: proto.String(test.server)})
+		if err != nil {
+			t.Errorf("dnsServer(%s): unexpected error: %v", test.server, err)
+		}
+		if got != test.want {
+			t.Errorf("dnsServer(%s)=%s, want=%s", test.server, got, test.want)
+		}
+	}
+}
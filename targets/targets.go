@@ -42,6 +42,7 @@ import (
 	"github.com/cloudprober/cloudprober/targets/gce"
 	targetspb "github.com/cloudprober/cloudprober/targets/proto"
 	dnsRes "github.com/cloudprober/cloudprober/targets/resolver"
+	"github.com/cloudprober/cloudprober/targets/srv"
 )
 
 // globalResolver is a singleton DNS resolver that is used as the default
@@ -82,6 +83,13 @@ type Targets interface {
 	resolver
 }
 
+// Closer is implemented by the targets that keep refreshing themselves in the
+// background, e.g. SRV targets and extension targets that need cleanup. Close stops the refreshing; it should be
+// called once the targets are not going to be used anymore.
+type Closer interface {
+	Close()
+}
+
 type resolver interface {
 	// Resolve, given a target and IP Version will return the IP address for that
 	// target.
@@ -128,6 +136,20 @@ type targets struct {
 	re       *regexp.Regexp
 	ldLister endpoint.Lister
 	l        *logger.Logger
+
+	typ     string         // Targets type, e.g. "rds_targets".
+	changes *changeTracker // Set through WatchChanges.
+
+	// Core lister's closer, set only for the targets types that refresh
+	// themselves in the background.
+	closer Closer
+}
+
+// Close stops the core lister's background refreshing, if any.
+func (t *targets) Close() {
+	if t.closer != nil {
+		t.closer.Close()
+	}
 }
 
 // Resolve either resolves a target using the core resolver, or returns an error
@@ -200,16 +222,28 @@ func (t *targets) ListEndpoints() []endpoint.Endpoint {
 	list = t.lister.ListEndpoints()
 
 	ldMap := t.lameduckMap()
+	var lameducked map[string]bool
 	if t.re != nil || len(ldMap) != 0 {
 		var result []endpoint.Endpoint
 		for _, ep := range list {
 			if t.includeInResult(ep, ldMap) {
 				result = append(result, ep)
+				continue
+			}
+			if _, ok := ldMap[ep.Name]; ok {
+				if lameducked == nil {
+					lameducked = make(map[string]bool)
+				}
+				lameducked[ep.Name] = true
 			}
 		}
 		list = result
 	}
 
+	if t.changes != nil {
+		t.changes.update(list, lameducked)
+	}
+
 	return list
 }
 
@@ -230,6 +264,12 @@ func baseTargets(targetsDef *targetspb.TargetsDef, ldLister endpoint.Lister, l *
 		return tgts, nil
 	}
 
+	tgts.typ = "extension"
+	m := proto.MessageReflect(targetsDef)
+	if fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("type")); fd != nil {
+		tgts.typ = string(fd.Name())
+	}
+
 	if targetsDef.GetRegex() != "" {
 		var err error
 		if tgts.re, err = regexp.Compile(targetsDef.GetRegex()); err != nil {
@@ -359,6 +399,13 @@ func New(targetsDef *targetspb.TargetsDef, ldLister endpoint.Lister, globalOpts
 		}
 		t.lister, t.resolver = ft, ft
 
+	case *targetspb.TargetsDef_SrvTargets:
+		st, err := srv.New(targetsDef.GetSrvTargets(), globalResolver, l)
+		if err != nil {
+			return nil, fmt.Errorf("target.New(): %v", err)
+		}
+		t.lister, t.resolver, t.closer = st, st, st
+
 	case *targetspb.TargetsDef_DummyTargets:
 		dummy := &dummy{}
 		t.lister, t.resolver = dummy, dummy
@@ -369,6 +416,9 @@ func New(targetsDef *targetspb.TargetsDef, ldLister endpoint.Lister, globalOpts
 			return nil, fmt.Errorf("targets.New(): %v", err)
 		}
 		t.lister, t.resolver = extT, extT
+		if c, ok := extT.(Closer); ok {
+			t.closer = c
+		}
 	}
 
 	return t, nil
@@ -415,6 +465,13 @@ func SetSharedTargets(name string, tgts Targets) {
 	sharedTargets[name] = tgts
 }
 
+// ConfigureGlobalResolver configures the global DNS resolver's cache using
+// the global targets options. It's safe to call it while the resolver is in
+// use, e.g. on a config reload.
+func ConfigureGlobalResolver(globalOpts *targetspb.GlobalTargetsOptions) {
+	globalResolver.SetMaxAge(time.Duration(globalOpts.GetDnsCacheTtlSec())*time.Second, time.Duration(globalOpts.GetDnsNegativeCacheTtlSec())*time.Second)
+}
+
 // init initializes the package by creating a new global resolver.
 func init() {
 	globalResolver = dnsRes.New()
@@ -87,7 +87,7 @@ func parseStatusCodeConfig(s string) ([]*numRange, error) {
 	var statusCodeRanges []*numRange
 
 	for _, codeStr := range strings.Split(s, ",") {
-		nr, err := parseNumRange(codeStr)
+		nr, err := parseNumRange(strings.TrimSpace(codeStr))
 		if err != nil {
 			return nil, err
 		}
@@ -109,6 +109,50 @@ func lookupStatusCode(statusCode int, statusCodeRanges []*numRange) bool {
 	return false
 }
 
+// StatusCodeRanges is a list of status codes and status code ranges.
+type StatusCodeRanges []*numRange
+
+// ParseStatusCodeRanges parses a comma-separated list of status codes and
+// status code ranges, for example: 200-299,301,404.
+func ParseStatusCodeRanges(s string) (StatusCodeRanges, error) {
+	return parseStatusCodeConfig(s)
+}
+
+// Match returns true if the status code is in one of the ranges.
+func (r StatusCodeRanges) Match(statusCode int) bool {
+	return lookupStatusCode(statusCode, r)
+}
+
+// HeaderMatcher matches HTTP response headers against a header name and an
+// optional value regex.
+type HeaderMatcher struct {
+	name        string
+	valueRegexp *regexp.Regexp
+}
+
+// NewHeaderMatcher returns a HeaderMatcher for the given header name and value
+// regex. If valueRegex is empty, it matches on header existence only.
+func NewHeaderMatcher(name, valueRegex string) (*HeaderMatcher, error) {
+	if name == "" {
+		return nil, errors.New("header name cannot be empty")
+	}
+	hm := &HeaderMatcher{name: nethttp.CanonicalHeaderKey(name)}
+	if valueRegex != "" {
+		re, err := regexp.Compile(valueRegex)
+		if err != nil {
+			return nil, err
+		}
+		hm.valueRegexp = re
+	}
+	return hm, nil
+}
+
+// Match returns true if the header is present and, if a value regex is
+// configured, one of its values matches the regex.
+func (hm *HeaderMatcher) Match(headers nethttp.Header) bool {
+	return lookupHTTPHeader(headers, hm.name, hm.valueRegexp)
+}
+
 // lookupHTTPHeader looks up for the given header in the HTTP response. It
 // returns true on the first match. If valueRegex is omitted - check for header
 // existence only.
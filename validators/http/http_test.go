@@ -93,6 +93,18 @@ func TestLookupStatusCode(t *testing.T) {
 	}
 }
 
+func TestStatusCodeRangesMatch(t *testing.T) {
+	r, err := ParseStatusCodeRanges("200-299, 301 ,404")
+	if err != nil {
+		t.Fatalf("ParseStatusCodeRanges(): unexpected error: %v", err)
+	}
+	for code, want := range map[int]bool{200: true, 250: true, 301: true, 404: true, 302: false, 500: false} {
+		if got := r.Match(code); got != want {
+			t.Errorf("Match(%d)=%v, want=%v", code, got, want)
+		}
+	}
+}
+
 func TestLookupHTTPHeader(t *testing.T) {
 	var header string
 
@@ -120,6 +132,32 @@ func TestLookupHTTPHeader(t *testing.T) {
 
 }
 
+func TestHeaderMatcher(t *testing.T) {
+	header := http.Header{"X-Health": []string{"degraded", "ok"}}
+
+	for _, test := range []struct {
+		name, regex string
+		wantMatch   bool
+	}{
+		{name: "x-health", wantMatch: true},
+		{name: "X-Health", regex: "^ok$", wantMatch: true},
+		{name: "X-Health", regex: "^down$"},
+		{name: "X-Other"},
+	} {
+		hm, err := NewHeaderMatcher(test.name, test.regex)
+		if err != nil {
+			t.Fatalf("NewHeaderMatcher(%q, %q): unexpected error: %v", test.name, test.regex, err)
+		}
+		if got := hm.Match(header); got != test.wantMatch {
+			t.Errorf("HeaderMatcher(%q, %q).Match(%v)=%v, want=%v", test.name, test.regex, header, got, test.wantMatch)
+		}
+	}
+
+	if _, err := NewHeaderMatcher("", ""); err == nil {
+		t.Error("NewHeaderMatcher with empty name: expected error")
+	}
+}
+
 func TestValidateStatusCode(t *testing.T) {
 	testConfig := &configpb.Validator{
 		SuccessStatusCodes: proto.String("200-299,301,302,404"),
@@ -18,6 +18,9 @@ package validators
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/cloudprober/cloudprober/metrics"
@@ -25,6 +28,9 @@ import (
 	"github.com/cloudprober/cloudprober/validators/integrity"
 	configpb "github.com/cloudprober/cloudprober/validators/proto"
 	"github.com/cloudprober/cloudprober/validators/regex"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // Validator implements a validator.
@@ -59,43 +65,134 @@ func Init(validatorConfs []*configpb.Validator, l *logger.Logger) ([]*Validator,
 	return validators, nil
 }
 
-func initValidator(validatorConf *configpb.Validator, l *logger.Logger) (validator *Validator, err error) {
-	validator = &Validator{Name: validatorConf.GetName()}
+// Factory creates a validator from its config. Validator's name is set by
+// the caller.
+type Factory func(cfg proto.Message, l *logger.Logger) (*Validator, error)
 
-	switch validatorConf.Type.(type) {
-	case *configpb.Validator_HttpValidator:
-		v := &http.Validator{}
-		if err := v.Init(validatorConf.GetHttpValidator(), l); err != nil {
-			return nil, err
-		}
-		validator.Validate = func(input *Input) (bool, error) {
+var (
+	registry   = make(map[string]Factory)
+	registryMu sync.Mutex
+)
+
+// RegisterValidator registers a validator factory for the given validator
+// type name. Built-in validators are registered by their config field name,
+// e.g. "http_validator", while custom validators are registered by the full
+// name of their config extension (see validators/proto/config.proto), e.g.:
+//
+//	validators.RegisterValidator("myorg.custom_validator", newCustomValidator)
+func RegisterValidator(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+func newHTTPValidator(cfg proto.Message, l *logger.Logger) (*Validator, error) {
+	v := &http.Validator{}
+	if err := v.Init(cfg, l); err != nil {
+		return nil, err
+	}
+	return &Validator{
+		Validate: func(input *Input) (bool, error) {
 			return v.Validate(input.Response, input.ResponseBody)
-		}
-		return
+		},
+	}, nil
+}
 
-	case *configpb.Validator_IntegrityValidator:
-		v := &integrity.Validator{}
-		if err := v.Init(validatorConf.GetIntegrityValidator(), l); err != nil {
-			return nil, err
-		}
-		validator.Validate = func(input *Input) (bool, error) {
+func newIntegrityValidator(cfg proto.Message, l *logger.Logger) (*Validator, error) {
+	v := &integrity.Validator{}
+	if err := v.Init(cfg, l); err != nil {
+		return nil, err
+	}
+	return &Validator{
+		Validate: func(input *Input) (bool, error) {
 			return v.Validate(input.ResponseBody)
-		}
-		return
+		},
+	}, nil
+}
 
-	case *configpb.Validator_Regex:
-		v := &regex.Validator{}
-		if err := v.Init(validatorConf.GetRegex(), l); err != nil {
-			return nil, err
-		}
-		validator.Validate = func(input *Input) (bool, error) {
+func newRegexValidator(cfg proto.Message, l *logger.Logger) (*Validator, error) {
+	s, ok := cfg.(*wrapperspb.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a valid regex validator config", cfg)
+	}
+	v := &regex.Validator{}
+	if err := v.Init(s.GetValue(), l); err != nil {
+		return nil, err
+	}
+	return &Validator{
+		Validate: func(input *Input) (bool, error) {
 			return v.Validate(input.ResponseBody)
+		},
+	}, nil
+}
+
+func init() {
+	RegisterValidator("http_validator", newHTTPValidator)
+	RegisterValidator("integrity_validator", newIntegrityValidator)
+	RegisterValidator("regex", newRegexValidator)
+}
+
+func registeredNames() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validatorType returns the validator type name and its config: either the
+// "type" oneof field, or an extension. Scalar configs (regex) are wrapped in
+// a StringValue.
+func validatorType(validatorConf *configpb.Validator) (string, proto.Message) {
+	m := proto.MessageReflect(validatorConf)
+
+	var fd protoreflect.FieldDescriptor
+	name := ""
+	if fd = m.WhichOneof(m.Descriptor().Oneofs().ByName("type")); fd != nil {
+		name = string(fd.Name())
+	} else {
+		m.Range(func(xd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if xd.IsExtension() {
+				fd, name = xd, string(xd.FullName())
+				return false
+			}
+			return true
+		})
+	}
+	if fd == nil {
+		return "", nil
+	}
+
+	if fd.Message() != nil {
+		return name, proto.MessageV1(m.Get(fd).Message().Interface())
+	}
+	return name, wrapperspb.String(m.Get(fd).String())
+}
+
+func initValidator(validatorConf *configpb.Validator, l *logger.Logger) (*Validator, error) {
+	name, cfg := validatorType(validatorConf)
+
+	registryMu.Lock()
+	factory, ok := registry[name]
+	registryMu.Unlock()
+
+	if !ok {
+		if name == "" {
+			return nil, fmt.Errorf("validator %s: no validator type configured, registered types: %s", validatorConf.GetName(), strings.Join(registeredNames(), ", "))
 		}
-		return
-	default:
-		err = fmt.Errorf("unknown validator type: %v", validatorConf.Type)
-		return
+		return nil, fmt.Errorf("validator %s: unknown validator type: %s, registered types: %s", validatorConf.GetName(), name, strings.Join(registeredNames(), ", "))
+	}
+
+	v, err := factory(cfg, l)
+	if err != nil {
+		return nil, err
 	}
+	v.Name = validatorConf.GetName()
+	return v, nil
 }
 
 // Input encapsulates the input for validators.
@@ -16,9 +16,13 @@ package validators
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/cloudprober/cloudprober/logger"
+	httppb "github.com/cloudprober/cloudprober/validators/http/proto"
+	configpb "github.com/cloudprober/cloudprober/validators/proto"
+	"github.com/golang/protobuf/proto"
 )
 
 type testValidator struct {
@@ -72,3 +76,71 @@ func TestValidatorFailureMap(t *testing.T) {
 		t.Errorf("Didn't get expected keys in the mao. Got: %s, Expected: %v", vfMap.Keys(), expectedKeys)
 	}
 }
+
+func TestInitValidators(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		conf    *configpb.Validator
+		wantErr string
+	}{
+		{
+			desc: "regex",
+			conf: &configpb.Validator{
+				Name: proto.String("v"),
+				Type: &configpb.Validator_Regex{Regex: "ok"},
+			},
+		},
+		{
+			desc: "http",
+			conf: &configpb.Validator{
+				Name: proto.String("v"),
+				Type: &configpb.Validator_HttpValidator{HttpValidator: &httppb.Validator{SuccessStatusCodes: proto.String("200")}},
+			},
+		},
+		{
+			desc: "bad_regex",
+			conf: &configpb.Validator{
+				Name: proto.String("v"),
+				Type: &configpb.Validator_Regex{Regex: "(ok"},
+			},
+			wantErr: "error compiling",
+		},
+		{
+			desc:    "no_type",
+			conf:    &configpb.Validator{Name: proto.String("v")},
+			wantErr: "registered types: http_validator, integrity_validator, regex",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			vs, err := Init([]*configpb.Validator{test.conf}, nil)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("Got error: %v, want error containing: %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(vs) != 1 || vs[0].Name != "v" || vs[0].Validate == nil {
+				t.Errorf("Got validators: %v, want one initialized validator named v", vs)
+			}
+		})
+	}
+}
+
+func TestUnknownValidatorType(t *testing.T) {
+	registryMu.Lock()
+	delete(registry, "regex")
+	registryMu.Unlock()
+	defer RegisterValidator("regex", newRegexValidator)
+
+	_, err := Init([]*configpb.Validator{{
+		Name: proto.String("v"),
+		Type: &configpb.Validator_Regex{Regex: "ok"},
+	}}, nil)
+	wantErr := "unknown validator type: regex, registered types: http_validator, integrity_validator"
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("Got error: %v, want error containing: %q", err, wantErr)
+	}
+}
@@ -17,6 +17,7 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -24,10 +25,15 @@ import (
 
 	"github.com/cloudprober/cloudprober"
 	"github.com/cloudprober/cloudprober/config/runconfig"
+	spb "github.com/cloudprober/cloudprober/prober/proto"
 	"github.com/cloudprober/cloudprober/probes"
+	"github.com/cloudprober/cloudprober/probes/common/runstats"
 	"github.com/cloudprober/cloudprober/servers"
 	"github.com/cloudprober/cloudprober/surfacers"
 	"github.com/cloudprober/cloudprober/sysvars"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func execTmpl(tmpl *template.Template, v interface{}) template.HTML {
@@ -70,8 +76,113 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, Status())
 }
 
+// probeStatsHandler returns probes' runtime stats in the JSON format.
+func probeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(runstats.Default().Snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// maintenanceHandler lists, sets and clears the probes' maintenance. GET
+// lists the probes in maintenance, POST with ?probe=<name> puts a probe in
+// maintenance, until the optional duration (e.g. &duration=30m, at least 1s)
+// expires, and DELETE with ?probe=<name> takes the probe out of maintenance.
+func maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	svc := cloudprober.CloudproberService()
+	if svc == nil {
+		http.Error(w, "prober is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	var resp proto.Message
+	var err error
+	probe := r.URL.Query().Get("probe")
+
+	switch r.Method {
+	case http.MethodGet:
+		resp, err = svc.ListMaintenance(r.Context(), &spb.ListMaintenanceRequest{})
+	case http.MethodPost:
+		req := &spb.SetMaintenanceRequest{ProbeName: proto.String(probe)}
+		if d := r.URL.Query().Get("duration"); d != "" {
+			duration, perr := time.ParseDuration(d)
+			if perr != nil {
+				http.Error(w, fmt.Sprintf("bad duration (%s): %v", d, perr), http.StatusBadRequest)
+				return
+			}
+			// Duration is sent in seconds, shorter durations would mean
+			// maintenance without expiry.
+			if duration < time.Second {
+				http.Error(w, fmt.Sprintf("bad duration (%s): must be at least 1s", d), http.StatusBadRequest)
+				return
+			}
+			req.DurationSec = proto.Int32(int32(duration.Seconds()))
+		}
+		resp, err = svc.SetMaintenance(r.Context(), req)
+	case http.MethodDelete:
+		resp, err = svc.ClearMaintenance(r.Context(), &spb.ClearMaintenanceRequest{ProbeName: proto.String(probe)})
+	default:
+		http.Error(w, "unsupported method: "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeServiceResponse(w, resp, err)
+}
+
+// triggerHandler runs a probe once for a target, out of the probe's regular
+// schedule, and returns the run's outcome. It expects a POST request with
+// ?probe=<name>&target=<target>.
+func triggerHandler(w http.ResponseWriter, r *http.Request) {
+	svc := cloudprober.CloudproberService()
+	if svc == nil {
+		http.Error(w, "prober is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "unsupported method: "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp, err := svc.TriggerProbe(r.Context(), &spb.TriggerProbeRequest{
+		ProbeName: proto.String(r.URL.Query().Get("probe")),
+		Target:    proto.String(r.URL.Query().Get("target")),
+	})
+	writeServiceResponse(w, resp, err)
+}
+
+// writeServiceResponse writes the cloudprober service's response as JSON, or
+// its error with the corresponding HTTP status code.
+func writeServiceResponse(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			code = http.StatusBadRequest
+		case codes.NotFound:
+			code = http.StatusNotFound
+		case codes.Unimplemented:
+			code = http.StatusNotImplemented
+		}
+		http.Error(w, status.Convert(err).Message(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // Init initializes cloudprober web interface handler.
 func Init() {
 	http.HandleFunc("/config", configHandler)
 	http.HandleFunc("/status", statusHandler)
+	http.HandleFunc("/debug/probes", probeStatsHandler)
+	http.HandleFunc("/maintenance", maintenanceHandler)
+	http.HandleFunc("/trigger", triggerHandler)
 }